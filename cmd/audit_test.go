@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+		wantErr  string
+	}{
+		{
+			name:     "duration",
+			value:    "36h",
+			expected: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "date",
+			value:    "2024-03-01",
+			expected: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "timestamp",
+			value:    "2024-03-01T08:30:00Z",
+			expected: time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid",
+			value:   "last week",
+			wantErr: `invalid --since "last week"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parseSince(tt.value, now)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(actual), "expected %s, got %s", tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/config"
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// setBackupPolicy makes the client back up what a migration drops before
+// running it, if the config file sets backup.location
+func setBackupPolicy(fs afero.Fs, client *db.Client) error {
+	cfg, err := config.Load(fs, flags.ConfigFile)
+	if err != nil {
+		return err
+	}
+	client.SetBackupPolicy(db.BackupPolicy{
+		Location:    cfg.Backup.Location,
+		FullCluster: cfg.Backup.FullCluster,
+		OnBackup: func(migration string, backup db.Backup) {
+			logging.Subtle(fmt.Sprintf("  %s before %s", formatBackup(backup), migration))
+		},
+	})
+	return nil
+}
+
+// backupDangerousChanges backs up the tables the dangerous differences change
+// before push applies them, or the whole database if they don't drop data
+// from a table, like a dropped enum value
+func backupDangerousChanges(ctx context.Context, client *db.Client, diffResult *schema.ComparisonResult) error {
+	if !client.BackupEnabled() {
+		return nil
+	}
+	var statements []string
+	dangerous := false
+	for _, diff := range diffResult.Differences {
+		if !diff.Dangerous {
+			continue
+		}
+		dangerous = true
+		for _, stmt := range diff.Statements() {
+			statements = append(statements, stmt.String())
+		}
+	}
+	if !dangerous {
+		return nil
+	}
+
+	tables, _ := db.BackupTables(statements)
+	logging.Info("⟳ Backing up before applying dangerous changes...")
+	backup, err := client.TakeBackup(ctx, tables)
+	if err != nil {
+		return fmt.Errorf("%w; nothing was applied", err)
+	}
+	logging.Success(fmt.Sprintf("✓ %s", formatBackup(*backup)))
+	return nil
+}
+
+// formatBackup describes what a backup holds and where it was written
+func formatBackup(backup db.Backup) string {
+	what := "the database"
+	switch {
+	case backup.Cluster:
+		what = "the cluster"
+	case len(backup.Tables) > 0:
+		what = strings.Join(backup.Tables, ", ")
+	}
+	return fmt.Sprintf("Backed up %s to %s", what, backup.Path)
+}
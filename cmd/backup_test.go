@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestFormatBackup(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Backed up public.a, public.b to s3://bucket/2026/10/16-120000.00",
+		formatBackup(db.Backup{Path: "s3://bucket/2026/10/16-120000.00", Tables: []string{"public.a", "public.b"}}))
+	assert.Equal(t, "Backed up the database to s3://bucket/2026/10/16-120000.00",
+		formatBackup(db.Backup{Path: "s3://bucket/2026/10/16-120000.00"}))
+	assert.Equal(t, "Backed up the cluster to s3://bucket/2026/10/16-120000.00",
+		formatBackup(db.Backup{Path: "s3://bucket/2026/10/16-120000.00", Cluster: true}))
+}
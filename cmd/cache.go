@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/logging"
+)
+
+var cacheCleanMaxSizeMB int64
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local checkpoint cache",
+	Long: `Manage the local checkpoint cache.
+
+Replaying migrations into a shadow database stores the resulting schema in
+~/.cache/scurry/checkpoints (or cache.dir in the config file, or
+$SCURRY_CACHE_DIR), keyed by the migrations it covers, so later runs start
+from it instead of replaying every migration again. The least recently used
+checkpoints are evicted once the cache is larger than cache.max_size_mb
+(default 512).`,
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove cached checkpoints",
+	Long: `Remove the checkpoints in the local checkpoint cache.
+
+Examples:
+  # Remove every cached checkpoint
+  scurry cache clean
+
+  # Evict the least recently used checkpoints until the cache is under 100 MB
+  scurry cache clean --max-size-mb 100
+`,
+	RunE: cacheClean,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+
+	cacheCleanCmd.Flags().Int64Var(&cacheCleanMaxSizeMB, "max-size-mb", 0, "Keep the most recently used checkpoints up to this size instead of removing them all")
+}
+
+func cacheClean(cmd *cobra.Command, args []string) error {
+	err := doCacheClean(afero.NewOsFs())
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func doCacheClean(fs afero.Fs) error {
+	if cacheCleanMaxSizeMB < 0 {
+		return fmt.Errorf("--max-size-mb must not be negative")
+	}
+
+	cache, err := openLocalCheckpointCache(fs)
+	if err != nil {
+		return err
+	}
+
+	removed, freed, err := cache.Evict(cacheCleanMaxSizeMB << 20)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		logging.Info(fmt.Sprintf("Nothing to remove from %s", cache.Path))
+		return nil
+	}
+	logging.Success(fmt.Sprintf("✓ Removed %d checkpoint(s) (%s) from %s", removed, formatMegabytes(freed), cache.Path))
+	return nil
+}
+
+// formatMegabytes formats a size in bytes as megabytes
+func formatMegabytes(bytes int64) string {
+	return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+}
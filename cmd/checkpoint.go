@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"crypto/sha256"
 	"fmt"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -11,11 +14,13 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/pjtatlow/scurry/internal/checkpointcache"
+	"github.com/pjtatlow/scurry/internal/config"
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
 	"github.com/pjtatlow/scurry/internal/schema"
-	"github.com/pjtatlow/scurry/internal/ui"
 )
 
 const (
@@ -40,12 +45,29 @@ var checkpointRegenCmd = &cobra.Command{
 	Use:   "checkpoint-regen",
 	Short: "Regenerate checkpoint.sql files for all migrations",
 	Long: `Regenerate checkpoint.sql files by replaying all migrations in order.
-This is useful after manually editing migrations or fixing checkpoint issues.`,
+This is useful after manually editing migrations or fixing checkpoint issues.
+
+Checkpoints are written where migrations.checkpoints in the config file calls
+for them (every migration by default) and for the last migration; others are
+removed.`,
 	RunE: runCheckpointRegen,
 }
 
+var checkpointVerifyCmd = &cobra.Command{
+	Use:   "checkpoint-verify",
+	Short: "Check every checkpoint.sql file against a replay of the migrations",
+	Long: `Replay all migrations in order into a shadow database and check that each
+checkpoint.sql file matches the schema at its migration, and that its hashes
+match its contents and the migrations before it.
+
+Fails if any checkpoint doesn't match, e.g. in CI to catch hand-edited
+checkpoints. Run 'scurry migration checkpoint-regen' to fix them.`,
+	RunE: runCheckpointVerify,
+}
+
 func init() {
 	migrationCmd.AddCommand(checkpointRegenCmd)
+	migrationCmd.AddCommand(checkpointVerifyCmd)
 }
 
 // computeMigrationsHash computes SHA-256 of concatenated migration contents
@@ -60,6 +82,18 @@ func computeMigrationsHash(migrations []db.Migration) string {
 	return fmt.Sprintf("%x", hash)
 }
 
+// computeMigrationsHashes returns computeMigrationsHash of every prefix of
+// migrations: the i-th hash covers migrations[:i+1]
+func computeMigrationsHashes(migrations []db.Migration) []string {
+	hasher := sha256.New()
+	hashes := make([]string, len(migrations))
+	for i, m := range migrations {
+		hasher.Write([]byte(migrationpkg.StripHeader(m.SQL)))
+		hashes[i] = fmt.Sprintf("%x", hasher.Sum(nil))
+	}
+	return hashes
+}
+
 // computeContentHash computes SHA-256 of content string
 func computeContentHash(content string) string {
 	hash := sha256.Sum256([]byte(content))
@@ -137,9 +171,13 @@ func loadCheckpoint(fs afero.Fs, migrationDir string) (*Checkpoint, error) {
 		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
 	}
 
-	// Split into header and content
-	contentStr := string(content)
-	lines := strings.SplitN(contentStr, "\n", 2)
+	// Extract migration name from directory path
+	return parseCheckpoint(string(content), filepath.Base(migrationDir))
+}
+
+// parseCheckpoint parses checkpoint.sql content: the header line, then the schema
+func parseCheckpoint(content, migrationName string) (*Checkpoint, error) {
+	lines := strings.SplitN(content, "\n", 2)
 	if len(lines) < 1 {
 		return nil, fmt.Errorf("checkpoint file is empty")
 	}
@@ -154,9 +192,6 @@ func loadCheckpoint(fs afero.Fs, migrationDir string) (*Checkpoint, error) {
 		schemaContent = lines[1]
 	}
 
-	// Extract migration name from directory path
-	migrationName := filepath.Base(migrationDir)
-
 	return &Checkpoint{
 		MigrationName: migrationName,
 		Header:        *header,
@@ -192,39 +227,168 @@ func validateCheckpoint(checkpoint *Checkpoint) error {
 	return nil
 }
 
-// findLatestValidCheckpoint finds the most recent valid checkpoint
+// findLatestValidCheckpoint finds the most recent valid checkpoint, either a
+// checkpoint.sql file or one in the local checkpoint cache
 // Returns the checkpoint, its index in allMigrations, and any error
 // Returns nil, -1, nil if no valid checkpoint found
 func findLatestValidCheckpoint(fs afero.Fs, allMigrations []db.Migration) (*Checkpoint, int, error) {
+	local, err := openLocalCheckpointCache(fs)
+	if err != nil {
+		logging.Debug(fmt.Sprintf("  Not using the local checkpoint cache: %v", err))
+	}
+	hashes := computeMigrationsHashes(allMigrations)
+
 	// Iterate from newest to oldest migration
 	for i := len(allMigrations) - 1; i >= 0; i-- {
 		migDir := filepath.Join(flags.MigrationDir, allMigrations[i].Name)
 		checkpoint, err := loadCheckpoint(fs, migDir)
-		if err != nil {
-			continue // Skip invalid checkpoints
+		if err == nil && checkpoint != nil && isValidCheckpoint(checkpoint, hashes[i]) {
+			return checkpoint, i, nil
 		}
-		if checkpoint == nil {
-			continue // No checkpoint in this directory
+
+		if local != nil {
+			content, err := local.Get(context.Background(), hashes[i])
+			if err != nil || content == nil {
+				continue
+			}
+			checkpoint, err := parseCheckpoint(string(content), allMigrations[i].Name)
+			if err == nil && isValidCheckpoint(checkpoint, hashes[i]) {
+				return checkpoint, i, nil
+			}
 		}
+	}
 
-		// Compute expected migrations hash for this point
-		migrationsUpTo := allMigrations[:i+1]
-		expectedHash := computeMigrationsHash(migrationsUpTo)
+	return nil, -1, nil
+}
 
-		// Validate content hash
-		if err := validateCheckpoint(checkpoint); err != nil {
+// latestCheckpointFile returns the index of the newest migration with a valid
+// checkpoint.sql file, or -1 if none has one
+func latestCheckpointFile(fs afero.Fs, allMigrations []db.Migration) int {
+	hashes := computeMigrationsHashes(allMigrations)
+	for i := len(allMigrations) - 1; i >= 0; i-- {
+		checkpoint, err := loadCheckpoint(fs, filepath.Join(flags.MigrationDir, allMigrations[i].Name))
+		if err == nil && checkpoint != nil && isValidCheckpoint(checkpoint, hashes[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// pruneCheckpoints removes the checkpoint.sql files superseded by the keep
+// newest valid ones, and invalid ones older than the newest valid one.
+// Returns the names of the migrations whose checkpoints were removed.
+func pruneCheckpoints(fs afero.Fs, allMigrations []db.Migration, keep int) ([]string, error) {
+	hashes := computeMigrationsHashes(allMigrations)
+	var pruned []string
+	valid := 0
+	for i := len(allMigrations) - 1; i >= 0; i-- {
+		migDir := filepath.Join(flags.MigrationDir, allMigrations[i].Name)
+		checkpoint, err := loadCheckpoint(fs, migDir)
+		if err == nil && checkpoint == nil {
 			continue
 		}
 
-		// Validate migrations hash
-		if checkpoint.Header.MigrationsHash != expectedHash {
+		isValid := err == nil && isValidCheckpoint(checkpoint, hashes[i])
+		if valid < keep && isValid {
+			valid++
+			continue
+		}
+		if valid == 0 {
+			// Newer than every valid checkpoint, so not superseded
 			continue
 		}
 
-		return checkpoint, i, nil
+		if err := fs.Remove(filepath.Join(migDir, checkpointFileName)); err != nil {
+			return pruned, fmt.Errorf("failed to remove checkpoint for %s: %w", allMigrations[i].Name, err)
+		}
+		pruned = append(pruned, allMigrations[i].Name)
 	}
+	return pruned, nil
+}
 
-	return nil, -1, nil
+// isValidCheckpoint reports whether checkpoint is intact and was made after
+// the migrations with migrationsHash
+func isValidCheckpoint(checkpoint *Checkpoint, migrationsHash string) bool {
+	return validateCheckpoint(checkpoint) == nil && checkpoint.Header.MigrationsHash == migrationsHash
+}
+
+// openLocalCheckpointCache opens the checkpoint cache in cache.dir from the
+// config file, or the user cache directory
+func openLocalCheckpointCache(fs afero.Fs) (*checkpointcache.Dir, error) {
+	cfg, err := config.Load(fs, flags.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(cfg.Cache.Dir, "checkpoints")
+	if cfg.Cache.Dir == "" {
+		if dir, err = checkpointcache.DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	return checkpointcache.NewDir(fs, dir, cmp.Or(cfg.Cache.MaxSizeMB<<20, checkpointcache.DefaultMaxSize)), nil
+}
+
+// openCheckpointCache opens the cache in --checkpoint-cache, or
+// migrations.checkpoint_cache in the config file. Returns nil if neither is set.
+func openCheckpointCache() (checkpointcache.Cache, error) {
+	cacheURL := flags.CheckpointCache
+	if cacheURL == "" {
+		cfg, err := config.Load(afero.NewOsFs(), flags.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		cacheURL = cfg.Migrations.CheckpointCache
+	}
+	if cacheURL == "" {
+		return nil, nil
+	}
+	return checkpointcache.Open(cacheURL)
+}
+
+// getCachedCheckpoint returns the cached checkpoint after all of migrations,
+// or nil if the cache doesn't have a valid one
+func getCachedCheckpoint(ctx context.Context, cache checkpointcache.Cache, migrations []db.Migration) (*Checkpoint, error) {
+	migrationsHash := computeMigrationsHash(migrations)
+	content, err := cache.Get(ctx, migrationsHash)
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	checkpoint, err := parseCheckpoint(string(content), migrations[len(migrations)-1].Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cached checkpoint %s: %w", migrationsHash, err)
+	}
+	if checkpoint.Header.MigrationsHash != migrationsHash {
+		return nil, fmt.Errorf("invalid cached checkpoint %s: it is for migrations %s", migrationsHash, checkpoint.Header.MigrationsHash)
+	}
+	if err := validateCheckpoint(checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid cached checkpoint %s: %w", migrationsHash, err)
+	}
+	return checkpoint, nil
+}
+
+// storeLocalCheckpoint copies a checkpoint from the shared cache into the
+// local one
+func storeLocalCheckpoint(ctx context.Context, fs afero.Fs, checkpoint *Checkpoint, migrations []db.Migration) {
+	local, err := openLocalCheckpointCache(fs)
+	if err == nil {
+		content := formatCheckpointHeader(checkpoint.Header.MigrationsHash, checkpoint.Header.CheckpointHash) + "\n" + checkpoint.SchemaContent
+		err = local.Put(ctx, computeMigrationsHash(migrations), []byte(content))
+	}
+	if err != nil {
+		logging.Debug(fmt.Sprintf("  Failed to write the local checkpoint cache: %v", err))
+	}
+}
+
+// putCachedCheckpoint stores the checkpoint after all of migrations in the cache
+func putCachedCheckpoint(ctx context.Context, cache checkpointcache.Cache, migrations []db.Migration, resultSchema *schema.Schema) error {
+	migrationsHash := computeMigrationsHash(migrations)
+	content, err := generateCheckpointContent(resultSchema, migrationsHash)
+	if err != nil {
+		return err
+	}
+	return cache.Put(ctx, migrationsHash, []byte(content))
 }
 
 // runCheckpointRegen regenerates all checkpoint.sql files
@@ -244,11 +408,17 @@ func runCheckpointRegen(cmd *cobra.Command, args []string) error {
 	}
 
 	if len(migrations) == 0 {
-		fmt.Println(ui.Info("No migrations found"))
+		logging.Info("No migrations found")
 		return nil
 	}
 
-	fmt.Println(ui.Header(fmt.Sprintf("Regenerating checkpoints for %d migrations...", len(migrations))))
+	cfg, err := config.Load(fs, flags.ConfigFile)
+	if err != nil {
+		return err
+	}
+	policy := cfg.Migrations.Checkpoints
+
+	logging.Header(fmt.Sprintf("Regenerating checkpoints for %d migrations...", len(migrations)))
 
 	// Start with empty database
 	client, err := db.GetShadowDB(ctx)
@@ -259,8 +429,10 @@ func runCheckpointRegen(cmd *cobra.Command, args []string) error {
 	defer client.Close()
 
 	// Apply migrations one by one and generate checkpoints
+	var written, sinceCheckpoint int
+	var replayTime time.Duration
 	for i, mig := range migrations {
-		fmt.Printf("Processing %s (%d/%d)...\n", mig.Name, i+1, len(migrations))
+		logging.Print(fmt.Sprintf("Processing %s (%d/%d)...", mig.Name, i+1, len(migrations)))
 
 		start := time.Now()
 
@@ -270,6 +442,17 @@ func runCheckpointRegen(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to apply migration %s: %w", mig.Name, err)
 		}
 
+		sinceCheckpoint++
+		replayTime += time.Since(start)
+		migDir := filepath.Join(flags.MigrationDir, mig.Name)
+		if i < len(migrations)-1 && !policy.Due(sinceCheckpoint, replayTime) {
+			if err := removeCheckpoint(fs, migDir); err != nil {
+				return err
+			}
+			continue
+		}
+		sinceCheckpoint, replayTime = 0, 0
+
 		// Get current schema state
 		currentSchema, err := schema.LoadFromDatabase(ctx, client)
 		if err != nil {
@@ -278,19 +461,137 @@ func runCheckpointRegen(cmd *cobra.Command, args []string) error {
 
 		// Generate checkpoint for this migration
 		migrationsUpTo := migrations[:i+1]
-		migDir := filepath.Join(flags.MigrationDir, mig.Name)
 
 		err = createCheckpointForMigration(fs, migrationsUpTo, currentSchema, migDir)
 		if err != nil {
 			return fmt.Errorf("failed to create checkpoint for %s: %w", mig.Name, err)
 		}
+		written++
 
 		duration := time.Since(start)
-		fmt.Println(ui.Success(fmt.Sprintf("  Checkpoint created in %v", duration.Round(time.Millisecond))))
+		logging.Success(fmt.Sprintf("  Checkpoint created in %v", duration.Round(time.Millisecond)))
+	}
+
+	if policy.Keep > 0 {
+		if _, err := pruneCheckpoints(fs, migrations, policy.Keep); err != nil {
+			return err
+		}
+		written = min(written, policy.Keep)
+	}
+
+	logging.Newline()
+	logging.Success(fmt.Sprintf("Regenerated %d checkpoint(s)", written))
+
+	return nil
+}
+
+// removeCheckpoint removes the checkpoint.sql file in a migration directory, if any
+func removeCheckpoint(fs afero.Fs, migrationDir string) error {
+	err := fs.Remove(filepath.Join(migrationDir, checkpointFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint in %s: %w", migrationDir, err)
+	}
+	return nil
+}
+
+// runCheckpointVerify replays all migrations and checks each checkpoint.sql
+// file against the schema at its migration
+func runCheckpointVerify(cmd *cobra.Command, args []string) error {
+	fs := afero.NewOsFs()
+
+	if err := validateMigrationsDir(fs); err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations(fs)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return verifyCheckpoints(cmd.Context(), fs, migrations)
+}
+
+// verifyCheckpoints replays migrations up to the last one with a checkpoint,
+// checking each checkpoint.sql file on the way
+func verifyCheckpoints(ctx context.Context, fs afero.Fs, migrations []db.Migration) error {
+	// Only replay up to the last migration with a checkpoint
+	last := -1
+	for i, mig := range migrations {
+		exists, err := afero.Exists(fs, filepath.Join(flags.MigrationDir, mig.Name, checkpointFileName))
+		if err != nil {
+			return fmt.Errorf("failed to check checkpoint for %s: %w", mig.Name, err)
+		}
+		if exists {
+			last = i
+		}
 	}
+	if last < 0 {
+		logging.Info("No checkpoints found")
+		return nil
+	}
+
+	logging.Header(fmt.Sprintf("Verifying checkpoints against %d migration(s)...", last+1))
+
+	client, err := db.GetShadowDB(ctx)
+	if err != nil {
+		return err
+	}
+	client.SetDisableAutocommitDDL(false)
+	defer client.Close()
 
-	fmt.Println()
-	fmt.Println(ui.Success(fmt.Sprintf("Regenerated %d checkpoint(s)", len(migrations))))
+	hashes := computeMigrationsHashes(migrations)
+	var checked, failed int
+	for i, mig := range migrations[:last+1] {
+		if err := client.ExecuteBulkDDL(ctx, mig.SQL); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", mig.Name, err)
+		}
 
+		checkpoint, err := loadCheckpoint(fs, filepath.Join(flags.MigrationDir, mig.Name))
+		if err == nil && checkpoint == nil {
+			continue
+		}
+		checked++
+
+		if err == nil {
+			err = verifyCheckpoint(ctx, client, checkpoint, hashes[i])
+		}
+		if err != nil {
+			failed++
+			logging.Error(fmt.Sprintf("✗ %s: %v", mig.Name, err))
+			continue
+		}
+		logging.Success(fmt.Sprintf("✓ %s", mig.Name))
+	}
+
+	logging.Newline()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checkpoint(s) failed verification; run 'scurry migration checkpoint-regen' to regenerate them", failed, checked)
+	}
+	logging.Success(fmt.Sprintf("Verified %d checkpoint(s)", checked))
+	return nil
+}
+
+// verifyCheckpoint checks a checkpoint's hashes, and that its schema matches the
+// database the migrations before it were applied to
+func verifyCheckpoint(ctx context.Context, client *db.Client, checkpoint *Checkpoint, migrationsHash string) error {
+	if err := validateCheckpoint(checkpoint); err != nil {
+		return err
+	}
+	if checkpoint.Header.MigrationsHash != migrationsHash {
+		return fmt.Errorf("checkpoint is for different migrations: expected %s, got %s", migrationsHash, checkpoint.Header.MigrationsHash)
+	}
+
+	statements, err := schema.ParseSQL(checkpoint.SchemaContent)
+	if err != nil {
+		return fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	replayed, err := schema.LoadFromDatabase(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to load replayed schema: %w", err)
+	}
+
+	diffResult := schema.Compare(replayed, schema.NewSchema(statements...))
+	if diffResult.HasChanges() {
+		return fmt.Errorf("checkpoint doesn't match the replayed schema:\n%s", diffResult.Summary())
+	}
 	return nil
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -554,3 +555,234 @@ func TestRoundTripCheckpoint(t *testing.T) {
 	err = validateCheckpoint(checkpoint)
 	require.NoError(t, err)
 }
+
+// memoryCheckpointCache is a checkpoint cache backed by a map
+type memoryCheckpointCache map[string]string
+
+func (c memoryCheckpointCache) Get(ctx context.Context, key string) ([]byte, error) {
+	content, ok := c[key]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(content), nil
+}
+
+func (c memoryCheckpointCache) Put(ctx context.Context, key string, data []byte) error {
+	c[key] = string(data)
+	return nil
+}
+
+func TestGetCachedCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	migrations := []db.Migration{
+		{Name: "20240101000000_users", SQL: "CREATE TABLE users (id INT PRIMARY KEY);"},
+		{Name: "20240102000000_posts", SQL: "CREATE TABLE posts (id INT PRIMARY KEY);"},
+	}
+	migrationsHash := computeMigrationsHash(migrations)
+	otherHash := computeMigrationsHash(migrations[:1])
+	schemaContent := "CREATE TABLE public.users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC));"
+	contentHash := computeContentHash(schemaContent)
+
+	tests := []struct {
+		name    string
+		cache   memoryCheckpointCache
+		want    bool
+		wantErr string
+	}{
+		{
+			name:  "hit",
+			cache: memoryCheckpointCache{migrationsHash: formatCheckpointHeader(migrationsHash, contentHash) + "\n" + schemaContent},
+			want:  true,
+		},
+		{
+			name:  "miss",
+			cache: memoryCheckpointCache{otherHash: formatCheckpointHeader(otherHash, contentHash) + "\n" + schemaContent},
+		},
+		{
+			name:    "stored under the wrong key",
+			cache:   memoryCheckpointCache{migrationsHash: formatCheckpointHeader(otherHash, contentHash) + "\n" + schemaContent},
+			wantErr: "it is for migrations " + otherHash,
+		},
+		{
+			name:    "modified content",
+			cache:   memoryCheckpointCache{migrationsHash: formatCheckpointHeader(migrationsHash, contentHash) + "\n" + schemaContent + "DROP TABLE users;"},
+			wantErr: "checkpoint content hash mismatch",
+		},
+		{
+			name:    "not a checkpoint",
+			cache:   memoryCheckpointCache{migrationsHash: "<Error>AccessDenied</Error>"},
+			wantErr: "invalid checkpoint header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			checkpoint, err := getCachedCheckpoint(context.Background(), tt.cache, migrations)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if !tt.want {
+				assert.Nil(t, checkpoint)
+				return
+			}
+			require.NotNil(t, checkpoint)
+			assert.Equal(t, "20240102000000_posts", checkpoint.MigrationName)
+			assert.Equal(t, schemaContent, checkpoint.SchemaContent)
+		})
+	}
+}
+
+func TestComputeMigrationsHashes(t *testing.T) {
+	t.Parallel()
+
+	migrations := []db.Migration{
+		{Name: "20240101000000_users", SQL: "CREATE TABLE users (id INT PRIMARY KEY);"},
+		{Name: "20240102000000_posts", SQL: "-- scurry:mode=sync\nCREATE TABLE posts (id INT PRIMARY KEY);"},
+		{Name: "20240103000000_tags", SQL: "CREATE TABLE tags (id INT PRIMARY KEY);"},
+	}
+
+	hashes := computeMigrationsHashes(migrations)
+	require.Len(t, hashes, 3)
+	for i := range migrations {
+		assert.Equal(t, computeMigrationsHash(migrations[:i+1]), hashes[i])
+	}
+}
+
+func TestFindLatestValidCheckpointInLocalCache(t *testing.T) {
+	t.Setenv("SCURRY_CACHE_DIR", "/cache")
+	ctx := context.Background()
+	fs := afero.NewMemMapFs()
+
+	migrations := []db.Migration{
+		{Name: "20240101000000_users", SQL: "CREATE TABLE users (id INT PRIMARY KEY);"},
+		{Name: "20240102000000_posts", SQL: "CREATE TABLE posts (id INT PRIMARY KEY);"},
+		{Name: "20240103000000_tags", SQL: "CREATE TABLE tags (id INT PRIMARY KEY);"},
+	}
+	checkpointContent := func(upTo int, schemaContent string) string {
+		return formatCheckpointHeader(computeMigrationsHash(migrations[:upTo]), computeContentHash(schemaContent)) + "\n" + schemaContent
+	}
+
+	// A checkpoint.sql file after the first migration
+	migDir := filepath.Join(flags.MigrationDir, migrations[0].Name)
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(migDir, checkpointFileName), []byte(checkpointContent(1, "CREATE TABLE users ();")), 0644))
+
+	checkpoint, index, err := findLatestValidCheckpoint(fs, migrations)
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, 0, index)
+
+	// A newer one in the local cache wins
+	local, err := openLocalCheckpointCache(fs)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/cache", "checkpoints"), local.Path)
+	require.NoError(t, local.Put(ctx, computeMigrationsHash(migrations[:2]), []byte(checkpointContent(2, "CREATE TABLE posts ();"))))
+
+	checkpoint, index, err = findLatestValidCheckpoint(fs, migrations)
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, "20240102000000_posts", checkpoint.MigrationName)
+	assert.Equal(t, "CREATE TABLE posts ();", checkpoint.SchemaContent)
+
+	// Cleaning the cache falls back to the file
+	require.NoError(t, doCacheClean(fs))
+	checkpoint, index, err = findLatestValidCheckpoint(fs, migrations)
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, 0, index)
+}
+
+func TestPruneCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	migrations := []db.Migration{
+		{Name: "20240101000000_a", SQL: "CREATE TABLE a (id INT PRIMARY KEY);"},
+		{Name: "20240102000000_b", SQL: "CREATE TABLE b (id INT PRIMARY KEY);"},
+		{Name: "20240103000000_c", SQL: "CREATE TABLE c (id INT PRIMARY KEY);"},
+		{Name: "20240104000000_d", SQL: "CREATE TABLE d (id INT PRIMARY KEY);"},
+		{Name: "20240105000000_e", SQL: "CREATE TABLE e (id INT PRIMARY KEY);"},
+	}
+	valid := func(i int) string {
+		return formatCheckpointHeader(computeMigrationsHash(migrations[:i+1]), computeContentHash("SELECT 1;")) + "\nSELECT 1;"
+	}
+	const invalid = "not a checkpoint"
+
+	tests := []struct {
+		name        string
+		checkpoints map[int]string
+		keep        int
+		wantPruned  []string
+	}{
+		{
+			name:        "keep the newest",
+			checkpoints: map[int]string{0: valid(0), 2: valid(2), 3: valid(3)},
+			keep:        1,
+			wantPruned:  []string{"20240103000000_c", "20240101000000_a"},
+		},
+		{
+			name:        "keep two",
+			checkpoints: map[int]string{0: valid(0), 2: valid(2), 3: valid(3)},
+			keep:        2,
+			wantPruned:  []string{"20240101000000_a"},
+		},
+		{
+			name:        "invalid checkpoints older than a valid one",
+			checkpoints: map[int]string{1: invalid, 2: valid(2), 3: valid(3)},
+			keep:        3,
+			wantPruned:  []string{"20240102000000_b"},
+		},
+		{
+			name:        "invalid checkpoints newer than every valid one",
+			checkpoints: map[int]string{1: valid(1), 4: invalid},
+			keep:        1,
+		},
+		{
+			name:        "checkpoint for other migrations",
+			checkpoints: map[int]string{1: valid(1), 3: valid(2)},
+			keep:        1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fs := afero.NewMemMapFs()
+			for i, content := range tt.checkpoints {
+				path := filepath.Join(flags.MigrationDir, migrations[i].Name, checkpointFileName)
+				require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+			}
+
+			pruned, err := pruneCheckpoints(fs, migrations, tt.keep)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPruned, pruned)
+
+			for i := range tt.checkpoints {
+				exists, err := afero.Exists(fs, filepath.Join(flags.MigrationDir, migrations[i].Name, checkpointFileName))
+				require.NoError(t, err)
+				assert.Equal(t, !slices.Contains(tt.wantPruned, migrations[i].Name), exists, migrations[i].Name)
+			}
+		})
+	}
+}
+
+func TestLatestCheckpointFile(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+
+	migrations := []db.Migration{
+		{Name: "20240101000000_a", SQL: "CREATE TABLE a (id INT PRIMARY KEY);"},
+		{Name: "20240102000000_b", SQL: "CREATE TABLE b (id INT PRIMARY KEY);"},
+		{Name: "20240103000000_c", SQL: "CREATE TABLE c (id INT PRIMARY KEY);"},
+	}
+	assert.Equal(t, -1, latestCheckpointFile(fs, migrations))
+
+	content := formatCheckpointHeader(computeMigrationsHash(migrations[:2]), computeContentHash("SELECT 1;")) + "\nSELECT 1;"
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(flags.MigrationDir, migrations[1].Name, checkpointFileName), []byte(content), 0644))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(flags.MigrationDir, migrations[2].Name, checkpointFileName), []byte(content), 0644))
+	assert.Equal(t, 1, latestCheckpointFile(fs, migrations))
+}
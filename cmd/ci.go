@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Commands for CI pipelines",
+	Long:  `Report on schema changes from CI pipelines, e.g. on the pull request that makes them.`,
+}
+
+func init() {
+	rootCmd.AddCommand(ciCmd)
+}
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/github"
+	"github.com/pjtatlow/scurry/internal/lint"
+	"github.com/pjtatlow/scurry/internal/logging"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// ciCommentMarker identifies scurry's comment, so re-runs edit it in place
+const ciCommentMarker = "<!-- scurry:ci-comment -->"
+
+var (
+	ciCommentToken  string
+	ciCommentRepo   string
+	ciCommentPR     int
+	ciCommentDryRun bool
+)
+
+var ciCommentCmd = &cobra.Command{
+	Use:   "comment",
+	Short: "Summarize the schema changes on the pull request",
+	Long: `Compare the definitions with the production schema (schema.sql), lint them, and
+post a Markdown summary on the pull request: the planned DDL with the estimated
+cost of each statement, dangerous changes, why the migration would be async,
+and lint issues.
+
+The comment is edited in place on later runs instead of adding another.
+
+In GitHub Actions the repository and pull request are read from the
+environment, and the token from GITHUB_TOKEN; the workflow needs the
+pull-requests: write permission. With --db-url, row counts come from the
+database instead of table_sizes.yaml.
+
+Examples:
+  # In a pull_request workflow
+  scurry ci comment
+
+  # Print the comment instead of posting it
+  scurry ci comment --dry-run
+`,
+	RunE: ciComment,
+}
+
+func init() {
+	ciCmd.AddCommand(ciCommentCmd)
+
+	flags.AddDefinitionDirs(ciCommentCmd)
+	flags.AddEnv(ciCommentCmd)
+	flags.AddMigrationDir(ciCommentCmd)
+	flags.AddDbUrl(ciCommentCmd)
+	flags.AddSkip(ciCommentCmd)
+	ciCommentCmd.Flags().StringVar(&ciCommentToken, "github-token", os.Getenv("GITHUB_TOKEN"), "GitHub token to comment with (also GITHUB_TOKEN env var)")
+	ciCommentCmd.Flags().StringVar(&ciCommentRepo, "repo", os.Getenv("GITHUB_REPOSITORY"), "Repository of the pull request, as owner/name (also GITHUB_REPOSITORY env var)")
+	ciCommentCmd.Flags().IntVar(&ciCommentPR, "pr", 0, "Pull request number (default: the pull request the GitHub Actions run is for)")
+	ciCommentCmd.Flags().BoolVar(&ciCommentDryRun, "dry-run", false, "Print the comment instead of posting it")
+}
+
+func ciComment(cmd *cobra.Command, args []string) error {
+	if ciCommentPR == 0 {
+		ciCommentPR = github.PullRequestFromEnv()
+	}
+	if !ciCommentDryRun {
+		switch {
+		case ciCommentToken == "":
+			return fmt.Errorf("a GitHub token is required (use --github-token or GITHUB_TOKEN)")
+		case ciCommentRepo == "":
+			return fmt.Errorf("the repository is required (use --repo or GITHUB_REPOSITORY)")
+		case ciCommentPR == 0:
+			return fmt.Errorf("no pull request to comment on (use --pr)")
+		}
+	}
+
+	err := doCIComment(cmd.Context())
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func doCIComment(ctx context.Context) error {
+	report, err := buildCIReport(ctx, afero.NewOsFs())
+	if err != nil {
+		return err
+	}
+	body := renderCIComment(report)
+
+	if ciCommentDryRun {
+		fmt.Print(body)
+		return nil
+	}
+
+	comment, err := github.NewClient(ciCommentToken).UpsertComment(ctx, ciCommentRepo, ciCommentPR, ciCommentMarker, body)
+	if err != nil {
+		return fmt.Errorf("failed to comment on pull request #%d: %w", ciCommentPR, err)
+	}
+	logging.Success(fmt.Sprintf("✓ Commented on pull request #%d: %s", ciCommentPR, comment.HTMLURL))
+	return nil
+}
+
+// ciReport is everything the pull request comment summarizes
+type ciReport struct {
+	Differences []schema.Difference
+	// Ignored are the differences to objects expected to differ
+	Ignored    []schema.Difference
+	Statements []string
+	// GenerateErr is why the migration can't be generated, e.g. a change the
+	// target version doesn't support
+	GenerateErr error
+	Classify    *migrationpkg.ClassifyResult
+	TableSizes  *migrationpkg.TableSizes
+	LintIssues  []lint.Issue
+}
+
+// buildCIReport diffs the definitions with the production schema the way
+// migration gen does, and lints them the way lint does
+func buildCIReport(ctx context.Context, fs afero.Fs) (*ciReport, error) {
+	logging.Debug(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(flags.DefinitionDirs, ", ")))
+	dbClient, err := db.GetShadowDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shadow database client: %w", err)
+	}
+	defer dbClient.Close()
+
+	localSchema, err := schema.LoadFromDirectoriesForEnv(ctx, fs, flags.DefinitionDirs, flags.Env, dbClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load local schema: %w", err)
+	}
+	if err := addIgnoredObjects(fs, localSchema, flags.Skip); err != nil {
+		return nil, err
+	}
+
+	logging.Debug(fmt.Sprintf("→ Loading production schema from %s...", getSchemaFilePath()))
+	prodSchema, err := loadProductionSchema(ctx, fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load production schema: %w", err)
+	}
+	prodSchema, err = withRepeatableMigrations(ctx, fs, prodSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply repeatable migrations: %w", err)
+	}
+
+	diffResult := schema.Compare(localSchema, prodSchema)
+	if err := forTargetDatabase(ctx, diffResult); err != nil {
+		return nil, err
+	}
+
+	report := &ciReport{Differences: diffResult.Differences, Ignored: diffResult.Ignored}
+	if diffResult.HasChanges() {
+		report.Statements, _, report.GenerateErr = diffResult.GenerateMigrations(true)
+	}
+
+	report.TableSizes, err = migrationpkg.LoadTableSizes(fs, flags.MigrationDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table_sizes.yaml: %w", err)
+	}
+	if flags.DbUrl != "" {
+		report.TableSizes = liveTableSizesOrFile(ctx, report.TableSizes)
+	}
+	classifyRules, err := loadClassifyRules(fs)
+	if err != nil {
+		return nil, err
+	}
+	report.Classify = migrationpkg.ClassifyDifferences(diffResult.Differences, report.TableSizes, classifyRules)
+
+	disables, err := lint.LoadDisables(fs, flags.DefinitionDirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lint directives: %w", err)
+	}
+	for _, issue := range lint.Check(localSchema) {
+		if !lint.IsSuppressed(issue, disables) {
+			report.LintIssues = append(report.LintIssues, issue)
+		}
+	}
+
+	return report, nil
+}
+
+// renderCIComment renders the report as the Markdown body of the comment
+func renderCIComment(r *ciReport) string {
+	var sb strings.Builder
+	sb.WriteString(ciCommentMarker + "\n")
+	sb.WriteString("### scurry schema changes\n\n")
+
+	sqls, asts := plannedStatements(r.Statements)
+	if len(r.Differences) == 0 {
+		sb.WriteString("No schema changes.\n")
+	} else {
+		fmt.Fprintf(&sb, "**%d change(s)**", len(r.Differences))
+		if r.GenerateErr == nil {
+			fmt.Fprintf(&sb, " in %d statement(s), classified **%s**", len(sqls), r.Classify.Mode)
+		}
+		sb.WriteString(".\n")
+
+		if r.GenerateErr != nil {
+			sb.WriteString("\n> [!CAUTION]\n> The migration can't be generated:\n>\n")
+			for _, line := range strings.Split(r.GenerateErr.Error(), "\n") {
+				fmt.Fprintf(&sb, "> %s\n", line)
+			}
+		}
+
+		sb.WriteString("\n#### Changes\n\n")
+		for _, diff := range r.Differences {
+			fmt.Fprintf(&sb, "- %s%s\n", diff.Description, markdownSource(diff.Source))
+		}
+
+		var warnings []string
+		for _, diff := range r.Differences {
+			switch {
+			case diff.Dangerous:
+				warnings = append(warnings, fmt.Sprintf("- **Dangerous:** %s%s", diff.Description, markdownDetail(diff.WarningMessage)))
+			case diff.WarningMessage != "":
+				warnings = append(warnings, fmt.Sprintf("- %s%s", diff.Description, markdownDetail(diff.WarningMessage)))
+			}
+		}
+		if len(warnings) > 0 {
+			sb.WriteString("\n#### ⚠️ Warnings\n\n")
+			sb.WriteString(strings.Join(warnings, "\n") + "\n")
+		}
+
+		if r.GenerateErr == nil && r.Classify.Mode == migrationpkg.ModeAsync {
+			sb.WriteString("\n#### Why it's async\n\n")
+			for _, reason := range r.Classify.Reasons {
+				fmt.Fprintf(&sb, "- %s\n", reason)
+			}
+		}
+
+		if r.GenerateErr == nil {
+			fmt.Fprintf(&sb, "\n<details>\n<summary>Planned DDL (%d statement(s))</summary>\n\n", len(sqls))
+			for i, stmt := range sqls {
+				fmt.Fprintf(&sb, "**%d.** %s\n\n```sql\n%s\n```\n\n", i+1, migrationpkg.EstimateCost(asts[i], r.TableSizes), stmt)
+			}
+			sb.WriteString("</details>\n")
+		}
+	}
+
+	if len(r.Ignored) > 0 {
+		fmt.Fprintf(&sb, "\n<details>\n<summary>Ignored (%d change(s) to objects expected to differ)</summary>\n\n", len(r.Ignored))
+		for _, diff := range r.Ignored {
+			fmt.Fprintf(&sb, "- %s%s: %s\n", diff.Description, markdownSource(diff.Source), diff.IgnoreReason)
+		}
+		sb.WriteString("\n</details>\n")
+	}
+
+	sb.WriteString("\n#### Lint\n\n")
+	if len(r.LintIssues) == 0 {
+		sb.WriteString("No issues found.\n")
+	}
+	for _, issue := range r.LintIssues {
+		fmt.Fprintf(&sb, "- `%s.%s` (%s)%s: %s. Suggestion: %s\n",
+			issue.Table, issue.Constraint, issue.Rule, markdownSource(issue.Source), issue.Description, issue.Suggestion)
+	}
+	return sb.String()
+}
+
+// plannedStatements parses the statements, leaving out transaction boundaries
+func plannedStatements(statements []string) ([]string, []tree.Statement) {
+	var sqls []string
+	var asts []tree.Statement
+	for _, stmt := range statements {
+		parsed, err := parser.ParseOne(stmt)
+		if err != nil {
+			continue
+		}
+		switch parsed.AST.(type) {
+		case *tree.BeginTransaction, *tree.CommitTransaction:
+			continue
+		}
+		sqls = append(sqls, stmt)
+		asts = append(asts, parsed.AST)
+	}
+	return sqls, asts
+}
+
+// markdownSource formats a definition location as a trailing code span
+func markdownSource(source schema.SourceLocation) string {
+	if source.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" (`%s`)", source)
+}
+
+// markdownDetail formats an optional explanation after a list item
+func markdownDetail(detail string) string {
+	if detail == "" {
+		return ""
+	}
+	return ": " + strings.ReplaceAll(detail, "\n", " ")
+}
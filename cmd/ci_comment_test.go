@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pjtatlow/scurry/internal/lint"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestRenderCIComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  *ciReport
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "no changes",
+			report:  &ciReport{Classify: &migrationpkg.ClassifyResult{Mode: migrationpkg.ModeSync}},
+			want:    []string{ciCommentMarker, "No schema changes.", "No issues found."},
+			notWant: []string{"Planned DDL", "Warnings"},
+		},
+		{
+			name: "async migration with costs",
+			report: &ciReport{
+				Differences: []schema.Difference{
+					{Description: `Index "posts_author_idx" added to "public.posts"`, Source: schema.SourceLocation{File: "definitions/posts.sql", Line: 3}},
+				},
+				Statements: []string{"CREATE INDEX posts_author_idx ON public.posts (author_id)"},
+				Classify:   &migrationpkg.ClassifyResult{Mode: migrationpkg.ModeAsync, Reasons: []string{"index build on large table public.posts"}},
+				TableSizes: &migrationpkg.TableSizes{Threshold: 1000, Tables: map[string]migrationpkg.TableInfo{"public.posts": {Rows: 15000000}}},
+			},
+			want: []string{
+				"**1 change(s)** in 1 statement(s), classified **async**.",
+				"(`definitions/posts.sql:3`)",
+				"#### Why it's async\n\n- index build on large table public.posts",
+				"**1.** index build on public.posts (~15,000,000 rows)\n\n```sql\nCREATE INDEX posts_author_idx ON public.posts (author_id)\n```",
+			},
+		},
+		{
+			name: "transaction boundaries are not statements",
+			report: &ciReport{
+				Differences: []schema.Difference{{Description: "Table added"}},
+				Statements:  []string{"BEGIN", "CREATE TABLE public.a (id INT8 PRIMARY KEY)", "COMMIT"},
+				Classify:    &migrationpkg.ClassifyResult{Mode: migrationpkg.ModeSync},
+			},
+			want:    []string{"in 1 statement(s), classified **sync**", "Planned DDL (1 statement(s))", "**1.** metadata-only"},
+			notWant: []string{"**2.**", "Why it's async"},
+		},
+		{
+			name: "dangerous change",
+			report: &ciReport{
+				Differences: []schema.Difference{{Description: `Table "public.old" removed`, Dangerous: true, WarningMessage: "Dropping table public.old deletes its data"}},
+				Statements:  []string{"DROP TABLE public.old"},
+				Classify:    &migrationpkg.ClassifyResult{Mode: migrationpkg.ModeSync},
+			},
+			want: []string{"#### ⚠️ Warnings", `- **Dangerous:** Table "public.old" removed: Dropping table public.old deletes its data`},
+		},
+		{
+			name: "migration can't be generated",
+			report: &ciReport{
+				Differences: []schema.Difference{{Description: "Trigger added"}},
+				GenerateErr: errors.New("schema change cannot be applied:\n  - triggers needs CockroachDB v24.3 or later"),
+				Classify:    &migrationpkg.ClassifyResult{Mode: migrationpkg.ModeSync},
+			},
+			want:    []string{"> [!CAUTION]", ">   - triggers needs CockroachDB v24.3 or later"},
+			notWant: []string{"Planned DDL", "classified"},
+		},
+		{
+			name: "ignored changes",
+			report: &ciReport{
+				Ignored:  []schema.Difference{{Description: `Column "email" added to "public.users"`, IgnoreReason: "--skip"}},
+				Classify: &migrationpkg.ClassifyResult{Mode: migrationpkg.ModeSync},
+			},
+			want: []string{"No schema changes.", "Ignored (1 change(s) to objects expected to differ)", "- Column \"email\" added to \"public.users\": --skip"},
+		},
+		{
+			name: "lint issues",
+			report: &ciReport{
+				Classify: &migrationpkg.ClassifyResult{Mode: migrationpkg.ModeSync},
+				LintIssues: []lint.Issue{{
+					Rule:        "fk-missing-index",
+					Table:       "public.orders",
+					Constraint:  "fk_user",
+					Description: "Foreign key on (user_id) has no covering index",
+					Suggestion:  "Add INDEX (user_id) to the table definition",
+					Source:      schema.SourceLocation{File: "definitions/orders.sql", Line: 1},
+				}},
+			},
+			want:    []string{"- `public.orders.fk_user` (fk-missing-index) (`definitions/orders.sql:1`): Foreign key on (user_id) has no covering index. Suggestion: Add INDEX (user_id) to the table definition"},
+			notWant: []string{"No issues found."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderCIComment(tt.report)
+			for _, want := range tt.want {
+				assert.Contains(t, got, want)
+			}
+			for _, notWant := range tt.notWant {
+				assert.NotContains(t, got, notWant)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/lint"
+	"github.com/pjtatlow/scurry/internal/logging"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// Exit codes of scurry ci verify, one per class of failure, so pipelines can
+// branch on them. Anything else that goes wrong exits with 1.
+const (
+	ciExitDefinitions = 2
+	ciExitLint        = 3
+	ciExitMigrations  = 4
+	ciExitCheckpoints = 5
+	ciExitReplay      = 6
+)
+
+var ciVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Run every consistency check on the definitions and migrations",
+	Long: `Run the checks a pipeline needs in one command:
+
+  definitions  the definition files parse and load into a shadow database
+  lint         the definitions have no lint issues
+  migrations   the migration headers are signed and their dependencies are
+               valid, and with --db-url, the applied migrations haven't been
+               edited since
+  checkpoints  every checkpoint.sql matches the schema at its migration
+  replay       the migrations, replayed on a shadow database with the
+               repeatable migrations, produce the definitions
+
+Every check runs, except the ones that need what an earlier check couldn't
+load, and the command exits with the code of the first one that failed:
+
+  0  all checks passed
+  1  the checks couldn't run, e.g. the shadow database is unavailable
+  2  definitions
+  3  lint
+  4  migrations
+  5  checkpoints
+  6  replay
+
+Objects in ignore_diff are left out of the replay comparison.
+
+Examples:
+  scurry ci verify
+  scurry ci verify --db-url="$PRODUCTION_URL"
+`,
+	RunE: ciVerify,
+}
+
+func init() {
+	ciCmd.AddCommand(ciVerifyCmd)
+
+	flags.AddDefinitionDirs(ciVerifyCmd)
+	flags.AddEnv(ciVerifyCmd)
+	flags.AddMigrationDir(ciVerifyCmd)
+	flags.AddDbUrl(ciVerifyCmd)
+}
+
+// ciCheckResult is the outcome of one of scurry ci verify's checks
+type ciCheckResult struct {
+	Name     string
+	ExitCode int
+	Err      error
+	// Skipped is set when the check couldn't run because an earlier one failed
+	Skipped bool
+}
+
+func ciVerify(cmd *cobra.Command, args []string) error {
+	if len(flags.DefinitionDirs) == 0 {
+		return fmt.Errorf("definition directory is required (use --definitions)")
+	}
+
+	results, err := doCIVerify(cmd.Context(), afero.NewOsFs())
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	logging.Newline()
+	logging.Print(formatCIResults(results))
+	if code := ciVerifyExitCode(results); code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+func doCIVerify(ctx context.Context, fs afero.Fs) ([]ciCheckResult, error) {
+	var results []ciCheckResult
+	report := func(name string, exitCode int, err error) {
+		if err != nil {
+			logging.Error(fmt.Sprintf("✗ %v", err))
+		}
+		results = append(results, ciCheckResult{Name: name, ExitCode: exitCode, Err: err})
+	}
+	skip := func(name string, exitCode int) {
+		results = append(results, ciCheckResult{Name: name, ExitCode: exitCode, Skipped: true})
+	}
+
+	shadow, err := db.GetShadowDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shadow database client: %w", err)
+	}
+	defer shadow.Close()
+
+	logging.Header("→ Loading definitions...")
+	localSchema, err := schema.LoadFromDirectoriesForEnv(ctx, fs, flags.DefinitionDirs, flags.Env, shadow)
+	if err != nil {
+		localSchema = nil
+		err = fmt.Errorf("failed to load definitions: %w", err)
+	}
+	report("definitions", ciExitDefinitions, err)
+
+	if localSchema != nil {
+		logging.Header("→ Linting definitions...")
+		report("lint", ciExitLint, ciLint(fs, localSchema))
+	} else {
+		skip("lint", ciExitLint)
+	}
+
+	exists, err := afero.DirExists(fs, flags.MigrationDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check migrations directory: %w", err)
+	}
+	if !exists {
+		logging.Subtle(fmt.Sprintf("No migrations directory at %s; skipping the migration checks", flags.MigrationDir))
+		return results, nil
+	}
+
+	logging.Header("→ Verifying migrations...")
+	migrations, err := loadMigrations(fs)
+	if err != nil {
+		report("migrations", ciExitMigrations, fmt.Errorf("failed to load migrations: %w", err))
+		skip("checkpoints", ciExitCheckpoints)
+		skip("replay", ciExitReplay)
+		return results, nil
+	}
+	report("migrations", ciExitMigrations, ciVerifyMigrations(ctx, fs, migrations))
+
+	logging.Header("→ Verifying checkpoints...")
+	report("checkpoints", ciExitCheckpoints, verifyCheckpoints(ctx, fs, migrations))
+
+	if localSchema == nil {
+		skip("replay", ciExitReplay)
+		return results, nil
+	}
+	logging.Header("→ Replaying migrations...")
+	report("replay", ciExitReplay, ciReplayMigrations(ctx, fs, migrations, localSchema))
+	return results, nil
+}
+
+// ciLint lints the definitions, leaving out the issues lint-disable
+// directives suppress
+func ciLint(fs afero.Fs, localSchema *schema.Schema) error {
+	disables, err := lint.LoadDisables(fs, flags.DefinitionDirs)
+	if err != nil {
+		return fmt.Errorf("failed to load lint directives: %w", err)
+	}
+	issues := filterLintIssues(lint.Check(localSchema), disables)
+	if len(issues) == 0 {
+		logging.Success("✓ No issues found!")
+		return nil
+	}
+	printLintIssues(issues)
+	return fmt.Errorf("%d lint issue(s)", len(issues))
+}
+
+// ciVerifyMigrations checks the migrations' signatures and dependencies, and
+// with --db-url, that the applied ones match their files
+func ciVerifyMigrations(ctx context.Context, fs afero.Fs, migrations []db.Migration) error {
+	if err := verifyAndReportSignatures(fs, migrations, false); err != nil {
+		return err
+	}
+	if _, err := migrationpkg.PlanExecution(migrations); err != nil {
+		return err
+	}
+	if flags.DbUrl == "" {
+		logging.Success(fmt.Sprintf("✓ %d migration(s) are signed", len(migrations)))
+		return nil
+	}
+
+	client, err := db.Connect(ctx, flags.DbUrl)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer client.Close()
+	return verifyAppliedMigrations(ctx, client, migrations, false)
+}
+
+// ciReplayMigrations replays the migrations on a clean shadow database and
+// checks that they produce the definitions
+func ciReplayMigrations(ctx context.Context, fs afero.Fs, migrations []db.Migration, localSchema *schema.Schema) error {
+	replayed, err := applyMigrationsToCleanDatabase(ctx, migrations, flags.Verbose)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	replayed, err = withRepeatableMigrations(ctx, fs, replayed)
+	if err != nil {
+		return err
+	}
+	if err := addIgnoredObjects(fs, localSchema, nil); err != nil {
+		return err
+	}
+
+	diffResult := schema.Compare(localSchema, replayed)
+	printIgnoredDifferences(diffResult)
+	if !diffResult.HasChanges() {
+		logging.Success(fmt.Sprintf("✓ %d migration(s) produce the definitions", len(migrations)))
+		return nil
+	}
+	logging.Print(diffResult.Summary())
+	return fmt.Errorf("the migrations don't produce the definitions; run 'scurry migration gen' to add a migration for the differences")
+}
+
+// ciVerifyExitCode is the exit code of the first check that failed, or 0
+func ciVerifyExitCode(results []ciCheckResult) int {
+	for _, result := range results {
+		if result.Err != nil {
+			return result.ExitCode
+		}
+	}
+	return 0
+}
+
+// formatCIResults lists each check with whether it passed
+func formatCIResults(results []ciCheckResult) string {
+	var lines []string
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			lines = append(lines, fmt.Sprintf("- %s: skipped", result.Name))
+		case result.Err != nil:
+			lines = append(lines, fmt.Sprintf("✗ %s: failed (exit %d)", result.Name, result.ExitCode))
+		default:
+			lines = append(lines, fmt.Sprintf("✓ %s", result.Name))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCIVerifyExitCode(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name    string
+		results []ciCheckResult
+		want    int
+	}{
+		{
+			name:    "no checks",
+			results: nil,
+			want:    0,
+		},
+		{
+			name: "all passed",
+			results: []ciCheckResult{
+				{Name: "definitions", ExitCode: ciExitDefinitions},
+				{Name: "lint", ExitCode: ciExitLint},
+			},
+			want: 0,
+		},
+		{
+			name: "first failure wins",
+			results: []ciCheckResult{
+				{Name: "definitions", ExitCode: ciExitDefinitions},
+				{Name: "lint", ExitCode: ciExitLint, Err: failed},
+				{Name: "migrations", ExitCode: ciExitMigrations},
+				{Name: "checkpoints", ExitCode: ciExitCheckpoints, Err: failed},
+			},
+			want: ciExitLint,
+		},
+		{
+			name: "skipped checks don't fail",
+			results: []ciCheckResult{
+				{Name: "definitions", ExitCode: ciExitDefinitions, Err: failed},
+				{Name: "lint", ExitCode: ciExitLint, Skipped: true},
+				{Name: "replay", ExitCode: ciExitReplay, Skipped: true},
+			},
+			want: ciExitDefinitions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ciVerifyExitCode(tt.results))
+		})
+	}
+}
+
+func TestFormatCIResults(t *testing.T) {
+	results := []ciCheckResult{
+		{Name: "definitions", ExitCode: ciExitDefinitions},
+		{Name: "migrations", ExitCode: ciExitMigrations, Err: errors.New("failed")},
+		{Name: "replay", ExitCode: ciExitReplay, Skipped: true},
+	}
+	assert.Equal(t, "✓ definitions\n✗ migrations: failed (exit 4)\n- replay: skipped", formatCIResults(results))
+}
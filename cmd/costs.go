@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+// printStatementCosts lists statements with their estimated cost, so reviewers
+// can see which ones backfill, build indexes or rewrite tables. Transaction
+// boundaries are left out.
+func printStatementCosts(statements []string, ts *migrationpkg.TableSizes) {
+	logging.Header("Estimated cost of each statement:")
+	n := 0
+	for _, stmt := range statements {
+		parsed, err := parser.ParseOne(stmt)
+		if err != nil {
+			continue
+		}
+		switch parsed.AST.(type) {
+		case *tree.BeginTransaction, *tree.CommitTransaction:
+			continue
+		}
+		n++
+
+		cost := migrationpkg.EstimateCost(parsed.AST, ts)
+		label := ui.Subtle(cost.String())
+		if cost.Class != migrationpkg.CostMetadata && (cost.Rows < 0 || ts.IsLargeTable(cost.Table)) {
+			label = ui.Warning(cost.String())
+		}
+		logging.Print(fmt.Sprintf("%s %s\n%s\n", ui.Info(fmt.Sprintf("%d.", n)), label, ui.SqlCode(stmt)))
+	}
+}
+
+// liveTableSizes reads the row counts of the database's tables
+func liveTableSizes(ctx context.Context, dbClient *db.Client, threshold int64) (*migrationpkg.TableSizes, error) {
+	tableSizes, err := dbClient.GetTableSizes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table sizes: %w", err)
+	}
+	return tableSizesFromInfo(tableSizes, threshold), nil
+}
+
+// tableSizesFromInfo builds table_sizes.yaml's contents from the sizes read
+// from the --db-url database
+func tableSizesFromInfo(tableSizes []db.TableSizeInfo, threshold int64) *migrationpkg.TableSizes {
+	ts := &migrationpkg.TableSizes{
+		Threshold:  threshold,
+		Source:     describeConnection(flags.DbUrl),
+		CapturedAt: time.Now().UTC().Truncate(time.Second),
+		Tables:     make(map[string]migrationpkg.TableInfo, len(tableSizes)),
+	}
+	for _, t := range tableSizes {
+		qualifiedName := fmt.Sprintf("%s.%s", t.SchemaName, t.TableName)
+		ts.Tables[qualifiedName] = migrationpkg.TableInfo{
+			Rows:  t.Rows,
+			Bytes: t.Bytes,
+		}
+	}
+	return ts
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+// formatDangerReport lays out each dangerous difference with why it is
+// dangerous, the statements it runs and how to make it safer
+func formatDangerReport(items []schema.DangerItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d dangerous change(s):\n", len(items))
+	for i, item := range items {
+		fmt.Fprintf(&b, "\n%d. %s (%s)\n", i+1, item.Object, item.Type)
+		if !item.Source.IsZero() {
+			fmt.Fprintf(&b, "   Defined in: %s\n", item.Source)
+		}
+		fmt.Fprintf(&b, "   Reason: %s\n", item.Reason)
+		b.WriteString("   Statements:\n")
+		for _, stmt := range item.Statements {
+			fmt.Fprintf(&b, "     %s;\n", stmt)
+		}
+		b.WriteString("   Mitigations:\n")
+		for _, m := range item.Mitigations {
+			fmt.Fprintf(&b, "     - %s\n", m)
+		}
+	}
+	return b.String()
+}
+
+// confirmDangerReport asks the user to type the name of the object of each
+// dangerous difference. It returns false as soon as one doesn't match.
+func confirmDangerReport(items []schema.DangerItem) (bool, error) {
+	if !ui.IsInteractive() {
+		return false, fmt.Errorf("--report requires an interactive terminal to confirm each dangerous change\nUse --dry-run to only print the report")
+	}
+	for i, item := range items {
+		confirmed, err := ui.TypedConfirmPrompt(fmt.Sprintf("[%d/%d] %s", i+1, len(items), item.Reason), item.Object)
+		if err != nil {
+			return false, fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirmed {
+			logging.Warning(fmt.Sprintf("⚠ Input didn't match %s", item.Object))
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestFormatDangerReport(t *testing.T) {
+	report := formatDangerReport([]schema.DangerItem{
+		{
+			Object:      "public.audit",
+			Type:        schema.DiffTypeTableRemoved,
+			Reason:      "Table 'public.audit' removed",
+			Statements:  []string{"DROP TABLE IF EXISTS public.audit RESTRICT"},
+			Mitigations: []string{"Back up the table before dropping it"},
+		},
+		{
+			Object:      "public.users",
+			Type:        schema.DiffTypeTableModified,
+			Reason:      "Index 'public.users.users_name_idx' removed",
+			Statements:  []string{"DROP INDEX public.users@users_name_idx"},
+			Mitigations: []string{"Check that no query still reads the index"},
+			Source:      schema.SourceLocation{File: "tables/users.sql", Line: 3},
+		},
+	})
+
+	assert.Equal(t, `2 dangerous change(s):
+
+1. public.audit (table_removed)
+   Reason: Table 'public.audit' removed
+   Statements:
+     DROP TABLE IF EXISTS public.audit RESTRICT;
+   Mitigations:
+     - Back up the table before dropping it
+
+2. public.users (table_modified)
+   Defined in: tables/users.sql:3
+   Reason: Index 'public.users.users_name_idx' removed
+   Statements:
+     DROP INDEX public.users@users_name_idx;
+   Mitigations:
+     - Check that no query still reads the index
+`, report)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// databasesDir is the directory in a definitions tree that holds one
+// definitions directory per logical database: databases/<dbname>/tables/...
+const databasesDir = "databases"
+
+// databaseDefinitions is a logical database and the directories that define it
+type databaseDefinitions struct {
+	Name           string
+	DefinitionDirs []string
+}
+
+// findDatabaseDefinitions returns the databases laid out under databases/ in the
+// definition directories, sorted by name. It returns nil if no definition
+// directory uses the layout, meaning the tree defines a single database.
+func findDatabaseDefinitions(fs afero.Fs, definitionDirs []string) ([]databaseDefinitions, error) {
+	dirsByName := make(map[string][]string)
+	for _, dir := range definitionDirs {
+		root := filepath.Join(dir, databasesDir)
+		exists, err := afero.DirExists(fs, root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", root, err)
+		}
+		if !exists {
+			continue
+		}
+
+		// Files outside databases/ wouldn't belong to any database
+		if err := checkNoStrayDefinitions(fs, dir, root); err != nil {
+			return nil, err
+		}
+
+		entries, err := afero.ReadDir(fs, root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", root, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			name := entry.Name()
+			dirsByName[name] = append(dirsByName[name], filepath.Join(root, name))
+		}
+	}
+
+	if len(dirsByName) == 0 {
+		return nil, nil
+	}
+
+	databases := make([]databaseDefinitions, 0, len(dirsByName))
+	for name, dirs := range dirsByName {
+		databases = append(databases, databaseDefinitions{Name: name, DefinitionDirs: dirs})
+	}
+	sort.Slice(databases, func(i, j int) bool {
+		return databases[i].Name < databases[j].Name
+	})
+	return databases, nil
+}
+
+// checkNoStrayDefinitions returns an error if dir has SQL files outside of root
+func checkNoStrayDefinitions(fs afero.Fs, dir, root string) error {
+	return afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(strings.ToLower(path), ".sql") {
+			return fmt.Errorf("%s is outside of %s; when using the %s/<name> layout, every definition file must belong to a database", path, root, databasesDir)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDatabaseDefinitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		dirs        []string
+		expected    []databaseDefinitions
+		errContains string
+	}{
+		{
+			name:     "single database layout",
+			files:    map[string]string{"defs/tables/users.sql": "CREATE TABLE users (id INT PRIMARY KEY);"},
+			dirs:     []string{"defs"},
+			expected: nil,
+		},
+		{
+			name: "databases are sorted by name",
+			files: map[string]string{
+				"defs/databases/orders/tables/orders.sql":  "CREATE TABLE orders (id INT PRIMARY KEY);",
+				"defs/databases/accounts/tables/users.sql": "CREATE TABLE users (id INT PRIMARY KEY);",
+			},
+			dirs: []string{"defs"},
+			expected: []databaseDefinitions{
+				{Name: "accounts", DefinitionDirs: []string{"defs/databases/accounts"}},
+				{Name: "orders", DefinitionDirs: []string{"defs/databases/orders"}},
+			},
+		},
+		{
+			name: "same database across definition directories",
+			files: map[string]string{
+				"a/databases/app/tables/users.sql": "CREATE TABLE users (id INT PRIMARY KEY);",
+				"b/databases/app/tables/posts.sql": "CREATE TABLE posts (id INT PRIMARY KEY);",
+			},
+			dirs: []string{"a", "b"},
+			expected: []databaseDefinitions{
+				{Name: "app", DefinitionDirs: []string{"a/databases/app", "b/databases/app"}},
+			},
+		},
+		{
+			name: "files outside databases are rejected",
+			files: map[string]string{
+				"defs/databases/app/tables/users.sql": "CREATE TABLE users (id INT PRIMARY KEY);",
+				"defs/tables/posts.sql":               "CREATE TABLE posts (id INT PRIMARY KEY);",
+			},
+			dirs:        []string{"defs"},
+			errContains: "defs/tables/posts.sql is outside of defs/databases",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, content := range tt.files {
+				require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+			}
+
+			databases, err := findDatabaseDefinitions(fs, tt.dirs)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, databases)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/logging"
 	"github.com/pjtatlow/scurry/internal/ui"
 )
 
@@ -45,13 +46,13 @@ func debug(cmd *cobra.Command, args []string) error {
 
 	report, err := parseErrorReport(reportPath)
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
 	err = doDebug(cmd.Context(), report)
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -59,25 +60,25 @@ func debug(cmd *cobra.Command, args []string) error {
 }
 
 func doDebug(ctx context.Context, report *ErrorReport) error {
-	fmt.Println(ui.Header("Debugging Push Error"))
-	fmt.Println()
+	logging.Header("Debugging Push Error")
+	logging.Newline()
 
 	// Show the original error
-	fmt.Println(ui.Info("Original error:"))
-	fmt.Println(ui.Error(report.Error))
-	fmt.Println()
+	logging.Info("Original error:")
+	logging.Error(report.Error)
+	logging.Newline()
 
 	// Check if we have migrations to replay
 	if len(report.Migrations) == 0 {
-		fmt.Println(ui.Warning("No migrations found in error report. The error occurred before migration generation."))
+		logging.Warning("No migrations found in error report. The error occurred before migration generation.")
 		return nil
 	}
 
-	fmt.Println(ui.Info(fmt.Sprintf("Found %d remote schema statement(s) and %d migration(s) to replay", len(report.RemoteStatements), len(report.Migrations))))
-	fmt.Println()
+	logging.Info(fmt.Sprintf("Found %d remote schema statement(s) and %d migration(s) to replay", len(report.RemoteStatements), len(report.Migrations)))
+	logging.Newline()
 
 	// Create shadow database
-	fmt.Println(ui.Subtle("→ Creating test database..."))
+	logging.Subtle("→ Creating test database...")
 	client, err := db.GetShadowDB(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to create test database: %w", err)
@@ -86,42 +87,42 @@ func doDebug(ctx context.Context, report *ErrorReport) error {
 
 	// Apply remote schema to set up initial state
 	if len(report.RemoteStatements) > 0 {
-		fmt.Println(ui.Subtle("→ Applying remote schema to establish initial state..."))
+		logging.Subtle("→ Applying remote schema to establish initial state...")
 		if err := client.ExecuteBulkDDL(ctx, report.RemoteStatements...); err != nil {
 			return fmt.Errorf("failed to apply remote schema: %w", err)
 		}
-		fmt.Println(ui.Success("  ✓ Remote schema applied successfully"))
+		logging.Success("  ✓ Remote schema applied successfully")
 	}
-	fmt.Println()
+	logging.Newline()
 
 	// Replay migrations one by one
-	fmt.Println(ui.Header("Replaying migrations:"))
-	fmt.Println()
+	logging.Header("Replaying migrations:")
+	logging.Newline()
 
 	for i, stmt := range report.Migrations {
-		fmt.Printf("%s %s\n", ui.Info(fmt.Sprintf("%d.", i+1)), ui.SqlCode(stmt))
+		logging.Print(fmt.Sprintf("%s %s", ui.Info(fmt.Sprintf("%d.", i+1)), ui.SqlCode(stmt)))
 
 		if err := client.ExecuteBulkDDL(ctx, stmt); err != nil {
-			fmt.Println()
-			fmt.Println(ui.Error(fmt.Sprintf("✗ Statement %d failed:", i+1)))
-			fmt.Println()
-			fmt.Println(ui.SqlCode(stmt))
-			fmt.Println()
-			fmt.Println(ui.Error(fmt.Sprintf("Error: %s", err)))
+			logging.Newline()
+			logging.Error(fmt.Sprintf("✗ Statement %d failed:", i+1))
+			logging.Newline()
+			logging.Print(ui.SqlCode(stmt))
+			logging.Newline()
+			logging.Error(fmt.Sprintf("Error: %s", err))
 			return nil // We found the failing statement, exit gracefully
 		}
 
-		fmt.Println(ui.Success("  ✓ Success"))
-		fmt.Println()
+		logging.Success("  ✓ Success")
+		logging.Newline()
 	}
 
-	fmt.Println(ui.Success("✓ All migrations replayed successfully!"))
-	fmt.Println()
-	fmt.Println(ui.Warning("The migrations succeeded in the test database. The original error may have been caused by:"))
-	fmt.Println("  • Concurrent schema changes")
-	fmt.Println("  • Data-dependent constraints")
-	fmt.Println("  • Permission issues")
-	fmt.Println("  • Network/connection issues")
+	logging.Success("✓ All migrations replayed successfully!")
+	logging.Newline()
+	logging.Warning("The migrations succeeded in the test database. The original error may have been caused by:")
+	logging.Print("  • Concurrent schema changes")
+	logging.Print("  • Data-dependent constraints")
+	logging.Print("  • Permission issues")
+	logging.Print("  • Network/connection issues")
 
 	return nil
 }
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/set"
+)
+
+const allowDestructivePrefix = "-- scurry:allow-destructive="
+
+// parseAllowDestructive scans lines from the top of a SQL file for
+// -- scurry:allow-destructive=<table> directives, returning the qualified
+// table names. It stops at the first non-comment, non-empty line.
+func parseAllowDestructive(sql string) []string {
+	var tables []string
+	scanner := bufio.NewScanner(strings.NewReader(sql))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if !strings.HasPrefix(line, allowDestructivePrefix) {
+			continue
+		}
+		value := strings.TrimPrefix(line, allowDestructivePrefix)
+		// Strip inline comments: "users -- renamed to accounts" → "users"
+		if idx := strings.Index(value, " "); idx != -1 {
+			value = value[:idx]
+		}
+		for _, table := range strings.Split(value, ",") {
+			if table = strings.TrimSpace(table); table != "" {
+				tables = append(tables, qualifyObjectName(table))
+			}
+		}
+	}
+	return tables
+}
+
+// loadAllowDestructive walks the definition directories and collects every table
+// that a -- scurry:allow-destructive directive permits dropping data from.
+// Dropped tables no longer have a definition file, so a directive in any file counts.
+func loadAllowDestructive(fs afero.Fs, dirPaths []string) (set.Set[string], error) {
+	allowed := set.New[string]()
+	for _, dirPath := range dirPaths {
+		err := afero.Walk(fs, dirPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".sql") {
+				return nil
+			}
+
+			content, err := afero.ReadFile(fs, path)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", path, err)
+			}
+			allowed.Add(parseAllowDestructive(string(content))...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return allowed, nil
+}
+
+// checkDestructivePolicy returns an error listing every difference that drops a
+// table or column, or renames one based on a guess, unless destructive changes
+// are allowed globally or the object is named in an allow-destructive directive.
+// An inferred rename is held to the same rule as the drop it replaces, since a
+// wrong guess changes which data ends up where.
+func checkDestructivePolicy(diffResult *schema.ComparisonResult, allowAll bool, allowed set.Set[string]) error {
+	if allowAll {
+		return nil
+	}
+
+	var blocked []string
+	for _, diff := range diffResult.Differences {
+		if (!diff.DropsData() && !diff.InferredRename) || allowed.Contains(diff.ObjectName) {
+			continue
+		}
+		if diff.InferredRename {
+			blocked = append(blocked, diff.DescriptionWithSource()+" (inferred)")
+			continue
+		}
+		blocked = append(blocked, diff.DescriptionWithSource())
+	}
+	if len(blocked) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("refusing to apply %d destructive change(s):\n  - %s\nThese changes drop tables or columns and their data, or rename them because they look alike. If this is intended, pass --allow-destructive or add\n  %s<table>\nto the top of a definition file. If a table or column was renamed, add\n  -- scurry:renamed-from=<old name>\nabove the table or on the column's line so it is renamed (or confirm an inferred rename).",
+		len(blocked), strings.Join(blocked, "\n  - "), allowDestructivePrefix)
+}
+
+// enforceDestructivePolicy loads allow-destructive directives from the definition
+// directories and checks the differences against them.
+func enforceDestructivePolicy(fs afero.Fs, dirPaths []string, diffResult *schema.ComparisonResult, allowAll bool) error {
+	if allowAll {
+		return nil
+	}
+	allowed, err := loadAllowDestructive(fs, dirPaths)
+	if err != nil {
+		return fmt.Errorf("failed to load allow-destructive directives: %w", err)
+	}
+	return checkDestructivePolicy(diffResult, allowAll, allowed)
+}
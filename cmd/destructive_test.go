@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/set"
+)
+
+func TestParseAllowDestructive(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected []string
+	}{
+		{
+			name:     "no directives",
+			sql:      "CREATE TABLE users (id INT PRIMARY KEY);",
+			expected: nil,
+		},
+		{
+			name:     "single table",
+			sql:      "-- scurry:allow-destructive=posts\nCREATE TABLE users (id INT PRIMARY KEY);",
+			expected: []string{"public.posts"},
+		},
+		{
+			name:     "multiple tables with schema and inline comment",
+			sql:      "-- scurry:allow-destructive=posts,app.Comments -- replaced by threads\nCREATE TABLE users (id INT PRIMARY KEY);",
+			expected: []string{"public.posts", "app.comments"},
+		},
+		{
+			name:     "directive after statement is ignored",
+			sql:      "CREATE TABLE users (id INT PRIMARY KEY);\n-- scurry:allow-destructive=posts",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseAllowDestructive(tt.sql))
+		})
+	}
+}
+
+func TestCheckDestructivePolicy(t *testing.T) {
+	dropTable := schema.Difference{
+		Type:        schema.DiffTypeTableRemoved,
+		ObjectName:  "public.posts",
+		Description: "Table 'public.posts' removed",
+		Phases: []schema.Phase{{Statements: []tree.Statement{
+			&tree.DropTable{Names: tree.TableNames{tree.MakeUnqualifiedTableName("posts")}},
+		}}},
+	}
+	users := tree.MakeUnqualifiedTableName("users")
+	dropColumn := schema.Difference{
+		Type:        schema.DiffTypeTableModified,
+		ObjectName:  "public.users",
+		Description: "Column 'email' removed from table 'public.users'",
+		Phases: []schema.Phase{{Statements: []tree.Statement{
+			&tree.AlterTable{
+				Table: users.ToUnresolvedObjectName(),
+				Cmds:  tree.AlterTableCmds{&tree.AlterTableDropColumn{Column: "email"}},
+			},
+		}}},
+	}
+	addTable := schema.Difference{
+		Type:        schema.DiffTypeTableAdded,
+		ObjectName:  "public.comments",
+		Description: "Table 'public.comments' added",
+	}
+	inferredRename := schema.Difference{
+		Type:           schema.DiffTypeTableRenamed,
+		ObjectName:     "public.accounts",
+		Description:    "Table 'public.users' renamed to 'public.accounts'",
+		InferredRename: true,
+	}
+	declaredRename := schema.Difference{
+		Type:        schema.DiffTypeTableRenamed,
+		ObjectName:  "public.accounts",
+		Description: "Table 'public.users' renamed to 'public.accounts'",
+	}
+
+	tests := []struct {
+		name        string
+		differences []schema.Difference
+		allowAll    bool
+		allowed     []string
+		errContains []string
+	}{
+		{
+			name:        "additive changes pass",
+			differences: []schema.Difference{addTable},
+		},
+		{
+			name:        "drop table refused",
+			differences: []schema.Difference{addTable, dropTable},
+			errContains: []string{"1 destructive change(s)", "Table 'public.posts' removed"},
+		},
+		{
+			name:        "drop column refused",
+			differences: []schema.Difference{dropColumn},
+			errContains: []string{"Column 'email' removed"},
+		},
+		{
+			name:        "all refused changes are reported",
+			differences: []schema.Difference{dropTable, dropColumn},
+			errContains: []string{"2 destructive change(s)"},
+		},
+		{
+			name:        "allowed globally",
+			differences: []schema.Difference{dropTable, dropColumn},
+			allowAll:    true,
+		},
+		{
+			name:        "allowed by directive",
+			differences: []schema.Difference{dropTable},
+			allowed:     []string{"public.posts"},
+		},
+		{
+			name:        "inferred rename refused",
+			differences: []schema.Difference{inferredRename},
+			errContains: []string{"renamed to 'public.accounts' (inferred)"},
+		},
+		{
+			name:        "inferred rename allowed by directive",
+			differences: []schema.Difference{inferredRename},
+			allowed:     []string{"public.accounts"},
+		},
+		{
+			name:        "declared rename passes",
+			differences: []schema.Difference{declaredRename},
+		},
+		{
+			name:        "directive for another table",
+			differences: []schema.Difference{dropTable, dropColumn},
+			allowed:     []string{"public.posts"},
+			errContains: []string{"1 destructive change(s)", "public.users"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &schema.ComparisonResult{Differences: tt.differences}
+			err := checkDestructivePolicy(result, tt.allowAll, set.New(tt.allowed...))
+			if len(tt.errContains) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, s := range tt.errContains {
+				assert.Contains(t, err.Error(), s)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+var (
+	diffAsOf   string
+	diffRevert bool
+	diffOutput string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show the differences between the definitions and a database",
+	Long: `Compare the definition files with the schema of a database, and print the
+differences and the statements that would bring the database in line with the
+definitions. Nothing is changed.
+
+With --as-of, the schema of the database is read as it was at that time, with
+AS OF SYSTEM TIME, e.g. to see what it looked like before an incident. The time
+is relative, like -1h or -30m, or a timestamp, and has to be within the garbage
+collection window of the database (4 hours by default).
+
+With --revert, the current schema of the database is compared with its schema
+at --as-of instead of with the definitions, and the statements bring it back to
+how it was then. Write them to a file with --output to review them as a
+corrective migration.
+
+Examples:
+  scurry diff --db-url="..." --definitions=./definitions
+  scurry diff --db-url="..." --definitions=./definitions --as-of=-1h
+  scurry diff --db-url="..." --as-of='2026-10-16 09:00:00+00' --revert -o revert.sql`,
+	RunE: diff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	flags.AddDbUrl(diffCmd)
+	flags.AddDefinitionDirs(diffCmd)
+	flags.AddEnv(diffCmd)
+	diffCmd.Flags().StringVar(&diffAsOf, "as-of", "", "Read the database's schema as it was at this time, e.g. -1h or a timestamp")
+	diffCmd.Flags().BoolVar(&diffRevert, "revert", false, "Compare the current schema with the one at --as-of instead of the definitions")
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "", "Also write the statements to this file")
+}
+
+func diff(cmd *cobra.Command, args []string) error {
+	if err := validateDiffFlags(flags.DbUrl, flags.DefinitionDirs, diffAsOf, diffRevert); err != nil {
+		return err
+	}
+
+	err := doDiff(cmd.Context(), afero.NewOsFs())
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func validateDiffFlags(dbURL string, definitionDirs []string, asOf string, revert bool) error {
+	if dbURL == "" {
+		return fmt.Errorf("database URL is required (use --db-url or CRDB_URL env var)")
+	}
+	if revert && asOf == "" {
+		return fmt.Errorf("--revert needs --as-of, the time to revert to")
+	}
+	if !revert && len(definitionDirs) == 0 {
+		return fmt.Errorf("definition directory is required (use --definitions)")
+	}
+	return nil
+}
+
+func doDiff(ctx context.Context, fs afero.Fs) error {
+	client, err := db.Connect(ctx, flags.DbUrl)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer client.Close()
+
+	var desired, actual *schema.Schema
+	if diffRevert {
+		desired, err = schema.LoadFromDatabaseAsOf(ctx, client, diffAsOf)
+		if err != nil {
+			return fmt.Errorf("failed to load database schema as of %s: %w", diffAsOf, err)
+		}
+		actual, err = schema.LoadFromDatabase(ctx, client)
+		if err != nil {
+			return fmt.Errorf("failed to load database schema: %w", err)
+		}
+	} else {
+		shadow, err := db.GetShadowDB(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer shadow.Close()
+
+		desired, err = schema.LoadFromDirectoriesForEnv(ctx, fs, flags.DefinitionDirs, flags.Env, shadow)
+		if err != nil {
+			return fmt.Errorf("failed to load local schema: %w", err)
+		}
+		if err := addIgnoredObjects(fs, desired, nil); err != nil {
+			return err
+		}
+		actual, err = schema.LoadFromDatabaseAsOf(ctx, client, diffAsOf)
+		if err != nil {
+			return fmt.Errorf("failed to load database schema: %w", err)
+		}
+	}
+
+	diffResult := schema.Compare(desired, actual)
+	printIgnoredDifferences(diffResult)
+	if !diffResult.HasChanges() {
+		logging.Success("✓ No differences")
+		return nil
+	}
+
+	statements, warnings, err := diffResult.GenerateMigrations(true)
+	if err != nil {
+		return fmt.Errorf("failed to generate migrations: %w", err)
+	}
+
+	logging.Header("\nDifferences found:")
+	logging.Print(diffResult.Summary())
+	logging.Header(fmt.Sprintf("\n%d statement(s):", len(statements)))
+	for _, stmt := range statements {
+		logging.Print(ui.SqlCode(stmt + ";"))
+	}
+	for i, warning := range warnings {
+		logging.Warning(fmt.Sprintf("WARNING: %d. %s", i+1, warning))
+	}
+
+	if diffOutput != "" {
+		content := diffFileContent(statements, diffAsOf, diffRevert)
+		if err := afero.WriteFile(fs, diffOutput, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		logging.Success(fmt.Sprintf("\nStatements written to %s", diffOutput))
+	}
+	return nil
+}
+
+// diffFileContent is the statements written with --output, after a comment
+// saying what they change the database to
+func diffFileContent(statements []string, asOf string, revert bool) string {
+	var comment string
+	switch {
+	case revert:
+		comment = fmt.Sprintf("-- Reverts the schema to how it was as of %s", asOf)
+	case asOf != "":
+		comment = fmt.Sprintf("-- Brings the schema as it was as of %s in line with the definitions", asOf)
+	default:
+		comment = "-- Brings the schema in line with the definitions"
+	}
+	return comment + "\n\n" + strings.Join(statements, ";\n\n") + ";\n"
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDiffFlags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		dbURL          string
+		definitionDirs []string
+		asOf           string
+		revert         bool
+		wantErr        string
+	}{
+		{name: "definitions", dbURL: "postgresql://db", definitionDirs: []string{"defs"}},
+		{name: "definitions as of", dbURL: "postgresql://db", definitionDirs: []string{"defs"}, asOf: "-1h"},
+		{name: "revert needs no definitions", dbURL: "postgresql://db", asOf: "-1h", revert: true},
+		{name: "no database", definitionDirs: []string{"defs"}, wantErr: "database URL is required"},
+		{name: "revert without as-of", dbURL: "postgresql://db", revert: true, wantErr: "--revert needs --as-of"},
+		{name: "no definitions", dbURL: "postgresql://db", wantErr: "definition directory is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateDiffFlags(tt.dbURL, tt.definitionDirs, tt.asOf, tt.revert)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDiffFileContent(t *testing.T) {
+	t.Parallel()
+
+	statements := []string{"ALTER TABLE public.a DROP COLUMN b", "DROP TABLE public.c"}
+	assert.Equal(t,
+		"-- Reverts the schema to how it was as of -1h\n\nALTER TABLE public.a DROP COLUMN b;\n\nDROP TABLE public.c;\n",
+		diffFileContent(statements, "-1h", true))
+	assert.Equal(t,
+		"-- Brings the schema in line with the definitions\n\nDROP TABLE public.c;\n",
+		diffFileContent(statements[1:], "", false))
+}
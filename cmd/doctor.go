@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	runtimedebug "runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+const parserModule = "github.com/cockroachdb/cockroachdb-parser"
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that scurry can work in this environment",
+	Long: `Check the environment scurry runs in and explain how to fix what's wrong:
+
+  definitions       the definition files parse and their references resolve
+  shadow database   a temporary CockroachDB server can be started
+  database          --db-url connects
+  migrations table  _scurry_.migrations exists and has every column this version uses
+  cluster version   the cluster isn't newer than the SQL parser built into scurry
+
+Checks that need --db-url are skipped without one. Exits non-zero if any check fails.
+
+Shell completions are generated with 'scurry completion <shell>'.`,
+	RunE: doctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	flags.AddDbUrl(doctorCmd)
+	flags.AddDefinitionDirs(doctorCmd)
+	flags.AddEnv(doctorCmd)
+}
+
+// Outcomes of a doctor check
+const (
+	doctorOK      = "ok"
+	doctorWarn    = "warn"
+	doctorFail    = "fail"
+	doctorSkipped = "skipped"
+)
+
+// doctorCheck is the outcome of one environment check, with what to do about
+// it when it didn't pass
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Remedy string
+}
+
+func doctor(cmd *cobra.Command, args []string) error {
+	checks := runDoctorChecks(cmd.Context(), afero.NewOsFs(), flags.DbUrl, flags.DefinitionDirs, flags.Env)
+
+	failed := 0
+	for _, check := range checks {
+		printDoctorCheck(check)
+		if check.Status == doctorFail {
+			failed++
+		}
+	}
+
+	logging.Newline()
+	if failed > 0 {
+		logging.Error(fmt.Sprintf("✗ %d check(s) failed", failed))
+		os.Exit(1)
+	}
+	logging.Success("✓ scurry is ready to go")
+	return nil
+}
+
+func printDoctorCheck(check doctorCheck) {
+	line := fmt.Sprintf("%s: %s", check.Name, check.Detail)
+	switch check.Status {
+	case doctorOK:
+		logging.Success("✓ " + line)
+	case doctorWarn:
+		logging.Warning("⚠ " + line)
+	case doctorFail:
+		logging.Error("✗ " + line)
+	default:
+		logging.Subtle("- " + line)
+	}
+	if check.Remedy != "" && check.Status != doctorOK {
+		logging.Subtle("  → " + check.Remedy)
+	}
+}
+
+// runDoctorChecks runs every check in turn. The database checks share one
+// connection and are skipped when there's no URL or it doesn't connect.
+func runDoctorChecks(ctx context.Context, fs afero.Fs, dbURL string, definitionDirs []string, env string) []doctorCheck {
+	checks := []doctorCheck{
+		checkDefinitions(fs, definitionDirs, env),
+		checkShadowDatabase(ctx),
+	}
+
+	if dbURL == "" {
+		remedy := "Pass --db-url or set CRDB_URL to check the database."
+		return append(checks,
+			doctorCheck{Name: "database", Status: doctorSkipped, Detail: "no database URL", Remedy: remedy},
+			doctorCheck{Name: "migrations table", Status: doctorSkipped, Detail: "no database URL", Remedy: remedy},
+			doctorCheck{Name: "cluster version", Status: doctorSkipped, Detail: "no database URL", Remedy: remedy},
+		)
+	}
+
+	client, err := db.Connect(ctx, dbURL)
+	if err != nil {
+		detail := "not checked, the database didn't connect"
+		return append(checks,
+			doctorCheck{Name: "database", Status: doctorFail, Detail: err.Error(), Remedy: "Check the host, port, credentials and sslmode in --db-url, and that the cluster is running."},
+			doctorCheck{Name: "migrations table", Status: doctorSkipped, Detail: detail},
+			doctorCheck{Name: "cluster version", Status: doctorSkipped, Detail: detail},
+		)
+	}
+	defer client.Close()
+
+	checks = append(checks, doctorCheck{Name: "database", Status: doctorOK, Detail: "connected to " + databaseName(dbURL)})
+	checks = append(checks, checkMigrationsTable(ctx, client))
+
+	version, err := client.GetServerVersion(ctx)
+	if err != nil {
+		return append(checks, doctorCheck{Name: "cluster version", Status: doctorFail, Detail: err.Error()})
+	}
+	return append(checks, checkClusterVersion(parserVersion(), version))
+}
+
+// checkDefinitions parses the definition files without a database and checks
+// that their references resolve
+func checkDefinitions(fs afero.Fs, definitionDirs []string, env string) doctorCheck {
+	check := doctorCheck{Name: "definitions"}
+	localSchema, err := schema.LoadDefinitions(fs, definitionDirs, env)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Remedy = "Fix the statement in the file named above, then run 'scurry validate' to check it against a shadow database."
+		return check
+	}
+
+	check.Detail = fmt.Sprintf("%d tables, %d types, %d routines, %d sequences, %d views in %s",
+		len(localSchema.Tables), len(localSchema.Types), len(localSchema.Routines), len(localSchema.Sequences), len(localSchema.Views),
+		strings.Join(definitionDirs, ", "))
+	if issues := schema.CheckReferences(localSchema); len(issues) > 0 {
+		check.Status = doctorWarn
+		check.Detail += fmt.Sprintf(", with %d unresolved reference(s)", len(issues))
+		check.Remedy = "Run 'scurry validate --offline' to list them."
+		return check
+	}
+	check.Status = doctorOK
+	return check
+}
+
+// checkShadowDatabase starts the temporary server other commands load the
+// definitions into
+func checkShadowDatabase(ctx context.Context) doctorCheck {
+	check := doctorCheck{Name: "shadow database"}
+	client, err := db.GetShadowDB(ctx)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Remedy = "Put a cockroach binary on PATH, or allow scurry to download one; --crdb-version (or CRDB_VERSION) picks the version."
+		return check
+	}
+	defer client.Close()
+
+	check.Status = doctorOK
+	check.Detail = "started"
+	if version, err := client.GetServerVersion(ctx); err == nil {
+		if v, ok := parseClusterVersion(version); ok {
+			check.Detail = "started " + v
+		}
+	}
+	return check
+}
+
+// checkMigrationsTable checks that the migration history table exists and has
+// the columns this version of scurry records
+func checkMigrationsTable(ctx context.Context, client *db.Client) doctorCheck {
+	check := doctorCheck{Name: "migrations table"}
+	exists, err := client.MigrationsTableExists(ctx)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		return check
+	}
+	if !exists {
+		check.Status = doctorWarn
+		check.Detail = "_scurry_.migrations doesn't exist yet"
+		check.Remedy = "It's created by the first 'scurry migration execute', or 'scurry migration baseline' for an existing database."
+		return check
+	}
+
+	changes, err := client.PendingMigrationsTableChanges(ctx)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		return check
+	}
+	if len(changes) > 0 {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("_scurry_.migrations is missing %d column(s) this version uses", len(changes))
+		check.Remedy = "The next 'scurry migration execute' adds them; make sure every process writing migrations has been upgraded."
+		return check
+	}
+	check.Status = doctorOK
+	check.Detail = "_scurry_.migrations is current"
+	return check
+}
+
+// checkClusterVersion warns when the cluster is a newer release than the parser
+// scurry reads its schema with, which may not understand everything it prints
+func checkClusterVersion(parser, serverVersion string) doctorCheck {
+	check := doctorCheck{Name: "cluster version"}
+	cluster, ok := parseClusterVersion(serverVersion)
+	if !ok {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("couldn't read a version from %q", serverVersion)
+		return check
+	}
+
+	clusterMajor, clusterMinor, _ := majorMinor(cluster)
+	parserMajor, parserMinor, ok := parserRelease(parser)
+	if !ok {
+		check.Status = doctorOK
+		check.Detail = fmt.Sprintf("cluster %s, parser version unknown", cluster)
+		return check
+	}
+
+	check.Detail = fmt.Sprintf("cluster %s, parser v%d.%d", cluster, parserMajor, parserMinor)
+	if clusterMajor > parserMajor || (clusterMajor == parserMajor && clusterMinor > parserMinor) {
+		check.Status = doctorWarn
+		check.Remedy = "The cluster is newer than scurry's parser, so schema it prints with new syntax may not load. Upgrade scurry."
+		return check
+	}
+	check.Status = doctorOK
+	return check
+}
+
+var clusterVersionPattern = regexp.MustCompile(`v\d+\.\d+(\.\d+)?`)
+
+// parseClusterVersion finds the release in a version() string, e.g. "v24.1.0"
+func parseClusterVersion(serverVersion string) (string, bool) {
+	v := clusterVersionPattern.FindString(serverVersion)
+	return v, v != ""
+}
+
+// majorMinor splits the major and minor release out of a version like "v24.1.0"
+func majorMinor(version string) (int, int, bool) {
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// parserRelease returns the CockroachDB release a parser module version was
+// extracted from: v0.25.2 tracks CockroachDB v25.2
+func parserRelease(parser string) (int, int, bool) {
+	parts := strings.Split(strings.TrimPrefix(parser, "v"), ".")
+	if len(parts) < 3 || parts[0] != "0" {
+		return 0, 0, false
+	}
+	return majorMinor(parts[1] + "." + strings.SplitN(parts[2], "-", 2)[0])
+}
+
+// parserVersion returns the version of the parser module scurry was built
+// with, or "" if the build info doesn't say
+func parserVersion() string {
+	info, ok := runtimedebug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == parserModule {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return ""
+}
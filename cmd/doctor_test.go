@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDefinitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		wantStatus string
+	}{
+		{
+			name:       "valid definitions",
+			files:      map[string]string{"defs/users.sql": "CREATE TABLE users (id INT PRIMARY KEY);"},
+			wantStatus: doctorOK,
+		},
+		{
+			name:       "syntax error",
+			files:      map[string]string{"defs/users.sql": "CREATE TABLE users (id INT PRIMARY KEY"},
+			wantStatus: doctorFail,
+		},
+		{
+			name:       "unresolved reference",
+			files:      map[string]string{"defs/posts.sql": "CREATE TABLE posts (id INT PRIMARY KEY, author_id INT REFERENCES users (id));"},
+			wantStatus: doctorWarn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, content := range tt.files {
+				require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+			}
+
+			check := checkDefinitions(fs, []string{"defs"}, "")
+			assert.Equal(t, tt.wantStatus, check.Status, check.Detail)
+			if tt.wantStatus != doctorOK {
+				assert.NotEmpty(t, check.Remedy)
+			}
+		})
+	}
+}
+
+func TestCheckClusterVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		parser     string
+		server     string
+		wantStatus string
+		wantDetail string
+	}{
+		{
+			name:       "same release",
+			parser:     "v0.25.2",
+			server:     "CockroachDB CCL v25.2.1 (x86_64-pc-linux-gnu, built 2025/06/01 00:00:00, go1.23.7)",
+			wantStatus: doctorOK,
+			wantDetail: "cluster v25.2.1, parser v25.2",
+		},
+		{
+			name:       "older cluster",
+			parser:     "v0.25.2",
+			server:     "CockroachDB CCL v24.1.0 (x86_64-pc-linux-gnu)",
+			wantStatus: doctorOK,
+		},
+		{
+			name:       "newer cluster",
+			parser:     "v0.25.2",
+			server:     "CockroachDB CCL v25.3.0 (x86_64-pc-linux-gnu)",
+			wantStatus: doctorWarn,
+		},
+		{
+			name:       "newer major release",
+			parser:     "v0.25.2",
+			server:     "CockroachDB CCL v26.1.0 (x86_64-pc-linux-gnu)",
+			wantStatus: doctorWarn,
+		},
+		{
+			name:       "unknown parser version",
+			parser:     "",
+			server:     "CockroachDB CCL v26.1.0 (x86_64-pc-linux-gnu)",
+			wantStatus: doctorOK,
+			wantDetail: "cluster v26.1.0, parser version unknown",
+		},
+		{
+			name:       "unreadable server version",
+			parser:     "v0.25.2",
+			server:     "PostgreSQL",
+			wantStatus: doctorWarn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := checkClusterVersion(tt.parser, tt.server)
+			assert.Equal(t, tt.wantStatus, check.Status)
+			if tt.wantDetail != "" {
+				assert.Equal(t, tt.wantDetail, check.Detail)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	"github.com/pjtatlow/scurry/internal/schema"
 	"github.com/pjtatlow/scurry/internal/ui"
 )
@@ -46,7 +47,7 @@ func dump(cmd *cobra.Command, args []string) error {
 	// Execution errors print and exit
 	err := doDump(ctx, outputFile)
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -68,15 +69,13 @@ func doDump(ctx context.Context, outputFile string) error {
 			return fmt.Errorf("confirmation prompt failed: %w", err)
 		}
 		if !confirmed {
-			fmt.Println(ui.Subtle("Dump canceled."))
+			logging.Subtle("Dump canceled.")
 			return nil
 		}
 	}
 
 	// Connect to database
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Connecting to database..."))
-	}
+	logging.Debug("→ Connecting to database...")
 
 	client, err := db.Connect(ctx, flags.DbUrl)
 	if err != nil {
@@ -85,9 +84,7 @@ func doDump(ctx context.Context, outputFile string) error {
 	defer client.Close()
 
 	// Load schema from database
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Loading database schema..."))
-	}
+	logging.Debug("→ Loading database schema...")
 
 	dbSchema, err := schema.LoadFromDatabase(ctx, client)
 	if err != nil {
@@ -95,14 +92,12 @@ func doDump(ctx context.Context, outputFile string) error {
 	}
 
 	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views in database",
-			len(dbSchema.Tables), len(dbSchema.Types), len(dbSchema.Routines), len(dbSchema.Sequences), len(dbSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views in database",
+			len(dbSchema.Tables), len(dbSchema.Types), len(dbSchema.Routines), len(dbSchema.Sequences), len(dbSchema.Views)))
 	}
 
 	// Generate CREATE statements
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Generating CREATE statements..."))
-	}
+	logging.Debug("→ Generating CREATE statements...")
 
 	statements, _, err := schema.Compare(dbSchema, schema.NewSchema()).GenerateMigrations(true)
 	if err != nil {
@@ -117,10 +112,10 @@ func doDump(ctx context.Context, outputFile string) error {
 	}
 
 	if flags.Verbose {
-		fmt.Println()
-		fmt.Println(ui.Success(fmt.Sprintf("✓ Successfully dumped schema to %s", outputFile)))
+		logging.Newline()
+		logging.Success(fmt.Sprintf("✓ Successfully dumped schema to %s", outputFile))
 	} else {
-		fmt.Println(ui.Success(fmt.Sprintf("Schema dumped to %s", outputFile)))
+		logging.Success(fmt.Sprintf("Schema dumped to %s", outputFile))
 	}
 
 	return nil
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/config"
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+// protectedFlags are refused in protected environments, since they skip the
+// confirmation prompt
+var protectedFlags = []string{"force", "watch"}
+
+// activeEnvironment is the --env connection profile from the config file, or
+// nil if there isn't one
+var activeEnvironment *config.Environment
+
+// applyEnvironment applies the --env connection profile from the config file:
+// its connection URL unless --db-url was passed, and its default flag values.
+// Without a profile, --env only selects definition overlays.
+func applyEnvironment(fs afero.Fs, cmd *cobra.Command) error {
+	activeEnvironment = nil
+	if flags.Env == "" {
+		return nil
+	}
+
+	cfg, err := config.Load(fs, flags.ConfigFile)
+	if err != nil {
+		return err
+	}
+	env, ok := cfg.Environments[flags.Env]
+	if !ok {
+		return nil
+	}
+	activeEnvironment = &env
+
+	for name, value := range env.Flags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid environments.%s.flags.%s: %w", flags.Env, name, err)
+		}
+	}
+
+	if env.ReadOnly {
+		if flag := cmd.Flags().Lookup("read-only"); flag == nil || !flag.Changed {
+			db.ConnectionOptions.ReadOnly = true
+		}
+	}
+
+	if env.Protected {
+		for _, name := range protectedFlags {
+			if flag := cmd.Flags().Lookup(name); flag != nil && flag.Value.String() == "true" {
+				return fmt.Errorf("--%s is not allowed in the protected environment %s", name, flags.Env)
+			}
+		}
+	}
+
+	if cmd.Flags().Lookup("db-url") != nil && !cmd.Flags().Changed("db-url") {
+		connectionURL, err := env.ConnectionURL()
+		if err != nil {
+			return fmt.Errorf("environment %s: %w", flags.Env, err)
+		}
+		if connectionURL != "" {
+			flags.DbUrl = connectionURL
+		}
+	}
+	return nil
+}
+
+// inProtectedEnvironment reports whether --env selected a protected profile
+func inProtectedEnvironment() bool {
+	return activeEnvironment != nil && activeEnvironment.Protected
+}
+
+// confirmChange asks question before changing the database. In a protected
+// environment, it first shows which cluster the database is in.
+func confirmChange(ctx context.Context, dbClient *db.Client, question string) (bool, error) {
+	if !inProtectedEnvironment() {
+		return ui.ConfirmPrompt(question)
+	}
+
+	target := describeConnection(flags.DbUrl)
+	if clusterID, err := dbClient.GetClusterID(ctx); err == nil {
+		target += ", cluster " + clusterID
+	} else {
+		logging.Debug(fmt.Sprintf("  %v", err))
+	}
+
+	logging.Newline()
+	logging.Warning(fmt.Sprintf("⚠ Protected environment %s: %s", flags.Env, target))
+	return ui.ConfirmPrompt(fmt.Sprintf("%s (%s)", question, flags.Env))
+}
+
+// describeConnection returns the host and database of a connection URL,
+// without its credentials
+func describeConnection(connectionURL string) string {
+	u, err := url.Parse(connectionURL)
+	if err != nil || u.Host == "" {
+		return "unknown database"
+	}
+	return u.Host + u.Path
+}
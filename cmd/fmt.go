@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Short: "Format schema definition files",
+	Long: `Rewrite every .sql file in the definition directories with the CockroachDB
+pretty-printer: upper case keywords, one column or constraint per line, and
+constraints in a stable order.
+
+Comments between statements are kept. Statements with comments inside them,
+such as -- scurry: column directives, are left as written.
+
+Use --check in CI to fail when any file is not formatted.`,
+	RunE: fmtDefinitions,
+}
+
+var fmtCheck bool
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+
+	flags.AddDefinitionDirs(fmtCmd)
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "List files that are not formatted and exit with an error instead of rewriting them")
+}
+
+func fmtDefinitions(cmd *cobra.Command, args []string) error {
+	if len(flags.DefinitionDirs) == 0 {
+		return fmt.Errorf("definition directory is required (use --definitions)")
+	}
+
+	err := doFmt(afero.NewOsFs(), flags.DefinitionDirs, fmtCheck)
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// doFmt formats the definition files in dirPaths, or with check only reports
+// the files that would change
+func doFmt(fs afero.Fs, dirPaths []string, check bool) error {
+	var changed []string
+	for _, dirPath := range dirPaths {
+		err := afero.Walk(fs, dirPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".sql") {
+				return nil
+			}
+			// Formatting would rewrite ALTER DATABASE ... SET as ALTER ROLE ALL IN DATABASE
+			if path == filepath.Join(dirPath, schema.DatabaseFile) {
+				return nil
+			}
+
+			content, err := afero.ReadFile(fs, path)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", path, err)
+			}
+			formatted, err := schema.FormatDefinitionFile(string(content))
+			if err != nil {
+				return fmt.Errorf("in file %s: %w", path, err)
+			}
+			if formatted == string(content) {
+				return nil
+			}
+
+			changed = append(changed, path)
+			if check {
+				return nil
+			}
+			if err := afero.WriteFile(fs, path, []byte(formatted), info.Mode()); err != nil {
+				return fmt.Errorf("failed to write file %s: %w", path, err)
+			}
+			logging.Debug(fmt.Sprintf("  formatted %s", path))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if check {
+		if len(changed) > 0 {
+			return fmt.Errorf("%d file(s) are not formatted, run scurry fmt:\n  %s", len(changed), strings.Join(changed, "\n  "))
+		}
+		logging.Success("✓ All definition files are formatted")
+		return nil
+	}
+
+	logging.Success(fmt.Sprintf("✓ Formatted %d file(s)", len(changed)))
+	return nil
+}
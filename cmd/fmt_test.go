@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoFmt(t *testing.T) {
+	const unformatted = "create type status as enum ('a')"
+	const formatted = "CREATE TYPE status AS ENUM ('a');\n"
+
+	tests := []struct {
+		name        string
+		check       bool
+		content     string
+		expected    string
+		errContains string
+	}{
+		{
+			name:     "rewrites unformatted files",
+			content:  unformatted,
+			expected: formatted,
+		},
+		{
+			name:        "check reports unformatted files",
+			check:       true,
+			content:     unformatted,
+			expected:    unformatted,
+			errContains: "1 file(s) are not formatted",
+		},
+		{
+			name:     "check passes formatted files",
+			check:    true,
+			content:  formatted,
+			expected: formatted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "defs/types.sql", []byte(tt.content), 0644))
+
+			err := doFmt(fs, []string{"defs"}, tt.check)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+
+			content, err := afero.ReadFile(fs, "defs/types.sql")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/pjtatlow/scurry/internal/flags"
 	"github.com/pjtatlow/scurry/internal/generate"
+	"github.com/pjtatlow/scurry/internal/logging"
 	"github.com/pjtatlow/scurry/internal/schema"
 )
 
@@ -57,11 +58,11 @@ func generateEnums(cmd *cobra.Command, args []string) error {
 	fs := afero.NewOsFs()
 	count, err := doGenerateEnums(fs, flags.DefinitionDirs, outputDir, lang)
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
-	fmt.Printf("Generated %d enum file(s) in %s\n", count, outputDir)
+	logging.Print(fmt.Sprintf("Generated %d enum file(s) in %s", count, outputDir))
 	return nil
 }
 
@@ -74,9 +75,12 @@ func doGenerateEnums(fs afero.Fs, definitionDirs []string, outDir, lang string)
 				return err
 			}
 			if info.IsDir() {
+				if path == filepath.Join(definitionDir, schema.SeedDir) || path == filepath.Join(definitionDir, schema.TestsDir) {
+					return filepath.SkipDir
+				}
 				return nil
 			}
-			if !strings.HasSuffix(strings.ToLower(path), ".sql") {
+			if !strings.HasSuffix(strings.ToLower(path), ".sql") || path == filepath.Join(definitionDir, schema.DatabaseFile) || path == filepath.Join(definitionDir, schema.ClusterSettingsFile) {
 				return nil
 			}
 
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/generate"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+var (
+	generateModelsOutput  string
+	generateModelsPackage string
+)
+
+var generateModelsCmd = &cobra.Command{
+	Use:   "models <language>",
+	Short: "Generate types for each table from the schema definitions",
+	Long: `Generate a type for every table in your schema definition files, so
+application models stay in sync with the schema.
+
+Supported languages:
+  go  Structs with db tags in models.go. Nullable columns are pointers, and
+      enum types are string types with a constant for each value.
+  ts  Interfaces in models.ts. Nullable columns are "T | null", and enum types
+      are unions of their values.
+
+Tables outside the public schema are prefixed with their schema name, e.g.
+billing.invoices becomes BillingInvoices.
+
+Example:
+  scurry generate models go --definitions ./definitions --output ./internal/models --package models`,
+	Args: cobra.ExactArgs(1),
+	RunE: generateModels,
+}
+
+func init() {
+	generateCmd.AddCommand(generateModelsCmd)
+
+	flags.AddDefinitionDirs(generateModelsCmd)
+	flags.AddEnv(generateModelsCmd)
+	generateModelsCmd.Flags().StringVar(&generateModelsOutput, "output", "", "Output directory for the generated file")
+	generateModelsCmd.Flags().StringVar(&generateModelsPackage, "package", "models", "Package name of the generated Go file")
+	generateModelsCmd.MarkFlagRequired("output")
+}
+
+func generateModels(cmd *cobra.Command, args []string) error {
+	newEmitter, ok := generate.Emitters[args[0]]
+	if !ok {
+		return fmt.Errorf("unsupported language %q (supported: %s)", args[0], strings.Join(generate.EmitterNames(), ", "))
+	}
+	if len(flags.DefinitionDirs) == 0 {
+		return fmt.Errorf("definition directory is required (use --definitions)")
+	}
+
+	emitter := newEmitter(generate.EmitterOptions{GoPackage: generateModelsPackage})
+	path, err := doGenerateModels(afero.NewOsFs(), flags.DefinitionDirs, flags.Env, generateModelsOutput, emitter)
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	logging.Print(fmt.Sprintf("Generated %s", path))
+	return nil
+}
+
+// doGenerateModels writes the emitter's file for the definitions to outDir,
+// returning its path
+func doGenerateModels(fs afero.Fs, definitionDirs []string, env, outDir string, emitter generate.Emitter) (string, error) {
+	s, err := schema.LoadDefinitions(fs, definitionDirs, env)
+	if err != nil {
+		return "", err
+	}
+	models, enums := generate.BuildModels(s)
+
+	if err := fs.MkdirAll(outDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	path := filepath.Join(outDir, emitter.FileName())
+	if err := afero.WriteFile(fs, path, []byte(emitter.Emit(models, enums)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Export the dependency graph of the local schema",
+	Long: `Print the dependencies between the objects in the definition files: the
+types and sequences tables use, foreign keys, the tables views read from, and
+the tables and functions of triggers. These are the same dependencies push and
+migration gen use to order statements.
+
+--format=dot writes Graphviz DOT, for example:
+
+  scurry graph --format=dot | dot -Tsvg > schema.svg
+
+--format=json writes the nodes, edges and any cycles as JSON.
+
+The graph is printed either way, but the command fails when objects depend on
+each other in a cycle, since they can't be created in any order.`,
+	RunE: graph,
+}
+
+var graphFormat string
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	flags.AddDefinitionDirs(graphCmd)
+	flags.AddEnv(graphCmd)
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format (dot or json)")
+	_ = graphCmd.RegisterFlagCompletionFunc("format", flags.Choices("dot", "json"))
+}
+
+func graph(cmd *cobra.Command, args []string) error {
+	if len(flags.DefinitionDirs) == 0 {
+		return fmt.Errorf("definition directory is required (use --definitions)")
+	}
+	if graphFormat != "dot" && graphFormat != "json" {
+		return fmt.Errorf("unknown format %q (use dot or json)", graphFormat)
+	}
+
+	err := doGraph(afero.NewOsFs(), os.Stdout, flags.DefinitionDirs, flags.Env, graphFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// doGraph writes the dependency graph of the definitions to w
+func doGraph(fs afero.Fs, w io.Writer, dirPaths []string, env, format string) error {
+	localSchema, err := schema.LoadDefinitions(fs, dirPaths, env)
+	if err != nil {
+		return fmt.Errorf("failed to load local schema: %w", err)
+	}
+
+	g := schema.BuildDependencyGraph(localSchema)
+	cycles := g.Cycles()
+
+	switch format {
+	case "json":
+		output := struct {
+			*schema.DependencyGraph
+			Cycles [][]string `json:"cycles"`
+		}{g, cycles}
+		if output.Cycles == nil {
+			output.Cycles = [][]string{}
+		}
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode graph: %w", err)
+		}
+		if _, err := fmt.Fprintln(w, string(data)); err != nil {
+			return err
+		}
+	default:
+		if _, err := io.WriteString(w, g.DOT()); err != nil {
+			return err
+		}
+	}
+
+	if len(cycles) > 0 {
+		for _, cycle := range cycles {
+			fmt.Fprintln(os.Stderr, ui.Error(fmt.Sprintf("✗ circular dependency: %s", strings.Join(cycle, ", "))))
+		}
+		return fmt.Errorf("found %d dependency cycle(s)", len(cycles))
+	}
+	return nil
+}
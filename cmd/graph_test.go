@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoGraph(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		edges       int
+		cycles      [][]string
+		errContains string
+	}{
+		{
+			name: "foreign keys",
+			content: `CREATE TABLE users (id INT8 PRIMARY KEY);
+CREATE TABLE orders (id INT8 PRIMARY KEY, user_id INT8 REFERENCES users (id));`,
+			edges:  1,
+			cycles: [][]string{},
+		},
+		{
+			name: "cycles are reported",
+			content: `CREATE TABLE a (id INT8 PRIMARY KEY, b_id INT8 REFERENCES b (id));
+CREATE TABLE b (id INT8 PRIMARY KEY, a_id INT8 REFERENCES a (id));`,
+			edges:       2,
+			cycles:      [][]string{{"public.a", "public.b"}},
+			errContains: "found 1 dependency cycle(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "defs/tables.sql", []byte(tt.content), 0644))
+
+			var out bytes.Buffer
+			err := doGraph(fs, &out, []string{"defs"}, "", "json")
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var result struct {
+				Edges  []map[string]string `json:"edges"`
+				Cycles [][]string          `json:"cycles"`
+			}
+			require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+			assert.Len(t, result.Edges, tt.edges)
+			assert.Equal(t, tt.cycles, result.Cycles)
+		})
+	}
+}
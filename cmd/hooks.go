@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/config"
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+)
+
+// loadHooks loads the hooks from the config file
+func loadHooks(fs afero.Fs) (config.Hooks, error) {
+	cfg, err := config.Load(fs, flags.ConfigFile)
+	if err != nil {
+		return config.Hooks{}, err
+	}
+	return cfg.Hooks, nil
+}
+
+// hookRunner runs the hooks configured for a command. SQL hooks all run on one
+// connection, so session settings made by one hook (like SET statement_timeout)
+// still apply in the hooks after it.
+type hookRunner struct {
+	hooks  config.Hooks
+	client *db.Client
+	conn   *sql.Conn
+}
+
+// newHookRunner returns a runner for hooks. SQL hooks fail until a database
+// client is set with setClient; command hooks run either way.
+func newHookRunner(hooks config.Hooks) *hookRunner {
+	return &hookRunner{hooks: hooks}
+}
+
+func (r *hookRunner) setClient(client *db.Client) {
+	r.client = client
+}
+
+// exec runs a SQL hook on the runner's connection, taking one from the client
+// the first time
+func (r *hookRunner) exec(ctx context.Context, query string) error {
+	if r.conn == nil {
+		if r.client == nil {
+			return fmt.Errorf("no database connection for SQL hook")
+		}
+		conn, err := r.client.GetDB().Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get a connection for SQL hooks: %w", err)
+		}
+		r.conn = conn
+	}
+	_, err := r.conn.ExecContext(ctx, query)
+	return err
+}
+
+// run runs the hooks for an event in order, stopping at the first one that
+// fails. Commands run with sh -c and get SCURRY_HOOK set to the event, plus any
+// extra env.
+func (r *hookRunner) run(ctx context.Context, event string, env map[string]string) error {
+	for i, hook := range r.hooks.For(event) {
+		if hook.SQL != "" {
+			logging.Debug(fmt.Sprintf("→ Running %s hook: %s", event, hook.SQL))
+			if err := r.exec(ctx, hook.SQL); err != nil {
+				return fmt.Errorf("%s hook %d failed: %w", event, i+1, err)
+			}
+			continue
+		}
+
+		logging.Debug(fmt.Sprintf("→ Running %s hook: %s", event, hook.Command))
+		cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), "SCURRY_HOOK="+event)
+		for k, v := range env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %d failed: %w", event, i+1, err)
+		}
+	}
+	return nil
+}
+
+// runFailure runs the on_failure hooks for an error from command. Hook errors
+// are only printed, so the original error is what gets reported.
+func (r *hookRunner) runFailure(ctx context.Context, command string, cause error) {
+	// The original context may be why the command failed
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	err := r.run(ctx, config.EventOnFailure, map[string]string{
+		"SCURRY_COMMAND": command,
+		"SCURRY_ERROR":   cause.Error(),
+	})
+	if err != nil {
+		logging.Warning(fmt.Sprintf("⚠ %s", err))
+	}
+}
+
+// close returns the SQL hooks' connection to the pool
+func (r *hookRunner) close() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/config"
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestRunHooks_Commands(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "out.txt")
+
+	hooks := config.Hooks{
+		BeforePush: []config.Hook{
+			{Command: `echo "first $SCURRY_HOOK $SCURRY_COMMAND" >> ` + out},
+			{Command: `echo second >> ` + out},
+		},
+	}
+	runner := newHookRunner(hooks)
+	require.NoError(t, runner.run(ctx, config.EventBeforePush, map[string]string{"SCURRY_COMMAND": "push"}))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "first before_push push\nsecond\n", string(data))
+
+	// Nothing configured for the event
+	require.NoError(t, runner.run(ctx, config.EventAfterPush, nil))
+}
+
+func TestRunHooks_StopsAtFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "out.txt")
+
+	hooks := config.Hooks{
+		AfterMigrationExecute: []config.Hook{
+			{Command: "exit 3"},
+			{Command: "echo never > " + out},
+		},
+	}
+	err := newHookRunner(hooks).run(ctx, config.EventAfterMigrationExecute, nil)
+	require.ErrorContains(t, err, "after_migration_execute hook 1 failed")
+	assert.NoFileExists(t, out)
+}
+
+func TestRunFailureHooks(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "out.txt")
+
+	hooks := config.Hooks{
+		OnFailure: []config.Hook{
+			{Command: `echo "$SCURRY_COMMAND: $SCURRY_ERROR" > ` + out},
+			// Failures in on_failure hooks are only printed
+			{Command: "exit 1"},
+		},
+	}
+	newHookRunner(hooks).runFailure(ctx, "push", errors.New("boom"))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "push: boom\n", string(data))
+}
+
+func TestRunHooks_SQL(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Session settings carry over from one SQL hook to the next
+	hooks := config.Hooks{
+		BeforeMigrationExecute: []config.Hook{
+			{SQL: "CREATE SCHEMA hook_schema"},
+			{SQL: "SET search_path = hook_schema"},
+		},
+		AfterMigrationExecute: []config.Hook{
+			{SQL: "CREATE TABLE hook_audit (id INT PRIMARY KEY)"},
+		},
+	}
+	runner := newHookRunner(hooks)
+	defer runner.close()
+	runner.setClient(client)
+	require.NoError(t, runner.run(ctx, config.EventBeforeMigrationExecute, nil))
+	require.NoError(t, runner.run(ctx, config.EventAfterMigrationExecute, nil))
+
+	var schemaName string
+	err = client.GetDB().QueryRowContext(ctx, `SELECT table_schema FROM information_schema.tables WHERE table_name = 'hook_audit'`).Scan(&schemaName)
+	require.NoError(t, err)
+	assert.Equal(t, "hook_schema", schemaName)
+}
+
+func TestRunHooks_SQLWithoutClient(t *testing.T) {
+	t.Parallel()
+
+	hooks := config.Hooks{OnFailure: []config.Hook{{SQL: "SELECT 1"}}}
+	err := newHookRunner(hooks).run(context.Background(), config.EventOnFailure, nil)
+	require.ErrorContains(t, err, "no database connection")
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/config"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// addIgnoredObjects adds the objects in ignore_diff in the config file and in
+// --skip to the ones the local schema's -- scurry:ignore-diff directives
+// declare, so comparing it reports their differences as ignored
+func addIgnoredObjects(fs afero.Fs, localSchema *schema.Schema, skip []string) error {
+	cfg, err := config.Load(fs, flags.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if len(cfg.IgnoreDiff) == 0 && len(skip) == 0 {
+		return nil
+	}
+
+	if localSchema.IgnoreDiff == nil {
+		localSchema.IgnoreDiff = make(schema.IgnoredObjects)
+	}
+	for _, name := range cfg.IgnoreDiff {
+		localSchema.IgnoreDiff.Add(qualifyObjectName(name), fmt.Sprintf("ignore_diff in %s", flags.ConfigFile))
+	}
+	for _, name := range skip {
+		localSchema.IgnoreDiff.Add(qualifyObjectName(name), "--skip")
+	}
+	return nil
+}
+
+// printIgnoredDifferences lists the differences to ignored objects, if any
+func printIgnoredDifferences(diff *schema.ComparisonResult) {
+	if summary := diff.IgnoredSummary(); summary != "" {
+		logging.Newline()
+		logging.Subtle(summary)
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/config"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestAddIgnoredObjects(t *testing.T) {
+	oldConfigFile := flags.ConfigFile
+	defer func() { flags.ConfigFile = oldConfigFile }()
+	flags.ConfigFile = config.DefaultFileName
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, config.DefaultFileName, []byte("ignore_diff:\n  - legacy_users\n  - schema:analytics\n"), 0644))
+
+	localSchema := schema.NewSchema()
+	localSchema.IgnoreDiff = schema.IgnoredObjects{"public.sessions": "being rebuilt"}
+	require.NoError(t, addIgnoredObjects(fs, localSchema, []string{"App.Orders"}))
+
+	assert.Equal(t, schema.IgnoredObjects{
+		"public.sessions":     "being rebuilt",
+		"public.legacy_users": "ignore_diff in .scurry.yaml",
+		"schema:analytics":    "ignore_diff in .scurry.yaml",
+		"app.orders":          "--skip",
+	}, localSchema.IgnoreDiff)
+}
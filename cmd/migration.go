@@ -26,6 +26,8 @@ or creating new migrations manually.`,
 func init() {
 	rootCmd.AddCommand(migrationCmd)
 	flags.AddMigrationDir(rootCmd)
+	flags.AddChecksumMode(migrationCmd)
+	flags.AddCheckpointCache(migrationCmd)
 }
 
 // Helper function to validate migrations directory structure
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestMigrationsThrough(t *testing.T) {
+	migrations := []db.Migration{{Name: "001_a"}, {Name: "002_b"}, {Name: "003_c"}}
+
+	got, err := migrationsThrough(migrations, "002_b")
+	require.NoError(t, err)
+	assert.Equal(t, migrations[:2], got)
+
+	got, err = migrationsThrough(migrations, "003_c")
+	require.NoError(t, err)
+	assert.Equal(t, migrations, got)
+
+	_, err = migrationsThrough(migrations, "004_missing")
+	assert.ErrorContains(t, err, `migration "004_missing" not found`)
+}
+
+func TestVerifyBaselineSchema(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx, "CREATE TABLE users (id INT PRIMARY KEY, name STRING)")
+	require.NoError(t, err)
+	defer client.Close()
+
+	matching, err := schema.LoadFromDatabase(ctx, client)
+	require.NoError(t, err)
+	assert.NoError(t, verifyBaselineSchema(ctx, client, matching, "001_create_users"))
+
+	stmts, err := schema.ParseSQL("CREATE TABLE users (id INT PRIMARY KEY)")
+	require.NoError(t, err)
+	err = verifyBaselineSchema(ctx, client, schema.NewSchema(stmts...), "001_create_users")
+	assert.ErrorContains(t, err, "differs from the schema after 001_create_users")
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
 	"github.com/pjtatlow/scurry/internal/recovery"
 	"github.com/pjtatlow/scurry/internal/schema"
@@ -34,8 +35,9 @@ var (
 var errMigrationLocalNotConverged = errors.New("schema, migrations, and database are not in sync")
 
 var migrationLocalCmd = &cobra.Command{
-	Use:   "execute-local",
-	Short: "Author, apply, and reconcile a migration against a dev database",
+	Use:         "execute-local",
+	Short:       "Author, apply, and reconcile a migration against a dev database",
+	Annotations: writesDatabase,
 	Long: `Author the next migration and apply it to a development database in one step.
 
 This is the migration-first local dev loop: it catches up any pending migrations,
@@ -60,6 +62,7 @@ func init() {
 
 	flags.AddDbUrl(migrationLocalCmd)
 	flags.AddDefinitionDirs(migrationLocalCmd)
+	flags.AddEnv(migrationLocalCmd)
 
 	migrationLocalCmd.Flags().StringVar(&migrationLocalName, "name", "", "Name for the migration (skips prompt)")
 	migrationLocalCmd.Flags().StringVar(&migrationLocalSQLPath, "migration-sql", "",
@@ -113,6 +116,7 @@ func runMigrationLocal(cmd *cobra.Command, args []string) error {
 	opts := MigrationLocalOptions{
 		Fs:             fs,
 		DefinitionDirs: flags.DefinitionDirs,
+		Env:            flags.Env,
 		DbClient:       client,
 		SuppliedSQL:    suppliedSQL,
 		UseSuppliedSQL: useSupplied,
@@ -128,11 +132,11 @@ func runMigrationLocal(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		reportPath, reportErr := writeErrorReport(errCtx, err)
 		if reportErr != nil {
-			fmt.Println(ui.Warning(fmt.Sprintf("Failed to write error report: %s", reportErr)))
+			logging.Warning(fmt.Sprintf("Failed to write error report: %s", reportErr))
 		} else if reportPath != "" {
-			fmt.Println(ui.Info(fmt.Sprintf("Error report written to: %s", reportPath)))
+			logging.Info(fmt.Sprintf("Error report written to: %s", reportPath))
 		}
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -143,6 +147,7 @@ func runMigrationLocal(cmd *cobra.Command, args []string) error {
 type MigrationLocalOptions struct {
 	Fs             afero.Fs
 	DefinitionDirs []string
+	Env            string     // selects the overlays/<env> definitions, if any
 	DbClient       *db.Client // live dev database
 	SuppliedSQL    string     // raw body already read from file/stdin
 	UseSuppliedSQL bool       // true when --migration-sql was given (an empty body is invalid)
@@ -229,19 +234,23 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 		if err != nil {
 			return result, fmt.Errorf("failed to load table_sizes.yaml: %w", err)
 		}
-		classifyResult := migrationpkg.ClassifyStatements(stmtAST, tableSizes)
+		classifyRules, err := loadClassifyRules(fs)
+		if err != nil {
+			return result, err
+		}
+		classifyResult := migrationpkg.ClassifyStatements(stmtAST, tableSizes, classifyRules)
 		if classifyResult.Mode == migrationpkg.ModeAsync {
-			fmt.Println()
-			fmt.Println(ui.Warning("Migration classified as async:"))
+			logging.Newline()
+			logging.Warning("Migration classified as async:")
 			for _, reason := range classifyResult.Reasons {
-				fmt.Printf("  - %s\n", reason)
+				logging.Print(fmt.Sprintf("  - %s", reason))
 			}
-			fmt.Println()
+			logging.Newline()
 		}
 		header = &migrationpkg.Header{Mode: classifyResult.Mode}
 	} else {
 		// --- Diff path: author from the definitions-vs-snapshot diff. ---
-		localSchema, err := loadDefinitionsSchema(ctx, fs, opts.DefinitionDirs)
+		localSchema, err := loadDefinitionsSchema(ctx, fs, opts.DefinitionDirs, opts.Env)
 		if err != nil {
 			return result, fmt.Errorf("failed to load local schema: %w", err)
 		}
@@ -252,9 +261,9 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 			// Nothing new to author. Catch-up may still have advanced the DB, so
 			// reconcile and report rather than treating this as an error.
 			if caughtUp == 0 && !baseline {
-				fmt.Println(ui.Success("✓ No schema changes to author"))
+				logging.Success("✓ No schema changes to author")
 			} else {
-				fmt.Println(ui.Info("No schema changes to author."))
+				logging.Info("No schema changes to author.")
 			}
 			if baseline && !opts.DryRun {
 				if err := markAllMigrationsComplete(ctx, dbClient, preAuthorMigs); err != nil {
@@ -281,14 +290,18 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 		if err != nil {
 			return result, fmt.Errorf("failed to load table_sizes.yaml: %w", err)
 		}
-		classifyResult := migrationpkg.ClassifyDifferences(diffResult.Differences, tableSizes)
+		classifyRules, err := loadClassifyRules(fs)
+		if err != nil {
+			return result, err
+		}
+		classifyResult := migrationpkg.ClassifyDifferences(diffResult.Differences, tableSizes, classifyRules)
 		if classifyResult.Mode == migrationpkg.ModeAsync {
-			fmt.Println()
-			fmt.Println(ui.Warning("Migration classified as async:"))
+			logging.Newline()
+			logging.Warning("Migration classified as async:")
 			for _, reason := range classifyResult.Reasons {
-				fmt.Printf("  - %s\n", reason)
+				logging.Print(fmt.Sprintf("  - %s", reason))
 			}
-			fmt.Println()
+			logging.Newline()
 		}
 		header = &migrationpkg.Header{Mode: classifyResult.Mode}
 		rawBody = ""
@@ -300,7 +313,7 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 		return result, err
 	}
 	if opts.DryRun {
-		fmt.Println(ui.Info("ℹ Dry run mode - no migration written, nothing applied."))
+		logging.Info("ℹ Dry run mode - no migration written, nothing applied.")
 		return result, nil
 	}
 	result.Authored = true
@@ -312,7 +325,7 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 		if err := markAllMigrationsComplete(ctx, dbClient, preAuthorMigs); err != nil {
 			return result, fmt.Errorf("failed to baseline migrations: %w", err)
 		}
-		fmt.Println(ui.Success(fmt.Sprintf("✓ Marked %d existing migration(s) as applied (baseline)", len(preAuthorMigs))))
+		logging.Success(fmt.Sprintf("✓ Marked %d existing migration(s) as applied (baseline)", len(preAuthorMigs)))
 	}
 
 	// 5. Apply the new migration via the execute core.
@@ -329,11 +342,11 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 		return result, err
 	}
 	for _, w := range warnings {
-		fmt.Println(ui.Warning(w))
+		logging.Warning(w)
 	}
 
-	fmt.Println()
-	fmt.Println(ui.Info("⟳ Applying migration..."))
+	logging.Newline()
+	logging.Info("⟳ Applying migration...")
 	_, _, applyErr := runMigrationList(ctx, dbClient, unapplied)
 	if applyErr == nil {
 		result.Applied = true
@@ -344,7 +357,7 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 	if err := dumpProductionSchema(ctx, fs, newSchema); err != nil {
 		return result, fmt.Errorf("failed to update schema.sql: %w", err)
 	}
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath())))
+	logging.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath()))
 
 	// 7. Three-way reconcile (compute before reporting so apply-failure handling can
 	// consult the database drift).
@@ -361,7 +374,7 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 			return result, fmt.Errorf("failed to record already-satisfied migration: %w", err)
 		}
 		result.Applied = true
-		fmt.Println(ui.Warning("Migration already satisfied by the database; recorded as applied."))
+		logging.Warning("Migration already satisfied by the database; recorded as applied.")
 	}
 
 	// 9. Report and enforce --strict.
@@ -375,20 +388,20 @@ func executeMigrationLocal(ctx context.Context, opts MigrationLocalOptions, errC
 
 // loadDefinitionsSchema loads the schema definitions (the "true" desired schema) via
 // an ephemeral shadow database, mirroring how migration gen loads them.
-func loadDefinitionsSchema(ctx context.Context, fs afero.Fs, dirs []string) (*schema.Schema, error) {
+func loadDefinitionsSchema(ctx context.Context, fs afero.Fs, dirs []string, env string) (*schema.Schema, error) {
 	shadow, err := db.GetShadowDB(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get shadow database client: %w", err)
 	}
 	defer shadow.Close()
-	return schema.LoadFromDirectories(ctx, fs, dirs, shadow)
+	return schema.LoadFromDirectoriesForEnv(ctx, fs, dirs, env, shadow)
 }
 
 // computeReconcile loads the three schema states and records how they diverge:
 //   - SchemaDrift:   definitions (T) vs snapshot (S) — migrations don't produce the schema
 //   - DatabaseDrift: snapshot (S) vs database (D)    — the DB drifted from the migrations
 func computeReconcile(ctx context.Context, opts MigrationLocalOptions, result *MigrationLocalResult) error {
-	t, err := loadDefinitionsSchema(ctx, opts.Fs, opts.DefinitionDirs)
+	t, err := loadDefinitionsSchema(ctx, opts.Fs, opts.DefinitionDirs, opts.Env)
 	if err != nil {
 		return fmt.Errorf("failed to load schema definitions for reconcile: %w", err)
 	}
@@ -409,17 +422,17 @@ func computeReconcile(ctx context.Context, opts MigrationLocalOptions, result *M
 
 // reportReconcile prints the reconcile outcome with targeted next steps.
 func reportReconcile(result *MigrationLocalResult) {
-	fmt.Println()
+	logging.Newline()
 	if result.SchemaDrift {
-		fmt.Println(ui.Warning("Your migrations do not fully produce your declared schema."))
-		fmt.Println(ui.Info("  Run 'scurry migration execute-local' again to author the remaining changes."))
+		logging.Warning("Your migrations do not fully produce your declared schema.")
+		logging.Info("  Run 'scurry migration execute-local' again to author the remaining changes.")
 	}
 	if result.DatabaseDrift {
-		fmt.Println(ui.Warning("Your database has drifted from the sum of your migrations."))
-		fmt.Println(ui.Info("  Run 'scurry push' to reconcile the database."))
+		logging.Warning("Your database has drifted from the sum of your migrations.")
+		logging.Info("  Run 'scurry push' to reconcile the database.")
 	}
 	if result.Converged {
-		fmt.Println(ui.Success("✓ Schema, migrations, and database are in sync."))
+		logging.Success("✓ Schema, migrations, and database are in sync.")
 	}
 }
 
@@ -488,7 +501,7 @@ func catchUpMigrations(ctx context.Context, fs afero.Fs, dbClient *db.Client, fo
 			return false, 0, err
 		}
 		if schemaHasObjects(existing) {
-			fmt.Println(ui.Info(fmt.Sprintf("Existing untracked database detected; baselining %d migration(s).", len(migrations))))
+			logging.Info(fmt.Sprintf("Existing untracked database detected; baselining %d migration(s).", len(migrations)))
 			return true, 0, nil
 		}
 	}
@@ -498,37 +511,38 @@ func catchUpMigrations(ctx context.Context, fs afero.Fs, dbClient *db.Client, fo
 		return false, 0, err
 	}
 	for _, warning := range warnings {
-		fmt.Println(ui.Warning(warning))
+		logging.Warning(warning)
 	}
 	if len(unapplied) == 0 {
 		if verbose {
-			fmt.Println(ui.Subtle("→ No pending migrations to run"))
+			logging.Subtle("→ No pending migrations to run")
 		}
 		return false, 0, nil
 	}
 
-	fmt.Printf("\n%s\n", ui.Header(fmt.Sprintf("Pending migrations to run (%d):", len(unapplied))))
+	logging.Newline()
+	logging.Header(fmt.Sprintf("Pending migrations to run (%d):", len(unapplied)))
 	for i, migration := range unapplied {
 		modeLabel := ""
 		if migration.Mode == db.MigrationModeAsync {
 			modeLabel = " (async)"
 		}
-		fmt.Printf("  %d. %s%s\n", i+1, migration.Name, modeLabel)
+		logging.Print(fmt.Sprintf("  %d. %s%s", i+1, migration.Name, modeLabel))
 	}
-	fmt.Println()
+	logging.Newline()
 
 	if dryRun {
-		fmt.Println(ui.Info(fmt.Sprintf("ℹ Dry run mode - %d migration(s) would run.", len(unapplied))))
+		logging.Info(fmt.Sprintf("ℹ Dry run mode - %d migration(s) would run.", len(unapplied)))
 		return false, 0, nil
 	}
 
-	fmt.Println(ui.Info("⟳ Running migrations..."))
+	logging.Info("⟳ Running migrations...")
 	executed, skipped, err := runMigrationList(ctx, dbClient, unapplied)
 	if err != nil {
 		return false, executed, err
 	}
 	if executed > 0 {
-		fmt.Println(ui.Success(fmt.Sprintf("✓ Ran %d migration(s)", executed)))
+		logging.Success(fmt.Sprintf("✓ Ran %d migration(s)", executed))
 	}
 	if skipped > 0 {
 		return false, executed, fmt.Errorf("could not run %d migration(s) due to unmet dependencies or a running async migration", skipped)
@@ -584,7 +598,7 @@ func recoverBeforeLocal(ctx context.Context, fs afero.Fs, dbClient *db.Client, f
 		OnRetryFailure: func(ctx context.Context, client *db.Client) (*db.AppliedMigration, error) {
 			refreshed, err := client.GetFailedMigration(ctx)
 			if err != nil {
-				fmt.Println(ui.Warning(fmt.Sprintf("Could not refresh migration status: %v", err)))
+				logging.Warning(fmt.Sprintf("Could not refresh migration status: %v", err))
 				return nil, err
 			}
 			if refreshed != nil {
@@ -600,7 +614,7 @@ func recoverBeforeLocal(ctx context.Context, fs afero.Fs, dbClient *db.Client, f
 		return err
 	}
 	if result == recovery.ResultAbort {
-		fmt.Println(ui.Subtle("Canceled."))
+		logging.Subtle("Canceled.")
 		return errMigrationCanceled
 	}
 
@@ -423,3 +423,160 @@ func TestMarkAllMigrationsComplete(t *testing.T) {
 		})
 	}
 }
+
+func TestShadowReplayList(t *testing.T) {
+	all := []db.Migration{{Name: "001_a"}, {Name: "002_b"}, {Name: "003_c"}, {Name: "004_d"}, {Name: "005_e"}}
+	applied := []db.AppliedMigration{{Name: "001_a"}, {Name: "003_c"}, {Name: "000_removed"}}
+	toExecute := []db.Migration{{Name: "004_d"}, {Name: "R__views", Repeatable: true}}
+
+	var names []string
+	for _, m := range shadowReplayList(all, applied, toExecute) {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"001_a", "003_c", "004_d", "R__views"}, names)
+}
+
+func TestSplitParallelBatches(t *testing.T) {
+	syncMigration := func(name string) db.Migration { return db.Migration{Name: name, Mode: db.MigrationModeSync} }
+	asyncMigration := func(name string) db.Migration { return db.Migration{Name: name, Mode: db.MigrationModeAsync} }
+
+	tests := []struct {
+		name  string
+		stage []db.Migration
+		want  [][]string
+		par   []bool
+	}{
+		{
+			name:  "empty",
+			stage: nil,
+		},
+		{
+			name:  "async before sync keeps timestamp order",
+			stage: []db.Migration{asyncMigration("001_a"), syncMigration("002_b")},
+			want:  [][]string{{"001_a"}, {"002_b"}},
+			par:   []bool{true, false},
+		},
+		{
+			name:  "consecutive async migrations run together",
+			stage: []db.Migration{syncMigration("001_a"), asyncMigration("002_b"), asyncMigration("003_c"), syncMigration("004_d"), asyncMigration("005_e")},
+			want:  [][]string{{"001_a"}, {"002_b", "003_c"}, {"004_d"}, {"005_e"}},
+			par:   []bool{false, true, false, true},
+		},
+		{
+			name:  "squashed async migration runs alone",
+			stage: []db.Migration{asyncMigration("001_a"), {Name: "002_b", Mode: db.MigrationModeAsync, Squash: true}, asyncMigration("003_c")},
+			want:  [][]string{{"001_a"}, {"002_b"}, {"003_c"}},
+			par:   []bool{true, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names [][]string
+			var par []bool
+			for _, batch := range splitParallelBatches(tt.stage) {
+				var batchNames []string
+				for _, m := range batch.migrations {
+					batchNames = append(batchNames, m.Name)
+				}
+				names = append(names, batchNames)
+				par = append(par, batch.parallel)
+			}
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, tt.par, par)
+		})
+	}
+}
+
+func TestLoadRepeatableMigrations(t *testing.T) {
+	t.Parallel()
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"R__views.sql":                         "CREATE OR REPLACE VIEW v AS SELECT 1;",
+		"R__functions.sql":                     "CREATE OR REPLACE FUNCTION f() RETURNS INT LANGUAGE SQL AS $$ SELECT 1 $$;",
+		"R__notes.txt":                         "not a migration",
+		"schema.sql":                           "CREATE TABLE t (id INT PRIMARY KEY);",
+		"20250101000000_initial/migration.sql": "CREATE TABLE t (id INT PRIMARY KEY);",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(flags.MigrationDir, path)
+		require.NoError(t, fs.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, afero.WriteFile(fs, fullPath, []byte(content), 0644))
+	}
+
+	repeatables, err := loadRepeatableMigrations(fs)
+	require.NoError(t, err)
+	require.Len(t, repeatables, 2)
+	assert.Equal(t, "R__functions", repeatables[0].Name)
+	assert.Equal(t, "R__views", repeatables[1].Name)
+	for _, m := range repeatables {
+		assert.True(t, m.Repeatable)
+		assert.Equal(t, computeChecksum(m.SQL), m.Checksum)
+	}
+
+	migrations, err := loadMigrations(fs)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, "20250101000000_initial", migrations[0].Name)
+}
+
+func TestFilterChangedRepeatables(t *testing.T) {
+	repeatables := []db.Migration{
+		{Name: "R__functions", Checksum: "aaa", Repeatable: true},
+		{Name: "R__new", Checksum: "bbb", Repeatable: true},
+		{Name: "R__views", Checksum: "ccc", Repeatable: true},
+	}
+	checksums := map[string]string{
+		"R__functions": "aaa",
+		"R__views":     "old",
+	}
+
+	var names []string
+	for _, m := range filterChangedRepeatables(repeatables, checksums) {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"R__new", "R__views"}, names)
+}
+
+func TestVerifyMigrationsOnShadow(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	all := []db.Migration{
+		{Name: "001_users", SQL: "CREATE TABLE vs_users (id INT PRIMARY KEY);"},
+		{Name: "002_add_name", SQL: "ALTER TABLE vs_users ADD COLUMN name STRING;"},
+		{Name: "003_bad_index", SQL: "CREATE INDEX ON vs_users (email);"},
+	}
+	applied := []db.AppliedMigration{{Name: "001_users"}}
+
+	err := verifyMigrationsOnShadow(ctx, all, applied, all[1:2])
+	require.NoError(t, err)
+
+	err = verifyMigrationsOnShadow(ctx, all, applied, all[1:])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "003_bad_index")
+}
+
+func TestAssertSchemaMatchesDefinitions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/definitions/users.sql", []byte("CREATE TABLE users (id INT PRIMARY KEY, name STRING);"), 0644))
+	dirs := []string{"/definitions"}
+
+	client, err := db.GetShadowDB(ctx, "CREATE TABLE users (id INT PRIMARY KEY)")
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Off unless a mode is given
+	require.NoError(t, assertSchemaMatchesDefinitions(ctx, fs, client, dirs, "", "", nil))
+
+	require.NoError(t, assertSchemaMatchesDefinitions(ctx, fs, client, dirs, "", "warn", nil))
+	err = assertSchemaMatchesDefinitions(ctx, fs, client, dirs, "", "fail", nil)
+	require.ErrorContains(t, err, "does not match the definitions")
+
+	_, err = client.ExecContext(ctx, "ALTER TABLE users ADD COLUMN name STRING")
+	require.NoError(t, err)
+	require.NoError(t, assertSchemaMatchesDefinitions(ctx, fs, client, dirs, "", "fail", nil))
+}
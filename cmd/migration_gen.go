@@ -12,22 +12,72 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/pjtatlow/scurry/internal/config"
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
 	"github.com/pjtatlow/scurry/internal/schema"
 	"github.com/pjtatlow/scurry/internal/ui"
 )
 
 var (
-	migrationName string
+	migrationName         string
+	migrationRefreshStats bool
+	migrationAnnotate     bool
 )
 
 var migrationGenCmd = &cobra.Command{
 	Use:   "gen",
 	Short: "Generate migration from schema changes",
 	Long: `Generate a migration by comparing the local schema with the production schema.
-This will detect differences and create a new migration file with the necessary SQL statements.`,
+This will detect differences and create a new migration file with the necessary SQL statements.
+
+Migrations that rebuild or scan large tables (such as building an index) are
+classified as async. Override the classification of a table's changes with a
+directive above its CREATE TABLE or CREATE INDEX, or of one index's changes at
+the end of its line:
+  -- scurry:classify=sync
+  UNIQUE INDEX users_email_key (email) -- scurry:classify=async
+
+To force the mode of an operation everywhere, map its rule to sync or async in
+the config file:
+  migrations:
+    classify:
+      create_index: async
+      update: sync
+
+Rules: create_index, add_column_default, set_not_null, add_unique_constraint,
+add_constraint, validate_constraint, alter_column_type, update, delete,
+insert_select and import. IMPORT INTO is async unless the import rule says
+sync. Directives win over rules.
+
+Table sizes come from migrations/table_sizes.yaml, or straight from the
+database when --db-url is given. scurry warns when the file is older than
+migrations.table_sizes_max_age in the config file (30 days by default), or has
+no size for a table the migration changes, since those changes are classified
+as if the table were small.
+
+Statements are generated for the CockroachDB release given by --crdb-version,
+or else the one --db-url connects to. Changes that release can't express, like
+dropping an enum value before v21.2, fail before the migration is written.
+
+With --dialect=postgres, statements are written for Postgres instead: types use
+their Postgres names, indexes are created with CREATE INDEX, and changes only
+CockroachDB can make (storage parameters, hash-sharded indexes, column
+families, ...) fail before the migration is written. Definitions are still
+loaded into a CockroachDB shadow database, reading INT as INT4 and SERIAL as a
+sequence the way Postgres does, so they must stick to the syntax both share.
+
+With --check-nulls, the database --db-url connects to is checked for NULL rows
+in each column the migration makes NOT NULL, since the ALTER fails if any
+remain. If some do, scurry offers to create migrations backfilling them, with
+the column's default when it has one, for the new migration to depend on.
+
+Each statement is annotated with a comment saying whether CockroachDB runs it
+online, whether it backfills the table, and what it blocks while it runs:
+  -- online: yes, backfills: yes, blocks: none
+  CREATE INDEX posts_author_idx ON posts (author_id);`,
 	RunE: migrationGen,
 }
 
@@ -35,7 +85,16 @@ func init() {
 	migrationCmd.AddCommand(migrationGenCmd)
 
 	flags.AddDefinitionDirs(migrationGenCmd)
+	flags.AddEnv(migrationGenCmd)
+	flags.AddAllowDestructive(migrationGenCmd)
+	flags.AddDeferValidation(migrationGenCmd)
+	flags.AddTTLIndex(migrationGenCmd)
+	flags.AddSkip(migrationGenCmd)
+	flags.AddCheckNulls(migrationGenCmd)
+	flags.AddDbUrl(migrationGenCmd)
 	migrationGenCmd.Flags().StringVar(&migrationName, "name", "", "Name for the migration (skips prompt)")
+	migrationGenCmd.Flags().BoolVar(&migrationRefreshStats, "refresh-stats", false, "Collect statistics on the large tables an async migration changes once it succeeds (also migrations.refresh_stats in the config file)")
+	migrationGenCmd.Flags().BoolVar(&migrationAnnotate, "annotate", true, "Comment each statement with whether it runs online, backfills, and what it blocks")
 }
 
 func migrationGen(cmd *cobra.Command, args []string) error {
@@ -45,17 +104,20 @@ func migrationGen(cmd *cobra.Command, args []string) error {
 	if len(flags.DefinitionDirs) == 0 {
 		return fmt.Errorf("definition directory is required (use --definitions)")
 	}
+	if flags.CheckNulls && flags.DbUrl == "" {
+		return fmt.Errorf("--check-nulls needs the database to count NULL rows in (use --db-url)")
+	}
 
 	errCtx := &ErrorContext{}
 	err := doMigrationGen(ctx, errCtx)
 	if err != nil {
 		reportPath, reportErr := writeErrorReport(errCtx, err)
 		if reportErr != nil {
-			fmt.Println(ui.Warning(fmt.Sprintf("Failed to write error report: %s", reportErr)))
+			logging.Warning(fmt.Sprintf("Failed to write error report: %s", reportErr))
 		} else if reportPath != "" {
-			fmt.Println(ui.Info(fmt.Sprintf("Error report written to: %s", reportPath)))
+			logging.Info(fmt.Sprintf("Error report written to: %s", reportPath))
 		}
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -71,9 +133,7 @@ func doMigrationGen(ctx context.Context, errCtx *ErrorContext) error {
 	}
 
 	// 1. Load local schema from schema-dir
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(flags.DefinitionDirs, ", "))))
-	}
+	logging.Debug(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(flags.DefinitionDirs, ", ")))
 
 	dbClient, err := db.GetShadowDB(ctx)
 	if err != nil {
@@ -81,53 +141,104 @@ func doMigrationGen(ctx context.Context, errCtx *ErrorContext) error {
 	}
 	defer dbClient.Close()
 
-	localSchema, err := schema.LoadFromDirectories(ctx, fs, flags.DefinitionDirs, dbClient)
+	localSchema, err := schema.LoadFromDirectoriesForEnv(ctx, fs, flags.DefinitionDirs, flags.Env, dbClient)
 	if err != nil {
 		return fmt.Errorf("failed to load local schema: %w", err)
 	}
 	errCtx.LocalSchema = localSchema
+	if err := addIgnoredObjects(fs, localSchema, flags.Skip); err != nil {
+		return err
+	}
 
 	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views locally",
-			len(localSchema.Tables), len(localSchema.Types), len(localSchema.Routines), len(localSchema.Sequences), len(localSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views locally",
+			len(localSchema.Tables), len(localSchema.Types), len(localSchema.Routines), len(localSchema.Sequences), len(localSchema.Views)))
 	}
 
 	// 2. Load production schema from schema.sql
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Loading production schema from %s...", getSchemaFilePath())))
-	}
+	logging.Debug(fmt.Sprintf("→ Loading production schema from %s...", getSchemaFilePath()))
 
 	prodSchema, err := loadProductionSchema(ctx, fs)
 	if err != nil {
 		return fmt.Errorf("failed to load production schema: %w", err)
 	}
+	prodSchema, err = withRepeatableMigrations(ctx, fs, prodSchema)
+	if err != nil {
+		return fmt.Errorf("failed to apply repeatable migrations: %w", err)
+	}
 	errCtx.RemoteSchema = prodSchema
 
 	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views in production",
-			len(prodSchema.Tables), len(prodSchema.Types), len(prodSchema.Routines), len(prodSchema.Sequences), len(prodSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views in production",
+			len(prodSchema.Tables), len(prodSchema.Types), len(prodSchema.Routines), len(prodSchema.Sequences), len(prodSchema.Views)))
 	}
 
 	// 3. Compare schemas
 	if flags.Verbose {
-		fmt.Println()
-		fmt.Println(ui.Subtle("→ Comparing schemas..."))
+		logging.Newline()
+		logging.Subtle("→ Comparing schemas...")
 	}
 
+	// Pair tables gaining a TTL with the index the TTL job needs
+	pairTTLIndexes(localSchema, prodSchema, flags.TTLIndex)
+
 	diffResult := schema.Compare(localSchema, prodSchema)
 
+	// Only generate statements the target database supports
+	if err := forTargetDatabase(ctx, diffResult); err != nil {
+		return err
+	}
+
+	printIgnoredDifferences(diffResult)
+
 	// 4. Check if there are any changes
 	if !diffResult.HasChanges() {
-		fmt.Println()
-		fmt.Println(ui.Success("✓ No schema changes detected"))
+		logging.Newline()
+		logging.Success("✓ No schema changes detected")
 		return nil
 	}
 
+	// Refuse to drop tables or columns unless explicitly allowed
+	if err := enforceDestructivePolicy(fs, flags.DefinitionDirs, diffResult, flags.AllowDestructive); err != nil {
+		return err
+	}
+
+	// Make sure columns made NOT NULL have no NULL rows left in the database,
+	// backfilling them in migrations of their own if the user asks
+	var backfills []string
+	if flags.CheckNulls {
+		backfills, err = checkNullRowsForGen(ctx, fs, localSchema, diffResult)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Prompt for USING expressions on column type changes
 	if err := promptForUsingExpressionsGen(diffResult); err != nil {
 		return err
 	}
 
+	tableSizes, err := migrationpkg.LoadTableSizes(fs, flags.MigrationDir)
+	if err != nil {
+		return fmt.Errorf("failed to load table_sizes.yaml: %w", err)
+	}
+	if flags.DbUrl != "" {
+		tableSizes = liveTableSizesOrFile(ctx, tableSizes)
+	}
+
+	// Split constraint validation out into its own migration. UNIQUE WITHOUT
+	// INDEX constraints have no index to check existing rows with, so on large
+	// tables they're always validated separately.
+	var deferred *schema.ComparisonResult
+	if flags.DeferValidation {
+		deferred, err = diffResult.DeferValidation()
+	} else {
+		deferred, err = diffResult.DeferUniqueValidation(tableSizes.IsLargeTable)
+	}
+	if err != nil {
+		return err
+	}
+
 	// Generate migration statements
 	statements, warnings, err := diffResult.GenerateMigrations(true)
 	if err != nil {
@@ -137,62 +248,121 @@ func doMigrationGen(ctx context.Context, errCtx *ErrorContext) error {
 
 	// Show differences
 	if flags.Verbose {
-		fmt.Println(ui.Header("\nDifferences found:"))
-		fmt.Println(diffResult.Summary())
-		fmt.Println()
-		fmt.Println(ui.Header(fmt.Sprintf("Generated %d migration statement(s) with %d warning(s):", len(statements), len(warnings))))
-
-		for i, stmt := range statements {
-			fmt.Printf("%s %s\n\n", ui.Info(fmt.Sprintf("%d.", i+1)), ui.SqlCode(stmt))
-		}
+		logging.Header("\nDifferences found:")
+		logging.Print(diffResult.Summary())
+		logging.Newline()
+		logging.Subtle(fmt.Sprintf("Generated %d migration statement(s) with %d warning(s)", len(statements), len(warnings)))
 	}
 	for i, warning := range warnings {
-		fmt.Printf("WARNING: %s \n\n", ui.Warning(fmt.Sprintf("%d. %s", i+1, warning)))
+		logging.Warning(fmt.Sprintf("WARNING: %d. %s", i+1, warning))
+		logging.Newline()
 	}
 
 	// Classify migration as sync or async
-	tableSizes, err := migrationpkg.LoadTableSizes(fs, flags.MigrationDir)
+	cfg, err := config.Load(fs, flags.ConfigFile)
 	if err != nil {
-		return fmt.Errorf("failed to load table_sizes.yaml: %w", err)
+		return err
 	}
+	classifyRules, err := migrationpkg.ParseClassifyRules(cfg.Migrations.Classify)
+	if err != nil {
+		return fmt.Errorf("invalid migrations.classify: %w", err)
+	}
+	warnTableSizes(tableSizes, cfg.Migrations.TableSizesMaxAge, changedTables(diffResult.Differences))
+
+	logging.Newline()
+	printStatementCosts(statements, tableSizes)
 
-	classifyResult := migrationpkg.ClassifyDifferences(diffResult.Differences, tableSizes)
+	classifyResult := migrationpkg.ClassifyDifferences(diffResult.Differences, tableSizes, classifyRules)
 
 	if classifyResult.Mode == migrationpkg.ModeAsync {
-		fmt.Println()
-		fmt.Println(ui.Warning("Migration classified as async:"))
+		logging.Newline()
+		logging.Warning("Migration classified as async:")
 		for _, reason := range classifyResult.Reasons {
-			fmt.Printf("  - %s\n", reason)
+			logging.Print(fmt.Sprintf("  - %s", reason))
 		}
-		fmt.Println()
+		logging.Newline()
 	}
 
 	header := &migrationpkg.Header{Mode: classifyResult.Mode}
 
+	// Big changes leave table statistics stale, so query plans can regress until
+	// they are refreshed
+	if classifyResult.Mode == migrationpkg.ModeAsync && (migrationRefreshStats || cfg.Migrations.RefreshStats) {
+		header.RefreshStats = classifyResult.Tables
+	}
+
+	if migrationAnnotate {
+		statements = migrationpkg.AnnotateStatements(statements)
+	}
+
+	// The migration has to run after the backfills it needs
+	if len(backfills) > 0 {
+		header.DependsOn, err = dependenciesWithBackfills(fs, statements, backfills)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Validate the statements, resolve the name, detect dependencies, and write
 	// the migration file (with the interactive manual-edit fallback on failure).
-	_, newSchema, err := finalizeAuthoredMigration(ctx, fs, prodSchema, statements, "", header, migrationName, flags.Force, false, flags.Verbose)
+	dirName, newSchema, err := finalizeAuthoredMigration(ctx, fs, prodSchema, statements, "", header, migrationName, flags.Force, false, flags.Verbose)
 	if err != nil {
 		return err
 	}
 
-	// Update the production schema snapshot (schema.sql).
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Updating production schema..."))
+	if deferred != nil && deferred.HasChanges() {
+		newSchema, err = genValidationMigration(ctx, fs, newSchema, deferred, dirName)
+		if err != nil {
+			return err
+		}
 	}
 
+	// Update the production schema snapshot (schema.sql).
+	logging.Debug("→ Updating production schema...")
+
 	if err := dumpProductionSchema(ctx, fs, newSchema); err != nil {
 		return fmt.Errorf("failed to update schema.sql: %w", err)
 	}
 
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath())))
+	logging.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath()))
 
-	fmt.Println()
-	fmt.Println(ui.Info("Migration created successfully! Apply it to your database with: scurry migration execute"))
+	logging.Newline()
+	logging.Info("Migration created successfully! Apply it to your database with: scurry migration execute")
 
 	return nil
 }
 
+// genValidationMigration writes the VALIDATE CONSTRAINT statements deferred from the
+// migration in dirName as a separate async migration that depends on it, so the
+// constraints are added without scanning tables and checked in the background.
+func genValidationMigration(ctx context.Context, fs afero.Fs, prodSchema *schema.Schema, deferred *schema.ComparisonResult, dirName string) (*schema.Schema, error) {
+	statements, _, err := deferred.GenerateMigrations(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate validation migration: %w", err)
+	}
+	if migrationAnnotate {
+		statements = migrationpkg.AnnotateStatements(statements)
+	}
+
+	if flags.Verbose {
+		logging.Newline()
+		logging.Subtle(fmt.Sprintf("→ Deferring validation of %d constraint(s) to an async migration...", len(deferred.Differences)))
+	}
+
+	// Drop the timestamp so the name reads "<timestamp>_<name>_validate"
+	name := dirName
+	if idx := strings.Index(name, "_"); idx != -1 {
+		name = name[idx+1:]
+	}
+	header := &migrationpkg.Header{Mode: migrationpkg.ModeAsync, DependsOn: []string{dirName}}
+
+	_, newSchema, err := finalizeAuthoredMigration(ctx, fs, prodSchema, statements, "", header, name+"_validate", flags.Force, false, flags.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	return newSchema, nil
+}
+
 // finalizeAuthoredMigration takes a set of migration statements (either generated
 // from a diff or supplied directly) and turns them into a migration file: it
 // validates them against prodSchema on an ephemeral shadow database, resolves the
@@ -229,9 +399,9 @@ func finalizeAuthoredMigration(
 			return "", nil, fmt.Errorf("failed to apply migrations to schema: %w", err)
 		}
 
-		fmt.Println(ui.Error(fmt.Sprintf("Failed to apply generated migration: %v", err)))
-		fmt.Println()
-		fmt.Println(ui.Info("The generated migration could not be applied. This may require manual intervention."))
+		logging.Error(fmt.Sprintf("Failed to apply generated migration: %v", err))
+		logging.Newline()
+		logging.Info("The generated migration could not be applied. This may require manual intervention.")
 
 		confirmed, confirmErr := ui.ConfirmPrompt("Would you like to create a manual migration instead?")
 		if confirmErr != nil {
@@ -345,8 +515,8 @@ func finalizeAuthoredMigration(
 
 	// 5. Write the migration file.
 	if verbose {
-		fmt.Println()
-		fmt.Println(ui.Subtle("→ Creating migration..."))
+		logging.Newline()
+		logging.Subtle("→ Creating migration...")
 	}
 
 	var dirName string
@@ -363,7 +533,7 @@ func finalizeAuthoredMigration(
 		return "", nil, fmt.Errorf("failed to create migration: %w", err)
 	}
 
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Created migration: %s", dirName)))
+	logging.Success(fmt.Sprintf("✓ Created migration: %s", dirName))
 
 	return dirName, newSchema, nil
 }
@@ -379,12 +549,13 @@ func promptForUsingExpressionsGen(diffResult *schema.ComparisonResult) error {
 
 	for i := range diffResult.Differences {
 		diff := &diffResult.Differences[i]
-		if diff.Type != schema.DiffTypeColumnTypeChanged {
+		// Skip changes that already use an expression from a -- scurry:using directive
+		if diff.Type != schema.DiffTypeColumnTypeChanged || diff.HasUsingDirective {
 			continue
 		}
 
 		// Find the AlterTableAlterColumnType command in the migration statements
-		for _, stmt := range diff.MigrationStatements {
+		for _, stmt := range diff.Statements() {
 			alterTable, ok := stmt.(*tree.AlterTable)
 			if !ok {
 				continue
@@ -396,7 +567,7 @@ func promptForUsingExpressionsGen(diffResult *schema.ComparisonResult) error {
 				}
 
 				// Prompt user if they want to add a USING expression
-				fmt.Println()
+				logging.Newline()
 				confirmed, err := ui.ConfirmPrompt(fmt.Sprintf("Add a USING expression for: %s?", diff.Description))
 				if err != nil {
 					return fmt.Errorf("confirmation prompt failed: %w", err)
@@ -412,7 +583,7 @@ func promptForUsingExpressionsGen(diffResult *schema.ComparisonResult) error {
 					huh.NewGroup(
 						huh.NewInput().
 							Title("USING expression").
-							Description("Enter the expression to convert the column value").
+							Description("Enter the expression to convert the column value. Add -- scurry:using=<expr> to the column's line to skip this prompt.").
 							Placeholder(alterColType.Column.String()).
 							Value(&exprStr).
 							Validate(func(s string) error {
@@ -443,3 +614,82 @@ func promptForUsingExpressionsGen(diffResult *schema.ComparisonResult) error {
 	}
 	return nil
 }
+
+// forTargetDatabase rewrites the differences' statements for --dialect, or
+// for the CockroachDB release migrations will run on
+func forTargetDatabase(ctx context.Context, diffResult *schema.ComparisonResult) error {
+	if flags.Dialect == string(schema.DialectPostgres) {
+		logging.Debug("→ Generating statements for Postgres")
+		diffResult.ForDialect(schema.DialectPostgres)
+		return nil
+	}
+	target, err := migrationTargetVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if target != nil {
+		logging.Debug(fmt.Sprintf("→ Generating statements for CockroachDB %s", target))
+		diffResult.ForTargetVersion(*target)
+	}
+	return nil
+}
+
+// migrationTargetVersion returns the release to generate statements for, from
+// --crdb-version or the cluster at --db-url. A cluster that can't be reached
+// only loses the check.
+func migrationTargetVersion(ctx context.Context) (*schema.Version, error) {
+	if db.CrdbVersion != "" || flags.DbUrl == "" {
+		return targetVersion(ctx, nil)
+	}
+	dbClient, err := db.Connect(ctx, flags.DbUrl)
+	if err != nil {
+		logging.Warning(fmt.Sprintf("Could not connect to the database for its version: %v", err))
+		return nil, nil
+	}
+	defer dbClient.Close()
+
+	target, err := targetVersion(ctx, dbClient)
+	if err != nil {
+		logging.Warning(fmt.Sprintf("Could not read the database version: %v", err))
+		return nil, nil
+	}
+	return target, nil
+}
+
+// loadClassifyRules reads the classification rules from the migrations.classify
+// section of the config file
+func loadClassifyRules(fs afero.Fs) (migrationpkg.ClassifyRules, error) {
+	cfg, err := config.Load(fs, flags.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := migrationpkg.ParseClassifyRules(cfg.Migrations.Classify)
+	if err != nil {
+		return nil, fmt.Errorf("invalid migrations.classify: %w", err)
+	}
+	return rules, nil
+}
+
+// liveTableSizesOrFile reads table sizes from the database, keeping the
+// threshold from table_sizes.yaml. It falls back to the file's sizes if the
+// database can't be reached.
+func liveTableSizesOrFile(ctx context.Context, fileSizes *migrationpkg.TableSizes) *migrationpkg.TableSizes {
+	dbClient, err := db.Connect(ctx, flags.DbUrl)
+	if err != nil {
+		logging.Warning(fmt.Sprintf("Could not connect to the database for table sizes, using table_sizes.yaml: %v", err))
+		return fileSizes
+	}
+	defer dbClient.Close()
+
+	threshold := int64(migrationpkg.DefaultLargeTableThreshold)
+	if fileSizes != nil && fileSizes.Threshold > 0 {
+		threshold = fileSizes.Threshold
+	}
+	ts, err := liveTableSizes(ctx, dbClient, threshold)
+	if err != nil {
+		logging.Warning(fmt.Sprintf("Could not read table sizes from the database, using table_sizes.yaml: %v", err))
+		return fileSizes
+	}
+	logging.Debug(fmt.Sprintf("→ Read sizes of %d table(s) from the database", len(ts.Tables)))
+	return ts
+}
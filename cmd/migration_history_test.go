@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestParseHistoryFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `{"version": 1, "exported_at": "2024-01-01T00:00:00Z", "migrations": [
+				{"name": "001_a", "checksum": "a", "status": "succeeded", "applied_at": "2024-01-01T00:00:00Z", "executed_by": "root"},
+				{"name": "002_b", "checksum": "b", "status": "failed", "applied_at": "2024-01-02T00:00:00Z", "executed_by": "root", "error_msg": "boom"}
+			]}`,
+			want: []string{"001_a", "002_b"},
+		},
+		{
+			name: "empty history",
+			data: `{"version": 1, "migrations": []}`,
+		},
+		{
+			name:    "unknown version",
+			data:    `{"version": 2, "migrations": []}`,
+			wantErr: "unsupported version 2",
+		},
+		{
+			name:    "not json",
+			data:    `name,checksum`,
+			wantErr: "invalid character",
+		},
+		{
+			name:    "missing name",
+			data:    `{"version": 1, "migrations": [{"checksum": "a", "status": "succeeded"}]}`,
+			wantErr: "migration 0 has no name",
+		},
+		{
+			name:    "duplicate",
+			data:    `{"version": 1, "migrations": [{"name": "001_a", "status": "succeeded"}, {"name": "001_a", "status": "failed"}]}`,
+			wantErr: "migration 001_a appears more than once",
+		},
+		{
+			name:    "unknown status",
+			data:    `{"version": 1, "migrations": [{"name": "001_a", "status": "done"}]}`,
+			wantErr: `unknown status "done"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := parseHistoryFile([]byte(tt.data))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, e := range history.Migrations {
+				names = append(names, e.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestParseHistoryFileKeepsFields(t *testing.T) {
+	errorMsg := "boom"
+	statement := 2
+	startedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry := db.HistoryEntry{
+		Name:             "002_b",
+		Checksum:         "b",
+		Status:           db.MigrationStatusFailed,
+		StartedAt:        &startedAt,
+		AppliedAt:        startedAt,
+		ExecutedBy:       "deployer",
+		ErrorMsg:         &errorMsg,
+		Async:            true,
+		CurrentStatement: &statement,
+	}
+
+	data := `{"version": 1, "migrations": [{"name": "002_b", "checksum": "b", "status": "failed", "started_at": "2024-01-02T03:04:05Z",
+		"applied_at": "2024-01-02T03:04:05Z", "executed_by": "deployer", "error_msg": "boom", "async": true, "current_statement": 2, "repeatable": false}]}`
+	history, err := parseHistoryFile([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, history.Migrations, 1)
+	assert.Equal(t, entry, history.Migrations[0])
+}
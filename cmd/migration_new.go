@@ -12,7 +12,10 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/pjtatlow/scurry/internal/config"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
 	"github.com/pjtatlow/scurry/internal/ui"
 )
 
@@ -20,20 +23,45 @@ var migrationNewCmd = &cobra.Command{
 	Use:   "new",
 	Short: "Create a new migration manually",
 	Long: `Create a new migration by entering SQL statements manually.
-You will be prompted to enter SQL statements, which will be validated before creating the migration.`,
+You will be prompted to enter SQL statements, which will be validated before creating the migration.
+
+For changes scurry can't check ahead of time, such as data fixes, --empty
+creates the migration from a template to edit instead:
+  scurry migration new --empty --name fix_emails
+  scurry migration new --template backfill --table users --name backfill_emails
+
+Its header is filled in but left unsigned. Once the body is written, sign it
+with 'scurry migration validate --signatures=fix'.`,
 	RunE: migrationNew,
 }
 
+var (
+	migrationNewEmpty    bool
+	migrationNewTemplate string
+	migrationNewTable    string
+	migrationNewName     string
+)
+
 func init() {
 	migrationCmd.AddCommand(migrationNewCmd)
+
+	migrationNewCmd.Flags().BoolVar(&migrationNewEmpty, "empty", false, "Create the migration from a template instead of prompting for SQL")
+	migrationNewCmd.Flags().StringVar(&migrationNewTemplate, "template", "", fmt.Sprintf("Template for the migration body (%s); implies --empty", strings.Join(migrationpkg.TemplateNames, ", ")))
+	migrationNewCmd.Flags().StringVar(&migrationNewTable, "table", "", "Table the template works on")
+	migrationNewCmd.Flags().StringVar(&migrationNewName, "name", "", "Name for the migration (skips prompt)")
 }
 
 func migrationNew(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
-	err := doMigrationNew(ctx)
+	var err error
+	if migrationNewEmpty || migrationNewTemplate != "" {
+		err = doMigrationNewFromTemplate(ctx)
+	} else {
+		err = doMigrationNew(ctx)
+	}
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -54,9 +82,7 @@ func doMigrationNew(ctx context.Context) error {
 	}
 
 	// Load production schema from schema.sql
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Loading production schema from %s...", getSchemaFilePath())))
-	}
+	logging.Debug(fmt.Sprintf("→ Loading production schema from %s...", getSchemaFilePath()))
 
 	prodSchema, err := loadProductionSchema(ctx, fs)
 	if err != nil {
@@ -64,14 +90,14 @@ func doMigrationNew(ctx context.Context) error {
 	}
 
 	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views in production",
-			len(prodSchema.Tables), len(prodSchema.Types), len(prodSchema.Routines), len(prodSchema.Sequences), len(prodSchema.Views))))
-		fmt.Println()
+		logging.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views in production",
+			len(prodSchema.Tables), len(prodSchema.Types), len(prodSchema.Routines), len(prodSchema.Sequences), len(prodSchema.Views)))
+		logging.Newline()
 	}
 
 	// Ask user for SQL statements
 	var sqlStatements string
-	var migrationName string
+	migrationName := migrationNewName
 
 	form := huh.NewForm(
 		huh.NewGroup(
@@ -126,15 +152,15 @@ func doMigrationNew(ctx context.Context) error {
 	}
 
 	if flags.Verbose {
-		fmt.Println()
-		fmt.Println(ui.Header(fmt.Sprintf("Parsed %d statement(s):", len(statementStrings))))
+		logging.Newline()
+		logging.Header(fmt.Sprintf("Parsed %d statement(s):", len(statementStrings)))
 		for i, stmt := range statementStrings {
-			fmt.Printf("%s %s\n\n", ui.Info(fmt.Sprintf("%d.", i+1)), ui.SqlCode(stmt))
+			logging.Print(fmt.Sprintf("%s %s\n", ui.Info(fmt.Sprintf("%d.", i+1)), ui.SqlCode(stmt)))
 		}
 	}
 
 	// Apply migrations to get the new schema (for updating schema.sql)
-	fmt.Println(ui.Subtle("→ Applying migrations to schema..."))
+	logging.Subtle("→ Applying migrations to schema...")
 
 	newSchema, err := applyMigrationsToSchema(ctx, prodSchema, statementStrings)
 	if err != nil {
@@ -158,17 +184,17 @@ func doMigrationNew(ctx context.Context) error {
 	}
 
 	// Create migration directory and file
-	fmt.Println(ui.Subtle("→ Creating migration..."))
+	logging.Subtle("→ Creating migration...")
 
 	migrationDirName, _, err := createMigration(fs, migrationName, statementStrings, header)
 	if err != nil {
 		return fmt.Errorf("failed to create migration: %w", err)
 	}
 
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Created migration: %s", migrationDirName)))
+	logging.Success(fmt.Sprintf("✓ Created migration: %s", migrationDirName))
 
 	// Update production schema
-	fmt.Println(ui.Subtle("→ Updating production schema..."))
+	logging.Subtle("→ Updating production schema...")
 
 	// Dump new schema to schema.sql
 	err = dumpProductionSchema(ctx, fs, newSchema)
@@ -176,10 +202,118 @@ func doMigrationNew(ctx context.Context) error {
 		return fmt.Errorf("failed to update schema.sql: %w", err)
 	}
 
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath())))
+	logging.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath()))
+
+	logging.Newline()
+	logging.Info(("Migration created successfully!"))
+
+	return nil
+}
+
+// doMigrationNewFromTemplate writes a migration from a template, for the author
+// to fill in. The production schema is left alone: the body has no schema
+// changes yet.
+func doMigrationNewFromTemplate(ctx context.Context) error {
+	fs := afero.NewOsFs()
+
+	if err := validateMigrationsDir(fs); err != nil {
+		return err
+	}
+
+	templateName := migrationNewTemplate
+	if templateName == "" {
+		templateName = migrationpkg.TemplateEmpty
+	}
+
+	name := migrationNewName
+	if name == "" {
+		if !ui.IsInteractive() {
+			return fmt.Errorf("migration name is required (use --name)")
+		}
+		err := huh.NewInput().
+			Title("Migration name").
+			Description("Enter a descriptive name for this migration").
+			Value(&name).
+			Validate(func(s string) error {
+				if s == "" {
+					return fmt.Errorf("migration name cannot be empty")
+				}
+				return nil
+			}).
+			WithTheme(ui.HuhTheme()).
+			Run()
+		if err != nil {
+			return fmt.Errorf("migration input canceled: %w", err)
+		}
+	}
+
+	opts := migrationpkg.TemplateOptions{}
+	header := &migrationpkg.Header{Mode: migrationpkg.ModeSync}
+	existingMigrations, err := loadMigrations(fs)
+	if err != nil {
+		return fmt.Errorf("failed to load existing migrations: %w", err)
+	}
+
+	if migrationNewTable != "" {
+		prodSchema, err := loadProductionSchema(ctx, fs)
+		if err != nil {
+			return fmt.Errorf("failed to load production schema: %w", err)
+		}
+		schemaName, tableName, ok := strings.Cut(migrationNewTable, ".")
+		if !ok {
+			schemaName, tableName = "public", migrationNewTable
+		}
+		opts.Table = schemaName + "." + tableName
+		for _, t := range prodSchema.Tables {
+			if t.Schema != schemaName || t.Name != tableName {
+				continue
+			}
+			for _, def := range t.Ast.Defs {
+				if col, ok := def.(*tree.ColumnTableDef); ok {
+					opts.Column = col.Name.Normalize()
+					break
+				}
+			}
+		}
+		if opts.Column == "" {
+			return fmt.Errorf("table %s not found in %s", opts.Table, getSchemaFilePath())
+		}
+
+		migInfos := make([]migrationpkg.MigrationInfo, len(existingMigrations))
+		for i, m := range existingMigrations {
+			migInfos[i] = migrationpkg.MigrationInfo{Name: m.Name, SQL: m.SQL}
+		}
+		header.DependsOn = migrationpkg.FindTableDependencies([]string{opts.Table}, migInfos)
+
+		// Rewriting every row of a large table takes too long to hold up a deploy
+		tableSizes, err := migrationpkg.LoadTableSizes(fs, flags.MigrationDir)
+		if err != nil {
+			return fmt.Errorf("failed to load table_sizes.yaml: %w", err)
+		}
+		if templateName == migrationpkg.TemplateBackfill {
+			cfg, err := config.Load(fs, flags.ConfigFile)
+			if err != nil {
+				return err
+			}
+			warnTableSizes(tableSizes, cfg.Migrations.TableSizesMaxAge, []string{opts.Table})
+			if tableSizes.IsLargeTable(opts.Table) {
+				header.Mode = migrationpkg.ModeAsync
+			}
+		}
+	}
+
+	body, err := migrationpkg.RenderTemplate(templateName, opts)
+	if err != nil {
+		return err
+	}
+
+	migrationDirName, err := writeMigrationFile(fs, name, migrationpkg.FormatHeader(header)+"\n"+body)
+	if err != nil {
+		return fmt.Errorf("failed to create migration: %w", err)
+	}
 
-	fmt.Println()
-	fmt.Println(ui.Info(("Migration created successfully!")))
+	logging.Success(fmt.Sprintf("✓ Created migration: %s", migrationDirName))
+	logging.Info("Write the migration, then sign its header with 'scurry migration validate --signatures=fix'")
 
 	return nil
 }
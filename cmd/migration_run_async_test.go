@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestRunAsyncMigrations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.InitMigrationHistory(ctx))
+
+	migrations := []db.Migration{
+		{Name: "001_sync", SQL: "CREATE TABLE ra_sync (id INT PRIMARY KEY);", Checksum: "sync"},
+		// Depends on an async migration that comes after it, so it only runs
+		// once that one has
+		{Name: "002_async_later", SQL: "CREATE TABLE ra_later (id INT PRIMARY KEY);", Checksum: "later", Mode: db.MigrationModeAsync, DependsOn: []string{"003_async_first"}},
+		{Name: "003_async_first", SQL: "CREATE TABLE ra_first (id INT PRIMARY KEY);", Checksum: "first", Mode: db.MigrationModeAsync},
+		{Name: "004_async_blocked", SQL: "CREATE TABLE ra_blocked (id INT PRIMARY KEY);", Checksum: "blocked", Mode: db.MigrationModeAsync, DependsOn: []string{"001_sync"}},
+	}
+
+	executed, blocked, err := runAsyncMigrations(ctx, client, migrations)
+	require.NoError(t, err)
+	assert.Equal(t, 2, executed)
+	assert.Equal(t, []string{"004_async_blocked"}, blocked)
+
+	applied, err := client.GetAppliedMigrations(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, m := range applied {
+		names = append(names, m.Name)
+		assert.Equal(t, db.MigrationStatusSucceeded, m.Status)
+		assert.True(t, m.Async)
+	}
+	assert.Equal(t, []string{"002_async_later", "003_async_first"}, names)
+
+	// Once the sync migration is applied, the blocked one runs
+	require.NoError(t, client.RecordMigration(ctx, "001_sync", "sync", false))
+	executed, blocked, err = runAsyncMigrations(ctx, client, migrations)
+	require.NoError(t, err)
+	assert.Equal(t, 1, executed)
+	assert.Empty(t, blocked)
+}
+
+func TestRunAsyncMigrations_StopsOnFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.InitMigrationHistory(ctx))
+
+	migrations := []db.Migration{
+		{Name: "001_bad", SQL: "ALTER TABLE ra_missing ADD COLUMN x STRING;", Checksum: "bad", Mode: db.MigrationModeAsync},
+		{Name: "002_never", SQL: "CREATE TABLE ra_never (id INT PRIMARY KEY);", Checksum: "never", Mode: db.MigrationModeAsync},
+	}
+
+	executed, _, err := runAsyncMigrations(ctx, client, migrations)
+	require.Error(t, err)
+	assert.Equal(t, 0, executed)
+
+	m, err := client.GetMigration(ctx, "002_never")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+}
+
+func TestRunAsyncMigrations_HoldsLock(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.InitMigrationHistory(ctx))
+
+	migrations := []db.Migration{
+		{Name: "001_async", SQL: "CREATE TABLE ra_locked (id INT PRIMARY KEY);", Checksum: "locked", Mode: db.MigrationModeAsync},
+	}
+
+	// Another process running migrations keeps this one from starting
+	lock, err := client.AcquireMigrationLock(ctx, db.LockOptions{Owner: "other"})
+	require.NoError(t, err)
+
+	executed, _, err := runAsyncMigrations(ctx, client, migrations)
+	var heldErr *db.LockHeldError
+	require.ErrorAs(t, err, &heldErr)
+	assert.Equal(t, 0, executed)
+
+	m, err := client.GetMigration(ctx, "001_async")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	require.NoError(t, lock.Release(ctx))
+	executed, _, err = runAsyncMigrations(ctx, client, migrations)
+	require.NoError(t, err)
+	assert.Equal(t, 1, executed)
+}
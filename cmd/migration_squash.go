@@ -14,6 +14,7 @@ import (
 
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
 	"github.com/pjtatlow/scurry/internal/schema"
 	"github.com/pjtatlow/scurry/internal/ui"
@@ -63,7 +64,7 @@ func runMigrationSquash(cmd *cobra.Command, args []string) error {
 	}
 	err = doMigrationSquash(cmd.Context(), afero.NewOsFs(), squashBefore)
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 	return nil
@@ -121,9 +122,7 @@ func doMigrationSquash(ctx context.Context, fs afero.Fs, squashBefore time.Durat
 	// Calculate cutoff time
 	cutoff := time.Now().Add(-squashBefore)
 
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Cutoff time: %s", cutoff.Format(time.RFC3339))))
-	}
+	logging.Debug(fmt.Sprintf("→ Cutoff time: %s", cutoff.Format(time.RFC3339)))
 
 	// Find migrations before cutoff by parsing timestamps from directory names
 	var toSquash []int // indices into migrations slice
@@ -131,7 +130,7 @@ func doMigrationSquash(ctx context.Context, fs afero.Fs, squashBefore time.Durat
 		ts, err := parseMigrationTimestamp(mig.Name)
 		if err != nil {
 			if flags.Verbose {
-				fmt.Println(ui.Warning(fmt.Sprintf("  Skipping %s: could not parse timestamp: %v", mig.Name, err)))
+				logging.Warning(fmt.Sprintf("  Skipping %s: could not parse timestamp: %v", mig.Name, err))
 			}
 			continue
 		}
@@ -145,11 +144,11 @@ func doMigrationSquash(ctx context.Context, fs afero.Fs, squashBefore time.Durat
 	}
 
 	// Display what will be squashed
-	fmt.Println(ui.Header(fmt.Sprintf("Migrations to squash (%d):", len(toSquash))))
+	logging.Header(fmt.Sprintf("Migrations to squash (%d):", len(toSquash)))
 	for _, idx := range toSquash {
-		fmt.Printf("  - %s\n", migrations[idx].Name)
+		logging.Print(fmt.Sprintf("  - %s", migrations[idx].Name))
 	}
-	fmt.Println()
+	logging.Newline()
 
 	// Confirm unless --force
 	if !flags.Force {
@@ -161,15 +160,13 @@ func doMigrationSquash(ctx context.Context, fs afero.Fs, squashBefore time.Durat
 			return err
 		}
 		if !confirmed {
-			fmt.Println(ui.Info("Aborted"))
+			logging.Info("Aborted")
 			return nil
 		}
 	}
 
 	// Apply squashed migrations to a shadow database to get the final schema state
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Applying squashed migrations to get final schema..."))
-	}
+	logging.Debug("→ Applying squashed migrations to get final schema...")
 
 	squashedMigrations := make([]db.Migration, len(toSquash))
 	for i, idx := range toSquash {
@@ -204,9 +201,7 @@ func doMigrationSquash(ctx context.Context, fs afero.Fs, squashBefore time.Durat
 	content := migrationpkg.FormatHeader(header) + "\n" + squashedSQL
 
 	// Create squash migration directory and file
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Creating squash migration..."))
-	}
+	logging.Debug("→ Creating squash migration...")
 
 	if err := fs.MkdirAll(squashDir, 0755); err != nil {
 		return fmt.Errorf("failed to create squash migration directory: %w", err)
@@ -218,24 +213,20 @@ func doMigrationSquash(ctx context.Context, fs afero.Fs, squashBefore time.Durat
 	}
 
 	// Delete old migration directories
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Removing squashed migrations..."))
-	}
+	logging.Debug("→ Removing squashed migrations...")
 
 	for _, idx := range toSquash {
 		oldDir := filepath.Join(flags.MigrationDir, migrations[idx].Name)
 		if err := fs.RemoveAll(oldDir); err != nil {
 			return fmt.Errorf("failed to remove migration directory %s: %w", migrations[idx].Name, err)
 		}
-		if flags.Verbose {
-			fmt.Println(ui.Subtle(fmt.Sprintf("  Removed %s", migrations[idx].Name)))
-		}
+		logging.Debug(fmt.Sprintf("  Removed %s", migrations[idx].Name))
 	}
 
-	fmt.Println()
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Squashed %d migrations into %s", len(toSquash), squashName)))
-	fmt.Println()
-	fmt.Println(ui.Info("Run 'scurry migration validate --overwrite' to update schema.sql and checkpoints"))
+	logging.Newline()
+	logging.Success(fmt.Sprintf("✓ Squashed %d migrations into %s", len(toSquash), squashName))
+	logging.Newline()
+	logging.Info("Run 'scurry migration validate --overwrite' to update schema.sql and checkpoints")
 
 	return nil
 }
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+func TestBuildMigrationStatus(t *testing.T) {
+	started := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	completed := started.Add(1500 * time.Millisecond)
+	errMsg := "relation does not exist"
+
+	migrations := []db.Migration{
+		{Name: "001_applied", Checksum: "a"},
+		{Name: "002_modified", Checksum: "b-new"},
+		{Name: "003_failed", Checksum: "c"},
+		{Name: "004_running", Checksum: "d", Mode: db.MigrationModeAsync},
+		{Name: "005_pending", Checksum: "e"},
+	}
+	applied := []db.AppliedMigration{
+		{Name: "000_removed", Checksum: "z", Status: db.MigrationStatusSucceeded, AppliedAt: started, ExecutedBy: "root"},
+		{Name: "001_applied", Checksum: "a", Status: db.MigrationStatusSucceeded, StartedAt: &started, CompletedAt: &completed, AppliedAt: started, ExecutedBy: "root"},
+		{Name: "002_modified", Checksum: "b", Status: db.MigrationStatusSucceeded, AppliedAt: started, ExecutedBy: "root"},
+		{Name: "003_failed", Checksum: "c", Status: db.MigrationStatusFailed, StartedAt: &started, CompletedAt: &completed, AppliedAt: started, ExecutedBy: "root", ErrorMsg: &errMsg},
+		{Name: "004_running", Checksum: "d", Status: db.MigrationStatusPending, StartedAt: &started, AppliedAt: started, ExecutedBy: "root", Async: true},
+	}
+
+	statuses := buildMigrationStatus(migrations, applied)
+	require.Len(t, statuses, 6)
+
+	byName := make(map[string]migrationStatus)
+	var names []string
+	for _, s := range statuses {
+		byName[s.Name] = s
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"001_applied", "002_modified", "003_failed", "004_running", "005_pending", "000_removed"}, names)
+
+	s := byName["001_applied"]
+	assert.Equal(t, migrationStateApplied, s.State)
+	assert.Equal(t, "sync", s.Mode)
+	require.NotNil(t, s.ChecksumMatch)
+	assert.True(t, *s.ChecksumMatch)
+	require.NotNil(t, s.AppliedAt)
+	assert.Equal(t, completed, *s.AppliedAt)
+	require.NotNil(t, s.DurationMs)
+	assert.Equal(t, int64(1500), *s.DurationMs)
+	assert.Equal(t, "root", s.ExecutedBy)
+
+	s = byName["002_modified"]
+	require.NotNil(t, s.ChecksumMatch)
+	assert.False(t, *s.ChecksumMatch)
+
+	s = byName["003_failed"]
+	assert.Equal(t, migrationStateFailed, s.State)
+	assert.Equal(t, errMsg, s.Error)
+
+	s = byName["004_running"]
+	assert.Equal(t, migrationStateAsyncRunning, s.State)
+	assert.Equal(t, "async", s.Mode)
+	assert.Nil(t, s.AppliedAt)
+	assert.Nil(t, s.DurationMs)
+
+	s = byName["005_pending"]
+	assert.Equal(t, migrationStatePending, s.State)
+	assert.Nil(t, s.ChecksumMatch)
+	assert.Nil(t, s.AppliedAt)
+
+	assert.Equal(t, migrationStateNotOnDisk, byName["000_removed"].State)
+
+	assert.Equal(t, "2 applied, 1 pending, 1 async-running, 1 failed, 1 not-on-disk", summarizeMigrationStatus(statuses))
+}
+
+func TestRenderMigrationStatus(t *testing.T) {
+	ui.SetNoColor(true)
+	defer ui.SetNoColor(false)
+
+	duration := int64(1500)
+	match := false
+	jobID := int64(987)
+	progress := 0.45
+	statuses := []migrationStatus{
+		{Name: "001_create_users", Mode: "sync", State: migrationStateApplied, ChecksumMatch: &match, ExecutedBy: "root", DurationMs: &duration},
+		{Name: "002_add_index", Mode: "async", State: migrationStatePending},
+		{Name: "003_backfill_index", Mode: "async", State: migrationStateAsyncRunning, JobID: &jobID, JobStatus: "running", Progress: &progress},
+	}
+
+	out := renderMigrationStatus(statuses)
+	assert.Contains(t, out, "Migration")
+	assert.Contains(t, out, "001_create_users")
+	assert.Contains(t, out, "modified")
+	assert.Contains(t, out, "1.5s")
+	assert.Contains(t, out, "002_add_index")
+	assert.Contains(t, out, "pending")
+	assert.Contains(t, out, "async-running (job 987 running, 45%)")
+}
+
+func TestWriteMigrationStatusJSON(t *testing.T) {
+	duration := int64(20)
+	statuses := []migrationStatus{
+		{Name: "001_create_users", Mode: "sync", State: migrationStateApplied, ExecutedBy: "root", DurationMs: &duration},
+		{Name: "002_add_index", Mode: "async", State: migrationStatePending},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeMigrationStatusJSON(&buf, statuses))
+
+	var decoded []map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded, 2)
+	assert.Equal(t, "applied", decoded[0]["state"])
+	assert.Equal(t, float64(20), decoded[0]["duration_ms"])
+	assert.Equal(t, "pending", decoded[1]["state"])
+	assert.NotContains(t, decoded[1], "applied_at")
+}
@@ -3,24 +3,37 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
-	"github.com/pjtatlow/scurry/internal/ui"
+	"github.com/pjtatlow/scurry/internal/schema"
 )
 
-var largeTableThreshold int64
+var (
+	largeTableThreshold int64
+	tableSizesMerge     bool
+)
 
 var migrationStatPullCmd = &cobra.Command{
 	Use:   "table-sizes",
 	Short: "Fetch table statistics from the database and write table_sizes.yaml",
 	Long: `Query the database for table row counts and sizes, then write
 the results to migrations/table_sizes.yaml. This file is used by
-'scurry migration gen' to classify migrations as sync or async.`,
+'scurry migration gen' to classify migrations as sync or async.
+
+With --merge, the tables already in the file keep their sizes, and only the
+tables missing from it are added from the database, recording where and when
+each was captured. Use it to fill in new tables without replacing sizes
+captured from production.
+
+Same as 'scurry table-sizes refresh'.`,
 	RunE: runMigrationStatPull,
 }
 
@@ -29,6 +42,7 @@ func init() {
 
 	flags.AddDbUrl(migrationStatPullCmd)
 	migrationStatPullCmd.Flags().Int64Var(&largeTableThreshold, "large-table-threshold", int64(migrationpkg.DefaultLargeTableThreshold), "Row count threshold for classifying tables as large")
+	migrationStatPullCmd.Flags().BoolVar(&tableSizesMerge, "merge", false, "Only add the tables missing from table_sizes.yaml, keeping the sizes it has")
 }
 
 func runMigrationStatPull(cmd *cobra.Command, args []string) error {
@@ -55,21 +69,26 @@ func doMigrationStatPull(ctx context.Context) error {
 	defer dbClient.Close()
 
 	// Fetch table sizes
-	tableSizes, err := dbClient.GetTableSizes(ctx)
+	ts, err := liveTableSizes(ctx, dbClient, largeTableThreshold)
 	if err != nil {
-		return fmt.Errorf("failed to get table sizes: %w", err)
-	}
-
-	// Build TableSizes struct
-	ts := &migrationpkg.TableSizes{
-		Threshold: largeTableThreshold,
-		Tables:    make(map[string]migrationpkg.TableInfo, len(tableSizes)),
+		return err
 	}
 
-	for _, t := range tableSizes {
-		qualifiedName := fmt.Sprintf("%s.%s", t.SchemaName, t.TableName)
-		ts.Tables[qualifiedName] = migrationpkg.TableInfo{
-			Rows: t.Rows,
+	if tableSizesMerge {
+		existing, err := migrationpkg.LoadTableSizes(fs, flags.MigrationDir)
+		if err != nil {
+			return fmt.Errorf("failed to load table_sizes.yaml: %w", err)
+		}
+		if existing != nil {
+			added := existing.Merge(ts)
+			if err := migrationpkg.SaveTableSizes(fs, flags.MigrationDir, existing); err != nil {
+				return fmt.Errorf("failed to save table_sizes.yaml: %w", err)
+			}
+			logging.Success(fmt.Sprintf("✓ Added %d table(s) to table_sizes.yaml from %s", len(added), ts.Source))
+			for _, name := range added {
+				logging.Subtle(fmt.Sprintf("  %s", name))
+			}
+			return nil
 		}
 	}
 
@@ -78,7 +97,39 @@ func doMigrationStatPull(ctx context.Context) error {
 		return fmt.Errorf("failed to save table_sizes.yaml: %w", err)
 	}
 
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Wrote table_sizes.yaml with %d table(s) (threshold: %d rows)", len(tableSizes), largeTableThreshold)))
+	logging.Success(fmt.Sprintf("✓ Wrote table_sizes.yaml with %d table(s) (threshold: %d rows)", len(ts.Tables), largeTableThreshold))
 
 	return nil
 }
+
+// warnTableSizes warns when classification may be off because the table sizes
+// are older than maxAge, or have no size for tables the migration changes.
+// Without table sizes every change is sync by design, so there's nothing to warn about.
+func warnTableSizes(ts *migrationpkg.TableSizes, maxAge time.Duration, tables []string) {
+	if ts == nil {
+		return
+	}
+	if maxAge <= 0 {
+		maxAge = migrationpkg.DefaultTableSizesMaxAge
+	}
+	if warning := ts.StaleWarning(maxAge, time.Now()); warning != "" {
+		logging.Warning(fmt.Sprintf("⚠ %s. Refresh it with 'scurry table-sizes refresh'.", warning))
+	}
+	for _, name := range ts.MissingTables(tables) {
+		logging.Warning(fmt.Sprintf("⚠ table_sizes.yaml has no size for %s, so its changes are classified as if it were small. Add it with 'scurry table-sizes refresh --merge'.", name))
+	}
+}
+
+// changedTables returns the existing tables the differences change, in order
+func changedTables(diffs []schema.Difference) []string {
+	var tables []string
+	for _, diff := range diffs {
+		switch diff.Type {
+		case schema.DiffTypeTableModified, schema.DiffTypeTableColumnModified, schema.DiffTypeColumnTypeChanged:
+			if !slices.Contains(tables, diff.ObjectName) {
+				tables = append(tables, diff.ObjectName)
+			}
+		}
+	}
+	return tables
+}
@@ -14,8 +14,10 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/pjtatlow/scurry/internal/config"
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
 	"github.com/pjtatlow/scurry/internal/schema"
 	"github.com/pjtatlow/scurry/internal/ui"
@@ -47,6 +49,7 @@ func init() {
 	migrationValidateCmd.Flags().BoolVar(&validateOverwrite, "overwrite", false, "Overwrite schema.sql with the result instead of comparing")
 	migrationValidateCmd.Flags().BoolVar(&validateNoCheckpoint, "no-checkpoint", false, "Skip checkpoint generation after successful validation")
 	migrationValidateCmd.Flags().StringVar(&validateSignatures, "signatures", signaturesNoVerify, "Signature handling mode: no-verify, verify, require, or fix")
+	_ = migrationValidateCmd.RegisterFlagCompletionFunc("signatures", flags.Choices(signaturesNoVerify, signaturesVerify, signaturesRequire, signaturesFix))
 }
 
 func migrationValidate(cmd *cobra.Command, args []string) error {
@@ -54,7 +57,7 @@ func migrationValidate(cmd *cobra.Command, args []string) error {
 
 	err := doMigrationValidate(ctx)
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -74,26 +77,24 @@ func doMigrationValidate(ctx context.Context) error {
 
 	// Validate schema.sql exists and is parseable (skip in overwrite mode)
 	if !validateOverwrite {
-		if flags.Verbose {
-			fmt.Println(ui.Subtle("→ Validating schema.sql..."))
-		}
+		logging.Debug("→ Validating schema.sql...")
 		if err := validateSchemaFile(ctx, fs); err != nil {
 			return err
 		}
 	}
 
 	// 1. Load all migration files in order
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Loading migrations..."))
-	}
+	logging.Debug("→ Loading migrations...")
 
 	migrations, err := loadMigrations(fs)
 	if err != nil {
 		return fmt.Errorf("failed to load migrations: %w", err)
 	}
 
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d migration(s)", len(migrations))))
+	logging.Debug(fmt.Sprintf("  Found %d migration(s)", len(migrations)))
+
+	if _, err := migrationpkg.PlanExecution(migrations); err != nil {
+		return err
 	}
 
 	signaturesOnly, err := handleMigrationSignatures(fs, migrations, validateSignatures)
@@ -105,37 +106,35 @@ func doMigrationValidate(ctx context.Context) error {
 	}
 
 	// 2. Apply migrations to empty shadow database
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Applying migrations to clean database..."))
-	}
+	logging.Debug("→ Applying migrations to clean database...")
 
+	replayStart := time.Now()
 	resultSchema, err := applyMigrationsToCleanDatabase(ctx, migrations, flags.Verbose)
 	if err != nil {
 		return fmt.Errorf("failed to apply migrations: %w", err)
 	}
+	replayTime := time.Since(replayStart)
 
 	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Result: %d tables, %d types, %d routines, %d sequences, %d views",
-			len(resultSchema.Tables), len(resultSchema.Types), len(resultSchema.Routines), len(resultSchema.Sequences), len(resultSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Result: %d tables, %d types, %d routines, %d sequences, %d views",
+			len(resultSchema.Tables), len(resultSchema.Types), len(resultSchema.Routines), len(resultSchema.Sequences), len(resultSchema.Views)))
 	}
 
 	// 3. Handle overwrite flag
 	if validateOverwrite {
-		if flags.Verbose {
-			fmt.Println(ui.Subtle("→ Overwriting schema.sql..."))
-		}
+		logging.Debug("→ Overwriting schema.sql...")
 
 		err = dumpProductionSchema(ctx, fs, resultSchema)
 		if err != nil {
 			return fmt.Errorf("failed to write schema.sql: %w", err)
 		}
 
-		fmt.Println()
-		fmt.Println(ui.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath())))
+		logging.Newline()
+		logging.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath()))
 
 		// Create checkpoint for the last migration if it doesn't exist
 		if !validateNoCheckpoint && len(migrations) > 0 {
-			if err := ensureCheckpointForLastMigration(fs, migrations, resultSchema, flags.Verbose); err != nil {
+			if err := ensureCheckpointForLastMigration(fs, migrations, resultSchema, replayTime, flags.Verbose); err != nil {
 				return fmt.Errorf("failed to create checkpoint: %w", err)
 			}
 		}
@@ -144,9 +143,7 @@ func doMigrationValidate(ctx context.Context) error {
 	}
 
 	// 4. Load expected schema from schema.sql
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Loading expected schema from %s...", getSchemaFilePath())))
-	}
+	logging.Debug(fmt.Sprintf("→ Loading expected schema from %s...", getSchemaFilePath()))
 
 	expectedSchema, err := loadProductionSchema(ctx, fs)
 	if err != nil {
@@ -154,26 +151,26 @@ func doMigrationValidate(ctx context.Context) error {
 	}
 
 	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Expected: %d tables, %d types, %d routines, %d sequences, %d views",
-			len(expectedSchema.Tables), len(expectedSchema.Types), len(expectedSchema.Routines), len(expectedSchema.Sequences), len(expectedSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Expected: %d tables, %d types, %d routines, %d sequences, %d views",
+			len(expectedSchema.Tables), len(expectedSchema.Types), len(expectedSchema.Routines), len(expectedSchema.Sequences), len(expectedSchema.Views)))
 	}
 
 	// 5. Compare schemas
 	if flags.Verbose {
-		fmt.Println()
-		fmt.Println(ui.Subtle("→ Comparing schemas..."))
+		logging.Newline()
+		logging.Subtle("→ Comparing schemas...")
 	}
 
 	diffResult := schema.Compare(resultSchema, expectedSchema)
 
 	// 6. Check for differences
 	if !diffResult.HasChanges() {
-		fmt.Println()
-		fmt.Println(ui.Success("✓ Migrations match schema.sql"))
+		logging.Newline()
+		logging.Success("✓ Migrations match schema.sql")
 
 		// Create checkpoint for the last migration if it doesn't exist
 		if !validateNoCheckpoint && len(migrations) > 0 {
-			if err := ensureCheckpointForLastMigration(fs, migrations, resultSchema, flags.Verbose); err != nil {
+			if err := ensureCheckpointForLastMigration(fs, migrations, resultSchema, replayTime, flags.Verbose); err != nil {
 				return fmt.Errorf("failed to create checkpoint: %w", err)
 			}
 		}
@@ -182,12 +179,12 @@ func doMigrationValidate(ctx context.Context) error {
 	}
 
 	// Show differences
-	fmt.Println()
-	fmt.Println(ui.Error("✗ Migrations do not match schema.sql"))
-	fmt.Println()
-	fmt.Println(ui.Header("Differences found:"))
-	fmt.Println(diffResult.Summary())
-	fmt.Println()
+	logging.Newline()
+	logging.Error("✗ Migrations do not match schema.sql")
+	logging.Newline()
+	logging.Header("Differences found:")
+	logging.Print(diffResult.Summary())
+	logging.Newline()
 
 	// Prompt user to overwrite schema.sql
 	shouldOverwrite, err := ui.ConfirmPrompt("Do you want to overwrite schema.sql with the migration results?")
@@ -200,20 +197,18 @@ func doMigrationValidate(ctx context.Context) error {
 	}
 
 	// User chose to overwrite
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Overwriting schema.sql..."))
-	}
+	logging.Debug("→ Overwriting schema.sql...")
 
 	err = dumpProductionSchema(ctx, fs, resultSchema)
 	if err != nil {
 		return fmt.Errorf("failed to write schema.sql: %w", err)
 	}
 
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath())))
+	logging.Success(fmt.Sprintf("✓ Updated %s", getSchemaFilePath()))
 
 	// Create checkpoint for the last migration if it doesn't exist
 	if !validateNoCheckpoint && len(migrations) > 0 {
-		if err := ensureCheckpointForLastMigration(fs, migrations, resultSchema, flags.Verbose); err != nil {
+		if err := ensureCheckpointForLastMigration(fs, migrations, resultSchema, replayTime, flags.Verbose); err != nil {
 			return fmt.Errorf("failed to create checkpoint: %w", err)
 		}
 	}
@@ -268,8 +263,13 @@ func validateSchemaFile(ctx context.Context, fs afero.Fs) error {
 // Headers are parsed for mode/depends_on and stripped from the SQL content.
 // Checksums are computed on the header-stripped SQL.
 func loadMigrations(fs afero.Fs) ([]db.Migration, error) {
+	return loadMigrationsFrom(fs, flags.MigrationDir)
+}
+
+// loadMigrationsFrom reads the migrations in migrationDir, in timestamp order
+func loadMigrationsFrom(fs afero.Fs, migrationDir string) ([]db.Migration, error) {
 	// Read migrations directory
-	entries, err := afero.ReadDir(fs, flags.MigrationDir)
+	entries, err := afero.ReadDir(fs, migrationDir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return []db.Migration{}, nil
@@ -298,7 +298,7 @@ func loadMigrations(fs afero.Fs) ([]db.Migration, error) {
 	// Read migration.sql from each directory
 	var allMigrations []db.Migration
 	for _, dir := range migrationDirs {
-		migrationFile := filepath.Join(flags.MigrationDir, dir, "migration.sql")
+		migrationFile := filepath.Join(migrationDir, dir, "migration.sql")
 
 		// Check if migration.sql exists
 		exists, err := afero.Exists(fs, migrationFile)
@@ -322,7 +322,7 @@ func loadMigrations(fs afero.Fs) ([]db.Migration, error) {
 		mode := db.MigrationModeSync
 		header, headerErr := migrationpkg.ParseHeader(sql)
 		if headerErr != nil {
-			fmt.Println(ui.Warning(fmt.Sprintf("Invalid header in %s: %v (defaulting to sync)", dir, headerErr)))
+			logging.Warning(fmt.Sprintf("Invalid header in %s: %v (defaulting to sync)", dir, headerErr))
 		}
 		if header != nil {
 			mode = string(header.Mode)
@@ -339,19 +339,89 @@ func loadMigrations(fs afero.Fs) ([]db.Migration, error) {
 
 		squash := header != nil && header.Squash
 
+		var refreshStats []string
+		var settings []db.SessionSetting
+		if header != nil {
+			refreshStats = header.RefreshStats
+			settings = header.Settings
+		}
+
 		allMigrations = append(allMigrations, db.Migration{
-			Name:      dir,
-			SQL:       strippedSQL,
-			Checksum:  checksum,
-			Mode:      mode,
-			DependsOn: dependsOn,
-			Squash:    squash,
+			Name:            dir,
+			SQL:             strippedSQL,
+			Checksum:        checksum,
+			LogicalChecksum: db.LogicalChecksum(strippedSQL),
+			Mode:            mode,
+			DependsOn:       dependsOn,
+			Squash:          squash,
+			RefreshStats:    refreshStats,
+			Settings:        settings,
 		})
 	}
 
 	return allMigrations, nil
 }
 
+// repeatablePrefix starts the file name of a repeatable migration
+const repeatablePrefix = "R__"
+
+// loadRepeatableMigrations loads the repeatable migrations (R__name.sql files at the
+// top of the migrations directory), sorted by name. They're named after their file,
+// without the extension.
+func loadRepeatableMigrations(fs afero.Fs) ([]db.Migration, error) {
+	entries, err := afero.ReadDir(fs, flags.MigrationDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var migrations []db.Migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, repeatablePrefix) || filepath.Ext(name) != ".sql" {
+			continue
+		}
+		content, err := afero.ReadFile(fs, filepath.Join(flags.MigrationDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read repeatable migration %s: %w", name, err)
+		}
+		migrations = append(migrations, db.Migration{
+			Name:       strings.TrimSuffix(name, ".sql"),
+			SQL:        string(content),
+			Checksum:   computeChecksum(string(content)),
+			Mode:       db.MigrationModeSync,
+			Repeatable: true,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Name < migrations[j].Name
+	})
+	return migrations, nil
+}
+
+// withRepeatableMigrations returns s with the repeatable migrations applied, since
+// production runs them after every deploy. Objects they define then match their
+// definitions instead of showing up as changes.
+func withRepeatableMigrations(ctx context.Context, fs afero.Fs, s *schema.Schema) (*schema.Schema, error) {
+	repeatables, err := loadRepeatableMigrations(fs)
+	if err != nil || len(repeatables) == 0 {
+		return s, err
+	}
+
+	var statements []string
+	for _, m := range repeatables {
+		stmts, err := db.SplitStatements(m.SQL)
+		if err != nil {
+			return nil, fmt.Errorf("repeatable migration %s: %w", m.Name, err)
+		}
+		statements = append(statements, stmts...)
+	}
+	return applyMigrationsToSchema(ctx, s, statements)
+}
+
 // applyMigrationsToCleanDatabase creates a clean shadow database and applies all migrations
 // It uses checkpoints to optimize validation when available
 func applyMigrationsToCleanDatabase(ctx context.Context, migrations []db.Migration, showProgress bool) (*schema.Schema, error) {
@@ -360,7 +430,23 @@ func applyMigrationsToCleanDatabase(ctx context.Context, migrations []db.Migrati
 	// Try to find a valid checkpoint to skip some migrations
 	checkpoint, checkpointIdx, err := findLatestValidCheckpoint(fs, migrations)
 	if err != nil && showProgress {
-		fmt.Println(ui.Warning(fmt.Sprintf("  Warning: error finding checkpoint: %v", err)))
+		logging.Warning(fmt.Sprintf("  Warning: error finding checkpoint: %v", err))
+	}
+
+	// Another machine may have already replayed all of the migrations
+	cache, err := openCheckpointCache()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint cache: %w", err)
+	}
+	if cache != nil && checkpointIdx < len(migrations)-1 {
+		cached, err := getCachedCheckpoint(ctx, cache, migrations)
+		switch {
+		case err != nil:
+			logging.Warning(fmt.Sprintf("  Warning: error reading checkpoint cache: %v", err))
+		case cached != nil:
+			checkpoint, checkpointIdx = cached, len(migrations)-1
+			storeLocalCheckpoint(ctx, fs, checkpoint, migrations)
+		}
 	}
 
 	var client *db.Client
@@ -369,8 +455,8 @@ func applyMigrationsToCleanDatabase(ctx context.Context, migrations []db.Migrati
 	if checkpoint != nil {
 		// Use checkpoint as starting point
 		if showProgress {
-			fmt.Println(ui.Subtle(fmt.Sprintf("  Using checkpoint from %s (skipping %d migration(s))",
-				checkpoint.MigrationName, checkpointIdx+1)))
+			logging.Subtle(fmt.Sprintf("  Using checkpoint from %s (skipping %d migration(s))",
+				checkpoint.MigrationName, checkpointIdx+1))
 		}
 
 		// Parse checkpoint schema content
@@ -378,7 +464,7 @@ func applyMigrationsToCleanDatabase(ctx context.Context, migrations []db.Migrati
 		if parseErr != nil {
 			// Fall back to full validation if checkpoint can't be parsed
 			if showProgress {
-				fmt.Println(ui.Warning(fmt.Sprintf("  Warning: failed to parse checkpoint, starting from scratch: %v", parseErr)))
+				logging.Warning(fmt.Sprintf("  Warning: failed to parse checkpoint, starting from scratch: %v", parseErr))
 			}
 			checkpoint = nil
 		} else {
@@ -399,7 +485,7 @@ func applyMigrationsToCleanDatabase(ctx context.Context, migrations []db.Migrati
 	if checkpoint == nil {
 		// No valid checkpoint, start from scratch
 		if showProgress && len(migrations) > 0 {
-			fmt.Println(ui.Subtle("  No valid checkpoint found, starting from empty database"))
+			logging.Subtle("  No valid checkpoint found, starting from empty database")
 		}
 
 		client, err = db.GetShadowDB(ctx)
@@ -414,16 +500,16 @@ func applyMigrationsToCleanDatabase(ctx context.Context, migrations []db.Migrati
 	for i := startIndex; i < len(migrations); i++ {
 		mig := migrations[i]
 		if showProgress {
-			fmt.Println(ui.Subtle(fmt.Sprintf("  Applying migration %d/%d: %s", i+1, len(migrations), mig.Name)))
+			logging.Subtle(fmt.Sprintf("  Applying migration %d/%d: %s", i+1, len(migrations), mig.Name))
 		}
 
 		start := time.Now()
 
 		// Split the migration SQL into individual statements before executing.
 		// ExecuteBulkDDL expects individual statements, not a single blob.
-		statements, splitErr := db.SplitStatements(mig.SQL)
+		statements, splitErr := db.MigrationStatements(mig, true)
 		if splitErr != nil {
-			return nil, fmt.Errorf("failed to parse migration %s: %w", mig.Name, splitErr)
+			return nil, splitErr
 		}
 
 		// Squash migrations contain clean CREATE statements (a schema snapshot),
@@ -444,20 +530,50 @@ func applyMigrationsToCleanDatabase(ctx context.Context, migrations []db.Migrati
 		}
 
 		if showProgress {
-			fmt.Println(ui.Success(fmt.Sprintf("    ✓ Completed in %v", duration.Round(time.Millisecond))))
+			logging.Success(fmt.Sprintf("    ✓ Completed in %v", duration.Round(time.Millisecond)))
 		}
 	}
 
 	// Get the schema from the database
-	return schema.LoadFromDatabase(ctx, client)
+	resultSchema, err := schema.LoadFromDatabase(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the replay for next time, and share it unless it started from the
+	// shared cache's checkpoint
+	if startIndex < len(migrations) {
+		if local, err := openLocalCheckpointCache(fs); err != nil {
+			logging.Debug(fmt.Sprintf("  Not using the local checkpoint cache: %v", err))
+		} else if err := putCachedCheckpoint(ctx, local, migrations, resultSchema); err != nil {
+			logging.Debug(fmt.Sprintf("  Failed to write the local checkpoint cache: %v", err))
+		}
+
+		if cache != nil {
+			if err := putCachedCheckpoint(ctx, cache, migrations, resultSchema); err != nil {
+				logging.Warning(fmt.Sprintf("  Warning: error writing checkpoint cache: %v", err))
+			} else if showProgress {
+				logging.Subtle("  Stored checkpoint in the checkpoint cache")
+			}
+		}
+	}
+	return resultSchema, nil
 }
 
-// ensureCheckpointForLastMigration creates a checkpoint for the last migration if one doesn't exist
-func ensureCheckpointForLastMigration(fs afero.Fs, migrations []db.Migration, resultSchema *schema.Schema, showProgress bool) error {
+// ensureCheckpointForLastMigration creates a checkpoint for the last migration if
+// one doesn't exist and migrations.checkpoints in the config file calls for one
+// after replaying the migrations for replayTime, then prunes superseded checkpoints
+func ensureCheckpointForLastMigration(fs afero.Fs, migrations []db.Migration, resultSchema *schema.Schema, replayTime time.Duration, showProgress bool) error {
 	if len(migrations) == 0 {
 		return nil
 	}
 
+	cfg, err := config.Load(fs, flags.ConfigFile)
+	if err != nil {
+		return err
+	}
+	policy := cfg.Migrations.Checkpoints
+
 	lastMigration := migrations[len(migrations)-1]
 	migrationDir := filepath.Join(flags.MigrationDir, lastMigration.Name)
 
@@ -466,7 +582,7 @@ func ensureCheckpointForLastMigration(fs afero.Fs, migrations []db.Migration, re
 	if err != nil {
 		// Error loading checkpoint, try to create a new one
 		if showProgress {
-			fmt.Println(ui.Subtle(fmt.Sprintf("→ Checkpoint error for %s, regenerating...", lastMigration.Name)))
+			logging.Subtle(fmt.Sprintf("→ Checkpoint error for %s, regenerating...", lastMigration.Name))
 		}
 	} else if checkpoint != nil {
 		// Checkpoint exists, validate it
@@ -474,17 +590,25 @@ func ensureCheckpointForLastMigration(fs afero.Fs, migrations []db.Migration, re
 		if checkpoint.Header.MigrationsHash == expectedHash {
 			if err := validateCheckpoint(checkpoint); err == nil {
 				// Checkpoint is valid, nothing to do
-				return nil
+				return pruneSupersededCheckpoints(fs, migrations, policy.Keep, showProgress)
 			}
 		}
 		// Checkpoint exists but is invalid, regenerate it
 		if showProgress {
-			fmt.Println(ui.Subtle(fmt.Sprintf("→ Checkpoint invalid for %s, regenerating...", lastMigration.Name)))
+			logging.Subtle(fmt.Sprintf("→ Checkpoint invalid for %s, regenerating...", lastMigration.Name))
 		}
 	} else {
-		// No checkpoint exists
+		// No checkpoint exists; only write one when it's due
+		sinceCheckpoint := len(migrations) - 1 - latestCheckpointFile(fs, migrations)
+		if !policy.Due(sinceCheckpoint, replayTime) {
+			if showProgress {
+				logging.Subtle(fmt.Sprintf("→ No checkpoint needed yet (%d migration(s) replayed in %v since the latest)",
+					sinceCheckpoint, replayTime.Round(time.Millisecond)))
+			}
+			return nil
+		}
 		if showProgress {
-			fmt.Println(ui.Subtle(fmt.Sprintf("→ Creating checkpoint for %s...", lastMigration.Name)))
+			logging.Subtle(fmt.Sprintf("→ Creating checkpoint for %s...", lastMigration.Name))
 		}
 	}
 
@@ -494,9 +618,25 @@ func ensureCheckpointForLastMigration(fs afero.Fs, migrations []db.Migration, re
 	}
 
 	if showProgress {
-		fmt.Println(ui.Success("  ✓ Checkpoint created"))
+		logging.Success("  ✓ Checkpoint created")
 	}
 
+	return pruneSupersededCheckpoints(fs, migrations, policy.Keep, showProgress)
+}
+
+// pruneSupersededCheckpoints removes the checkpoints older than the keep newest,
+// if keep is set
+func pruneSupersededCheckpoints(fs afero.Fs, migrations []db.Migration, keep int, showProgress bool) error {
+	if keep == 0 {
+		return nil
+	}
+	pruned, err := pruneCheckpoints(fs, migrations, keep)
+	if err != nil {
+		return err
+	}
+	if showProgress && len(pruned) > 0 {
+		logging.Subtle(fmt.Sprintf("→ Pruned %d superseded checkpoint(s)", len(pruned)))
+	}
 	return nil
 }
 
@@ -577,13 +717,13 @@ func verifyAndReportSignatures(fs afero.Fs, migrations []db.Migration, require b
 	}
 
 	for _, name := range invalid {
-		fmt.Println(ui.Error(fmt.Sprintf("✗ %s: invalid scurry header signature — the header was hand-authored or edited. Regenerate it with scurry (never hand-author the '-- scurry:' header).", name)))
+		logging.Error(fmt.Sprintf("✗ %s: invalid scurry header signature — the header was hand-authored or edited. Regenerate it with scurry (never hand-author the '-- scurry:' header).", name))
 	}
 	for _, name := range missing {
 		if require {
-			fmt.Println(ui.Error(fmt.Sprintf("✗ %s: missing scurry header signature", name)))
+			logging.Error(fmt.Sprintf("✗ %s: missing scurry header signature", name))
 		} else {
-			fmt.Println(ui.Warning(fmt.Sprintf("⚠ %s: missing scurry header signature (run 'scurry migration validate --signatures=fix' to backfill)", name)))
+			logging.Warning(fmt.Sprintf("⚠ %s: missing scurry header signature (run 'scurry migration validate --signatures=fix' to backfill)", name))
 		}
 	}
 
@@ -633,7 +773,7 @@ func signMigrationHeaders(fs afero.Fs, migrations []db.Migration) error {
 		changed++
 	}
 
-	fmt.Println(ui.Success(fmt.Sprintf("✓ Signed %d migration(s) (%d already up to date)", changed, len(migrations)-changed)))
+	logging.Success(fmt.Sprintf("✓ Signed %d migration(s) (%d already up to date)", changed, len(migrations)-changed))
 	return nil
 }
 
@@ -648,14 +788,18 @@ func headerForStatements(fs afero.Fs, stmts []tree.Statement, prior []db.Migrati
 		return nil, fmt.Errorf("failed to load table_sizes.yaml: %w", err)
 	}
 
-	result := migrationpkg.ClassifyStatements(stmts, tableSizes)
+	classifyRules, err := loadClassifyRules(fs)
+	if err != nil {
+		return nil, err
+	}
+	result := migrationpkg.ClassifyStatements(stmts, tableSizes, classifyRules)
 	if announce && result.Mode == migrationpkg.ModeAsync {
-		fmt.Println()
-		fmt.Println(ui.Warning("Migration classified as async:"))
+		logging.Newline()
+		logging.Warning("Migration classified as async:")
 		for _, reason := range result.Reasons {
-			fmt.Printf("  - %s\n", reason)
+			logging.Print(fmt.Sprintf("  - %s", reason))
 		}
-		fmt.Println()
+		logging.Newline()
 	}
 
 	header := &migrationpkg.Header{Mode: result.Mode}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestDiffStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []string
+		after  []string
+		want   []statementChange
+	}{
+		{
+			name:   "unchanged",
+			before: []string{"a", "b"},
+			after:  []string{"a", "b"},
+			want:   nil,
+		},
+		{
+			name:   "statement added",
+			before: []string{"a", "c"},
+			after:  []string{"a", "b", "c"},
+			want:   []statementChange{{'+', "b"}},
+		},
+		{
+			name:   "statement removed",
+			before: []string{"a", "b", "c"},
+			after:  []string{"a", "c"},
+			want:   []statementChange{{'-', "b"}},
+		},
+		{
+			name:   "statement changed",
+			before: []string{"a", "b", "c"},
+			after:  []string{"a", "B", "c"},
+			want:   []statementChange{{'-', "b"}, {'+', "B"}},
+		},
+		{
+			name:   "everything replaced",
+			before: []string{"a"},
+			after:  []string{"b", "c"},
+			want:   []statementChange{{'-', "a"}, {'+', "b"}, {'+', "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diffStatements(tt.before, tt.after))
+		})
+	}
+}
+
+func TestFindMigrationDrift(t *testing.T) {
+	originalSQL := "CREATE TABLE users (id INT PRIMARY KEY);\nCREATE INDEX users_id ON users (id);"
+	editedSQL := "CREATE TABLE users (id INT PRIMARY KEY, name STRING);\nCREATE INDEX users_id ON users (id);"
+	reformattedSQL := "-- a comment\ncreate table users (id int primary key);\n\ncreate index users_id on users (id);"
+
+	migrations := []db.Migration{
+		{Name: "001_unchanged", SQL: "CREATE TABLE a (id INT PRIMARY KEY);", Checksum: computeChecksum("CREATE TABLE a (id INT PRIMARY KEY);")},
+		{Name: "002_edited", SQL: editedSQL, Checksum: computeChecksum(editedSQL)},
+		{Name: "003_reformatted", SQL: reformattedSQL, Checksum: computeChecksum(reformattedSQL)},
+		{Name: "004_old", SQL: editedSQL, Checksum: computeChecksum(editedSQL)},
+		{Name: "005_marked", SQL: editedSQL, Checksum: computeChecksum(editedSQL)},
+		{Name: "006_unapplied", SQL: editedSQL, Checksum: computeChecksum(editedSQL)},
+	}
+	applied := []db.AppliedMigration{
+		{Name: "000_deleted", Checksum: "x"},
+		{Name: "001_unchanged", Checksum: migrations[0].Checksum},
+		{Name: "002_edited", Checksum: computeChecksum(originalSQL)},
+		{Name: "003_reformatted", Checksum: computeChecksum(originalSQL)},
+		{Name: "004_old", Checksum: computeChecksum(originalSQL)},
+		{Name: "005_marked", Checksum: ""},
+	}
+	appliedSQL := map[string]string{
+		"002_edited":      originalSQL,
+		"003_reformatted": originalSQL,
+	}
+
+	drifts, err := findMigrationDrift(migrations, applied, appliedSQL)
+	require.NoError(t, err)
+	require.Len(t, drifts, 4)
+
+	assert.Equal(t, migrationDrift{Name: "000_deleted", Missing: true}, drifts[0])
+
+	assert.Equal(t, "002_edited", drifts[1].Name)
+	assert.True(t, drifts[1].SQLRecorded)
+	require.Len(t, drifts[1].Changes, 2)
+	assert.Equal(t, byte('-'), drifts[1].Changes[0].Op)
+	assert.Equal(t, "CREATE TABLE users (id INT8 PRIMARY KEY)", drifts[1].Changes[0].Statement)
+	assert.Equal(t, byte('+'), drifts[1].Changes[1].Op)
+	assert.Equal(t, "CREATE TABLE users (id INT8 PRIMARY KEY, name STRING)", drifts[1].Changes[1].Statement)
+
+	assert.Equal(t, "003_reformatted", drifts[2].Name)
+	assert.True(t, drifts[2].SQLRecorded)
+	assert.Empty(t, drifts[2].Changes)
+
+	assert.Equal(t, migrationDrift{Name: "004_old"}, drifts[3])
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+)
+
+var (
+	waitTimeout      time.Duration
+	waitPollInterval time.Duration
+)
+
+var migrationWaitCmd = &cobra.Command{
+	Use:   "wait <name>",
+	Short: "Wait for a migration to finish",
+	Long: `Block until the named migration has finished, for gating CI on async
+migrations run by 'scurry migration run-async'.
+
+Exits successfully once the migration has succeeded or been recovered, and fails
+if the migration failed or the timeout is reached. A migration that hasn't
+started yet is waited on too.
+
+Examples:
+  # Wait up to an hour for an async migration
+  scurry migration wait 20240101120000_backfill_emails --timeout=1h
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMigrationWait,
+}
+
+func init() {
+	migrationCmd.AddCommand(migrationWaitCmd)
+
+	flags.AddDbUrl(migrationWaitCmd)
+	migrationWaitCmd.Flags().DurationVar(&waitTimeout, "timeout", 0, "Give up after this long (0 waits forever)")
+	migrationWaitCmd.Flags().DurationVar(&waitPollInterval, "poll-interval", 5*time.Second, "How often to check the migration's status")
+}
+
+func runMigrationWait(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if flags.DbUrl == "" {
+		return fmt.Errorf("database URL is required (use --db-url or CRDB_URL env var)")
+	}
+
+	dbClient, err := db.Connect(ctx, flags.DbUrl)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+
+	if err := dbClient.InitMigrationHistory(ctx); err != nil {
+		return err
+	}
+
+	return waitForMigration(ctx, dbClient, args[0], waitTimeout, waitPollInterval)
+}
+
+// waitForMigration waits for the named migration to finish, returning an error
+// if it failed or didn't finish within timeout
+func waitForMigration(ctx context.Context, dbClient *db.Client, name string, timeout, pollInterval time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	logging.Info(fmt.Sprintf("Waiting for migration %s...", name))
+	warnedStalled := false
+	m, err := dbClient.WaitForMigration(ctx, name, pollInterval, func(m *db.AppliedMigration) {
+		if warnedStalled || m == nil || !isMigrationStalled(m, time.Now()) {
+			return
+		}
+		warnedStalled = true
+		logging.Warning(fmt.Sprintf("Migration %s hasn't reported progress since %s; the process running it may have stopped",
+			name, m.HeartbeatAt.Format(time.RFC3339)))
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		if m == nil {
+			return fmt.Errorf("timed out after %v: migration %s has not started", timeout, name)
+		}
+		return fmt.Errorf("timed out after %v: migration %s is still %s", timeout, name, m.Status)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch m.Status {
+	case db.MigrationStatusFailed:
+		if m.ErrorMsg != nil {
+			logging.Error(fmt.Sprintf("Error: %s", *m.ErrorMsg))
+		}
+		return fmt.Errorf("migration %s failed", name)
+	case db.MigrationStatusRecovered:
+		logging.Success(fmt.Sprintf("✓ Migration %s was recovered", name))
+	default:
+		logging.Success(fmt.Sprintf("✓ Migration %s succeeded", name))
+	}
+	return nil
+}
+
+// isMigrationStalled reports whether a pending migration has missed several
+// heartbeats
+func isMigrationStalled(m *db.AppliedMigration, now time.Time) bool {
+	if m.Status != db.MigrationStatusPending || m.HeartbeatAt == nil {
+		return false
+	}
+	return now.Sub(*m.HeartbeatAt) > 3*db.MigrationHeartbeatInterval
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestIsMigrationStalled(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-db.MigrationHeartbeatInterval)
+	old := now.Add(-10 * db.MigrationHeartbeatInterval)
+
+	tests := []struct {
+		name      string
+		migration db.AppliedMigration
+		want      bool
+	}{
+		{"pending with recent heartbeat", db.AppliedMigration{Status: db.MigrationStatusPending, HeartbeatAt: &recent}, false},
+		{"pending with old heartbeat", db.AppliedMigration{Status: db.MigrationStatusPending, HeartbeatAt: &old}, true},
+		{"pending without heartbeat", db.AppliedMigration{Status: db.MigrationStatusPending}, false},
+		{"succeeded with old heartbeat", db.AppliedMigration{Status: db.MigrationStatusSucceeded, HeartbeatAt: &old}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isMigrationStalled(&tt.migration, now))
+		})
+	}
+}
+
+func TestWaitForMigration(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.InitMigrationHistory(ctx))
+
+	// Not started yet
+	err = waitForMigration(ctx, client, "001_async", 200*time.Millisecond, 50*time.Millisecond)
+	require.ErrorContains(t, err, "has not started")
+
+	// Still running
+	require.NoError(t, client.StartMigration(ctx, "001_async", "x", true))
+	err = waitForMigration(ctx, client, "001_async", 200*time.Millisecond, 50*time.Millisecond)
+	require.ErrorContains(t, err, "is still pending")
+
+	// Finishes while waiting
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		client.CompleteMigration(ctx, "001_async")
+	}()
+	require.NoError(t, waitForMigration(ctx, client, "001_async", 10*time.Second, 50*time.Millisecond))
+
+	// Failed
+	require.NoError(t, client.StartMigration(ctx, "002_async", "y", true))
+	require.NoError(t, client.FailMigration(ctx, "002_async", "SELECT 1", "boom"))
+	err = waitForMigration(ctx, client, "002_async", time.Second, 50*time.Millisecond)
+	require.ErrorContains(t, err, "failed")
+}
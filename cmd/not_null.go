@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+// nullRowsError explains which columns made NOT NULL still have NULL rows, which
+// would make the ALTER fail partway through the deploy
+func nullRowsError(nulls []schema.NullRows) error {
+	var b strings.Builder
+	b.WriteString("SET NOT NULL would fail on column(s) that still have NULL rows:")
+	for _, n := range nulls {
+		fmt.Fprintf(&b, "\n  %s.%s has %d NULL row(s)", n.Table, n.Column, n.Rows)
+	}
+	b.WriteString("\nBackfill them first with UPDATE <table> SET <column> = <value> WHERE <column> IS NULL, or create backfill migrations with 'scurry migration gen --check-nulls'")
+	return errors.New(b.String())
+}
+
+// offerNullBackfills asks whether to create a migration backfilling the NULL
+// rows of each column, returning the names of the migrations it created. Without
+// a TTY nothing is created.
+func offerNullBackfills(fs afero.Fs, nulls []schema.NullRows) ([]string, error) {
+	if !ui.IsInteractive() {
+		return nil, nil
+	}
+	for _, n := range nulls {
+		logging.Warning(fmt.Sprintf("%s.%s has %d NULL row(s)", n.Table, n.Column, n.Rows))
+	}
+	confirmed, err := ui.ConfirmPrompt(fmt.Sprintf("Create %d migration(s) backfilling the NULL rows?", len(nulls)))
+	if err != nil {
+		return nil, fmt.Errorf("confirmation prompt failed: %w", err)
+	}
+	if !confirmed {
+		return nil, nil
+	}
+	return createNullBackfillMigrations(fs, nulls)
+}
+
+// createNullBackfillMigrations writes a migration backfilling the NULL rows of
+// each column. Columns with a default are backfilled with it and the migration
+// is signed; the others get a placeholder for the author to replace, and are
+// left unsigned like migrations created from a template.
+func createNullBackfillMigrations(fs afero.Fs, nulls []schema.NullRows) ([]string, error) {
+	existingMigrations, err := loadMigrations(fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing migrations: %w", err)
+	}
+	migInfos := make([]migrationpkg.MigrationInfo, len(existingMigrations))
+	for i, m := range existingMigrations {
+		migInfos[i] = migrationpkg.MigrationInfo{Name: m.Name, SQL: m.SQL}
+	}
+
+	// Rewriting every row of a large table takes too long to hold up a deploy
+	tableSizes, err := migrationpkg.LoadTableSizes(fs, flags.MigrationDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table_sizes.yaml: %w", err)
+	}
+
+	var names []string
+	for _, n := range nulls {
+		header := &migrationpkg.Header{Mode: migrationpkg.ModeSync}
+		if tableSizes.IsLargeTable(n.Table) {
+			header.Mode = migrationpkg.ModeAsync
+		}
+		header.DependsOn = migrationpkg.FindTableDependencies([]string{n.Table}, migInfos)
+
+		body, complete := migrationpkg.NullBackfill(n.Table, n.Column, n.Default)
+		if complete {
+			if err := migrationpkg.SignHeader(header, body); err != nil {
+				return nil, fmt.Errorf("failed to sign migration: %w", err)
+			}
+		}
+
+		_, tableName, _ := strings.Cut(n.Table, ".")
+		name, err := writeMigrationFile(fs, fmt.Sprintf("backfill_%s_%s", tableName, n.Column), migrationpkg.FormatHeader(header)+"\n"+body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create migration: %w", err)
+		}
+		names = append(names, name)
+		migInfos = append(migInfos, migrationpkg.MigrationInfo{Name: name, SQL: body})
+
+		logging.Success(fmt.Sprintf("✓ Created migration: %s", name))
+		if !complete {
+			logging.Info(fmt.Sprintf("%s.%s has no default: replace the placeholder value in %s, then sign its header with 'scurry migration validate --signatures=fix'", n.Table, n.Column, name))
+		}
+	}
+	return names, nil
+}
+
+// checkNullRowsForGen counts the NULL rows in the --db-url database of each
+// column the differences make NOT NULL. If any remain, it offers to create
+// backfill migrations and returns their names, or else fails.
+func checkNullRowsForGen(ctx context.Context, fs afero.Fs, localSchema *schema.Schema, diffResult *schema.ComparisonResult) ([]string, error) {
+	dbClient, err := db.Connect(ctx, flags.DbUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+
+	nulls, err := schema.CheckNullRows(ctx, dbClient, localSchema, diffResult)
+	if err != nil {
+		return nil, err
+	}
+	if len(nulls) == 0 {
+		return nil, nil
+	}
+
+	backfills, err := offerNullBackfills(fs, nulls)
+	if err != nil {
+		return nil, err
+	}
+	if len(backfills) == 0 {
+		return nil, nullRowsError(nulls)
+	}
+	return backfills, nil
+}
+
+// dependenciesWithBackfills returns the migrations the statements depend on,
+// including the backfills created for them, which touch no schema objects the
+// dependency detection could find
+func dependenciesWithBackfills(fs afero.Fs, statements []string, backfills []string) ([]string, error) {
+	var stmts []tree.Statement
+	for _, s := range statements {
+		parsed, err := parser.Parse(s)
+		if err != nil {
+			continue
+		}
+		for _, p := range parsed {
+			stmts = append(stmts, p.AST)
+		}
+	}
+
+	existingMigrations, err := loadMigrations(fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing migrations: %w", err)
+	}
+	migInfos := make([]migrationpkg.MigrationInfo, len(existingMigrations))
+	for i, m := range existingMigrations {
+		migInfos[i] = migrationpkg.MigrationInfo{Name: m.Name, SQL: m.SQL}
+	}
+
+	deps := migrationpkg.FindDependencies(stmts, migInfos)
+	for _, name := range backfills {
+		if !slices.Contains(deps, name) {
+			deps = append(deps, name)
+		}
+	}
+	return deps, nil
+}
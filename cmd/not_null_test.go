@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/flags"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestNullRowsError(t *testing.T) {
+	err := nullRowsError([]schema.NullRows{
+		{Table: "public.users", Column: "email", Rows: 3},
+		{Table: "app.orders", Column: "total", Rows: 1},
+	})
+	assert.Equal(t, "SET NOT NULL would fail on column(s) that still have NULL rows:\n"+
+		"  public.users.email has 3 NULL row(s)\n"+
+		"  app.orders.total has 1 NULL row(s)\n"+
+		"Backfill them first with UPDATE <table> SET <column> = <value> WHERE <column> IS NULL, or create backfill migrations with 'scurry migration gen --check-nulls'", err.Error())
+}
+
+func TestCreateNullBackfillMigrations(t *testing.T) {
+	// Not parallel: modifies flags.MigrationDir
+	oldMigrationDir := flags.MigrationDir
+	flags.MigrationDir = "migrations"
+	defer func() { flags.MigrationDir = oldMigrationDir }()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("migrations", "20240101000000_create_users", "migration.sql"), []byte("CREATE TABLE public.users (id INT8 PRIMARY KEY, email STRING);\n"), 0644))
+
+	value, err := parser.ParseExpr("'unknown'")
+	require.NoError(t, err)
+	names, err := createNullBackfillMigrations(fs, []schema.NullRows{
+		{Table: "public.users", Column: "email", Rows: 3, Default: value},
+		{Table: "public.users", Column: "name", Rows: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+	assert.Contains(t, names[0], "_backfill_users_email")
+	assert.Contains(t, names[1], "_backfill_users_name")
+
+	// The backfill with a default is signed and runs after the table's migration
+	content, err := afero.ReadFile(fs, filepath.Join("migrations", names[0], "migration.sql"))
+	require.NoError(t, err)
+	header, err := migrationpkg.ParseHeader(string(content))
+	require.NoError(t, err)
+	require.NotNil(t, header)
+	assert.Equal(t, []string{"20240101000000_create_users"}, header.DependsOn)
+	assert.NotEmpty(t, header.Sig)
+	assert.Contains(t, string(content), "UPDATE public.users SET email = 'unknown' WHERE email IS NULL LIMIT 1000;")
+
+	// The other is left unsigned for the author to fill in
+	content, err = afero.ReadFile(fs, filepath.Join("migrations", names[1], "migration.sql"))
+	require.NoError(t, err)
+	header, err = migrationpkg.ParseHeader(string(content))
+	require.NoError(t, err)
+	require.NotNil(t, header)
+	assert.Empty(t, header.Sig)
+	assert.Contains(t, string(content), "SET name = <value> WHERE name IS NULL")
+}
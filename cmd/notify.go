@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/notify"
+)
+
+// sendNotification posts the event to --notify-url, if set. Failing to notify
+// is only printed, so it never changes the outcome of the command.
+func sendNotification(ctx context.Context, event notify.Event) {
+	if flags.NotifyUrl == "" {
+		return
+	}
+	// The original context may be why the command failed
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	if event.Database == "" {
+		event.Database = databaseName(flags.DbUrl)
+	}
+	if err := notify.Send(ctx, flags.NotifyUrl, event); err != nil {
+		logging.Warning(fmt.Sprintf("⚠ %s", err))
+	}
+}
+
+// databaseName returns the host and database of a connection URL, leaving out
+// the credentials
+func databaseName(dbURL string) string {
+	u, err := url.Parse(dbURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	name := strings.TrimPrefix(u.Path, "/")
+	if name == "" {
+		return u.Host
+	}
+	return u.Host + "/" + name
+}
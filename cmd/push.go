@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -14,23 +15,47 @@ import (
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 
+	"github.com/pjtatlow/scurry/internal/config"
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+	"github.com/pjtatlow/scurry/internal/notify"
 	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/set"
 	"github.com/pjtatlow/scurry/internal/ui"
 )
 
 var pushCmd = &cobra.Command{
-	Use:   "push",
-	Short: "Push local schema changes to the database",
+	Use:         "push",
+	Short:       "Push local schema changes to the database",
+	Annotations: writesDatabase,
 	Long: `Push local schema changes to the database by applying the necessary migrations.
 This will compare the local schema with the database schema and apply the differences.
-All non-system schemas will be pushed automatically.`,
+All non-system schemas will be pushed automatically. Definitions laid out as
+databases/<name>/... push each database in turn.
+
+The definitions can also hold the settings of the database (database.sql), the
+cluster settings it relies on (cluster_settings.sql) and the owners of objects
+(ALTER ... OWNER TO), and .scurry.yaml can set hooks and backups. See
+docs/push.md for these and for how the flags below work.`,
 	RunE: push,
 }
 
 var (
-	pushDryRun bool
+	pushDryRun           bool
+	pushInteractive      bool
+	pushFilter           DiffFilter
+	pushStatementTimeout time.Duration
+	pushWithSeed         bool
+	pushWatch            bool
+	pushWatchInterval    time.Duration
+	pushReport           bool
+	pushResume           bool
+	pushReorder          bool
+	pushClusterSettings  bool
+	pushHideIndexes      bool
+	pushConfirmDrops     bool
 )
 
 func init() {
@@ -38,9 +63,30 @@ func init() {
 
 	flags.AddDbUrl(pushCmd)
 	flags.AddDefinitionDirs(pushCmd)
+	flags.AddEnv(pushCmd)
+	flags.AddNotifyUrl(pushCmd)
 	flags.AddMigrationDir(pushCmd)
-
-	pushCmd.Flags().BoolVar(&pushDryRun, "dry-run", false, "Show what would be executed without applying changes")
+	flags.AddAllowDestructive(pushCmd)
+	flags.AddLockWait(pushCmd)
+	flags.AddTTLIndex(pushCmd)
+	flags.AddCheckNulls(pushCmd)
+	flags.AddSkip(pushCmd)
+
+	pushCmd.Flags().BoolVar(&pushDryRun, "dry-run", false, "Show what would be executed, with the estimated cost of each statement, without applying changes")
+	pushCmd.Flags().BoolVarP(&pushInteractive, "interactive", "i", false, "Review each difference and choose to apply or skip it")
+	pushCmd.Flags().BoolVar(&pushReport, "report", false, "Summarize the dangerous changes with suggested mitigations, and require typing each object's name to apply them")
+	pushCmd.Flags().BoolVar(&pushReorder, "reorder", false, "Pin differences to run after others before applying them")
+	pushCmd.Flags().BoolVar(&pushClusterSettings, "cluster-settings", false, "Also apply the cluster settings in <definitions>/cluster_settings.sql, which needs the admin role or MODIFYCLUSTERSETTING")
+	pushCmd.Flags().BoolVar(&pushHideIndexes, "hide-indexes", false, "Make indexes removed from the definitions NOT VISIBLE instead of dropping them")
+	pushCmd.Flags().BoolVar(&pushConfirmDrops, "confirm-index-drops", false, "With --hide-indexes, drop the removed indexes that are already NOT VISIBLE")
+	pushCmd.Flags().BoolVar(&pushResume, "resume", false, "Continue a push that failed partway from the statement that failed")
+	pushCmd.Flags().StringArrayVar(&pushFilter.IncludeTables, "include-table", nil, "Only apply differences for this table (can be specified multiple times)")
+	pushCmd.Flags().StringArrayVar(&pushFilter.ExcludeTables, "exclude-table", nil, "Do not apply differences for this table (can be specified multiple times)")
+	pushCmd.Flags().StringArrayVar(&pushFilter.Only, "only", nil, "Only apply differences for objects matching this glob, e.g. 'public.users*' (can be specified multiple times)")
+	pushCmd.Flags().DurationVar(&pushStatementTimeout, "statement-timeout", 0, "Set statement timeout (e.g., 30s, 5m, 1h)")
+	pushCmd.Flags().BoolVar(&pushWithSeed, "with-seed", false, "Apply the seed data in <definitions>/seed after the schema")
+	pushCmd.Flags().BoolVarP(&pushWatch, "watch", "w", false, "Keep running and push again whenever a definition file changes")
+	pushCmd.Flags().DurationVar(&pushWatchInterval, "watch-interval", 500*time.Millisecond, "How often --watch checks the definition files for changes")
 }
 
 func push(cmd *cobra.Command, args []string) error {
@@ -51,10 +97,39 @@ func push(cmd *cobra.Command, args []string) error {
 	if len(flags.DefinitionDirs) == 0 {
 		return fmt.Errorf("definition directory is required (use --definitions)")
 	}
+	if pushInteractive && flags.Force {
+		return fmt.Errorf("--interactive cannot be used with --force")
+	}
+	if flags.Dialect == string(schema.DialectPostgres) {
+		return fmt.Errorf("push reads the database's schema from CockroachDB, so it doesn't support --dialect=postgres; generate migrations with 'scurry migration gen --dialect=postgres' instead")
+	}
+	if pushInteractive && pushWatch {
+		return fmt.Errorf("--interactive cannot be used with --watch")
+	}
+	if pushResume && (pushWatch || pushInteractive || pushReorder) {
+		return fmt.Errorf("--resume cannot be used with --watch, --interactive or --reorder")
+	}
+	if pushReorder && pushWatch {
+		return fmt.Errorf("--reorder cannot be used with --watch")
+	}
+	if pushConfirmDrops && !pushHideIndexes {
+		return fmt.Errorf("--confirm-index-drops only applies with --hide-indexes")
+	}
+	if pushWatch && pushWatchInterval <= 0 {
+		return fmt.Errorf("--watch-interval must be positive")
+	}
+	if err := pushFilter.Validate(); err != nil {
+		return err
+	}
 
-	err := doPush(cmd.Context())
+	var err error
+	if pushWatch {
+		err = watchPush(cmd.Context(), afero.NewOsFs(), flags.DefinitionDirs, pushWatchInterval, doPush)
+	} else {
+		err = doPush(cmd.Context())
+	}
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -65,10 +140,56 @@ func push(cmd *cobra.Command, args []string) error {
 type PushOptions struct {
 	Fs             afero.Fs
 	DefinitionDirs []string
+	Env            string // Selects the overlays/<env> definitions, if any
 	DbClient       *db.Client
 	Verbose        bool
 	DryRun         bool
 	Force          bool
+	Interactive    bool
+	Filter         DiffFilter
+
+	// Skip are objects expected to differ, whose differences are reported
+	// instead of applied, on top of ignore_diff and ignore-diff directives
+	Skip []string
+
+	// StatementTimeout limits how long each statement may run, if set
+	StatementTimeout time.Duration
+
+	// AllowDestructive permits dropping tables and columns without an
+	// allow-destructive directive in the definition files
+	AllowDestructive bool
+
+	// TTLIndex creates the index a new TTL expiration expression needs when
+	// the table definition doesn't have one
+	TTLIndex bool
+
+	// Report summarizes the dangerous differences and has the user type the
+	// name of each one's object to confirm it
+	Report bool
+
+	// CheckNulls counts the NULL rows of each column made NOT NULL before
+	// applying anything, and stops if any remain
+	CheckNulls bool
+
+	// Reorder lets the user pin differences to run after others, on top of
+	// the dependencies between them
+	Reorder bool
+
+	// Resume applies the statements of an earlier push that failed partway,
+	// starting with the one that failed, instead of comparing the schemas
+	Resume bool
+
+	// TargetVersion, if set, is the release statements are generated for
+	TargetVersion *schema.Version
+
+	// ClusterSettings compares and applies the cluster settings in
+	// cluster_settings.sql, which affect the whole cluster
+	ClusterSettings bool
+
+	// HideIndexes makes removed indexes NOT VISIBLE instead of dropping them,
+	// and ConfirmIndexDrops drops the ones already NOT VISIBLE
+	HideIndexes       bool
+	ConfirmIndexDrops bool
 }
 
 // PushResult contains the result of a push operation
@@ -131,39 +252,152 @@ func writeErrorReport(errCtx *ErrorContext, err error) (string, error) {
 }
 
 func doPush(ctx context.Context) error {
+	fs := afero.NewOsFs()
+
+	databases, err := findDatabaseDefinitions(fs, flags.DefinitionDirs)
+	if err != nil {
+		return err
+	}
+	if databases == nil {
+		return pushDatabase(ctx, fs, flags.DbUrl, flags.DefinitionDirs)
+	}
+
+	// Each database is pushed on its own connection, and keeps its own migration history
+	for _, database := range databases {
+		logging.Header(fmt.Sprintf("\nDatabase %s", database.Name))
+		dbURL, err := db.WithDatabase(flags.DbUrl, database.Name)
+		if err != nil {
+			return err
+		}
+		if err := pushDatabase(ctx, fs, dbURL, database.DefinitionDirs); err != nil {
+			return fmt.Errorf("database %s: %w", database.Name, err)
+		}
+	}
+	return nil
+}
 
-	client, err := db.Connect(ctx, flags.DbUrl)
+// pushDatabase pushes the definitions in definitionDirs to the database at dbURL
+func pushDatabase(ctx context.Context, fs afero.Fs, dbURL string, definitionDirs []string) (err error) {
+	hooks, err := loadHooks(fs)
+	if err != nil {
+		return err
+	}
+
+	// Every failure from here on runs the on_failure hooks, before the hooks'
+	// connection and the database client are closed
+	var client *db.Client
+	defer func() {
+		if client != nil {
+			client.Close()
+		}
+	}()
+	hookRunner := newHookRunner(hooks)
+	defer hookRunner.close()
+	defer func() {
+		if err != nil && !pushDryRun {
+			hookRunner.runFailure(ctx, "push", err)
+		}
+	}()
+
+	client, err = db.Connect(ctx, dbURL)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer client.Close()
+	hookRunner.setClient(client)
+	if err := setBackupPolicy(fs, client); err != nil {
+		return err
+	}
+
+	target, err := targetVersion(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to determine the cluster version: %w", err)
+	}
+
+	if !pushDryRun {
+		lock, err := acquireMigrationLock(ctx, client)
+		if err != nil {
+			return err
+		}
+		defer lock.Release(context.Background())
+		ctx = lock.Context()
+
+		hookEnv := map[string]string{"SCURRY_COMMAND": "push"}
+		if err := hookRunner.run(ctx, config.EventBeforePush, hookEnv); err != nil {
+			event := notify.NewEvent("push", 0, err)
+			event.Database = databaseName(dbURL)
+			sendNotification(ctx, event)
+			return err
+		}
+	}
 
 	opts := PushOptions{
-		Fs:             afero.NewOsFs(),
-		DefinitionDirs: flags.DefinitionDirs,
+		Fs:             fs,
+		DefinitionDirs: definitionDirs,
+		Env:            flags.Env,
 		DbClient:       client,
 		Verbose:        flags.Verbose,
 		DryRun:         pushDryRun,
-		Force:          flags.Force,
+		Force:          flags.Force || pushWatch,
+		Interactive:    pushInteractive,
+		Filter:         pushFilter,
+		Skip:           flags.Skip,
+
+		StatementTimeout: pushStatementTimeout,
+		AllowDestructive: flags.AllowDestructive,
+		TTLIndex:         flags.TTLIndex,
+		CheckNulls:       flags.CheckNulls,
+		Report:           pushReport,
+		Reorder:          pushReorder,
+		Resume:           pushResume,
+		TargetVersion:    target,
+		ClusterSettings:  pushClusterSettings,
+
+		HideIndexes:       pushHideIndexes,
+		ConfirmIndexDrops: pushConfirmDrops,
 	}
 
+	start := time.Now()
 	errCtx := &ErrorContext{}
-	_, err = executePush(ctx, opts, errCtx)
+	result, err := executePush(ctx, opts, errCtx)
 	if err != nil {
 		reportPath, reportErr := writeErrorReport(errCtx, err)
 		if reportErr != nil {
-			fmt.Println(ui.Warning(fmt.Sprintf("Failed to write error report: %s", reportErr)))
+			logging.Warning(fmt.Sprintf("Failed to write error report: %s", reportErr))
 		} else if reportPath != "" {
-			fmt.Println(ui.Info(fmt.Sprintf("Error report written to: %s", reportPath)))
+			logging.Info(fmt.Sprintf("Error report written to: %s", reportPath))
+		}
+	} else if pushWithSeed {
+		if pushDryRun && result.HasChanges {
+			// The seed may need tables that the dry run didn't create
+			logging.Subtle("Seed data isn't checked until the schema changes are applied.")
+		} else {
+			err = applySeed(ctx, fs, client, definitionDirs, pushDryRun)
+		}
+	}
+
+	if !pushDryRun {
+		if err == nil {
+			err = hookRunner.run(ctx, config.EventAfterPush, map[string]string{"SCURRY_COMMAND": "push"})
 		}
+
+		event := notify.NewEvent("push", time.Since(start), err)
+		event.Database = databaseName(dbURL)
+		if result != nil {
+			event.Statements = len(result.Statements)
+		}
+		sendNotification(ctx, event)
 	}
 	return err
 }
 
 func executePush(ctx context.Context, opts PushOptions, errCtx *ErrorContext) (*PushResult, error) {
+	if opts.Resume {
+		return resumePush(ctx, opts)
+	}
+
 	// Load local schema from files
 	if opts.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(opts.DefinitionDirs, ", "))))
+		logging.Subtle(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(opts.DefinitionDirs, ", ")))
 	}
 
 	dbClient, err := db.GetShadowDB(ctx)
@@ -172,52 +406,106 @@ func executePush(ctx context.Context, opts PushOptions, errCtx *ErrorContext) (*
 	}
 	defer dbClient.Close()
 
-	localSchema, err := schema.LoadFromDirectories(ctx, opts.Fs, opts.DefinitionDirs, dbClient)
+	localSchema, err := schema.LoadFromDirectoriesForEnv(ctx, opts.Fs, opts.DefinitionDirs, opts.Env, dbClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load local schema: %w", err)
 	}
 	errCtx.LocalSchema = localSchema
+	if err := addIgnoredObjects(opts.Fs, localSchema, opts.Skip); err != nil {
+		return nil, err
+	}
 
 	if opts.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views locally",
-			len(localSchema.Tables), len(localSchema.Types), len(localSchema.Routines), len(localSchema.Sequences), len(localSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views locally",
+			len(localSchema.Tables), len(localSchema.Types), len(localSchema.Routines), len(localSchema.Sequences), len(localSchema.Views)))
 	}
 
 	// Load remote schema from database (all schemas)
 	if opts.Verbose {
-		fmt.Println(ui.Subtle("→ Loading database schema..."))
+		logging.Subtle("→ Loading database schema...")
 	}
 
 	remoteSchema, err := schema.LoadFromDatabase(ctx, opts.DbClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load database schema: %w", err)
 	}
+	if err := loadRemoteSettings(ctx, opts, localSchema, remoteSchema); err != nil {
+		return nil, err
+	}
 	errCtx.RemoteSchema = remoteSchema
+	if localSchema.ClusterSettings != nil && !opts.ClusterSettings {
+		logging.Subtle(fmt.Sprintf("→ Leaving out %s, pass --cluster-settings to apply it", schema.ClusterSettingsFile))
+	}
 
 	if opts.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views in database",
-			len(remoteSchema.Tables), len(remoteSchema.Types), len(remoteSchema.Routines), len(remoteSchema.Sequences), len(remoteSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views in database",
+			len(remoteSchema.Tables), len(remoteSchema.Types), len(remoteSchema.Routines), len(remoteSchema.Sequences), len(remoteSchema.Views)))
 	}
 
 	// Compare schemas
 	if opts.Verbose {
-		fmt.Println()
-		fmt.Println(ui.Subtle("→ Comparing schemas..."))
+		logging.Newline()
+		logging.Subtle("→ Comparing schemas...")
 	}
 
+	// Pair tables gaining a TTL with the index the TTL job needs
+	pairTTLIndexes(localSchema, remoteSchema, opts.TTLIndex)
+
 	diffResult := schema.Compare(localSchema, remoteSchema)
+	if opts.TargetVersion != nil {
+		diffResult.ForTargetVersion(*opts.TargetVersion)
+	}
+	if opts.HideIndexes {
+		if waiting := diffResult.HideRemovedIndexes(remoteSchema, opts.ConfirmIndexDrops); len(waiting) > 0 {
+			logging.Subtle(fmt.Sprintf("→ %d NOT VISIBLE index(es) waiting for --confirm-index-drops: %s", len(waiting), strings.Join(waiting, ", ")))
+		}
+	}
+
+	// Drop differences the user didn't ask for
+	if !opts.Filter.IsEmpty() {
+		total := len(diffResult.Differences)
+		diffResult = diffResult.Filter(opts.Filter.Matches)
+		if opts.Verbose {
+			logging.Subtle(fmt.Sprintf("  Filtered out %d of %d difference(s)", total-len(diffResult.Differences), total))
+		}
+	}
+
+	printIgnoredDifferences(diffResult)
 
 	if !diffResult.HasChanges() {
 		if opts.Verbose {
-			fmt.Println()
-			fmt.Println(ui.Success("✓ No changes"))
+			logging.Newline()
+			logging.Success("✓ No changes")
 		}
 		return &PushResult{HasChanges: false, Statements: []string{}}, nil
 	}
 
+	// Warn about enum values that are dropped while rows still use them; the
+	// drop itself fails if they do, so a failed check only loses the warning
+	if err := schema.CheckDroppedEnumValues(ctx, opts.DbClient, localSchema, remoteSchema, diffResult); err != nil {
+		logging.Debug(fmt.Sprintf("  %v", err))
+	}
+
+	// SET NOT NULL fails partway through the push if NULL rows remain, so stop
+	// before applying anything
+	if opts.CheckNulls {
+		nulls, err := schema.CheckNullRows(ctx, opts.DbClient, localSchema, diffResult)
+		if err != nil {
+			return nil, err
+		}
+		if len(nulls) > 0 {
+			if !opts.Force && validateMigrationsDir(opts.Fs) == nil {
+				if _, err := offerNullBackfills(opts.Fs, nulls); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nullRowsError(nulls)
+		}
+	}
+
 	// Show differences
-	fmt.Println(ui.Header("\nDifferences found:"))
-	fmt.Println(diffResult.Summary())
+	logging.Header("\nDifferences found:")
+	logging.Print(diffResult.Summary())
 
 	// Prompt for USING expressions on column type changes
 	if !opts.Force {
@@ -226,6 +514,45 @@ func executePush(ctx context.Context, opts PushOptions, errCtx *ErrorContext) (*
 		}
 	}
 
+	// Let the user approve or skip each difference
+	skipped := set.New[string]()
+	if opts.Interactive {
+		approved, aborted, err := reviewDifferences(diffResult)
+		if err != nil {
+			return nil, err
+		}
+		if aborted {
+			logging.Subtle("Push canceled.")
+			return &PushResult{HasChanges: true, Statements: []string{}}, nil
+		}
+		for _, diff := range diffResult.Differences {
+			skipped.Add(diff.Key())
+		}
+		for _, diff := range approved.Differences {
+			skipped.Remove(diff.Key())
+		}
+		if !approved.HasChanges() {
+			logging.Subtle("All differences skipped, nothing to apply.")
+			return &PushResult{HasChanges: true, Statements: []string{}}, nil
+		}
+		diffResult = approved
+	}
+
+	if opts.Reorder {
+		if err := reorderDifferences(diffResult); err != nil {
+			return nil, err
+		}
+	}
+
+	// Refuse to drop tables or columns unless explicitly allowed
+	if err := enforceDestructivePolicy(opts.Fs, opts.DefinitionDirs, diffResult, opts.AllowDestructive); err != nil {
+		if !opts.DryRun {
+			return nil, err
+		}
+		logging.Warning(fmt.Sprintf("⚠ %s", err))
+		logging.Newline()
+	}
+
 	// Get migration statements
 	statements, warnings, err := diffResult.GenerateMigrations(true)
 	if err != nil {
@@ -233,103 +560,258 @@ func executePush(ctx context.Context, opts PushOptions, errCtx *ErrorContext) (*
 	}
 	errCtx.Statements = statements
 
-	if opts.Verbose {
-		fmt.Println()
-		fmt.Println(ui.Header(fmt.Sprintf("Generated %d migration statement(s) with %d warning(s):", len(statements), len(warnings))))
+	if opts.Verbose && !opts.DryRun {
+		logging.Newline()
+		logging.Header(fmt.Sprintf("Generated %d migration statement(s) with %d warning(s):", len(statements), len(warnings)))
 
 		for i, stmt := range statements {
-			fmt.Printf("%s %s\n\n", ui.Info(fmt.Sprintf("%d.", i+1)), ui.SqlCode(stmt))
+			logging.Print(fmt.Sprintf("%s %s\n", ui.Info(fmt.Sprintf("%d.", i+1)), ui.SqlCode(stmt)))
 		}
 	}
 	for i, warning := range warnings {
-		fmt.Printf("WARNING: %s \n\n", ui.Warning(fmt.Sprintf("%d. %s", i+1, warning)))
+		logging.Warning(fmt.Sprintf("WARNING: %d. %s", i+1, warning))
+		logging.Newline()
+	}
+
+	var dangers []schema.DangerItem
+	if opts.Report {
+		dangers = diffResult.DangerReport()
+		logging.Newline()
+		if len(dangers) == 0 {
+			logging.Success("✓ No dangerous changes")
+		} else {
+			logging.Print(ui.Destructive(formatDangerReport(dangers)))
+		}
 	}
 
 	if opts.DryRun {
+		// Row counts come from the database itself; without them the costs
+		// are still classified
+		tableSizes, err := liveTableSizes(ctx, opts.DbClient, migrationpkg.DefaultLargeTableThreshold)
+		if err != nil {
+			logging.Debug(fmt.Sprintf("  %v", err))
+		}
+		logging.Newline()
+		printStatementCosts(statements, tableSizes)
+
 		if opts.Verbose {
-			fmt.Println()
-			fmt.Println(ui.Info("ℹ Dry run mode - no changes applied."))
+			logging.Newline()
+			logging.Info("ℹ Dry run mode - no changes applied.")
 		}
 		return &PushResult{HasChanges: true, Statements: statements}, nil
 	}
 
-	if !opts.Force {
-		fmt.Println()
-		confirmed, err := ui.ConfirmPrompt("Do you want to apply these changes?")
+	if len(dangers) > 0 {
+		logging.Newline()
+		confirmed, err := confirmDangerReport(dangers)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			logging.Subtle("Push canceled.")
+			return &PushResult{HasChanges: true, Statements: statements}, nil
+		}
+	}
+
+	if (!opts.Force && !opts.Interactive) || inProtectedEnvironment() {
+		logging.Newline()
+		confirmed, err := confirmChange(ctx, opts.DbClient, "Do you want to apply these changes?")
 		if err != nil {
 			return nil, fmt.Errorf("confirmation prompt failed: %w", err)
 		}
 
 		if !confirmed {
-			fmt.Println(ui.Subtle("Push canceled."))
+			logging.Subtle("Push canceled.")
 			return &PushResult{HasChanges: true, Statements: statements}, nil
 		}
 	}
 
+	// Back up the data the dangerous changes put at risk, if configured
+	logging.Newline()
+	if err := backupDangerousChanges(ctx, opts.DbClient, diffResult); err != nil {
+		return nil, err
+	}
+
 	// Apply migrations
-	fmt.Println()
-	fmt.Println(ui.Info("⟳ Applying migrations..."))
+	logging.Info("⟳ Applying migrations...")
 
-	if err := opts.DbClient.ExecuteBulkDDL(ctx, statements...); err != nil {
-		fmt.Println()
-		fmt.Println(ui.Warning("⚠ Bulk apply failed, retrying statements one-by-one to identify the failure..."))
-		fmt.Println()
+	if opts.StatementTimeout > 0 {
+		if err := opts.DbClient.SetStatementTimeout(ctx, opts.StatementTimeout); err != nil {
+			return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+
+	// Refuse to apply the statements if the schema changed since it was
+	// compared, e.g. while the changes were being confirmed
+	opts.DbClient.SetExpectedSchemaFingerprint(remoteSchema.Fingerprint)
+
+	// Record each statement applied, so a push that fails partway can be resumed
+	tracker := startPushProgress(ctx, opts.DbClient, statements, 0)
+	start := time.Now()
+	if err := opts.DbClient.ExecuteDDLWithProgress(ctx, tracker.onStatement, statements...); err != nil {
+		if errors.Is(err, db.ErrSchemaChanged) {
+			// Nothing was applied, and the statements no longer fit the schema
+			tracker.done()
+			return nil, fmt.Errorf("%s: %w", ui.Error("✗ Failed to apply migrations"), err)
+		}
+		logging.Newline()
+		logging.Warning("⚠ Bulk apply failed, retrying statements one-by-one to identify the failure...")
+		logging.Newline()
 
 		// Re-load remote schema to capture any partial progress
-		retryRemoteSchema, reloadErr := schema.LoadFromDatabase(ctx, opts.DbClient)
+		retryRemoteSchema, reloadErr := reloadRemoteSchema(ctx, opts.DbClient)
+		if reloadErr == nil {
+			reloadErr = loadRemoteSettings(ctx, opts, localSchema, retryRemoteSchema)
+		}
 		if reloadErr != nil {
+			// Statements before the failing one may have been applied
+			tracker.failed(err)
 			return nil, fmt.Errorf("%s: %w (additionally, failed to reload schema for retry: %s)", ui.Error("✗ Failed to apply migrations"), err, reloadErr)
 		}
 
-		// Re-compare with local schema
-		retryDiff := schema.Compare(localSchema, retryRemoteSchema)
+		// Re-compare with local schema, leaving out anything filtered or skipped
+		retryDiff := schema.Compare(localSchema, retryRemoteSchema).Filter(func(d schema.Difference) bool {
+			return opts.Filter.Matches(d) && !skipped.Contains(d.Key())
+		})
+		if opts.TargetVersion != nil {
+			retryDiff.ForTargetVersion(*opts.TargetVersion)
+		}
+		if opts.HideIndexes {
+			retryDiff.HideRemovedIndexes(retryRemoteSchema, opts.ConfirmIndexDrops)
+		}
+		keepPinnedOrder(retryDiff, diffResult)
 		if !retryDiff.HasChanges() {
-			fmt.Println(ui.Warning("⚠ Despite the error, all changes appear to have been applied."))
-			fmt.Println(ui.Subtle(fmt.Sprintf("  Original error: %s", err)))
+			tracker.done()
+			logging.Warning("⚠ Despite the error, all changes appear to have been applied.")
+			logging.Subtle(fmt.Sprintf("  Original error: %s", db.WithDetail(err)))
 			return &PushResult{HasChanges: true, Statements: statements}, nil
 		}
 
 		// Re-generate migration statements from the current state
 		retryStatements, _, genErr := retryDiff.GenerateMigrations(true)
 		if genErr != nil {
+			tracker.failed(err)
 			return nil, fmt.Errorf("%s: %w (additionally, failed to regenerate migrations for retry: %s)", ui.Error("✗ Failed to apply migrations"), err, genErr)
 		}
 
-		fmt.Println(ui.Info(fmt.Sprintf("⟳ Retrying %d remaining statement(s) individually:", len(retryStatements))))
-		fmt.Println()
+		logging.Info(fmt.Sprintf("⟳ Retrying %d remaining statement(s) individually:", len(retryStatements)))
+		logging.Newline()
 
+		// A resume continues with the regenerated statements
+		retryTracker := startPushProgress(ctx, opts.DbClient, retryStatements, 0)
 		for i, stmt := range retryStatements {
-			fmt.Printf("%s %s\n", ui.Info(fmt.Sprintf("%d/%d:", i+1, len(retryStatements))), ui.SqlCode(stmt))
-			if stmtErr := opts.DbClient.ExecuteBulkDDL(ctx, stmt); stmtErr != nil {
-				fmt.Println()
-				fmt.Println(ui.Error(fmt.Sprintf("✗ Statement %d failed:", i+1)))
-				fmt.Println(ui.SqlCode(stmt))
-				return nil, fmt.Errorf("%s: %w", ui.Error("✗ Failed to apply migrations"), stmtErr)
+			logging.Print(fmt.Sprintf("%s %s", ui.Info(fmt.Sprintf("%d/%d:", i+1, len(retryStatements))), ui.SqlCode(stmt)))
+			if stmtErr := opts.DbClient.ExecuteDDLWithProgress(ctx, func(db.StatementProgress) { retryTracker.statementApplied() }, stmt); stmtErr != nil {
+				logging.Newline()
+				logging.Error(fmt.Sprintf("✗ Statement %d failed:", i+1))
+				logging.Print(ui.SqlCode(stmt))
+				retryTracker.failed(stmtErr)
+				return nil, fmt.Errorf("%s: %w", ui.Error("✗ Failed to apply migrations"), changeError(retryDiff, stmt, stmtErr))
 			}
-			fmt.Println(ui.Success(fmt.Sprintf("  ✓ Statement %d applied", i+1)))
-			fmt.Println()
+			logging.Success(fmt.Sprintf("  ✓ Statement %d applied", i+1))
+			logging.Newline()
 		}
+		retryTracker.done()
 
-		fmt.Println(ui.Success("✓ All remaining statements applied individually."))
+		logging.Success("✓ All remaining statements applied individually.")
 		return &PushResult{HasChanges: true, Statements: statements}, nil
 	}
 
-	fmt.Println()
-	fmt.Println(ui.Success("✓ Successfully applied all migrations!"))
+	tracker.done()
+
+	logging.Newline()
+	logging.Success(fmt.Sprintf("✓ Successfully applied all migrations in %v!", time.Since(start).Round(time.Millisecond)))
 	return &PushResult{HasChanges: true, Statements: statements}, nil
 }
 
+// reloadRemoteSchema reads the database's schema again after a bulk apply
+// failed partway, replaced in tests
+var reloadRemoteSchema = schema.LoadFromDatabase
+
+// loadRemoteSettings reads the settings of the database itself into remote,
+// when the definitions have a database.sql to compare them with, and the
+// cluster settings when they have a cluster_settings.sql and --cluster-settings
+// allows changing them, and the owners of objects when they set any
+func loadRemoteSettings(ctx context.Context, opts PushOptions, local, remote *schema.Schema) error {
+	if local.Database != nil {
+		settings, err := schema.LoadDatabaseSettings(ctx, opts.DbClient)
+		if err != nil {
+			return fmt.Errorf("failed to load database settings: %w", err)
+		}
+		remote.Database = settings
+	}
+	if local.ClusterSettings != nil && opts.ClusterSettings {
+		settings, err := schema.LoadClusterSettings(ctx, opts.DbClient)
+		if err != nil {
+			return fmt.Errorf("failed to load cluster settings (--cluster-settings needs the admin role or VIEWCLUSTERSETTING): %w", err)
+		}
+		remote.ClusterSettings = settings
+	}
+	if len(local.Owners) > 0 {
+		owners, err := schema.LoadOwners(ctx, opts.DbClient)
+		if err != nil {
+			return fmt.Errorf("failed to load object owners: %w", err)
+		}
+		remote.Owners = owners
+	}
+	return nil
+}
+
+// changeError says which change in the definitions a failed statement was
+// generated for, and where it's defined, along with the detail and hint the
+// database gave for the error
+func changeError(diffResult *schema.ComparisonResult, stmt string, err error) error {
+	err = db.WithDetail(err)
+	diff, ok := diffResult.DifferenceOf(stmt)
+	if !ok {
+		return err
+	}
+	if diff.Source.IsZero() {
+		return fmt.Errorf("error applying change (%s): %w", diff.Description, err)
+	}
+	return fmt.Errorf("error applying change from %s (%s): %w", diff.Source, diff.Description, err)
+}
+
+// printStatementProgress prints a line for each statement as it's applied
+func printStatementProgress(p db.StatementProgress) {
+	logging.Print(formatStatementProgress(p))
+}
+
+func formatStatementProgress(p db.StatementProgress) string {
+	// Only the first line of the statement, they're pretty printed
+	summary, _, multiline := strings.Cut(strings.TrimSpace(p.Statement), "\n")
+	if multiline {
+		summary += " ..."
+	}
+
+	details := p.Duration.Round(time.Millisecond).String()
+	if p.RowsAffected > 0 {
+		details += fmt.Sprintf(", %d row(s)", p.RowsAffected)
+	}
+	if p.Attempt > 1 {
+		details += fmt.Sprintf(", attempt %d", p.Attempt)
+	}
+
+	return fmt.Sprintf("  %s %s %s %s",
+		ui.Success("✓"),
+		ui.Info(fmt.Sprintf("%d/%d", p.Index, p.Total)),
+		summary,
+		ui.Subtle(fmt.Sprintf("(%s)", details)),
+	)
+}
+
 // promptForUsingExpressions checks for column type changes and prompts the user
 // to optionally provide a USING expression for each one.
 func promptForUsingExpressions(diffResult *schema.ComparisonResult) error {
 	for i := range diffResult.Differences {
 		diff := &diffResult.Differences[i]
-		if diff.Type != schema.DiffTypeColumnTypeChanged {
+		// Skip changes that already use an expression from a -- scurry:using directive
+		if diff.Type != schema.DiffTypeColumnTypeChanged || diff.HasUsingDirective {
 			continue
 		}
 
 		// Find the AlterTableAlterColumnType command in the migration statements
-		for _, stmt := range diff.MigrationStatements {
+		for _, stmt := range diff.Statements() {
 			alterTable, ok := stmt.(*tree.AlterTable)
 			if !ok {
 				continue
@@ -341,7 +823,7 @@ func promptForUsingExpressions(diffResult *schema.ComparisonResult) error {
 				}
 
 				// Prompt user if they want to add a USING expression
-				fmt.Println()
+				logging.Newline()
 				confirmed, err := ui.ConfirmPrompt(fmt.Sprintf("Add a USING expression for: %s?", diff.Description))
 				if err != nil {
 					return fmt.Errorf("confirmation prompt failed: %w", err)
@@ -357,7 +839,7 @@ func promptForUsingExpressions(diffResult *schema.ComparisonResult) error {
 					huh.NewGroup(
 						huh.NewInput().
 							Title("USING expression").
-							Description("Enter the expression to convert the column value").
+							Description("Enter the expression to convert the column value. Add -- scurry:using=<expr> to the column's line to skip this prompt.").
 							Placeholder(alterColType.Column.String()).
 							Value(&exprStr).
 							Validate(func(s string) error {
@@ -388,3 +870,112 @@ func promptForUsingExpressions(diffResult *schema.ComparisonResult) error {
 	}
 	return nil
 }
+
+const (
+	reviewApply = "Apply"
+	reviewSkip  = "Skip"
+	reviewAbort = "Abort push"
+)
+
+// reviewDifferences steps through each difference, showing its description,
+// DDL and any danger warning, and asks the user whether to apply it. It returns
+// the approved differences, or aborted=true if the user chose to stop.
+func reviewDifferences(diffResult *schema.ComparisonResult) (*schema.ComparisonResult, bool, error) {
+	if !ui.IsInteractive() {
+		return nil, false, fmt.Errorf("--interactive requires an interactive terminal")
+	}
+
+	approved := &schema.ComparisonResult{Differences: make([]schema.Difference, 0)}
+	skipped := make([]schema.Difference, 0)
+	total := len(diffResult.Differences)
+
+	for i, diff := range diffResult.Differences {
+		logging.Newline()
+		logging.Header(fmt.Sprintf("[%d/%d] %s", i+1, total, diff.DescriptionWithSource()))
+		if diff.Dangerous {
+			logging.Print(ui.Destructive("⚠ This change is destructive and may cause data loss"))
+		}
+		if diff.WarningMessage != "" {
+			logging.Warning(diff.WarningMessage)
+		}
+		for _, stmt := range diff.Statements() {
+			pretty, err := tree.Pretty(stmt)
+			if err != nil {
+				pretty = stmt.String()
+			}
+			logging.Print(ui.SqlCode(pretty))
+		}
+
+		choice, err := ui.SelectPrompt("Apply this change?", reviewApply, reviewSkip, reviewAbort)
+		if err != nil {
+			return nil, false, fmt.Errorf("review prompt failed: %w", err)
+		}
+
+		switch choice {
+		case reviewApply:
+			approved.Differences = append(approved.Differences, diff)
+		case reviewSkip:
+			skipped = append(skipped, diff)
+		case reviewAbort:
+			return nil, true, nil
+		}
+	}
+
+	var dependents []schema.Difference
+	approved.Differences, dependents = skipDependentDifferences(approved.Differences, skipped)
+	if len(dependents) > 0 {
+		logging.Newline()
+		for _, diff := range dependents {
+			logging.Warning(fmt.Sprintf("⚠ Skipping %s, it depends on a skipped change", diff.DescriptionWithSource()))
+		}
+	}
+
+	return approved, false, nil
+}
+
+// skipDependentDifferences removes the approved differences that depend on an
+// object created by a skipped one, directly or through another removed
+// difference. It returns the differences that are left and the ones removed.
+func skipDependentDifferences(approved, skipped []schema.Difference) ([]schema.Difference, []schema.Difference) {
+	provides := func(diff schema.Difference) set.Set[string] {
+		names := set.New[string]()
+		for _, stmt := range diff.Statements() {
+			names = names.Union(schema.GetProvidedNames(stmt, true))
+		}
+		return names
+	}
+
+	missing := set.New[string]()
+	for _, diff := range skipped {
+		missing = missing.Union(provides(diff))
+	}
+	// Names that an approved difference also provides, like an overload, aren't missing
+	for _, diff := range approved {
+		missing = missing.Difference(provides(diff))
+	}
+
+	kept := approved
+	var removed []schema.Difference
+	for {
+		next := make([]schema.Difference, 0, len(kept))
+		for _, diff := range kept {
+			dependent := false
+			for _, stmt := range diff.Statements() {
+				if schema.GetDependencyNames(stmt, true).Intersection(missing).Size() > 0 {
+					dependent = true
+					break
+				}
+			}
+			if dependent {
+				removed = append(removed, diff)
+				missing = missing.Union(provides(diff))
+			} else {
+				next = append(next, diff)
+			}
+		}
+		if len(next) == len(kept) {
+			return kept, removed
+		}
+		kept = next
+	}
+}
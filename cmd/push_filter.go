@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// DiffFilter selects which differences a push applies. An empty filter matches everything.
+type DiffFilter struct {
+	IncludeTables []string // Only apply differences for these tables
+	ExcludeTables []string // Never apply differences for these tables
+	Only          []string // Glob patterns matched against the qualified object name
+}
+
+// IsEmpty returns true if the filter has no criteria
+func (f DiffFilter) IsEmpty() bool {
+	return len(f.IncludeTables) == 0 && len(f.ExcludeTables) == 0 && len(f.Only) == 0
+}
+
+// Validate checks that all glob patterns are well formed
+func (f DiffFilter) Validate() error {
+	for _, pattern := range f.Only {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --only pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the difference should be applied
+func (f DiffFilter) Matches(diff schema.Difference) bool {
+	name := diff.ObjectName
+
+	for _, table := range f.ExcludeTables {
+		if qualifyObjectName(table) == name {
+			return false
+		}
+	}
+
+	if len(f.IncludeTables) > 0 {
+		included := false
+		for _, table := range f.IncludeTables {
+			if qualifyObjectName(table) == name {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	if len(f.Only) > 0 {
+		for _, pattern := range f.Only {
+			if matched, _ := path.Match(qualifyObjectName(pattern), name); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// qualifyObjectName lowercases a name and adds the public schema if none is given,
+// matching the form used by Difference.ObjectName ("schema:name" for schemas)
+func qualifyObjectName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.Contains(name, ".") && !strings.HasPrefix(name, "schema:") {
+		return "public." + name
+	}
+	return name
+}
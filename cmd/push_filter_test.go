@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestDiffFilterMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     DiffFilter
+		objectName string
+		want       bool
+	}{
+		{
+			name:       "empty filter matches everything",
+			filter:     DiffFilter{},
+			objectName: "public.users",
+			want:       true,
+		},
+		{
+			name:       "include table without schema",
+			filter:     DiffFilter{IncludeTables: []string{"users"}},
+			objectName: "public.users",
+			want:       true,
+		},
+		{
+			name:       "include table excludes others",
+			filter:     DiffFilter{IncludeTables: []string{"users"}},
+			objectName: "public.posts",
+			want:       false,
+		},
+		{
+			name:       "include table with explicit schema",
+			filter:     DiffFilter{IncludeTables: []string{"app.users"}},
+			objectName: "public.users",
+			want:       false,
+		},
+		{
+			name:       "include table is case insensitive",
+			filter:     DiffFilter{IncludeTables: []string{"Users"}},
+			objectName: "public.users",
+			want:       true,
+		},
+		{
+			name:       "exclude table",
+			filter:     DiffFilter{ExcludeTables: []string{"users"}},
+			objectName: "public.users",
+			want:       false,
+		},
+		{
+			name:       "exclude wins over include",
+			filter:     DiffFilter{IncludeTables: []string{"users"}, ExcludeTables: []string{"public.users"}},
+			objectName: "public.users",
+			want:       false,
+		},
+		{
+			name:       "only glob matches prefix",
+			filter:     DiffFilter{Only: []string{"public.users*"}},
+			objectName: "public.users_archive",
+			want:       true,
+		},
+		{
+			name:       "only glob without schema",
+			filter:     DiffFilter{Only: []string{"users*"}},
+			objectName: "public.users",
+			want:       true,
+		},
+		{
+			name:       "only glob across schemas",
+			filter:     DiffFilter{Only: []string{"*.users"}},
+			objectName: "app.users",
+			want:       true,
+		},
+		{
+			name:       "only glob does not match",
+			filter:     DiffFilter{Only: []string{"public.users*"}},
+			objectName: "public.posts",
+			want:       false,
+		},
+		{
+			name:       "only glob does not match schema differences",
+			filter:     DiffFilter{Only: []string{"*"}},
+			objectName: "schema:app",
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := schema.Difference{ObjectName: tt.objectName}
+			assert.Equal(t, tt.want, tt.filter.Matches(diff))
+		})
+	}
+}
+
+func TestDiffFilterValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    DiffFilter
+		expectErr bool
+	}{
+		{
+			name:   "valid patterns",
+			filter: DiffFilter{Only: []string{"public.users*", "app.?osts"}},
+		},
+		{
+			name:      "malformed pattern",
+			filter:    DiffFilter{Only: []string{"public.[users"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+// reorderDifferences lets the user pin differences to run after others, e.g. a
+// backfill before the SET NOT NULL that needs it. Pins that contradict the
+// dependencies between the changes are refused.
+func reorderDifferences(diffResult *schema.ComparisonResult) error {
+	if !ui.IsInteractive() {
+		return fmt.Errorf("--reorder requires an interactive terminal")
+	}
+
+	for {
+		logging.Newline()
+		logging.Print(formatDifferenceOrder(diffResult))
+
+		var input string
+		err := huh.NewInput().
+			Title("Pin an order").
+			Description("Enter '<n> after <m>' or '<m> before <n>', or leave empty to continue").
+			Value(&input).
+			Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return nil
+				}
+				_, _, err := parseOrderPin(s, len(diffResult.Differences))
+				return err
+			}).
+			WithTheme(ui.HuhTheme()).
+			Run()
+		if err != nil {
+			return fmt.Errorf("reorder prompt failed: %w", err)
+		}
+		if strings.TrimSpace(input) == "" {
+			return nil
+		}
+
+		first, then, err := parseOrderPin(input, len(diffResult.Differences))
+		if err != nil {
+			return err
+		}
+		if err := diffResult.PinOrder(first, then); err != nil {
+			logging.Warning(fmt.Sprintf("⚠ %v", err))
+			continue
+		}
+		logging.Success(fmt.Sprintf("✓ %d runs after %d", then+1, first+1))
+	}
+}
+
+// parseOrderPin parses "<n> after <m>" or "<m> before <n>", numbered from 1,
+// into the indexes of the difference to run first and the one to run after it
+func parseOrderPin(input string, total int) (first, then int, err error) {
+	fields := strings.Fields(strings.ToLower(input))
+	if len(fields) != 3 || (fields[1] != "after" && fields[1] != "before") {
+		return 0, 0, fmt.Errorf("invalid order %q: want '<n> after <m>' or '<m> before <n>'", input)
+	}
+	var numbers [2]int
+	for i, field := range []string{fields[0], fields[2]} {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > total {
+			return 0, 0, fmt.Errorf("invalid order %q: %s isn't a difference between 1 and %d", input, field, total)
+		}
+		numbers[i] = n - 1
+	}
+	if fields[1] == "after" {
+		return numbers[1], numbers[0], nil
+	}
+	return numbers[0], numbers[1], nil
+}
+
+// formatDifferenceOrder lists the differences with the ones each has to run
+// after, because of its dependencies or a pin
+func formatDifferenceOrder(diffResult *schema.ComparisonResult) string {
+	needs := make(map[int][]string)
+	for _, dep := range diffResult.Dependencies() {
+		needs[dep.Then] = append(needs[dep.Then], strconv.Itoa(dep.First+1))
+	}
+	keys := make(map[string]int, len(diffResult.Differences))
+	for i, diff := range diffResult.Differences {
+		keys[diff.Key()] = i
+	}
+
+	var b strings.Builder
+	for i, diff := range diffResult.Differences {
+		fmt.Fprintf(&b, "%s %s", ui.Info(fmt.Sprintf("%d.", i+1)), diff.DescriptionWithSource())
+		if len(needs[i]) > 0 {
+			b.WriteString(ui.Subtle(fmt.Sprintf(" (after %s)", strings.Join(needs[i], ", "))))
+		}
+		var pinned []string
+		for _, key := range diff.RunsAfter {
+			if j, ok := keys[key]; ok {
+				pinned = append(pinned, strconv.Itoa(j+1))
+			}
+		}
+		if len(pinned) > 0 {
+			b.WriteString(ui.Subtle(fmt.Sprintf(" (pinned after %s)", strings.Join(pinned, ", "))))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// keepPinnedOrder copies the pins of the differences in pinned to the same
+// differences in result, which compared the schemas again
+func keepPinnedOrder(result, pinned *schema.ComparisonResult) {
+	runsAfter := make(map[string][]string)
+	for _, diff := range pinned.Differences {
+		if len(diff.RunsAfter) > 0 {
+			runsAfter[diff.Key()] = diff.RunsAfter
+		}
+	}
+	for i := range result.Differences {
+		result.Differences[i].RunsAfter = runsAfter[result.Differences[i].Key()]
+	}
+}
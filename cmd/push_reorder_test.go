@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+func TestParseOrderPin(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantFirst int
+		wantThen  int
+		wantErr   string
+	}{
+		{input: "3 after 1", wantFirst: 0, wantThen: 2},
+		{input: "1 before 3", wantFirst: 0, wantThen: 2},
+		{input: " 2 AFTER 3 ", wantFirst: 2, wantThen: 1},
+		{input: "3 after", wantErr: "invalid order \"3 after\": want '<n> after <m>' or '<m> before <n>'"},
+		{input: "3 with 1", wantErr: "invalid order \"3 with 1\": want '<n> after <m>' or '<m> before <n>'"},
+		{input: "4 after 1", wantErr: "invalid order \"4 after 1\": 4 isn't a difference between 1 and 3"},
+		{input: "a after 1", wantErr: "invalid order \"a after 1\": a isn't a difference between 1 and 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			first, then, err := parseOrderPin(tt.input, 3)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFirst, first)
+			assert.Equal(t, tt.wantThen, then)
+		})
+	}
+}
+
+func TestFormatDifferenceOrder(t *testing.T) {
+	ui.SetNoColor(true)
+	defer ui.SetNoColor(false)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "defs/tables.sql", []byte(`
+CREATE TABLE public.a (id INT8 NOT NULL, CONSTRAINT a_pkey PRIMARY KEY (id ASC));
+CREATE TABLE public.b (id INT8 NOT NULL, a_id INT8 NOT NULL, CONSTRAINT b_pkey PRIMARY KEY (id ASC), CONSTRAINT b_a_id_fkey FOREIGN KEY (a_id) REFERENCES public.a (id));
+CREATE TABLE public.c (id INT8 NOT NULL, CONSTRAINT c_pkey PRIMARY KEY (id ASC));
+`), 0644))
+	local, err := schema.LoadDefinitions(fs, []string{"defs"}, "")
+	require.NoError(t, err)
+	compare := func() *schema.ComparisonResult {
+		result := schema.Compare(local, schema.NewSchema())
+		slices.SortFunc(result.Differences, func(a, b schema.Difference) int { return strings.Compare(a.ObjectName, b.ObjectName) })
+		return result
+	}
+	result := compare()
+	require.Len(t, result.Differences, 3)
+	require.NoError(t, result.PinOrder(2, 0))
+
+	assert.Equal(t, `1. Table 'public.a' added (defs/tables.sql:2) (pinned after 3)
+2. Table 'public.b' added (defs/tables.sql:3) (after 1)
+3. Table 'public.c' added (defs/tables.sql:4)`, formatDifferenceOrder(result))
+
+	// Comparing again keeps the pins
+	again := compare()
+	keepPinnedOrder(again, result)
+	assert.Equal(t, result.Differences[0].RunsAfter, again.Differences[0].RunsAfter)
+	assert.Empty(t, again.Differences[1].RunsAfter)
+}
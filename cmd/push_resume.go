@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+// pushProgressTracker records in the database how many of a push's statements
+// were applied, so a push that fails partway can be resumed with --resume.
+// Failing to record progress doesn't fail the push, it only loses the resume.
+type pushProgressTracker struct {
+	ctx     context.Context
+	client  *db.Client
+	applied int
+	enabled bool
+}
+
+// startPushProgress records that statements are about to be applied, after
+// the applied first ones when resuming
+func startPushProgress(ctx context.Context, client *db.Client, statements []string, applied int) *pushProgressTracker {
+	t := &pushProgressTracker{ctx: context.WithoutCancel(ctx), client: client, applied: applied, enabled: true}
+	if applied == 0 {
+		if err := client.StartPushProgress(t.ctx, statements); err != nil {
+			logging.Debug(fmt.Sprintf("  %v", err))
+			t.enabled = false
+		}
+	}
+	return t
+}
+
+// onStatement prints each applied statement and records it, for ExecuteDDLWithProgress
+func (t *pushProgressTracker) onStatement(p db.StatementProgress) {
+	printStatementProgress(p)
+	t.statementApplied()
+}
+
+// statementApplied records that one more statement was applied
+func (t *pushProgressTracker) statementApplied() {
+	t.applied++
+	if !t.enabled {
+		return
+	}
+	if err := t.client.RecordPushProgress(t.ctx, t.applied); err != nil {
+		logging.Debug(fmt.Sprintf("  %v", err))
+	}
+}
+
+// failed records why the push stopped, and tells the user how to resume it
+func (t *pushProgressTracker) failed(err error) {
+	if !t.enabled {
+		return
+	}
+	if recordErr := t.client.FailPushProgress(t.ctx, err); recordErr != nil {
+		logging.Debug(fmt.Sprintf("  %v", recordErr))
+		return
+	}
+	logging.Info(fmt.Sprintf("ℹ %d statement(s) were applied. Fix the problem, then run 'scurry push --resume' to continue from the one that failed.", t.applied))
+}
+
+// done removes the progress once every statement was applied
+func (t *pushProgressTracker) done() {
+	if !t.enabled {
+		return
+	}
+	if err := t.client.ClearPushProgress(t.ctx); err != nil {
+		logging.Debug(fmt.Sprintf("  %v", err))
+	}
+}
+
+// resumePush applies the statements an earlier push didn't get to, starting
+// with the one that failed. It doesn't read the definitions: the statements
+// are the ones generated then, so run push again afterwards for any other changes.
+func resumePush(ctx context.Context, opts PushOptions) (*PushResult, error) {
+	progress, err := opts.DbClient.GetPushProgress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		return nil, fmt.Errorf("there is no interrupted push to resume")
+	}
+
+	remaining := progress.Remaining()
+	if len(remaining) == 0 {
+		if err := opts.DbClient.ClearPushProgress(ctx); err != nil {
+			return nil, err
+		}
+		logging.Success("✓ Every statement of the interrupted push was already applied")
+		return &PushResult{HasChanges: false, Statements: []string{}}, nil
+	}
+
+	logging.Header(fmt.Sprintf("\nResuming the push started %s at statement %d of %d:",
+		progress.StartedAt.Local().Format(time.DateTime), progress.Applied+1, progress.Total()))
+	if progress.Error != "" {
+		logging.Subtle(fmt.Sprintf("  It stopped with: %s", progress.Error))
+	}
+	logging.Newline()
+	for _, stmt := range remaining {
+		logging.Print(ui.SqlCode(stmt))
+	}
+
+	if opts.DryRun {
+		logging.Newline()
+		logging.Info("ℹ Dry run mode - no changes applied.")
+		return &PushResult{HasChanges: true, Statements: remaining}, nil
+	}
+
+	if !opts.Force || inProtectedEnvironment() {
+		logging.Newline()
+		confirmed, err := confirmChange(ctx, opts.DbClient, "Do you want to apply the remaining statements?")
+		if err != nil {
+			return nil, fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirmed {
+			logging.Subtle("Push canceled.")
+			return &PushResult{HasChanges: true, Statements: remaining}, nil
+		}
+	}
+
+	logging.Newline()
+	logging.Info("⟳ Applying remaining statements...")
+
+	if opts.StatementTimeout > 0 {
+		if err := opts.DbClient.SetStatementTimeout(ctx, opts.StatementTimeout); err != nil {
+			return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+
+	tracker := startPushProgress(ctx, opts.DbClient, progress.Statements, progress.Applied)
+	start := time.Now()
+	if err := opts.DbClient.ExecuteDDLWithProgress(ctx, tracker.onStatement, remaining...); err != nil {
+		tracker.failed(err)
+		return nil, fmt.Errorf("%s: %w", ui.Error("✗ Failed to apply migrations"), err)
+	}
+	tracker.done()
+
+	logging.Newline()
+	logging.Success(fmt.Sprintf("✓ Applied the remaining statements in %v. Run 'scurry push' again to apply any other changes.", time.Since(start).Round(time.Millisecond)))
+	return &PushResult{HasChanges: true, Statements: remaining}, nil
+}
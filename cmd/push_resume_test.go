@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestPushResume(t *testing.T) {
+	ctx := context.Background()
+	client, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("/schema", "tables/users.sql"), []byte("CREATE TABLE users (id INT PRIMARY KEY);"), 0644))
+	opts := PushOptions{Fs: fs, DefinitionDirs: []string{"/schema"}, DbClient: client, Force: true}
+
+	// A push that completes leaves nothing to resume
+	_, err = executePush(ctx, opts, &ErrorContext{})
+	require.NoError(t, err)
+	progress, err := client.GetPushProgress(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, progress)
+
+	opts.Resume = true
+	_, err = executePush(ctx, opts, &ErrorContext{})
+	require.EqualError(t, err, "there is no interrupted push to resume")
+
+	// A push that stopped after its first statement
+	statements := []string{"CREATE TABLE a (id INT8 PRIMARY KEY)", "CREATE TABLE b (id INT8 PRIMARY KEY)"}
+	require.NoError(t, client.ExecuteBulkDDL(ctx, statements[0]))
+	require.NoError(t, client.StartPushProgress(ctx, statements))
+	require.NoError(t, client.RecordPushProgress(ctx, 1))
+
+	result, err := executePush(ctx, opts, &ErrorContext{})
+	require.NoError(t, err)
+	assert.Equal(t, statements[1:], result.Statements)
+
+	remote, err := schema.LoadFromDatabase(ctx, client)
+	require.NoError(t, err)
+	assert.Len(t, remote.Tables, 3)
+
+	progress, err = client.GetPushProgress(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, progress)
+}
+
+func TestPushRecordsFailureWhenRetryCantStart(t *testing.T) {
+	ctx := context.Background()
+	client, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Duplicate emails make adding the unique index fail
+	require.NoError(t, client.ExecuteBulkDDL(ctx, "CREATE TABLE users (id INT8 PRIMARY KEY, email STRING)"))
+	_, err = client.GetDB().ExecContext(ctx, "INSERT INTO users VALUES (1, 'a@example.com'), (2, 'a@example.com')")
+	require.NoError(t, err)
+
+	defer func(reload func(context.Context, *db.Client) (*schema.Schema, error)) { reloadRemoteSchema = reload }(reloadRemoteSchema)
+	reloadRemoteSchema = func(context.Context, *db.Client) (*schema.Schema, error) {
+		return nil, errors.New("connection lost")
+	}
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("/schema", "tables/users.sql"), []byte(
+		"CREATE TABLE users (id INT8 PRIMARY KEY, email STRING, UNIQUE INDEX users_email_key (email));"), 0644))
+	opts := PushOptions{Fs: fs, DefinitionDirs: []string{"/schema"}, DbClient: client, Force: true}
+
+	_, err = executePush(ctx, opts, &ErrorContext{})
+	require.ErrorContains(t, err, "failed to reload schema for retry: connection lost")
+
+	// The push can be resumed from where it stopped, with the error that stopped it
+	progress, err := client.GetPushProgress(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, progress)
+	assert.NotEmpty(t, progress.Error)
+}
@@ -7,13 +7,18 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/lib/pq"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/ui"
 )
 
 func TestPushIntegration(t *testing.T) {
@@ -389,12 +394,13 @@ func TestPushIntegration(t *testing.T) {
 			writeSchemaFiles(tt.initialSchema)
 
 			opts := PushOptions{
-				Fs:             fs,
-				DefinitionDirs: []string{schemaDir},
-				DbClient:       client,
-				Verbose:        false,
-				DryRun:         false,
-				Force:          true,
+				Fs:               fs,
+				DefinitionDirs:   []string{schemaDir},
+				DbClient:         client,
+				Verbose:          false,
+				DryRun:           false,
+				Force:            true,
+				AllowDestructive: true,
 			}
 
 			result, err := executePush(ctx, opts, &ErrorContext{})
@@ -696,12 +702,13 @@ func TestPushIntegrationComplex(t *testing.T) {
 			writeSchemaFiles(tt.initialSchema)
 
 			opts := PushOptions{
-				Fs:             fs,
-				DefinitionDirs: []string{schemaDir},
-				DbClient:       client,
-				Verbose:        false,
-				DryRun:         false,
-				Force:          true,
+				Fs:               fs,
+				DefinitionDirs:   []string{schemaDir},
+				DbClient:         client,
+				Verbose:          false,
+				DryRun:           false,
+				Force:            true,
+				AllowDestructive: true,
 			}
 
 			result, err := executePush(ctx, opts, &ErrorContext{})
@@ -1148,12 +1155,13 @@ func TestPushIntegrationColumnTypeChanges(t *testing.T) {
 			writeSchemaFiles(tt.initialSchema)
 
 			opts := PushOptions{
-				Fs:             fs,
-				DefinitionDirs: []string{schemaDir},
-				DbClient:       client,
-				Verbose:        false,
-				DryRun:         false,
-				Force:          true,
+				Fs:               fs,
+				DefinitionDirs:   []string{schemaDir},
+				DbClient:         client,
+				Verbose:          false,
+				DryRun:           false,
+				Force:            true,
+				AllowDestructive: true,
 			}
 
 			result, err := executePush(ctx, opts, &ErrorContext{})
@@ -1274,12 +1282,13 @@ func TestPushDropColumnWithConstraint(t *testing.T) {
 			writeSchemaFiles(tt.initialSchema)
 
 			opts := PushOptions{
-				Fs:             fs,
-				DefinitionDirs: []string{schemaDir},
-				DbClient:       client,
-				Verbose:        false,
-				DryRun:         false,
-				Force:          true,
+				Fs:               fs,
+				DefinitionDirs:   []string{schemaDir},
+				DbClient:         client,
+				Verbose:          false,
+				DryRun:           false,
+				Force:            true,
+				AllowDestructive: true,
 			}
 
 			result, err := executePush(ctx, opts, &ErrorContext{})
@@ -1378,12 +1387,13 @@ func TestPushDropColumnWithIndex(t *testing.T) {
 			writeSchemaFiles(tt.initialSchema)
 
 			opts := PushOptions{
-				Fs:             fs,
-				DefinitionDirs: []string{schemaDir},
-				DbClient:       client,
-				Verbose:        false,
-				DryRun:         false,
-				Force:          true,
+				Fs:               fs,
+				DefinitionDirs:   []string{schemaDir},
+				DbClient:         client,
+				Verbose:          false,
+				DryRun:           false,
+				Force:            true,
+				AllowDestructive: true,
 			}
 
 			result, err := executePush(ctx, opts, &ErrorContext{})
@@ -1483,12 +1493,13 @@ func TestPushAddPartialIndexOnNewColumn(t *testing.T) {
 			writeSchemaFiles(tt.initialSchema)
 
 			opts := PushOptions{
-				Fs:             fs,
-				DefinitionDirs: []string{schemaDir},
-				DbClient:       client,
-				Verbose:        false,
-				DryRun:         false,
-				Force:          true,
+				Fs:               fs,
+				DefinitionDirs:   []string{schemaDir},
+				DbClient:         client,
+				Verbose:          false,
+				DryRun:           false,
+				Force:            true,
+				AllowDestructive: true,
 			}
 
 			result, err := executePush(ctx, opts, &ErrorContext{})
@@ -1627,12 +1638,13 @@ func TestPushRowLevelTTL(t *testing.T) {
 			writeSchemaFiles(tt.initialSchema)
 
 			opts := PushOptions{
-				Fs:             fs,
-				DefinitionDirs: []string{schemaDir},
-				DbClient:       client,
-				Verbose:        false,
-				DryRun:         false,
-				Force:          true,
+				Fs:               fs,
+				DefinitionDirs:   []string{schemaDir},
+				DbClient:         client,
+				Verbose:          false,
+				DryRun:           false,
+				Force:            true,
+				AllowDestructive: true,
 			}
 
 			result, err := executePush(ctx, opts, &ErrorContext{})
@@ -1847,3 +1859,185 @@ func TestGenerateErrorReportContent(t *testing.T) {
 		})
 	}
 }
+
+func TestPushRefusesDestructiveChanges(t *testing.T) {
+	tests := []struct {
+		name             string
+		initialSchema    string
+		updatedSchema    string
+		allowDestructive bool
+		expectErr        bool
+	}{
+		{
+			name:          "drop column is refused",
+			initialSchema: `CREATE TABLE users (id INT PRIMARY KEY, email TEXT);`,
+			updatedSchema: `CREATE TABLE users (id INT PRIMARY KEY);`,
+			expectErr:     true,
+		},
+		{
+			name:          "drop table is refused",
+			initialSchema: `CREATE TABLE users (id INT PRIMARY KEY); CREATE TABLE posts (id INT PRIMARY KEY);`,
+			updatedSchema: `CREATE TABLE users (id INT PRIMARY KEY);`,
+			expectErr:     true,
+		},
+		{
+			name:             "drop column allowed by flag",
+			initialSchema:    `CREATE TABLE users (id INT PRIMARY KEY, email TEXT);`,
+			updatedSchema:    `CREATE TABLE users (id INT PRIMARY KEY);`,
+			allowDestructive: true,
+		},
+		{
+			name:          "drop table allowed by directive",
+			initialSchema: `CREATE TABLE users (id INT PRIMARY KEY); CREATE TABLE posts (id INT PRIMARY KEY);`,
+			updatedSchema: "-- scurry:allow-destructive=posts\nCREATE TABLE users (id INT PRIMARY KEY);",
+		},
+		{
+			name:          "additive change is not affected",
+			initialSchema: `CREATE TABLE users (id INT PRIMARY KEY);`,
+			updatedSchema: `CREATE TABLE users (id INT PRIMARY KEY, email TEXT);`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, err := db.GetShadowDB(ctx, tt.initialSchema)
+			require.NoError(t, err)
+			defer client.Close()
+
+			fs := afero.NewMemMapFs()
+			schemaDir := "/schema"
+			require.NoError(t, fs.MkdirAll(schemaDir, 0755))
+			require.NoError(t, afero.WriteFile(fs, filepath.Join(schemaDir, "schema.sql"), []byte(tt.updatedSchema), 0644))
+
+			opts := PushOptions{
+				Fs:               fs,
+				DefinitionDirs:   []string{schemaDir},
+				DbClient:         client,
+				Force:            true,
+				AllowDestructive: tt.allowDestructive,
+			}
+
+			result, err := executePush(ctx, opts, &ErrorContext{})
+			if tt.expectErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "refusing to apply")
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, result.HasChanges)
+
+			// Everything was applied
+			result, err = executePush(ctx, opts, &ErrorContext{})
+			require.NoError(t, err)
+			assert.False(t, result.HasChanges)
+		})
+	}
+}
+
+func TestFormatStatementProgress(t *testing.T) {
+	ui.SetNoColor(true)
+	defer ui.SetNoColor(false)
+
+	tests := []struct {
+		name     string
+		progress db.StatementProgress
+		expected string
+	}{
+		{
+			name:     "single line statement",
+			progress: db.StatementProgress{Statement: "CREATE TYPE status AS ENUM ('a')", Index: 1, Total: 3, Duration: 1234 * time.Microsecond},
+			expected: "  ✓ 1/3 CREATE TYPE status AS ENUM ('a') (1ms)",
+		},
+		{
+			name:     "multi line statement with rows and a retry",
+			progress: db.StatementProgress{Statement: "UPDATE users\n\tSET n = 1", Index: 2, Total: 3, Duration: 2 * time.Second, RowsAffected: 42, Attempt: 2},
+			expected: "  ✓ 2/3 UPDATE users ... (2s, 42 row(s), attempt 2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatStatementProgress(tt.progress))
+		})
+	}
+}
+
+func TestChangeError(t *testing.T) {
+	stmts, err := parser.Parse("ALTER TABLE public.users ADD CONSTRAINT users_email_key UNIQUE (email)")
+	require.NoError(t, err)
+	diffResult := &schema.ComparisonResult{Differences: []schema.Difference{{
+		Type:        schema.DiffTypeTableModified,
+		ObjectName:  "public.users",
+		Description: "Constraint 'users_email_key' added",
+		Phases:      []schema.Phase{{Statements: []tree.Statement{stmts[0].AST}}},
+		Source:      schema.SourceLocation{File: "definitions/tables/users.sql", Line: 27},
+	}}}
+	pqErr := &pq.Error{
+		Code:    "23505",
+		Message: `could not create unique constraint "users_email_key"`,
+		Detail:  "Key (email)=('a@example.com') is duplicated.",
+	}
+
+	tests := []struct {
+		name     string
+		result   *schema.ComparisonResult
+		stmt     string
+		expected string
+	}{
+		{
+			name:   "statement from the definitions",
+			result: diffResult,
+			stmt:   stmts[0].AST.String(),
+			expected: "error applying change from definitions/tables/users.sql:27 (Constraint 'users_email_key' added): " +
+				"pq: could not create unique constraint \"users_email_key\"\nDETAIL: Key (email)=('a@example.com') is duplicated.",
+		},
+		{
+			name:     "unknown statement",
+			result:   diffResult,
+			stmt:     "DROP TABLE public.users",
+			expected: "pq: could not create unique constraint \"users_email_key\"\nDETAIL: Key (email)=('a@example.com') is duplicated.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := changeError(tt.result, tt.stmt, pqErr)
+			assert.EqualError(t, err, tt.expected)
+			assert.ErrorIs(t, err, pqErr)
+		})
+	}
+}
+
+func TestSkipDependentDifferences(t *testing.T) {
+	diff := func(description, sql string) schema.Difference {
+		stmts, err := parser.Parse(sql)
+		require.NoError(t, err)
+		var phase schema.Phase
+		for _, stmt := range stmts {
+			phase.Statements = append(phase.Statements, stmt.AST)
+		}
+		return schema.Difference{Description: description, Phases: []schema.Phase{phase}}
+	}
+
+	status := diff("type status", "CREATE TYPE public.status AS ENUM ('a')")
+	users := diff("table users", "CREATE TABLE public.users (id INT PRIMARY KEY, s public.status)")
+	view := diff("view active_users", "CREATE VIEW public.active_users AS SELECT id FROM public.users")
+	posts := diff("table posts", "CREATE TABLE public.posts (id INT PRIMARY KEY)")
+
+	kept, removed := skipDependentDifferences([]schema.Difference{users, view, posts}, []schema.Difference{status})
+
+	var keptNames, removedNames []string
+	for _, d := range kept {
+		keptNames = append(keptNames, d.Description)
+	}
+	for _, d := range removed {
+		removedNames = append(removedNames, d.Description)
+	}
+	assert.Equal(t, []string{"table posts"}, keptNames)
+	assert.ElementsMatch(t, []string{"table users", "view active_users"}, removedNames)
+
+	kept, removed = skipDependentDifferences([]schema.Difference{status, users}, []schema.Difference{posts})
+	assert.Len(t, kept, 2)
+	assert.Empty(t, removed)
+}
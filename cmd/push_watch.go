@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/logging"
+)
+
+// fileStamp is what a definition file is compared by between polls
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// definitionsSnapshot records every .sql file under the definition directories
+type definitionsSnapshot map[string]fileStamp
+
+// snapshotDefinitions stamps every .sql file under dirPaths. Directories that
+// don't exist yet are skipped, so creating one is seen as a change.
+func snapshotDefinitions(fs afero.Fs, dirPaths []string) (definitionsSnapshot, error) {
+	snapshot := make(definitionsSnapshot)
+	for _, dirPath := range dirPaths {
+		if exists, err := afero.DirExists(fs, dirPath); err != nil || !exists {
+			continue
+		}
+		err := afero.Walk(fs, dirPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".sql") {
+				return nil
+			}
+			snapshot[path] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// changedFiles returns the files added, removed or modified in next, sorted
+func (s definitionsSnapshot) changedFiles(next definitionsSnapshot) []string {
+	var changed []string
+	for path, stamp := range next {
+		if prev, ok := s[path]; !ok || prev.size != stamp.size || !prev.modTime.Equal(stamp.modTime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range s {
+		if _, ok := next[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// watchPush runs push, then polls the definition directories every interval and
+// runs it again once the files have changed and stopped changing for an
+// interval, so an editor saving several files pushes once. A failed push is
+// logged and the watch carries on until ctx is canceled.
+func watchPush(ctx context.Context, fs afero.Fs, dirPaths []string, interval time.Duration, push func(context.Context) error) error {
+	snapshot, err := snapshotDefinitions(fs, dirPaths)
+	if err != nil {
+		return err
+	}
+
+	run := func() {
+		if err := push(ctx); err != nil && ctx.Err() == nil {
+			logging.Error(fmt.Sprintf("Error: %v", err))
+		}
+		logging.Newline()
+		logging.Subtle(fmt.Sprintf("Watching %s for changes (Ctrl+C to stop)...", strings.Join(dirPaths, ", ")))
+	}
+	run()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pending []string
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		next, err := snapshotDefinitions(fs, dirPaths)
+		if err != nil {
+			logging.Warning(fmt.Sprintf("⚠ Failed to read definitions: %s", err))
+			continue
+		}
+		if changed := snapshot.changedFiles(next); len(changed) > 0 {
+			pending = mergeChanged(pending, changed)
+			snapshot = next
+			continue
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		logging.Newline()
+		logging.Info(fmt.Sprintf("↻ Changed: %s", strings.Join(pending, ", ")))
+		pending = nil
+		run()
+	}
+}
+
+// mergeChanged adds the newly changed files to those already pending, sorted
+func mergeChanged(pending, changed []string) []string {
+	seen := make(map[string]bool, len(pending))
+	for _, path := range pending {
+		seen[path] = true
+	}
+	for _, path := range changed {
+		if !seen[path] {
+			seen[path] = true
+			pending = append(pending, path)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionsSnapshotChangedFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "defs/users.sql", []byte("CREATE TABLE users (id INT PRIMARY KEY);"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/posts.sql", []byte("CREATE TABLE posts (id INT PRIMARY KEY);"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/README.md", []byte("notes"), 0644))
+
+	before, err := snapshotDefinitions(fs, []string{"defs", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, before, 2)
+	assert.Empty(t, before.changedFiles(before))
+
+	require.NoError(t, afero.WriteFile(fs, "defs/users.sql", []byte("CREATE TABLE users (id INT PRIMARY KEY, name STRING);"), 0644))
+	require.NoError(t, fs.Remove("defs/posts.sql"))
+	require.NoError(t, afero.WriteFile(fs, "defs/nested/comments.sql", []byte("CREATE TABLE comments (id INT PRIMARY KEY);"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/README.md", []byte("more notes"), 0644))
+
+	after, err := snapshotDefinitions(fs, []string{"defs", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"defs/nested/comments.sql", "defs/posts.sql", "defs/users.sql"}, before.changedFiles(after))
+}
+
+func TestWatchPush(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "defs/users.sql", []byte("CREATE TABLE users (id INT PRIMARY KEY);"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pushes atomic.Int32
+	done := make(chan error)
+	go func() {
+		done <- watchPush(ctx, fs, []string{"defs"}, 10*time.Millisecond, func(context.Context) error {
+			pushes.Add(1)
+			return nil
+		})
+	}()
+
+	require.Eventually(t, func() bool { return pushes.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	// Several saves in quick succession push once they settle
+	require.NoError(t, afero.WriteFile(fs, "defs/users.sql", []byte("CREATE TABLE users (id INT PRIMARY KEY, name STRING);"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/posts.sql", []byte("CREATE TABLE posts (id INT PRIMARY KEY);"), 0644))
+	require.Eventually(t, func() bool { return pushes.Load() == 2 }, time.Second, 5*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), pushes.Load(), "unchanged files shouldn't push again")
+
+	cancel()
+	require.NoError(t, <-done)
+}
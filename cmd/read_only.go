@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+)
+
+// writeAnnotation marks commands that change the database, so they're refused
+// in read-only mode
+const writeAnnotation = "scurry_writes_database"
+
+// writesDatabase is the Annotations of commands that change the database
+var writesDatabase = map[string]string{writeAnnotation: "true"}
+
+// checkReadOnly refuses commands that change the database when connections
+// are read-only, from --read-only or the --env profile
+func checkReadOnly(cmd *cobra.Command) error {
+	if !db.ConnectionOptions.ReadOnly || cmd.Annotations[writeAnnotation] == "" {
+		return nil
+	}
+	if activeEnvironment != nil && activeEnvironment.ReadOnly && !cmd.Flags().Changed("read-only") {
+		return fmt.Errorf("%s changes the database, and the environment %s is read-only (pass --read-only=false to allow it)", cmd.CommandPath(), flags.Env)
+	}
+	return fmt.Errorf("%s changes the database, which --read-only doesn't allow", cmd.CommandPath())
+}
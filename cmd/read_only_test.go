@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pjtatlow/scurry/internal/config"
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		readOnly    bool
+		environment *config.Environment
+		wantErr     string
+	}{
+		{
+			name: "read-write",
+			args: []string{"push"},
+		},
+		{
+			name:     "read command",
+			args:     []string{"migration", "status"},
+			readOnly: true,
+		},
+		{
+			name:     "write command",
+			args:     []string{"migration", "execute"},
+			readOnly: true,
+			wantErr:  "scurry migration execute changes the database, which --read-only doesn't allow",
+		},
+		{
+			name:     "nested write command",
+			args:     []string{"data", "load"},
+			readOnly: true,
+			wantErr:  "scurry data load changes the database",
+		},
+		{
+			name:     "serve only reads",
+			args:     []string{"serve"},
+			readOnly: true,
+		},
+		{
+			name:        "read-only environment",
+			args:        []string{"push"},
+			readOnly:    true,
+			environment: &config.Environment{ReadOnly: true},
+			wantErr:     "the environment prod is read-only (pass --read-only=false to allow it)",
+		},
+	}
+
+	oldReadOnly, oldEnv := db.ConnectionOptions.ReadOnly, flags.Env
+	defer func() {
+		db.ConnectionOptions.ReadOnly, flags.Env = oldReadOnly, oldEnv
+		activeEnvironment = nil
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, _, err := rootCmd.Find(tt.args)
+			if !assert.NoError(t, err) {
+				return
+			}
+			db.ConnectionOptions.ReadOnly = tt.readOnly
+			activeEnvironment = tt.environment
+			flags.Env = "prod"
+
+			err = checkReadOnly(cmd)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
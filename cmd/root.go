@@ -1,16 +1,23 @@
 package cmd
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/pjtatlow/scurry/internal/config"
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/telemetry"
 	"github.com/pjtatlow/scurry/internal/ui"
 )
 
@@ -18,6 +25,9 @@ var (
 	// Global context for signal handling
 	rootContext context.Context
 	cancelFunc  context.CancelFunc
+
+	// sessionFlags are the --session name=value settings for db.ConnectionOptions
+	sessionFlags []string
 )
 
 var rootCmd = &cobra.Command{
@@ -26,13 +36,97 @@ var rootCmd = &cobra.Command{
 	Long: `Scurry is a CLI tool for managing CockroachDB database schemas.
 It allows you to define your database schema in SQL files and keep them in sync with your database.`,
 	SilenceUsage: true, // Don't print usage on runtime errors (only on argument validation errors)
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		if flags.NoColor || isNoColorEnv() {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if flags.NoColor || isNoColorEnv() || flags.LogFormat == logging.FormatJSON {
 			ui.SetNoColor(true)
 		}
+
+		level := flags.LogLevel
+		if flags.Verbose && !cmd.Flags().Changed("log-level") {
+			level = "debug"
+		}
+		if err := logging.Configure(flags.LogFormat, level, flags.LogTimestamps); err != nil {
+			return err
+		}
+		// Errors are logged as JSON by Execute instead
+		if logging.IsJSON() {
+			cmd.Root().SilenceErrors = true
+		}
+
+		dialect, err := schema.ParseDialect(flags.Dialect)
+		if err != nil {
+			return err
+		}
+		flags.Dialect = string(dialect)
+		if dialect == schema.DialectPostgres {
+			// Load definitions the way Postgres reads them: INT is 4 bytes and
+			// SERIAL columns are backed by a sequence
+			db.ShadowSettings = map[string]string{
+				"default_int_size":     "4",
+				"serial_normalization": "sql_sequence",
+			}
+		}
+
+		db.OnStatementLogError = func(err error) {
+			logging.Warning(fmt.Sprintf("⚠ %v", err))
+		}
+
+		settings, err := parseSessionFlags(sessionFlags)
+		if err != nil {
+			return err
+		}
+		db.ConnectionOptions.SessionSettings = settings
+
+		if cmd.Flags().Lookup("env") != nil {
+			if err := applyEnvironment(afero.NewOsFs(), cmd); err != nil {
+				return err
+			}
+		}
+
+		if err := checkReadOnly(cmd); err != nil {
+			return err
+		}
+
+		if cmd.Flags().Lookup("checksum") != nil {
+			return resolveChecksumMode()
+		}
+		return nil
 	},
 }
 
+// resolveChecksumMode falls back to migrations.checksum in the config file
+// when --checksum isn't set, then to raw
+func resolveChecksumMode() error {
+	if flags.ChecksumMode == "" {
+		cfg, err := config.Load(afero.NewOsFs(), flags.ConfigFile)
+		if err != nil {
+			return err
+		}
+		flags.ChecksumMode = cmp.Or(cfg.Migrations.Checksum, flags.ChecksumRaw)
+	}
+	switch flags.ChecksumMode {
+	case flags.ChecksumRaw, flags.ChecksumLogical:
+		return nil
+	}
+	return fmt.Errorf("invalid --checksum %q: must be raw or logical", flags.ChecksumMode)
+}
+
+// parseSessionFlags parses --session name=value flags
+func parseSessionFlags(values []string) ([]db.SessionSetting, error) {
+	var settings []db.SessionSetting
+	for _, value := range values {
+		name, val, ok := strings.Cut(value, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid --session %q: want name=value", value)
+		}
+		if !db.IsSessionVariableName(name) {
+			return nil, fmt.Errorf("invalid --session name: %q", name)
+		}
+		settings = append(settings, db.SessionSetting{Name: name, Value: val})
+	}
+	return settings, nil
+}
+
 func Execute() error {
 	// Create context with signal handling
 	ctx, cancel := context.WithCancel(context.Background())
@@ -46,6 +140,8 @@ func Execute() error {
 	// Start goroutine to handle signals
 	go func() {
 		<-sigChan
+		// A second interrupt exits right away, without waiting for cleanup
+		signal.Stop(sigChan)
 		if flags.Verbose {
 			fmt.Fprintln(os.Stderr, "\nReceived interrupt signal, canceling...")
 		}
@@ -60,7 +156,24 @@ func Execute() error {
 		db.StopShadowDbServer()
 	}()
 
-	return rootCmd.ExecuteContext(ctx)
+	// Export traces and metrics when configured, flushing them on exit even if
+	// the command was interrupted
+	shutdownTelemetry, err := telemetry.Setup(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ui.Warning(fmt.Sprintf("⚠ Telemetry disabled: %s", err)))
+	} else {
+		defer func() {
+			if err := shutdownTelemetry(context.Background()); err != nil {
+				logging.Warning(fmt.Sprintf("⚠ Failed to export telemetry: %s", err))
+			}
+		}()
+	}
+
+	err = rootCmd.ExecuteContext(ctx)
+	if err != nil && rootCmd.SilenceErrors {
+		logging.Error(err.Error())
+	}
+	return err
 }
 
 // isNoColorEnv checks for the NO_COLOR env var per https://no-color.org/
@@ -71,10 +184,28 @@ func isNoColorEnv() bool {
 }
 
 func init() {
+	db.AppVersion = Version
+
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&db.CrdbVersion, "crdb-version", os.Getenv("CRDB_VERSION"), "CockroachDB version, defaults to latest.")
+	rootCmd.PersistentFlags().StringVar(&db.CrdbVersion, "crdb-version", os.Getenv("CRDB_VERSION"), "CockroachDB version of the shadow database and of the cluster statements are generated for (defaults to latest, or the connected cluster's)")
+
+	rootCmd.PersistentFlags().StringVar(&db.ConnectionOptions.SSLMode, "sslmode", os.Getenv("SCURRY_SSLMODE"), "TLS mode of database connections, e.g. verify-full (also SCURRY_SSLMODE env var)")
+	rootCmd.PersistentFlags().StringVar(&db.ConnectionOptions.SSLRootCert, "sslrootcert", os.Getenv("SCURRY_SSLROOTCERT"), "CA certificate to verify the database server with (also SCURRY_SSLROOTCERT env var)")
+	rootCmd.PersistentFlags().StringVar(&db.ConnectionOptions.SSLCert, "sslcert", os.Getenv("SCURRY_SSLCERT"), "Client certificate to connect to the database with (also SCURRY_SSLCERT env var)")
+	rootCmd.PersistentFlags().StringVar(&db.ConnectionOptions.SSLKey, "sslkey", os.Getenv("SCURRY_SSLKEY"), "Key of the client certificate (also SCURRY_SSLKEY env var)")
+	rootCmd.PersistentFlags().StringVar(&db.ConnectionOptions.ApplicationName, "application-name", os.Getenv("SCURRY_APPLICATION_NAME"), "application_name of database connections (default: the connection URL's, or scurry)")
+	rootCmd.PersistentFlags().StringVar(&db.ConnectionOptions.Cluster, "cluster", os.Getenv("SCURRY_CLUSTER"), "Routing ID of the serverless cluster to connect to, sent as the --cluster connection option (also SCURRY_CLUSTER env var)")
+	rootCmd.PersistentFlags().BoolVar(&db.ConnectionOptions.ReadOnly, "read-only", false, "Connect with default_transaction_read_only and refuse commands that change the database (default: read_only of the --env profile)")
+	rootCmd.PersistentFlags().StringArrayVar(&sessionFlags, "session", nil, "Session variable to set on database connections, as name=value (can be specified multiple times)")
+	_ = rootCmd.RegisterFlagCompletionFunc("sslmode", flags.Choices("disable", "allow", "prefer", "require", "verify-ca", "verify-full"))
+	_ = rootCmd.MarkPersistentFlagFilename("sslrootcert")
+	_ = rootCmd.MarkPersistentFlagFilename("sslcert")
+	_ = rootCmd.MarkPersistentFlagFilename("sslkey")
 
 	flags.AddVerbose(rootCmd)
 	flags.AddForce(rootCmd)
 	flags.AddNoColor(rootCmd)
+	flags.AddConfig(rootCmd)
+	flags.AddLogging(rootCmd)
+	flags.AddDialect(rootCmd)
 }
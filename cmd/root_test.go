@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestParseSessionFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []db.SessionSetting
+		wantErr string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:   "settings",
+			values: []string{"statement_timeout=5min", "search_path=app,public"},
+			want: []db.SessionSetting{
+				{Name: "statement_timeout", Value: "5min"},
+				{Name: "search_path", Value: "app,public"},
+			},
+		},
+		{
+			name:    "missing value",
+			values:  []string{"statement_timeout="},
+			wantErr: `invalid --session "statement_timeout=": want name=value`,
+		},
+		{
+			name:    "invalid name",
+			values:  []string{"a b=1"},
+			wantErr: `invalid --session name: "a b"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := parseSessionFlags(tt.values)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, settings)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Inspect and export the schema",
+	Long:  `Commands that work with the schema as a whole.`,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+var (
+	schemaExportFromDb          bool
+	schemaExportFromDefinitions bool
+	schemaExportOutput          string
+)
+
+var schemaExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the schema as a single SQL file",
+	Long: `Export the schema as one SQL file of CREATE statements, in the order they
+can be run to recreate it. Each statement is preceded by a comment naming the
+object and, when exported from definitions, the file it's defined in.
+
+The schema is read from the definition files by default (--from-definitions),
+which are loaded into a shadow database first so the output is in the same
+canonical form as the database's. Use --from-db to export a live database.
+
+Examples:
+  scurry schema export -o schema.sql
+  scurry schema export --from-db --db-url="..." -o -`,
+	RunE: schemaExport,
+}
+
+func init() {
+	schemaCmd.AddCommand(schemaExportCmd)
+
+	flags.AddDbUrl(schemaExportCmd)
+	flags.AddDefinitionDirs(schemaExportCmd)
+	flags.AddEnv(schemaExportCmd)
+	schemaExportCmd.Flags().BoolVar(&schemaExportFromDb, "from-db", false, "Export the schema of the database at --db-url")
+	schemaExportCmd.Flags().BoolVar(&schemaExportFromDefinitions, "from-definitions", false, "Export the schema in the definition files (the default)")
+	schemaExportCmd.Flags().StringVarP(&schemaExportOutput, "output", "o", "", "File to write, or - for stdout")
+	schemaExportCmd.MarkFlagsMutuallyExclusive("from-db", "from-definitions")
+	schemaExportCmd.MarkFlagRequired("output")
+}
+
+func schemaExport(cmd *cobra.Command, args []string) error {
+	if schemaExportFromDb && flags.DbUrl == "" {
+		return fmt.Errorf("database URL is required with --from-db (use --db-url or CRDB_URL env var)")
+	}
+	if !schemaExportFromDb && len(flags.DefinitionDirs) == 0 {
+		return fmt.Errorf("definition directory is required (use --definitions)")
+	}
+
+	err := doSchemaExport(cmd.Context(), afero.NewOsFs())
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func doSchemaExport(ctx context.Context, fs afero.Fs) error {
+	var sch *schema.Schema
+	var source string
+	if schemaExportFromDb {
+		client, err := db.Connect(ctx, flags.DbUrl)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer client.Close()
+
+		sch, err = schema.LoadFromDatabase(ctx, client)
+		if err != nil {
+			return fmt.Errorf("failed to load database schema: %w", err)
+		}
+		source = "database " + databaseName(flags.DbUrl)
+	} else {
+		client, err := db.GetShadowDB(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer client.Close()
+
+		sch, err = schema.LoadFromDirectoriesForEnv(ctx, fs, flags.DefinitionDirs, flags.Env, client)
+		if err != nil {
+			return fmt.Errorf("failed to load local schema: %w", err)
+		}
+		source = strings.Join(flags.DefinitionDirs, ", ")
+		if flags.Env != "" {
+			source += fmt.Sprintf(" (env %s)", flags.Env)
+		}
+	}
+
+	content, err := exportSchema(sch, source, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if schemaExportOutput == "-" {
+		_, err := io.WriteString(os.Stdout, content)
+		return err
+	}
+	if err := afero.WriteFile(fs, schemaExportOutput, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	logging.Success(fmt.Sprintf("Schema exported to %s", schemaExportOutput))
+	return nil
+}
+
+// exportSchema renders sch as an ordered, commented SQL file
+func exportSchema(sch *schema.Schema, source string, now time.Time) (string, error) {
+	statements, _, err := schema.Compare(sch, schema.NewSchema()).GenerateMigrations(true)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate CREATE statements: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("-- Schema exported by scurry\n")
+	fmt.Fprintf(&b, "-- Source: %s\n", source)
+	fmt.Fprintf(&b, "-- Generated: %s\n", now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "-- %d schemas, %d types, %d sequences, %d routines, %d tables, %d views, %d triggers\n",
+		len(sch.Schemas), len(sch.Types), len(sch.Sequences), len(sch.Routines), len(sch.Tables), len(sch.Views), len(sch.Triggers))
+
+	for _, stmt := range statements {
+		parsed, err := parser.ParseOne(stmt)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse generated statement: %w", err)
+		}
+		switch parsed.AST.(type) {
+		case *tree.BeginTransaction, *tree.CommitTransaction:
+			// The file is run statement by statement, not as a migration
+			continue
+		}
+
+		b.WriteString("\n")
+		if comment := describeExportedStatement(sch, parsed.AST); comment != "" {
+			b.WriteString("-- " + comment + "\n")
+		}
+		b.WriteString(stmt + ";\n")
+	}
+	return b.String(), nil
+}
+
+// describeExportedStatement names the object a statement creates, and where
+// it's defined if known
+func describeExportedStatement(sch *schema.Schema, stmt tree.Statement) string {
+	var kind, name string
+	lookup := ""
+	switch s := stmt.(type) {
+	case *tree.CreateSchema:
+		kind, name = "Schema", s.Schema.Schema()
+		lookup = "schema:" + name
+	case *tree.CreateType:
+		kind, name = "Type", qualifiedObjectName(s.TypeName.Schema(), s.TypeName.Object())
+	case *tree.CreateSequence:
+		kind, name = "Sequence", qualifiedObjectName(s.Name.Schema(), s.Name.Object())
+	case *tree.CreateTable:
+		kind, name = "Table", qualifiedObjectName(s.Table.Schema(), s.Table.Table())
+	case *tree.CreateView:
+		kind, name = "View", qualifiedObjectName(s.Name.Schema(), s.Name.Object())
+	case *tree.CreateRoutine:
+		kind, name = "Function", qualifiedObjectName(s.Name.Schema(), s.Name.Object())
+		if s.IsProcedure {
+			kind = "Procedure"
+		}
+	case *tree.CreateIndex:
+		kind, name = "Index", qualifiedObjectName(s.Table.Schema(), s.Table.Table())+"@"+s.Name.String()
+	case *tree.CreateTrigger:
+		kind, name = "Trigger", s.Name.String()
+	default:
+		return stmt.StatementTag()
+	}
+
+	if lookup == "" {
+		lookup = name
+	}
+	if loc := sch.SourceOf(lookup); !loc.IsZero() {
+		return fmt.Sprintf("%s %s (%s)", kind, name, loc)
+	}
+	return kind + " " + name
+}
+
+func qualifiedObjectName(schemaName, name string) string {
+	if schemaName == "" {
+		schemaName = "public"
+	}
+	return schemaName + "." + name
+}
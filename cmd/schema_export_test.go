@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestExportSchema(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "defs/types.sql", []byte("CREATE TYPE status AS ENUM ('a', 'b');"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/tables.sql", []byte(`
+CREATE TABLE users (id INT8 PRIMARY KEY, status status NOT NULL);
+
+CREATE TABLE posts (id INT8 PRIMARY KEY, user_id INT8 NOT NULL REFERENCES users (id));
+`), 0644))
+
+	sch, err := schema.LoadDefinitions(fs, []string{"defs"}, "")
+	require.NoError(t, err)
+
+	content, err := exportSchema(sch, "defs", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(content, "-- Schema exported by scurry\n-- Source: defs\n-- Generated: 2026-01-02T03:04:05Z\n"))
+	assert.Contains(t, content, "-- 0 schemas, 1 types, 0 sequences, 0 routines, 2 tables, 0 views, 0 triggers\n")
+	assert.NotContains(t, content, "COMMIT")
+
+	// Objects come in dependency order, each with where it's defined
+	typeAt := strings.Index(content, "-- Type public.status (defs/types.sql:1)\nCREATE TYPE")
+	usersAt := strings.Index(content, "-- Table public.users (defs/tables.sql:2)\nCREATE TABLE")
+	postsAt := strings.Index(content, "-- Table public.posts (defs/tables.sql:4)\nCREATE TABLE")
+	require.NotEqual(t, -1, typeAt)
+	require.NotEqual(t, -1, usersAt)
+	require.NotEqual(t, -1, postsAt)
+	assert.Less(t, typeAt, usersAt)
+	assert.Less(t, usersAt, postsAt)
+}
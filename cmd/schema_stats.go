@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+var (
+	schemaStatsJSON            bool
+	schemaStatsRanges          int
+	schemaStatsWriteTableSizes bool
+	schemaStatsThreshold       int64
+)
+
+var schemaStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show table, index and range sizes of a database",
+	Long: `Connect to a database and print each table's estimated row count and
+approximate size on disk, the size of each of its indexes, and the largest
+ranges holding its data. Sizes come from the ranges' statistics, so they lag
+behind recent writes.
+
+With --write-table-sizes, the row counts are also written to
+migrations/table_sizes.yaml, the file 'scurry migration gen' uses to classify
+migrations as sync or async.
+
+Examples:
+  scurry schema stats --db-url="..."
+  scurry schema stats --db-url="..." --json --ranges 20
+  scurry schema stats --db-url="..." --write-table-sizes`,
+	RunE: schemaStats,
+}
+
+func init() {
+	schemaCmd.AddCommand(schemaStatsCmd)
+
+	flags.AddDbUrl(schemaStatsCmd)
+	schemaStatsCmd.Flags().BoolVar(&schemaStatsJSON, "json", false, "Print the statistics as JSON")
+	schemaStatsCmd.Flags().IntVar(&schemaStatsRanges, "ranges", 10, "Show this many of the largest ranges (0 for none)")
+	schemaStatsCmd.Flags().BoolVar(&schemaStatsWriteTableSizes, "write-table-sizes", false, "Also write the row counts to table_sizes.yaml")
+	schemaStatsCmd.Flags().Int64Var(&schemaStatsThreshold, "large-table-threshold", int64(migrationpkg.DefaultLargeTableThreshold), "Row count threshold for classifying tables as large, with --write-table-sizes")
+}
+
+func schemaStats(cmd *cobra.Command, args []string) error {
+	if flags.DbUrl == "" {
+		return fmt.Errorf("database URL is required (use --db-url or CRDB_URL env var)")
+	}
+	if schemaStatsRanges < 0 {
+		return fmt.Errorf("--ranges must not be negative")
+	}
+
+	err := doSchemaStats(cmd.Context(), afero.NewOsFs())
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// schemaStatsResult is what 'scurry schema stats' prints
+type schemaStatsResult struct {
+	Tables []tableStats   `json:"tables"`
+	Ranges []db.RangeInfo `json:"largest_ranges"`
+}
+
+// tableStats is the size of one table and of each of its indexes
+type tableStats struct {
+	Name    string             `json:"name"` // "schema.table"
+	Rows    int64              `json:"rows"`
+	Bytes   int64              `json:"bytes"`
+	Indexes []db.IndexSizeInfo `json:"indexes"`
+}
+
+func doSchemaStats(ctx context.Context, fs afero.Fs) error {
+	if schemaStatsWriteTableSizes {
+		if err := validateMigrationsDir(fs); err != nil {
+			return err
+		}
+	}
+
+	dbClient, err := db.Connect(ctx, flags.DbUrl)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+
+	tables, err := dbClient.GetTableSizes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get table sizes: %w", err)
+	}
+	indexes, err := dbClient.GetIndexSizes(ctx)
+	if err != nil {
+		return err
+	}
+	var ranges []db.RangeInfo
+	if schemaStatsRanges > 0 {
+		ranges, err = dbClient.GetLargestRanges(ctx, schemaStatsRanges)
+		if err != nil {
+			return err
+		}
+	}
+
+	stats := buildSchemaStats(tables, indexes, ranges)
+	if schemaStatsJSON {
+		if err := writeSchemaStatsJSON(os.Stdout, stats); err != nil {
+			return err
+		}
+	} else {
+		logging.Print(renderSchemaStats(stats))
+	}
+
+	if schemaStatsWriteTableSizes {
+		ts := tableSizesFromInfo(tables, schemaStatsThreshold)
+		if err := migrationpkg.SaveTableSizes(fs, flags.MigrationDir, ts); err != nil {
+			return fmt.Errorf("failed to save table_sizes.yaml: %w", err)
+		}
+		// Logging shares stdout, which must stay valid JSON
+		if !schemaStatsJSON {
+			logging.Success(fmt.Sprintf("✓ Wrote table_sizes.yaml with %d table(s) (threshold: %d rows)", len(ts.Tables), schemaStatsThreshold))
+		}
+	}
+	return nil
+}
+
+// buildSchemaStats groups the index sizes under their tables. Tables keep the
+// order they were read in, largest ranges first.
+func buildSchemaStats(tables []db.TableSizeInfo, indexes []db.IndexSizeInfo, ranges []db.RangeInfo) schemaStatsResult {
+	stats := schemaStatsResult{
+		Tables: make([]tableStats, 0, len(tables)),
+		Ranges: ranges,
+	}
+	if stats.Ranges == nil {
+		stats.Ranges = []db.RangeInfo{}
+	}
+
+	byName := make(map[string]int, len(tables))
+	for _, t := range tables {
+		name := t.SchemaName + "." + t.TableName
+		byName[name] = len(stats.Tables)
+		stats.Tables = append(stats.Tables, tableStats{Name: name, Rows: t.Rows, Bytes: t.Bytes, Indexes: []db.IndexSizeInfo{}})
+	}
+	for _, idx := range indexes {
+		i, ok := byName[idx.SchemaName+"."+idx.TableName]
+		if !ok {
+			continue
+		}
+		stats.Tables[i].Indexes = append(stats.Tables[i].Indexes, idx)
+	}
+	return stats
+}
+
+func writeSchemaStatsJSON(w io.Writer, stats schemaStatsResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
+
+func renderSchemaStats(stats schemaStatsResult) string {
+	if len(stats.Tables) == 0 {
+		return "No tables"
+	}
+
+	var tableRows, indexRows [][]string
+	for _, t := range stats.Tables {
+		tableRows = append(tableRows, []string{t.Name, strconv.FormatInt(t.Rows, 10), formatBytes(t.Bytes), strconv.Itoa(len(t.Indexes))})
+		for _, idx := range t.Indexes {
+			indexRows = append(indexRows, []string{t.Name, idx.IndexName, formatBytes(idx.Bytes)})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("Tables\n")
+	b.WriteString(ui.Table([]string{"Table", "Rows", "Size", "Indexes"}, tableRows))
+	if len(indexRows) > 0 {
+		b.WriteString("\n\nIndexes\n")
+		b.WriteString(ui.Table([]string{"Table", "Index", "Size"}, indexRows))
+	}
+	if len(stats.Ranges) > 0 {
+		var rangeRows [][]string
+		for _, r := range stats.Ranges {
+			rangeRows = append(rangeRows, []string{strconv.FormatInt(r.RangeID, 10), formatBytes(r.Bytes), strings.Join(r.Tables, ", ")})
+		}
+		b.WriteString("\n\nLargest ranges\n")
+		b.WriteString(ui.Table([]string{"Range", "Size", "Tables"}, rangeRows))
+	}
+	return b.String()
+}
+
+// formatBytes formats a size in bytes with a binary unit, e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
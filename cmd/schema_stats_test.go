@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestBuildSchemaStats(t *testing.T) {
+	tables := []db.TableSizeInfo{
+		{SchemaName: "public", TableName: "users", Rows: 1000, Bytes: 2048},
+		{SchemaName: "public", TableName: "posts", Rows: 10, Bytes: 100},
+	}
+	indexes := []db.IndexSizeInfo{
+		{SchemaName: "public", TableName: "posts", IndexName: "posts_pkey", Bytes: 100},
+		{SchemaName: "public", TableName: "users", IndexName: "users_pkey", Bytes: 1500},
+		{SchemaName: "public", TableName: "users", IndexName: "users_email_idx", Bytes: 548},
+		{SchemaName: "public", TableName: "dropped", IndexName: "dropped_pkey", Bytes: 1},
+	}
+	ranges := []db.RangeInfo{{RangeID: 70, Bytes: 2148, Tables: []string{"public.posts", "public.users"}}}
+
+	stats := buildSchemaStats(tables, indexes, ranges)
+
+	require.Len(t, stats.Tables, 2)
+	assert.Equal(t, "public.users", stats.Tables[0].Name)
+	assert.Equal(t, int64(1000), stats.Tables[0].Rows)
+	require.Len(t, stats.Tables[0].Indexes, 2)
+	assert.Equal(t, "users_pkey", stats.Tables[0].Indexes[0].IndexName)
+	assert.Equal(t, "public.posts", stats.Tables[1].Name)
+	require.Len(t, stats.Tables[1].Indexes, 1)
+	assert.Equal(t, ranges, stats.Ranges)
+
+	rendered := renderSchemaStats(stats)
+	assert.Contains(t, rendered, "users_email_idx")
+	assert.Contains(t, rendered, "2.0 KiB")
+	assert.Contains(t, rendered, "public.posts, public.users")
+	assert.NotContains(t, rendered, "dropped_pkey")
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSchemaStatsJSON(&buf, stats))
+	var decoded schemaStatsResult
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, stats, decoded)
+}
+
+func TestBuildSchemaStatsEmpty(t *testing.T) {
+	stats := buildSchemaStats(nil, nil, nil)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSchemaStatsJSON(&buf, stats))
+	assert.JSONEq(t, `{"tables": [], "largest_ranges": []}`, buf.String())
+	assert.Equal(t, "No tables", renderSchemaStats(stats))
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatBytes(tt.bytes))
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/seed"
+)
+
+var seedCmd = &cobra.Command{
+	Use:         "seed",
+	Short:       "Apply seed data from the definitions directory",
+	Annotations: writesDatabase,
+	Long: `Apply the reference data in <definitions>/seed/*.sql to the database.
+
+Seed files hold rows for lookup tables, like enum-like tables and feature
+flags. Each statement must be an INSERT with an ON CONFLICT clause, or an
+UPSERT, so seeding again only adds the rows that are missing:
+
+  INSERT INTO plans (id, name) VALUES (1, 'free'), (2, 'pro')
+  ON CONFLICT DO NOTHING;
+
+Files are applied in name order, all in one transaction, so name them to insert
+parent tables first (e.g. 01_plans.sql, 02_features.sql). With --dry-run, the
+rows that would be inserted are counted and nothing is changed.
+
+The same data is applied after the schema by scurry push --with-seed.`,
+	RunE: runSeed,
+}
+
+var seedDryRun bool
+
+func init() {
+	rootCmd.AddCommand(seedCmd)
+
+	flags.AddDbUrl(seedCmd)
+	flags.AddDefinitionDirs(seedCmd)
+	seedCmd.Flags().BoolVar(&seedDryRun, "dry-run", false, "Show how many rows would be inserted without changing anything")
+}
+
+func runSeed(cmd *cobra.Command, args []string) error {
+	if flags.DbUrl == "" {
+		return fmt.Errorf("database URL is required (use --db-url or CRDB_URL env var)")
+	}
+	if len(flags.DefinitionDirs) == 0 {
+		return fmt.Errorf("definition directory is required (use --definitions)")
+	}
+
+	err := doSeed(cmd.Context())
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func doSeed(ctx context.Context) error {
+	client, err := db.Connect(ctx, flags.DbUrl)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer client.Close()
+
+	return applySeed(ctx, afero.NewOsFs(), client, flags.DefinitionDirs, seedDryRun)
+}
+
+// applySeed applies the seed data in definitionDirs, printing how many rows
+// were inserted into each table
+func applySeed(ctx context.Context, fs afero.Fs, client *db.Client, definitionDirs []string, dryRun bool) error {
+	statements, err := seed.Load(fs, definitionDirs)
+	if err != nil {
+		return fmt.Errorf("failed to load seed data: %w", err)
+	}
+	if len(statements) == 0 {
+		logging.Subtle("No seed data found.")
+		return nil
+	}
+
+	logging.Newline()
+	logging.Info("⟳ Applying seed data...")
+	results, err := seed.Apply(ctx, client, statements, dryRun)
+	if err != nil {
+		return err
+	}
+	logging.Print(seed.Summary(results))
+
+	if dryRun {
+		logging.Info("ℹ Dry run mode - no seed data applied.")
+	} else {
+		logging.Success("✓ Seed data applied")
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaServerRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		method     string
+		path       string
+		header     string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "health check",
+			token:      "secret",
+			method:     http.MethodGet,
+			path:       "/healthz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing token",
+			token:      "secret",
+			method:     http.MethodPost,
+			path:       "/diff",
+			body:       `{"files": {"definitions/a.sql": "CREATE TABLE a (id INT PRIMARY KEY)"}}`,
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "bearer token",
+		},
+		{
+			name:       "wrong token",
+			token:      "secret",
+			method:     http.MethodPost,
+			path:       "/diff",
+			header:     "Bearer guess",
+			body:       `{"files": {"definitions/a.sql": "CREATE TABLE a (id INT PRIMARY KEY)"}}`,
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "bearer token",
+		},
+		{
+			name:       "invalid body",
+			method:     http.MethodPost,
+			path:       "/lint",
+			body:       `{"files": `,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid request body",
+		},
+		{
+			name:       "no source",
+			method:     http.MethodPost,
+			path:       "/lint",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "no files or git source",
+		},
+		{
+			name:       "both sources",
+			method:     http.MethodPost,
+			path:       "/lint",
+			body:       `{"files": {"definitions/a.sql": ""}, "git": {"url": "https://example.com/app.git"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "either files or git",
+		},
+		{
+			name:       "git option injection",
+			method:     http.MethodPost,
+			path:       "/lint",
+			body:       `{"git": {"url": "--upload-pack=touch /tmp/x"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid git source",
+		},
+		{
+			name:       "local git repository",
+			method:     http.MethodPost,
+			path:       "/lint",
+			body:       `{"git": {"url": "file:///etc"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "must start with https:// or ssh://",
+		},
+		{
+			name:       "git ref option injection",
+			method:     http.MethodPost,
+			path:       "/lint",
+			body:       `{"git": {"url": "https://example.com/app.git", "ref": "--upload-pack=touch /tmp/x"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid git source",
+		},
+		{
+			name:       "database from the request",
+			method:     http.MethodPost,
+			path:       "/diff",
+			body:       `{"files": {"definitions/a.sql": ""}, "db_url": "postgresql://root@attacker:26257/db"}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  `unknown field "db_url"`,
+		},
+		{
+			name:       "diff without a database",
+			token:      "secret",
+			method:     http.MethodPost,
+			path:       "/diff",
+			header:     "Bearer secret",
+			body:       `{"files": {"definitions/a.sql": "CREATE TABLE a (id INT PRIMARY KEY)"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "database URL is required",
+		},
+		{
+			name:       "migration status without a database",
+			method:     http.MethodPost,
+			path:       "/migrations/status",
+			body:       `{"files": {"migrations/20240101000000_init/migration.sql": "CREATE TABLE a (id INT PRIMARY KEY)"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "database URL is required",
+		},
+		{
+			name:       "unknown endpoint",
+			method:     http.MethodPost,
+			path:       "/apply",
+			body:       `{}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(newSchemaServer("", tt.token).handler())
+			defer server.Close()
+
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantError != "" {
+				var body map[string]string
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				assert.Contains(t, body["error"], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCheckServeAddr(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		token   string
+		wantErr string
+	}{
+		{name: "loopback", addr: "127.0.0.1:8080"},
+		{name: "ipv6 loopback", addr: "[::1]:8080"},
+		{name: "localhost", addr: "localhost:8080"},
+		{name: "every interface", addr: ":8080", wantErr: "--token (or SCURRY_SERVE_TOKEN) is required to listen on :8080"},
+		{name: "public address", addr: "10.0.0.5:8080", wantErr: "isn't a loopback address"},
+		{name: "every interface with a token", addr: ":8080", token: "secret"},
+		{name: "invalid address", addr: "8080", wantErr: "invalid --addr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkServeAddr(tt.addr, tt.token)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSchemaServerCheckout(t *testing.T) {
+	server := newSchemaServer("", "")
+	var cloned gitSource
+	server.clone = func(ctx context.Context, src gitSource, dir string) error {
+		cloned = src
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "definitions"), 0755))
+		return os.WriteFile(filepath.Join(dir, "definitions", "users.sql"), []byte("CREATE TABLE users (id INT PRIMARY KEY);"), 0644)
+	}
+
+	t.Run("files", func(t *testing.T) {
+		fs, cleanup, err := server.checkout(context.Background(), serveRequest{Files: map[string]string{"definitions/users.sql": "CREATE TABLE users (id INT PRIMARY KEY);"}})
+		require.NoError(t, err)
+		defer cleanup()
+
+		content, err := afero.ReadFile(fs, "definitions/users.sql")
+		require.NoError(t, err)
+		assert.Equal(t, "CREATE TABLE users (id INT PRIMARY KEY);", string(content))
+	})
+
+	t.Run("git", func(t *testing.T) {
+		fs, cleanup, err := server.checkout(context.Background(), serveRequest{Git: &gitSource{URL: "https://example.com/app.git", Ref: "v1"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, gitSource{URL: "https://example.com/app.git", Ref: "v1"}, cloned)
+		content, err := afero.ReadFile(fs, "./definitions/users.sql")
+		require.NoError(t, err)
+		assert.Equal(t, "CREATE TABLE users (id INT PRIMARY KEY);", string(content))
+
+		realPath, err := fs.(*afero.BasePathFs).RealPath("definitions")
+		require.NoError(t, err)
+		cleanup()
+		_, err = os.Stat(realPath)
+		assert.True(t, os.IsNotExist(err), "the clone should be removed after the request")
+	})
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/flags"
+	migrationpkg "github.com/pjtatlow/scurry/internal/migration"
+)
+
+var tableSizesCmd = &cobra.Command{
+	Use:   "table-sizes",
+	Short: "Manage table_sizes.yaml",
+	Long: `Commands that maintain migrations/table_sizes.yaml, the table row counts
+'scurry migration gen' uses to classify migrations as sync or async.`,
+}
+
+var tableSizesRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Fetch table sizes from the database and write table_sizes.yaml",
+	Long: `Query the database for each table's estimated row count and approximate
+size on disk, then write the results to migrations/table_sizes.yaml.
+
+Run this regularly (e.g. from a scheduled CI job) so classification keeps up
+with how the tables grow. With --merge, only the tables missing from the file
+are added, each recording where and when it was captured. To skip the file, pass --db-url to
+'scurry migration gen' and it reads the sizes from the database directly.`,
+	RunE: runMigrationStatPull,
+}
+
+func init() {
+	rootCmd.AddCommand(tableSizesCmd)
+	tableSizesCmd.AddCommand(tableSizesRefreshCmd)
+
+	flags.AddDbUrl(tableSizesRefreshCmd)
+	tableSizesRefreshCmd.Flags().Int64Var(&largeTableThreshold, "large-table-threshold", int64(migrationpkg.DefaultLargeTableThreshold), "Row count threshold for classifying tables as large")
+	tableSizesRefreshCmd.Flags().BoolVar(&tableSizesMerge, "merge", false, "Only add the tables missing from table_sizes.yaml, keeping the sizes it has")
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// targetVersion returns the CockroachDB release to generate statements for:
+// --crdb-version when it's set, otherwise the release client is connected to.
+// It returns nil when neither is known, and statements aren't adjusted.
+func targetVersion(ctx context.Context, client *db.Client) (*schema.Version, error) {
+	if db.CrdbVersion != "" {
+		v, err := schema.ParseVersion(db.CrdbVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --crdb-version: %w", err)
+		}
+		return &v, nil
+	}
+	if client == nil {
+		return nil, nil
+	}
+
+	serverVersion, err := client.GetServerVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v, err := schema.ParseVersion(serverVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
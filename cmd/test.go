@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+	"github.com/pjtatlow/scurry/internal/schematest"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run schema tests against a shadow database",
+	Long: `Load the definitions into a shadow database and run the queries in
+<definitions>/tests/*.sql against it, to catch index regressions and broken
+constraints before they reach production.
+
+Each file runs in order against its own fresh shadow database. Statements
+without a -- scurry:test directive set up data for the tests after them. A
+test is the statement below a -- scurry:test directive, with the expect-*
+directives after it saying what must hold:
+
+  INSERT INTO users (id, email) VALUES (1, 'a@example.com');
+
+  -- scurry:test email lookups use the email index
+  -- scurry:expect-index=users@users_email_key
+  -- scurry:expect-no-full-scan
+  -- scurry:expect-result
+  -- 1 | a@example.com
+  SELECT id, email FROM users WHERE email = 'a@example.com';
+
+  -- scurry:test emails are unique
+  -- scurry:expect-error=duplicate key
+  INSERT INTO users (id, email) VALUES (2, 'a@example.com');
+
+Directives:
+  scurry:expect-index=<index>   the plan (EXPLAIN) reads the index, as index or table@index
+  scurry:expect-no-full-scan    the plan doesn't scan a whole table or index
+  scurry:expect-rows=<n>        the statement returns or affects n rows
+  scurry:expect-result          the rows returned, one comment line per row, columns separated by |
+  scurry:expect-error=<text>    the statement fails with an error containing text
+
+A test without expect directives passes if its statement succeeds. The plan
+is checked before the statement runs, so tests that change data can still
+check their plan.`,
+	RunE: runTest,
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+
+	flags.AddDefinitionDirs(testCmd)
+	flags.AddEnv(testCmd)
+}
+
+func runTest(cmd *cobra.Command, args []string) error {
+	if len(flags.DefinitionDirs) == 0 {
+		return fmt.Errorf("definition directory is required (use --definitions)")
+	}
+
+	err := doTest(cmd.Context(), afero.NewOsFs(), flags.DefinitionDirs, flags.Env)
+	if err != nil {
+		logging.Error(fmt.Sprintf("Error: %v", err))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func doTest(ctx context.Context, fs afero.Fs, definitionDirs []string, env string) error {
+	files, err := schematest.Load(fs, definitionDirs)
+	if err != nil {
+		return fmt.Errorf("failed to load schema tests: %w", err)
+	}
+	if len(files) == 0 {
+		logging.Subtle(fmt.Sprintf("No schema tests found in %s.", strings.Join(testsDirs(definitionDirs), ", ")))
+		return nil
+	}
+
+	logging.Debug(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(definitionDirs, ", ")))
+	shadowClient, err := db.GetShadowDB(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get shadow database client: %w", err)
+	}
+	localSchema, err := schema.LoadFromDirectoriesForEnv(ctx, fs, definitionDirs, env, shadowClient)
+	shadowClient.Close()
+	if err != nil {
+		return fmt.Errorf("failed to load local schema: %w", err)
+	}
+
+	statements, _, err := schema.Compare(localSchema, schema.NewSchema()).GenerateMigrations(false)
+	if err != nil {
+		return fmt.Errorf("failed to generate schema statements: %w", err)
+	}
+
+	var results []schematest.Result
+	for _, file := range files {
+		fileResults, err := runTestFile(ctx, file, statements)
+		results = append(results, fileResults...)
+		if err != nil {
+			printTestResults(results)
+			return fmt.Errorf("in file %s: %w", file.Path, err)
+		}
+	}
+
+	passed, failed := printTestResults(results)
+	logging.Newline()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d schema test(s) failed", failed, passed+failed)
+	}
+	logging.Success(fmt.Sprintf("✓ %d schema test(s) passed", passed))
+	return nil
+}
+
+// runTestFile runs a test file against a new shadow database with the schema
+func runTestFile(ctx context.Context, file schematest.File, statements []string) ([]schematest.Result, error) {
+	client, err := db.GetShadowDB(ctx, statements...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shadow database: %w", err)
+	}
+	defer client.Close()
+
+	return schematest.Run(ctx, client, file)
+}
+
+// printTestResults prints each test and why it failed, returning how many
+// passed and failed
+func printTestResults(results []schematest.Result) (passed, failed int) {
+	file := ""
+	for _, result := range results {
+		if result.File != file {
+			file = result.File
+			logging.Newline()
+			logging.Header(file)
+		}
+		if result.Passed() {
+			passed++
+			logging.Success(fmt.Sprintf("  ✓ %s", result.Name))
+			continue
+		}
+		failed++
+		logging.Error(fmt.Sprintf("  ✗ %s", result.Name))
+		for _, failure := range result.Failures {
+			logging.Print(fmt.Sprintf("    %s", strings.ReplaceAll(failure, "\n", "\n    ")))
+		}
+	}
+	return passed, failed
+}
+
+func testsDirs(definitionDirs []string) []string {
+	dirs := make([]string, len(definitionDirs))
+	for i, dir := range definitionDirs {
+		dirs[i] = filepath.Join(dir, schema.TestsDir)
+	}
+	return dirs
+}
@@ -11,8 +11,8 @@ import (
 
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	"github.com/pjtatlow/scurry/internal/schema"
-	"github.com/pjtatlow/scurry/internal/ui"
 )
 
 var testserverCmd = &cobra.Command{
@@ -31,6 +31,7 @@ func init() {
 	rootCmd.AddCommand(testserverCmd)
 
 	flags.AddDefinitionDirs(testserverCmd)
+	flags.AddEnv(testserverCmd)
 
 	testserverCmd.Flags().StringVar(&urlFile, "url-file", "", "File to write the database URL to when it's ready")
 	testserverCmd.Flags().StringVar(&db.TestServerHost, "host", "", "Host address for the test database server")
@@ -51,7 +52,7 @@ func runTestserver(cmd *cobra.Command, args []string) error {
 
 	err := doTestserver(ctx, urlFile)
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -60,9 +61,7 @@ func runTestserver(cmd *cobra.Command, args []string) error {
 
 func doTestserver(ctx context.Context, urlFile string) error {
 	// Start test server
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Starting CRDB test server..."))
-	}
+	logging.Debug("→ Starting CRDB test server...")
 
 	dbClient, err := db.GetShadowDB(ctx)
 	if err != nil {
@@ -73,22 +72,18 @@ func doTestserver(ctx context.Context, urlFile string) error {
 	testServerUrl := dbClient.ConnectionString()
 
 	// Load local schema
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(flags.DefinitionDirs, ", "))))
-	}
-	testSchema, err := schema.LoadFromDirectories(ctx, afero.NewOsFs(), flags.DefinitionDirs, dbClient)
+	logging.Debug(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(flags.DefinitionDirs, ", ")))
+	testSchema, err := schema.LoadFromDirectoriesForEnv(ctx, afero.NewOsFs(), flags.DefinitionDirs, flags.Env, dbClient)
 	if err != nil {
 		return fmt.Errorf("failed to load local schema: %w", err)
 	}
 	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views locally",
-			len(testSchema.Tables), len(testSchema.Types), len(testSchema.Routines), len(testSchema.Sequences), len(testSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Found %d tables, %d types, %d routines, %d sequences, %d views locally",
+			len(testSchema.Tables), len(testSchema.Types), len(testSchema.Routines), len(testSchema.Sequences), len(testSchema.Views)))
 	}
 
 	// Write URL to file
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Writing database URL to %s...", urlFile)))
-	}
+	logging.Debug(fmt.Sprintf("→ Writing database URL to %s...", urlFile))
 
 	err = os.WriteFile(urlFile, []byte(testServerUrl), 0644)
 	if err != nil {
@@ -96,30 +91,28 @@ func doTestserver(ctx context.Context, urlFile string) error {
 	}
 
 	if flags.Verbose {
-		fmt.Println(ui.Success(fmt.Sprintf("✓ Database URL written to %s", urlFile)))
+		logging.Success(fmt.Sprintf("✓ Database URL written to %s", urlFile))
 	}
 
 	// Print success message
-	fmt.Println()
-	fmt.Println(ui.Success("✓ Test database is ready!"))
-	fmt.Println(ui.Info(fmt.Sprintf("  Database URL: %s", testServerUrl)))
-	fmt.Println(ui.Info(fmt.Sprintf("  URL file: %s", urlFile)))
-	fmt.Println()
-	fmt.Println(ui.Subtle("Press Ctrl+C to stop the test server..."))
+	logging.Newline()
+	logging.Success("✓ Test database is ready!")
+	logging.Info(fmt.Sprintf("  Database URL: %s", testServerUrl))
+	logging.Info(fmt.Sprintf("  URL file: %s", urlFile))
+	logging.Newline()
+	logging.Subtle("Press Ctrl+C to stop the test server...")
 
 	// Wait for interrupt signal
 	<-ctx.Done()
 
-	fmt.Println()
-	if flags.Verbose {
-		fmt.Println(ui.Subtle("→ Stopping test server..."))
-	}
+	logging.Newline()
+	logging.Debug("→ Stopping test server...")
 
 	// Clean up URL file
 	os.Remove(urlFile)
 
 	if flags.Verbose {
-		fmt.Println(ui.Success("✓ Test server stopped"))
+		logging.Success("✓ Test server stopped")
 	}
 
 	return nil
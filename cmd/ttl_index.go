@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/lint"
+	"github.com/pjtatlow/scurry/internal/logging"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// ttlIndex is the index a table's new TTL expiration expression needs
+type ttlIndex struct {
+	tableName string
+	table     *tree.CreateTable
+	column    string
+}
+
+// newTTLIndexes returns the index the ttl-missing-index lint rule asks for on
+// each table whose ttl_expiration_expression is new or changed in local, so it
+// can be created with the TTL instead of in a later change.
+func newTTLIndexes(local, remote *schema.Schema) []ttlIndex {
+	remoteExprs := make(map[string]string, len(remote.Tables))
+	for _, table := range remote.Tables {
+		remoteExprs[table.ResolvedName()] = lint.TTLExpression(table.Ast)
+	}
+
+	var indexes []ttlIndex
+	for _, table := range local.Tables {
+		tableName := table.ResolvedName()
+		expr := lint.TTLExpression(table.Ast)
+		if expr == "" || expr == remoteExprs[tableName] {
+			continue
+		}
+		cols := lint.UncoveredTTLColumns(table.Ast)
+		if len(cols) == 0 {
+			continue
+		}
+		indexes = append(indexes, ttlIndex{tableName: tableName, table: table.Ast, column: cols[0]})
+	}
+	return indexes
+}
+
+// def returns the index definition, named the way CockroachDB names an
+// index created without one
+func (i ttlIndex) def() *tree.IndexTableDef {
+	return &tree.IndexTableDef{
+		Name:    tree.Name(fmt.Sprintf("%s_%s_idx", i.table.Table.Table(), i.column)),
+		Columns: tree.IndexElemList{{Column: tree.Name(i.column), Direction: tree.Ascending}},
+	}
+}
+
+// pairTTLIndexes warns about tables gaining a TTL expiration expression
+// without an index the TTL job can use. With add, the index is added to the
+// local schema so the same push or migration creates it.
+func pairTTLIndexes(local, remote *schema.Schema, add bool) {
+	for _, idx := range newTTLIndexes(local, remote) {
+		def := idx.def()
+		if !add {
+			logging.Warning(fmt.Sprintf(
+				"⚠ %s gains a TTL expiration expression but no index starts with %s, so the TTL job scans the whole table. Add INDEX (%s) to the table definition, or pass --ttl-index to create it now.",
+				idx.tableName, idx.column, idx.column,
+			))
+			continue
+		}
+		idx.table.Defs = append(idx.table.Defs, def)
+		logging.Warning(fmt.Sprintf(
+			"⚠ Creating INDEX %s (%s) on %s for its TTL expiration expression. Add it to the table definition too, or a later change drops it.",
+			def.Name, idx.column, idx.tableName,
+		))
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/lint"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestNewTTLIndexes(t *testing.T) {
+	const withTTL = `CREATE TABLE public.sessions (
+		id INT8 NOT NULL,
+		expires_at TIMESTAMPTZ,
+		CONSTRAINT sessions_pkey PRIMARY KEY (id)
+	) WITH (ttl_expiration_expression = 'expires_at')`
+	const withoutTTL = `CREATE TABLE public.sessions (
+		id INT8 NOT NULL,
+		expires_at TIMESTAMPTZ,
+		CONSTRAINT sessions_pkey PRIMARY KEY (id)
+	)`
+
+	tests := []struct {
+		name      string
+		local     string
+		remote    string
+		wantIndex string
+	}{
+		{
+			name:      "new table with TTL",
+			local:     withTTL,
+			wantIndex: "INDEX sessions_expires_at_idx (expires_at ASC)",
+		},
+		{
+			name:      "existing table gains TTL",
+			local:     withTTL,
+			remote:    withoutTTL,
+			wantIndex: "INDEX sessions_expires_at_idx (expires_at ASC)",
+		},
+		{
+			name:   "TTL unchanged",
+			local:  withTTL,
+			remote: withTTL,
+		},
+		{
+			name:   "no TTL",
+			local:  withoutTTL,
+			remote: withoutTTL,
+		},
+		{
+			name: "TTL with covering index",
+			local: `CREATE TABLE public.sessions (
+				id INT8 NOT NULL,
+				expires_at TIMESTAMPTZ,
+				CONSTRAINT sessions_pkey PRIMARY KEY (id),
+				INDEX sessions_expires_at_idx (expires_at ASC)
+			) WITH (ttl_expiration_expression = 'expires_at')`,
+			remote: withoutTTL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := &schema.Schema{Tables: []schema.ObjectSchema[*tree.CreateTable]{parseTestTable(t, tt.local)}}
+			remote := &schema.Schema{}
+			if tt.remote != "" {
+				remote.Tables = append(remote.Tables, parseTestTable(t, tt.remote))
+			}
+
+			indexes := newTTLIndexes(local, remote)
+			if tt.wantIndex == "" {
+				assert.Empty(t, indexes)
+				return
+			}
+			require.Len(t, indexes, 1)
+			assert.Equal(t, "public.sessions", indexes[0].tableName)
+			assert.Equal(t, tt.wantIndex, tree.AsString(indexes[0].def()))
+		})
+	}
+}
+
+func TestPairTTLIndexes(t *testing.T) {
+	local := &schema.Schema{Tables: []schema.ObjectSchema[*tree.CreateTable]{parseTestTable(t, `CREATE TABLE public.tokens (
+		id INT8 NOT NULL,
+		expires_at TIMESTAMPTZ,
+		CONSTRAINT tokens_pkey PRIMARY KEY (id)
+	) WITH (ttl_expiration_expression = 'expires_at')`)}}
+
+	pairTTLIndexes(local, &schema.Schema{}, false)
+	assert.NotEmpty(t, lint.UncoveredTTLColumns(local.Tables[0].Ast), "suggesting the index should not add it")
+
+	pairTTLIndexes(local, &schema.Schema{}, true)
+	assert.Empty(t, lint.UncoveredTTLColumns(local.Tables[0].Ast))
+	assert.Contains(t, tree.AsString(local.Tables[0].Ast), "INDEX tokens_expires_at_idx (expires_at ASC)")
+}
+
+func parseTestTable(t *testing.T, sql string) schema.ObjectSchema[*tree.CreateTable] {
+	t.Helper()
+	stmt, err := parser.ParseOne(sql)
+	require.NoError(t, err)
+	createTable, ok := stmt.AST.(*tree.CreateTable)
+	require.True(t, ok)
+	return schema.ObjectSchema[*tree.CreateTable]{
+		Name:   createTable.Table.Table(),
+		Schema: createTable.Table.Schema(),
+		Ast:    createTable,
+	}
+}
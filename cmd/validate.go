@@ -12,22 +12,31 @@ import (
 
 	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/flags"
+	"github.com/pjtatlow/scurry/internal/logging"
 	"github.com/pjtatlow/scurry/internal/schema"
-	"github.com/pjtatlow/scurry/internal/ui"
 )
 
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate local schema",
 	Long: `Validate local schema.
-This will try to execute the local schema in a new shadow database to ensure it is valid.`,
+This will try to execute the local schema in a new shadow database to ensure it is valid.
+
+With --offline, the definition files are only parsed and checked for references
+that don't resolve (schemas, types, sequences, foreign key targets), without
+starting a database. This is fast enough for editors and pre-commit hooks, but
+doesn't catch everything the database would.`,
 	RunE: validate,
 }
 
+var validateOffline bool
+
 func init() {
 	rootCmd.AddCommand(validateCmd)
 
 	flags.AddDefinitionDirs(validateCmd)
+	flags.AddEnv(validateCmd)
+	validateCmd.Flags().BoolVar(&validateOffline, "offline", false, "Parse and check references without starting a shadow database")
 }
 
 func validate(cmd *cobra.Command, args []string) error {
@@ -36,9 +45,14 @@ func validate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("definition directory is required (use --definitions)")
 	}
 
-	err := doValidate(cmd.Context())
+	var err error
+	if validateOffline {
+		err = doValidateOffline(afero.NewOsFs(), flags.DefinitionDirs, flags.Env)
+	} else {
+		err = doValidate(cmd.Context())
+	}
 	if err != nil {
-		fmt.Println("Error:", err)
+		logging.Error(fmt.Sprintf("Error: %v", err))
 		os.Exit(1)
 	}
 
@@ -48,9 +62,7 @@ func validate(cmd *cobra.Command, args []string) error {
 func doValidate(ctx context.Context) error {
 
 	// Load local schema from files
-	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(flags.DefinitionDirs, ", "))))
-	}
+	logging.Debug(fmt.Sprintf("→ Loading local schema from %s...", strings.Join(flags.DefinitionDirs, ", ")))
 
 	dbClient, err := db.GetShadowDB(ctx)
 	if err != nil {
@@ -58,7 +70,7 @@ func doValidate(ctx context.Context) error {
 	}
 	defer dbClient.Close()
 
-	localSchema, err := schema.LoadFromDirectories(ctx, afero.NewOsFs(), flags.DefinitionDirs, dbClient)
+	localSchema, err := schema.LoadFromDirectoriesForEnv(ctx, afero.NewOsFs(), flags.DefinitionDirs, flags.Env, dbClient)
 	if err != nil {
 		return fmt.Errorf("failed to load local schema: %w", err)
 	}
@@ -73,11 +85,30 @@ func doValidate(ctx context.Context) error {
 	}
 
 	if flags.Verbose {
-		fmt.Println(ui.Subtle(fmt.Sprintf("  Found %d tables with %d columns, %d types, %d routines, %d sequences, %d views locally",
-			len(localSchema.Tables), numColumns, len(localSchema.Types), len(localSchema.Routines), len(localSchema.Sequences), len(localSchema.Views))))
+		logging.Subtle(fmt.Sprintf("  Found %d tables with %d columns, %d types, %d routines, %d sequences, %d views locally",
+			len(localSchema.Tables), numColumns, len(localSchema.Types), len(localSchema.Routines), len(localSchema.Sequences), len(localSchema.Views)))
+	}
+
+	logging.Newline()
+	logging.Success("✓ Successfully validated local schema!")
+	return nil
+}
+
+// doValidateOffline parses the definitions and checks their references without a database
+func doValidateOffline(fs afero.Fs, dirPaths []string, env string) error {
+	localSchema, err := schema.LoadDefinitions(fs, dirPaths, env)
+	if err != nil {
+		return fmt.Errorf("failed to load local schema: %w", err)
+	}
+
+	issues := schema.CheckReferences(localSchema)
+	if len(issues) > 0 {
+		for _, issue := range issues {
+			logging.Error(fmt.Sprintf("✗ %s", issue))
+		}
+		return fmt.Errorf("found %d unresolved reference(s)", len(issues))
 	}
 
-	fmt.Println()
-	fmt.Println(ui.Success("✓ Successfully validated local schema!"))
+	logging.Success("✓ Successfully validated local schema!")
 	return nil
 }
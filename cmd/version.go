@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/logging"
 )
 
 var (
@@ -15,7 +17,7 @@ var versionCmd = &cobra.Command{
 	Use:  "version",
 	Long: `Print the version number of scurry`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("scurry version %s\n", Version)
+		logging.Print(fmt.Sprintf("scurry version %s", Version))
 	},
 }
 
@@ -0,0 +1,87 @@
+// Package checkpointcache caches checkpoint.sql contents, keyed by the hash of
+// the migrations they cover, in a local directory or in object storage shared
+// between machines.
+package checkpointcache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Timeout bounds each request to the cache
+var Timeout = 30 * time.Second
+
+// maxCheckpointSize bounds the size of a cached checkpoint, larger ones are
+// refused rather than read in part
+var maxCheckpointSize int64 = 256 << 20
+
+// Cache stores checkpoint contents keyed by migrations hash
+type Cache interface {
+	// Get returns the checkpoint stored under key, or nil if there isn't one
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put stores the checkpoint under key, replacing any earlier one
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// Open returns the cache at rawURL: s3://bucket/prefix or gs://bucket/prefix
+func Open(rawURL string) (Cache, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint cache URL %q: %w", rawURL, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid checkpoint cache URL %q: missing bucket", rawURL)
+	}
+	prefix := strings.Trim(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3":
+		return newS3Cache(u.Host, prefix, u.Query().Get("region"))
+	case "gs":
+		return newGCSCache(u.Host, prefix)
+	}
+	return nil, fmt.Errorf("unsupported checkpoint cache URL %q: must be s3:// or gs://", rawURL)
+}
+
+// objectName is where the checkpoint for key is stored under prefix
+func objectName(prefix, key string) string {
+	if prefix == "" {
+		return key + ".sql"
+	}
+	return prefix + "/" + key + ".sql"
+}
+
+// send sends req with the cache timeout. A 404 is returned as a nil body;
+// other non-2xx responses are errors.
+func send(req *http.Request) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), Timeout)
+	defer cancel()
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint cache request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("checkpoint cache %s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	// One byte more than allowed tells a checkpoint at the limit from a larger one
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckpointSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint cache response: %w", err)
+	}
+	if int64(len(data)) > maxCheckpointSize {
+		return nil, fmt.Errorf("checkpoint cache %s %s returned more than %d bytes", req.Method, req.URL.Path, maxCheckpointSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,156 @@
+package checkpointcache
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// DefaultMaxSize is how large the local cache grows before the least recently
+// used checkpoints are evicted
+const DefaultMaxSize = 512 << 20
+
+// Dir is a checkpoint cache in a local directory, so repeated runs on one
+// machine don't replay the same migrations. Reads refresh a checkpoint's
+// modification time, and writes evict the least recently used checkpoints
+// once the directory is larger than MaxSize.
+type Dir struct {
+	fs      afero.Fs
+	Path    string
+	MaxSize int64
+}
+
+// NewDir returns the cache in path on fs
+func NewDir(fs afero.Fs, path string, maxSize int64) *Dir {
+	return &Dir{fs: fs, Path: path, MaxSize: maxSize}
+}
+
+// DefaultDir is where the local cache lives: checkpoints under
+// $SCURRY_CACHE_DIR, or under scurry in the user cache directory
+// (~/.cache/scurry on Linux)
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("SCURRY_CACHE_DIR"); dir != "" {
+		return filepath.Join(dir, "checkpoints"), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "scurry", "checkpoints"), nil
+}
+
+func (d *Dir) Get(ctx context.Context, key string) ([]byte, error) {
+	path := filepath.Join(d.Path, objectName("", key))
+	data, err := afero.ReadFile(d.fs, path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached checkpoint: %w", err)
+	}
+
+	// Mark it recently used; a failure only makes it likelier to be evicted
+	now := time.Now()
+	_ = d.fs.Chtimes(path, now, now)
+	return data, nil
+}
+
+func (d *Dir) Put(ctx context.Context, key string, data []byte) error {
+	if err := d.fs.MkdirAll(d.Path, 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so concurrent runs never read a
+	// partial checkpoint
+	path := filepath.Join(d.Path, objectName("", key))
+	tmp, err := afero.TempFile(d.fs, d.Path, ".tmp-"+key+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cached checkpoint: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = d.fs.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = d.fs.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cached checkpoint: %w", err)
+	}
+
+	_, _, err = d.Evict(d.MaxSize)
+	return err
+}
+
+// Evict removes the least recently used checkpoints until the cache is no
+// larger than maxSize, returning how many it removed and the bytes they used
+func (d *Dir) Evict(maxSize int64) (int, int64, error) {
+	entries, err := d.entries()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size()
+	}
+
+	// Oldest first
+	slices.SortFunc(entries, func(a, b os.FileInfo) int {
+		return cmp.Or(a.ModTime().Compare(b.ModTime()), strings.Compare(a.Name(), b.Name()))
+	})
+
+	var removed int
+	var freed int64
+	for _, entry := range entries {
+		if total-freed <= maxSize {
+			break
+		}
+		if err := d.fs.Remove(filepath.Join(d.Path, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return removed, freed, fmt.Errorf("failed to evict cached checkpoint: %w", err)
+		}
+		removed++
+		freed += entry.Size()
+	}
+	return removed, freed, nil
+}
+
+// Size returns how many checkpoints the cache holds and the bytes they use
+func (d *Dir) Size() (int, int64, error) {
+	entries, err := d.entries()
+	if err != nil {
+		return 0, 0, err
+	}
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size()
+	}
+	return len(entries), total, nil
+}
+
+// entries lists the cached checkpoints; a missing directory is an empty cache
+func (d *Dir) entries() ([]os.FileInfo, error) {
+	infos, err := afero.ReadDir(d.fs, d.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoint cache: %w", err)
+	}
+
+	var entries []os.FileInfo
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".sql") {
+			entries = append(entries, info)
+		}
+	}
+	return entries, nil
+}
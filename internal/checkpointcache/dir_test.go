@@ -0,0 +1,70 @@
+package checkpointcache
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirRoundTrip(t *testing.T) {
+	testRoundTrip(t, NewDir(afero.NewMemMapFs(), "/cache/checkpoints", DefaultMaxSize))
+}
+
+func TestDirEviction(t *testing.T) {
+	ctx := context.Background()
+	fs := afero.NewMemMapFs()
+	dir := NewDir(fs, "/cache/checkpoints", 250)
+
+	// Three 100 byte checkpoints, used a, b, c from oldest to newest
+	checkpoint := []byte(strings.Repeat("x", 100))
+	base := time.Now().Add(-time.Hour)
+	for i, key := range []string{"a", "b", "c"} {
+		require.NoError(t, afero.WriteFile(fs, filepath.Join(dir.Path, key+".sql"), checkpoint, 0644))
+		used := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, fs.Chtimes(filepath.Join(dir.Path, key+".sql"), used, used))
+	}
+
+	// Reading a makes b the least recently used
+	data, err := dir.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, checkpoint, data)
+
+	// Adding d goes over 250 bytes, so b and c are evicted
+	require.NoError(t, dir.Put(ctx, "d", checkpoint))
+	for key, want := range map[string]bool{"a": true, "b": false, "c": false, "d": true} {
+		exists, err := afero.Exists(fs, filepath.Join(dir.Path, key+".sql"))
+		require.NoError(t, err)
+		assert.Equal(t, want, exists, key)
+	}
+
+	count, size, err := dir.Size()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, int64(200), size)
+
+	// Evicting down to nothing clears the cache
+	removed, freed, err := dir.Evict(0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, int64(200), freed)
+}
+
+func TestDirSizeMissing(t *testing.T) {
+	count, size, err := NewDir(afero.NewMemMapFs(), "/cache/checkpoints", DefaultMaxSize).Size()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Zero(t, size)
+}
+
+func TestDefaultDir(t *testing.T) {
+	t.Setenv("SCURRY_CACHE_DIR", "/tmp/scurry-cache")
+	dir, err := DefaultDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/tmp/scurry-cache", "checkpoints"), dir)
+}
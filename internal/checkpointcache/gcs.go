@@ -0,0 +1,69 @@
+package checkpointcache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// gcsCache stores checkpoints in a Google Cloud Storage bucket through the JSON
+// API, authenticated with the access token in GOOGLE_OAUTH_ACCESS_TOKEN (e.g.
+// from gcloud auth print-access-token). STORAGE_EMULATOR_HOST points it at an
+// emulator instead.
+type gcsCache struct {
+	endpoint string
+	bucket   string
+	prefix   string
+	token    string
+}
+
+func newGCSCache(bucket, prefix string) (*gcsCache, error) {
+	c := &gcsCache{
+		endpoint: "https://storage.googleapis.com",
+		bucket:   bucket,
+		prefix:   prefix,
+		token:    os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+	}
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		c.endpoint = "http://" + host
+	} else if c.token == "" {
+		return nil, fmt.Errorf("the gs checkpoint cache needs GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+	return c, nil
+}
+
+func (c *gcsCache) Get(ctx context.Context, key string) ([]byte, error) {
+	target := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media",
+		c.endpoint, url.PathEscape(c.bucket), url.PathEscape(objectName(c.prefix, key)))
+	req, err := c.newRequest(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	return send(req)
+}
+
+func (c *gcsCache) Put(ctx context.Context, key string, data []byte) error {
+	target := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		c.endpoint, url.PathEscape(c.bucket), url.QueryEscape(objectName(c.prefix, key)))
+	req, err := c.newRequest(ctx, http.MethodPost, target, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	_, err = send(req)
+	return err
+}
+
+func (c *gcsCache) newRequest(ctx context.Context, method, target string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint cache request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
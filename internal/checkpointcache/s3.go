@@ -0,0 +1,149 @@
+package checkpointcache
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// s3Cache stores checkpoints in an S3 bucket, or an S3-compatible store when
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL is set. Requests are signed with
+// Signature Version 4 using the standard AWS_* credential variables.
+type s3Cache struct {
+	// endpoint is the bucket's base URL, without a trailing slash
+	endpoint     string
+	prefix       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	now          func() time.Time
+}
+
+func newS3Cache(bucket, prefix, region string) (*s3Cache, error) {
+	c := &s3Cache{
+		prefix:       prefix,
+		region:       cmp.Or(region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		now:          time.Now,
+	}
+	if c.accessKey == "" || c.secretKey == "" {
+		return nil, fmt.Errorf("the s3 checkpoint cache needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	// Custom endpoints (MinIO, R2, ...) are addressed path-style
+	if endpoint := cmp.Or(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")); endpoint != "" {
+		c.endpoint = strings.TrimSuffix(endpoint, "/") + "/" + bucket
+	} else {
+		c.endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, c.region)
+	}
+	return c, nil
+}
+
+func (c *s3Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return send(req)
+}
+
+func (c *s3Cache) Put(ctx context.Context, key string, data []byte) error {
+	req, err := c.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	_, err = send(req)
+	return err
+}
+
+func (c *s3Cache) newRequest(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	var segments []string
+	for _, segment := range strings.Split(objectName(c.prefix, key), "/") {
+		segments = append(segments, url.PathEscape(segment))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+"/"+strings.Join(segments, "/"), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint cache request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	c.sign(req, body)
+	return req, nil
+}
+
+// sign adds the Signature Version 4 Authorization header, signing the host
+// and every header already set on req
+func (c *s3Cache) sign(req *http.Request, body []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	for _, part := range []string{c.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
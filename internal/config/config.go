@@ -0,0 +1,270 @@
+// Package config loads the optional .scurry.yaml project configuration.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the config file scurry looks for in the working directory
+const DefaultFileName = ".scurry.yaml"
+
+// Hook events
+const (
+	EventBeforePush             = "before_push"
+	EventAfterPush              = "after_push"
+	EventBeforeMigrationExecute = "before_migration_execute"
+	EventAfterMigrationExecute  = "after_migration_execute"
+	EventOnFailure              = "on_failure"
+)
+
+// Config is the contents of .scurry.yaml
+type Config struct {
+	Hooks      Hooks      `yaml:"hooks"`
+	Dump       Dump       `yaml:"dump"`
+	Migrations Migrations `yaml:"migrations"`
+	Cache      Cache      `yaml:"cache"`
+	Backup     Backup     `yaml:"backup"`
+	// Environments are connection profiles by name, selected with --env
+	Environments map[string]Environment `yaml:"environments"`
+	// IgnoreDiff are objects expected to differ from the definitions, e.g.
+	// during a multi-step rollout. Their differences are reported as ignored
+	// instead of generating statements.
+	IgnoreDiff []string `yaml:"ignore_diff"`
+}
+
+// Environment is a named connection profile
+type Environment struct {
+	// URL is the database connection URL. URLEnv names an environment
+	// variable holding it instead, to keep credentials out of the file.
+	URL    string `yaml:"url"`
+	URLEnv string `yaml:"url_env"`
+	// SSLMode, SSLRootCert, SSLCert and SSLKey are added to the connection URL
+	SSLMode     string `yaml:"sslmode"`
+	SSLRootCert string `yaml:"sslrootcert"`
+	SSLCert     string `yaml:"sslcert"`
+	SSLKey      string `yaml:"sslkey"`
+	// Flags are default values for the flags of the commands run against the
+	// environment, by flag name
+	Flags map[string]string `yaml:"flags"`
+	// Protected refuses --force and shows the target cluster before asking
+	// to change its schema
+	Protected bool `yaml:"protected"`
+	// ReadOnly connects to the environment read-only and refuses commands
+	// that change the database, unless --read-only=false is passed
+	ReadOnly bool `yaml:"read_only"`
+}
+
+// ConnectionURL returns the environment's connection URL with its TLS
+// settings, or "" if it doesn't set one
+func (e Environment) ConnectionURL() (string, error) {
+	base := e.URL
+	if e.URLEnv != "" {
+		base = os.Getenv(e.URLEnv)
+		if base == "" {
+			return "", fmt.Errorf("environment variable %s is not set", e.URLEnv)
+		}
+	}
+	if base == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid connection URL: %w", err)
+	}
+	query := u.Query()
+	for name, value := range map[string]string{
+		"sslmode":     e.SSLMode,
+		"sslrootcert": e.SSLRootCert,
+		"sslcert":     e.SSLCert,
+		"sslkey":      e.SSLKey,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Backup configures the backups taken before push or migration execute drop data
+type Backup struct {
+	// Location is the collection URI backups are written into, e.g.
+	// s3://bucket/scurry?AUTH=implicit. Without it nothing is backed up.
+	Location string `yaml:"location"`
+	// FullCluster backs up the whole cluster instead of the tables the
+	// changes affect
+	FullCluster bool `yaml:"full_cluster"`
+}
+
+// Cache configures the local checkpoint cache
+type Cache struct {
+	// Dir replaces the user cache directory (~/.cache/scurry on Linux)
+	Dir string `yaml:"dir"`
+	// MaxSizeMB is how large the checkpoint cache grows before the least
+	// recently used checkpoints are evicted; 0 is the default of 512
+	MaxSizeMB int64 `yaml:"max_size_mb"`
+}
+
+// Migrations configures scurry migration gen
+type Migrations struct {
+	// RefreshStats collects statistics on the large tables an async migration
+	// changes once it succeeds, as if --refresh-stats were always passed
+	RefreshStats bool `yaml:"refresh_stats"`
+	// Checksum is how applied migrations are compared with their files: raw
+	// (the default) or logical, as if --checksum were passed
+	Checksum string `yaml:"checksum"`
+	// CheckpointCache is an s3:// or gs:// URL where checkpoints are shared
+	// between machines, as if --checkpoint-cache were passed
+	CheckpointCache string `yaml:"checkpoint_cache"`
+	// Checkpoints is when migration validate writes checkpoint.sql files
+	Checkpoints Checkpoints `yaml:"checkpoints"`
+	// TableSizesMaxAge is how old table_sizes.yaml can get before migration
+	// gen warns that it may be out of date (30 days if unset)
+	TableSizesMaxAge time.Duration `yaml:"table_sizes_max_age"`
+	// Classify forces the mode of an operation, by rule name, whatever the
+	// size of the table it changes, e.g. create_index: async
+	Classify map[string]string `yaml:"classify"`
+}
+
+// Checkpoints configures when checkpoint.sql files are written and pruned.
+// Without Every or AfterReplay, a checkpoint is written for the newest
+// migration every time.
+type Checkpoints struct {
+	// Every writes a checkpoint once this many migrations were added since the
+	// latest one
+	Every int `yaml:"every"`
+	// AfterReplay writes a checkpoint once replaying the migrations since the
+	// latest one takes at least this long
+	AfterReplay time.Duration `yaml:"after_replay"`
+	// Keep removes all but this many of the newest checkpoints; 0 keeps them all
+	Keep int `yaml:"keep"`
+}
+
+// Due reports whether a checkpoint should be written after sinceCheckpoint
+// migrations that took replayTime to replay
+func (c Checkpoints) Due(sinceCheckpoint int, replayTime time.Duration) bool {
+	if c.Every == 0 && c.AfterReplay == 0 {
+		return true
+	}
+	return (c.Every > 0 && sinceCheckpoint >= c.Every) ||
+		(c.AfterReplay > 0 && replayTime >= c.AfterReplay)
+}
+
+// Dump configures scurry data dump
+type Dump struct {
+	// Mask maps columns, as [schema.]table.column, to the rule that masks
+	// their values: hash, email, null or value:<text>
+	Mask map[string]string `yaml:"mask"`
+}
+
+// Hooks are run around push and migration execute
+type Hooks struct {
+	BeforePush             []Hook `yaml:"before_push"`
+	AfterPush              []Hook `yaml:"after_push"`
+	BeforeMigrationExecute []Hook `yaml:"before_migration_execute"`
+	AfterMigrationExecute  []Hook `yaml:"after_migration_execute"`
+	// OnFailure runs when push or migration execute fails
+	OnFailure []Hook `yaml:"on_failure"`
+}
+
+// Hook is either a SQL snippet run against the target database or a shell
+// command
+type Hook struct {
+	SQL     string `yaml:"sql,omitempty"`
+	Command string `yaml:"command,omitempty"`
+}
+
+// For returns the hooks for an event
+func (h Hooks) For(event string) []Hook {
+	switch event {
+	case EventBeforePush:
+		return h.BeforePush
+	case EventAfterPush:
+		return h.AfterPush
+	case EventBeforeMigrationExecute:
+		return h.BeforeMigrationExecute
+	case EventAfterMigrationExecute:
+		return h.AfterMigrationExecute
+	case EventOnFailure:
+		return h.OnFailure
+	}
+	return nil
+}
+
+// Load reads the config file at path. A missing file is an empty config.
+func Load(fs afero.Fs, path string) (*Config, error) {
+	exists, err := afero.Exists(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &Config{}, nil
+	}
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	for _, event := range []string{EventBeforePush, EventAfterPush, EventBeforeMigrationExecute, EventAfterMigrationExecute, EventOnFailure} {
+		for i, hook := range c.Hooks.For(event) {
+			if (hook.SQL == "") == (hook.Command == "") {
+				return fmt.Errorf("hooks.%s[%d] must set exactly one of sql or command", event, i)
+			}
+		}
+	}
+	switch c.Migrations.Checksum {
+	case "", "raw", "logical":
+	default:
+		return fmt.Errorf("migrations.checksum must be raw or logical, not %q", c.Migrations.Checksum)
+	}
+	if c.Migrations.TableSizesMaxAge < 0 {
+		return fmt.Errorf("migrations.table_sizes_max_age must not be negative")
+	}
+	for rule, mode := range c.Migrations.Classify {
+		if mode != "sync" && mode != "async" {
+			return fmt.Errorf("migrations.classify.%s must be sync or async, not %q", rule, mode)
+		}
+	}
+	if c.Migrations.Checkpoints.Every < 0 || c.Migrations.Checkpoints.AfterReplay < 0 || c.Migrations.Checkpoints.Keep < 0 {
+		return fmt.Errorf("migrations.checkpoints settings must not be negative")
+	}
+	for name, env := range c.Environments {
+		if env.URL != "" && env.URLEnv != "" {
+			return fmt.Errorf("environments.%s must set at most one of url or url_env", name)
+		}
+		switch env.SSLMode {
+		case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			return fmt.Errorf("environments.%s.sslmode %q is not a valid sslmode", name, env.SSLMode)
+		}
+		if env.Protected && env.Flags["force"] == "true" {
+			return fmt.Errorf("environments.%s is protected, so it can't default --force to true", name)
+		}
+	}
+	if c.Backup.FullCluster && c.Backup.Location == "" {
+		return fmt.Errorf("backup.full_cluster needs a backup.location")
+	}
+	if c.Cache.MaxSizeMB < 0 {
+		return fmt.Errorf("cache.max_size_mb must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte(`
+hooks:
+  before_push:
+    - sql: SET CLUSTER SETTING sql.defaults.use_declarative_schema_changer = 'on'
+  after_migration_execute:
+    - command: ./notify.sh "migrations done"
+  on_failure:
+    - command: echo "$SCURRY_ERROR"
+`), 0644))
+
+	cfg, err := Load(fs, DefaultFileName)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Hook{{SQL: "SET CLUSTER SETTING sql.defaults.use_declarative_schema_changer = 'on'"}}, cfg.Hooks.For(EventBeforePush))
+	assert.Equal(t, []Hook{{Command: `./notify.sh "migrations done"`}}, cfg.Hooks.For(EventAfterMigrationExecute))
+	assert.Equal(t, []Hook{{Command: `echo "$SCURRY_ERROR"`}}, cfg.Hooks.For(EventOnFailure))
+	assert.Empty(t, cfg.Hooks.For(EventAfterPush))
+	assert.Empty(t, cfg.Hooks.For("unknown"))
+}
+
+func TestLoadDumpMasks(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte(`
+dump:
+  mask:
+    users.email: email
+    billing.cards.number: "null"
+`), 0644))
+
+	cfg, err := Load(fs, DefaultFileName)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"users.email": "email", "billing.cards.number": "null"}, cfg.Dump.Mask)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte(`
+migrations:
+  refresh_stats: true
+  checksum: logical
+  checkpoint_cache: s3://ci-cache/checkpoints
+  table_sizes_max_age: 168h
+  classify:
+    create_index: async
+    update: sync
+`), 0644))
+
+	cfg, err := Load(fs, DefaultFileName)
+	require.NoError(t, err)
+	assert.True(t, cfg.Migrations.RefreshStats)
+	assert.Equal(t, "logical", cfg.Migrations.Checksum)
+	assert.Equal(t, "s3://ci-cache/checkpoints", cfg.Migrations.CheckpointCache)
+	assert.Equal(t, 168*time.Hour, cfg.Migrations.TableSizesMaxAge)
+	assert.Equal(t, map[string]string{"create_index": "async", "update": "sync"}, cfg.Migrations.Classify)
+}
+
+func TestLoadInvalidChecksum(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte("migrations:\n  checksum: fuzzy\n"), 0644))
+	_, err := Load(fs, DefaultFileName)
+	assert.ErrorContains(t, err, "migrations.checksum must be raw or logical")
+}
+
+func TestLoadInvalidClassify(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte("migrations:\n  classify:\n    create_index: later\n"), 0644))
+	_, err := Load(fs, DefaultFileName)
+	assert.ErrorContains(t, err, "migrations.classify.create_index must be sync or async")
+}
+
+func TestLoadCheckpoints(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte(`
+migrations:
+  checkpoints:
+    every: 20
+    after_replay: 2m
+    keep: 3
+`), 0644))
+
+	cfg, err := Load(fs, DefaultFileName)
+	require.NoError(t, err)
+	assert.Equal(t, Checkpoints{Every: 20, AfterReplay: 2 * time.Minute, Keep: 3}, cfg.Migrations.Checkpoints)
+
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte("migrations:\n  checkpoints:\n    every: -1\n"), 0644))
+	_, err = Load(fs, DefaultFileName)
+	assert.ErrorContains(t, err, "migrations.checkpoints settings must not be negative")
+}
+
+func TestCheckpointsDue(t *testing.T) {
+	tests := []struct {
+		name            string
+		checkpoints     Checkpoints
+		sinceCheckpoint int
+		replayTime      time.Duration
+		want            bool
+	}{
+		{"default", Checkpoints{}, 1, 0, true},
+		{"too few migrations", Checkpoints{Every: 10}, 9, time.Hour, false},
+		{"enough migrations", Checkpoints{Every: 10}, 10, 0, true},
+		{"quick replay", Checkpoints{AfterReplay: time.Minute}, 100, 59 * time.Second, false},
+		{"slow replay", Checkpoints{AfterReplay: time.Minute}, 1, time.Minute, true},
+		{"either", Checkpoints{Every: 10, AfterReplay: time.Minute}, 3, 2 * time.Minute, true},
+		{"neither", Checkpoints{Every: 10, AfterReplay: time.Minute}, 3, time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.checkpoints.Due(tt.sinceCheckpoint, tt.replayTime))
+		})
+	}
+}
+
+func TestLoadEnvironments(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte(`
+environments:
+  dev:
+    url: postgresql://root@localhost:26257/app?sslmode=disable
+    flags:
+      force: "true"
+  prod:
+    url_env: PROD_DB_URL
+    sslmode: verify-full
+    sslrootcert: certs/ca.crt
+    sslcert: certs/client.deploy.crt
+    sslkey: certs/client.deploy.key
+    protected: true
+`), 0644))
+
+	cfg, err := Load(fs, DefaultFileName)
+	require.NoError(t, err)
+	require.Len(t, cfg.Environments, 2)
+	assert.Equal(t, map[string]string{"force": "true"}, cfg.Environments["dev"].Flags)
+	assert.True(t, cfg.Environments["prod"].Protected)
+
+	dev, err := cfg.Environments["dev"].ConnectionURL()
+	require.NoError(t, err)
+	assert.Equal(t, "postgresql://root@localhost:26257/app?sslmode=disable", dev)
+
+	_, err = cfg.Environments["prod"].ConnectionURL()
+	assert.EqualError(t, err, "environment variable PROD_DB_URL is not set")
+
+	t.Setenv("PROD_DB_URL", "postgresql://deploy@prod.example.com:26257/app?sslmode=require&application_name=scurry")
+	prod, err := cfg.Environments["prod"].ConnectionURL()
+	require.NoError(t, err)
+	assert.Equal(t, "postgresql://deploy@prod.example.com:26257/app?application_name=scurry&sslcert=certs%2Fclient.deploy.crt&sslkey=certs%2Fclient.deploy.key&sslmode=verify-full&sslrootcert=certs%2Fca.crt", prod)
+
+	url, err := Environment{Flags: map[string]string{"verbose": "true"}}.ConnectionURL()
+	require.NoError(t, err)
+	assert.Empty(t, url)
+}
+
+func TestLoadInvalidEnvironment(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"url and url_env", "environments:\n  prod:\n    url: postgresql://prod\n    url_env: PROD_DB_URL\n", "environments.prod must set at most one of url or url_env"},
+		{"sslmode", "environments:\n  prod:\n    sslmode: strict\n", `environments.prod.sslmode "strict" is not a valid sslmode`},
+		{"protected force", "environments:\n  prod:\n    protected: true\n    flags:\n      force: \"true\"\n", "environments.prod is protected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte(tt.content), 0644))
+			_, err := Load(fs, DefaultFileName)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLoadCache(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte("cache:\n  dir: /var/cache/scurry\n  max_size_mb: 64\n"), 0644))
+	cfg, err := Load(fs, DefaultFileName)
+	require.NoError(t, err)
+	assert.Equal(t, Cache{Dir: "/var/cache/scurry", MaxSizeMB: 64}, cfg.Cache)
+
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte("cache:\n  max_size_mb: -1\n"), 0644))
+	_, err = Load(fs, DefaultFileName)
+	assert.ErrorContains(t, err, "cache.max_size_mb must not be negative")
+}
+
+func TestLoadBackup(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte("backup:\n  location: s3://bucket/scurry?AUTH=implicit\n  full_cluster: true\n"), 0644))
+	cfg, err := Load(fs, DefaultFileName)
+	require.NoError(t, err)
+	assert.Equal(t, Backup{Location: "s3://bucket/scurry?AUTH=implicit", FullCluster: true}, cfg.Backup)
+
+	require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte("backup:\n  full_cluster: true\n"), 0644))
+	_, err = Load(fs, DefaultFileName)
+	assert.ErrorContains(t, err, "backup.full_cluster needs a backup.location")
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load(afero.NewMemMapFs(), DefaultFileName)
+	require.NoError(t, err)
+	assert.Equal(t, &Config{}, cfg)
+}
+
+func TestLoadInvalidHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"neither sql nor command", "hooks:\n  before_push:\n    - {}\n"},
+		{"both sql and command", "hooks:\n  after_push:\n    - sql: SELECT 1\n      command: echo hi\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, DefaultFileName, []byte(tt.content), 0644))
+			_, err := Load(fs, DefaultFileName)
+			assert.ErrorContains(t, err, "must set exactly one of sql or command")
+		})
+	}
+}
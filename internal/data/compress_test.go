@@ -0,0 +1,65 @@
+package data
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	content := "-- scurry:data-dump\n-- version: 1\n"
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			w, err := NewCompressWriter(&buf, compression)
+			require.NoError(t, err)
+			_, err = io.WriteString(w, content)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			if compression != CompressionNone {
+				assert.NotEqual(t, content, buf.String())
+			}
+
+			r, err := NewDecompressReader(&buf)
+			require.NoError(t, err)
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(got))
+		})
+	}
+}
+
+func TestNewCompressWriterUnknown(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewCompressWriter(&bytes.Buffer{}, "lz4")
+	assert.Error(t, err)
+}
+
+func TestDecompressReaderShortInput(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewDecompressReader(bytes.NewBufferString("x"))
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "x", string(got))
+}
+
+func TestCompressionForFile(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, CompressionNone, CompressionForFile("dump.sql"))
+	assert.Equal(t, CompressionGzip, CompressionForFile("dump.sql.gz"))
+	assert.Equal(t, CompressionZstd, CompressionForFile("dump.ndjson.zst"))
+	assert.Equal(t, CompressionZstd, CompressionForFile("dump.sql.zstd"))
+}
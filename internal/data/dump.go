@@ -18,8 +18,141 @@ type rowData struct {
 	values []*string
 }
 
+// DumpSummary describes what a streaming dump wrote.
+type DumpSummary struct {
+	Tables    int
+	Rows      int
+	Sequences int
+}
+
+// DumpOptions controls what DumpTo reads and how.
+type DumpOptions struct {
+	// BatchSize is the number of rows per INSERT statement
+	BatchSize int
+	// Concurrency is the number of tables read at once. Above one, tables are
+	// read at a single point in time with AS OF SYSTEM TIME, spooled to
+	// temporary files, and written to the encoder in insertion order.
+	Concurrency int
+	// Tables limits the dump to these tables. Names without a schema are in
+	// the public schema.
+	Tables []string
+	// ExcludeTables leaves these tables out of the dump
+	ExcludeTables []string
+	// Where filters a table's rows with a SQL predicate, keyed by table name
+	Where map[string]string
+	// Masks replaces column values as they're read, so the dump holds no
+	// sensitive data. See ParseMasks.
+	Masks Masks
+	// MaskSalt keys the hashes used by masks. Dumps made with the same salt
+	// mask equal values the same way.
+	MaskSalt []byte
+}
+
+// ParseWhere parses --where values of the form "table:predicate" into
+// DumpOptions.Where.
+func ParseWhere(specs []string) (map[string]string, error) {
+	where := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		table, predicate, ok := strings.Cut(spec, ":")
+		table = strings.TrimSpace(table)
+		predicate = strings.TrimSpace(predicate)
+		if !ok || table == "" || predicate == "" {
+			return nil, fmt.Errorf("invalid filter %q: expected table:predicate", spec)
+		}
+		name := qualifyTableName(table)
+		if _, dup := where[name]; dup {
+			return nil, fmt.Errorf("table %s has more than one filter", name)
+		}
+		where[name] = predicate
+	}
+	return where, nil
+}
+
+// qualifyTableName adds the public schema to names without a schema
+func qualifyTableName(name string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+	return "public." + name
+}
+
+// selectTables returns the tables in order that opts includes, checking that
+// every table named in opts exists
+func selectTables(order []string, opts DumpOptions) ([]string, error) {
+	known := make(map[string]bool, len(order))
+	for _, name := range order {
+		known[name] = true
+	}
+
+	lookup := func(names []string) (map[string]bool, error) {
+		set := make(map[string]bool, len(names))
+		for _, name := range names {
+			qualified := qualifyTableName(name)
+			if !known[qualified] {
+				return nil, fmt.Errorf("unknown table %s", qualified)
+			}
+			set[qualified] = true
+		}
+		return set, nil
+	}
+
+	include, err := lookup(opts.Tables)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := lookup(opts.ExcludeTables)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []string
+	for _, name := range order {
+		if (len(include) > 0 && !include[name]) || exclude[name] {
+			continue
+		}
+		selected = append(selected, name)
+	}
+
+	dumped := make(map[string]bool, len(selected))
+	for _, name := range selected {
+		dumped[name] = true
+	}
+	for name := range opts.Where {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown table %s", name)
+		}
+		if !dumped[name] {
+			return nil, fmt.Errorf("filter for table %s, which isn't being dumped", name)
+		}
+	}
+
+	return selected, nil
+}
+
+// querier is satisfied by *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // Dump reads all data from the database and returns a DumpFile.
 func Dump(ctx context.Context, client *db.Client, batchSize int) (*DumpFile, error) {
+	enc := &memoryEncoder{}
+	if _, err := DumpTo(ctx, client, enc, DumpOptions{BatchSize: batchSize}); err != nil {
+		return nil, err
+	}
+	return &enc.file, nil
+}
+
+// DumpTo reads data from the database and writes it to enc as it is read, at
+// most opts.BatchSize rows at a time. The dump is consistent: tables are read
+// in a single read-only transaction, or at a single timestamp when they are
+// read concurrently.
+func DumpTo(ctx context.Context, client *db.Client, enc Encoder, opts DumpOptions) (*DumpSummary, error) {
+	if opts.BatchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", opts.BatchSize)
+	}
+
 	// Load schema from database
 	dbSchema, err := schema.LoadFromDatabase(ctx, client)
 	if err != nil {
@@ -32,6 +165,11 @@ func Dump(ctx context.Context, client *db.Client, batchSize int) (*DumpFile, err
 		return nil, fmt.Errorf("failed to compute table insertion order: %w", err)
 	}
 
+	tables, err := selectTables(insertionOrder.Order, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate schema SQL (CREATE statements)
 	schemaSQL, err := generateSchemaSQL(dbSchema)
 	if err != nil {
@@ -44,36 +182,83 @@ func Dump(ctx context.Context, client *db.Client, batchSize int) (*DumpFile, err
 		tableMap[t.ResolvedName()] = t.Ast
 	}
 
-	// Dump each table in FK-safe order
-	var tableDumps []TableDump
-	for _, tableName := range insertionOrder.Order {
+	masks, err := resolveMasks(opts.Masks, tableMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var q querier = client.GetDB()
+	asOf := ""
+	if opts.Concurrency > 1 {
+		if err := q.QueryRowContext(ctx, "SELECT cluster_logical_timestamp()::STRING").Scan(&asOf); err != nil {
+			return nil, fmt.Errorf("failed to get dump timestamp: %w", err)
+		}
+	} else {
+		tx, err := client.GetDB().BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+
+	// Build the queries for each table in FK-safe order
+	var queries []tableQuery
+	for _, tableName := range tables {
 		tableAST, ok := tableMap[tableName]
 		if !ok {
 			continue
 		}
-
 		selfRefCols := insertionOrder.SelfRefColumns[tableName]
-		td, err := dumpTable(ctx, client, tableName, tableAST, selfRefCols, batchSize)
+		tq := buildTableQuery(tableName, tableAST, selfRefCols, opts.Where[tableName], asOf)
+		tq.masks = masks[tableName]
+		tq.maskSalt = opts.MaskSalt
+		queries = append(queries, tq)
+	}
+
+	err = enc.WriteHeader(DumpHeader{
+		Version:   1,
+		CreatedAt: time.Now(),
+		Tables:    tables,
+		SchemaSQL: schemaSQL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write dump header: %w", err)
+	}
+
+	summary := &DumpSummary{}
+	if opts.Concurrency > 1 {
+		summary.Rows, err = dumpTablesConcurrently(ctx, q, enc, queries, opts.BatchSize, opts.Concurrency)
 		if err != nil {
-			return nil, fmt.Errorf("failed to dump table %s: %w", tableName, err)
+			return nil, err
+		}
+	} else {
+		for _, tq := range queries {
+			rowCount, err := dumpTable(ctx, q, enc, tq, opts.BatchSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to dump table %s: %w", tq.header.QualifiedName, err)
+			}
+			summary.Rows += rowCount
 		}
-		tableDumps = append(tableDumps, td)
 	}
+	summary.Tables = len(queries)
 
 	// Dump sequences
-	sequences, err := dumpSequences(ctx, client, dbSchema.Sequences)
+	sequences, err := dumpSequences(ctx, q, dbSchema.Sequences)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dump sequences: %w", err)
 	}
+	for _, seq := range sequences {
+		if err := enc.WriteSequence(seq); err != nil {
+			return nil, fmt.Errorf("failed to write sequence %s: %w", seq.QualifiedName, err)
+		}
+	}
+	summary.Sequences = len(sequences)
 
-	return &DumpFile{
-		Version:   1,
-		CreatedAt: time.Now(),
-		Tables:    insertionOrder.Order,
-		SchemaSQL: schemaSQL,
-		TableData: tableDumps,
-		Sequences: sequences,
-	}, nil
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write dump: %w", err)
+	}
+	return summary, nil
 }
 
 func generateSchemaSQL(s *schema.Schema) (string, error) {
@@ -85,25 +270,35 @@ func generateSchemaSQL(s *schema.Schema) (string, error) {
 	return strings.Join(statements, ";\n") + ";", nil
 }
 
-func dumpTable(ctx context.Context, client *db.Client, qualifiedName string, tableAST *tree.CreateTable, selfRefCols []string, batchSize int) (TableDump, error) {
+// tableQuery holds the queries that read a table's data
+type tableQuery struct {
+	header    TableHeader
+	countSQL  string
+	selectSQL string
+	// masks are applied to each row read, indexed like header.Columns
+	masks    []*columnMask
+	maskSalt []byte
+}
+
+// buildTableQuery builds the queries for a table. A non-empty where filters
+// the rows, and a non-empty asOf reads them at that timestamp.
+func buildTableQuery(qualifiedName string, tableAST *tree.CreateTable, selfRefCols []string, where, asOf string) tableQuery {
 	// Get column info
 	columns, pkColumns := getTableColumns(tableAST)
+	tq := tableQuery{header: TableHeader{
+		QualifiedName:  qualifiedName,
+		PrimaryKey:     pkColumns,
+		SelfRefColumns: selfRefCols,
+	}}
 	if len(columns) == 0 {
-		return TableDump{QualifiedName: qualifiedName}, nil
-	}
-
-	// Build self-ref column set for quick lookup
-	selfRefSet := make(map[string]bool)
-	for _, col := range selfRefCols {
-		selfRefSet[col] = true
+		return tq
 	}
 
 	// Build column lists
-	var allColNames []string
 	var selectColNames []string
 	for _, col := range columns {
 		colName := col.Name.Normalize()
-		allColNames = append(allColNames, colName)
+		tq.header.Columns = append(tq.header.Columns, colName)
 		selectColNames = append(selectColNames, pq.QuoteIdentifier(colName))
 	}
 
@@ -115,22 +310,49 @@ func dumpTable(ctx context.Context, client *db.Client, qualifiedName string, tab
 
 	// Split qualified name for quoting
 	parts := strings.SplitN(qualifiedName, ".", 2)
-	quotedTable := pq.QuoteIdentifier(parts[0]) + "." + pq.QuoteIdentifier(parts[1])
+	from := pq.QuoteIdentifier(parts[0]) + "." + pq.QuoteIdentifier(parts[1])
+	if asOf != "" {
+		from += " AS OF SYSTEM TIME " + pq.QuoteLiteral(asOf)
+	}
+	if where != "" {
+		from += " WHERE (" + where + ")"
+	}
 
-	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selectColNames, ", "), quotedTable)
+	tq.countSQL = fmt.Sprintf("SELECT count(*) FROM %s", from)
+	tq.selectSQL = fmt.Sprintf("SELECT %s FROM %s", strings.Join(selectColNames, ", "), from)
 	if len(orderBy) > 0 {
-		query += " ORDER BY " + strings.Join(orderBy, ", ")
+		tq.selectSQL += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+	return tq
+}
+
+func dumpTable(ctx context.Context, q querier, enc Encoder, tq tableQuery, batchSize int) (int, error) {
+	header := tq.header
+	if len(header.Columns) == 0 {
+		if err := enc.BeginTable(header); err != nil {
+			return 0, err
+		}
+		return 0, enc.EndTable()
 	}
 
-	rows, err := client.GetDB().QueryContext(ctx, query)
+	// The row count goes in the table header, so it's needed before any rows
+	if err := q.QueryRowContext(ctx, tq.countSQL).Scan(&header.RowCount); err != nil {
+		return 0, fmt.Errorf("count failed: %w", err)
+	}
+	if err := enc.BeginTable(header); err != nil {
+		return 0, err
+	}
+
+	rows, err := q.QueryContext(ctx, tq.selectSQL)
 	if err != nil {
-		return TableDump{}, fmt.Errorf("query failed: %w", err)
+		return 0, fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
-	// Scan all rows
-	var allRows []rowData
-	numCols := len(allColNames)
+	// Stream rows to the encoder a batch at a time
+	numCols := len(header.Columns)
+	batch := make([][]*string, 0, batchSize)
+	rowCount := 0
 
 	for rows.Next() {
 		values := make([]*string, numCols)
@@ -139,35 +361,28 @@ func dumpTable(ctx context.Context, client *db.Client, qualifiedName string, tab
 			scanArgs[i] = &values[i]
 		}
 		if err := rows.Scan(scanArgs...); err != nil {
-			return TableDump{}, fmt.Errorf("scan failed: %w", err)
+			return 0, fmt.Errorf("scan failed: %w", err)
+		}
+		batch = append(batch, values)
+		rowCount++
+
+		if len(batch) == batchSize {
+			maskRows(batch, tq.masks, tq.maskSalt)
+			if err := enc.WriteRows(batch); err != nil {
+				return 0, err
+			}
+			batch = make([][]*string, 0, batchSize)
 		}
-		allRows = append(allRows, rowData{values: values})
 	}
 	if err := rows.Err(); err != nil {
-		return TableDump{}, fmt.Errorf("rows iteration failed: %w", err)
+		return 0, fmt.Errorf("rows iteration failed: %w", err)
 	}
 
-	if len(allRows) == 0 {
-		return TableDump{QualifiedName: qualifiedName, RowCount: 0}, nil
+	maskRows(batch, tq.masks, tq.maskSalt)
+	if err := enc.WriteRows(batch); err != nil {
+		return 0, err
 	}
-
-	var statements []string
-
-	if len(selfRefCols) > 0 {
-		// Two-phase insert for self-referential tables
-		stmts := generateSelfRefInserts(quotedTable, allColNames, selfRefSet, allRows, pkColumns, batchSize)
-		statements = append(statements, stmts...)
-	} else {
-		// Normal insert
-		stmts := generateInserts(quotedTable, allColNames, allRows, batchSize)
-		statements = append(statements, stmts...)
-	}
-
-	return TableDump{
-		QualifiedName: qualifiedName,
-		RowCount:      len(allRows),
-		Statements:    statements,
-	}, nil
+	return rowCount, enc.EndTable()
 }
 
 // getTableColumns returns the non-computed columns and primary key column names for a table.
@@ -348,7 +563,7 @@ func formatValue(val *string) string {
 	return pq.QuoteLiteral(*val)
 }
 
-func dumpSequences(ctx context.Context, client *db.Client, sequences []schema.ObjectSchema[*tree.CreateSequence]) ([]SequenceValue, error) {
+func dumpSequences(ctx context.Context, q querier, sequences []schema.ObjectSchema[*tree.CreateSequence]) ([]SequenceValue, error) {
 	var results []SequenceValue
 	for _, seq := range sequences {
 		qualifiedName := seq.ResolvedName()
@@ -357,7 +572,7 @@ func dumpSequences(ctx context.Context, client *db.Client, sequences []schema.Ob
 
 		var lastVal sql.NullInt64
 		query := fmt.Sprintf("SELECT last_value FROM %s", quotedSeq)
-		err := client.GetDB().QueryRowContext(ctx, query).Scan(&lastVal)
+		err := q.QueryRowContext(ctx, query).Scan(&lastVal)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get sequence value for %s: %w", qualifiedName, err)
 		}
@@ -182,6 +182,153 @@ func TestDumpSelfRefInserts(t *testing.T) {
 	assert.True(t, hasUpdate, "expected UPDATE statements for self-ref columns")
 }
 
+func TestDumpToOptions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx,
+		"CREATE TABLE public.users (id INT8 PRIMARY KEY, name STRING NOT NULL)",
+		"CREATE TABLE public.posts (id INT8 PRIMARY KEY, user_id INT8 REFERENCES public.users(id), title STRING)",
+		"CREATE TABLE public.audit_log (id INT8 PRIMARY KEY, entry STRING)",
+		"CREATE TABLE public.categories (id INT8 PRIMARY KEY, parent_id INT8 REFERENCES public.categories(id))",
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	for _, sql := range []string{
+		"INSERT INTO public.users VALUES (1, 'Alice'), (2, 'Bob'), (3, 'Carol')",
+		"INSERT INTO public.posts VALUES (1, 1, 'Hello'), (2, 2, NULL)",
+		"INSERT INTO public.audit_log VALUES (1, 'created')",
+		"INSERT INTO public.categories VALUES (1, NULL), (2, 1)",
+	} {
+		_, err := client.GetDB().ExecContext(ctx, sql)
+		require.NoError(t, err)
+	}
+
+	dump := func(t *testing.T, opts DumpOptions) (*DumpFile, *DumpSummary) {
+		t.Helper()
+		enc := &memoryEncoder{}
+		summary, err := DumpTo(ctx, client, enc, opts)
+		require.NoError(t, err)
+		return &enc.file, summary
+	}
+
+	t.Run("concurrent matches serial", func(t *testing.T) {
+		serial, serialSummary := dump(t, DumpOptions{BatchSize: 1})
+		concurrent, concurrentSummary := dump(t, DumpOptions{BatchSize: 1, Concurrency: 3})
+
+		assert.Equal(t, serial.Tables, concurrent.Tables)
+		assert.Equal(t, serial.TableData, concurrent.TableData)
+		assert.Equal(t, serialSummary, concurrentSummary)
+		assert.Equal(t, 8, concurrentSummary.Rows)
+	})
+
+	t.Run("table selection and filter", func(t *testing.T) {
+		file, summary := dump(t, DumpOptions{
+			BatchSize:     100,
+			Concurrency:   2,
+			ExcludeTables: []string{"audit_log", "public.categories"},
+			Where:         map[string]string{"public.users": "id < 3"},
+		})
+
+		assert.ElementsMatch(t, []string{"public.users", "public.posts"}, file.Tables)
+		assert.Equal(t, 2, summary.Tables)
+		rows := map[string]int{}
+		for _, td := range file.TableData {
+			rows[td.QualifiedName] = td.RowCount
+		}
+		assert.Equal(t, map[string]int{"public.users": 2, "public.posts": 2}, rows)
+	})
+
+	t.Run("only listed tables", func(t *testing.T) {
+		file, _ := dump(t, DumpOptions{BatchSize: 100, Tables: []string{"audit_log"}})
+		assert.Equal(t, []string{"public.audit_log"}, file.Tables)
+	})
+
+	t.Run("masks", func(t *testing.T) {
+		file, _ := dump(t, DumpOptions{
+			BatchSize: 100,
+			Tables:    []string{"users"},
+			Masks:     Masks{"public.users.name": "value:anon"},
+			MaskSalt:  []byte("salt"),
+		})
+		require.Len(t, file.TableData, 1)
+		stmts := strings.Join(file.TableData[0].Statements, "\n")
+		assert.Contains(t, stmts, "'anon'")
+		assert.NotContains(t, stmts, "Alice")
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		_, err := DumpTo(ctx, client, &memoryEncoder{}, DumpOptions{BatchSize: 100, Tables: []string{"missing"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown table public.missing")
+	})
+
+	t.Run("bad predicate", func(t *testing.T) {
+		_, err := DumpTo(ctx, client, &memoryEncoder{}, DumpOptions{
+			BatchSize:   100,
+			Concurrency: 2,
+			Where:       map[string]string{"public.posts": "no_such_column = 1"},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to dump table public.posts")
+	})
+}
+
+func TestParseWhere(t *testing.T) {
+	t.Parallel()
+
+	where, err := ParseWhere([]string{"users:id < 10", "audit.log: created_at > '2026-01-01'"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"public.users": "id < 10",
+		"audit.log":    "created_at > '2026-01-01'",
+	}, where)
+
+	for _, spec := range []string{"users", "users:", ":id < 10"} {
+		_, err := ParseWhere([]string{spec})
+		assert.Error(t, err, spec)
+	}
+
+	_, err = ParseWhere([]string{"users:id < 10", "public.users:id > 5"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than one filter")
+}
+
+func TestSelectTables(t *testing.T) {
+	t.Parallel()
+
+	order := []string{"public.users", "public.posts", "audit.log"}
+
+	tests := []struct {
+		name    string
+		opts    DumpOptions
+		want    []string
+		wantErr string
+	}{
+		{name: "all", want: order},
+		{name: "include keeps insertion order", opts: DumpOptions{Tables: []string{"posts", "users"}}, want: []string{"public.users", "public.posts"}},
+		{name: "exclude", opts: DumpOptions{ExcludeTables: []string{"audit.log"}}, want: []string{"public.users", "public.posts"}},
+		{name: "unknown table", opts: DumpOptions{Tables: []string{"comments"}}, wantErr: "unknown table public.comments"},
+		{name: "filter on excluded table", opts: DumpOptions{ExcludeTables: []string{"users"}, Where: map[string]string{"public.users": "true"}}, wantErr: "isn't being dumped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := selectTables(order, tt.opts)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestGenerateInserts(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,357 @@
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Dump file formats
+const (
+	// FormatSQL is a SQL script of INSERT and UPDATE statements, with comments
+	// marking each section
+	FormatSQL = "sql"
+	// FormatNDJSON is one JSON object per line, holding the raw row values
+	FormatNDJSON = "ndjson"
+)
+
+// FormatForFile returns the format implied by a file's extension, ignoring
+// any compression extension
+func FormatForFile(name string) string {
+	for _, ext := range []string{".gz", ".zst", ".zstd"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if strings.HasSuffix(name, ".ndjson") || strings.HasSuffix(name, ".jsonl") {
+		return FormatNDJSON
+	}
+	return FormatSQL
+}
+
+// DumpHeader is written at the start of a dump
+type DumpHeader struct {
+	Version   int
+	CreatedAt time.Time
+	Tables    []string // table names in insertion order
+	SchemaSQL string
+}
+
+// TableHeader describes a table before its rows are written
+type TableHeader struct {
+	QualifiedName  string
+	Columns        []string
+	PrimaryKey     []string
+	SelfRefColumns []string
+	RowCount       int
+}
+
+// Encoder writes a dump as it is read, so tables never have to fit in memory.
+// Rows are written in batches between BeginTable and EndTable.
+type Encoder interface {
+	WriteHeader(h DumpHeader) error
+	BeginTable(t TableHeader) error
+	WriteRows(rows [][]*string) error
+	EndTable() error
+	WriteSequence(seq SequenceValue) error
+	// Close flushes the dump. It doesn't close the underlying writer.
+	Close() error
+}
+
+// NewEncoder returns an encoder writing the format to w
+func NewEncoder(w io.Writer, format string) (Encoder, error) {
+	switch format {
+	case FormatSQL, "":
+		return &sqlEncoder{w: bufio.NewWriter(w)}, nil
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonEncoder{w: bw, enc: json.NewEncoder(bw)}, nil
+	default:
+		return nil, fmt.Errorf("unknown dump format %q (use sql or ndjson)", format)
+	}
+}
+
+// tableStatements generates the statements for batches of a table's rows.
+// Self-referencing columns are inserted as NULL and set by UPDATE statements
+// that must run after all of the table's rows are inserted.
+type tableStatements struct {
+	header      TableHeader
+	quotedTable string
+	selfRefSet  map[string]bool
+	written     int
+}
+
+func newTableStatements(t TableHeader) *tableStatements {
+	parts := strings.SplitN(t.QualifiedName, ".", 2)
+	ts := &tableStatements{
+		header:      t,
+		quotedTable: pq.QuoteIdentifier(parts[0]) + "." + pq.QuoteIdentifier(parts[1]),
+		selfRefSet:  make(map[string]bool, len(t.SelfRefColumns)),
+	}
+	for _, col := range t.SelfRefColumns {
+		ts.selfRefSet[col] = true
+	}
+	return ts
+}
+
+// batch returns the INSERT statement for the rows and any UPDATE statements
+// to run after the table is loaded
+func (ts *tableStatements) batch(values [][]*string) (string, []string) {
+	rows := make([]rowData, len(values))
+	for i, v := range values {
+		rows[i] = rowData{values: v}
+	}
+	ts.written += len(rows)
+
+	if len(ts.selfRefSet) == 0 {
+		return generateInserts(ts.quotedTable, ts.header.Columns, rows, len(rows))[0], nil
+	}
+	stmts := generateSelfRefInserts(ts.quotedTable, ts.header.Columns, ts.selfRefSet, rows, ts.header.PrimaryKey, len(rows))
+	return stmts[0], stmts[1:]
+}
+
+func (ts *tableStatements) checkRowCount() error {
+	if ts.written != ts.header.RowCount {
+		return fmt.Errorf("table %s had %d rows but %d were written", ts.header.QualifiedName, ts.header.RowCount, ts.written)
+	}
+	return nil
+}
+
+// sqlEncoder writes the SQL format. UPDATE statements for self-referencing
+// tables are spilled to a temporary file until the table's INSERTs are written.
+type sqlEncoder struct {
+	w       *bufio.Writer
+	table   *tableStatements
+	updates *os.File
+	spill   *bufio.Writer
+}
+
+func (e *sqlEncoder) WriteHeader(h DumpHeader) error {
+	fmt.Fprintln(e.w, headerMarker)
+	fmt.Fprintf(e.w, "%s%d\n", versionPrefix, h.Version)
+	fmt.Fprintf(e.w, "%s%s\n", createdAtPrefix, h.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(e.w, "%s%s\n", tablesPrefix, strings.Join(h.Tables, ","))
+
+	fmt.Fprintln(e.w)
+	fmt.Fprintln(e.w, schemaBeginMarker)
+	if h.SchemaSQL != "" {
+		fmt.Fprintln(e.w, h.SchemaSQL)
+	}
+	_, err := fmt.Fprintln(e.w, schemaEndMarker)
+	return err
+}
+
+func (e *sqlEncoder) BeginTable(t TableHeader) error {
+	e.table = newTableStatements(t)
+	if len(t.SelfRefColumns) > 0 {
+		f, err := os.CreateTemp("", "scurry-dump-*.sql")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		e.updates = f
+		e.spill = bufio.NewWriter(f)
+	}
+	return e.writeTableHeader(t.QualifiedName, t.RowCount)
+}
+
+func (e *sqlEncoder) writeTableHeader(name string, rowCount int) error {
+	fmt.Fprintln(e.w)
+	_, err := fmt.Fprintf(e.w, "%s%s (%d rows)\n", tablePrefix, name, rowCount)
+	return err
+}
+
+func (e *sqlEncoder) writeStatement(w io.Writer, stmt string) error {
+	_, err := fmt.Fprintf(w, "%s\n\n", stmt)
+	return err
+}
+
+func (e *sqlEncoder) WriteRows(rows [][]*string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	insert, updates := e.table.batch(rows)
+	if err := e.writeStatement(e.w, insert); err != nil {
+		return err
+	}
+	for _, stmt := range updates {
+		if err := e.writeStatement(e.spill, stmt); err != nil {
+			return fmt.Errorf("failed to write temporary file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *sqlEncoder) EndTable() error {
+	if e.updates != nil {
+		defer e.removeSpill()
+		if err := e.spill.Flush(); err != nil {
+			return fmt.Errorf("failed to write temporary file: %w", err)
+		}
+		if _, err := e.updates.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read temporary file: %w", err)
+		}
+		if _, err := io.Copy(e.w, e.updates); err != nil {
+			return err
+		}
+	}
+	return e.table.checkRowCount()
+}
+
+func (e *sqlEncoder) removeSpill() {
+	e.updates.Close()
+	os.Remove(e.updates.Name())
+	e.updates = nil
+	e.spill = nil
+}
+
+func (e *sqlEncoder) WriteSequence(seq SequenceValue) error {
+	fmt.Fprintln(e.w)
+	fmt.Fprintf(e.w, "%s%s\n", sequencePrefix, seq.QualifiedName)
+	_, err := fmt.Fprintf(e.w, "SELECT setval('%s', %d);\n", seq.QualifiedName, seq.Value)
+	return err
+}
+
+func (e *sqlEncoder) Close() error {
+	if e.updates != nil {
+		e.removeSpill()
+	}
+	return e.w.Flush()
+}
+
+// ndjsonRecord is one line of the NDJSON format. Type decides which fields
+// are set: "header", "table", "row" (a row of the preceding table),
+// "table_end" and "sequence".
+type ndjsonRecord struct {
+	Type           string     `json:"type"`
+	Version        int        `json:"version,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	Tables         []string   `json:"tables,omitempty"`
+	Schema         string     `json:"schema,omitempty"`
+	Table          string     `json:"table,omitempty"`
+	Columns        []string   `json:"columns,omitempty"`
+	PrimaryKey     []string   `json:"primary_key,omitempty"`
+	SelfRefColumns []string   `json:"self_ref_columns,omitempty"`
+	Rows           *int       `json:"rows,omitempty"`
+	Values         []*string  `json:"values,omitempty"`
+	Sequence       string     `json:"sequence,omitempty"`
+	Value          *int64     `json:"value,omitempty"`
+}
+
+// Types of NDJSON records
+const (
+	ndjsonHeader   = "header"
+	ndjsonTable    = "table"
+	ndjsonRow      = "row"
+	ndjsonTableEnd = "table_end"
+	ndjsonSequence = "sequence"
+)
+
+// ndjsonEncoder writes the NDJSON format
+type ndjsonEncoder struct {
+	w     *bufio.Writer
+	enc   *json.Encoder
+	table TableHeader
+	rows  int
+}
+
+func (e *ndjsonEncoder) WriteHeader(h DumpHeader) error {
+	createdAt := h.CreatedAt.UTC()
+	return e.enc.Encode(ndjsonRecord{
+		Type:      ndjsonHeader,
+		Version:   h.Version,
+		CreatedAt: &createdAt,
+		Tables:    h.Tables,
+		Schema:    h.SchemaSQL,
+	})
+}
+
+func (e *ndjsonEncoder) BeginTable(t TableHeader) error {
+	e.table = t
+	e.rows = 0
+	rowCount := t.RowCount
+	return e.enc.Encode(ndjsonRecord{
+		Type:           ndjsonTable,
+		Table:          t.QualifiedName,
+		Columns:        t.Columns,
+		PrimaryKey:     t.PrimaryKey,
+		SelfRefColumns: t.SelfRefColumns,
+		Rows:           &rowCount,
+	})
+}
+
+func (e *ndjsonEncoder) WriteRows(rows [][]*string) error {
+	for _, values := range rows {
+		if err := e.enc.Encode(ndjsonRecord{Type: ndjsonRow, Values: values}); err != nil {
+			return err
+		}
+	}
+	e.rows += len(rows)
+	return nil
+}
+
+func (e *ndjsonEncoder) EndTable() error {
+	if e.rows != e.table.RowCount {
+		return fmt.Errorf("table %s had %d rows but %d were written", e.table.QualifiedName, e.table.RowCount, e.rows)
+	}
+	rows := e.rows
+	return e.enc.Encode(ndjsonRecord{Type: ndjsonTableEnd, Table: e.table.QualifiedName, Rows: &rows})
+}
+
+func (e *ndjsonEncoder) WriteSequence(seq SequenceValue) error {
+	value := seq.Value
+	return e.enc.Encode(ndjsonRecord{Type: ndjsonSequence, Sequence: seq.QualifiedName, Value: &value})
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return e.w.Flush()
+}
+
+// memoryEncoder builds a DumpFile, for callers that want the whole dump
+type memoryEncoder struct {
+	file    DumpFile
+	table   *tableStatements
+	current TableDump
+	updates []string
+}
+
+func (e *memoryEncoder) WriteHeader(h DumpHeader) error {
+	e.file.Version = h.Version
+	e.file.CreatedAt = h.CreatedAt
+	e.file.Tables = h.Tables
+	e.file.SchemaSQL = h.SchemaSQL
+	return nil
+}
+
+func (e *memoryEncoder) BeginTable(t TableHeader) error {
+	e.table = newTableStatements(t)
+	e.current = TableDump{QualifiedName: t.QualifiedName, RowCount: t.RowCount}
+	e.updates = nil
+	return nil
+}
+
+func (e *memoryEncoder) WriteRows(rows [][]*string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	insert, updates := e.table.batch(rows)
+	e.current.Statements = append(e.current.Statements, insert)
+	e.updates = append(e.updates, updates...)
+	return nil
+}
+
+func (e *memoryEncoder) EndTable() error {
+	e.current.Statements = append(e.current.Statements, e.updates...)
+	e.file.TableData = append(e.file.TableData, e.current)
+	return e.table.checkRowCount()
+}
+
+func (e *memoryEncoder) WriteSequence(seq SequenceValue) error {
+	e.file.Sequences = append(e.file.Sequences, seq)
+	return nil
+}
+
+func (e *memoryEncoder) Close() error { return nil }
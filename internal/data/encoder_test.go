@@ -0,0 +1,202 @@
+package data
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeTestDump writes a small dump with a self-referencing table through enc
+func encodeTestDump(t *testing.T, enc Encoder) {
+	t.Helper()
+
+	require.NoError(t, enc.WriteHeader(DumpHeader{
+		Version:   1,
+		CreatedAt: time.Date(2026, 2, 14, 10, 30, 0, 0, time.UTC),
+		Tables:    []string{"public.users", "public.categories"},
+		SchemaSQL: "CREATE TABLE public.users (id INT8 PRIMARY KEY, name STRING);",
+	}))
+
+	require.NoError(t, enc.BeginTable(TableHeader{
+		QualifiedName: "public.users",
+		Columns:       []string{"id", "name"},
+		PrimaryKey:    []string{"id"},
+		RowCount:      3,
+	}))
+	require.NoError(t, enc.WriteRows([][]*string{
+		{strPtr("1"), strPtr("Alice")},
+		{strPtr("2"), nil},
+	}))
+	require.NoError(t, enc.WriteRows([][]*string{
+		{strPtr("3"), strPtr("O'Brien")},
+	}))
+	require.NoError(t, enc.EndTable())
+
+	require.NoError(t, enc.BeginTable(TableHeader{
+		QualifiedName:  "public.categories",
+		Columns:        []string{"id", "parent_id"},
+		PrimaryKey:     []string{"id"},
+		SelfRefColumns: []string{"parent_id"},
+		RowCount:       2,
+	}))
+	require.NoError(t, enc.WriteRows([][]*string{{strPtr("1"), nil}}))
+	require.NoError(t, enc.WriteRows([][]*string{{strPtr("2"), strPtr("1")}}))
+	require.NoError(t, enc.EndTable())
+
+	require.NoError(t, enc.WriteSequence(SequenceValue{QualifiedName: "public.user_seq", Value: 42}))
+	require.NoError(t, enc.Close())
+}
+
+func TestSQLEncoderMatchesDumpFile(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryEncoder{}
+	encodeTestDump(t, mem)
+
+	var expected bytes.Buffer
+	require.NoError(t, mem.file.Write(&expected))
+
+	var streamed bytes.Buffer
+	enc, err := NewEncoder(&streamed, FormatSQL)
+	require.NoError(t, err)
+	encodeTestDump(t, enc)
+
+	assert.Equal(t, expected.String(), streamed.String())
+
+	// Updates for self-referencing rows come after all of the table's inserts
+	categories := mem.file.TableData[1]
+	require.Len(t, categories.Statements, 3)
+	assert.Contains(t, categories.Statements[0], "INSERT INTO")
+	assert.Contains(t, categories.Statements[1], "INSERT INTO")
+	assert.Equal(t, `UPDATE "public"."categories" SET "parent_id" = '1' WHERE "id" = '2';`, categories.Statements[2])
+}
+
+func TestNDJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	mem := &memoryEncoder{}
+	encodeTestDump(t, mem)
+
+	var buf bytes.Buffer
+	enc, err := NewEncoder(&buf, FormatNDJSON)
+	require.NoError(t, err)
+	encodeTestDump(t, enc)
+
+	parsed, err := ParseDumpFile(&buf)
+	require.NoError(t, err)
+
+	// Rows are re-batched when parsed, so compare the data rather than
+	// the statements
+	assert.Equal(t, mem.file.Version, parsed.Version)
+	assert.True(t, mem.file.CreatedAt.Equal(parsed.CreatedAt))
+	assert.Equal(t, mem.file.Tables, parsed.Tables)
+	assert.Equal(t, mem.file.SchemaSQL, parsed.SchemaSQL)
+	assert.Equal(t, mem.file.Sequences, parsed.Sequences)
+	require.Len(t, parsed.TableData, 2)
+
+	users := parsed.TableData[0]
+	assert.Equal(t, "public.users", users.QualifiedName)
+	assert.Equal(t, 3, users.RowCount)
+	require.Len(t, users.Statements, 1)
+	assert.Equal(t, "INSERT INTO \"public\".\"users\" (\"id\", \"name\") VALUES\n('1', 'Alice'),\n('2', NULL),\n('3', 'O''Brien');", users.Statements[0])
+
+	categories := parsed.TableData[1]
+	require.Len(t, categories.Statements, 2)
+	assert.Equal(t, "INSERT INTO \"public\".\"categories\" (\"id\", \"parent_id\") VALUES\n('1', NULL),\n('2', NULL);", categories.Statements[0])
+	assert.Equal(t, mem.file.TableData[1].Statements[2], categories.Statements[1])
+}
+
+func TestEncoderRowCountMismatch(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []string{FormatSQL, FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			enc, err := NewEncoder(&buf, format)
+			require.NoError(t, err)
+
+			require.NoError(t, enc.BeginTable(TableHeader{
+				QualifiedName: "public.users",
+				Columns:       []string{"id"},
+				RowCount:      2,
+			}))
+			require.NoError(t, enc.WriteRows([][]*string{{strPtr("1")}}))
+			err = enc.EndTable()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "had 2 rows but 1 were written")
+		})
+	}
+}
+
+func TestNewEncoderUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewEncoder(&bytes.Buffer{}, "xml")
+	assert.Error(t, err)
+}
+
+func TestParseNDJSONErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{
+			name:    "missing header",
+			content: `{"type":"table","table":"public.users","columns":["id"],"rows":0}`,
+			errMsg:  "missing header record",
+		},
+		{
+			name: "row outside table",
+			content: `{"type":"header","version":1}
+{"type":"row","values":["1"]}`,
+			errMsg: "row outside of a table",
+		},
+		{
+			name: "wrong number of values",
+			content: `{"type":"header","version":1}
+{"type":"table","table":"public.users","columns":["id","name"],"rows":1}
+{"type":"row","values":["1"]}`,
+			errMsg: "expected 2 values, got 1",
+		},
+		{
+			name: "incomplete table",
+			content: `{"type":"header","version":1}
+{"type":"table","table":"public.users","columns":["id"],"rows":1}
+{"type":"row","values":["1"]}`,
+			errMsg: "table public.users is incomplete",
+		},
+		{
+			name: "unknown record",
+			content: `{"type":"header","version":1}
+{"type":"index"}`,
+			errMsg: `unknown record type "index"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseDumpFile(bytes.NewBufferString(tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestFormatForFile(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, FormatSQL, FormatForFile("dump.sql"))
+	assert.Equal(t, FormatSQL, FormatForFile("dump.sql.gz"))
+	assert.Equal(t, FormatNDJSON, FormatForFile("dump.ndjson"))
+	assert.Equal(t, FormatNDJSON, FormatForFile("dump.ndjson.zst"))
+	assert.Equal(t, FormatNDJSON, FormatForFile("dump.jsonl.gz"))
+}
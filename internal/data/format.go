@@ -2,6 +2,7 @@ package data
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
@@ -43,45 +44,44 @@ type SequenceValue struct {
 	Value         int64
 }
 
-// Write serializes the DumpFile to the given writer.
+// Write serializes the DumpFile to the given writer in the SQL format.
 func (d *DumpFile) Write(w io.Writer) error {
-	bw := bufio.NewWriter(w)
-
-	// Header
-	fmt.Fprintln(bw, headerMarker)
-	fmt.Fprintf(bw, "%s%d\n", versionPrefix, d.Version)
-	fmt.Fprintf(bw, "%s%s\n", createdAtPrefix, d.CreatedAt.UTC().Format(time.RFC3339))
-	fmt.Fprintf(bw, "%s%s\n", tablesPrefix, strings.Join(d.Tables, ","))
-
-	// Schema section
-	fmt.Fprintln(bw)
-	fmt.Fprintln(bw, schemaBeginMarker)
-	if d.SchemaSQL != "" {
-		fmt.Fprintln(bw, d.SchemaSQL)
+	enc := &sqlEncoder{w: bufio.NewWriter(w)}
+
+	err := enc.WriteHeader(DumpHeader{
+		Version:   d.Version,
+		CreatedAt: d.CreatedAt,
+		Tables:    d.Tables,
+		SchemaSQL: d.SchemaSQL,
+	})
+	if err != nil {
+		return err
 	}
-	fmt.Fprintln(bw, schemaEndMarker)
 
 	// Table data sections
 	for _, td := range d.TableData {
-		fmt.Fprintln(bw)
-		fmt.Fprintf(bw, "%s%s (%d rows)\n", tablePrefix, td.QualifiedName, td.RowCount)
+		if err := enc.writeTableHeader(td.QualifiedName, td.RowCount); err != nil {
+			return err
+		}
 		for _, stmt := range td.Statements {
-			fmt.Fprintln(bw, stmt)
-			fmt.Fprintln(bw)
+			if err := enc.writeStatement(enc.w, stmt); err != nil {
+				return err
+			}
 		}
 	}
 
 	// Sequence sections
 	for _, seq := range d.Sequences {
-		fmt.Fprintln(bw)
-		fmt.Fprintf(bw, "%s%s\n", sequencePrefix, seq.QualifiedName)
-		fmt.Fprintf(bw, "SELECT setval('%s', %d);\n", seq.QualifiedName, seq.Value)
+		if err := enc.WriteSequence(seq); err != nil {
+			return err
+		}
 	}
 
-	return bw.Flush()
+	return enc.Close()
 }
 
-// ParseDumpFile parses a dump file from the given reader.
+// ParseDumpFile parses a dump file in either the SQL or NDJSON format from
+// the given reader. NDJSON rows are converted into INSERT statements.
 func ParseDumpFile(r io.Reader) (*DumpFile, error) {
 	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
@@ -99,6 +99,10 @@ func ParseDumpFile(r io.Reader) (*DumpFile, error) {
 		return nil, fmt.Errorf("empty dump file")
 	}
 
+	if strings.HasPrefix(strings.TrimSpace(lines[0]), "{") {
+		return parseNDJSONLines(lines)
+	}
+
 	df := &DumpFile{}
 	i := 0
 
@@ -294,3 +298,106 @@ func parseSequenceLines(lines []string, start int) (SequenceValue, int, error) {
 
 	return sv, i, nil
 }
+
+// ndjsonBatchSize is the number of rows per INSERT statement generated when
+// reading an NDJSON dump
+const ndjsonBatchSize = 100
+
+// parseNDJSONLines parses an NDJSON dump, generating the same statements the
+// SQL format would hold for its rows.
+func parseNDJSONLines(lines []string) (*DumpFile, error) {
+	df := &DumpFile{}
+	var table *tableStatements
+	var current TableDump
+	var pending [][]*string
+	var updates []string
+	sawHeader := false
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		insert, stmts := table.batch(pending)
+		current.Statements = append(current.Statements, insert)
+		updates = append(updates, stmts...)
+		pending = pending[:0]
+	}
+
+	for n, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var rec ndjsonRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("invalid dump file: line %d: %w", n+1, err)
+		}
+
+		if !sawHeader && rec.Type != ndjsonHeader {
+			return nil, fmt.Errorf("invalid dump file: missing header record")
+		}
+
+		switch rec.Type {
+		case ndjsonHeader:
+			sawHeader = true
+			df.Version = rec.Version
+			if rec.CreatedAt != nil {
+				df.CreatedAt = *rec.CreatedAt
+			}
+			df.Tables = rec.Tables
+			df.SchemaSQL = strings.TrimSpace(rec.Schema)
+		case ndjsonTable:
+			if table != nil {
+				return nil, fmt.Errorf("invalid dump file: line %d: table %s started before %s ended", n+1, rec.Table, table.header.QualifiedName)
+			}
+			if strings.Count(rec.Table, ".") < 1 {
+				return nil, fmt.Errorf("invalid dump file: line %d: invalid table name %q", n+1, rec.Table)
+			}
+			header := TableHeader{
+				QualifiedName:  rec.Table,
+				Columns:        rec.Columns,
+				PrimaryKey:     rec.PrimaryKey,
+				SelfRefColumns: rec.SelfRefColumns,
+			}
+			if rec.Rows != nil {
+				header.RowCount = *rec.Rows
+			}
+			table = newTableStatements(header)
+			current = TableDump{QualifiedName: header.QualifiedName, RowCount: header.RowCount}
+			updates = nil
+		case ndjsonRow:
+			if table == nil {
+				return nil, fmt.Errorf("invalid dump file: line %d: row outside of a table", n+1)
+			}
+			if len(rec.Values) != len(table.header.Columns) {
+				return nil, fmt.Errorf("invalid dump file: line %d: expected %d values, got %d", n+1, len(table.header.Columns), len(rec.Values))
+			}
+			pending = append(pending, rec.Values)
+			if len(pending) == ndjsonBatchSize {
+				flush()
+			}
+		case ndjsonTableEnd:
+			if table == nil {
+				return nil, fmt.Errorf("invalid dump file: line %d: table end outside of a table", n+1)
+			}
+			flush()
+			if err := table.checkRowCount(); err != nil {
+				return nil, fmt.Errorf("invalid dump file: %w", err)
+			}
+			current.Statements = append(current.Statements, updates...)
+			df.TableData = append(df.TableData, current)
+			table = nil
+		case ndjsonSequence:
+			if rec.Value == nil {
+				return nil, fmt.Errorf("invalid dump file: line %d: sequence %s has no value", n+1, rec.Sequence)
+			}
+			df.Sequences = append(df.Sequences, SequenceValue{QualifiedName: rec.Sequence, Value: *rec.Value})
+		default:
+			return nil, fmt.Errorf("invalid dump file: line %d: unknown record type %q", n+1, rec.Type)
+		}
+	}
+
+	if table != nil {
+		return nil, fmt.Errorf("invalid dump file: table %s is incomplete", table.header.QualifiedName)
+	}
+	return df, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/cockroachdb/cockroach-go/v2/crdb"
@@ -19,16 +20,42 @@ type LoadOptions struct {
 	DryRun        bool
 	TruncateFirst bool
 	CreateSchema  bool
+	// SchemaOnly creates the dump's schema without loading any data. It implies
+	// CreateSchema.
+	SchemaOnly bool
+	// DataOnly loads the data into the existing schema, which must be
+	// compatible with the dump's
+	DataOnly bool
+}
+
+func (o LoadOptions) validate() error {
+	switch {
+	case o.SchemaOnly && o.DataOnly:
+		return fmt.Errorf("schema-only and data-only can't be used together")
+	case o.DataOnly && o.CreateSchema:
+		return fmt.Errorf("data-only can't be used with create-schema")
+	case o.SchemaOnly && o.TruncateFirst:
+		return fmt.Errorf("schema-only can't be used with truncate, since no data is loaded")
+	}
+	return nil
 }
 
 // LoadResult contains summary information about the load operation.
 type LoadResult struct {
-	TablesLoaded int
-	RowsInserted int
+	TablesLoaded      int
+	RowsInserted      int
+	SequencesRestored int
+	SchemaCreated     bool
 }
 
-// Load imports data from a DumpFile into the target database.
+// Load imports data from a DumpFile into the target database. Tables are loaded
+// in foreign key order regardless of their order in the dump.
 func Load(ctx context.Context, client *db.Client, dump *DumpFile, opts LoadOptions) (*LoadResult, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	createSchema := opts.CreateSchema || opts.SchemaOnly
+
 	// Parse dump schema
 	dumpStatements, err := parseDumpSchema(dump.SchemaSQL)
 	if err != nil {
@@ -36,11 +63,17 @@ func Load(ctx context.Context, client *db.Client, dump *DumpFile, opts LoadOptio
 	}
 	dumpSchema := schema.NewSchema(dumpStatements...)
 
-	if opts.CreateSchema {
+	if createSchema {
+		if opts.DryRun {
+			return dryRunResult(dump, opts), nil
+		}
 		// Apply the dump's schema to the target DB
 		if err := applySchema(ctx, client, dumpSchema); err != nil {
 			return nil, fmt.Errorf("failed to create schema: %w", err)
 		}
+		if opts.SchemaOnly {
+			return &LoadResult{SchemaCreated: true}, nil
+		}
 	} else {
 		// Load target schema and check compatibility
 		targetSchema, err := schema.LoadFromDatabase(ctx, client)
@@ -62,15 +95,7 @@ func Load(ctx context.Context, client *db.Client, dump *DumpFile, opts LoadOptio
 	}
 
 	if opts.DryRun {
-		// Just return summary
-		totalRows := 0
-		for _, td := range dump.TableData {
-			totalRows += td.RowCount
-		}
-		return &LoadResult{
-			TablesLoaded: len(dump.TableData),
-			RowsInserted: totalRows,
-		}, nil
+		return dryRunResult(dump, opts), nil
 	}
 
 	// Compute insertion order for truncation
@@ -92,7 +117,7 @@ func Load(ctx context.Context, client *db.Client, dump *DumpFile, opts LoadOptio
 	// Execute table data
 	totalRows := 0
 	tablesLoaded := 0
-	for _, td := range dump.TableData {
+	for _, td := range orderTableData(dump.TableData, insertionOrder.Order) {
 		if len(td.Statements) == 0 {
 			continue
 		}
@@ -131,11 +156,53 @@ func Load(ctx context.Context, client *db.Client, dump *DumpFile, opts LoadOptio
 	}
 
 	return &LoadResult{
-		TablesLoaded: tablesLoaded,
-		RowsInserted: totalRows,
+		TablesLoaded:      tablesLoaded,
+		RowsInserted:      totalRows,
+		SequencesRestored: len(dump.Sequences),
+		SchemaCreated:     createSchema,
 	}, nil
 }
 
+// dryRunResult summarizes what loading the dump would do
+func dryRunResult(dump *DumpFile, opts LoadOptions) *LoadResult {
+	result := &LoadResult{SchemaCreated: opts.CreateSchema || opts.SchemaOnly}
+	if opts.SchemaOnly {
+		return result
+	}
+	for _, td := range dump.TableData {
+		result.RowsInserted += td.RowCount
+	}
+	result.TablesLoaded = len(dump.TableData)
+	result.SequencesRestored = len(dump.Sequences)
+	return result
+}
+
+// orderTableData sorts the dumped tables into insertion order, so referenced
+// tables are loaded before the tables referencing them. Tables missing from the
+// order keep their relative position after the ordered ones.
+func orderTableData(tableData []TableDump, order []string) []TableDump {
+	position := make(map[string]int, len(order))
+	for i, name := range order {
+		position[name] = i
+	}
+	ordered := slices.Clone(tableData)
+	slices.SortStableFunc(ordered, func(a, b TableDump) int {
+		pa, okA := position[a.QualifiedName]
+		pb, okB := position[b.QualifiedName]
+		switch {
+		case okA && okB:
+			return pa - pb
+		case okA:
+			return -1
+		case okB:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return ordered
+}
+
 // CompatibilityError wraps compatibility issues for structured error handling.
 type CompatibilityError struct {
 	Issues []CompatibilityIssue
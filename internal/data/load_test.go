@@ -180,6 +180,103 @@ func TestLoadCreateSchema(t *testing.T) {
 	assert.Equal(t, 1, count)
 }
 
+func TestLoadSchemaOnly(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	srcClient, err := db.GetShadowDB(ctx,
+		"CREATE TABLE public.users (id INT8 PRIMARY KEY, name STRING NOT NULL)",
+		"CREATE SEQUENCE public.user_ids",
+	)
+	require.NoError(t, err)
+	defer srcClient.Close()
+
+	_, err = srcClient.GetDB().ExecContext(ctx, "INSERT INTO public.users VALUES (1, 'Alice')")
+	require.NoError(t, err)
+
+	dumpFile, err := Dump(ctx, srcClient, 100)
+	require.NoError(t, err)
+
+	targetClient, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer targetClient.Close()
+
+	result, err := Load(ctx, targetClient, dumpFile, LoadOptions{SchemaOnly: true})
+	require.NoError(t, err)
+	assert.True(t, result.SchemaCreated)
+	assert.Equal(t, 0, result.TablesLoaded)
+	assert.Equal(t, 0, result.SequencesRestored)
+
+	// The table exists but is empty
+	var count int
+	err = targetClient.GetDB().QueryRowContext(ctx, "SELECT count(*) FROM public.users").Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestLoadDryRunCreateSchema(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	srcClient, err := db.GetShadowDB(ctx, "CREATE TABLE public.users (id INT8 PRIMARY KEY, name STRING NOT NULL)")
+	require.NoError(t, err)
+	defer srcClient.Close()
+
+	_, err = srcClient.GetDB().ExecContext(ctx, "INSERT INTO public.users VALUES (1, 'Alice')")
+	require.NoError(t, err)
+
+	dumpFile, err := Dump(ctx, srcClient, 100)
+	require.NoError(t, err)
+
+	targetClient, err := db.GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer targetClient.Close()
+
+	result, err := Load(ctx, targetClient, dumpFile, LoadOptions{DryRun: true, CreateSchema: true})
+	require.NoError(t, err)
+	assert.True(t, result.SchemaCreated)
+	assert.Equal(t, 1, result.RowsInserted)
+
+	// Nothing was created
+	var exists bool
+	err = targetClient.GetDB().QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = 'public' AND table_name = 'users')",
+	).Scan(&exists)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLoadOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, LoadOptions{}.validate())
+	assert.NoError(t, LoadOptions{SchemaOnly: true, CreateSchema: true}.validate())
+	assert.NoError(t, LoadOptions{DataOnly: true, TruncateFirst: true}.validate())
+	assert.ErrorContains(t, LoadOptions{SchemaOnly: true, DataOnly: true}.validate(), "can't be used together")
+	assert.ErrorContains(t, LoadOptions{DataOnly: true, CreateSchema: true}.validate(), "create-schema")
+	assert.ErrorContains(t, LoadOptions{SchemaOnly: true, TruncateFirst: true}.validate(), "truncate")
+}
+
+func TestOrderTableData(t *testing.T) {
+	t.Parallel()
+
+	tableData := []TableDump{
+		{QualifiedName: "public.comments"},
+		{QualifiedName: "public.audit"},
+		{QualifiedName: "public.posts"},
+		{QualifiedName: "public.users"},
+	}
+	order := []string{"public.users", "public.posts", "public.comments"}
+
+	var names []string
+	for _, td := range orderTableData(tableData, order) {
+		names = append(names, td.QualifiedName)
+	}
+	assert.Equal(t, []string{"public.users", "public.posts", "public.comments", "public.audit"}, names)
+	// The dump itself is left alone
+	assert.Equal(t, "public.comments", tableData[0].QualifiedName)
+}
+
 func TestLoadCompatibilityError(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -0,0 +1,341 @@
+package data
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/types"
+)
+
+// Masking rules
+const (
+	// MaskHash replaces a value with a keyed hash of it. Equal values hash the
+	// same, so joins and foreign keys still line up. Integer and UUID columns
+	// get an integer that fits the column or a UUID, and string columns a hex
+	// string of hashLength characters.
+	MaskHash = "hash"
+	// MaskEmail replaces a value in a string column with an address at
+	// example.com, built from its hash
+	MaskEmail = "email"
+	// MaskNull replaces a value with NULL
+	MaskNull = "null"
+	// MaskValuePrefix starts a rule that replaces every value with a constant,
+	// e.g. "value:redacted"
+	MaskValuePrefix = "value:"
+)
+
+// Lengths of the strings the hash and email masks produce
+const (
+	hashLength  = 32
+	emailLength = len("user_") + 16 + len("@example.com")
+)
+
+// Masks maps qualified column names (schema.table.column) to the rule that
+// masks them.
+type Masks map[string]string
+
+// ParseMasks qualifies and checks the column masks from the config file,
+// keyed by [schema.]table.column.
+func ParseMasks(rules map[string]string) (Masks, error) {
+	masks := make(Masks, len(rules))
+	for column, rule := range rules {
+		parts := strings.Split(column, ".")
+		switch len(parts) {
+		case 2:
+			parts = append([]string{"public"}, parts...)
+		case 3:
+		default:
+			return nil, fmt.Errorf("invalid masked column %q: expected [schema.]table.column", column)
+		}
+		if err := checkMaskRule(rule); err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+		name := strings.Join(parts, ".")
+		if _, dup := masks[name]; dup {
+			return nil, fmt.Errorf("column %s has more than one mask", name)
+		}
+		masks[name] = rule
+	}
+	return masks, nil
+}
+
+func checkMaskRule(rule string) error {
+	switch {
+	case rule == MaskHash, rule == MaskEmail, rule == MaskNull:
+		return nil
+	case strings.HasPrefix(rule, MaskValuePrefix):
+		return nil
+	}
+	return fmt.Errorf("unknown mask %q: expected hash, email, null or value:<text>", rule)
+}
+
+// columnMask masks one column's values
+type columnMask struct {
+	rule   string
+	family types.Family
+	width  int32
+}
+
+// resolveMasks returns the masks for each dumped table's columns, indexed like
+// the table's header columns. Masks on a column that a foreign key references
+// carry over to the referencing columns, so masked keys still match.
+func resolveMasks(masks Masks, tables map[string]*tree.CreateTable) (map[string][]*columnMask, error) {
+	if len(masks) == 0 {
+		return nil, nil
+	}
+
+	rules := make(Masks, len(masks))
+	for column, rule := range masks {
+		table, col := splitColumnName(column)
+		tableAST, ok := tables[table]
+		if !ok {
+			return nil, fmt.Errorf("masked column %s: unknown table %s", column, table)
+		}
+		if findColumn(tableAST, col) == nil {
+			return nil, fmt.Errorf("masked column %s: unknown column %s", column, col)
+		}
+		rules[column] = rule
+	}
+
+	// Follow foreign keys until no more masks carry over
+	for changed := true; changed; {
+		changed = false
+		for _, name := range slices.Sorted(maps.Keys(tables)) {
+			for _, fk := range foreignKeys(tables[name]) {
+				schemaName, tableName := tableNameOf(fk.Table)
+				target := schemaName + "." + tableName
+				toCols := referencedColumns(fk, tables[target])
+				for i, from := range fk.FromCols {
+					if i >= len(toCols) {
+						break
+					}
+					rule, ok := rules[target+"."+toCols[i]]
+					column := name + "." + from.Normalize()
+					if _, masked := rules[column]; ok && !masked {
+						// Integer hashes are cut down to the column's width, so
+						// they'd only match across columns of the same width
+						if rule == MaskHash && intWidth(findColumn(tables[name], from.Normalize())) != intWidth(findColumn(tables[target], toCols[i])) {
+							return nil, fmt.Errorf("masked column %s.%s: %s references it with a different integer width, so their hashes wouldn't match", target, toCols[i], column)
+						}
+						rules[column] = rule
+						changed = true
+					}
+				}
+			}
+		}
+	}
+
+	resolved := make(map[string][]*columnMask)
+	for name, tableAST := range tables {
+		columns, pkColumns := getTableColumns(tableAST)
+		uniqueCols := uniqueColumns(tableAST)
+		var tableMasks []*columnMask
+		for i, col := range columns {
+			rule, ok := rules[name+"."+col.Name.Normalize()]
+			if !ok {
+				continue
+			}
+			if rule == MaskNull && slices.Contains(pkColumns, col.Name.Normalize()) {
+				return nil, fmt.Errorf("masked column %s.%s: primary key columns can't be null", name, col.Name)
+			}
+			// Every row would get the same value, so the inserts would fail on duplicate keys
+			if strings.HasPrefix(rule, MaskValuePrefix) && slices.Contains(pkColumns, col.Name.Normalize()) {
+				return nil, fmt.Errorf("masked column %s.%s: primary key columns can't all be set to the same value", name, col.Name)
+			}
+			if strings.HasPrefix(rule, MaskValuePrefix) && slices.Contains(uniqueCols, col.Name.Normalize()) {
+				return nil, fmt.Errorf("masked column %s.%s: unique columns can't all be set to the same value", name, col.Name)
+			}
+			if tableMasks == nil {
+				tableMasks = make([]*columnMask, len(columns))
+			}
+			mask := &columnMask{rule: rule}
+			t, _ := col.Type.(*types.T)
+			if t != nil {
+				mask.family = t.Family()
+				mask.width = t.Width()
+			}
+			if err := checkMaskType(rule, t); err != nil {
+				return nil, fmt.Errorf("masked column %s.%s: %w", name, col.Name, err)
+			}
+			tableMasks[i] = mask
+		}
+		if tableMasks != nil {
+			resolved[name] = tableMasks
+		}
+	}
+	return resolved, nil
+}
+
+// checkMaskType fails if the hash or email mask can't produce a value the
+// column's type accepts
+func checkMaskType(rule string, t *types.T) error {
+	if rule != MaskHash && rule != MaskEmail {
+		return nil
+	}
+	if t == nil {
+		return fmt.Errorf("the %s mask needs a built-in column type", rule)
+	}
+	length := hashLength
+	if rule == MaskEmail {
+		length = emailLength
+	}
+	switch t.Family() {
+	case types.IntFamily, types.UuidFamily:
+		if rule == MaskHash {
+			return nil
+		}
+	case types.StringFamily, types.CollatedStringFamily:
+		if t.Width() > 0 && int(t.Width()) < length {
+			return fmt.Errorf("the %s mask produces %d characters, which don't fit in %s", rule, length, t.SQLString())
+		}
+		return nil
+	}
+	return fmt.Errorf("the %s mask can't produce %s values", rule, t.SQLString())
+}
+
+// intWidth returns the width of an integer column, or 0 for other columns
+func intWidth(col *tree.ColumnTableDef) int32 {
+	if col == nil {
+		return 0
+	}
+	if t, ok := col.Type.(*types.T); ok && t.Family() == types.IntFamily {
+		return t.Width()
+	}
+	return 0
+}
+
+// maskRows masks values in place
+func maskRows(rows [][]*string, masks []*columnMask, salt []byte) {
+	for _, row := range rows {
+		for i, mask := range masks {
+			if mask != nil && row[i] != nil {
+				row[i] = mask.apply(*row[i], salt)
+			}
+		}
+	}
+}
+
+func (m *columnMask) apply(value string, salt []byte) *string {
+	var masked string
+	switch {
+	case m.rule == MaskNull:
+		return nil
+	case strings.HasPrefix(m.rule, MaskValuePrefix):
+		masked = strings.TrimPrefix(m.rule, MaskValuePrefix)
+	case m.rule == MaskEmail:
+		sum := maskHash(value, salt)
+		masked = "user_" + hex.EncodeToString(sum[:8]) + "@example.com"
+	default:
+		sum := maskHash(value, salt)
+		switch m.family {
+		case types.IntFamily:
+			n := binary.BigEndian.Uint64(sum[:8]) >> 1
+			if m.width > 0 && m.width < 64 {
+				n %= 1 << (m.width - 1)
+			}
+			masked = strconv.FormatUint(n, 10)
+		case types.UuidFamily:
+			h := hex.EncodeToString(sum[:16])
+			masked = h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+		default:
+			masked = hex.EncodeToString(sum[:16])
+		}
+	}
+	return &masked
+}
+
+func maskHash(value string, salt []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// uniqueColumns returns the columns in the table's UNIQUE constraints and
+// indexes, other than its primary key
+func uniqueColumns(tableAST *tree.CreateTable) []string {
+	var columns []string
+	for _, def := range tableAST.Defs {
+		switch d := def.(type) {
+		case *tree.ColumnTableDef:
+			if d.Unique.IsUnique {
+				columns = append(columns, d.Name.Normalize())
+			}
+		case *tree.UniqueConstraintTableDef:
+			if d.PrimaryKey {
+				continue
+			}
+			for _, col := range d.Columns {
+				if col.Column != "" {
+					columns = append(columns, col.Column.Normalize())
+				}
+			}
+		}
+	}
+	return columns
+}
+
+func splitColumnName(column string) (table, col string) {
+	i := strings.LastIndex(column, ".")
+	return column[:i], column[i+1:]
+}
+
+func findColumn(tableAST *tree.CreateTable, name string) *tree.ColumnTableDef {
+	for _, def := range tableAST.Defs {
+		if col, ok := def.(*tree.ColumnTableDef); ok && col.Name.Normalize() == name {
+			return col
+		}
+	}
+	return nil
+}
+
+// foreignKeys returns the table's foreign keys, including ones declared inline
+// on a column
+func foreignKeys(tableAST *tree.CreateTable) []*tree.ForeignKeyConstraintTableDef {
+	var fks []*tree.ForeignKeyConstraintTableDef
+	for _, def := range tableAST.Defs {
+		switch d := def.(type) {
+		case *tree.ForeignKeyConstraintTableDef:
+			fks = append(fks, d)
+		case *tree.ColumnTableDef:
+			if d.References.Table != nil {
+				fks = append(fks, &tree.ForeignKeyConstraintTableDef{
+					Table:    *d.References.Table,
+					FromCols: tree.NameList{d.Name},
+					ToCols:   tree.NameList{d.References.Col},
+				})
+			}
+		}
+	}
+	return fks
+}
+
+// referencedColumns returns the columns a foreign key references, which are the
+// target's primary key when it doesn't name them
+func referencedColumns(fk *tree.ForeignKeyConstraintTableDef, target *tree.CreateTable) []string {
+	var cols []string
+	for _, col := range fk.ToCols {
+		if col != "" {
+			cols = append(cols, col.Normalize())
+		}
+	}
+	if len(cols) == 0 && target != nil {
+		_, cols = getTableColumns(target)
+	}
+	return cols
+}
+
+func tableNameOf(name tree.TableName) (string, string) {
+	schemaName := "public"
+	if name.ExplicitSchema {
+		schemaName = name.Schema()
+	}
+	return schemaName, name.Table()
+}
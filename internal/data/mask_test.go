@@ -0,0 +1,179 @@
+package data
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMasks(t *testing.T) {
+	t.Parallel()
+
+	masks, err := ParseMasks(map[string]string{
+		"users.email":          MaskEmail,
+		"billing.cards.number": "value:4111",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Masks{"public.users.email": MaskEmail, "billing.cards.number": "value:4111"}, masks)
+
+	_, err = ParseMasks(map[string]string{"email": MaskHash})
+	assert.ErrorContains(t, err, "expected [schema.]table.column")
+
+	_, err = ParseMasks(map[string]string{"users.email": "scramble"})
+	assert.ErrorContains(t, err, `unknown mask "scramble"`)
+
+	_, err = ParseMasks(map[string]string{"users.email": MaskHash, "public.users.email": MaskNull})
+	assert.ErrorContains(t, err, "more than one mask")
+}
+
+func parseTables(t *testing.T, sqls ...string) map[string]*tree.CreateTable {
+	t.Helper()
+	tables := make(map[string]*tree.CreateTable)
+	for _, sql := range sqls {
+		stmts, err := parser.Parse(sql)
+		require.NoError(t, err)
+		ct := stmts[0].AST.(*tree.CreateTable)
+		schemaName, tableName := tableNameOf(ct.Table)
+		tables[schemaName+"."+tableName] = ct
+	}
+	return tables
+}
+
+func TestResolveMasks(t *testing.T) {
+	t.Parallel()
+
+	tables := parseTables(t,
+		"CREATE TABLE users (id INT8 PRIMARY KEY, email STRING, name STRING)",
+		"CREATE TABLE posts (id INT8 PRIMARY KEY, author_email STRING, FOREIGN KEY (author_email) REFERENCES users (email))",
+		"CREATE TABLE comments (id INT8 PRIMARY KEY, post_author STRING REFERENCES posts (author_email))",
+	)
+
+	masks, err := resolveMasks(Masks{"public.users.email": MaskEmail}, tables)
+	require.NoError(t, err)
+
+	// The mask follows foreign keys through posts to comments
+	require.Contains(t, masks, "public.posts")
+	require.Contains(t, masks, "public.comments")
+	assert.Nil(t, masks["public.users"][0])
+	assert.Equal(t, MaskEmail, masks["public.users"][1].rule)
+	assert.Equal(t, MaskEmail, masks["public.posts"][1].rule)
+	assert.Equal(t, MaskEmail, masks["public.comments"][1].rule)
+}
+
+func TestResolveMasksErrors(t *testing.T) {
+	t.Parallel()
+
+	tables := parseTables(t,
+		"CREATE TABLE users (id INT8 PRIMARY KEY, email STRING UNIQUE, handle STRING, name STRING, UNIQUE INDEX (handle))",
+		"CREATE TABLE profiles (id INT4 PRIMARY KEY, born DATE, seen TIMESTAMP, balance DECIMAL, ip INET, prefs JSONB, avatar BYTES, code VARCHAR(8), contact VARCHAR(40), ref UUID, small INT2)",
+		"CREATE TABLE logins (id INT8 PRIMARY KEY, profile_id INT8 REFERENCES profiles (id))",
+	)
+
+	tests := []struct {
+		name    string
+		masks   Masks
+		wantErr string
+	}{
+		{name: "unknown column", masks: Masks{"public.users.phone": MaskNull}, wantErr: "unknown column phone"},
+		{name: "unknown table", masks: Masks{"public.accounts.email": MaskNull}, wantErr: "unknown table public.accounts"},
+		{name: "null primary key", masks: Masks{"public.users.id": MaskNull}, wantErr: "primary key columns can't be null"},
+		{name: "constant primary key", masks: Masks{"public.users.id": "value:1"}, wantErr: "primary key columns can't all be set to the same value"},
+		{name: "constant unique column", masks: Masks{"public.users.email": "value:a@example.com"}, wantErr: "public.users.email: unique columns can't all be set to the same value"},
+		{name: "constant unique index column", masks: Masks{"public.users.handle": "value:someone"}, wantErr: "unique columns can't all be set to the same value"},
+		{name: "constant on other columns", masks: Masks{"public.users.name": "value:Jane Doe"}},
+		{name: "hashed unique column", masks: Masks{"public.users.email": MaskHash}},
+		{name: "hashed date", masks: Masks{"public.profiles.born": MaskHash}, wantErr: "public.profiles.born: the hash mask can't produce DATE values"},
+		{name: "hashed timestamp", masks: Masks{"public.profiles.seen": MaskHash}, wantErr: "can't produce TIMESTAMP values"},
+		{name: "hashed decimal", masks: Masks{"public.profiles.balance": MaskHash}, wantErr: "can't produce DECIMAL values"},
+		{name: "hashed inet", masks: Masks{"public.profiles.ip": MaskHash}, wantErr: "can't produce INET values"},
+		{name: "hashed jsonb", masks: Masks{"public.profiles.prefs": MaskHash}, wantErr: "can't produce JSONB values"},
+		{name: "hashed bytes", masks: Masks{"public.profiles.avatar": MaskHash}, wantErr: "can't produce BYTES values"},
+		{name: "hashed short varchar", masks: Masks{"public.profiles.code": MaskHash}, wantErr: "the hash mask produces 32 characters, which don't fit in VARCHAR(8)"},
+		{name: "hashed long enough varchar", masks: Masks{"public.profiles.contact": MaskHash}},
+		{name: "hashed uuid", masks: Masks{"public.profiles.ref": MaskHash}},
+		{name: "hashed int2", masks: Masks{"public.profiles.small": MaskHash}},
+		{name: "email on uuid", masks: Masks{"public.profiles.ref": MaskEmail}, wantErr: "the email mask can't produce UUID values"},
+		{name: "email on short varchar", masks: Masks{"public.profiles.code": MaskEmail}, wantErr: "the email mask produces 33 characters, which don't fit in VARCHAR(8)"},
+		{name: "email on long enough varchar", masks: Masks{"public.profiles.contact": MaskEmail}},
+		{name: "hash carried to a wider integer", masks: Masks{"public.profiles.id": MaskHash}, wantErr: "public.logins.profile_id references it with a different integer width"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveMasks(tt.masks, tables)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMaskRows(t *testing.T) {
+	t.Parallel()
+
+	tables := parseTables(t, "CREATE TABLE users (id INT8 PRIMARY KEY, ref UUID, email STRING, ssn STRING, name STRING)")
+	masks, err := resolveMasks(Masks{
+		"public.users.id":    MaskHash,
+		"public.users.ref":   MaskHash,
+		"public.users.email": MaskEmail,
+		"public.users.ssn":   MaskNull,
+		"public.users.name":  "value:Jane Doe",
+	}, tables)
+	require.NoError(t, err)
+
+	str := func(s string) *string { return &s }
+	row := func() []*string {
+		return []*string{str("42"), str("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), str("alice@corp.com"), str("123-45-6789"), nil}
+	}
+	rows := [][]*string{row(), row()}
+	maskRows(rows, masks["public.users"], []byte("salt"))
+
+	// Masking is deterministic
+	assert.Equal(t, rows[0], rows[1])
+
+	masked := rows[0]
+	assert.Regexp(t, `^[0-9]+$`, *masked[0])
+	assert.NotEqual(t, "42", *masked[0])
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, *masked[1])
+	assert.Regexp(t, `^user_[0-9a-f]{16}@example\.com$`, *masked[2])
+	assert.Nil(t, masked[3])
+	// NULLs stay NULL
+	assert.Nil(t, masked[4])
+
+	// A different salt masks differently
+	other := [][]*string{row()}
+	maskRows(other, masks["public.users"], []byte("pepper"))
+	assert.NotEqual(t, *masked[2], *other[0][2])
+}
+
+func TestMaskIntegerWidths(t *testing.T) {
+	t.Parallel()
+
+	tables := parseTables(t, "CREATE TABLE counters (id INT8 PRIMARY KEY, small INT2, medium INT4, large INT8)")
+	masks, err := resolveMasks(Masks{
+		"public.counters.small":  MaskHash,
+		"public.counters.medium": MaskHash,
+		"public.counters.large":  MaskHash,
+	}, tables)
+	require.NoError(t, err)
+
+	for _, value := range []string{"1", "42", "-7", "123456789"} {
+		str := func(s string) *string { return &s }
+		rows := [][]*string{{str(value), str(value), str(value), str(value)}}
+		maskRows(rows, masks["public.counters"], []byte("salt"))
+
+		for i, max := range map[int]int64{1: math.MaxInt16, 2: math.MaxInt32, 3: math.MaxInt64} {
+			n, err := strconv.ParseInt(*rows[0][i], 10, 64)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, n, int64(0))
+			assert.LessOrEqual(t, n, max)
+		}
+	}
+}
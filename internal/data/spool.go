@@ -0,0 +1,215 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// dumpTablesConcurrently reads up to concurrency tables at once, spooling each
+// one to a temporary file, and replays the spools into enc in query order. It
+// returns the number of rows written.
+func dumpTablesConcurrently(ctx context.Context, q querier, enc Encoder, queries []tableQuery, batchSize, concurrency int) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	spools := make([]*tableSpool, len(queries))
+	errs := make([]error, len(queries))
+	done := make([]chan struct{}, len(queries))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(concurrency, len(queries)) {
+		wg.Go(func() {
+			for i := range jobs {
+				spools[i], errs[i] = spoolTable(ctx, q, queries[i], batchSize)
+				if errs[i] != nil {
+					cancel()
+				}
+				close(done[i])
+			}
+		})
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range queries {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Remove whatever spools weren't replayed, once the workers have stopped
+	defer func() {
+		cancel()
+		wg.Wait()
+		for _, s := range spools {
+			if s != nil {
+				s.remove()
+			}
+		}
+	}()
+
+	rows := 0
+	for i, tq := range queries {
+		select {
+		case <-done[i]:
+		case <-ctx.Done():
+			wg.Wait()
+			// Report the first table that failed on its own, rather than one
+			// canceled because of it
+			for j, err := range errs {
+				if err != nil && !errors.Is(err, context.Canceled) {
+					return 0, fmt.Errorf("failed to dump table %s: %w", queries[j].header.QualifiedName, err)
+				}
+			}
+			return 0, ctx.Err()
+		}
+		if errs[i] != nil {
+			return 0, fmt.Errorf("failed to dump table %s: %w", tq.header.QualifiedName, errs[i])
+		}
+
+		s := spools[i]
+		spools[i] = nil
+		err := s.replay(enc)
+		s.remove()
+		if err != nil {
+			return 0, fmt.Errorf("failed to write table %s: %w", tq.header.QualifiedName, err)
+		}
+		rows += s.rows
+	}
+	return rows, nil
+}
+
+// tableSpool is a table's dump held in a temporary file
+type tableSpool struct {
+	file *os.File
+	rows int
+}
+
+// spoolBatch is a batch of rows in a spool. Values are flattened row by row,
+// with Nulls marking the NULL ones, since gob can't encode nil pointers.
+type spoolBatch struct {
+	Values []string
+	Nulls  []bool
+}
+
+// spoolTable dumps a table to a temporary file
+func spoolTable(ctx context.Context, q querier, tq tableQuery, batchSize int) (*tableSpool, error) {
+	f, err := os.CreateTemp("", "scurry-dump-*.spool")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	s := &tableSpool{file: f}
+
+	bw := bufio.NewWriter(f)
+	enc := &spoolEncoder{enc: gob.NewEncoder(bw)}
+	s.rows, err = dumpTable(ctx, q, enc, tq, batchSize)
+	if err != nil {
+		s.remove()
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		s.remove()
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	return s, nil
+}
+
+// replay writes the spooled table to enc
+func (s *tableSpool) replay(enc Encoder) error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read temporary file: %w", err)
+	}
+	dec := gob.NewDecoder(bufio.NewReader(s.file))
+
+	var header TableHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("failed to read temporary file: %w", err)
+	}
+	if err := enc.BeginTable(header); err != nil {
+		return err
+	}
+
+	width := len(header.Columns)
+	for {
+		var batch spoolBatch
+		if err := dec.Decode(&batch); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read temporary file: %w", err)
+		}
+
+		rows := make([][]*string, 0, len(batch.Values)/max(width, 1))
+		for start := 0; start < len(batch.Values); start += width {
+			values := make([]*string, width)
+			for j := range values {
+				if !batch.Nulls[start+j] {
+					values[j] = &batch.Values[start+j]
+				}
+			}
+			rows = append(rows, values)
+		}
+		if err := enc.WriteRows(rows); err != nil {
+			return err
+		}
+	}
+	return enc.EndTable()
+}
+
+func (s *tableSpool) remove() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
+
+// spoolEncoder writes a single table's header and row batches with gob
+type spoolEncoder struct {
+	enc *gob.Encoder
+}
+
+func (e *spoolEncoder) WriteHeader(DumpHeader) error {
+	return fmt.Errorf("spool holds a single table")
+}
+
+func (e *spoolEncoder) BeginTable(t TableHeader) error {
+	return e.enc.Encode(t)
+}
+
+func (e *spoolEncoder) WriteRows(rows [][]*string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	var batch spoolBatch
+	for _, row := range rows {
+		for _, val := range row {
+			if val == nil {
+				batch.Values = append(batch.Values, "")
+				batch.Nulls = append(batch.Nulls, true)
+			} else {
+				batch.Values = append(batch.Values, *val)
+				batch.Nulls = append(batch.Nulls, false)
+			}
+		}
+	}
+	return e.enc.Encode(batch)
+}
+
+func (e *spoolEncoder) EndTable() error { return nil }
+
+func (e *spoolEncoder) WriteSequence(SequenceValue) error {
+	return fmt.Errorf("spool holds a single table")
+}
+
+func (e *spoolEncoder) Close() error { return nil }
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"bufio"
+	"encoding/gob"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpoolReplay(t *testing.T) {
+	t.Parallel()
+
+	header := TableHeader{
+		QualifiedName:  "public.categories",
+		Columns:        []string{"id", "name", "parent_id"},
+		PrimaryKey:     []string{"id"},
+		SelfRefColumns: []string{"parent_id"},
+		RowCount:       3,
+	}
+	batches := [][][]*string{
+		{{strPtr("1"), strPtr("Root"), nil}, {strPtr("2"), strPtr(""), strPtr("1")}},
+		{{strPtr("3"), nil, strPtr("2")}},
+	}
+
+	expected := &memoryEncoder{}
+	require.NoError(t, expected.BeginTable(header))
+	for _, batch := range batches {
+		require.NoError(t, expected.WriteRows(batch))
+	}
+	require.NoError(t, expected.EndTable())
+
+	f, err := os.CreateTemp(t.TempDir(), "spool")
+	require.NoError(t, err)
+	s := &tableSpool{file: f}
+	defer s.remove()
+
+	bw := bufio.NewWriter(f)
+	spool := &spoolEncoder{enc: gob.NewEncoder(bw)}
+	require.NoError(t, spool.BeginTable(header))
+	for _, batch := range batches {
+		require.NoError(t, spool.WriteRows(batch))
+	}
+	require.NoError(t, spool.WriteRows(nil))
+	require.NoError(t, spool.EndTable())
+	require.NoError(t, bw.Flush())
+
+	replayed := &memoryEncoder{}
+	require.NoError(t, s.replay(replayed))
+
+	// NULLs and empty strings survive the spool
+	assert.Equal(t, expected.file.TableData, replayed.file.TableData)
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/lib/pq"
+)
+
+// BackupPolicy controls the backups taken before statements that drop data
+type BackupPolicy struct {
+	// Location is the collection URI backups are written into, e.g.
+	// s3://bucket/scurry?AUTH=implicit. No backups are taken without it.
+	Location string
+	// FullCluster backs up the whole cluster instead of the affected tables
+	FullCluster bool
+	// OnBackup, if set, is called with each backup ExecuteMigrationWithTracking takes
+	OnBackup func(migration string, backup Backup)
+}
+
+// Backup is a backup taken before statements that drop data
+type Backup struct {
+	// Path is where the backup was written, without the location's
+	// credentials, e.g. s3://bucket/scurry/2026/10/16-120000.00
+	Path string
+	// Subdir is the backup in its collection, for RESTORE ... FROM <subdir> IN <location>
+	Subdir string
+	// Tables are the tables backed up; none means the current database
+	Tables []string
+	// Cluster is set when the whole cluster was backed up
+	Cluster bool
+}
+
+// SetBackupPolicy sets the backups ExecuteMigrationWithTracking takes before
+// migrations that drop data
+func (c *Client) SetBackupPolicy(policy BackupPolicy) {
+	c.backupPolicy = policy
+}
+
+// BackupEnabled reports whether the backup policy has a location to back up to
+func (c *Client) BackupEnabled() bool {
+	return c.backupPolicy.Location != ""
+}
+
+// BackupTables returns the tables stmts drop, drop columns from or rewrite,
+// in the order they appear, and whether any statement drops data at all.
+// Statements that drop data outside of a table, like an enum value, report
+// no table for it.
+func BackupTables(stmts []string) ([]string, bool) {
+	var tables []string
+	destructive := false
+	add := func(name string) {
+		destructive = true
+		if !slices.Contains(tables, name) {
+			tables = append(tables, name)
+		}
+	}
+	for _, stmt := range stmts {
+		parsed, err := parser.ParseOne(stmt)
+		if err != nil {
+			continue
+		}
+		switch s := parsed.AST.(type) {
+		case *tree.DropTable:
+			for _, name := range s.Names {
+				add(name.String())
+			}
+		case *tree.Truncate:
+			for _, name := range s.Tables {
+				add(name.String())
+			}
+		case *tree.AlterTable:
+			for _, cmd := range s.Cmds {
+				switch cmd.(type) {
+				case *tree.AlterTableDropColumn, *tree.AlterTableAlterColumnType:
+					add(s.Table.String())
+				}
+			}
+		case *tree.AlterType:
+			if _, ok := s.Cmd.(*tree.AlterTypeDropValue); ok {
+				destructive = true
+			}
+		case *tree.DropType:
+			destructive = true
+		}
+	}
+	return tables, destructive
+}
+
+// TakeBackup backs up tables into the backup policy's location, or the current
+// database if there are none, or the whole cluster if the policy says so
+func (c *Client) TakeBackup(ctx context.Context, tables []string) (*Backup, error) {
+	location := c.backupPolicy.Location
+	if location == "" {
+		return nil, fmt.Errorf("no backup location is configured")
+	}
+
+	var target string
+	switch {
+	case c.backupPolicy.FullCluster:
+		tables = nil
+	case len(tables) > 0:
+		target = "TABLE " + strings.Join(tables, ", ") + " "
+	default:
+		var database string
+		if err := c.db.QueryRowContext(ctx, "SELECT current_database()").Scan(&database); err != nil {
+			return nil, fmt.Errorf("failed to get the current database: %w", err)
+		}
+		target = "DATABASE " + pq.QuoteIdentifier(database) + " "
+	}
+	if _, err := c.db.ExecContext(ctx, "BACKUP "+target+"INTO "+pq.QuoteLiteral(location)); err != nil {
+		return nil, fmt.Errorf("failed to back up to %s: %w", redactLocation(location), err)
+	}
+
+	subdir, err := c.latestBackup(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	return &Backup{Path: backupPath(location, subdir), Subdir: subdir, Tables: tables, Cluster: c.backupPolicy.FullCluster}, nil
+}
+
+// latestBackup returns the subdirectory of the newest backup in location
+func (c *Client) latestBackup(ctx context.Context, location string) (string, error) {
+	rows, err := c.db.QueryContext(ctx, "SHOW BACKUPS IN "+pq.QuoteLiteral(location))
+	if err != nil {
+		return "", fmt.Errorf("failed to list the backups in %s: %w", redactLocation(location), err)
+	}
+	defer rows.Close()
+
+	var latest string
+	for rows.Next() {
+		var subdir string
+		if err := rows.Scan(&subdir); err != nil {
+			return "", fmt.Errorf("failed to scan backup: %w", err)
+		}
+		// Subdirectories are named after the time the backup was taken
+		latest = max(latest, subdir)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no backup found in %s", redactLocation(location))
+	}
+	return latest, nil
+}
+
+// RecordMigrationBackup records where the backup taken before a migration was written
+func (c *Client) RecordMigrationBackup(ctx context.Context, name, path string) error {
+	_, err := c.db.ExecContext(ctx, `
+		UPDATE _scurry_.migrations SET backup_path = $2 WHERE name = $1
+	`, name, path)
+	if err != nil {
+		return fmt.Errorf("failed to record the backup of migration %s: %w", name, err)
+	}
+	return nil
+}
+
+// backupPath joins a backup's subdirectory to its location, leaving out the
+// location's credentials so the path can be stored and printed
+func backupPath(location, subdir string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return strings.TrimSuffix(redactLocation(location), "/") + "/" + strings.TrimPrefix(subdir, "/")
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Path = path.Join("/", u.Path, subdir)
+	return u.String()
+}
+
+// redactLocation leaves the query string, which holds credentials like
+// AWS_SECRET_ACCESS_KEY, out of a backup location
+func redactLocation(location string) string {
+	base, _, _ := strings.Cut(location, "?")
+	return base
+}
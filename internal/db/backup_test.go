@@ -0,0 +1,76 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupTables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		stmts           []string
+		wantTables      []string
+		wantDestructive bool
+	}{
+		{
+			name:  "nothing dropped",
+			stmts: []string{"CREATE TABLE a (id INT PRIMARY KEY)", "ALTER TABLE a ADD COLUMN b INT", "CREATE INDEX ON a (b)"},
+		},
+		{
+			name:            "dropped table",
+			stmts:           []string{"BEGIN", "DROP TABLE public.a", "COMMIT"},
+			wantTables:      []string{"public.a"},
+			wantDestructive: true,
+		},
+		{
+			name:            "dropped column and changed type, once per table",
+			stmts:           []string{"ALTER TABLE public.a DROP COLUMN b", "ALTER TABLE public.a ALTER COLUMN c TYPE STRING", "ALTER TABLE public.b DROP COLUMN d"},
+			wantTables:      []string{"public.a", "public.b"},
+			wantDestructive: true,
+		},
+		{
+			name:            "truncate",
+			stmts:           []string{"TRUNCATE public.a, public.b"},
+			wantTables:      []string{"public.a", "public.b"},
+			wantDestructive: true,
+		},
+		{
+			name:            "dropped enum value has no table",
+			stmts:           []string{"ALTER TYPE public.status DROP VALUE 'old'"},
+			wantDestructive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tables, destructive := BackupTables(tt.stmts)
+			assert.Equal(t, tt.wantTables, tables)
+			assert.Equal(t, tt.wantDestructive, destructive)
+		})
+	}
+}
+
+func TestBackupPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		location string
+		subdir   string
+		want     string
+	}{
+		{"s3://bucket/scurry?AUTH=implicit", "/2026/10/16-120000.00", "s3://bucket/scurry/2026/10/16-120000.00"},
+		{"s3://bucket?AWS_ACCESS_KEY_ID=key&AWS_SECRET_ACCESS_KEY=secret", "/2026/10/16-120000.00", "s3://bucket/2026/10/16-120000.00"},
+		{"nodelocal://1/backups/", "/2026/10/16-120000.00", "nodelocal://1/backups/2026/10/16-120000.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, backupPath(tt.location, tt.subdir))
+		})
+	}
+}
@@ -23,6 +23,31 @@ type Client struct {
 	// transaction. Callers that need production-like behavior (e.g.
 	// migration generation) can set this to false.
 	disableAutocommitDDL bool
+
+	// statementTimeout is applied to connections pinned by ExecuteDDLWithProgress
+	statementTimeout time.Duration
+
+	// retryPolicy controls retries of transient errors in ExecuteMigrationWithTracking
+	retryPolicy RetryPolicy
+
+	// readOnly is set when the connection was opened with
+	// default_transaction_read_only, so scurry doesn't try to write to it
+	readOnly bool
+
+	// expectedFingerprint is the schema the next ExecuteDDLWithProgress
+	// expects to apply its statements to
+	expectedFingerprint string
+
+	// statementLogReady is set once the statement log table is known to exist
+	statementLogReady bool
+
+	// backupPolicy controls the backups ExecuteMigrationWithTracking takes
+	// before migrations that drop data
+	backupPolicy BackupPolicy
+
+	// onJobProgress is called with the progress of IMPORT jobs while their
+	// statements run, if set
+	onJobProgress func(SchemaChangeJob)
 }
 
 // SetDisableAutocommitDDL controls whether ExecuteBulkDDL disables
@@ -31,18 +56,45 @@ func (c *Client) SetDisableAutocommitDDL(disable bool) {
 	c.disableAutocommitDDL = disable
 }
 
+// ConnectOptions are connection parameters Connect applies on top of the
+// connection URL, so they don't have to be written into it by hand
+type ConnectOptions struct {
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+	// ApplicationName defaults to scurry when neither it nor the URL sets one
+	ApplicationName string
+	// Cluster is the routing ID of a serverless cluster, sent as --cluster
+	Cluster string
+	// SessionSettings are set on every connection
+	SessionSettings []SessionSetting
+	// ReadOnly sets default_transaction_read_only, so the database refuses
+	// any statement that would change it
+	ReadOnly bool
+}
+
+// ConnectionOptions are applied to every connection Connect opens
+var ConnectionOptions ConnectOptions
+
 // Connect establishes a connection to the CockroachDB database
 func Connect(ctx context.Context, dbURL string) (*Client, error) {
-	parsedUrl, err := url.Parse(dbURL)
+	return connect(ctx, dbURL, ConnectionOptions)
+}
+
+// connect opens a connection with options applied to dbURL. Unless the
+// connection is read-only, it creates the URL's database if it doesn't exist.
+func connect(ctx context.Context, dbURL string, options ConnectOptions) (*Client, error) {
+	connectURL, err := options.Apply(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	parsedUrl, err := url.Parse(connectURL)
 	if err != nil {
 		return nil, err
 	}
 
-	queryParams := parsedUrl.Query()
-	queryParams.Add("application_name", "scurry")
-	parsedUrl.RawQuery = queryParams.Encode()
-
-	db, err := sql.Open("postgres", parsedUrl.String())
+	db, err := sql.Open("postgres", connectURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -53,14 +105,84 @@ func Connect(ctx context.Context, dbURL string) (*Client, error) {
 	}
 
 	dbName := strings.TrimLeft(parsedUrl.Path, "/")
-	if dbName != "" {
+	if dbName != "" && !options.ReadOnly {
 		_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", pq.QuoteIdentifier(dbName)))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create database: %w", err)
 		}
 	}
 
-	return &Client{db: db, url: dbURL}, nil
+	return &Client{db: db, url: dbURL, retryPolicy: DefaultRetryPolicy, readOnly: options.ReadOnly}, nil
+}
+
+// IsReadOnly reports whether the connection was opened read-only
+func (c *Client) IsReadOnly() bool {
+	return c.readOnly
+}
+
+// Apply returns dbURL with the options set in its query parameters. Options
+// override the URL's parameters; the cluster and session settings are added
+// to its options parameter.
+func (o ConnectOptions) Apply(dbURL string) (string, error) {
+	parsedUrl, err := url.Parse(dbURL)
+	if err != nil {
+		return "", err
+	}
+
+	queryParams := parsedUrl.Query()
+	for name, value := range map[string]string{
+		"sslmode":          o.SSLMode,
+		"sslrootcert":      o.SSLRootCert,
+		"sslcert":          o.SSLCert,
+		"sslkey":           o.SSLKey,
+		"application_name": o.ApplicationName,
+	} {
+		if value != "" {
+			queryParams.Set(name, value)
+		}
+	}
+	if queryParams.Get("application_name") == "" {
+		queryParams.Set("application_name", "scurry")
+	}
+
+	var options []string
+	if existing := queryParams.Get("options"); existing != "" {
+		options = append(options, existing)
+	}
+	if o.Cluster != "" {
+		options = append(options, "--cluster="+o.Cluster)
+	}
+	if o.ReadOnly {
+		options = append(options, "-c default_transaction_read_only=on")
+	}
+	for _, setting := range o.SessionSettings {
+		if !IsSessionVariableName(setting.Name) {
+			return "", fmt.Errorf("invalid session setting name: %q", setting.Name)
+		}
+		options = append(options, "-c "+setting.Name+"="+escapeOptionValue(setting.Value))
+	}
+	if len(options) > 0 {
+		queryParams.Set("options", strings.Join(options, " "))
+	}
+
+	parsedUrl.RawQuery = queryParams.Encode()
+	return parsedUrl.String(), nil
+}
+
+// escapeOptionValue escapes the spaces and backslashes in a value of the
+// options connection parameter, which separates arguments with spaces
+func escapeOptionValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, " ", `\ `).Replace(value)
+}
+
+// WithDatabase returns the connection URL with its database replaced by name
+func WithDatabase(dbURL, name string) (string, error) {
+	parsedUrl, err := url.Parse(dbURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	parsedUrl.Path = "/" + name
+	return parsedUrl.String(), nil
 }
 
 func (c *Client) ConnectionString() string {
@@ -77,11 +199,15 @@ func (c *Client) Close() error {
 
 // SetStatementTimeout sets the session-level statement timeout.
 func (c *Client) SetStatementTimeout(ctx context.Context, d time.Duration) error {
-	ms := int64(d / time.Millisecond)
-	_, err := c.db.ExecContext(ctx, fmt.Sprintf("SET statement_timeout = '%dms'", ms))
+	c.statementTimeout = d
+	_, err := c.db.ExecContext(ctx, statementTimeoutSQL(d))
 	return err
 }
 
+func statementTimeoutSQL(d time.Duration) string {
+	return fmt.Sprintf("SET statement_timeout = '%dms'", int64(d/time.Millisecond))
+}
+
 // GetDB returns the underlying database connection
 func (c *Client) GetDB() *sql.DB {
 	return c.db
@@ -102,6 +228,27 @@ func (c *Client) GetCurrentDatabase(ctx context.Context) (string, error) {
 	return dbName, nil
 }
 
+// GetServerVersion returns the cluster's version string, e.g.
+// "CockroachDB CCL v24.1.0 (x86_64-pc-linux-gnu, ...)"
+func (c *Client) GetServerVersion(ctx context.Context) (string, error) {
+	var version string
+	err := c.db.QueryRowContext(ctx, "SELECT version()").Scan(&version)
+	if err != nil {
+		return "", fmt.Errorf("failed to get server version: %w", err)
+	}
+	return version, nil
+}
+
+// GetClusterID returns the ID of the cluster the client is connected to
+func (c *Client) GetClusterID(ctx context.Context) (string, error) {
+	var clusterID string
+	err := c.db.QueryRowContext(ctx, "SELECT crdb_internal.cluster_id()::STRING").Scan(&clusterID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster ID: %w", err)
+	}
+	return clusterID, nil
+}
+
 // DropCurrentDatabase drops the currently connected database.
 // This connects to the defaultdb first, then drops the target database.
 func (c *Client) DropCurrentDatabase(ctx context.Context) error {
@@ -38,3 +38,138 @@ func TestDropCurrentDatabase(t *testing.T) {
 	err = client.DropCurrentDatabase(ctx)
 	require.NoError(t, err)
 }
+
+func TestWithDatabase(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		database string
+		expected string
+	}{
+		{
+			name:     "replaces database",
+			url:      "postgresql://root@localhost:26257/defaultdb?sslmode=disable",
+			database: "orders",
+			expected: "postgresql://root@localhost:26257/orders?sslmode=disable",
+		},
+		{
+			name:     "adds database",
+			url:      "postgresql://root@localhost:26257?sslmode=disable",
+			database: "orders",
+			expected: "postgresql://root@localhost:26257/orders?sslmode=disable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := WithDatabase(tt.url, tt.database)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestShadowConnectOptions(t *testing.T) {
+	// The target database's options must not reach the shadow server
+	defer func(options ConnectOptions, settings map[string]string) {
+		ConnectionOptions, ShadowSettings = options, settings
+	}(ConnectionOptions, ShadowSettings)
+	ConnectionOptions = ConnectOptions{
+		SSLMode:         "verify-full",
+		SSLRootCert:     "/certs/ca.crt",
+		Cluster:         "prod-cluster",
+		ReadOnly:        true,
+		SessionSettings: []SessionSetting{{Name: "statement_timeout", Value: "5s"}},
+	}
+
+	tests := []struct {
+		name     string
+		settings map[string]string
+		expected string
+	}{
+		{
+			name:     "no shadow settings",
+			expected: "postgresql://root@localhost:26257/_shadow_1?application_name=scurry&options=-c+experimental_enable_unique_without_index_constraints%3Dtrue&sslmode=disable",
+		},
+		{
+			name:     "shadow settings",
+			settings: map[string]string{"serial_normalization": "sql_sequence", "default_int_size": "4"},
+			expected: "postgresql://root@localhost:26257/_shadow_1?application_name=scurry&options=-c+default_int_size%3D4+-c+experimental_enable_unique_without_index_constraints%3Dtrue+-c+serial_normalization%3Dsql_sequence&sslmode=disable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ShadowSettings = tt.settings
+			actual, err := shadowConnectOptions().Apply("postgresql://root@localhost:26257/_shadow_1?sslmode=disable")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestConnectOptionsApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  ConnectOptions
+		url      string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "defaults the application name",
+			url:      "postgresql://root@localhost:26257/app?sslmode=disable",
+			expected: "postgresql://root@localhost:26257/app?application_name=scurry&sslmode=disable",
+		},
+		{
+			name:     "keeps the URL's application name",
+			url:      "postgresql://root@localhost:26257/app?application_name=deploy",
+			expected: "postgresql://root@localhost:26257/app?application_name=deploy",
+		},
+		{
+			name: "TLS",
+			options: ConnectOptions{
+				SSLMode:     "verify-full",
+				SSLRootCert: "certs/ca.crt",
+				SSLCert:     "certs/client.root.crt",
+				SSLKey:      "certs/client.root.key",
+			},
+			url:      "postgresql://root@localhost:26257/app?sslmode=disable",
+			expected: "postgresql://root@localhost:26257/app?application_name=scurry&sslcert=certs%2Fclient.root.crt&sslkey=certs%2Fclient.root.key&sslmode=verify-full&sslrootcert=certs%2Fca.crt",
+		},
+		{
+			name: "cluster and session settings",
+			options: ConnectOptions{
+				ApplicationName: "release",
+				Cluster:         "blue-cat-123",
+				SessionSettings: []SessionSetting{{Name: "statement_timeout", Value: "5min"}, {Name: "search_path", Value: "app, public"}},
+			},
+			url:      "postgresql://root@free-tier.example.com:26257/app?options=-c%20timezone%3DUTC",
+			expected: "postgresql://root@free-tier.example.com:26257/app?application_name=release&options=-c+timezone%3DUTC+--cluster%3Dblue-cat-123+-c+statement_timeout%3D5min+-c+search_path%3Dapp%2C%5C+public",
+		},
+		{
+			name:     "read-only",
+			options:  ConnectOptions{ReadOnly: true},
+			url:      "postgresql://root@localhost:26257/app",
+			expected: "postgresql://root@localhost:26257/app?application_name=scurry&options=-c+default_transaction_read_only%3Don",
+		},
+		{
+			name:    "invalid session setting",
+			options: ConnectOptions{SessionSettings: []SessionSetting{{Name: "x; DROP TABLE users", Value: "1"}}},
+			url:     "postgresql://root@localhost:26257/app",
+			wantErr: "invalid session setting name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.options.Apply(tt.url)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
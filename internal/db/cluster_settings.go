@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetClusterSettings reads the current value of every cluster setting, by name.
+// It needs the admin role or the VIEWCLUSTERSETTING privilege.
+func (c *Client) GetClusterSettings(ctx context.Context) (map[string]string, error) {
+	rows, err := c.db.QueryContext(ctx, "SELECT variable, value FROM [SHOW ALL CLUSTER SETTINGS]")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan cluster setting: %w", err)
+		}
+		settings[name] = value
+	}
+	return settings, rows.Err()
+}
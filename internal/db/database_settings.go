@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach-go/v2/crdb"
+	"github.com/lib/pq"
+)
+
+// DatabaseSettings are the settings of the current database itself
+type DatabaseSettings struct {
+	Name string
+	// Settings are the session variable defaults set with ALTER DATABASE ... SET
+	Settings map[string]string
+	// PrimaryRegion is empty unless the database is multi-region
+	PrimaryRegion string
+	// Regions include the primary region
+	Regions []string
+	// SurvivalGoal is "zone" or "region"
+	SurvivalGoal string
+	// Placement is "default" or "restricted"
+	Placement string
+}
+
+// GetDatabaseSettings reads the session variable defaults and the multi-region
+// configuration of the current database
+func (c *Client) GetDatabaseSettings(ctx context.Context) (*DatabaseSettings, error) {
+	setUnsafeInternals, err := c.needsUnsafeInternals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings *DatabaseSettings
+	err = crdb.ExecuteTx(ctx, c.db, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+		if setUnsafeInternals {
+			if _, err := tx.ExecContext(ctx, "SET LOCAL allow_unsafe_internals = 'on';"); err != nil {
+				return fmt.Errorf("failed to set allow_unsafe_internals: %w", err)
+			}
+		}
+
+		settings = &DatabaseSettings{Settings: make(map[string]string)}
+		err := tx.QueryRowContext(ctx, `
+			SELECT name, COALESCE(primary_region, ''), COALESCE(regions, ARRAY[]::STRING[]),
+				COALESCE(survival_goal, ''), COALESCE(placement_policy, '')
+			FROM crdb_internal.databases
+			WHERE name = current_database()
+		`).Scan(&settings.Name, &settings.PrimaryRegion, pq.Array(&settings.Regions), &settings.SurvivalGoal, &settings.Placement)
+		if err != nil {
+			return fmt.Errorf("failed to read the database's regions: %w", err)
+		}
+		settings.SurvivalGoal = strings.ToLower(settings.SurvivalGoal)
+		settings.Placement = strings.ToLower(settings.Placement)
+
+		// Defaults for every role are stored with role 0, as "name=value"
+		rows, err := tx.QueryContext(ctx, `
+			SELECT unnest(setconfig)
+			FROM pg_catalog.pg_db_role_setting
+			WHERE setrole = 0
+				AND setdatabase = (SELECT oid FROM pg_catalog.pg_database WHERE datname = current_database())
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to read the database's settings: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var setting string
+			if err := rows.Scan(&setting); err != nil {
+				return fmt.Errorf("failed to scan database setting: %w", err)
+			}
+			name, value, _ := strings.Cut(setting, "=")
+			settings.Settings[strings.ToLower(name)] = value
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
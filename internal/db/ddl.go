@@ -2,98 +2,162 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach-go/v2/crdb"
+	"github.com/lib/pq"
 )
 
+// createStatementsQuery returns the CREATE statements of every object outside
+// the _scurry_ schema
+const createStatementsQuery = `
+	WITH create_schema_statements AS (
+		SELECT schema_name, create_statement
+		FROM crdb_internal.create_schema_statements
+		WHERE schema_name != '_scurry_'
+	),
+	create_statements AS (
+		SELECT create_statement
+		FROM crdb_internal.create_statements
+		WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
+	),
+	create_type_statements AS (
+		SELECT create_statement
+		FROM crdb_internal.create_type_statements
+		WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
+	),
+	create_function_statements AS (
+		SELECT create_statement
+		FROM crdb_internal.create_function_statements
+		WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
+	),
+	create_procedure_statements AS (
+		SELECT create_statement
+		FROM crdb_internal.create_procedure_statements
+		WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
+	),
+	create_trigger_statements AS (
+		SELECT create_statement
+		FROM crdb_internal.create_trigger_statements
+		WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
+	)
+	SELECT create_statement FROM create_statements
+
+	UNION ALL
+
+	SELECT create_statement FROM create_type_statements
+
+	UNION ALL
+
+	SELECT create_statement FROM create_function_statements
+
+	UNION ALL
+
+	SELECT create_statement FROM create_procedure_statements
+
+	UNION ALL
+
+	SELECT create_statement FROM create_trigger_statements
+
+	UNION ALL
+
+	SELECT create_statement FROM create_schema_statements
+`
+
 func (c *Client) GetAllCreateStatements(ctx context.Context) ([]string, error) {
-	var statements []string
-	row := c.db.QueryRowContext(ctx, "SHOW allow_unsafe_internals;")
+	return c.GetAllCreateStatementsAsOf(ctx, "")
+}
 
-	var allowUnsafeInternals string
-	err := row.Scan(&allowUnsafeInternals)
-	allowUnsafeInternalsSupported := true
+// GetAllCreateStatementsAsOf is GetAllCreateStatements for the schema as it was
+// at asOf, an AS OF SYSTEM TIME expression like '-1h' or
+// '2026-10-16 09:00:00+00', or as it is now if asOf is empty. The time has to
+// be within the garbage collection window of the database.
+func (c *Client) GetAllCreateStatementsAsOf(ctx context.Context, asOf string) ([]string, error) {
+	setUnsafeInternals, err := c.needsUnsafeInternals(ctx)
 	if err != nil {
-		if strings.Contains(err.Error(), "unrecognized configuration parameter \"allow_unsafe_internals\"") {
-			allowUnsafeInternalsSupported = false
-		} else {
-			return nil, err
-		}
+		return nil, err
 	}
 
-	shouldSetUnsafeInternals := allowUnsafeInternalsSupported && allowUnsafeInternals == "off"
-
+	var statements []string
 	err = crdb.ExecuteTx(ctx, c.db, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
-		var err error
-		if shouldSetUnsafeInternals {
-			_, err := tx.ExecContext(ctx, "SET LOCAL allow_unsafe_internals = 'on';")
-			if err != nil {
-				return fmt.Errorf("failed to set allow_unsafe_internals: %w", err)
+		if asOf != "" {
+			if _, err := tx.ExecContext(ctx, "SET TRANSACTION AS OF SYSTEM TIME "+pq.QuoteLiteral(asOf)); err != nil {
+				return fmt.Errorf("failed to read the schema as of %s: %w", asOf, err)
 			}
 		}
-		statements, err = queryAndScanCreateStatements(tx, `
-			WITH create_schema_statements AS (
-				SELECT schema_name, create_statement
-				FROM crdb_internal.create_schema_statements
-				WHERE schema_name != '_scurry_'
-			),
-			create_statements AS (
-				SELECT create_statement
-				FROM crdb_internal.create_statements
-				WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
-			),
-			create_type_statements AS (
-				SELECT create_statement
-				FROM crdb_internal.create_type_statements
-				WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
-			),
-			create_function_statements AS (
-				SELECT create_statement
-				FROM crdb_internal.create_function_statements
-				WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
-			),
-			create_procedure_statements AS (
-				SELECT create_statement
-				FROM crdb_internal.create_procedure_statements
-				WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
-			),
-			create_trigger_statements AS (
-				SELECT create_statement
-				FROM crdb_internal.create_trigger_statements
-				WHERE schema_name IN (SELECT schema_name FROM create_schema_statements)
-			)
-			SELECT create_statement FROM create_statements
-
-			UNION ALL
-
-			SELECT create_statement FROM create_type_statements
-
-			UNION ALL
-
-			SELECT create_statement FROM create_function_statements
-
-			UNION ALL
-
-			SELECT create_statement FROM create_procedure_statements
-
-			UNION ALL
-
-			SELECT create_statement FROM create_trigger_statements
-
-			UNION ALL
-
-			SELECT create_statement FROM create_schema_statements
-		`)
+		var err error
+		statements, err = createStatementsInTx(ctx, tx, setUnsafeInternals)
 		return err
 	})
 
 	return statements, err
 }
 
+// needsUnsafeInternals reports whether allow_unsafe_internals has to be turned
+// on to read crdb_internal, on versions that have the setting
+func (c *Client) needsUnsafeInternals(ctx context.Context) (bool, error) {
+	var allowUnsafeInternals string
+	err := c.db.QueryRowContext(ctx, "SHOW allow_unsafe_internals;").Scan(&allowUnsafeInternals)
+	if err != nil {
+		if strings.Contains(err.Error(), "unrecognized configuration parameter \"allow_unsafe_internals\"") {
+			return false, nil
+		}
+		return false, err
+	}
+	return allowUnsafeInternals == "off", nil
+}
+
+// createStatementsInTx reads the create statements within tx
+func createStatementsInTx(ctx context.Context, tx *sql.Tx, setUnsafeInternals bool) ([]string, error) {
+	if setUnsafeInternals {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL allow_unsafe_internals = 'on';"); err != nil {
+			return nil, fmt.Errorf("failed to set allow_unsafe_internals: %w", err)
+		}
+	}
+	return queryAndScanCreateStatements(tx, createStatementsQuery)
+}
+
+// SchemaFingerprint hashes a database's create statements, in any order, so
+// two reads of the schema can be compared
+func SchemaFingerprint(statements []string) string {
+	sorted := slices.Clone(statements)
+	slices.Sort(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrSchemaChanged is returned by ExecuteDDLWithProgress when the schema no
+// longer matches the fingerprint set with SetExpectedSchemaFingerprint
+var ErrSchemaChanged = errors.New("the database schema changed since it was compared with the definitions; re-run to compare it again")
+
+// SetExpectedSchemaFingerprint makes the next ExecuteDDLWithProgress check that
+// the schema still has fingerprint before applying anything, within the
+// transaction of its first statements. The check is made once; pass "" to
+// skip it.
+func (c *Client) SetExpectedSchemaFingerprint(fingerprint string) {
+	c.expectedFingerprint = fingerprint
+}
+
+// checkSchemaFingerprint returns ErrSchemaChanged if the schema read within tx
+// doesn't have the expected fingerprint
+func checkSchemaFingerprint(ctx context.Context, tx *sql.Tx, expected string, setUnsafeInternals bool) error {
+	statements, err := createStatementsInTx(ctx, tx, setUnsafeInternals)
+	if err != nil {
+		return fmt.Errorf("failed to re-read the database schema: %w", err)
+	}
+	if SchemaFingerprint(statements) != expected {
+		return ErrSchemaChanged
+	}
+	return nil
+}
+
 func queryAndScanCreateStatements(
 	tx *sql.Tx,
 	query string,
@@ -178,6 +242,189 @@ func (c *Client) ExecuteBulkDDL(ctx context.Context, statements ...string) error
 	return nil
 }
 
+// StatementProgress describes a statement applied by ExecuteDDLWithProgress
+type StatementProgress struct {
+	Statement    string
+	Index        int // 1-based position among the statements being applied
+	Total        int
+	StartedAt    time.Time
+	Duration     time.Duration
+	RowsAffected int64
+	Attempt      int // Greater than 1 when the statement ran again after a serialization failure
+}
+
+// ExecuteDDLWithProgress executes statements like ExecuteBulkDDL, but one at a
+// time, calling onStatement for each one that's been applied. Statements in a
+// transaction are reported once it commits. Transactions that fail with a
+// serialization error (40001) are retried, and so are statements that run
+// outside of a transaction. Everything runs on one connection, so the session's
+// statement timeout applies to each statement. Applied statements are recorded
+// in the statement log as pushed.
+func (c *Client) ExecuteDDLWithProgress(ctx context.Context, onStatement func(StatementProgress), statements ...string) error {
+	chunks := chunkStatementsByTransaction(statements, 50)
+	total := 0
+	for _, chunk := range chunks {
+		total += len(chunk)
+	}
+
+	if !c.isShadow {
+		if err := c.initStatementLog(ctx); err != nil {
+			return err
+		}
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if c.statementTimeout > 0 {
+		if _, err := conn.ExecContext(ctx, statementTimeoutSQL(c.statementTimeout)); err != nil {
+			return fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+
+	// The expected schema is checked in the first transaction, or on its own
+	// right before the first statement when that runs outside of one
+	expectedFingerprint := c.expectedFingerprint
+	c.expectedFingerprint = ""
+	var setUnsafeInternals bool
+	if expectedFingerprint != "" {
+		if setUnsafeInternals, err = c.needsUnsafeInternals(ctx); err != nil {
+			return err
+		}
+	}
+
+	execute := func(execer interface {
+		ExecContext(context.Context, string, ...any) (sql.Result, error)
+	}, stmt string, index, attempt int) (StatementProgress, error) {
+		start := time.Now()
+		result, err := execer.ExecContext(ctx, stmt)
+		if err != nil {
+			return StatementProgress{}, err
+		}
+		return StatementProgress{
+			Statement:    stmt,
+			Index:        index,
+			Total:        total,
+			StartedAt:    start,
+			Duration:     time.Since(start),
+			RowsAffected: rowsAffected(result),
+			Attempt:      attempt,
+		}, nil
+	}
+	report := func(progress ...StatementProgress) {
+		for _, p := range progress {
+			c.logStatement(ctx, StatementLogEntry{
+				ExecutedAt:   p.StartedAt,
+				Statement:    p.Statement,
+				Source:       StatementSourcePush,
+				DurationMs:   p.Duration.Milliseconds(),
+				RowsAffected: p.RowsAffected,
+			})
+			if onStatement != nil {
+				onStatement(p)
+			}
+		}
+	}
+
+	done := 0
+	for i := 0; i < len(chunks); i++ {
+		chunk := chunks[i]
+
+		// nil chunk signals the next chunk should run without a transaction
+		if chunk == nil {
+			i++
+			if i >= len(chunks) {
+				break
+			}
+			if expectedFingerprint != "" && len(chunks[i]) > 0 {
+				if err := crdb.ExecuteTx(ctx, c.db, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+					return checkSchemaFingerprint(ctx, tx, expectedFingerprint, setUnsafeInternals)
+				}); err != nil {
+					return err
+				}
+				expectedFingerprint = ""
+			}
+			for _, stmt := range chunks[i] {
+				done++
+				attempt := 0
+				var progress StatementProgress
+				if err := crdb.ExecuteCtx(ctx, func(ctx context.Context, _ ...any) error {
+					attempt++
+					var err error
+					progress, err = execute(conn, stmt, done, attempt)
+					return err
+				}); err != nil {
+					return err
+				}
+				report(progress)
+			}
+			continue
+		}
+
+		if len(chunk) == 0 {
+			continue
+		}
+		tx, err := conn.BeginTx(ctx, &sql.TxOptions{})
+		if err != nil {
+			return err
+		}
+		// Progress is only reported once the transaction commits, since a
+		// retry runs every statement in the chunk again
+		attempt := 0
+		var progress []StatementProgress
+		if err := crdb.ExecuteInTx(ctx, sqlTx{tx}, func() error {
+			attempt++
+			progress = progress[:0]
+			if c.disableAutocommitDDL {
+				if _, err := tx.ExecContext(ctx, "SET LOCAL autocommit_before_ddl = false"); err != nil {
+					return fmt.Errorf("failed to set autocommit_before_ddl: %w", err)
+				}
+			}
+			if expectedFingerprint != "" {
+				if err := checkSchemaFingerprint(ctx, tx, expectedFingerprint, setUnsafeInternals); err != nil {
+					return err
+				}
+			}
+			for j, stmt := range chunk {
+				p, err := execute(tx, stmt, done+j+1, attempt)
+				if err != nil {
+					return err
+				}
+				progress = append(progress, p)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		report(progress...)
+		done += len(chunk)
+		expectedFingerprint = ""
+	}
+
+	return nil
+}
+
+// sqlTx adapts a *sql.Tx to crdb.Tx, so transactions on a single connection can be retried
+type sqlTx struct {
+	tx *sql.Tx
+}
+
+func (t sqlTx) Exec(ctx context.Context, query string, args ...any) error {
+	_, err := t.tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (t sqlTx) Commit(context.Context) error {
+	return t.tx.Commit()
+}
+
+func (t sqlTx) Rollback(context.Context) error {
+	return t.tx.Rollback()
+}
+
 // chunkStatementsByTransaction splits statements into chunks based on COMMIT/BEGIN
 // pair boundaries. A COMMIT immediately followed by BEGIN signals a transaction
 // boundary - statements before the pair go in one chunk, statements after go in
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach-go/v2/crdb"
 	"github.com/cockroachdb/cockroachdb-parser/pkg/util/uuid"
@@ -163,6 +164,120 @@ func TestAutocommitDDLWithTransactionBoundaries(t *testing.T) {
 	assert.Contains(t, createStmt, "idx_posts_user")
 }
 
+func TestExecuteDDLWithProgress(t *testing.T) {
+	ctx := context.Background()
+	client := getProdLikeClient(t, ctx)
+	require.NoError(t, client.SetStatementTimeout(ctx, time.Minute))
+
+	var progress []StatementProgress
+	err := client.ExecuteDDLWithProgress(ctx, func(p StatementProgress) {
+		progress = append(progress, p)
+	},
+		"CREATE TABLE progress_users (id INT PRIMARY KEY, name TEXT)",
+		"COMMIT",
+		"ALTER TABLE progress_users ALTER COLUMN name TYPE VARCHAR(100)",
+		"BEGIN",
+		"CREATE INDEX idx_progress_name ON progress_users (name)",
+	)
+	require.NoError(t, err)
+
+	require.Len(t, progress, 3)
+	for i, p := range progress {
+		assert.Equal(t, i+1, p.Index)
+		assert.Equal(t, 3, p.Total)
+		assert.Equal(t, 1, p.Attempt)
+	}
+	assert.Equal(t, "CREATE INDEX idx_progress_name ON progress_users (name)", progress[2].Statement)
+}
+
+func TestExecuteDDLWithProgressFailedChunk(t *testing.T) {
+	ctx := context.Background()
+	client := getProdLikeClient(t, ctx)
+
+	// Nothing in a chunk that rolls back is reported as applied
+	var progress []StatementProgress
+	err := client.ExecuteDDLWithProgress(ctx, func(p StatementProgress) {
+		progress = append(progress, p)
+	},
+		"CREATE TABLE progress_applied (id INT PRIMARY KEY)",
+		"COMMIT",
+		"BEGIN",
+		"CREATE TABLE progress_rolled_back (id INT PRIMARY KEY)",
+		"CREATE TABLE progress_applied (id INT PRIMARY KEY)",
+	)
+	require.Error(t, err)
+
+	require.Len(t, progress, 1)
+	assert.Equal(t, "CREATE TABLE progress_applied (id INT PRIMARY KEY)", progress[0].Statement)
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	t.Parallel()
+
+	a := "CREATE TABLE public.a (id INT8 PRIMARY KEY)"
+	b := "CREATE TABLE public.b (id INT8 PRIMARY KEY)"
+	assert.Equal(t, SchemaFingerprint([]string{a, b}), SchemaFingerprint([]string{b, a}))
+	assert.NotEqual(t, SchemaFingerprint([]string{a, b}), SchemaFingerprint([]string{a}))
+	assert.NotEqual(t, SchemaFingerprint([]string{a + b}), SchemaFingerprint([]string{a, b}))
+}
+
+func TestExecuteDDLWithProgressSchemaChanged(t *testing.T) {
+	ctx := context.Background()
+	client := getProdLikeClient(t, ctx)
+
+	require.NoError(t, client.ExecuteBulkDDL(ctx, "CREATE TABLE fingerprint_a (id INT PRIMARY KEY)"))
+	statements, err := client.GetAllCreateStatements(ctx)
+	require.NoError(t, err)
+	fingerprint := SchemaFingerprint(statements)
+
+	// Unchanged, the statements apply
+	client.SetExpectedSchemaFingerprint(fingerprint)
+	require.NoError(t, client.ExecuteDDLWithProgress(ctx, nil, "ALTER TABLE fingerprint_a ADD COLUMN name STRING"))
+
+	// Someone else changed the schema since it was read
+	statements, err = client.GetAllCreateStatements(ctx)
+	require.NoError(t, err)
+	client.SetExpectedSchemaFingerprint(SchemaFingerprint(statements))
+	require.NoError(t, client.ExecuteBulkDDL(ctx, "CREATE TABLE fingerprint_b (id INT PRIMARY KEY)"))
+	err = client.ExecuteDDLWithProgress(ctx, nil, "CREATE INDEX ON fingerprint_a (name)")
+	require.ErrorIs(t, err, ErrSchemaChanged)
+
+	// Outside of a transaction too
+	client.SetExpectedSchemaFingerprint(fingerprint)
+	err = client.ExecuteDDLWithProgress(ctx, nil, "COMMIT", "CREATE INDEX ON fingerprint_a (name)")
+	require.ErrorIs(t, err, ErrSchemaChanged)
+
+	var indexes int
+	require.NoError(t, client.GetDB().QueryRowContext(ctx,
+		`SELECT count(*) FROM [SHOW INDEXES FROM fingerprint_a] WHERE column_name = 'name'`).Scan(&indexes))
+	assert.Zero(t, indexes)
+
+	// The check is made once
+	require.NoError(t, client.ExecuteDDLWithProgress(ctx, nil, "CREATE INDEX ON fingerprint_a (name)"))
+}
+
+func TestGetAllCreateStatementsAsOf(t *testing.T) {
+	ctx := context.Background()
+	client := getProdLikeClient(t, ctx)
+
+	require.NoError(t, client.ExecuteBulkDDL(ctx, "CREATE TABLE as_of_a (id INT PRIMARY KEY)"))
+	var before string
+	require.NoError(t, client.GetDB().QueryRowContext(ctx, "SELECT now()::STRING").Scan(&before))
+	require.NoError(t, client.ExecuteBulkDDL(ctx, "ALTER TABLE as_of_a ADD COLUMN name STRING"))
+
+	current, err := client.GetAllCreateStatements(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, strings.Join(current, "\n"), "name STRING")
+
+	past, err := client.GetAllCreateStatementsAsOf(ctx, before)
+	require.NoError(t, err)
+	assert.Contains(t, strings.Join(past, "\n"), "as_of_a")
+	assert.NotContains(t, strings.Join(past, "\n"), "name STRING")
+
+	_, err = client.GetAllCreateStatementsAsOf(ctx, "not a time")
+	assert.ErrorContains(t, err, "failed to read the schema as of not a time")
+}
+
 // TestAutocommitMultipleDDLInOneChunk tests what happens when multiple DDL
 // statements are joined and sent in one shot inside crdb.ExecuteTx with
 // autocommit_before_ddl ON. This is the core concern: does the auto-commit
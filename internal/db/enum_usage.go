@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// CountEnumValueRows counts the rows of a table whose enum column holds value,
+// or for an array column, has it as an element. The column is compared as a
+// string, so values that don't belong to the enum count zero rows instead of
+// failing.
+func (c *Client) CountEnumValueRows(ctx context.Context, schemaName, tableName, column, value string, array bool) (int64, error) {
+	table := tree.NameString(schemaName) + "." + tree.NameString(tableName)
+	where := fmt.Sprintf("%s::STRING = $1", tree.NameString(column))
+	if array {
+		where = fmt.Sprintf("$1 = ANY (%s::STRING[])", tree.NameString(column))
+	}
+
+	var rows int64
+	query := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", table, where)
+	if err := c.db.QueryRowContext(ctx, query, value).Scan(&rows); err != nil {
+		return 0, fmt.Errorf("failed to count rows of %s using '%s': %w", table, value, err)
+	}
+	return rows, nil
+}
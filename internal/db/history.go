@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/cockroach-go/v2/crdb"
+)
+
+// HistoryEntry is a row of the _scurry_.migrations table, as exported by
+// migration export-history
+type HistoryEntry struct {
+	Name               string     `json:"name"`
+	Checksum           string     `json:"checksum"`
+	Status             string     `json:"status"`
+	StartedAt          *time.Time `json:"started_at,omitempty"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	AppliedAt          time.Time  `json:"applied_at"`
+	ExecutedBy         string     `json:"executed_by"`
+	FailedStatement    *string    `json:"failed_statement,omitempty"`
+	ErrorMsg           *string    `json:"error_msg,omitempty"`
+	Async              bool       `json:"async"`
+	HeartbeatAt        *time.Time `json:"heartbeat_at,omitempty"`
+	AppliedSQL         *string    `json:"applied_sql,omitempty"`
+	LogicalChecksum    *string    `json:"logical_checksum,omitempty"`
+	CurrentStatement   *int       `json:"current_statement,omitempty"`
+	StatementStartedAt *time.Time `json:"statement_started_at,omitempty"`
+	Repeatable         bool       `json:"repeatable"`
+}
+
+// ExportHistory returns every row of the migrations table, repeatable
+// migrations included. Job IDs are left out since they only mean something on
+// the cluster that ran the job.
+func (c *Client) ExportHistory(ctx context.Context) ([]HistoryEntry, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async, heartbeat_at, applied_sql, logical_checksum, current_statement, statement_started_at, repeatable
+		FROM _scurry_.migrations
+		ORDER BY repeatable, name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migration history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var e HistoryEntry
+		if err := rows.Scan(&e.Name, &e.Checksum, &e.Status, &e.StartedAt, &e.CompletedAt, &e.AppliedAt, &e.ExecutedBy, &e.FailedStatement, &e.ErrorMsg, &e.Async, &e.HeartbeatAt, &e.AppliedSQL, &e.LogicalChecksum, &e.CurrentStatement, &e.StatementStartedAt, &e.Repeatable); err != nil {
+			return nil, fmt.Errorf("failed to scan migration history: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ImportHistory writes exported rows into the migrations table in a single
+// transaction. With replace, the existing rows are deleted first; otherwise
+// migrations already in the table are left alone. Returns the number of rows
+// written.
+func (c *Client) ImportHistory(ctx context.Context, entries []HistoryEntry, replace bool) (int, error) {
+	imported := 0
+	err := crdb.ExecuteTx(ctx, c.db, &sql.TxOptions{}, func(tx *sql.Tx) error {
+		imported = 0
+		if replace {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM _scurry_.migrations WHERE true`); err != nil {
+				return fmt.Errorf("failed to clear migration history: %w", err)
+			}
+		}
+		for _, e := range entries {
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO _scurry_.migrations (name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async, heartbeat_at, applied_sql, logical_checksum, current_statement, statement_started_at, repeatable)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+				ON CONFLICT (name) DO NOTHING
+			`, e.Name, e.Checksum, e.Status, e.StartedAt, e.CompletedAt, e.AppliedAt, e.ExecutedBy, e.FailedStatement, e.ErrorMsg, e.Async, e.HeartbeatAt, e.AppliedSQL, e.LogicalChecksum, e.CurrentStatement, e.StatementStartedAt, e.Repeatable)
+			if err != nil {
+				return fmt.Errorf("failed to import migration %s: %w", e.Name, err)
+			}
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to check rows affected: %w", err)
+			}
+			imported += int(rows)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportHistory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	source, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer source.Close()
+	require.NoError(t, source.InitMigrationHistory(ctx))
+
+	require.NoError(t, source.RecordMigration(ctx, "001_a", "a", false))
+	require.NoError(t, source.RecordMigrationSQL(ctx, "001_a", "CREATE TABLE a (id INT PRIMARY KEY)"))
+	require.NoError(t, source.StartMigration(ctx, "002_b", "b", true))
+	require.NoError(t, source.FailMigration(ctx, "002_b", "CREATE INDEX ON a (id)", "boom"))
+	require.NoError(t, source.StartRepeatableMigration(ctx, "R__views", "r"))
+
+	exported, err := source.ExportHistory(ctx)
+	require.NoError(t, err)
+	require.Len(t, exported, 3)
+	assert.Equal(t, []string{"001_a", "002_b", "R__views"}, []string{exported[0].Name, exported[1].Name, exported[2].Name})
+	assert.Equal(t, "CREATE TABLE a (id INT PRIMARY KEY)", *exported[0].AppliedSQL)
+	assert.Equal(t, MigrationStatusFailed, exported[1].Status)
+	assert.Equal(t, "boom", *exported[1].ErrorMsg)
+	assert.True(t, exported[2].Repeatable)
+
+	target, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer target.Close()
+	require.NoError(t, target.InitMigrationHistory(ctx))
+
+	// Without replace, existing migrations are left alone
+	require.NoError(t, target.RecordMigration(ctx, "001_a", "other", false))
+	imported, err := target.ImportHistory(ctx, exported, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, imported)
+	m, err := target.GetMigration(ctx, "001_a")
+	require.NoError(t, err)
+	assert.Equal(t, "other", m.Checksum)
+
+	// With replace, the clone's history matches the source
+	imported, err = target.ImportHistory(ctx, exported, true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, imported)
+	restored, err := target.ExportHistory(ctx)
+	require.NoError(t, err)
+	require.Len(t, restored, 3)
+	for i := range exported {
+		assert.Equal(t, exported[i].Name, restored[i].Name)
+		assert.Equal(t, exported[i].Checksum, restored[i].Checksum)
+		assert.Equal(t, exported[i].Status, restored[i].Status)
+		assert.True(t, exported[i].AppliedAt.Equal(restored[i].AppliedAt))
+		assert.Equal(t, exported[i].ExecutedBy, restored[i].ExecutedBy)
+	}
+}
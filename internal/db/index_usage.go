@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// IndexUsage is how often a secondary index has been read, from the cluster's
+// index usage statistics
+type IndexUsage struct {
+	SchemaName string
+	TableName  string
+	IndexName  string
+	TotalReads int64
+	LastRead   time.Time // Zero if the index hasn't been read
+}
+
+// GetIndexUsage returns the read counts of the secondary indexes in the current
+// database. The counts cover the time since the statistics were last reset,
+// e.g. with crdb_internal.reset_index_usage_stats().
+func (c *Client) GetIndexUsage(ctx context.Context) ([]IndexUsage, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT t.schema_name, t.name, i.index_name, COALESCE(s.total_reads, 0), s.last_read
+		FROM crdb_internal.table_indexes AS i
+		JOIN crdb_internal.tables AS t ON t.table_id = i.descriptor_id
+		LEFT JOIN crdb_internal.index_usage_statistics AS s
+			ON s.table_id = i.descriptor_id AND s.index_id = i.index_id
+		WHERE t.database_name = current_database()
+			AND t.state = 'PUBLIC'
+			AND i.index_type = 'secondary'
+			AND t.schema_name NOT IN ('pg_catalog', 'information_schema', 'crdb_internal', '_scurry_')
+		ORDER BY t.schema_name, t.name, i.index_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index usage statistics: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []IndexUsage
+	for rows.Next() {
+		var u IndexUsage
+		var lastRead sql.NullTime
+		if err := rows.Scan(&u.SchemaName, &u.TableName, &u.IndexName, &u.TotalReads, &lastRead); err != nil {
+			return nil, fmt.Errorf("failed to scan index usage: %w", err)
+		}
+		if lastRead.Valid {
+			u.LastRead = lastRead.Time
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Job statuses that won't change any more
+const (
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
+// SchemaChangeJob is a schema change or IMPORT job from crdb_internal.jobs
+type SchemaChangeJob struct {
+	ID          int64
+	Status      string
+	Description string
+	// FractionCompleted is the job's progress, from 0 to 1
+	FractionCompleted float64
+	Error             string
+}
+
+// migrationJobTypes are the job_type values of the jobs a migration's
+// statements start, which keep running after the client goes away: schema
+// changes, from the legacy and declarative schema changers, and IMPORT INTO
+const migrationJobTypes = `('SCHEMA CHANGE', 'NEW SCHEMA CHANGE', 'IMPORT')`
+
+// JobProgressInterval is how often the progress of an IMPORT job is reported
+// while its statement runs
+var JobProgressInterval = 5 * time.Second
+
+// Running reports whether the job hasn't finished, including jobs that are
+// paused or reverting
+func (j SchemaChangeJob) Running() bool {
+	switch j.Status {
+	case JobStatusSucceeded, JobStatusFailed, JobStatusCanceled:
+		return false
+	}
+	return true
+}
+
+// SchemaChangeJobsSince returns the schema change and IMPORT jobs created at or after
+// since, oldest first. A statement that's canceled after its transaction
+// commits leaves its job running, so these tell whether an interrupted schema
+// change took effect.
+func (c *Client) SchemaChangeJobsSince(ctx context.Context, since time.Time) ([]SchemaChangeJob, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT job_id, status, description, COALESCE(fraction_completed, 0), COALESCE(error, '')
+		FROM crdb_internal.jobs
+		WHERE job_type IN `+migrationJobTypes+` AND created >= $1
+		ORDER BY created
+	`, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema change jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []SchemaChangeJob
+	for rows.Next() {
+		var job SchemaChangeJob
+		if err := rows.Scan(&job.ID, &job.Status, &job.Description, &job.FractionCompleted, &job.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan schema change job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// GetSchemaChangeJob returns the job with the given ID, or nil if the cluster
+// no longer has it
+func (c *Client) GetSchemaChangeJob(ctx context.Context, id int64) (*SchemaChangeJob, error) {
+	var job SchemaChangeJob
+	err := c.db.QueryRowContext(ctx, `
+		SELECT job_id, status, description, COALESCE(fraction_completed, 0), COALESCE(error, '')
+		FROM crdb_internal.jobs
+		WHERE job_id = $1
+	`, id).Scan(&job.ID, &job.Status, &job.Description, &job.FractionCompleted, &job.Error)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job %d: %w", id, err)
+	}
+	return &job, nil
+}
+
+// RecordMigrationJob records the latest schema change job started since the
+// migration's current statement began, keeping the one recorded before if the
+// statement hasn't started one
+func (c *Client) RecordMigrationJob(ctx context.Context, name string) error {
+	_, err := c.db.ExecContext(ctx, `
+		UPDATE _scurry_.migrations
+		SET job_id = COALESCE((
+			SELECT job_id FROM crdb_internal.jobs
+			WHERE job_type IN `+migrationJobTypes+` AND created >= statement_started_at::TIMESTAMP
+			ORDER BY created DESC
+			LIMIT 1
+		), job_id)
+		WHERE name = $1 AND statement_started_at IS NOT NULL
+	`, name)
+	if err != nil {
+		return fmt.Errorf("failed to record job of migration %s: %w", name, err)
+	}
+	return nil
+}
+
+// SetJobProgressHandler sets a function ExecuteMigrationWithTracking calls
+// with the progress of each IMPORT job, every JobProgressInterval while its
+// statement runs
+func (c *Client) SetJobProgressHandler(onProgress func(SchemaChangeJob)) {
+	c.onJobProgress = onProgress
+}
+
+// watchImportJobs reports the progress of the IMPORT jobs created since since
+// to the job progress handler, until the returned function is called
+func (c *Client) watchImportJobs(ctx context.Context, since time.Time) (stop func()) {
+	if c.onJobProgress == nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(JobProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			rows, err := c.db.QueryContext(ctx, `
+				SELECT job_id, status, description, COALESCE(fraction_completed, 0), COALESCE(error, '')
+				FROM crdb_internal.jobs
+				WHERE job_type = 'IMPORT' AND created >= $1
+				ORDER BY created
+			`, since.UTC())
+			if err != nil {
+				// Progress is only informational
+				continue
+			}
+			var jobs []SchemaChangeJob
+			for rows.Next() {
+				var job SchemaChangeJob
+				if err := rows.Scan(&job.ID, &job.Status, &job.Description, &job.FractionCompleted, &job.Error); err == nil {
+					jobs = append(jobs, job)
+				}
+			}
+			rows.Close()
+			for _, job := range jobs {
+				c.onJobProgress(job)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaChangeJobRunning(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{"running", true},
+		{"pending", true},
+		{"paused", true},
+		{"reverting", true},
+		{JobStatusSucceeded, false},
+		{JobStatusFailed, false},
+		{JobStatusCanceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, SchemaChangeJob{Status: tt.status}.Running())
+		})
+	}
+}
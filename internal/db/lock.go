@@ -0,0 +1,246 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/util/uuid"
+)
+
+// LockTableSchema is embedded from schema/lock_table.sql
+//
+//go:embed schema/lock_table.sql
+var LockTableSchema string
+
+// migrationLockName is the lock row taken by anything that changes the schema
+const migrationLockName = "migrations"
+
+// DefaultLockTTL is how long a lock is held without a heartbeat before another
+// process may take it
+const DefaultLockTTL = 30 * time.Second
+
+// LockHolder describes the process holding the migration lock
+type LockHolder struct {
+	Owner      string
+	AcquiredAt time.Time
+	ExpiresAt  time.Time
+}
+
+// LockHeldError is returned when another process holds the migration lock
+type LockHeldError struct {
+	Holder LockHolder
+}
+
+func (e *LockHeldError) Error() string {
+	return fmt.Sprintf("migration lock is held by %s (since %s, expires %s)",
+		e.Holder.Owner, e.Holder.AcquiredAt.Format(time.RFC3339), e.Holder.ExpiresAt.Format(time.RFC3339))
+}
+
+// LockOptions controls how the migration lock is acquired
+type LockOptions struct {
+	// Owner identifies the process in the lock row. Defaults to DefaultLockOwner().
+	Owner string
+	// TTL is how long the lock lasts without a heartbeat. Defaults to DefaultLockTTL.
+	TTL time.Duration
+	// Wait is how long to keep trying while another process holds the lock.
+	// Zero fails immediately.
+	Wait time.Duration
+}
+
+// MigrationLock is a held migration lock. It is extended in the background
+// until Release is called.
+type MigrationLock struct {
+	client *Client
+	token  string
+	ttl    time.Duration
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+// DefaultLockOwner returns the hostname and pid of this process
+func DefaultLockOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s (pid %d)", hostname, os.Getpid())
+}
+
+// AcquireMigrationLock takes the migration lock, so only one scurry process
+// changes the schema at a time. The lock is extended every TTL/3 until it is
+// released; if an extension fails, the lock's Context is canceled.
+func (c *Client) AcquireMigrationLock(ctx context.Context, opts LockOptions) (*MigrationLock, error) {
+	if opts.Owner == "" {
+		opts.Owner = DefaultLockOwner()
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultLockTTL
+	}
+
+	if err := c.initLockTable(ctx); err != nil {
+		return nil, err
+	}
+
+	token := uuid.NewV4().String()
+	deadline := time.Now().Add(opts.Wait)
+	for {
+		acquired, err := c.tryAcquireLock(ctx, opts.Owner, token, opts.TTL)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+
+		if !time.Now().Before(deadline) {
+			holder, err := c.GetLockHolder(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if holder == nil {
+				// Released between our attempt and the lookup
+				continue
+			}
+			return nil, &LockHeldError{Holder: *holder}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(time.Second, time.Until(deadline))):
+		}
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	lock := &MigrationLock{
+		client: c,
+		token:  token,
+		ttl:    opts.TTL,
+		ctx:    lockCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go lock.heartbeat()
+	return lock, nil
+}
+
+// Context returns a context that is canceled if the lock is lost
+func (l *MigrationLock) Context() context.Context {
+	return l.ctx
+}
+
+// Release stops extending the lock and deletes it
+func (l *MigrationLock) Release(ctx context.Context) error {
+	l.cancel(nil)
+	<-l.done
+
+	_, err := l.client.db.ExecContext(ctx, `
+		DELETE FROM _scurry_.lock WHERE name = $1 AND token = $2
+	`, migrationLockName, l.token)
+	if err != nil {
+		return fmt.Errorf("failed to release migration lock: %w", err)
+	}
+	return nil
+}
+
+func (l *MigrationLock) heartbeat() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := l.client.db.ExecContext(l.ctx, `
+			UPDATE _scurry_.lock
+			SET expires_at = now() + $3 * INTERVAL '1 millisecond'
+			WHERE name = $1 AND token = $2
+		`, migrationLockName, l.token, l.ttl.Milliseconds())
+		if err != nil {
+			if l.ctx.Err() != nil {
+				return
+			}
+			l.cancel(fmt.Errorf("failed to extend migration lock: %w", err))
+			return
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+			l.cancel(errors.New("migration lock was taken by another process"))
+			return
+		}
+	}
+}
+
+// GetLockHolder returns the current holder of the migration lock, or nil if
+// it isn't held
+func (c *Client) GetLockHolder(ctx context.Context) (*LockHolder, error) {
+	if err := c.initLockTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var holder LockHolder
+	err := c.db.QueryRowContext(ctx, `
+		SELECT owner, acquired_at, expires_at
+		FROM _scurry_.lock
+		WHERE name = $1 AND expires_at >= now()
+	`, migrationLockName).Scan(&holder.Owner, &holder.AcquiredAt, &holder.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration lock holder: %w", err)
+	}
+	return &holder, nil
+}
+
+// ForceUnlock deletes the migration lock regardless of who holds it, returning
+// whether there was a lock to delete
+func (c *Client) ForceUnlock(ctx context.Context) (bool, error) {
+	if err := c.initLockTable(ctx); err != nil {
+		return false, err
+	}
+
+	result, err := c.db.ExecContext(ctx, `DELETE FROM _scurry_.lock WHERE name = $1`, migrationLockName)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete migration lock: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// tryAcquireLock inserts the lock row, or takes it over if it has expired
+func (c *Client) tryAcquireLock(ctx context.Context, owner, token string, ttl time.Duration) (bool, error) {
+	var acquired string
+	err := c.db.QueryRowContext(ctx, `
+		INSERT INTO _scurry_.lock (name, owner, token, expires_at)
+		VALUES ($1, $2, $3, now() + $4 * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE
+		SET owner = excluded.owner, token = excluded.token, acquired_at = now(), expires_at = excluded.expires_at
+		WHERE lock.expires_at < now()
+		RETURNING token
+	`, migrationLockName, owner, token, ttl.Milliseconds()).Scan(&acquired)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	return acquired == token, nil
+}
+
+func (c *Client) initLockTable(ctx context.Context) error {
+	return c.initInternalTable(ctx, "lock", LockTableSchema)
+}
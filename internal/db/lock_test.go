@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireMigrationLock(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	lock, err := client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-1"})
+	require.NoError(t, err)
+
+	holder, err := client.GetLockHolder(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, "runner-1", holder.Owner)
+
+	// A second process can't take the lock while it's held
+	_, err = client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-2"})
+	var heldErr *LockHeldError
+	require.ErrorAs(t, err, &heldErr)
+	assert.Equal(t, "runner-1", heldErr.Holder.Owner)
+
+	require.NoError(t, lock.Release(ctx))
+	assert.ErrorIs(t, lock.Context().Err(), context.Canceled)
+
+	holder, err = client.GetLockHolder(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+
+	lock, err = client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-2"})
+	require.NoError(t, err)
+	require.NoError(t, lock.Release(ctx))
+}
+
+func TestAcquireMigrationLock_Heartbeat(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	lock, err := client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-1", TTL: 300 * time.Millisecond})
+	require.NoError(t, err)
+	defer lock.Release(ctx)
+
+	// The lock outlives its TTL because it is extended in the background
+	time.Sleep(time.Second)
+	require.NoError(t, lock.Context().Err())
+
+	_, err = client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-2"})
+	var heldErr *LockHeldError
+	require.ErrorAs(t, err, &heldErr)
+}
+
+func TestAcquireMigrationLock_TakesOverExpiredLock(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Simulate a crashed process that left an expired lock behind
+	require.NoError(t, client.initLockTable(ctx))
+	_, err = client.db.ExecContext(ctx, `
+		INSERT INTO _scurry_.lock (name, owner, token, expires_at)
+		VALUES ($1, 'crashed', 'stale', now() - INTERVAL '1 minute')
+	`, migrationLockName)
+	require.NoError(t, err)
+
+	lock, err := client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-1"})
+	require.NoError(t, err)
+	defer lock.Release(ctx)
+
+	holder, err := client.GetLockHolder(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, "runner-1", holder.Owner)
+}
+
+func TestAcquireMigrationLock_Wait(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	lock, err := client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-1"})
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		lock.Release(ctx)
+	}()
+
+	second, err := client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-2", Wait: 10 * time.Second})
+	require.NoError(t, err)
+	require.NoError(t, second.Release(ctx))
+}
+
+func TestForceUnlock(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	unlocked, err := client.ForceUnlock(ctx)
+	require.NoError(t, err)
+	assert.False(t, unlocked)
+
+	lock, err := client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-1"})
+	require.NoError(t, err)
+
+	unlocked, err = client.ForceUnlock(ctx)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+
+	// The old holder's release doesn't remove a lock it no longer owns
+	other, err := client.AcquireMigrationLock(ctx, LockOptions{Owner: "runner-2"})
+	require.NoError(t, err)
+	require.NoError(t, lock.Release(ctx))
+
+	holder, err := client.GetLockHolder(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, "runner-2", holder.Owner)
+	require.NoError(t, other.Release(ctx))
+}
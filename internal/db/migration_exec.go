@@ -2,12 +2,174 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pjtatlow/scurry/internal/telemetry"
 )
 
-// SplitStatements parses SQL into individual statements using the CockroachDB parser
+// MigrationHeartbeatInterval is how often a running migration updates its
+// heartbeat_at in the history table
+var MigrationHeartbeatInterval = 10 * time.Second
+
+// RetryPolicy controls how failed migration statements are retried when the
+// error is transient
+type RetryPolicy struct {
+	// MaxRetries is how many times a statement is retried before the migration
+	// is marked failed. Zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry, doubled on each retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy clients start with
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// SetRetryPolicy sets how ExecuteMigrationWithTracking retries transient errors
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// backoff returns the delay before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// IsRetryableError reports whether err is a transient error where the statement
+// is known not to have been applied, so running it again is safe. That covers
+// serialization failures, which abort the statement's implicit transaction,
+// and connection errors raised before the statement was sent. A connection lost
+// while a statement runs, or an ambiguous result (40003), may have committed
+// and is never retried: migration statements aren't necessarily idempotent.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", "CR000":
+			// serialization failure / transaction retry
+			return true
+		case "08001", "08004":
+			// the connection couldn't be established, so nothing was sent
+			return true
+		}
+		return false
+	}
+
+	// database/sql drivers only return ErrBadConn when the server can't have
+	// run the statement, and a refused dial happens before anything is sent
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// WithDetail adds the DETAIL and HINT of a database error to its message, which
+// lib/pq leaves out even though CockroachDB often explains the fix in them.
+// Other errors are returned as they are.
+func WithDetail(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || (pqErr.Detail == "" && pqErr.Hint == "") {
+		return err
+	}
+	return &detailedError{err: err, detail: pqErr.Detail, hint: pqErr.Hint}
+}
+
+// detailedError is a database error with its DETAIL and HINT in its message
+type detailedError struct {
+	err    error
+	detail string
+	hint   string
+}
+
+func (e *detailedError) Error() string {
+	msg := e.err.Error()
+	if e.detail != "" {
+		msg += "\nDETAIL: " + e.detail
+	}
+	if e.hint != "" {
+		msg += "\nHINT: " + e.hint
+	}
+	return msg
+}
+
+func (e *detailedError) Unwrap() error {
+	return e.err
+}
+
+// execer executes statements on a database or a connection pinned from it
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// execWithRetry executes a single statement, retrying transient errors with
+// exponential backoff according to the client's retry policy
+func (c *Client) execWithRetry(ctx context.Context, conn execer, stmt string) (sql.Result, error) {
+	for retry := 0; ; retry++ {
+		result, err := conn.ExecContext(ctx, stmt)
+		if err == nil {
+			return result, nil
+		}
+		if retry >= c.retryPolicy.MaxRetries || !IsRetryableError(err) {
+			if retry > 0 {
+				return nil, fmt.Errorf("%w (after %d retries)", err, retry)
+			}
+			return nil, err
+		}
+
+		timer := time.NewTimer(c.retryPolicy.backoff(retry + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// rowsAffected returns the rows a statement affected, or 0 if the driver
+// doesn't report them
+func rowsAffected(result sql.Result) int64 {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return rows
+}
+
+// SplitStatements parses SQL into individual statements using the CockroachDB
+// parser. URIs, like the files of an IMPORT INTO, are kept in full rather than
+// redacted the way the parser formats them by default.
 func SplitStatements(sqlContent string) ([]string, error) {
 	statements, err := parser.Parse(sqlContent)
 	if err != nil {
@@ -16,11 +178,78 @@ func SplitStatements(sqlContent string) ([]string, error) {
 
 	var results []string
 	for _, stmt := range statements {
-		results = append(results, stmt.AST.String())
+		results = append(results, tree.AsStringWithFlags(stmt.AST, tree.FmtShowFullURIs))
 	}
 	return results, nil
 }
 
+// IsImportStatement reports whether stmt is an IMPORT, which can't run in an
+// explicit transaction and runs as a job that can take a long time
+func IsImportStatement(stmt string) bool {
+	parsed, err := parser.ParseOne(stmt)
+	if err != nil {
+		return false
+	}
+	_, ok := parsed.AST.(*tree.Import)
+	return ok
+}
+
+// LogicalChecksum hashes the statements of a migration as the parser formats
+// them, so edits to comments, whitespace or keyword case don't change it.
+// Returns "" if the SQL doesn't parse.
+func LogicalChecksum(sqlContent string) string {
+	statements, err := SplitStatements(sqlContent)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(strings.Join(statements, ";\n")))
+	return fmt.Sprintf("%x", hash)
+}
+
+// SessionSetting is a session variable a migration sets for itself
+type SessionSetting struct {
+	Name  string
+	Value string
+}
+
+var sessionVariablePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// IsSessionVariableName reports whether name can be a session variable, so it
+// can be used in SET without quoting
+func IsSessionVariableName(name string) bool {
+	return sessionVariablePattern.MatchString(name)
+}
+
+// setSessionSettings sets each setting on the connection
+func setSessionSettings(ctx context.Context, conn *sql.Conn, settings []SessionSetting) error {
+	for _, setting := range settings {
+		if !IsSessionVariableName(setting.Name) {
+			return fmt.Errorf("invalid session setting name: %q", setting.Name)
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET %s = %s", setting.Name, pq.QuoteLiteral(setting.Value))); err != nil {
+			return fmt.Errorf("failed to set %s: %w", setting.Name, err)
+		}
+	}
+	return nil
+}
+
+// resetSessionSettings puts the settings back to their defaults before the
+// connection returns to the pool. If that fails, the connection is discarded
+// instead so the settings can't leak into later queries.
+func resetSessionSettings(conn *sql.Conn, settings []SessionSetting) {
+	if len(settings) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, setting := range settings {
+		if _, err := conn.ExecContext(ctx, "RESET "+setting.Name); err != nil {
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			return
+		}
+	}
+}
+
 // ExecuteMigration executes a single migration and records it in the history
 // This does NOT use a transaction - if it fails, it fails, and we report the error
 // Deprecated: Use ExecuteMigrationWithTracking instead for better failure tracking
@@ -40,35 +269,217 @@ func (c *Client) ExecuteMigration(ctx context.Context, migration Migration) erro
 }
 
 // ExecuteMigrationWithTracking executes a migration with statement-level tracking
-// Returns the index of the failed statement (0-based) and any error
+// Transient errors are retried according to the client's retry policy before the
+// migration is marked failed. The migration and each statement are traced, and
+// the outcome is recorded for the push gateway (see the telemetry package).
+//
+// The statement about to run is recorded before it starts. If ctx is canceled,
+// e.g. on SIGINT, the running statement is canceled and the migration is marked
+// failed as interrupted, so migration recover can resume it.
 func (c *Client) ExecuteMigrationWithTracking(ctx context.Context, migration Migration) error {
-	// Parse SQL into statements
-	statements, err := SplitStatements(migration.SQL)
+	return c.executeMigration(ctx, migration, 0, false)
+}
+
+// ResumeMigration runs a failed or interrupted migration again from the
+// statement at index from (in MigrationStatements order), skipping the
+// statements before it that already took effect
+func (c *Client) ResumeMigration(ctx context.Context, migration Migration, from int) error {
+	return c.executeMigration(ctx, migration, from, true)
+}
+
+func (c *Client) executeMigration(ctx context.Context, migration Migration, from int, resume bool) (err error) {
+	mode := migration.Mode
+	if mode == "" {
+		mode = MigrationModeSync
+	}
+	ctx, span := telemetry.Tracer().Start(ctx, "scurry.migration", trace.WithAttributes(
+		attribute.String("scurry.migration.name", migration.Name),
+		attribute.String("scurry.migration.mode", mode),
+	))
+	start := time.Now()
+	executed := 0
+	defer func() {
+		status := MigrationStatusSucceeded
+		if err != nil {
+			status = MigrationStatusFailed
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.Int("scurry.migration.statements", executed))
+		span.End()
+		telemetry.RecordMigration(telemetry.Migration{
+			Name:       migration.Name,
+			Mode:       mode,
+			Status:     status,
+			Duration:   time.Since(start),
+			Statements: executed,
+		})
+	}()
+
+	// Parse SQL into statements, applying its transaction directives
+	statements, err := MigrationStatements(migration, false)
+	if err != nil {
+		return err
+	}
+	if from < 0 || from > len(statements) {
+		return fmt.Errorf("migration %s has no statement %d", migration.Name, from+1)
+	}
+
+	// The statements share a connection, so the transactions they open and
+	// commit hold together
+	conn, err := c.db.Conn(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to parse migration %s: %w", migration.Name, err)
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+	if c.statementTimeout > 0 {
+		if _, err := conn.ExecContext(ctx, statementTimeoutSQL(c.statementTimeout)); err != nil {
+			return fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+	if err := setSessionSettings(ctx, conn, migration.Settings); err != nil {
+		return err
+	}
+	defer resetSessionSettings(conn, migration.Settings)
+
+	// Make sure executed statements can be recorded before running any
+	if !c.isShadow {
+		if err := c.initStatementLog(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Back up what the migration drops before starting it, so a failed backup
+	// leaves the migration unapplied. A resumed migration was backed up when
+	// it first ran.
+	var backup *Backup
+	if c.BackupEnabled() && !resume && !c.isShadow {
+		if tables, destructive := BackupTables(statements); destructive {
+			backup, err = c.TakeBackup(ctx, tables)
+			if err != nil {
+				return fmt.Errorf("migration %s: %w", migration.Name, err)
+			}
+			if c.backupPolicy.OnBackup != nil {
+				c.backupPolicy.OnBackup(migration.Name, *backup)
+			}
+		}
 	}
 
 	// Record migration as pending
-	if err := c.StartMigration(ctx, migration.Name, migration.Checksum, migration.Mode == MigrationModeAsync); err != nil {
+	if resume {
+		err = c.resetMigrationForResume(ctx, migration.Name, migration.Checksum)
+	} else if migration.Repeatable {
+		err = c.StartRepeatableMigration(ctx, migration.Name, migration.Checksum)
+	} else {
+		err = c.StartMigration(ctx, migration.Name, migration.Checksum, migration.Mode == MigrationModeAsync)
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.RecordMigrationSQL(ctx, migration.Name, migration.SQL); err != nil {
 		return err
 	}
+	if backup != nil {
+		if err := c.RecordMigrationBackup(ctx, migration.Name, backup.Path); err != nil {
+			return err
+		}
+	}
+
+	// Keep the heartbeat fresh while statements run, so anyone waiting on the
+	// migration can tell it's still making progress
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(MigrationHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-heartbeatCtx.Done():
+				return
+			case <-ticker.C:
+				// A missed heartbeat only makes the migration look stalled
+				_ = c.HeartbeatMigration(heartbeatCtx, migration.Name)
+				// Async schema changes are tracked by their job, so status and
+				// run-async can follow them if this process goes away
+				if migration.Mode == MigrationModeAsync {
+					_ = c.RecordMigrationJob(heartbeatCtx, migration.Name)
+				}
+			}
+		}
+	}()
+	defer func() {
+		stopHeartbeat()
+		<-heartbeatDone
+	}()
 
 	// Execute statements one at a time
-	for _, stmt := range statements {
-		_, err := c.db.ExecContext(ctx, stmt)
+	for i, stmt := range statements {
+		if i < from {
+			continue
+		}
+		if err := c.StartStatement(ctx, migration.Name, i); err != nil {
+			return c.stopMigration(ctx, migration.Name, stmt, err)
+		}
+		stmtCtx, stmtSpan := telemetry.Tracer().Start(ctx, "scurry.migration.statement", trace.WithAttributes(
+			attribute.Int("scurry.statement.index", i),
+			attribute.String("db.query.text", stmt),
+		))
+		stmtStart := time.Now()
+		stopWatching := func() {}
+		if IsImportStatement(stmt) {
+			stopWatching = c.watchImportJobs(ctx, stmtStart)
+		}
+		result, err := c.execWithRetry(stmtCtx, conn, stmt)
+		stopWatching()
 		if err != nil {
-			// Record failure
-			if failErr := c.FailMigration(ctx, migration.Name, stmt, err.Error()); failErr != nil {
-				return fmt.Errorf("migration failed and could not record failure: %w (original error: %v)", failErr, err)
-			}
-			return fmt.Errorf("failed to execute statement: %w", err)
+			stmtSpan.RecordError(err)
+			stmtSpan.SetStatus(codes.Error, err.Error())
+		}
+		stmtSpan.End()
+		if err != nil {
+			return c.stopMigration(ctx, migration.Name, stmt, err)
 		}
+		executed++
+		c.logStatement(ctx, StatementLogEntry{
+			ExecutedAt:   stmtStart,
+			Statement:    stmt,
+			Migration:    migration.Name,
+			Source:       StatementSourceMigration,
+			DurationMs:   time.Since(stmtStart).Milliseconds(),
+			RowsAffected: rowsAffected(result),
+		})
 	}
 
-	// Mark as completed
-	if err := c.CompleteMigration(ctx, migration.Name); err != nil {
+	// Every statement ran, so record that even if ctx was canceled since
+	if err := c.CompleteMigration(context.WithoutCancel(ctx), migration.Name); err != nil {
 		return fmt.Errorf("migration succeeded but failed to mark as completed: %w", err)
 	}
 
 	return nil
 }
+
+// InterruptedPrefix starts the error recorded for a migration whose context was
+// canceled while a statement ran
+const InterruptedPrefix = "interrupted: "
+
+// interruptRecordTimeout bounds recording an interruption after ctx is canceled
+const interruptRecordTimeout = 10 * time.Second
+
+// stopMigration marks the migration failed at stmt. When ctx was canceled the
+// failure is recorded as an interruption, on a context of its own so the
+// cancellation doesn't lose the record.
+func (c *Client) stopMigration(ctx context.Context, name, stmt string, err error) error {
+	msg := err.Error()
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), interruptRecordTimeout)
+		defer cancel()
+		msg = InterruptedPrefix + msg
+		err = fmt.Errorf("migration interrupted: %w", err)
+	}
+	if failErr := c.FailMigration(ctx, name, stmt, msg); failErr != nil {
+		return fmt.Errorf("migration failed and could not record failure: %w (original error: %v)", failErr, err)
+	}
+	return fmt.Errorf("failed to execute statement: %w", err)
+}
@@ -19,7 +19,18 @@ var DesiredMigrationsTableSchema string
 
 // InitMigrationHistory creates the _scurry_ schema and migrations table if they don't exist.
 // For existing databases with an old schema, it uses schema diffing to migrate to the current schema.
+// On a read-only connection it only checks that the table is up to date.
 func (c *Client) InitMigrationHistory(ctx context.Context) error {
+	if c.readOnly {
+		return c.checkMigrationHistory(ctx)
+	}
+
+	return c.initInternalTable(ctx, "migrations", DesiredMigrationsTableSchema)
+}
+
+// initInternalTable creates the _scurry_ schema and the named table from desiredSQL
+// if they don't exist. An existing table is migrated to desiredSQL with schema diffing.
+func (c *Client) initInternalTable(ctx context.Context, name, desiredSQL string) error {
 	// Create the schema first
 	_, err := c.db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS _scurry_`)
 	if err != nil {
@@ -27,22 +38,22 @@ func (c *Client) InitMigrationHistory(ctx context.Context) error {
 	}
 
 	// Get current CREATE TABLE statement from database (if table exists)
-	currentSchema, err := c.getMigrationsTableSchema(ctx)
+	currentSchema, err := c.getInternalTableSchema(ctx, name)
 	if err != nil {
-		return fmt.Errorf("failed to get current migrations table schema: %w", err)
+		return fmt.Errorf("failed to get current %s table schema: %w", name, err)
 	}
 
 	// If table doesn't exist, create it with the desired schema
 	if currentSchema == "" {
-		_, err = c.db.ExecContext(ctx, DesiredMigrationsTableSchema)
+		_, err = c.db.ExecContext(ctx, desiredSQL)
 		if err != nil {
-			return fmt.Errorf("failed to create migrations table: %w", err)
+			return fmt.Errorf("failed to create %s table: %w", name, err)
 		}
 		return nil
 	}
 
 	// Table exists - compare schemas and generate migration statements
-	alterStatements, err := generateMigrationsTableAlterStatements(currentSchema, DesiredMigrationsTableSchema)
+	alterStatements, err := generateMigrationsTableAlterStatements(currentSchema, desiredSQL)
 	if err != nil {
 		return fmt.Errorf("failed to generate schema migration: %w", err)
 	}
@@ -51,13 +62,33 @@ func (c *Client) InitMigrationHistory(ctx context.Context) error {
 	for _, stmt := range alterStatements {
 		_, err = c.db.ExecContext(ctx, stmt)
 		if err != nil {
-			return fmt.Errorf("failed to migrate migrations table schema: %w", err)
+			return fmt.Errorf("failed to migrate %s table schema: %w", name, err)
 		}
 	}
 
 	return nil
 }
 
+// checkMigrationHistory fails if the migrations table doesn't exist or needs
+// changes, which a read-only connection can't make
+func (c *Client) checkMigrationHistory(ctx context.Context) error {
+	exists, err := c.MigrationsTableExists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current migrations table schema: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("the _scurry_.migrations table doesn't exist and can't be created in read-only mode")
+	}
+	pending, err := c.PendingMigrationsTableChanges(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("the _scurry_.migrations table is out of date and can't be upgraded in read-only mode")
+	}
+	return nil
+}
+
 // MigrationsTableExists checks if the _scurry_.migrations table exists
 func (c *Client) MigrationsTableExists(ctx context.Context) (bool, error) {
 	schema, err := c.getMigrationsTableSchema(ctx)
@@ -67,16 +98,33 @@ func (c *Client) MigrationsTableExists(ctx context.Context) (bool, error) {
 	return schema != "", nil
 }
 
+// PendingMigrationsTableChanges returns the statements InitMigrationHistory
+// would run to bring an existing migrations table up to date, or nil if the
+// table is current or doesn't exist yet.
+func (c *Client) PendingMigrationsTableChanges(ctx context.Context) ([]string, error) {
+	currentSchema, err := c.getMigrationsTableSchema(ctx)
+	if err != nil || currentSchema == "" {
+		return nil, err
+	}
+	return generateMigrationsTableAlterStatements(currentSchema, DesiredMigrationsTableSchema)
+}
+
 // getMigrationsTableSchema returns the current CREATE TABLE statement for the migrations table,
 // or empty string if the table doesn't exist.
 func (c *Client) getMigrationsTableSchema(ctx context.Context) (string, error) {
+	return c.getInternalTableSchema(ctx, "migrations")
+}
+
+// getInternalTableSchema returns the current CREATE TABLE statement for the named
+// _scurry_ table, or empty string if the table doesn't exist.
+func (c *Client) getInternalTableSchema(ctx context.Context, name string) (string, error) {
 	var createStatement string
 	err := c.db.QueryRowContext(ctx, `
 		SELECT create_statement
 		FROM crdb_internal.create_statements
-		WHERE descriptor_name = 'migrations'
+		WHERE descriptor_name = $1
 		AND schema_name = '_scurry_'
-	`).Scan(&createStatement)
+	`, name).Scan(&createStatement)
 
 	if err == sql.ErrNoRows {
 		return "", nil
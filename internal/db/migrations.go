@@ -6,19 +6,35 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/cockroachdb/cockroach-go/v2/crdb"
 	"github.com/lib/pq"
 )
 
 // Migration represents a migration file with its metadata
 type Migration struct {
-	Name      string
-	SQL       string
-	Checksum  string
-	Mode      string // "sync", "async", or "" (treated as sync)
-	DependsOn []string
-	Squash    bool
+	Name     string
+	SQL      string
+	Checksum string
+	// LogicalChecksum hashes the parsed statements rather than the text, so
+	// comment and formatting edits don't change it (see LogicalChecksum)
+	LogicalChecksum string
+	Mode            string // "sync", "async", or "" (treated as sync)
+	DependsOn       []string
+	Squash          bool
+	// RefreshStats are the tables to collect statistics on once the migration
+	// succeeds (see RefreshStatistics)
+	RefreshStats []string
+	// Settings are session variables set on the migration's connection while
+	// it runs (see ExecuteMigrationWithTracking)
+	Settings []SessionSetting
+	// Repeatable migrations (R__name.sql) re-run whenever their checksum
+	// changes, after the versioned migrations
+	Repeatable bool
 }
 
+// BaselineExecutedBy is recorded as executed_by for migrations marked applied by a baseline
+const BaselineExecutedBy = "baseline"
+
 // Migration status constants
 const (
 	MigrationStatusPending   = "pending"
@@ -45,13 +61,38 @@ type AppliedMigration struct {
 	FailedStatement *string
 	ErrorMsg        *string
 	Async           bool
+	HeartbeatAt     *time.Time // last time the process running a pending migration reported in
+	LogicalChecksum string     // empty for migrations applied without recording their SQL
+	// CurrentStatement is the index of the statement that was running, or
+	// failed, in MigrationStatements order, and StatementStartedAt when it began
+	CurrentStatement   *int
+	StatementStartedAt *time.Time
+	// JobID is the latest schema change job an async migration started, which
+	// keeps running in the cluster even if the process running it goes away
+	JobID *int64
+	// BackupPath is where the backup taken before the migration, because it
+	// drops data, was written (see BackupPolicy)
+	BackupPath string
+}
+
+// Modified returns true if the migration on disk isn't the one that was
+// applied. With logical set, edits that only change comments or formatting
+// don't count, when the logical checksum of the applied migration is known.
+func (a AppliedMigration) Modified(m Migration, logical bool) bool {
+	// An empty checksum means the migration was marked applied when it was created
+	if a.Checksum == "" || a.Checksum == m.Checksum {
+		return false
+	}
+	return !logical || a.LogicalChecksum == "" || a.LogicalChecksum != m.LogicalChecksum
 }
 
-// GetAppliedMigrations returns all migrations that have been applied to the database
+// GetAppliedMigrations returns all versioned migrations that have been applied to the
+// database. Repeatable migrations are left out (see GetRepeatableChecksums).
 func (c *Client) GetAppliedMigrations(ctx context.Context) ([]AppliedMigration, error) {
 	rows, err := c.db.QueryContext(ctx, `
-		SELECT name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async
+		SELECT name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async, heartbeat_at, COALESCE(logical_checksum, ''), current_statement, statement_started_at, job_id, COALESCE(backup_path, '')
 		FROM _scurry_.migrations
+		WHERE NOT repeatable
 		ORDER BY name ASC
 	`)
 	if err != nil {
@@ -62,7 +103,7 @@ func (c *Client) GetAppliedMigrations(ctx context.Context) ([]AppliedMigration,
 	var migrations []AppliedMigration
 	for rows.Next() {
 		var m AppliedMigration
-		if err := rows.Scan(&m.Name, &m.Checksum, &m.Status, &m.StartedAt, &m.CompletedAt, &m.AppliedAt, &m.ExecutedBy, &m.FailedStatement, &m.ErrorMsg, &m.Async); err != nil {
+		if err := rows.Scan(&m.Name, &m.Checksum, &m.Status, &m.StartedAt, &m.CompletedAt, &m.AppliedAt, &m.ExecutedBy, &m.FailedStatement, &m.ErrorMsg, &m.Async, &m.HeartbeatAt, &m.LogicalChecksum, &m.CurrentStatement, &m.StatementStartedAt, &m.JobID, &m.BackupPath); err != nil {
 			return nil, fmt.Errorf("failed to scan migration: %w", err)
 		}
 		migrations = append(migrations, m)
@@ -84,11 +125,78 @@ func (c *Client) RecordMigration(ctx context.Context, name, checksum string, asy
 	return nil
 }
 
+// BaselineMigrations records migrations as succeeded without executing them, in a
+// single transaction. Migrations already in the history table are left alone.
+// Returns the number of migrations recorded.
+func (c *Client) BaselineMigrations(ctx context.Context, migrations []Migration) (int, error) {
+	recorded := 0
+	err := crdb.ExecuteTx(ctx, c.db, &sql.TxOptions{}, func(tx *sql.Tx) error {
+		recorded = 0
+		for _, m := range migrations {
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO _scurry_.migrations (name, checksum, status, completed_at, executed_by, async, applied_sql, logical_checksum)
+				VALUES ($1, $2, $3, now(), $4, $5, $6, NULLIF($7, ''))
+				ON CONFLICT (name) DO NOTHING
+			`, m.Name, m.Checksum, MigrationStatusSucceeded, BaselineExecutedBy, m.Mode == MigrationModeAsync, m.SQL, LogicalChecksum(m.SQL))
+			if err != nil {
+				return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+			}
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to check rows affected: %w", err)
+			}
+			recorded += int(rows)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return recorded, nil
+}
+
+// GetRepeatableChecksums returns the checksum each repeatable migration last ran
+// (or was recovered) with, by name
+func (c *Client) GetRepeatableChecksums(ctx context.Context) (map[string]string, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT name, checksum
+		FROM _scurry_.migrations
+		WHERE repeatable AND status IN ($1, $2)
+	`, MigrationStatusSucceeded, MigrationStatusRecovered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repeatable migrations: %w", err)
+	}
+	defer rows.Close()
+
+	checksums := make(map[string]string)
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan repeatable migration: %w", err)
+		}
+		checksums[name] = checksum
+	}
+	return checksums, rows.Err()
+}
+
+// StartRepeatableMigration records a repeatable migration as pending, replacing
+// the record of its last run
+func (c *Client) StartRepeatableMigration(ctx context.Context, name, checksum string) error {
+	_, err := c.db.ExecContext(ctx, `
+		UPSERT INTO _scurry_.migrations (name, checksum, status, started_at, heartbeat_at, completed_at, failed_statement, error_msg, async, repeatable)
+		VALUES ($1, $2, $3, now(), now(), NULL, NULL, NULL, false, true)
+	`, name, checksum, MigrationStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to start migration %s: %w", name, err)
+	}
+	return nil
+}
+
 // StartMigration records a migration as pending with started_at timestamp
 func (c *Client) StartMigration(ctx context.Context, name, checksum string, async bool) error {
 	_, err := c.db.ExecContext(ctx, `
-		INSERT INTO _scurry_.migrations (name, checksum, status, started_at, async)
-		VALUES ($1, $2, $3, now(), $4)
+		INSERT INTO _scurry_.migrations (name, checksum, status, started_at, heartbeat_at, async)
+		VALUES ($1, $2, $3, now(), now(), $4)
 	`, name, checksum, MigrationStatusPending, async)
 	if err != nil {
 		return fmt.Errorf("failed to start migration %s: %w", name, err)
@@ -174,7 +282,8 @@ func (c *Client) RecoverMigration(ctx context.Context, name string) error {
 func (c *Client) ResetMigrationForRetry(ctx context.Context, name, checksum string) error {
 	result, err := c.db.ExecContext(ctx, `
 		UPDATE _scurry_.migrations
-		SET status = $2, checksum = $3, started_at = now(), completed_at = NULL, failed_statement = NULL, error_msg = NULL
+		SET status = $2, checksum = $3, started_at = now(), completed_at = NULL, failed_statement = NULL, error_msg = NULL,
+			current_statement = NULL, statement_started_at = NULL
 		WHERE name = $1 AND status = $4
 	`, name, MigrationStatusPending, checksum, MigrationStatusFailed)
 	if err != nil {
@@ -192,20 +301,54 @@ func (c *Client) ResetMigrationForRetry(ctx context.Context, name, checksum stri
 	return nil
 }
 
+// resetMigrationForResume marks a failed migration, or one left pending by a
+// process that's gone, as pending again without clearing its progress
+func (c *Client) resetMigrationForResume(ctx context.Context, name, checksum string) error {
+	result, err := c.db.ExecContext(ctx, `
+		UPDATE _scurry_.migrations
+		SET status = $2, checksum = $3, completed_at = NULL, failed_statement = NULL, error_msg = NULL, heartbeat_at = now()
+		WHERE name = $1 AND status IN ($4, $2)
+	`, name, MigrationStatusPending, checksum, MigrationStatusFailed)
+	if err != nil {
+		return fmt.Errorf("failed to reset migration %s to resume: %w", name, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("migration %s is not in failed or pending state", name)
+	}
+	return nil
+}
+
+// StartStatement records the statement a migration is about to run, so a
+// migration that's interrupted or crashes can be resumed from it
+func (c *Client) StartStatement(ctx context.Context, name string, index int) error {
+	_, err := c.db.ExecContext(ctx, `
+		UPDATE _scurry_.migrations SET current_statement = $2, statement_started_at = now(), job_id = NULL WHERE name = $1
+	`, name, index)
+	if err != nil {
+		return fmt.Errorf("failed to record progress of migration %s: %w", name, err)
+	}
+	return nil
+}
+
 // GetFailedMigration returns a failed or pending migration if one exists.
 // A failed migration always blocks. A pending sync migration blocks.
 // A pending async migration does NOT block (it's expected to be in-flight).
 func (c *Client) GetFailedMigration(ctx context.Context) (*AppliedMigration, error) {
 	var m AppliedMigration
 	err := c.db.QueryRowContext(ctx, `
-		SELECT name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async
+		SELECT name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async, heartbeat_at, COALESCE(logical_checksum, ''), current_statement, statement_started_at, job_id, COALESCE(backup_path, '')
 		FROM _scurry_.migrations
 		WHERE status = $1
 		   OR (status = $2 AND async = false)
 		ORDER BY name ASC
 		LIMIT 1
 	`, MigrationStatusFailed, MigrationStatusPending).Scan(
-		&m.Name, &m.Checksum, &m.Status, &m.StartedAt, &m.CompletedAt, &m.AppliedAt, &m.ExecutedBy, &m.FailedStatement, &m.ErrorMsg, &m.Async,
+		&m.Name, &m.Checksum, &m.Status, &m.StartedAt, &m.CompletedAt, &m.AppliedAt, &m.ExecutedBy, &m.FailedStatement, &m.ErrorMsg, &m.Async, &m.HeartbeatAt, &m.LogicalChecksum, &m.CurrentStatement, &m.StatementStartedAt, &m.JobID, &m.BackupPath,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -260,13 +403,13 @@ func (c *Client) CheckDependenciesMet(ctx context.Context, dependsOn []string) (
 func (c *Client) HasRunningAsyncMigration(ctx context.Context) (*AppliedMigration, error) {
 	var m AppliedMigration
 	err := c.db.QueryRowContext(ctx, `
-		SELECT name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async
+		SELECT name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async, heartbeat_at, COALESCE(logical_checksum, ''), current_statement, statement_started_at, job_id, COALESCE(backup_path, '')
 		FROM _scurry_.migrations
 		WHERE async = true AND status = $1
 		ORDER BY name ASC
 		LIMIT 1
 	`, MigrationStatusPending).Scan(
-		&m.Name, &m.Checksum, &m.Status, &m.StartedAt, &m.CompletedAt, &m.AppliedAt, &m.ExecutedBy, &m.FailedStatement, &m.ErrorMsg, &m.Async,
+		&m.Name, &m.Checksum, &m.Status, &m.StartedAt, &m.CompletedAt, &m.AppliedAt, &m.ExecutedBy, &m.FailedStatement, &m.ErrorMsg, &m.Async, &m.HeartbeatAt, &m.LogicalChecksum, &m.CurrentStatement, &m.StatementStartedAt, &m.JobID, &m.BackupPath,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -276,3 +419,101 @@ func (c *Client) HasRunningAsyncMigration(ctx context.Context) (*AppliedMigratio
 	}
 	return &m, nil
 }
+
+// RecordMigrationSQL stores the SQL a migration was executed with, and its
+// logical checksum, so later edits to the migration file can be shown as a
+// diff, or ignored when they only change comments or formatting
+func (c *Client) RecordMigrationSQL(ctx context.Context, name, sql string) error {
+	_, err := c.db.ExecContext(ctx, `
+		UPDATE _scurry_.migrations SET applied_sql = $2, logical_checksum = NULLIF($3, '') WHERE name = $1
+	`, name, sql, LogicalChecksum(sql))
+	if err != nil {
+		return fmt.Errorf("failed to record SQL for migration %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetAppliedMigrationSQL returns the SQL each migration was executed with, for
+// the migrations that recorded it
+func (c *Client) GetAppliedMigrationSQL(ctx context.Context) (map[string]string, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT name, applied_sql
+		FROM _scurry_.migrations
+		WHERE applied_sql IS NOT NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migration SQL: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var name, sql string
+		if err := rows.Scan(&name, &sql); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration SQL: %w", err)
+		}
+		result[name] = sql
+	}
+	return result, rows.Err()
+}
+
+// GetMigration returns the named migration from the history table, or nil if it
+// hasn't been started
+func (c *Client) GetMigration(ctx context.Context, name string) (*AppliedMigration, error) {
+	var m AppliedMigration
+	err := c.db.QueryRowContext(ctx, `
+		SELECT name, checksum, status, started_at, completed_at, applied_at, executed_by, failed_statement, error_msg, async, heartbeat_at, COALESCE(logical_checksum, ''), current_statement, statement_started_at, job_id, COALESCE(backup_path, '')
+		FROM _scurry_.migrations
+		WHERE name = $1
+	`, name).Scan(
+		&m.Name, &m.Checksum, &m.Status, &m.StartedAt, &m.CompletedAt, &m.AppliedAt, &m.ExecutedBy, &m.FailedStatement, &m.ErrorMsg, &m.Async, &m.HeartbeatAt, &m.LogicalChecksum, &m.CurrentStatement, &m.StatementStartedAt, &m.JobID, &m.BackupPath,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration %s: %w", name, err)
+	}
+	return &m, nil
+}
+
+// HeartbeatMigration records that the process running a pending migration is
+// still alive
+func (c *Client) HeartbeatMigration(ctx context.Context, name string) error {
+	_, err := c.db.ExecContext(ctx, `
+		UPDATE _scurry_.migrations
+		SET heartbeat_at = now()
+		WHERE name = $1 AND status = $2
+	`, name, MigrationStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat for migration %s: %w", name, err)
+	}
+	return nil
+}
+
+// WaitForMigration polls the history table until the named migration has
+// finished, returning it once it has succeeded, failed or been recovered.
+// onPoll, if not nil, is called with the migration (nil if not started) each
+// time it is still running.
+func (c *Client) WaitForMigration(ctx context.Context, name string, pollInterval time.Duration, onPoll func(*AppliedMigration)) (*AppliedMigration, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		m, err := c.GetMigration(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil && m.Status != MigrationStatusPending {
+			return m, nil
+		}
+		if onPoll != nil {
+			onPoll(m)
+		}
+
+		select {
+		case <-ctx.Done():
+			return m, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
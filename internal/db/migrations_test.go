@@ -2,8 +2,17 @@ package db
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
 	"sort"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/lib/pq"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -29,6 +38,14 @@ func TestDesiredMigrationsTableSchemaIsValid(t *testing.T) {
 		"failed_statement",
 		"error_msg",
 		"async",
+		"heartbeat_at",
+		"applied_sql",
+		"logical_checksum",
+		"current_statement",
+		"statement_started_at",
+		"job_id",
+		"repeatable",
+		"backup_path",
 	}
 	for _, col := range expectedColumns {
 		assert.Contains(t, statements[0], col, "DesiredMigrationsTableSchema should contain column %q", col)
@@ -55,10 +72,18 @@ func TestGenerateMigrationsTableAlterStatements(t *testing.T) {
 			`,
 			desiredSchema: DesiredMigrationsTableSchema,
 			expectedStatements: []string{
+				`ALTER TABLE _scurry_.migrations ADD COLUMN applied_sql STRING`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN async BOOL NOT NULL DEFAULT false`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN completed_at TIMESTAMPTZ`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN error_msg STRING`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN failed_statement STRING`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN heartbeat_at TIMESTAMPTZ`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN logical_checksum STRING`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN current_statement INT8`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN statement_started_at TIMESTAMPTZ`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN job_id INT8`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN repeatable BOOL NOT NULL DEFAULT false`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN backup_path STRING`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN started_at TIMESTAMPTZ`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN status STRING NOT NULL DEFAULT 'succeeded'`,
 			},
@@ -77,7 +102,15 @@ func TestGenerateMigrationsTableAlterStatements(t *testing.T) {
 					executed_by STRING NOT NULL DEFAULT current_user(),
 					failed_statement STRING,
 					error_msg STRING,
-					async BOOL NOT NULL DEFAULT false
+					async BOOL NOT NULL DEFAULT false,
+					heartbeat_at TIMESTAMPTZ,
+					applied_sql STRING,
+					logical_checksum STRING,
+					current_statement INT,
+					statement_started_at TIMESTAMPTZ,
+					job_id INT8,
+					repeatable BOOL NOT NULL DEFAULT false,
+					backup_path STRING
 				)
 			`,
 			desiredSchema:      DesiredMigrationsTableSchema,
@@ -97,14 +130,38 @@ func TestGenerateMigrationsTableAlterStatements(t *testing.T) {
 			`,
 			desiredSchema: DesiredMigrationsTableSchema,
 			expectedStatements: []string{
+				`ALTER TABLE _scurry_.migrations ADD COLUMN applied_sql STRING`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN async BOOL NOT NULL DEFAULT false`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN completed_at TIMESTAMPTZ`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN error_msg STRING`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN failed_statement STRING`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN heartbeat_at TIMESTAMPTZ`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN logical_checksum STRING`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN current_statement INT8`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN statement_started_at TIMESTAMPTZ`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN job_id INT8`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN repeatable BOOL NOT NULL DEFAULT false`,
+				`ALTER TABLE _scurry_.migrations ADD COLUMN backup_path STRING`,
 				`ALTER TABLE _scurry_.migrations ADD COLUMN started_at TIMESTAMPTZ`,
 			},
 			wantErr: false,
 		},
+		{
+			name: "lock table missing a column",
+			currentSchema: `
+				CREATE TABLE _scurry_.lock (
+					name STRING PRIMARY KEY,
+					owner STRING NOT NULL,
+					token STRING NOT NULL,
+					expires_at TIMESTAMPTZ NOT NULL
+				)
+			`,
+			desiredSchema: LockTableSchema,
+			expectedStatements: []string{
+				`ALTER TABLE _scurry_.lock ADD COLUMN acquired_at TIMESTAMPTZ NOT NULL DEFAULT now()`,
+			},
+			wantErr: false,
+		},
 		{
 			name:          "invalid current schema",
 			currentSchema: "CREATE TABLE (",
@@ -245,6 +302,14 @@ func TestSplitStatements(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "import keeps its file URIs",
+			sql:  "IMPORT INTO users (id, name) CSV DATA ('s3://bucket/users.csv?AWS_ACCESS_KEY_ID=key&AWS_SECRET_ACCESS_KEY=secret')",
+			expected: []string{
+				"IMPORT INTO users(id, name) CSV DATA ('s3://bucket/users.csv?AWS_ACCESS_KEY_ID=key&AWS_SECRET_ACCESS_KEY=secret')",
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -262,6 +327,91 @@ func TestSplitStatements(t *testing.T) {
 	}
 }
 
+func TestLogicalChecksum(t *testing.T) {
+	original := "CREATE TABLE users (id INT PRIMARY KEY);\nCREATE INDEX users_id_idx ON users (id);"
+
+	tests := []struct {
+		name     string
+		sql      string
+		wantSame bool
+	}{
+		{
+			name:     "comments added",
+			sql:      "-- users table\nCREATE TABLE users (id INT PRIMARY KEY); /* lookup */\nCREATE INDEX users_id_idx ON users (id);",
+			wantSame: true,
+		},
+		{
+			name:     "whitespace and keyword case",
+			sql:      "create table users (\n    id int primary key\n);\n\ncreate index users_id_idx on users (id);\n",
+			wantSame: true,
+		},
+		{
+			name:     "statement changed",
+			sql:      "CREATE TABLE users (id INT PRIMARY KEY);\nCREATE INDEX users_id_idx ON users (id DESC);",
+			wantSame: false,
+		},
+		{
+			name:     "statement added",
+			sql:      original + "\nDROP TABLE old_users;",
+			wantSame: false,
+		},
+	}
+
+	want := LogicalChecksum(original)
+	require.NotEmpty(t, want)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantSame, LogicalChecksum(tt.sql) == want)
+		})
+	}
+
+	assert.Empty(t, LogicalChecksum("CREATE TABLE ("), "unparseable SQL has no logical checksum")
+}
+
+func TestAppliedMigrationModified(t *testing.T) {
+	migration := Migration{Name: "001_init", Checksum: "raw-new", LogicalChecksum: "logical"}
+
+	tests := []struct {
+		name        string
+		applied     AppliedMigration
+		wantRaw     bool
+		wantLogical bool
+	}{
+		{
+			name:    "unchanged",
+			applied: AppliedMigration{Checksum: "raw-new", LogicalChecksum: "logical"},
+		},
+		{
+			name:    "marked applied without a checksum",
+			applied: AppliedMigration{},
+		},
+		{
+			name:    "formatting edited",
+			applied: AppliedMigration{Checksum: "raw-old", LogicalChecksum: "logical"},
+			wantRaw: true,
+		},
+		{
+			name:        "statements edited",
+			applied:     AppliedMigration{Checksum: "raw-old", LogicalChecksum: "logical-old"},
+			wantRaw:     true,
+			wantLogical: true,
+		},
+		{
+			name:        "applied before logical checksums were recorded",
+			applied:     AppliedMigration{Checksum: "raw-old"},
+			wantRaw:     true,
+			wantLogical: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantRaw, tt.applied.Modified(migration, false))
+			assert.Equal(t, tt.wantLogical, tt.applied.Modified(migration, true))
+		})
+	}
+}
+
 // Integration tests that use a real database
 
 func TestInitMigrationHistory(t *testing.T) {
@@ -320,12 +470,87 @@ func TestExecuteMigrationWithTracking_Success(t *testing.T) {
 	assert.Nil(t, migrations[0].FailedStatement)
 	assert.Nil(t, migrations[0].ErrorMsg)
 
+	// The SQL it was applied with is kept for migration verify
+	appliedSQL, err := client.GetAppliedMigrationSQL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{migration.Name: migration.SQL}, appliedSQL)
+
 	// Verify no failed migrations
 	failed, err := client.GetFailedMigration(ctx)
 	require.NoError(t, err)
 	assert.Nil(t, failed)
 }
 
+func TestExecuteMigrationWithTracking_Settings(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+	// One connection, so the check below sees the one the migration used
+	client.db.SetMaxOpenConns(1)
+
+	err = client.InitMigrationHistory(ctx)
+	require.NoError(t, err)
+
+	migration := Migration{
+		Name:     "20240101120000_slow",
+		SQL:      "SELECT pg_sleep(1)",
+		Checksum: "abc123",
+		Settings: []SessionSetting{{Name: "statement_timeout", Value: "10ms"}},
+	}
+	err = client.ExecuteMigrationWithTracking(ctx, migration)
+	require.Error(t, err, "the migration's statement_timeout should cancel the statement")
+
+	var timeout string
+	require.NoError(t, client.db.QueryRowContext(ctx, "SHOW statement_timeout").Scan(&timeout))
+	assert.Equal(t, "0", timeout, "settings should be reset after the migration")
+}
+
+func TestExecuteMigrationWithTracking_Interrupted(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.InitMigrationHistory(ctx)
+	require.NoError(t, err)
+
+	migration := Migration{
+		Name:     "20240101120000_interrupted",
+		SQL:      "CREATE TABLE interrupted (id INT PRIMARY KEY);\nSELECT pg_sleep(10);\nCREATE TABLE resumed (id INT PRIMARY KEY);",
+		Checksum: "abc123",
+	}
+
+	// Cancel while the second statement runs, as SIGINT does
+	runCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	err = client.ExecuteMigrationWithTracking(runCtx, migration)
+	require.ErrorContains(t, err, "migration interrupted")
+
+	failed, err := client.GetFailedMigration(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, failed)
+	assert.Equal(t, MigrationStatusFailed, failed.Status)
+	require.NotNil(t, failed.CurrentStatement)
+	assert.Equal(t, 1, *failed.CurrentStatement)
+	assert.NotNil(t, failed.StatementStartedAt)
+	require.NotNil(t, failed.ErrorMsg)
+	assert.True(t, strings.HasPrefix(*failed.ErrorMsg, InterruptedPrefix))
+
+	// Resuming after the interrupted statement runs only the rest
+	require.NoError(t, client.ResumeMigration(ctx, migration, 2))
+	failed, err = client.GetFailedMigration(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, failed)
+
+	var count int
+	require.NoError(t, client.db.QueryRowContext(ctx, "SELECT count(*) FROM resumed").Scan(&count))
+}
+
 func TestExecuteMigrationWithTracking_Failure(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -1021,3 +1246,159 @@ func TestSchemaUpgradeAddsAsyncColumn(t *testing.T) {
 	require.NotNil(t, found)
 	assert.True(t, found.Async)
 }
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pq.Error{Code: "40001", Message: "restart transaction"}, true},
+		{"wrapped serialization failure", fmt.Errorf("exec: %w", &pq.Error{Code: "40001"}), true},
+		{"transaction retry", &pq.Error{Code: "CR000"}, true},
+		{"unable to connect", &pq.Error{Code: "08001"}, true},
+		{"connection refused", fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED), true},
+		{"bad connection", driver.ErrBadConn, true},
+		// These may have been applied, so they are failures
+		{"connection failure mid-statement", &pq.Error{Code: "08006"}, false},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, false},
+		{"ambiguous result", &pq.Error{Code: "40003", Message: "result is ambiguous"}, false},
+		{"unexpected EOF", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), false},
+		{"connection reset", fmt.Errorf("read tcp: %w", syscall.ECONNRESET), false},
+		{"broken pipe", fmt.Errorf("write tcp: %w", syscall.EPIPE), false},
+		{"syntax error", &pq.Error{Code: "42601", Message: "syntax error"}, false},
+		{"undefined table", &pq.Error{Code: "42P01", Message: `relation "users" does not exist`}, false},
+		{"plain error", errors.New("something went wrong"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryableError(tt.err))
+		})
+	}
+}
+
+func TestWithDetail(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"plain error", errors.New("something went wrong"), "something went wrong"},
+		{"no detail or hint", &pq.Error{Code: "42P01", Message: `relation "users" does not exist`}, `pq: relation "users" does not exist`},
+		{
+			name: "detail and hint",
+			err: fmt.Errorf("exec: %w", &pq.Error{
+				Code:    "23505",
+				Message: `could not create unique constraint "users_email_key"`,
+				Detail:  "Key (email)=('a@example.com') is duplicated.",
+				Hint:    "Remove the duplicate values before adding the constraint.",
+			}),
+			want: "exec: pq: could not create unique constraint \"users_email_key\"\n" +
+				"DETAIL: Key (email)=('a@example.com') is duplicated.\n" +
+				"HINT: Remove the duplicate values before adding the constraint.",
+		},
+		{
+			name: "hint only",
+			err:  &pq.Error{Code: "0A000", Message: "unimplemented: column type change", Hint: "See: https://go.crdb.dev/issue-v/49329/v24.1"},
+			want: "pq: unimplemented: column type change\nHINT: See: https://go.crdb.dev/issue-v/49329/v24.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WithDetail(tt.err)
+			assert.EqualError(t, err, tt.want)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxRetries:     5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, 800*time.Millisecond, policy.backoff(4))
+	assert.Equal(t, time.Second, policy.backoff(5))
+	assert.Equal(t, time.Second, policy.backoff(10))
+}
+
+func TestHeartbeatMigration(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.InitMigrationHistory(ctx))
+
+	m, err := client.GetMigration(ctx, "001_async")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	require.NoError(t, client.StartMigration(ctx, "001_async", "x", true))
+	m, err = client.GetMigration(ctx, "001_async")
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	require.NotNil(t, m.HeartbeatAt)
+	started := *m.HeartbeatAt
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, client.HeartbeatMigration(ctx, "001_async"))
+	m, err = client.GetMigration(ctx, "001_async")
+	require.NoError(t, err)
+	assert.True(t, m.HeartbeatAt.After(started))
+
+	// Finished migrations are left alone
+	require.NoError(t, client.CompleteMigration(ctx, "001_async"))
+	beat := *m.HeartbeatAt
+	require.NoError(t, client.HeartbeatMigration(ctx, "001_async"))
+	m, err = client.GetMigration(ctx, "001_async")
+	require.NoError(t, err)
+	assert.Equal(t, beat, *m.HeartbeatAt)
+}
+
+func TestBaselineMigrations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.InitMigrationHistory(ctx))
+
+	// Already applied migrations are left alone
+	require.NoError(t, client.RecordMigration(ctx, "001_a", "a", false))
+
+	recorded, err := client.BaselineMigrations(ctx, []Migration{
+		{Name: "001_a", SQL: "CREATE TABLE a (id INT PRIMARY KEY)", Checksum: "a"},
+		{Name: "002_b", SQL: "CREATE TABLE b (id INT PRIMARY KEY)", Checksum: "b"},
+		{Name: "003_c", SQL: "CREATE INDEX ON b (id)", Checksum: "c", Mode: MigrationModeAsync},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, recorded)
+
+	applied, err := client.GetAppliedMigrations(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+	assert.NotEqual(t, BaselineExecutedBy, applied[0].ExecutedBy)
+	for _, m := range applied[1:] {
+		assert.Equal(t, MigrationStatusSucceeded, m.Status)
+		assert.Equal(t, BaselineExecutedBy, m.ExecutedBy)
+	}
+	assert.False(t, applied[1].Async)
+	assert.True(t, applied[2].Async)
+
+	// The tables were never created
+	var n int
+	err = client.db.QueryRowContext(ctx, `SELECT count(*) FROM information_schema.tables WHERE table_name IN ('a', 'b')`).Scan(&n)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
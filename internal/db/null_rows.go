@@ -0,0 +1,21 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// CountNullRows counts the rows of a table where column is NULL. It scans the
+// table unless an index leads with the column.
+func (c *Client) CountNullRows(ctx context.Context, schemaName, tableName, column string) (int64, error) {
+	table := tree.NameString(schemaName) + "." + tree.NameString(tableName)
+
+	var rows int64
+	query := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s IS NULL", table, tree.NameString(column))
+	if err := c.db.QueryRowContext(ctx, query).Scan(&rows); err != nil {
+		return 0, fmt.Errorf("failed to count NULL rows of %s.%s: %w", table, column, err)
+	}
+	return rows, nil
+}
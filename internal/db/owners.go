@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetOwners reads the owner of every table, view and sequence outside the
+// system schemas and the _scurry_ schema, by "schema.name"
+func (c *Client) GetOwners(ctx context.Context) (map[string]string, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT n.nspname, c.relname, pg_catalog.pg_get_userbyid(c.relowner)
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'v', 'm', 'S')
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema', 'crdb_internal', 'pg_extension', '_scurry_')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := make(map[string]string)
+	for rows.Next() {
+		var schemaName, name, owner string
+		if err := rows.Scan(&schemaName, &name, &owner); err != nil {
+			return nil, fmt.Errorf("failed to scan object owner: %w", err)
+		}
+		owners[schemaName+"."+name] = owner
+	}
+	return owners, rows.Err()
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PushProgressTableSchema is embedded from schema/push_progress_table.sql
+//
+//go:embed schema/push_progress_table.sql
+var PushProgressTableSchema string
+
+// PushProgress is how far a push got through its statements
+type PushProgress struct {
+	// Statements are the statements being applied, including the BEGIN and
+	// COMMIT statements marking their transactions
+	Statements []string
+	// Applied is how many of the statements were applied, not counting BEGIN
+	// and COMMIT. Statements in a transaction count once it commits.
+	Applied   int
+	Error     string // Why the statement after the applied ones failed, if it did
+	StartedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Total returns the number of statements, not counting BEGIN and COMMIT
+func (p *PushProgress) Total() int {
+	total := 0
+	for _, stmt := range p.Statements {
+		if begin, commit := transactionMarker(stmt); !begin && !commit {
+			total++
+		}
+	}
+	return total
+}
+
+// Remaining returns the statements that weren't applied, for
+// ExecuteDDLWithProgress. When they start in the middle of a run of
+// statements outside of a transaction, a COMMIT is added in front so they
+// still run outside of one.
+func (p *PushProgress) Remaining() []string {
+	applied := 0
+	nonTransactional := false
+	for i := 0; i < len(p.Statements); i++ {
+		begin, commit := transactionMarker(p.Statements[i])
+		switch {
+		case commit:
+			if i+1 < len(p.Statements) {
+				if nextBegin, _ := transactionMarker(p.Statements[i+1]); nextBegin {
+					i++
+					nonTransactional = false
+					continue
+				}
+			}
+			nonTransactional = true
+		case begin:
+			nonTransactional = false
+		default:
+			if applied == p.Applied {
+				if nonTransactional {
+					return append([]string{"COMMIT"}, p.Statements[i:]...)
+				}
+				return p.Statements[i:]
+			}
+			applied++
+		}
+	}
+	return nil
+}
+
+// transactionMarker reports whether stmt is a BEGIN or a COMMIT, which
+// chunkStatementsByTransaction turns into transaction boundaries
+func transactionMarker(stmt string) (begin, commit bool) {
+	normalized := strings.ToUpper(strings.TrimSpace(stmt))
+	return normalized == "BEGIN" || normalized == "BEGIN TRANSACTION",
+		normalized == "COMMIT" || normalized == "COMMIT TRANSACTION"
+}
+
+// StartPushProgress records that a push is about to apply statements,
+// replacing the progress of any earlier push
+func (c *Client) StartPushProgress(ctx context.Context, statements []string) error {
+	if err := c.initInternalTable(ctx, "push_progress", PushProgressTableSchema); err != nil {
+		return err
+	}
+	if _, err := c.db.ExecContext(ctx, `
+		UPSERT INTO _scurry_.push_progress (id, statements, applied, error, started_at, updated_at)
+		VALUES (1, $1, 0, '', now(), now())
+	`, pq.Array(statements)); err != nil {
+		return fmt.Errorf("failed to record push progress: %w", err)
+	}
+	return nil
+}
+
+// RecordPushProgress records that the first applied statements of the push
+// were applied
+func (c *Client) RecordPushProgress(ctx context.Context, applied int) error {
+	if _, err := c.db.ExecContext(ctx, `
+		UPDATE _scurry_.push_progress SET applied = $1, updated_at = now() WHERE id = 1
+	`, applied); err != nil {
+		return fmt.Errorf("failed to record push progress: %w", err)
+	}
+	return nil
+}
+
+// FailPushProgress records why the push stopped
+func (c *Client) FailPushProgress(ctx context.Context, pushErr error) error {
+	if _, err := c.db.ExecContext(ctx, `
+		UPDATE _scurry_.push_progress SET error = $1, updated_at = now() WHERE id = 1
+	`, pushErr.Error()); err != nil {
+		return fmt.Errorf("failed to record push progress: %w", err)
+	}
+	return nil
+}
+
+// ClearPushProgress removes the progress of the push once it's done
+func (c *Client) ClearPushProgress(ctx context.Context) error {
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM _scurry_.push_progress WHERE id = 1`); err != nil {
+		return fmt.Errorf("failed to clear push progress: %w", err)
+	}
+	return nil
+}
+
+// GetPushProgress returns the progress of the push that didn't finish, or nil
+// if there is none
+func (c *Client) GetPushProgress(ctx context.Context) (*PushProgress, error) {
+	var exists bool
+	if err := c.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.tables
+			WHERE table_schema = '_scurry_' AND table_name = 'push_progress'
+		)
+	`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check for push progress: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT statements, applied, error, started_at, updated_at FROM _scurry_.push_progress WHERE id = 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query push progress: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var p PushProgress
+	if err := rows.Scan(pq.Array(&p.Statements), &p.Applied, &p.Error, &p.StartedAt, &p.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan push progress: %w", err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushProgressRemaining(t *testing.T) {
+	statements := []string{
+		"BEGIN",
+		"CREATE TABLE a (id INT8 PRIMARY KEY)",
+		"CREATE TABLE b (id INT8 PRIMARY KEY)",
+		"COMMIT",
+		"CREATE INDEX a_idx ON a (id)",
+		"CREATE INDEX b_idx ON b (id)",
+		"BEGIN",
+		"ALTER TABLE a ADD COLUMN c INT8",
+		"COMMIT",
+		"BEGIN",
+		"ALTER TABLE b ADD COLUMN c INT8",
+		"COMMIT",
+	}
+
+	tests := []struct {
+		name     string
+		applied  int
+		expected []string
+	}{
+		{
+			name:     "nothing applied",
+			applied:  0,
+			expected: statements[1:],
+		},
+		{
+			name:     "first transaction applied",
+			applied:  2,
+			expected: []string{"COMMIT", "CREATE INDEX a_idx ON a (id)", "CREATE INDEX b_idx ON b (id)", "BEGIN", "ALTER TABLE a ADD COLUMN c INT8", "COMMIT", "BEGIN", "ALTER TABLE b ADD COLUMN c INT8", "COMMIT"},
+		},
+		{
+			name:     "within statements outside of a transaction",
+			applied:  3,
+			expected: []string{"COMMIT", "CREATE INDEX b_idx ON b (id)", "BEGIN", "ALTER TABLE a ADD COLUMN c INT8", "COMMIT", "BEGIN", "ALTER TABLE b ADD COLUMN c INT8", "COMMIT"},
+		},
+		{
+			name:     "after a COMMIT and BEGIN pair",
+			applied:  5,
+			expected: []string{"ALTER TABLE b ADD COLUMN c INT8", "COMMIT"},
+		},
+		{
+			name:     "everything applied",
+			applied:  6,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PushProgress{Statements: statements, Applied: tt.applied}
+			assert.Equal(t, 6, p.Total())
+			assert.Equal(t, tt.expected, p.Remaining())
+		})
+	}
+}
+
+func TestPushProgressRemainingKeepsTransactions(t *testing.T) {
+	statements := []string{
+		"BEGIN",
+		"CREATE TABLE a (id INT8 PRIMARY KEY)",
+		"COMMIT",
+		"CREATE INDEX a_idx ON a (id)",
+		"CREATE INDEX a_idx2 ON a (id)",
+		"BEGIN",
+		"ALTER TABLE a ADD COLUMN c INT8",
+		"COMMIT",
+	}
+	p := &PushProgress{Statements: statements, Applied: 2}
+
+	// The last index still runs outside of a transaction, then the ALTER in one
+	assert.Equal(t, [][]string{nil, {"CREATE INDEX a_idx2 ON a (id)"}, {"ALTER TABLE a ADD COLUMN c INT8"}},
+		chunkStatementsByTransaction(p.Remaining(), 50))
+}
+
+func TestPushProgress(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+
+	progress, err := client.GetPushProgress(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, progress)
+
+	statements := []string{"CREATE TABLE a (id INT8 PRIMARY KEY)", "CREATE TABLE b (id INT8 PRIMARY KEY)"}
+	require.NoError(t, client.StartPushProgress(ctx, statements))
+	require.NoError(t, client.RecordPushProgress(ctx, 1))
+	require.NoError(t, client.FailPushProgress(ctx, errors.New("relation \"b\" already exists")))
+
+	progress, err = client.GetPushProgress(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, progress)
+	assert.Equal(t, statements, progress.Statements)
+	assert.Equal(t, 1, progress.Applied)
+	assert.Equal(t, "relation \"b\" already exists", progress.Error)
+	assert.Equal(t, statements[1:], progress.Remaining())
+
+	// A new push starts over
+	require.NoError(t, client.StartPushProgress(ctx, statements[:1]))
+	progress, err = client.GetPushProgress(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, progress.Applied)
+	assert.Empty(t, progress.Error)
+
+	require.NoError(t, client.ClearPushProgress(ctx))
+	progress, err = client.GetPushProgress(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, progress)
+}
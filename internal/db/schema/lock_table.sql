@@ -0,0 +1,11 @@
+-- Schema for the _scurry_.lock table
+-- A row is held by the scurry process running migrations against the database,
+-- and expires unless the holder keeps extending it
+
+CREATE TABLE _scurry_.lock (
+    name STRING PRIMARY KEY,
+    owner STRING NOT NULL,
+    token STRING NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
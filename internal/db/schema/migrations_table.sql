@@ -11,5 +11,13 @@ CREATE TABLE _scurry_.migrations (
     executed_by STRING NOT NULL DEFAULT current_user(),
     failed_statement STRING,
     error_msg STRING,
-    async BOOL NOT NULL DEFAULT false
+    async BOOL NOT NULL DEFAULT false,
+    heartbeat_at TIMESTAMPTZ,
+    applied_sql STRING,
+    logical_checksum STRING,
+    current_statement INT,
+    statement_started_at TIMESTAMPTZ,
+    job_id INT8,
+    repeatable BOOL NOT NULL DEFAULT false,
+    backup_path STRING
 );
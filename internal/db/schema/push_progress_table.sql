@@ -0,0 +1,13 @@
+-- Schema for the _scurry_.push_progress table
+-- Holds the statements of a push that didn't finish and how many of them were
+-- applied, so 'scurry push --resume' can continue from the one that failed.
+-- There's at most one row, removed once the push completes.
+
+CREATE TABLE _scurry_.push_progress (
+    id INT8 PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    statements STRING[] NOT NULL,
+    applied INT8 NOT NULL DEFAULT 0,
+    error STRING NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
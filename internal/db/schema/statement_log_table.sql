@@ -0,0 +1,21 @@
+-- Schema for the _scurry_.statement_log table
+-- Every statement scurry executes against the database (by push or a migration)
+-- is appended here. Each row's hash covers the row and the previous row's hash,
+-- so editing or deleting a row breaks the chain. The hashes aren't keyed, so a
+-- rewritten chain is only caught against a head hash kept outside the database
+-- ('scurry audit --verify --anchor').
+
+CREATE TABLE _scurry_.statement_log (
+    seq INT8 PRIMARY KEY,
+    executed_at TIMESTAMPTZ NOT NULL,
+    statement STRING NOT NULL,
+    migration STRING NOT NULL DEFAULT '',
+    source STRING NOT NULL,
+    duration_ms INT8 NOT NULL,
+    rows_affected INT8 NOT NULL,
+    executor STRING NOT NULL,
+    host STRING NOT NULL,
+    app_version STRING NOT NULL,
+    prev_hash STRING NOT NULL,
+    hash STRING NOT NULL
+);
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// IndexSizeInfo is the approximate size on disk of one index
+type IndexSizeInfo struct {
+	SchemaName string `json:"schema"`
+	TableName  string `json:"table"`
+	IndexName  string `json:"index"`
+	Bytes      int64  `json:"bytes"`
+}
+
+// RangeInfo is one range of the current database and the tables with data in it
+type RangeInfo struct {
+	RangeID int64    `json:"range_id"`
+	Bytes   int64    `json:"bytes"`
+	Tables  []string `json:"tables"` // "schema.table"
+}
+
+// GetIndexSizes returns the approximate size on disk of each index in the
+// current database, from the statistics of the ranges holding it. A range
+// shared by several indexes counts toward each of them.
+func (c *Client) GetIndexSizes(ctx context.Context) ([]IndexSizeInfo, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT schema_name, table_name, index_name, sum(range_size)::INT8
+		FROM [SHOW RANGES FROM CURRENT_CATALOG WITH INDEXES, DETAILS]
+		WHERE index_name IS NOT NULL
+			AND schema_name NOT IN ('pg_catalog', 'information_schema', 'crdb_internal', '_scurry_')
+		GROUP BY schema_name, table_name, index_name
+		ORDER BY schema_name, table_name, index_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index sizes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []IndexSizeInfo
+	for rows.Next() {
+		var idx IndexSizeInfo
+		if err := rows.Scan(&idx.SchemaName, &idx.TableName, &idx.IndexName, &idx.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan index size: %w", err)
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, rows.Err()
+}
+
+// GetLargestRanges returns the limit largest ranges holding data of the
+// current database, largest first
+func (c *Client) GetLargestRanges(ctx context.Context, limit int) ([]RangeInfo, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT range_id, max(range_size)::INT8, string_agg(DISTINCT schema_name || '.' || table_name, ',')
+		FROM [SHOW RANGES FROM CURRENT_CATALOG WITH TABLES, DETAILS]
+		WHERE table_name IS NOT NULL
+			AND schema_name NOT IN ('pg_catalog', 'information_schema', 'crdb_internal', '_scurry_')
+		GROUP BY range_id
+		ORDER BY 2 DESC, range_id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ranges: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []RangeInfo
+	for rows.Next() {
+		var r RangeInfo
+		var tables string
+		if err := rows.Scan(&r.RangeID, &r.Bytes, &tables); err != nil {
+			return nil, fmt.Errorf("failed to scan range: %w", err)
+		}
+		r.Tables = strings.Split(tables, ",")
+		ranges = append(ranges, r)
+	}
+	return ranges, rows.Err()
+}
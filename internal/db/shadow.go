@@ -5,8 +5,10 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"net/url"
 	"os"
+	"slices"
 	"sync"
 
 	"github.com/cockroachdb/cockroach-go/v2/testserver"
@@ -21,6 +23,10 @@ var (
 
 	CrdbVersion string
 
+	// ShadowSettings are session settings every shadow database connection
+	// starts with, such as default_int_size for definitions written for Postgres
+	ShadowSettings map[string]string
+
 	// Optional host and port for test server
 	TestServerHost     string
 	TestServerPort     int
@@ -59,7 +65,8 @@ func getShadowDbClient(ctx context.Context) (*Client, error) {
 	defer shadowServerMu.Unlock()
 
 	// Create test server if it doesn't exist
-	if sharedDbServer == nil {
+	newServer := sharedDbServer == nil
+	if newServer {
 		// Ensure crdbVersion is set
 		//
 		// // Hide log output from cockroachdb testserver package
@@ -126,14 +133,20 @@ func getShadowDbClient(ctx context.Context) (*Client, error) {
 	urlClone, _ := url.Parse(shadowServerURL.String())
 	urlClone.Path = fmt.Sprintf("/%s", dbName)
 
-	// Connect will make sure the database exists
-	client, err := Connect(ctx, urlClone.String())
+	// connect will make sure the database exists
+	client, err := connect(ctx, urlClone.String(), shadowConnectOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to test server: %w", err)
 	}
 	client.isShadow = true
 	client.disableAutocommitDDL = true
 
+	// Vector indexes are behind a cluster setting, so enable it once on a new
+	// server so definitions using them can be loaded.
+	if newServer {
+		_, _ = client.db.ExecContext(ctx, "SET CLUSTER SETTING feature.vector_index.enabled = true")
+	}
+
 	// Shadow databases are ephemeral and don't benefit from schema_locked.
 	// Disable it so tables can be freely modified without unlock overhead.
 	_, _ = client.db.ExecContext(ctx, "SET create_table_with_schema_locked = false")
@@ -145,6 +158,23 @@ func getShadowDbClient(ctx context.Context) (*Client, error) {
 	return client, nil
 }
 
+// shadowConnectOptions are the options of every shadow database connection.
+// The shadow server is local and scratch, so the TLS, cluster, read-only and
+// session options of ConnectionOptions, which are for the target database,
+// are left out; only ShadowSettings are set, sorted so the URL is stable.
+func shadowConnectOptions() ConnectOptions {
+	// UNIQUE WITHOUT INDEX constraints are behind a session setting, which has
+	// to be on for every connection so definitions using them can be loaded
+	settings := map[string]string{"experimental_enable_unique_without_index_constraints": "true"}
+	maps.Copy(settings, ShadowSettings)
+
+	var options ConnectOptions
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		options.SessionSettings = append(options.SessionSettings, SessionSetting{Name: name, Value: settings[name]})
+	}
+	return options
+}
+
 func StopShadowDbServer() {
 	shadowServerMu.Lock()
 	defer shadowServerMu.Unlock()
@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/cockroach-go/v2/crdb"
+)
+
+// StatementLogTableSchema is embedded from schema/statement_log_table.sql
+//
+//go:embed schema/statement_log_table.sql
+var StatementLogTableSchema string
+
+// Sources of statement log entries
+const (
+	StatementSourcePush      = "push"
+	StatementSourceMigration = "migration"
+)
+
+// AppVersion is the scurry version recorded with each statement
+var AppVersion = "dev"
+
+// OnStatementLogError is called when a statement ran but couldn't be recorded
+// in the statement log. The statement already took effect, so this doesn't
+// fail the push or migration.
+var OnStatementLogError = func(err error) {}
+
+// StatementLogEntry is a row of the _scurry_.statement_log table
+type StatementLogEntry struct {
+	Seq          int64     `json:"seq"`
+	ExecutedAt   time.Time `json:"executed_at"`
+	Statement    string    `json:"statement"`
+	Migration    string    `json:"migration,omitempty"`
+	Source       string    `json:"source"`
+	DurationMs   int64     `json:"duration_ms"`
+	RowsAffected int64     `json:"rows_affected"`
+	Executor     string    `json:"executor"`
+	Host         string    `json:"host"`
+	AppVersion   string    `json:"app_version"`
+	PrevHash     string    `json:"prev_hash"`
+	Hash         string    `json:"hash"`
+}
+
+// computeHash returns the hash of the entry's fields and the previous entry's
+// hash, which the entry's Hash must match
+func (e StatementLogEntry) computeHash() string {
+	e.Hash = ""
+	e.ExecutedAt = e.ExecutedAt.UTC()
+	// Marshaling a struct of strings, ints and a time can't fail
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// StatementLogFilter narrows the entries StatementLog returns
+type StatementLogFilter struct {
+	// Migration only returns the statements of the named migration
+	Migration string
+	// Since only returns statements executed at or after it
+	Since time.Time
+	// Limit returns the latest Limit entries, or all of them if 0
+	Limit int
+}
+
+// initStatementLog creates or upgrades the statement log table, once per
+// client
+func (c *Client) initStatementLog(ctx context.Context) error {
+	if c.statementLogReady {
+		return nil
+	}
+	if err := c.initInternalTable(ctx, "statement_log", StatementLogTableSchema); err != nil {
+		return err
+	}
+	c.statementLogReady = true
+	return nil
+}
+
+// logStatement appends an executed statement to the statement log, chained to
+// the latest entry. Failures go to OnStatementLogError. Statements run on
+// shadow databases aren't logged.
+func (c *Client) logStatement(ctx context.Context, entry StatementLogEntry) {
+	if c.isShadow {
+		return
+	}
+	if err := c.appendStatementLog(context.WithoutCancel(ctx), entry); err != nil {
+		OnStatementLogError(fmt.Errorf("failed to record statement in the statement log: %w", err))
+	}
+}
+
+func (c *Client) appendStatementLog(ctx context.Context, entry StatementLogEntry) error {
+	if err := c.initStatementLog(ctx); err != nil {
+		return err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	entry.Host = host
+	entry.AppVersion = AppVersion
+	entry.ExecutedAt = entry.ExecutedAt.UTC().Truncate(time.Microsecond)
+
+	return crdb.ExecuteTx(ctx, c.db, &sql.TxOptions{}, func(tx *sql.Tx) error {
+		var prevSeq int64
+		var prevHash string
+		err := tx.QueryRowContext(ctx, `
+			SELECT current_user(),
+				COALESCE((SELECT max(seq) FROM _scurry_.statement_log), 0),
+				COALESCE((SELECT hash FROM _scurry_.statement_log ORDER BY seq DESC LIMIT 1), '')
+		`).Scan(&entry.Executor, &prevSeq, &prevHash)
+		if err != nil {
+			return err
+		}
+		entry.Seq = prevSeq + 1
+		entry.PrevHash = prevHash
+		entry.Hash = entry.computeHash()
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO _scurry_.statement_log (seq, executed_at, statement, migration, source, duration_ms, rows_affected, executor, host, app_version, prev_hash, hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, entry.Seq, entry.ExecutedAt, entry.Statement, entry.Migration, entry.Source, entry.DurationMs, entry.RowsAffected,
+			entry.Executor, entry.Host, entry.AppVersion, entry.PrevHash, entry.Hash)
+		return err
+	})
+}
+
+// StatementLog returns the statement log entries matching filter, oldest
+// first. Returns nil if nothing has been logged yet.
+func (c *Client) StatementLog(ctx context.Context, filter StatementLogFilter) ([]StatementLogEntry, error) {
+	var exists bool
+	if err := c.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.tables
+			WHERE table_schema = '_scurry_' AND table_name = 'statement_log'
+		)
+	`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check for the statement log: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	query := `
+		SELECT seq, executed_at, statement, migration, source, duration_ms, rows_affected, executor, host, app_version, prev_hash, hash
+		FROM _scurry_.statement_log
+		WHERE ($1 = '' OR migration = $1) AND executed_at >= $2
+		ORDER BY seq DESC`
+	args := []any{filter.Migration, filter.Since}
+	if filter.Limit > 0 {
+		query += ` LIMIT $3`
+		args = append(args, filter.Limit)
+	}
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the statement log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []StatementLogEntry
+	for rows.Next() {
+		var e StatementLogEntry
+		if err := rows.Scan(&e.Seq, &e.ExecutedAt, &e.Statement, &e.Migration, &e.Source, &e.DurationMs, &e.RowsAffected,
+			&e.Executor, &e.Host, &e.AppVersion, &e.PrevHash, &e.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan the statement log: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// VerifyStatementLog checks the hash chain of the whole statement log, oldest
+// entry first: sequence numbers start at 1 with no gaps, each entry points at
+// the previous entry's hash, and each hash matches its entry. The hashes aren't
+// keyed, so a writer who recomputes every hash after its change is only caught
+// by CheckStatementLogAnchor.
+func VerifyStatementLog(entries []StatementLogEntry) error {
+	prevHash := ""
+	for i, e := range entries {
+		if e.Seq != int64(i+1) {
+			return fmt.Errorf("statement log entry %d is missing", i+1)
+		}
+		if e.PrevHash != prevHash {
+			return fmt.Errorf("statement log entry %d doesn't follow entry %d", e.Seq, e.Seq-1)
+		}
+		if e.Hash != e.computeHash() {
+			return fmt.Errorf("statement log entry %d was modified", e.Seq)
+		}
+		prevHash = e.Hash
+	}
+	return nil
+}
+
+// CheckStatementLogAnchor checks that the log still has the entry with the
+// anchored hash, a head hash recorded outside the database by an earlier
+// verification. The hashes aren't keyed, but with the chain intact an entry
+// can only keep its hash if it and every entry before it are unchanged.
+func CheckStatementLogAnchor(entries []StatementLogEntry, anchor string) error {
+	for _, e := range entries {
+		if e.Hash == anchor {
+			return nil
+		}
+	}
+	return fmt.Errorf("no statement log entry has the anchored hash %s, so entries up to it were modified or deleted", anchor)
+}
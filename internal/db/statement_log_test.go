@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyStatementLog(t *testing.T) {
+	chain := func() []StatementLogEntry {
+		executedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		entries := []StatementLogEntry{
+			{Seq: 1, ExecutedAt: executedAt, Statement: "CREATE TABLE a (id INT PRIMARY KEY)", Source: StatementSourcePush, Executor: "root"},
+			{Seq: 2, ExecutedAt: executedAt.Add(time.Minute), Statement: "CREATE INDEX ON a (id)", Migration: "001_a", Source: StatementSourceMigration, Executor: "root"},
+			{Seq: 3, ExecutedAt: executedAt.Add(2 * time.Minute), Statement: "UPDATE a SET id = id", Migration: "001_a", Source: StatementSourceMigration, RowsAffected: 3, Executor: "root"},
+		}
+		prevHash := ""
+		for i := range entries {
+			entries[i].PrevHash = prevHash
+			entries[i].Hash = entries[i].computeHash()
+			prevHash = entries[i].Hash
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func([]StatementLogEntry) []StatementLogEntry
+		wantErr string
+	}{
+		{
+			name:   "intact",
+			tamper: func(e []StatementLogEntry) []StatementLogEntry { return e },
+		},
+		{
+			name:   "empty",
+			tamper: func(e []StatementLogEntry) []StatementLogEntry { return nil },
+		},
+		{
+			name: "edited statement",
+			tamper: func(e []StatementLogEntry) []StatementLogEntry {
+				e[1].Statement = "SELECT 1"
+				return e
+			},
+			wantErr: "statement log entry 2 was modified",
+		},
+		{
+			name: "deleted entry",
+			tamper: func(e []StatementLogEntry) []StatementLogEntry {
+				return append(e[:1], e[2:]...)
+			},
+			wantErr: "statement log entry 2 is missing",
+		},
+		{
+			name: "rewritten chain",
+			tamper: func(e []StatementLogEntry) []StatementLogEntry {
+				e[2].PrevHash = "forged"
+				e[2].Hash = e[2].computeHash()
+				return e
+			},
+			wantErr: "statement log entry 3 doesn't follow entry 2",
+		},
+		{
+			name: "time zone doesn't matter",
+			tamper: func(e []StatementLogEntry) []StatementLogEntry {
+				e[0].ExecutedAt = e[0].ExecutedAt.In(time.FixedZone("UTC-7", -7*60*60))
+				return e
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyStatementLog(tt.tamper(chain()))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckStatementLogAnchor(t *testing.T) {
+	executedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	chain := func() []StatementLogEntry {
+		entries := []StatementLogEntry{
+			{Seq: 1, ExecutedAt: executedAt, Statement: "CREATE TABLE a (id INT PRIMARY KEY)", Source: StatementSourcePush, Executor: "root"},
+			{Seq: 2, ExecutedAt: executedAt.Add(time.Minute), Statement: "CREATE INDEX ON a (id)", Source: StatementSourcePush, Executor: "root"},
+		}
+		prevHash := ""
+		for i := range entries {
+			entries[i].PrevHash = prevHash
+			entries[i].Hash = entries[i].computeHash()
+			prevHash = entries[i].Hash
+		}
+		return entries
+	}
+	anchor := chain()[1].Hash
+
+	tests := []struct {
+		name    string
+		entries func() []StatementLogEntry
+		wantErr string
+	}{
+		{
+			name:    "anchor is the head",
+			entries: chain,
+		},
+		{
+			name: "entries added after the anchor",
+			entries: func() []StatementLogEntry {
+				e := chain()
+				next := StatementLogEntry{Seq: 3, ExecutedAt: executedAt.Add(2 * time.Minute), Statement: "DROP INDEX a@a_id_idx", Source: StatementSourcePush, Executor: "root", PrevHash: e[1].Hash}
+				next.Hash = next.computeHash()
+				return append(e, next)
+			},
+		},
+		{
+			// Rewriting an entry and recomputing the chain passes VerifyStatementLog,
+			// but not the anchor
+			name: "chain recomputed after an edit",
+			entries: func() []StatementLogEntry {
+				e := chain()
+				e[0].Statement = "SELECT 1"
+				prevHash := ""
+				for i := range e {
+					e[i].PrevHash = prevHash
+					e[i].Hash = e[i].computeHash()
+					prevHash = e[i].Hash
+				}
+				return e
+			},
+			wantErr: "no statement log entry has the anchored hash " + anchor,
+		},
+		{
+			name:    "anchored entry deleted",
+			entries: func() []StatementLogEntry { return chain()[:1] },
+			wantErr: "entries up to it were modified or deleted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := tt.entries()
+			require.NoError(t, VerifyStatementLog(entries))
+			err := CheckStatementLogAnchor(entries, anchor)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStatementLog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := GetShadowDB(ctx)
+	require.NoError(t, err)
+	defer client.Close()
+	// Statements on shadow databases aren't logged, so treat this one as a target
+	client.isShadow = false
+
+	entries, err := client.StatementLog(ctx, StatementLogFilter{})
+	require.NoError(t, err)
+	assert.Nil(t, entries)
+
+	require.NoError(t, client.ExecuteDDLWithProgress(ctx, nil, "CREATE TABLE a (id INT PRIMARY KEY)"))
+	require.NoError(t, client.InitMigrationHistory(ctx))
+	require.NoError(t, client.ExecuteMigrationWithTracking(ctx, Migration{Name: "001_a", SQL: "INSERT INTO a VALUES (1), (2);", Checksum: "x"}))
+
+	entries, err = client.StatementLog(ctx, StatementLogFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, StatementSourcePush, entries[0].Source)
+	assert.Equal(t, "001_a", entries[1].Migration)
+	assert.Equal(t, int64(2), entries[1].RowsAffected)
+	assert.Equal(t, AppVersion, entries[1].AppVersion)
+	assert.NotEmpty(t, entries[1].Executor)
+	require.NoError(t, VerifyStatementLog(entries))
+
+	filtered, err := client.StatementLog(ctx, StatementLogFilter{Migration: "001_a"})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, int64(2), filtered[0].Seq)
+
+	_, err = client.GetDB().ExecContext(ctx, `UPDATE _scurry_.statement_log SET statement = 'SELECT 1' WHERE seq = 1`)
+	require.NoError(t, err)
+	entries, err = client.StatementLog(ctx, StatementLogFilter{})
+	require.NoError(t, err)
+	assert.EqualError(t, VerifyStatementLog(entries), "statement log entry 1 was modified")
+}
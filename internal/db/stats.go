@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// RefreshStatistics collects fresh statistics on each table, given as
+// "schema.table", one at a time. CockroachDB refreshes statistics on its own
+// eventually, but query plans can regress badly until it does after a large
+// backfill or index build.
+func (c *Client) RefreshStatistics(ctx context.Context, tables []string) error {
+	for _, table := range tables {
+		schemaName, tableName, ok := strings.Cut(table, ".")
+		if !ok {
+			schemaName, tableName = "public", table
+		}
+		stmt := fmt.Sprintf("CREATE STATISTICS __auto__ FROM %s.%s", tree.NameString(schemaName), tree.NameString(tableName))
+		if _, err := c.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to refresh statistics on %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Explain returns the lines of the query plan CockroachDB chooses for the
+// statement, which is not executed
+func (c *Client) Explain(ctx context.Context, query string) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		plan = append(plan, line)
+	}
+	return plan, rows.Err()
+}
@@ -10,11 +10,12 @@ type TableSizeInfo struct {
 	SchemaName string
 	TableName  string
 	Rows       int64
+	Bytes      int64 // Approximate size on disk, or 0 if unknown
 }
 
 // GetTableSizes queries the database for table sizes.
-// Uses crdb_internal.table_row_statistics for row estimates and
-// range stats for approximate disk size.
+// Uses the estimated row counts from SHOW TABLES and range stats for
+// approximate disk size.
 func (c *Client) GetTableSizes(ctx context.Context) ([]TableSizeInfo, error) {
 	rows, err := c.db.QueryContext(ctx, `
 		SELECT schema_name, table_name, estimated_row_count
@@ -36,5 +37,43 @@ func (c *Client) GetTableSizes(ctx context.Context) ([]TableSizeInfo, error) {
 		tables = append(tables, t)
 	}
 
-	return tables, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Sizes on disk come from range statistics, which older versions and
+	// restricted users can't read, so they're left out if the query fails
+	if bytes, err := c.getTableBytes(ctx); err == nil {
+		for i := range tables {
+			tables[i].Bytes = bytes[tables[i].SchemaName+"."+tables[i].TableName]
+		}
+	}
+
+	return tables, nil
+}
+
+// getTableBytes returns the approximate size on disk of each table, keyed by
+// "schema.table"
+func (c *Client) getTableBytes(ctx context.Context) (map[string]int64, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT schema_name, table_name, sum(range_size)::INT8
+		FROM [SHOW RANGES FROM CURRENT_CATALOG WITH TABLES, DETAILS]
+		WHERE table_name IS NOT NULL
+		GROUP BY schema_name, table_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table bytes: %w", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var schemaName, tableName string
+		var bytes int64
+		if err := rows.Scan(&schemaName, &tableName, &bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan table bytes: %w", err)
+		}
+		sizes[schemaName+"."+tableName] = bytes
+	}
+	return sizes, rows.Err()
 }
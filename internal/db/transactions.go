@@ -0,0 +1,144 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Directives in a migration's body that control the transactions its statements
+// run in. Each goes on a line of its own.
+const (
+	// NoTransactionDirective runs every statement of the migration outside of an
+	// explicit transaction, ignoring the BEGIN and COMMIT statements in it
+	NoTransactionDirective = "-- scurry:no-transaction"
+	// NoTransactionStatementDirective runs the statement after it outside of an
+	// explicit transaction, committing the open one first and starting another
+	// after it
+	NoTransactionStatementDirective = "-- scurry:statement no-txn"
+)
+
+// IsTransactionDirective reports whether line is one of the transaction directives
+func IsTransactionDirective(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == NoTransactionDirective || line == NoTransactionStatementDirective
+}
+
+// migrationStatement is a statement of a migration body
+type migrationStatement struct {
+	SQL           string
+	NoTransaction bool
+}
+
+// splitMigrationStatements splits a migration body into statements, noting the
+// directives that apply to them. It returns whether the whole migration runs
+// outside of a transaction.
+func splitMigrationStatements(sqlContent string) ([]migrationStatement, bool, error) {
+	// Each statement directive starts a new segment, whose first statement it applies to
+	var segments []string
+	var current strings.Builder
+	noTransaction := false
+	for _, line := range strings.Split(sqlContent, "\n") {
+		switch strings.TrimSpace(line) {
+		case NoTransactionDirective:
+			noTransaction = true
+			continue
+		case NoTransactionStatementDirective:
+			segments = append(segments, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	segments = append(segments, current.String())
+
+	var statements []migrationStatement
+	for i, segment := range segments {
+		stmts, err := SplitStatements(segment)
+		if err != nil {
+			return nil, false, err
+		}
+		if i > 0 && len(stmts) == 0 {
+			return nil, false, fmt.Errorf("%q must be followed by a statement", NoTransactionStatementDirective)
+		}
+		for j, stmt := range stmts {
+			// IMPORT can't run in an explicit transaction, directive or not
+			statements = append(statements, migrationStatement{SQL: stmt, NoTransaction: (i > 0 && j == 0) || IsImportStatement(stmt)})
+		}
+	}
+	return statements, noTransaction, nil
+}
+
+// MigrationStatements returns the statements to execute for a migration, with its
+// transaction directives applied as BEGIN and COMMIT statements. inTransaction
+// says whether execution starts inside a transaction, as it does for
+// ExecuteBulkDDL.
+func MigrationStatements(migration Migration, inTransaction bool) ([]string, error) {
+	stmts, noTransaction, err := splitMigrationStatements(migration.SQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse migration %s: %w", migration.Name, err)
+	}
+
+	startedInTransaction := inTransaction
+	var statements []string
+	if noTransaction && inTransaction {
+		statements = append(statements, "COMMIT TRANSACTION")
+	}
+	for _, stmt := range stmts {
+		switch {
+		case isBeginStatement(stmt.SQL):
+			if noTransaction {
+				continue
+			}
+			inTransaction = true
+		case isCommitStatement(stmt.SQL):
+			if noTransaction || !inTransaction {
+				continue
+			}
+			inTransaction = false
+		case stmt.NoTransaction && inTransaction:
+			statements = append(statements, "COMMIT TRANSACTION", stmt.SQL, "BEGIN TRANSACTION")
+			continue
+		}
+		statements = append(statements, stmt.SQL)
+	}
+	// Don't leave a transaction the migration opened for the caller to roll back
+	if inTransaction && !startedInTransaction {
+		statements = append(statements, "COMMIT TRANSACTION")
+	}
+	return statements, nil
+}
+
+// ResumePoint returns the statement to resume a migration from after it
+// stopped at statement stopped (both index MigrationStatements). completed says
+// whether that statement took effect anyway, as a schema change whose job
+// finished after the client went away does. Statements inside an explicit
+// transaction rolled back with it, so those resume from its BEGIN.
+func ResumePoint(statements []string, stopped int, completed bool) int {
+	if completed {
+		return stopped + 1
+	}
+	begin := -1
+	for i := 0; i < stopped && i < len(statements); i++ {
+		switch {
+		case isBeginStatement(statements[i]):
+			begin = i
+		case isCommitStatement(statements[i]):
+			begin = -1
+		}
+	}
+	if begin >= 0 {
+		return begin
+	}
+	return stopped
+}
+
+func isBeginStatement(stmt string) bool {
+	normalized := strings.ToUpper(strings.TrimSpace(stmt))
+	return normalized == "BEGIN" || normalized == "BEGIN TRANSACTION"
+}
+
+func isCommitStatement(stmt string) bool {
+	normalized := strings.ToUpper(strings.TrimSpace(stmt))
+	return normalized == "COMMIT" || normalized == "COMMIT TRANSACTION"
+}
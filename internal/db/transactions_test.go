@@ -0,0 +1,143 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		sql           string
+		inTransaction bool
+		want          []string
+		wantErr       bool
+	}{
+		{
+			name: "no directives",
+			sql:  "CREATE TABLE a (id INT PRIMARY KEY);\nCREATE TABLE b (id INT PRIMARY KEY);",
+			want: []string{
+				"CREATE TABLE a (id INT8 PRIMARY KEY)",
+				"CREATE TABLE b (id INT8 PRIMARY KEY)",
+			},
+		},
+		{
+			name: "transaction left open is committed",
+			sql:  "CREATE TABLE a (id INT PRIMARY KEY);\nCOMMIT;\nBEGIN;\nCREATE TABLE b (id INT PRIMARY KEY);",
+			want: []string{
+				"CREATE TABLE a (id INT8 PRIMARY KEY)",
+				"BEGIN TRANSACTION",
+				"CREATE TABLE b (id INT8 PRIMARY KEY)",
+				"COMMIT TRANSACTION",
+			},
+		},
+		{
+			name:          "no-transaction drops BEGIN and COMMIT",
+			sql:           "-- scurry:no-transaction\nALTER TYPE status ADD VALUE 'archived';\nCOMMIT;\nBEGIN;\nUPDATE t SET s = 'archived';",
+			inTransaction: true,
+			want: []string{
+				"COMMIT TRANSACTION",
+				"ALTER TYPE status ADD VALUE 'archived'",
+				"UPDATE t SET s = 'archived'",
+			},
+		},
+		{
+			name: "statement directive outside a transaction",
+			sql:  "CREATE TABLE a (id INT PRIMARY KEY);\n-- scurry:statement no-txn\nCREATE INDEX ON a (id);",
+			want: []string{
+				"CREATE TABLE a (id INT8 PRIMARY KEY)",
+				"CREATE INDEX ON a (id)",
+			},
+		},
+		{
+			name:          "statement directive inside a transaction",
+			sql:           "CREATE TABLE a (id INT PRIMARY KEY);\n-- scurry:statement no-txn\nCREATE INDEX ON a (id);\nCREATE TABLE b (id INT PRIMARY KEY);",
+			inTransaction: true,
+			want: []string{
+				"CREATE TABLE a (id INT8 PRIMARY KEY)",
+				"COMMIT TRANSACTION",
+				"CREATE INDEX ON a (id)",
+				"BEGIN TRANSACTION",
+				"CREATE TABLE b (id INT8 PRIMARY KEY)",
+			},
+		},
+		{
+			name:          "import runs outside the transaction",
+			sql:           "CREATE TABLE a (id INT PRIMARY KEY);\nIMPORT INTO a (id) CSV DATA ('gs://bucket/a.csv?AUTH=implicit');\nCREATE TABLE b (id INT PRIMARY KEY);",
+			inTransaction: true,
+			want: []string{
+				"CREATE TABLE a (id INT8 PRIMARY KEY)",
+				"COMMIT TRANSACTION",
+				"IMPORT INTO a(id) CSV DATA ('gs://bucket/a.csv?AUTH=implicit')",
+				"BEGIN TRANSACTION",
+				"CREATE TABLE b (id INT8 PRIMARY KEY)",
+			},
+		},
+		{
+			name:    "statement directive with nothing after it",
+			sql:     "CREATE TABLE a (id INT PRIMARY KEY);\n-- scurry:statement no-txn\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := MigrationStatements(Migration{Name: "test", SQL: tt.sql}, tt.inTransaction)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResumePoint(t *testing.T) {
+	t.Parallel()
+
+	statements := []string{
+		"CREATE TABLE a (id INT8 PRIMARY KEY)",
+		"CREATE INDEX ON a (id)",
+		"BEGIN TRANSACTION",
+		"CREATE TABLE b (id INT8 PRIMARY KEY)",
+		"ALTER TABLE b ADD COLUMN x INT8",
+		"COMMIT TRANSACTION",
+		"UPDATE a SET id = id",
+	}
+
+	tests := []struct {
+		name      string
+		stopped   int
+		completed bool
+		want      int
+	}{
+		{name: "first statement", stopped: 0, want: 0},
+		{name: "schema change that didn't finish", stopped: 1, want: 1},
+		{name: "schema change whose job finished", stopped: 1, completed: true, want: 2},
+		{name: "inside a transaction", stopped: 4, want: 2},
+		{name: "at the commit", stopped: 5, want: 2},
+		{name: "commit whose job finished", stopped: 5, completed: true, want: 6},
+		{name: "after the transaction", stopped: 6, want: 6},
+		{name: "last statement finished", stopped: 6, completed: true, want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ResumePoint(statements, tt.stopped, tt.completed))
+		})
+	}
+}
+
+func TestIsTransactionDirective(t *testing.T) {
+	t.Parallel()
+	assert.True(t, IsTransactionDirective("-- scurry:no-transaction"))
+	assert.True(t, IsTransactionDirective("  -- scurry:statement no-txn  "))
+	assert.False(t, IsTransactionDirective("-- scurry:mode=sync"))
+}
@@ -2,17 +2,44 @@ package flags
 
 import (
 	"os"
+	"path/filepath"
+	"slices"
+	"time"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+
+	"github.com/pjtatlow/scurry/internal/config"
 )
 
 var (
-	Verbose        bool
-	Force          bool
-	NoColor        bool
-	MigrationDir   string
-	DefinitionDirs []string
-	DbUrl          string
+	Verbose          bool
+	Force            bool
+	NoColor          bool
+	MigrationDir     string
+	DefinitionDirs   []string
+	Env              string
+	DbUrl            string
+	AllowDestructive bool
+	DeferValidation  bool
+	TTLIndex         bool
+	CheckNulls       bool
+	LockWait         time.Duration
+	ConfigFile       string
+	NotifyUrl        string
+	LogFormat        string
+	LogLevel         string
+	LogTimestamps    bool
+	Dialect          string
+	ChecksumMode     string
+	CheckpointCache  string
+	Skip             []string
+)
+
+// Checksum modes for comparing migration files with the applied migrations
+const (
+	ChecksumRaw     = "raw"
+	ChecksumLogical = "logical"
 )
 
 func AddVerbose(cmd *cobra.Command) {
@@ -27,8 +54,36 @@ func AddNoColor(cmd *cobra.Command) {
 	cmd.PersistentFlags().BoolVar(&NoColor, "no-color", false, "Disable colored output (also respects NO_COLOR env var)")
 }
 
+func AddConfig(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&ConfigFile, "config", coalesceDefaults(os.Getenv("SCURRY_CONFIG"), ".scurry.yaml"), "Path to the scurry config file")
+	_ = cmd.MarkPersistentFlagFilename("config", "yaml", "yml")
+}
+
+func AddLogging(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&LogFormat, "log-format", coalesceDefaults(os.Getenv("SCURRY_LOG_FORMAT"), "text"), "Output format: text or json (one JSON object per line)")
+	cmd.PersistentFlags().StringVar(&LogLevel, "log-level", coalesceDefaults(os.Getenv("SCURRY_LOG_LEVEL"), "info"), "Minimum level to log: debug, info, warn or error (--verbose implies debug)")
+	cmd.PersistentFlags().BoolVar(&LogTimestamps, "log-timestamps", false, "Prefix text output with timestamps (JSON output always has them)")
+	_ = cmd.RegisterFlagCompletionFunc("log-format", Choices("text", "json"))
+	_ = cmd.RegisterFlagCompletionFunc("log-level", Choices("debug", "info", "warn", "error"))
+}
+
+func AddDialect(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&Dialect, "dialect", coalesceDefaults(os.Getenv("SCURRY_DIALECT"), "cockroachdb"), "Database the definitions and generated migrations are written for: cockroachdb or postgres")
+	_ = cmd.RegisterFlagCompletionFunc("dialect", Choices("cockroachdb", "postgres"))
+}
+
+func AddChecksumMode(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&ChecksumMode, "checksum", os.Getenv("SCURRY_CHECKSUM"), "How applied migrations are compared with their files: raw, or logical to ignore comment and formatting edits (default: migrations.checksum in the config file, or raw)")
+	_ = cmd.RegisterFlagCompletionFunc("checksum", Choices(ChecksumRaw, ChecksumLogical))
+}
+
+func AddCheckpointCache(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&CheckpointCache, "checkpoint-cache", os.Getenv("SCURRY_CHECKPOINT_CACHE"), "s3:// or gs:// URL to share checkpoints through, so replays can start from another machine's (default: migrations.checkpoint_cache in the config file)")
+}
+
 func AddMigrationDir(cmd *cobra.Command) {
 	cmd.PersistentFlags().StringVar(&MigrationDir, "migrations", coalesceDefaults(os.Getenv("MIGRATION_DIR"), "./migrations"), "Directory containing migration files")
+	_ = cmd.MarkPersistentFlagDirname("migrations")
 }
 
 func AddDefinitionDirs(cmd *cobra.Command) {
@@ -37,10 +92,83 @@ func AddDefinitionDirs(cmd *cobra.Command) {
 		defaultDirs = []string{envDir}
 	}
 	cmd.Flags().StringArrayVar(&DefinitionDirs, "definitions", defaultDirs, "Directories containing schema definition files (can be specified multiple times)")
+	_ = cmd.MarkFlagDirname("definitions")
+}
+
+// AddEnv adds --env, unless the command already has it from AddDbUrl
+func AddEnv(cmd *cobra.Command) {
+	if cmd.Flags().Lookup("env") != nil {
+		return
+	}
+	cmd.Flags().StringVar(&Env, "env", os.Getenv("SCURRY_ENV"), "Environment to use: its connection profile from environments in the config file, and its overlays/<env> definitions merged over the base definitions")
+	_ = cmd.RegisterFlagCompletionFunc("env", completeEnvs)
+}
+
+// Choices completes a flag with a fixed set of values
+func Choices(values ...string) cobra.CompletionFunc {
+	return cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)
+}
+
+// completeEnvs completes --env with the environments in the config file and
+// the overlays/<env> directories of the definition directories
+func completeEnvs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	seen := make(map[string]bool)
+	var envs []string
+	if cfg, err := config.Load(afero.NewOsFs(), ConfigFile); err == nil {
+		for name := range cfg.Environments {
+			seen[name] = true
+			envs = append(envs, name)
+		}
+		slices.Sort(envs)
+	}
+	for _, dir := range DefinitionDirs {
+		entries, err := os.ReadDir(filepath.Join(dir, "overlays"))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				envs = append(envs, entry.Name())
+			}
+		}
+	}
+	return envs, cobra.ShellCompDirectiveNoFileComp
+}
+
+func AddAllowDestructive(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&AllowDestructive, "allow-destructive", false, "Allow changes that drop tables or columns")
+}
+
+func AddDeferValidation(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&DeferValidation, "defer-validation", false, "Add foreign keys, checks and UNIQUE WITHOUT INDEX constraints NOT VALID and validate them in a separate async migration")
+}
+
+func AddTTLIndex(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&TTLIndex, "ttl-index", false, "Create the index a new TTL expiration expression needs when the table definition doesn't have one")
+}
+
+func AddCheckNulls(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&CheckNulls, "check-nulls", false, "Count the NULL rows of each column made NOT NULL in the database first, and stop if any remain")
+}
+
+func AddSkip(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&Skip, "skip", nil, "Object expected to differ from the definitions: its differences are reported as ignored instead of applied (can be specified multiple times; see also ignore_diff in the config file)")
+}
+
+func AddLockWait(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&LockWait, "lock-wait", 0, "How long to wait for another scurry process to release the migration lock")
+}
+
+func AddNotifyUrl(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&NotifyUrl, "notify-url", os.Getenv("SCURRY_NOTIFY_URL"), "Webhook URL to POST the outcome to, e.g. a Slack incoming webhook (also SCURRY_NOTIFY_URL env var)")
 }
 
+// AddDbUrl adds --db-url, and --env to connect with a profile from the config
+// file instead
 func AddDbUrl(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&DbUrl, "db-url", coalesceDefaults(os.Getenv("CRDB_URL"), os.Getenv("DB_URL")), "Database connection URL")
+	AddEnv(cmd)
 }
 
 func coalesceDefaults(defaults ...string) string {
@@ -0,0 +1,191 @@
+package generate
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// goInitialisms are written in upper case in Go names, per Go convention
+var goInitialisms = map[string]bool{
+	"Api": true, "Id": true, "Ip": true, "Json": true, "Sql": true, "Url": true, "Uri": true, "Uuid": true, "Http": true,
+}
+
+// goName converts a snake_case name to an exported Go name
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == ' ' }) {
+		word := ToPascalCase(part)
+		if goInitialisms[word] {
+			word = strings.ToUpper(word)
+		}
+		b.WriteString(word)
+	}
+	if out := b.String(); out != "" && out[0] >= '0' && out[0] <= '9' {
+		return "X" + out
+	}
+	return b.String()
+}
+
+// goTypeName is the Go type name for an object, prefixed with its schema
+// outside of public
+func goTypeName(schemaName, name string) string {
+	if schemaName == "public" || schemaName == "" {
+		return goName(name)
+	}
+	return goName(schemaName) + goName(name)
+}
+
+// goEmitter writes Go structs with db tags
+type goEmitter struct {
+	pkg string
+}
+
+func (e goEmitter) FileName() string { return "models.go" }
+
+func (e goEmitter) Emit(models []Model, enums []Enum) string {
+	var body strings.Builder
+	imports := map[string]bool{}
+
+	for _, enum := range enums {
+		typeName := goTypeName(enum.Schema, enum.Name)
+		fmt.Fprintf(&body, "\n// %s is the %s.%s enum\ntype %s string\n", typeName, enum.Schema, enum.Name, typeName)
+		if len(enum.Values) > 0 {
+			body.WriteString("\nconst (\n")
+			seen := map[string]bool{}
+			for _, v := range enum.Values {
+				member := resolveMemberName(typeName+goName(v), seen)
+				fmt.Fprintf(&body, "\t%s %s = %q\n", member, typeName, v)
+			}
+			body.WriteString(")\n")
+		}
+	}
+
+	for _, model := range models {
+		typeName := goTypeName(model.Schema, model.Table)
+		fmt.Fprintf(&body, "\n// %s is a row of %s.%s\ntype %s struct {\n", typeName, model.Schema, model.Table, typeName)
+		for _, field := range model.Columns {
+			goType := e.goType(field.Type, imports)
+			if field.Nullable && field.Type.Kind != KindArray && field.Type.Kind != KindBytes && field.Type.Kind != KindJSON {
+				goType = "*" + goType
+			}
+			fmt.Fprintf(&body, "\t%s %s `db:%q`\n", goName(field.Column), goType, field.Column)
+		}
+		body.WriteString("}\n")
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by scurry generate models. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n", e.pkg)
+	if len(imports) > 0 {
+		b.WriteString("\nimport (\n")
+		for _, imp := range []string{"encoding/json", "time"} {
+			if imports[imp] {
+				fmt.Fprintf(&b, "\t%q\n", imp)
+			}
+		}
+		b.WriteString(")\n")
+	}
+	b.WriteString(body.String())
+
+	// Align the struct fields like gofmt would
+	formatted, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return b.String()
+	}
+	return string(formatted)
+}
+
+func (e goEmitter) goType(t FieldType, imports map[string]bool) string {
+	switch t.Kind {
+	case KindInt:
+		switch t.Width {
+		case 16:
+			return "int16"
+		case 32:
+			return "int32"
+		}
+		return "int64"
+	case KindFloat:
+		if t.Width == 32 {
+			return "float32"
+		}
+		return "float64"
+	case KindBool:
+		return "bool"
+	case KindBytes:
+		return "[]byte"
+	case KindTime:
+		imports["time"] = true
+		return "time.Time"
+	case KindJSON:
+		imports["encoding/json"] = true
+		return "json.RawMessage"
+	case KindEnum:
+		return goTypeName(t.Enum.Schema, t.Enum.Name)
+	case KindArray:
+		return "[]" + e.goType(*t.Elem, imports)
+	}
+	// Decimals are strings so no precision is lost
+	return "string"
+}
+
+// typeScriptEmitter writes TypeScript interfaces
+type typeScriptEmitter struct{}
+
+func (e typeScriptEmitter) FileName() string { return "models.ts" }
+
+func (e typeScriptEmitter) Emit(models []Model, enums []Enum) string {
+	var b strings.Builder
+	b.WriteString("// Code generated by scurry generate models. DO NOT EDIT.\n")
+
+	for _, enum := range enums {
+		values := make([]string, len(enum.Values))
+		for i, v := range enum.Values {
+			values[i] = fmt.Sprintf("%q", v)
+		}
+		union := strings.Join(values, " | ")
+		if union == "" {
+			union = "never"
+		}
+		fmt.Fprintf(&b, "\nexport type %s = %s;\n", modelName(enum.Schema, enum.Name), union)
+	}
+
+	for _, model := range models {
+		fmt.Fprintf(&b, "\nexport interface %s {\n", modelName(model.Schema, model.Table))
+		for _, field := range model.Columns {
+			tsType := e.tsType(field.Type)
+			if field.Nullable {
+				tsType += " | null"
+			}
+			fmt.Fprintf(&b, "  %s: %s;\n", field.Column, tsType)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func (e typeScriptEmitter) tsType(t FieldType) string {
+	switch t.Kind {
+	case KindInt, KindFloat:
+		return "number"
+	case KindBool:
+		return "boolean"
+	case KindBytes:
+		return "Uint8Array"
+	case KindTime:
+		return "Date"
+	case KindJSON:
+		return "unknown"
+	case KindEnum:
+		return modelName(t.Enum.Schema, t.Enum.Name)
+	case KindArray:
+		elem := e.tsType(*t.Elem)
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	}
+	// Decimals are strings so no precision is lost
+	return "string"
+}
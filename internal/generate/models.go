@@ -0,0 +1,193 @@
+package generate
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/types"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// Model is a table to generate a type for
+type Model struct {
+	Schema  string
+	Table   string
+	Columns []Field
+}
+
+// Field is a column of a model
+type Field struct {
+	Column   string
+	Type     FieldType
+	Nullable bool
+}
+
+// FieldType is a column's type, reduced to what emitters need
+type FieldType struct {
+	Kind  Kind
+	Width int32      // Bits, for integers and floats
+	Enum  *Enum      // The enum, for KindEnum
+	Elem  *FieldType // The element type, for KindArray
+}
+
+// Kind groups SQL types by how they map to a language's types
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindInt
+	KindFloat
+	KindDecimal
+	KindBool
+	KindBytes
+	KindTime
+	KindUUID
+	KindJSON
+	KindEnum
+	KindArray
+)
+
+// Enum is an enum type and its values
+type Enum struct {
+	Schema string
+	Name   string
+	Values []string
+}
+
+// Emitter turns models into a source file for one language
+type Emitter interface {
+	// FileName is the name of the generated file
+	FileName() string
+	// Emit returns the contents of the generated file
+	Emit(models []Model, enums []Enum) string
+}
+
+// Emitters are the supported languages, by name
+var Emitters = map[string]func(options EmitterOptions) Emitter{
+	"go": func(options EmitterOptions) Emitter { return goEmitter{pkg: options.GoPackage} },
+	"ts": func(EmitterOptions) Emitter { return typeScriptEmitter{} },
+}
+
+// EmitterOptions configures the emitters
+type EmitterOptions struct {
+	// GoPackage is the package name of generated Go files
+	GoPackage string
+}
+
+// EmitterNames returns the names of the supported languages, sorted
+func EmitterNames() []string {
+	names := make([]string, 0, len(Emitters))
+	for name := range Emitters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// BuildModels returns a model for each table in s and every enum type, sorted
+// by qualified name
+func BuildModels(s *schema.Schema) ([]Model, []Enum) {
+	enums := make([]Enum, 0)
+	enumsByName := make(map[string]*Enum)
+	for _, t := range s.Types {
+		if t.Ast.Variety != tree.Enum {
+			continue
+		}
+		enum := Enum{Schema: t.Schema, Name: t.Name}
+		for _, label := range t.Ast.EnumLabels {
+			enum.Values = append(enum.Values, string(label))
+		}
+		enums = append(enums, enum)
+	}
+	slices.SortFunc(enums, func(a, b Enum) int {
+		return strings.Compare(a.Schema+"."+a.Name, b.Schema+"."+b.Name)
+	})
+	for i := range enums {
+		enumsByName[enums[i].Schema+"."+enums[i].Name] = &enums[i]
+	}
+
+	models := make([]Model, 0, len(s.Tables))
+	for _, t := range s.Tables {
+		model := Model{Schema: t.Schema, Table: t.Name}
+		primaryKey := make(map[string]bool)
+		for _, def := range t.Ast.Defs {
+			if d, ok := def.(*tree.UniqueConstraintTableDef); ok && d.PrimaryKey {
+				for _, col := range d.Columns {
+					primaryKey[col.Column.Normalize()] = true
+				}
+			}
+		}
+		for _, def := range t.Ast.Defs {
+			col, ok := def.(*tree.ColumnTableDef)
+			if !ok || col.Hidden {
+				continue
+			}
+			name := col.Name.Normalize()
+			model.Columns = append(model.Columns, Field{
+				Column:   name,
+				Type:     fieldType(col.Type, enumsByName),
+				Nullable: col.Nullable.Nullability != tree.NotNull && !col.PrimaryKey.IsPrimaryKey && !primaryKey[name],
+			})
+		}
+		models = append(models, model)
+	}
+	slices.SortFunc(models, func(a, b Model) int {
+		return strings.Compare(a.Schema+"."+a.Table, b.Schema+"."+b.Table)
+	})
+
+	return models, enums
+}
+
+func fieldType(ref tree.ResolvableTypeReference, enums map[string]*Enum) FieldType {
+	switch t := ref.(type) {
+	case *types.T:
+		return builtinFieldType(t)
+	case *tree.UnresolvedObjectName:
+		schemaName := "public"
+		if t.NumParts > 1 {
+			schemaName = t.Parts[1]
+		}
+		if enum, ok := enums[schemaName+"."+t.Parts[0]]; ok {
+			return FieldType{Kind: KindEnum, Enum: enum}
+		}
+	case *tree.ArrayTypeReference:
+		elem := fieldType(t.ElementType, enums)
+		return FieldType{Kind: KindArray, Elem: &elem}
+	}
+	return FieldType{Kind: KindString}
+}
+
+func builtinFieldType(t *types.T) FieldType {
+	switch t.Family() {
+	case types.IntFamily:
+		return FieldType{Kind: KindInt, Width: t.Width()}
+	case types.FloatFamily:
+		return FieldType{Kind: KindFloat, Width: t.Width()}
+	case types.DecimalFamily:
+		return FieldType{Kind: KindDecimal}
+	case types.BoolFamily:
+		return FieldType{Kind: KindBool}
+	case types.BytesFamily:
+		return FieldType{Kind: KindBytes}
+	case types.TimestampFamily, types.TimestampTZFamily, types.DateFamily:
+		return FieldType{Kind: KindTime}
+	case types.UuidFamily:
+		return FieldType{Kind: KindUUID}
+	case types.JsonFamily:
+		return FieldType{Kind: KindJSON}
+	case types.ArrayFamily:
+		elem := builtinFieldType(t.ArrayContents())
+		return FieldType{Kind: KindArray, Elem: &elem}
+	}
+	return FieldType{Kind: KindString}
+}
+
+// modelName is the type name for a model, prefixed with its schema outside of public
+func modelName(schemaName, name string) string {
+	if schemaName == "public" || schemaName == "" {
+		return ToPascalCase(name)
+	}
+	return ToPascalCase(schemaName) + ToPascalCase(name)
+}
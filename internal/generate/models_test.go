@@ -0,0 +1,132 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+const modelsTestSQL = `
+CREATE TYPE user_status AS ENUM ('active', 'banned');
+CREATE SCHEMA billing;
+CREATE TABLE users (
+	id UUID PRIMARY KEY,
+	email STRING NOT NULL,
+	status user_status NOT NULL,
+	age INT4,
+	tags STRING[],
+	profile JSONB,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE billing.invoices (
+	id INT8,
+	user_id UUID NOT NULL,
+	total DECIMAL(10, 2),
+	PRIMARY KEY (id)
+);
+`
+
+func buildTestModels(t *testing.T) ([]Model, []Enum) {
+	t.Helper()
+	statements, err := schema.ParseSQL(modelsTestSQL)
+	require.NoError(t, err)
+	return BuildModels(schema.NewSchema(statements...))
+}
+
+func TestBuildModels(t *testing.T) {
+	t.Parallel()
+
+	models, enums := buildTestModels(t)
+
+	require.Len(t, enums, 1)
+	assert.Equal(t, Enum{Schema: "public", Name: "user_status", Values: []string{"active", "banned"}}, enums[0])
+
+	require.Len(t, models, 2)
+	assert.Equal(t, "billing", models[0].Schema)
+	assert.Equal(t, "users", models[1].Table)
+
+	invoiceID := models[0].Columns[0]
+	assert.Equal(t, KindInt, invoiceID.Type.Kind)
+	assert.False(t, invoiceID.Nullable, "primary key columns aren't nullable")
+
+	status := models[1].Columns[2]
+	assert.Equal(t, KindEnum, status.Type.Kind)
+	assert.Equal(t, "user_status", status.Type.Enum.Name)
+}
+
+func TestGoEmitter(t *testing.T) {
+	t.Parallel()
+
+	models, enums := buildTestModels(t)
+	expected := "// Code generated by scurry generate models. DO NOT EDIT.\n" +
+		"\n" +
+		"package models\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"encoding/json\"\n" +
+		"\t\"time\"\n" +
+		")\n" +
+		"\n" +
+		"// UserStatus is the public.user_status enum\n" +
+		"type UserStatus string\n" +
+		"\n" +
+		"const (\n" +
+		"\tUserStatusActive UserStatus = \"active\"\n" +
+		"\tUserStatusBanned UserStatus = \"banned\"\n" +
+		")\n" +
+		"\n" +
+		"// BillingInvoices is a row of billing.invoices\n" +
+		"type BillingInvoices struct {\n" +
+		"\tID     int64   `db:\"id\"`\n" +
+		"\tUserID string  `db:\"user_id\"`\n" +
+		"\tTotal  *string `db:\"total\"`\n" +
+		"}\n" +
+		"\n" +
+		"// Users is a row of public.users\n" +
+		"type Users struct {\n" +
+		"\tID        string          `db:\"id\"`\n" +
+		"\tEmail     string          `db:\"email\"`\n" +
+		"\tStatus    UserStatus      `db:\"status\"`\n" +
+		"\tAge       *int32          `db:\"age\"`\n" +
+		"\tTags      []string        `db:\"tags\"`\n" +
+		"\tProfile   json.RawMessage `db:\"profile\"`\n" +
+		"\tCreatedAt time.Time       `db:\"created_at\"`\n" +
+		"}\n"
+
+	emitter := Emitters["go"](EmitterOptions{GoPackage: "models"})
+	assert.Equal(t, "models.go", emitter.FileName())
+	assert.Equal(t, expected, emitter.Emit(models, enums))
+}
+
+func TestTypeScriptEmitter(t *testing.T) {
+	t.Parallel()
+
+	models, enums := buildTestModels(t)
+	expected := `// Code generated by scurry generate models. DO NOT EDIT.
+
+export type UserStatus = "active" | "banned";
+
+export interface BillingInvoices {
+  id: number;
+  user_id: string;
+  total: string | null;
+}
+
+export interface Users {
+  id: string;
+  email: string;
+  status: UserStatus;
+  age: number | null;
+  tags: string[] | null;
+  profile: unknown | null;
+  created_at: Date;
+}
+`
+
+	emitter := Emitters["ts"](EmitterOptions{})
+	assert.Equal(t, "models.ts", emitter.FileName())
+	assert.Equal(t, expected, emitter.Emit(models, enums))
+}
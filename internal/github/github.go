@@ -0,0 +1,164 @@
+// Package github posts scurry's reports to pull requests through the GitHub REST API.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the API of github.com. GitHub Enterprise Server runners set
+// GITHUB_API_URL to their own.
+const DefaultAPIURL = "https://api.github.com"
+
+// Timeout bounds each API request
+var Timeout = 30 * time.Second
+
+// commentsPerPage is the most comments GitHub returns per page
+const commentsPerPage = 100
+
+// Client calls the GitHub REST API with a token
+type Client struct {
+	APIURL string
+	Token  string
+}
+
+// NewClient returns a client for the API in GITHUB_API_URL, or github.com
+func NewClient(token string) *Client {
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &Client{APIURL: strings.TrimSuffix(apiURL, "/"), Token: token}
+}
+
+// Comment is an issue or pull request comment
+type Comment struct {
+	ID      int64  `json:"id"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+// UpsertComment posts body on the pull request, or edits the earlier comment
+// that contains marker, so re-runs update one comment instead of adding more.
+// repo is "owner/name".
+func (c *Client) UpsertComment(ctx context.Context, repo string, pr int, marker, body string) (*Comment, error) {
+	existing, err := c.findComment(ctx, repo, pr, marker)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment Comment
+	payload := map[string]string{"body": body}
+	if existing != nil {
+		err = c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/comments/%d", repo, existing.ID), payload, &comment)
+	} else {
+		err = c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", repo, pr), payload, &comment)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// findComment returns the first comment on the pull request containing marker,
+// or nil if there isn't one
+func (c *Client) findComment(ctx context.Context, repo string, pr int, marker string) (*Comment, error) {
+	for page := 1; ; page++ {
+		var comments []Comment
+		path := fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=%d&page=%d", repo, pr, commentsPerPage, page)
+		if err := c.do(ctx, http.MethodGet, path, nil, &comments); err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, marker) {
+				return &comment, nil
+			}
+		}
+		if len(comments) < commentsPerPage {
+			return nil, nil
+		}
+	}
+}
+
+// do sends payload, if any, as JSON and decodes the response into out
+func (c *Client) do(ctx context.Context, method, path string, payload, out any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode GitHub request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, c.APIURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GitHub %s %s returned %s: %s", method, strings.SplitN(path, "?", 2)[0], resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GitHub response: %w", err)
+	}
+	return nil
+}
+
+var pullRefPattern = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// PullRequestFromEnv returns the number of the pull request a GitHub Actions
+// run is for, from the event payload or the ref, or 0 if it isn't for one
+func PullRequestFromEnv() int {
+	if path := os.Getenv("GITHUB_EVENT_PATH"); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			var event struct {
+				Number      int `json:"number"`
+				PullRequest struct {
+					Number int `json:"number"`
+				} `json:"pull_request"`
+			}
+			if json.Unmarshal(data, &event) == nil {
+				if event.PullRequest.Number > 0 {
+					return event.PullRequest.Number
+				}
+				if event.Number > 0 {
+					return event.Number
+				}
+			}
+		}
+	}
+	if match := pullRefPattern.FindStringSubmatch(os.Getenv("GITHUB_REF")); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertComment(t *testing.T) {
+	const marker = "<!-- scurry:ci-comment -->"
+
+	tests := []struct {
+		name       string
+		existing   []Comment
+		wantMethod string
+		wantPath   string
+	}{
+		{
+			name:       "creates a comment",
+			existing:   []Comment{{ID: 1, Body: "LGTM"}},
+			wantMethod: http.MethodPost,
+			wantPath:   "/repos/acme/app/issues/7/comments",
+		},
+		{
+			name:       "edits its earlier comment",
+			existing:   []Comment{{ID: 1, Body: "LGTM"}, {ID: 2, Body: marker + "\nold report"}},
+			wantMethod: http.MethodPatch,
+			wantPath:   "/repos/acme/app/issues/comments/2",
+		},
+		{
+			name: "finds the comment on a later page",
+			existing: func() []Comment {
+				comments := make([]Comment, commentsPerPage+1)
+				for i := range comments {
+					comments[i] = Comment{ID: int64(i + 1), Body: "chatter"}
+				}
+				comments[commentsPerPage].Body = marker
+				return comments
+			}(),
+			wantMethod: http.MethodPatch,
+			wantPath:   fmt.Sprintf("/repos/acme/app/issues/comments/%d", commentsPerPage+1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod, gotPath, gotBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				if r.Method == http.MethodGet {
+					page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+					start := min((page-1)*commentsPerPage, len(tt.existing))
+					end := min(start+commentsPerPage, len(tt.existing))
+					require.NoError(t, json.NewEncoder(w).Encode(tt.existing[start:end]))
+					return
+				}
+				gotMethod, gotPath = r.Method, r.URL.Path
+				var payload map[string]string
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+				gotBody = payload["body"]
+				require.NoError(t, json.NewEncoder(w).Encode(Comment{ID: 9, Body: gotBody, HTMLURL: "https://github.com/acme/app/pull/7#issuecomment-9"}))
+			}))
+			defer server.Close()
+
+			client := &Client{APIURL: server.URL, Token: "token"}
+			comment, err := client.UpsertComment(t.Context(), "acme/app", 7, marker, marker+"\nnew report")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantMethod, gotMethod)
+			assert.Equal(t, tt.wantPath, gotPath)
+			assert.Equal(t, marker+"\nnew report", gotBody)
+			assert.Equal(t, "https://github.com/acme/app/pull/7#issuecomment-9", comment.HTMLURL)
+		})
+	}
+}
+
+func TestUpsertCommentError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Resource not accessible by integration"}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := &Client{APIURL: server.URL, Token: "token"}
+	_, err := client.UpsertComment(t.Context(), "acme/app", 7, "marker", "body")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403 Forbidden")
+	assert.Contains(t, err.Error(), "Resource not accessible by integration")
+}
+
+func TestPullRequestFromEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		ref   string
+		want  int
+	}{
+		{name: "pull_request event", event: `{"number": 12, "pull_request": {"number": 12}}`, want: 12},
+		{name: "pull request ref", ref: "refs/pull/34/merge", want: 34},
+		{name: "push", event: `{"ref": "refs/heads/main"}`, ref: "refs/heads/main", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventPath := ""
+			if tt.event != "" {
+				eventPath = filepath.Join(t.TempDir(), "event.json")
+				require.NoError(t, os.WriteFile(eventPath, []byte(tt.event), 0644))
+			}
+			t.Setenv("GITHUB_EVENT_PATH", eventPath)
+			t.Setenv("GITHUB_REF", tt.ref)
+
+			assert.Equal(t, tt.want, PullRequestFromEnv())
+		})
+	}
+}
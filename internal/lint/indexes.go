@@ -0,0 +1,183 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/idxtype"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// Rules about indexes that could be dropped. scurry lint --drop-migration offers
+// to drop the indexes these rules report.
+const (
+	RuleRedundantIndex = "redundant-index"
+	RuleUnusedIndex    = "unused-index"
+)
+
+// tableIndex is an index of a table, with its key columns formatted so two
+// indexes can be compared
+type tableIndex struct {
+	name    string
+	keys    []string // Columns or expressions, with DESC for descending ones
+	storing []string
+	primary bool
+	unique  bool
+	// plain is true for forward indexes that aren't partial, sharded or
+	// partitioned, the only ones this check compares
+	plain bool
+}
+
+func collectTableIndexes(table *tree.CreateTable) []tableIndex {
+	var indexes []tableIndex
+	add := func(def *tree.IndexTableDef, primary, unique bool) {
+		idx := tableIndex{
+			name:    def.Name.Normalize(),
+			primary: primary,
+			unique:  unique,
+			plain:   def.Type == idxtype.FORWARD && def.Predicate == nil && def.Sharded == nil && def.PartitionByIndex == nil,
+		}
+		for _, elem := range def.Columns {
+			key := elem.Column.Normalize()
+			if elem.Expr != nil {
+				key = tree.AsString(elem.Expr)
+			}
+			if elem.Direction == tree.Descending {
+				key += " DESC"
+			}
+			idx.keys = append(idx.keys, key)
+		}
+		for _, col := range def.Storing {
+			idx.storing = append(idx.storing, col.Normalize())
+		}
+		indexes = append(indexes, idx)
+	}
+
+	for _, def := range table.Defs {
+		switch d := def.(type) {
+		case *tree.ColumnTableDef:
+			if d.PrimaryKey.IsPrimaryKey {
+				indexes = append(indexes, tableIndex{
+					name:    table.Table.Table() + "_pkey",
+					keys:    []string{d.Name.Normalize()},
+					primary: true,
+					unique:  true,
+					plain:   true,
+				})
+			}
+		case *tree.IndexTableDef:
+			add(d, false, false)
+		case *tree.UniqueConstraintTableDef:
+			if !d.WithoutIndex {
+				add(&d.IndexTableDef, d.PrimaryKey, true)
+			}
+		}
+	}
+	return indexes
+}
+
+// checkRedundantIndexes checks for indexes that another index on the same table
+// makes unnecessary: an exact duplicate, or an index whose columns are a prefix
+// of another's. Unique indexes enforce a constraint, so only plain non-unique
+// indexes are reported.
+func checkRedundantIndexes(s *schema.Schema) []Issue {
+	var issues []Issue
+
+	for _, table := range s.Tables {
+		tableName := table.ResolvedName()
+		tableIssues := checkTableRedundantIndexes(tableName, table.Ast)
+		issues = append(issues, withSource(tableIssues, table.Source)...)
+	}
+
+	return issues
+}
+
+func checkTableRedundantIndexes(tableName string, table *tree.CreateTable) []Issue {
+	var issues []Issue
+
+	indexes := collectTableIndexes(table)
+	for i, idx := range indexes {
+		if idx.unique || !idx.plain {
+			continue
+		}
+		for j, other := range indexes {
+			if i == j || !other.plain || !covers(other, idx) {
+				continue
+			}
+			// Of two identical indexes, only the second is reported
+			if !other.unique && len(other.keys) == len(idx.keys) && j > i && slices.Equal(other.storing, idx.storing) {
+				continue
+			}
+
+			description := fmt.Sprintf("Index on (%s) is a prefix of %s on (%s), which serves the same queries", formatColumnList(idx.keys), other.name, formatColumnList(other.keys))
+			if len(other.keys) == len(idx.keys) {
+				description = fmt.Sprintf("Index on (%s) duplicates %s", formatColumnList(idx.keys), other.name)
+			}
+			issues = append(issues, Issue{
+				Rule:        RuleRedundantIndex,
+				Table:       tableName,
+				Constraint:  idx.name,
+				Description: description,
+				Suggestion:  fmt.Sprintf("Remove INDEX %s from the table definition", idx.name),
+			})
+			break
+		}
+	}
+
+	return issues
+}
+
+// covers returns true if other can serve every query idx can: idx's keys are
+// a prefix of other's, and other has every column idx stores
+func covers(other, idx tableIndex) bool {
+	if len(idx.keys) > len(other.keys) || !slices.Equal(idx.keys, other.keys[:len(idx.keys)]) {
+		return false
+	}
+	// The primary index stores every column
+	if other.primary {
+		return true
+	}
+	for _, col := range idx.storing {
+		if !slices.Contains(other.keys, col) && !slices.Contains(other.storing, col) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckIndexUsage checks for secondary indexes in the schema that the database's
+// usage statistics show were never read. Unique indexes enforce a constraint even
+// if no query reads them, so they aren't reported. Indexes the database doesn't
+// have yet aren't reported either.
+func CheckIndexUsage(s *schema.Schema, usage []db.IndexUsage) []Issue {
+	reads := make(map[string]int64, len(usage))
+	for _, u := range usage {
+		reads[u.SchemaName+"."+u.TableName+"@"+u.IndexName] = u.TotalReads
+	}
+
+	var issues []Issue
+	for _, table := range s.Tables {
+		tableName := table.ResolvedName()
+		for _, idx := range collectTableIndexes(table.Ast) {
+			if idx.unique {
+				continue
+			}
+			n, ok := reads[tableName+"@"+idx.name]
+			if !ok || n > 0 {
+				continue
+			}
+			issues = append(issues, Issue{
+				Rule:        RuleUnusedIndex,
+				Table:       tableName,
+				Constraint:  idx.name,
+				Description: fmt.Sprintf("Index on (%s) hasn't been read since the index usage statistics were last reset, but every write to the table updates it", formatColumnList(idx.keys)),
+				Suggestion:  fmt.Sprintf("Remove INDEX %s from the table definition if no query needs it", idx.name),
+				Source:      table.Source,
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,179 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestCheckTableRedundantIndexes(t *testing.T) {
+	tests := []struct {
+		name     string
+		tableSQL string
+		expected map[string]string // Reported index -> description
+	}{
+		{
+			name: "distinct indexes",
+			tableSQL: `CREATE TABLE orders (
+				id INT PRIMARY KEY,
+				user_id INT,
+				created_at TIMESTAMPTZ,
+				INDEX orders_user_id_idx (user_id),
+				INDEX orders_created_at_idx (created_at)
+			)`,
+		},
+		{
+			name: "exact duplicate reports the second index",
+			tableSQL: `CREATE TABLE orders (
+				id INT PRIMARY KEY,
+				user_id INT,
+				INDEX orders_user_id_idx (user_id),
+				INDEX orders_user_idx (user_id ASC)
+			)`,
+			expected: map[string]string{"orders_user_idx": "Index on (user_id) duplicates orders_user_id_idx"},
+		},
+		{
+			name: "prefix of a longer index",
+			tableSQL: `CREATE TABLE orders (
+				id INT PRIMARY KEY,
+				user_id INT,
+				created_at TIMESTAMPTZ,
+				INDEX orders_user_id_idx (user_id),
+				INDEX orders_user_id_created_at_idx (user_id, created_at DESC)
+			)`,
+			expected: map[string]string{"orders_user_id_idx": "Index on (user_id) is a prefix of orders_user_id_created_at_idx on (user_id, created_at DESC), which serves the same queries"},
+		},
+		{
+			name: "prefix of the primary key",
+			tableSQL: `CREATE TABLE order_items (
+				order_id INT,
+				item_id INT,
+				quantity INT,
+				CONSTRAINT order_items_pkey PRIMARY KEY (order_id, item_id),
+				INDEX order_items_order_id_idx (order_id) STORING (quantity)
+			)`,
+			expected: map[string]string{"order_items_order_id_idx": "Index on (order_id) is a prefix of order_items_pkey on (order_id, item_id), which serves the same queries"},
+		},
+		{
+			name: "duplicate of a column primary key",
+			tableSQL: `CREATE TABLE orders (
+				id INT PRIMARY KEY,
+				INDEX orders_id_idx (id)
+			)`,
+			expected: map[string]string{"orders_id_idx": "Index on (id) duplicates orders_pkey"},
+		},
+		{
+			name: "duplicate of a unique index",
+			tableSQL: `CREATE TABLE users (
+				id INT PRIMARY KEY,
+				email STRING,
+				UNIQUE INDEX users_email_key (email),
+				INDEX users_email_idx (email)
+			)`,
+			expected: map[string]string{"users_email_idx": "Index on (email) duplicates users_email_key"},
+		},
+		{
+			name: "different direction",
+			tableSQL: `CREATE TABLE orders (
+				id INT PRIMARY KEY,
+				created_at TIMESTAMPTZ,
+				INDEX orders_created_at_idx (created_at),
+				INDEX orders_created_at_desc_idx (created_at DESC)
+			)`,
+		},
+		{
+			name: "stored columns the longer index lacks",
+			tableSQL: `CREATE TABLE orders (
+				id INT PRIMARY KEY,
+				user_id INT,
+				created_at TIMESTAMPTZ,
+				total DECIMAL,
+				INDEX orders_user_id_idx (user_id) STORING (total),
+				INDEX orders_user_id_created_at_idx (user_id, created_at)
+			)`,
+		},
+		{
+			name: "duplicate with fewer stored columns",
+			tableSQL: `CREATE TABLE orders (
+				id INT PRIMARY KEY,
+				user_id INT,
+				total DECIMAL,
+				INDEX orders_user_id_total_idx (user_id) STORING (total),
+				INDEX orders_user_id_idx (user_id)
+			)`,
+			expected: map[string]string{"orders_user_id_idx": "Index on (user_id) duplicates orders_user_id_total_idx"},
+		},
+		{
+			name: "partial, inverted and unique indexes are left alone",
+			tableSQL: `CREATE TABLE docs (
+				id INT PRIMARY KEY,
+				owner_id INT,
+				body JSONB,
+				INDEX docs_owner_id_idx (owner_id, id),
+				INDEX docs_active_owner_idx (owner_id) WHERE body IS NOT NULL,
+				UNIQUE INDEX docs_owner_key (owner_id),
+				INVERTED INDEX docs_body_idx (body),
+				INVERTED INDEX docs_body_idx2 (body)
+			)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := parser.ParseOne(tt.tableSQL)
+			require.NoError(t, err)
+
+			actual := make(map[string]string)
+			for _, issue := range checkTableRedundantIndexes("public.test", stmt.AST.(*tree.CreateTable)) {
+				assert.Equal(t, RuleRedundantIndex, issue.Rule)
+				assert.Equal(t, "public.test", issue.Table)
+				actual[issue.Constraint] = issue.Description
+			}
+			if tt.expected == nil {
+				tt.expected = map[string]string{}
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCheckIndexUsage(t *testing.T) {
+	stmt, err := parser.ParseOne(`CREATE TABLE orders (
+		id INT PRIMARY KEY,
+		user_id INT,
+		created_at TIMESTAMPTZ,
+		code STRING,
+		INDEX orders_user_id_idx (user_id),
+		INDEX orders_created_at_idx (created_at),
+		INDEX orders_new_idx (user_id, created_at),
+		UNIQUE INDEX orders_code_key (code)
+	)`)
+	require.NoError(t, err)
+	s := schema.NewSchema(stmt.AST)
+	s.Tables[0].Source = schema.SourceLocation{File: "tables/orders.sql", Line: 1}
+
+	usage := []db.IndexUsage{
+		{SchemaName: "public", TableName: "orders", IndexName: "orders_user_id_idx", TotalReads: 12},
+		{SchemaName: "public", TableName: "orders", IndexName: "orders_created_at_idx"},
+		{SchemaName: "public", TableName: "orders", IndexName: "orders_code_key"},
+		// Only in the database, e.g. dropped from the definitions already
+		{SchemaName: "public", TableName: "orders", IndexName: "orders_old_idx"},
+	}
+
+	issues := CheckIndexUsage(s, usage)
+	require.Len(t, issues, 1)
+	assert.Equal(t, Issue{
+		Rule:        RuleUnusedIndex,
+		Table:       "public.orders",
+		Constraint:  "orders_created_at_idx",
+		Description: "Index on (created_at) hasn't been read since the index usage statistics were last reset, but every write to the table updates it",
+		Suggestion:  "Remove INDEX orders_created_at_idx from the table definition if no query needs it",
+		Source:      schema.SourceLocation{File: "tables/orders.sql", Line: 1},
+	}, issues[0])
+}
@@ -0,0 +1,794 @@
+// Package lint checks a schema for problems that are valid SQL but likely
+// mistakes, like foreign keys without a covering index.
+package lint
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/idxtype"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree/treebin"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/types"
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// Issue represents a potential problem found in the schema
+type Issue struct {
+	Rule        string
+	Table       string
+	Constraint  string
+	Description string
+	Suggestion  string
+	Source      schema.SourceLocation
+}
+
+// withSource sets the definition location on each issue
+func withSource(issues []Issue, source schema.SourceLocation) []Issue {
+	for i := range issues {
+		issues[i].Source = source
+	}
+	return issues
+}
+
+// Disable represents a parsed -- scurry:lint-disable directive
+type Disable struct {
+	Rule       string // e.g. "nullable-unique"
+	Table      string // e.g. "public.users" once loaded (empty = all tables in file)
+	Constraint string // e.g. "phone_key" (empty = all constraints on table)
+}
+
+// Check runs every lint rule over the schema. Lint-disable directives aren't
+// applied; filter the issues with IsSuppressed.
+func Check(s *schema.Schema) []Issue {
+	var issues []Issue
+	issues = append(issues, checkForeignKeyIndexes(s)...)
+	issues = append(issues, checkNullableUniqueColumns(s)...)
+	issues = append(issues, checkTTLIndexes(s)...)
+	issues = append(issues, checkVectorIndexes(s)...)
+	issues = append(issues, checkRedundantIndexes(s)...)
+	return issues
+}
+
+// checkForeignKeyIndexes checks that all foreign keys have a covering index
+func checkForeignKeyIndexes(s *schema.Schema) []Issue {
+	var issues []Issue
+
+	for _, table := range s.Tables {
+		tableName := table.ResolvedName()
+		tableIssues := checkTableForeignKeyIndexes(tableName, table.Ast)
+		issues = append(issues, withSource(tableIssues, table.Source)...)
+	}
+
+	return issues
+}
+
+func checkTableForeignKeyIndexes(tableName string, table *tree.CreateTable) []Issue {
+	var issues []Issue
+
+	// Collect all indexes (explicit indexes + primary key + unique constraints)
+	indexedPrefixes := collectIndexPrefixes(table)
+
+	// Check each foreign key
+	for _, def := range table.Defs {
+		fk, ok := def.(*tree.ForeignKeyConstraintTableDef)
+		if !ok {
+			continue
+		}
+
+		// Get the columns in this foreign key
+		fkCols := make([]string, len(fk.FromCols))
+		for i, col := range fk.FromCols {
+			fkCols[i] = col.Normalize()
+		}
+
+		// Check if any index covers these columns as a prefix
+		if !hasCoveringIndex(fkCols, indexedPrefixes) {
+			constraintName := fk.Name.Normalize()
+			if constraintName == "" {
+				constraintName = fmt.Sprintf("fk_%s", fkCols[0])
+			}
+
+			issues = append(issues, Issue{
+				Rule:        "fk-missing-index",
+				Table:       tableName,
+				Constraint:  constraintName,
+				Description: fmt.Sprintf("Foreign key on (%s) has no covering index", formatColumnList(fkCols)),
+				Suggestion:  fmt.Sprintf("Add INDEX (%s) to the table definition", formatColumnList(fkCols)),
+			})
+		}
+	}
+
+	return issues
+}
+
+// collectIndexPrefixes returns all column prefixes that are covered by indexes
+// An index on (a, b, c) covers prefixes: [a], [a, b], [a, b, c]
+func collectIndexPrefixes(table *tree.CreateTable) [][]string {
+	var prefixes [][]string
+
+	for _, def := range table.Defs {
+		switch d := def.(type) {
+		case *tree.IndexTableDef:
+			// Regular index
+			cols := getIndexKeyColumns(d.Columns)
+			prefixes = append(prefixes, allPrefixes(cols)...)
+
+		case *tree.UniqueConstraintTableDef:
+			// Unique constraint (including primary key) creates an index
+			cols := getIndexKeyColumns(d.Columns)
+			prefixes = append(prefixes, allPrefixes(cols)...)
+		}
+	}
+
+	return prefixes
+}
+
+// getIndexKeyColumns extracts the key column names from an index element list
+// (excludes STORING columns which don't help with index lookups)
+func getIndexKeyColumns(columns tree.IndexElemList) []string {
+	cols := make([]string, 0, len(columns))
+	for _, col := range columns {
+		if col.Column != "" {
+			cols = append(cols, col.Column.Normalize())
+		}
+	}
+	return cols
+}
+
+// allPrefixes returns all prefixes of a column list
+// e.g., [a, b, c] -> [[a], [a, b], [a, b, c]]
+func allPrefixes(cols []string) [][]string {
+	if len(cols) == 0 {
+		return nil
+	}
+	prefixes := make([][]string, len(cols))
+	for i := range cols {
+		prefix := make([]string, i+1)
+		copy(prefix, cols[:i+1])
+		prefixes[i] = prefix
+	}
+	return prefixes
+}
+
+// hasCoveringIndex checks if the foreign key columns are covered by any index prefix
+func hasCoveringIndex(fkCols []string, indexPrefixes [][]string) bool {
+	for _, prefix := range indexPrefixes {
+		if columnsMatch(fkCols, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnsMatch checks if two column lists are equal
+func columnsMatch(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func formatColumnList(cols []string) string {
+	if len(cols) == 1 {
+		return cols[0]
+	}
+	result := cols[0]
+	for _, col := range cols[1:] {
+		result += ", " + col
+	}
+	return result
+}
+
+// checkNullableUniqueColumns checks that unique indexes/constraints don't contain nullable columns.
+// In SQL, NULL != NULL, so a unique constraint on a nullable column doesn't actually enforce uniqueness
+// for NULL values — multiple rows can have NULL in the same unique column.
+func checkNullableUniqueColumns(s *schema.Schema) []Issue {
+	var issues []Issue
+
+	for _, table := range s.Tables {
+		tableName := table.ResolvedName()
+		tableIssues := checkTableNullableUniqueColumns(tableName, table.Ast)
+		issues = append(issues, withSource(tableIssues, table.Source)...)
+	}
+
+	return issues
+}
+
+func checkTableNullableUniqueColumns(tableName string, table *tree.CreateTable) []Issue {
+	var issues []Issue
+
+	// Build a map of column name -> column definition for nullability lookups
+	columns := make(map[string]*tree.ColumnTableDef)
+	for _, def := range table.Defs {
+		col, ok := def.(*tree.ColumnTableDef)
+		if !ok {
+			continue
+		}
+		columns[col.Name.Normalize()] = col
+	}
+
+	for _, def := range table.Defs {
+		switch d := def.(type) {
+		case *tree.UniqueConstraintTableDef:
+			// Skip primary keys — PK columns are implicitly NOT NULL
+			if d.PrimaryKey {
+				continue
+			}
+			notNullGuarded := collectIsNotNullColumns(d.Predicate)
+			checkUniqueColumnsNullability(tableName, d.Name.Normalize(), getIndexKeyColumns(d.Columns), columns, notNullGuarded, &issues)
+
+		}
+	}
+
+	return issues
+}
+
+// collectIsNotNullColumns extracts column names guarded by IS NOT NULL in a predicate expression.
+// It handles single IS NOT NULL expressions and AND-combined expressions.
+func collectIsNotNullColumns(predicate tree.Expr) map[string]bool {
+	cols := make(map[string]bool)
+	collectIsNotNullColumnsRecursive(predicate, cols)
+	return cols
+}
+
+func collectIsNotNullColumnsRecursive(expr tree.Expr, cols map[string]bool) {
+	if expr == nil {
+		return
+	}
+	switch e := expr.(type) {
+	case *tree.IsNotNullExpr:
+		if name, ok := e.Expr.(*tree.UnresolvedName); ok {
+			cols[name.String()] = true
+		}
+	case *tree.AndExpr:
+		collectIsNotNullColumnsRecursive(e.Left, cols)
+		collectIsNotNullColumnsRecursive(e.Right, cols)
+	}
+}
+
+func checkUniqueColumnsNullability(tableName, constraintName string, cols []string, columns map[string]*tree.ColumnTableDef, notNullGuarded map[string]bool, issues *[]Issue) {
+	for _, colName := range cols {
+		col, ok := columns[colName]
+		if !ok {
+			continue
+		}
+		if col.Nullable.Nullability == tree.NotNull {
+			continue
+		}
+		// Skip columns that are guarded by a WHERE col IS NOT NULL predicate
+		if notNullGuarded[colName] {
+			continue
+		}
+		// Column is nullable (either explicitly NULL or default/silent null)
+		if constraintName == "" {
+			constraintName = fmt.Sprintf("unique_%s", formatColumnList(cols))
+		}
+		*issues = append(*issues, Issue{
+			Rule:        "nullable-unique",
+			Table:       tableName,
+			Constraint:  constraintName,
+			Description: fmt.Sprintf("Unique constraint on (%s) includes nullable column %q (NULL values are not considered equal, so uniqueness is not enforced for NULLs)", formatColumnList(cols), colName),
+			Suggestion:  fmt.Sprintf("Make column %q NOT NULL, or add a partial unique index with a WHERE %s IS NOT NULL clause", colName, colName),
+		})
+	}
+}
+
+// checkTTLIndexes checks that tables with ttl_expiration_expression have an index
+// on the column(s) referenced in the expression. Without such an index, the TTL
+// deletion job must perform full table scans to find expired rows.
+func checkTTLIndexes(s *schema.Schema) []Issue {
+	var issues []Issue
+
+	for _, table := range s.Tables {
+		tableName := table.ResolvedName()
+		tableIssues := checkTableTTLIndexes(tableName, table.Ast)
+		issues = append(issues, withSource(tableIssues, table.Source)...)
+	}
+
+	return issues
+}
+
+func checkTableTTLIndexes(tableName string, table *tree.CreateTable) []Issue {
+	cols := UncoveredTTLColumns(table)
+	if len(cols) == 0 {
+		return nil
+	}
+
+	return []Issue{{
+		Rule:        "ttl-missing-index",
+		Table:       tableName,
+		Constraint:  "ttl_expiration_expression",
+		Description: fmt.Sprintf("TTL expression references column(s) (%s) but no index starts with any of these columns — the TTL deletion job will not be able to use an index to find expired rows", formatColumnList(cols)),
+		Suggestion:  fmt.Sprintf("Add INDEX (%s) to the table definition", cols[0]),
+	}}
+}
+
+// TTLExpression returns the table's ttl_expiration_expression, or "" if it has none.
+func TTLExpression(table *tree.CreateTable) string {
+	for _, param := range table.StorageParams {
+		if param.Key == "ttl_expiration_expression" {
+			return getStorageParamStringValue(param.Value)
+		}
+	}
+	return ""
+}
+
+// UncoveredTTLColumns returns the columns referenced in the table's
+// ttl_expiration_expression when no non-partial index starts with any of them,
+// or nil if the table has no expression or an index already covers it.
+func UncoveredTTLColumns(table *tree.CreateTable) []string {
+	ttlExpr := TTLExpression(table)
+	if ttlExpr == "" {
+		return nil
+	}
+
+	// Parse the expression to extract column references
+	cols := extractColumnsFromExpression(ttlExpr)
+	if len(cols) == 0 {
+		return nil
+	}
+
+	// Check if any column from the TTL expression is the first column of an index
+	indexFirstCols := collectIndexFirstColumns(table)
+	for _, col := range cols {
+		if indexFirstCols[col] {
+			return nil
+		}
+	}
+	return cols
+}
+
+// getStorageParamStringValue extracts the raw string value from a storage param expression.
+func getStorageParamStringValue(value tree.Expr) string {
+	switch v := value.(type) {
+	case *tree.StrVal:
+		return v.RawString()
+	case *tree.DString:
+		return string(*v)
+	default:
+		// Fallback: use AsString and strip surrounding quotes
+		s := tree.AsString(value)
+		if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+			s = s[1 : len(s)-1]
+			s = strings.ReplaceAll(s, "''", "'")
+		}
+		return s
+	}
+}
+
+// extractColumnsFromExpression parses a SQL expression string and returns the
+// column names referenced in it.
+func extractColumnsFromExpression(expr string) []string {
+	// Wrap in SELECT to make it a valid statement for parsing
+	stmts, err := parser.Parse(fmt.Sprintf("SELECT %s", expr))
+	if err != nil {
+		return nil
+	}
+	if len(stmts) == 0 {
+		return nil
+	}
+
+	selectStmt, ok := stmts[0].AST.(*tree.Select)
+	if !ok {
+		return nil
+	}
+
+	selectClause, ok := selectStmt.Select.(*tree.SelectClause)
+	if !ok || len(selectClause.Exprs) == 0 {
+		return nil
+	}
+
+	var cols []string
+	seen := make(map[string]bool)
+	collectColumnRefs(selectClause.Exprs[0].Expr, &cols, seen)
+	return cols
+}
+
+// collectColumnRefs recursively walks an expression tree and collects column references.
+func collectColumnRefs(expr tree.Expr, cols *[]string, seen map[string]bool) {
+	if expr == nil {
+		return
+	}
+	switch e := expr.(type) {
+	case *tree.UnresolvedName:
+		name := strings.ToLower(e.Parts[0])
+		if !seen[name] {
+			seen[name] = true
+			*cols = append(*cols, name)
+		}
+	case *tree.BinaryExpr:
+		collectColumnRefs(e.Left, cols, seen)
+		collectColumnRefs(e.Right, cols, seen)
+	case *tree.ParenExpr:
+		collectColumnRefs(e.Expr, cols, seen)
+	case *tree.FuncExpr:
+		for _, arg := range e.Exprs {
+			collectColumnRefs(arg, cols, seen)
+		}
+	case *tree.CastExpr:
+		collectColumnRefs(e.Expr, cols, seen)
+	case *tree.CoalesceExpr:
+		for _, arg := range e.Exprs {
+			collectColumnRefs(arg, cols, seen)
+		}
+	case *tree.CaseExpr:
+		collectColumnRefs(e.Expr, cols, seen)
+		for _, w := range e.Whens {
+			collectColumnRefs(w.Cond, cols, seen)
+			collectColumnRefs(w.Val, cols, seen)
+		}
+		collectColumnRefs(e.Else, cols, seen)
+	}
+}
+
+// collectIndexFirstColumns returns a set of column names that are the first column
+// of any non-partial index (including primary key and unique constraints).
+func collectIndexFirstColumns(table *tree.CreateTable) map[string]bool {
+	firstCols := make(map[string]bool)
+	for _, def := range table.Defs {
+		switch d := def.(type) {
+		case *tree.IndexTableDef:
+			if d.Predicate != nil {
+				continue // Skip partial indexes
+			}
+			cols := getIndexKeyColumns(d.Columns)
+			if len(cols) > 0 {
+				firstCols[cols[0]] = true
+			}
+		case *tree.UniqueConstraintTableDef:
+			if d.Predicate != nil {
+				continue // Skip partial indexes
+			}
+			cols := getIndexKeyColumns(d.Columns)
+			if len(cols) > 0 {
+				firstCols[cols[0]] = true
+			}
+		}
+	}
+	return firstCols
+}
+
+// vectorOpClasses maps similarity operators to the vector index operator class
+// that serves them. An index without an operator class uses vector_l2_ops.
+var vectorOpClasses = map[treebin.BinaryOperatorSymbol]string{
+	treebin.Distance:        "vector_l2_ops",
+	treebin.CosDistance:     "vector_cosine_ops",
+	treebin.NegInnerProduct: "vector_ip_ops",
+}
+
+// checkVectorIndexes checks that VECTOR columns compared with a similarity
+// operator in a view have a vector index with the matching operator class.
+// Without one, every similarity search scans the whole table.
+func checkVectorIndexes(s *schema.Schema) []Issue {
+	tables := make(map[string]*tree.CreateTable, len(s.Tables))
+	for _, table := range s.Tables {
+		tables[table.ResolvedName()] = table.Ast
+	}
+
+	var issues []Issue
+	for _, view := range s.Views {
+		viewIssues := checkViewVectorIndexes(view.Ast, tables)
+		issues = append(issues, withSource(viewIssues, view.Source)...)
+	}
+	return issues
+}
+
+func checkViewVectorIndexes(view *tree.CreateView, tables map[string]*tree.CreateTable) []Issue {
+	if view.AsSource == nil {
+		return nil
+	}
+	from := viewFromTables(view.AsSource, tables)
+
+	var issues []Issue
+	reported := make(map[string]bool)
+	_, _ = tree.SimpleStmtVisit(view.AsSource, func(expr tree.Expr) (bool, tree.Expr, error) {
+		binary, ok := expr.(*tree.BinaryExpr)
+		if !ok {
+			return true, expr, nil
+		}
+		opClass, ok := vectorOpClasses[binary.Operator.Symbol]
+		if !ok {
+			return true, expr, nil
+		}
+		for _, operand := range []tree.Expr{binary.Left, binary.Right} {
+			tableName, column, ok := resolveVectorColumn(operand, from, tables)
+			if !ok || hasVectorIndex(tables[tableName], column, opClass) {
+				continue
+			}
+			key := tableName + "." + column + "." + opClass
+			if reported[key] {
+				continue
+			}
+			reported[key] = true
+			issues = append(issues, Issue{
+				Rule:        "vector-missing-index",
+				Table:       tableName,
+				Constraint:  column,
+				Description: fmt.Sprintf("View %s compares VECTOR column %q with %s but no vector index uses %s — similarity searches will scan the whole table", qualifiedTableName(view.Name), column, binary.Operator, opClass),
+				Suggestion:  fmt.Sprintf("Add VECTOR INDEX (%s %s) to the table definition", column, opClass),
+			})
+		}
+		return true, expr, nil
+	})
+	return issues
+}
+
+// viewFromTables returns the tables in a view's top-level FROM clause, keyed by
+// the name or alias the view refers to them with
+func viewFromTables(sel *tree.Select, tables map[string]*tree.CreateTable) map[string]string {
+	from := make(map[string]string)
+	clause, ok := sel.Select.(*tree.SelectClause)
+	if !ok {
+		return from
+	}
+	var walk func(expr tree.TableExpr)
+	walk = func(expr tree.TableExpr) {
+		switch e := expr.(type) {
+		case *tree.AliasedTableExpr:
+			name, ok := e.Expr.(*tree.TableName)
+			if !ok {
+				return
+			}
+			qualified := qualifiedTableName(*name)
+			if _, ok := tables[qualified]; !ok {
+				return
+			}
+			if e.As.Alias != "" {
+				from[e.As.Alias.Normalize()] = qualified
+			} else {
+				from[name.Table()] = qualified
+			}
+		case *tree.JoinTableExpr:
+			walk(e.Left)
+			walk(e.Right)
+		case *tree.ParenTableExpr:
+			walk(e.Expr)
+		}
+	}
+	for _, expr := range clause.From.Tables {
+		walk(expr)
+	}
+	return from
+}
+
+// resolveVectorColumn returns the table and name of the VECTOR column an
+// operand refers to, if it is one
+func resolveVectorColumn(expr tree.Expr, from map[string]string, tables map[string]*tree.CreateTable) (string, string, bool) {
+	name, ok := tree.StripParens(expr).(*tree.UnresolvedName)
+	if !ok || name.Star {
+		return "", "", false
+	}
+	column := strings.ToLower(name.Parts[0])
+
+	var candidates []string
+	switch name.NumParts {
+	case 1:
+		candidates = slices.Sorted(maps.Values(from))
+	case 2:
+		if tableName, ok := from[strings.ToLower(name.Parts[1])]; ok {
+			candidates = append(candidates, tableName)
+		}
+	}
+	for _, tableName := range candidates {
+		for _, def := range tables[tableName].Defs {
+			col, ok := def.(*tree.ColumnTableDef)
+			if !ok || col.Name.Normalize() != column {
+				continue
+			}
+			if t, ok := col.Type.(*types.T); ok && t.Family() == types.PGVectorFamily {
+				return tableName, column, true
+			}
+			return "", "", false
+		}
+	}
+	return "", "", false
+}
+
+// hasVectorIndex reports whether table has a vector index on column with the
+// given operator class
+func hasVectorIndex(table *tree.CreateTable, column, opClass string) bool {
+	for _, def := range table.Defs {
+		index, ok := def.(*tree.IndexTableDef)
+		if !ok || index.Type != idxtype.VECTOR || len(index.Columns) == 0 {
+			continue
+		}
+		// The vector column is the last key column; any before it are prefixes
+		last := index.Columns[len(index.Columns)-1]
+		indexOpClass := last.OpClass.Normalize()
+		if indexOpClass == "" {
+			indexOpClass = "vector_l2_ops"
+		}
+		if last.Column.Normalize() == column && indexOpClass == opClass {
+			return true
+		}
+	}
+	return false
+}
+
+const disablePrefix = "-- scurry:lint-disable="
+
+// parseDisables scans lines from the top of a SQL file for
+// -- scurry:lint-disable=<rule>[:[<schema>.]<table>[.<constraint>]] directives.
+// It stops at the first non-comment, non-empty line.
+func parseDisables(sql string) []Disable {
+	var directives []Disable
+	scanner := bufio.NewScanner(strings.NewReader(sql))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if !strings.HasPrefix(line, disablePrefix) {
+			continue
+		}
+		value := strings.TrimPrefix(line, disablePrefix)
+		// Strip inline comments: "nullable-unique -- explanation" → "nullable-unique"
+		if idx := strings.Index(value, " "); idx != -1 {
+			value = value[:idx]
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		d := Disable{}
+		// Split rule from optional table.constraint qualifier. The table may
+		// have a schema, so the constraint is after the last dot.
+		if colonIdx := strings.IndexByte(value, ':'); colonIdx != -1 {
+			d.Rule = value[:colonIdx]
+			qualifier := value[colonIdx+1:]
+			if dotIdx := strings.LastIndexByte(qualifier, '.'); dotIdx != -1 {
+				d.Table = qualifier[:dotIdx]
+				d.Constraint = qualifier[dotIdx+1:]
+			} else {
+				d.Table = qualifier
+			}
+		} else {
+			d.Rule = value
+		}
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+// LoadDisables walks multiple definition directories and merges lint-disable directives.
+func LoadDisables(fs afero.Fs, dirPaths []string) (map[string][]Disable, error) {
+	result := make(map[string][]Disable)
+	for _, dirPath := range dirPaths {
+		dirResult, err := loadDisables(fs, dirPath)
+		if err != nil {
+			return nil, err
+		}
+		for table, disables := range dirResult {
+			result[table] = append(result[table], disables...)
+		}
+	}
+	return result, nil
+}
+
+// loadDisables walks the definition directory, parses lint-disable directives
+// from each .sql file, and associates them with the table names defined in that file.
+// Returns a map from qualified table name (schema.table) to the directives that
+// apply to it, with the tables the directives name qualified the same way.
+func loadDisables(fs afero.Fs, dirPath string) (map[string][]Disable, error) {
+	result := make(map[string][]Disable)
+
+	err := afero.Walk(fs, dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".sql") {
+			return nil
+		}
+
+		content, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+
+		sql := string(content)
+		directives := parseDisables(sql)
+		if len(directives) == 0 {
+			return nil
+		}
+
+		// Parse SQL to find table names defined in this file
+		stmts, err := parser.Parse(sql)
+		if err != nil {
+			return nil // Parsing errors will be caught by schema loading
+		}
+
+		var tables []string
+		for _, stmt := range stmts {
+			if ct, ok := stmt.AST.(*tree.CreateTable); ok {
+				tables = append(tables, qualifiedTableName(ct.Table))
+			}
+		}
+		directives = qualifyDisables(directives, tables)
+		for _, table := range tables {
+			result[table] = append(result[table], directives...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// qualifyDisables qualifies the tables named by directives with their
+// schema. A table without one is the file's table of that name, or else in
+// public. A two-part qualifier naming one of the file's tables, like
+// "app.users", is that table rather than a constraint on a table named "app".
+func qualifyDisables(directives []Disable, tables []string) []Disable {
+	qualified := make([]Disable, 0, len(directives))
+	for _, d := range directives {
+		switch {
+		case d.Table == "":
+		case strings.Contains(d.Table, "."):
+		case d.Constraint != "" && slices.Contains(tables, d.Table+"."+d.Constraint):
+			d.Table, d.Constraint = d.Table+"."+d.Constraint, ""
+		default:
+			name := "public." + d.Table
+			for _, table := range tables {
+				if strings.HasSuffix(table, "."+d.Table) {
+					name = table
+					break
+				}
+			}
+			d.Table = name
+		}
+		qualified = append(qualified, d)
+	}
+	return qualified
+}
+
+func qualifiedTableName(name tree.TableName) string {
+	schemaName := "public"
+	if name.ExplicitSchema {
+		schemaName = name.Schema()
+	}
+	return schemaName + "." + name.Table()
+}
+
+// IsSuppressed checks if an issue is suppressed by any lint-disable directive.
+func IsSuppressed(issue Issue, disables map[string][]Disable) bool {
+	directives, ok := disables[issue.Table]
+	if !ok {
+		return false
+	}
+	for _, d := range directives {
+		if d.Rule != issue.Rule {
+			continue
+		}
+		// File-wide: no table qualifier
+		if d.Table == "" {
+			return true
+		}
+		// Table-wide: matches table, no constraint qualifier
+		if d.Table == issue.Table && d.Constraint == "" {
+			return true
+		}
+		// Specific constraint
+		if d.Table == issue.Table && d.Constraint == issue.Constraint {
+			return true
+		}
+	}
+	return false
+}
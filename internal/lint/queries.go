@@ -0,0 +1,185 @@
+package lint
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// Query is a representative query from the application's workload, checked
+// against the query plan CockroachDB chooses for it
+type Query struct {
+	SQL    string
+	Source schema.SourceLocation
+}
+
+// LoadQueries reads every statement in the .sql files under dirPath, in file
+// name order
+func LoadQueries(fs afero.Fs, dirPath string) ([]Query, error) {
+	var paths []string
+	err := afero.Walk(fs, dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), ".sql") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+
+	var queries []Query
+	for _, path := range paths {
+		content, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		fileQueries, err := parseQueries(string(content), path)
+		if err != nil {
+			return nil, fmt.Errorf("in file %s: %w", path, err)
+		}
+		queries = append(queries, fileQueries...)
+	}
+	return queries, nil
+}
+
+func parseQueries(sql, path string) ([]Query, error) {
+	statements, err := parser.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]Query, 0, len(statements))
+	offset := 0
+	for _, stmt := range statements {
+		line := 0
+		if idx := strings.Index(sql[offset:], stmt.SQL); idx != -1 {
+			line = strings.Count(sql[:offset+idx], "\n") + 1
+			offset += idx + len(stmt.SQL)
+		}
+		queries = append(queries, Query{
+			SQL:    stmt.SQL,
+			Source: schema.SourceLocation{File: path, Line: line},
+		})
+	}
+	return queries, nil
+}
+
+// CheckQueryPlan checks the EXPLAIN output of a workload query for scans of a
+// whole table and for the indexes CockroachDB recommends adding. A recommendation
+// for a table that's fully scanned becomes the suggestion for that scan.
+func CheckQueryPlan(query Query, plan []string) []Issue {
+	recommendations := indexRecommendations(plan)
+
+	var issues []Issue
+	for _, scanned := range fullScans(plan) {
+		table, index, _ := strings.Cut(scanned, "@")
+		table = qualifyPlanTable(table)
+		suggestion := "Add an index on the columns the query filters by, or suppress this check for the table if the scan is expected"
+		if rec, ok := recommendations[table]; ok {
+			suggestion = fmt.Sprintf("Add the index CockroachDB recommends: %s", rec)
+			delete(recommendations, table)
+		}
+		issues = append(issues, Issue{
+			Rule:        "query-full-scan",
+			Table:       table,
+			Constraint:  index,
+			Description: fmt.Sprintf("Query scans all of %s: %s", scanned, collapseWhitespace(query.SQL)),
+			Suggestion:  suggestion,
+			Source:      query.Source,
+		})
+	}
+
+	for _, table := range slices.Sorted(maps.Keys(recommendations)) {
+		issues = append(issues, Issue{
+			Rule:        "query-missing-index",
+			Table:       table,
+			Description: fmt.Sprintf("CockroachDB recommends an index on %s for query: %s", table, collapseWhitespace(query.SQL)),
+			Suggestion:  recommendations[table],
+			Source:      query.Source,
+		})
+	}
+	return issues
+}
+
+// fullScans returns the "table@index" of every scan in the plan that reads a
+// whole index. EXPLAIN shows each scan's table before its spans:
+//
+//   - scan
+//     table: users@users_pkey
+//     spans: FULL SCAN
+//
+// Scans with a soft limit usually stop early, so they aren't counted.
+func fullScans(plan []string) []string {
+	var scans []string
+	table := ""
+	for _, line := range plan {
+		line = strings.TrimLeft(line, " │└├─•")
+		if t, ok := strings.CutPrefix(line, "table: "); ok {
+			table = strings.TrimSpace(t)
+			continue
+		}
+		if spans, ok := strings.CutPrefix(line, "spans: "); ok && table != "" {
+			if strings.HasPrefix(spans, "FULL SCAN") && !strings.Contains(spans, "SOFT LIMIT") {
+				scans = append(scans, table)
+			}
+			table = ""
+		}
+	}
+	return scans
+}
+
+// indexRecommendations returns the SQL of the indexes EXPLAIN recommends,
+// keyed by the table they're on:
+//
+//	index recommendations: 1
+//	1. type: index creation
+//	   SQL command: CREATE INDEX ON users (email);
+func indexRecommendations(plan []string) map[string]string {
+	recommendations := make(map[string]string)
+	for _, line := range plan {
+		_, sql, ok := strings.Cut(line, "SQL command: ")
+		if !ok {
+			_, sql, ok = strings.Cut(line, "SQL commands: ")
+		}
+		if !ok {
+			continue
+		}
+		sql = strings.TrimSpace(sql)
+		statements, err := parser.Parse(sql)
+		if err != nil {
+			continue
+		}
+		for _, stmt := range statements {
+			if create, ok := stmt.AST.(*tree.CreateIndex); ok {
+				recommendations[qualifiedTableName(create.Table)] = sql
+				break
+			}
+		}
+	}
+	return recommendations
+}
+
+// qualifyPlanTable adds the public schema to a table name from a query plan,
+// which omits the schema
+func qualifyPlanTable(table string) string {
+	if strings.Contains(table, ".") {
+		return table
+	}
+	return "public." + table
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,128 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+func TestLoadQueries(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "queries/users.sql", []byte("-- Login\nSELECT id FROM users WHERE email = 'a';\n\nSELECT *\nFROM users\nWHERE id = 1;\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "queries/admin/orders.sql", []byte("SELECT count(*) FROM orders;"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "queries/README.md", []byte("not sql"), 0644))
+
+	queries, err := LoadQueries(fs, "queries")
+	require.NoError(t, err)
+	assert.Equal(t, []Query{
+		{SQL: "SELECT count(*) FROM orders", Source: schema.SourceLocation{File: "queries/admin/orders.sql", Line: 1}},
+		{SQL: "SELECT id FROM users WHERE email = 'a'", Source: schema.SourceLocation{File: "queries/users.sql", Line: 2}},
+		{SQL: "SELECT *\nFROM users\nWHERE id = 1", Source: schema.SourceLocation{File: "queries/users.sql", Line: 4}},
+	}, queries)
+
+	_, err = LoadQueries(fs, "missing")
+	assert.Error(t, err)
+}
+
+func TestCheckQueryPlan(t *testing.T) {
+	query := Query{SQL: "SELECT *\nFROM users WHERE email = 'a'", Source: schema.SourceLocation{File: "queries/users.sql", Line: 3}}
+
+	tests := []struct {
+		name     string
+		plan     []string
+		expected []Issue
+	}{
+		{
+			name: "constrained scan",
+			plan: []string{
+				"distribution: local",
+				"vectorized: true",
+				"",
+				"• scan",
+				"  missing stats",
+				"  table: users@users_email_key",
+				"  spans: [/'a' - /'a']",
+			},
+		},
+		{
+			name: "full scan with a recommendation",
+			plan: []string{
+				"• filter",
+				"│ filter: email = 'a'",
+				"│",
+				"└── • scan",
+				"      missing stats",
+				"      table: users@users_pkey",
+				"      spans: FULL SCAN",
+				"",
+				"index recommendations: 1",
+				"1. type: index creation",
+				"   SQL command: CREATE INDEX ON users (email);",
+			},
+			expected: []Issue{{
+				Rule:        "query-full-scan",
+				Table:       "public.users",
+				Constraint:  "users_pkey",
+				Description: "Query scans all of users@users_pkey: SELECT * FROM users WHERE email = 'a'",
+				Suggestion:  "Add the index CockroachDB recommends: CREATE INDEX ON users (email);",
+				Source:      query.Source,
+			}},
+		},
+		{
+			name: "full scan without a recommendation",
+			plan: []string{
+				"• scan",
+				"  table: users@users_pkey",
+				"  spans: FULL SCAN",
+			},
+			expected: []Issue{{
+				Rule:        "query-full-scan",
+				Table:       "public.users",
+				Constraint:  "users_pkey",
+				Description: "Query scans all of users@users_pkey: SELECT * FROM users WHERE email = 'a'",
+				Suggestion:  "Add an index on the columns the query filters by, or suppress this check for the table if the scan is expected",
+				Source:      query.Source,
+			}},
+		},
+		{
+			name: "soft limit scans aren't full scans",
+			plan: []string{
+				"• scan",
+				"  table: users@users_pkey",
+				"  spans: FULL SCAN (SOFT LIMIT)",
+			},
+		},
+		{
+			name: "recommendation without a full scan",
+			plan: []string{
+				"• index join",
+				"│ table: users@users_pkey",
+				"│",
+				"└── • scan",
+				"      table: users@users_email_idx",
+				"      spans: [/'a' - /'a']",
+				"",
+				"index recommendations: 1",
+				"1. type: index replacement",
+				"   SQL commands: CREATE INDEX ON app.users (email) STORING (name); DROP INDEX app.users@users_email_idx;",
+			},
+			expected: []Issue{{
+				Rule:        "query-missing-index",
+				Table:       "app.users",
+				Description: "CockroachDB recommends an index on app.users for query: SELECT * FROM users WHERE email = 'a'",
+				Suggestion:  "CREATE INDEX ON app.users (email) STORING (name); DROP INDEX app.users@users_email_idx;",
+				Source:      query.Source,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CheckQueryPlan(query, tt.plan))
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package logging writes scurry's progress output. The default text format
+// is the colored, human-readable output scurry has always printed; the json
+// format writes one JSON object per line so CI log aggregation can parse it.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Kinds of message, which decide how text output is styled. They're included
+// in JSON output as "kind".
+const (
+	KindPlain   = "plain"
+	KindInfo    = "info"
+	KindSuccess = "success"
+	KindSubtle  = "subtle"
+	KindHeader  = "header"
+	KindWarning = "warning"
+	KindError   = "error"
+)
+
+var (
+	mu         sync.Mutex
+	format     = FormatText
+	level      = slog.LevelInfo
+	timestamps bool
+	// output is nil to write to whatever os.Stdout is at the time
+	output io.Writer
+	now    = time.Now
+)
+
+// Configure sets the log format ("text" or "json") and minimum level ("debug",
+// "info", "warn" or "error"). Timestamps are always included in JSON output;
+// withTimestamps adds them to text output too.
+func Configure(logFormat, logLevel string, withTimestamps bool) error {
+	if logFormat != FormatText && logFormat != FormatJSON {
+		return fmt.Errorf("unknown log format %q (use text or json)", logFormat)
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(logLevel)); err != nil {
+		return fmt.Errorf("unknown log level %q (use debug, info, warn or error)", logLevel)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	format = logFormat
+	level = l
+	timestamps = withTimestamps
+	return nil
+}
+
+// SetOutput sets where log lines are written. Nil writes to os.Stdout.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	output = w
+}
+
+// IsJSON reports whether output is JSON, for commands that need to keep
+// free-form output like tables out of the log
+func IsJSON() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return format == FormatJSON
+}
+
+// Debug logs details that are only shown at the debug level
+func Debug(msg string) { write(slog.LevelDebug, KindSubtle, msg) }
+
+// Info logs an informational message
+func Info(msg string) { write(slog.LevelInfo, KindInfo, msg) }
+
+// Success logs that something completed
+func Success(msg string) { write(slog.LevelInfo, KindSuccess, msg) }
+
+// Subtle logs a de-emphasized informational message
+func Subtle(msg string) { write(slog.LevelInfo, KindSubtle, msg) }
+
+// Header logs the heading of a section of output
+func Header(msg string) { write(slog.LevelInfo, KindHeader, msg) }
+
+// Warning logs a warning
+func Warning(msg string) { write(slog.LevelWarn, KindWarning, msg) }
+
+// Error logs an error
+func Error(msg string) { write(slog.LevelError, KindError, msg) }
+
+// Print logs an unstyled message, which may already contain styling like
+// highlighted SQL
+func Print(msg string) { write(slog.LevelInfo, KindPlain, msg) }
+
+// Newline separates sections of text output. It writes nothing in JSON output.
+func Newline() {
+	mu.Lock()
+	defer mu.Unlock()
+	if format == FormatText && level <= slog.LevelInfo {
+		fmt.Fprintln(writer())
+	}
+}
+
+func write(l slog.Level, kind, msg string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l < level {
+		return
+	}
+
+	w := writer()
+	t := now()
+	if format == FormatJSON {
+		msg = strings.TrimSpace(ansiEscape.ReplaceAllString(msg, ""))
+		if msg == "" {
+			return
+		}
+		// Errors writing logs have nowhere to be reported
+		_ = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}).
+			Handle(context.Background(), newRecord(t, l, msg, kind))
+		return
+	}
+
+	if timestamps {
+		// Keep leading newlines ahead of the timestamp, so spacing is unchanged
+		trimmed := strings.TrimLeft(msg, "\n")
+		fmt.Fprint(w, msg[:len(msg)-len(trimmed)])
+		fmt.Fprint(w, ui.Subtle(t.Format("15:04:05.000"))+" ")
+		msg = trimmed
+	}
+	fmt.Fprintln(w, style(kind, msg))
+}
+
+func newRecord(t time.Time, l slog.Level, msg, kind string) slog.Record {
+	r := slog.NewRecord(t, l, msg, 0)
+	r.AddAttrs(slog.String("kind", kind))
+	return r
+}
+
+func writer() io.Writer {
+	if output != nil {
+		return output
+	}
+	return os.Stdout
+}
+
+func style(kind, msg string) string {
+	switch kind {
+	case KindInfo:
+		return ui.Info(msg)
+	case KindSuccess:
+		return ui.Success(msg)
+	case KindSubtle:
+		return ui.Subtle(msg)
+	case KindHeader:
+		return ui.Header(msg)
+	case KindWarning:
+		return ui.Warning(msg)
+	case KindError:
+		return ui.Error(msg)
+	default:
+		return msg
+	}
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/ui"
+)
+
+// capture configures logging for a test and returns its output
+func capture(t *testing.T, logFormat, logLevel string, withTimestamps bool) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, Configure(logFormat, logLevel, withTimestamps))
+	SetOutput(&buf)
+	now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	ui.SetNoColor(true)
+	t.Cleanup(func() {
+		_ = Configure(FormatText, "info", false)
+		SetOutput(nil)
+		now = time.Now
+		ui.SetNoColor(false)
+	})
+	return &buf
+}
+
+func TestConfigure_Invalid(t *testing.T) {
+	assert.ErrorContains(t, Configure("xml", "info", false), `unknown log format "xml"`)
+	assert.ErrorContains(t, Configure(FormatText, "loud", false), `unknown log level "loud"`)
+}
+
+func TestText(t *testing.T) {
+	buf := capture(t, FormatText, "info", false)
+
+	Info("Loading migrations")
+	Debug("hidden")
+	Newline()
+	Success("✓ Done")
+	Print("SELECT 1;")
+
+	assert.Equal(t, "Loading migrations\n\n✓ Done\nSELECT 1;\n", buf.String())
+}
+
+func TestText_Timestamps(t *testing.T) {
+	buf := capture(t, FormatText, "debug", true)
+
+	Debug("details")
+	Warning("\nCareful")
+
+	assert.Equal(t, "03:04:05.000 details\n\n03:04:05.000 Careful\n", buf.String())
+}
+
+func TestText_Level(t *testing.T) {
+	buf := capture(t, FormatText, "warn", false)
+
+	Info("not shown")
+	Newline()
+	Warning("shown")
+	Error("also shown")
+
+	assert.Equal(t, "shown\nalso shown\n", buf.String())
+}
+
+func TestJSON(t *testing.T) {
+	buf := capture(t, FormatJSON, "info", false)
+
+	Newline()
+	Error("\nMigration failed: 001_users  ")
+	Print("\x1b[1mSELECT 1;\x1b[0m")
+	Debug("hidden")
+	Subtle("   ")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, map[string]any{
+		"time":  "2024-01-02T03:04:05Z",
+		"level": "ERROR",
+		"msg":   "Migration failed: 001_users",
+		"kind":  KindError,
+	}, first)
+
+	var second map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "SELECT 1;", second["msg"])
+	assert.Equal(t, "INFO", second["level"])
+	assert.Equal(t, KindPlain, second["kind"])
+	assert.True(t, IsJSON())
+}
@@ -2,6 +2,8 @@ package migration
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
 
@@ -12,16 +14,64 @@ import (
 type ClassifyResult struct {
 	Mode    MigrationMode
 	Reasons []string
+	Tables  []string // Tables whose changes made the migration async, in order
+}
+
+// Rules name the operations classification considers, for forcing their mode
+// with the migrations.classify section of the config file
+const (
+	RuleCreateIndex         = "create_index"
+	RuleAddColumnDefault    = "add_column_default"
+	RuleSetNotNull          = "set_not_null"
+	RuleAddUniqueConstraint = "add_unique_constraint"
+	RuleAddConstraint       = "add_constraint"
+	RuleValidateConstraint  = "validate_constraint"
+	RuleAlterColumnType     = "alter_column_type"
+	RuleUpdate              = "update"
+	RuleDelete              = "delete"
+	RuleInsertSelect        = "insert_select"
+	RuleImport              = "import"
+)
+
+// RuleNames are the names of the classification rules
+var RuleNames = []string{
+	RuleCreateIndex, RuleAddColumnDefault, RuleSetNotNull, RuleAddUniqueConstraint, RuleAddConstraint,
+	RuleValidateConstraint, RuleAlterColumnType, RuleUpdate, RuleDelete, RuleInsertSelect,
+	RuleImport,
+}
+
+// ClassifyRules force the mode of operations by rule name, whatever the size of
+// the table they change. Rules without a mode fall back to the table sizes.
+type ClassifyRules map[string]MigrationMode
+
+// ParseClassifyRules checks the rule names and modes from the config file
+func ParseClassifyRules(raw map[string]string) (ClassifyRules, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	rules := make(ClassifyRules, len(raw))
+	for rule, mode := range raw {
+		if !slices.Contains(RuleNames, rule) {
+			return nil, fmt.Errorf("unknown classify rule %q: expected one of %s", rule, strings.Join(RuleNames, ", "))
+		}
+		switch MigrationMode(mode) {
+		case ModeSync, ModeAsync:
+			rules[rule] = MigrationMode(mode)
+		default:
+			return nil, fmt.Errorf("invalid mode %q for classify rule %s: expected sync or async", mode, rule)
+		}
+	}
+	return rules, nil
 }
 
 // ClassifyDifferences determines whether a migration should be sync or async
-// based on the diff types and table sizes. If any operation is async, the whole
-// migration is classified as async.
-func ClassifyDifferences(diffs []schema.Difference, tableSizes *TableSizes) *ClassifyResult {
+// based on the diff types and table sizes, unless rules force an operation's
+// mode. If any operation is async, the whole migration is classified as async.
+func ClassifyDifferences(diffs []schema.Difference, tableSizes *TableSizes, rules ClassifyRules) *ClassifyResult {
 	result := &ClassifyResult{Mode: ModeSync}
 
 	for i := range diffs {
-		classifyDifference(&diffs[i], tableSizes, result)
+		classifyDifference(&diffs[i], tableSizes, rules, result)
 	}
 
 	return result
@@ -31,17 +81,32 @@ func ClassifyDifferences(diffs []schema.Difference, tableSizes *TableSizes) *Cla
 // its raw statements and table sizes. It applies the same per-statement rules as
 // ClassifyDifferences, for migrations authored directly (e.g. custom SQL supplied to
 // `migration local`) rather than generated from a schema diff.
-func ClassifyStatements(stmts []tree.Statement, tableSizes *TableSizes) *ClassifyResult {
+func ClassifyStatements(stmts []tree.Statement, tableSizes *TableSizes, rules ClassifyRules) *ClassifyResult {
 	result := &ClassifyResult{Mode: ModeSync}
 
 	for _, stmt := range stmts {
-		classifyStatement(stmt, tableSizes, result)
+		classifyStatement(stmt, tableSizes, rules, result)
 	}
 
 	return result
 }
 
-func classifyDifference(diff *schema.Difference, ts *TableSizes, result *ClassifyResult) {
+func classifyDifference(diff *schema.Difference, ts *TableSizes, rules ClassifyRules, result *ClassifyResult) {
+	// A -- scurry:classify directive overrides the rules below
+	switch diff.Classification {
+	case schema.ClassifySync:
+		return
+	case schema.ClassifyAsync:
+		result.Mode = ModeAsync
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s (forced by scurry:classify=async)", diff.Description))
+		for _, stmt := range diff.Statements() {
+			if tableName, ok := statementTable(stmt); ok {
+				addTable(result, tableName)
+			}
+		}
+		return
+	}
+
 	switch diff.Type {
 	case schema.DiffTypeTableAdded:
 		// CREATE TABLE is always sync
@@ -52,7 +117,7 @@ func classifyDifference(diff *schema.Difference, ts *TableSizes, result *Classif
 		return
 
 	case schema.DiffTypeTableModified, schema.DiffTypeTableColumnModified, schema.DiffTypeColumnTypeChanged:
-		classifyTableModification(diff, ts, result)
+		classifyTableModification(diff, ts, rules, result)
 
 	default:
 		// Schema/type/sequence/view/routine operations are always sync
@@ -60,47 +125,65 @@ func classifyDifference(diff *schema.Difference, ts *TableSizes, result *Classif
 	}
 }
 
-func classifyTableModification(diff *schema.Difference, ts *TableSizes, result *ClassifyResult) {
-	for _, stmt := range diff.MigrationStatements {
-		classifyStatement(stmt, ts, result)
+func classifyTableModification(diff *schema.Difference, ts *TableSizes, rules ClassifyRules, result *ClassifyResult) {
+	for _, stmt := range diff.Statements() {
+		classifyStatement(stmt, ts, rules, result)
 	}
 }
 
 // classifyStatement marks the result async if the single statement is an expensive
-// operation against a large table. Statements that don't touch a large table (or aren't
-// index/alter/bulk-DML operations, e.g. CREATE TABLE) leave the result unchanged (sync).
-func classifyStatement(stmt tree.Statement, ts *TableSizes, result *ClassifyResult) {
+// operation against a large table, or one the rules force async. Statements that don't
+// touch a large table (or aren't index/alter/bulk-DML operations, e.g. CREATE TABLE)
+// leave the result unchanged (sync).
+func classifyStatement(stmt tree.Statement, ts *TableSizes, rules ClassifyRules, result *ClassifyResult) {
 	switch s := stmt.(type) {
 	case *tree.CreateIndex:
-		tableName := qualifiedTableName(s.Table)
-		if ts.IsLargeTable(tableName) {
-			markAsync(result, fmt.Sprintf("CREATE INDEX on large table %s", tableName))
+		kind := "CREATE INDEX"
+		if s.Unique {
+			kind = "CREATE UNIQUE INDEX"
 		}
+		applyRule(result, ts, rules, RuleCreateIndex, qualifiedTableName(s.Table), kind)
 
 	case *tree.AlterTable:
 		tableName := qualifiedTableName(s.Table.ToTableName())
 		for _, cmd := range s.Cmds {
-			classifyAlterTableCmd(cmd, tableName, ts, result)
+			classifyAlterTableCmd(cmd, tableName, ts, rules, result)
 		}
 
 	case *tree.Update:
 		// Data backfills (UPDATE across a large table) should roll out async.
-		if name, ok := dmlTargetTable(s.Table); ok && ts.IsLargeTable(name) {
-			markAsync(result, fmt.Sprintf("UPDATE on large table %s", name))
+		if name, ok := dmlTargetTable(s.Table); ok {
+			applyRule(result, ts, rules, RuleUpdate, name, "UPDATE")
 		}
 
 	case *tree.Delete:
 		// Bulk deletes on a large table should roll out async.
-		if name, ok := dmlTargetTable(s.Table); ok && ts.IsLargeTable(name) {
-			markAsync(result, fmt.Sprintf("DELETE on large table %s", name))
+		if name, ok := dmlTargetTable(s.Table); ok {
+			applyRule(result, ts, rules, RuleDelete, name, "DELETE")
 		}
 
 	case *tree.Insert:
 		// A bulk INSERT ... SELECT into a large table is expensive; a small
 		// INSERT ... VALUES (seed data) is not.
 		if isSelectSourcedInsert(s) {
-			if name, ok := dmlTargetTable(s.Table); ok && ts.IsLargeTable(name) {
-				markAsync(result, fmt.Sprintf("INSERT ... SELECT into large table %s", name))
+			if name, ok := dmlTargetTable(s.Table); ok {
+				applyRule(result, ts, rules, RuleInsertSelect, name, "INSERT ... SELECT")
+			}
+		}
+
+	case *tree.Import:
+		// IMPORT INTO loads data from external files as a job, which can't run
+		// in a transaction and takes as long as the files are big, whatever
+		// the size of the table.
+		if s.Into && s.Table != nil {
+			name := qualifiedTableName(*s.Table)
+			switch rules[RuleImport] {
+			case ModeSync:
+				return
+			case ModeAsync:
+				markAsync(result, name, fmt.Sprintf("IMPORT INTO %s (forced by migrations.classify.%s=async)", name, RuleImport))
+			default:
+				markAsync(result, name, fmt.Sprintf("IMPORT INTO %s loads data from external files", name))
 			}
 		}
 	}
@@ -130,26 +213,46 @@ func isSelectSourcedInsert(ins *tree.Insert) bool {
 	return !isValues
 }
 
-func classifyAlterTableCmd(cmd tree.AlterTableCmd, tableName string, ts *TableSizes, result *ClassifyResult) {
+func classifyAlterTableCmd(cmd tree.AlterTableCmd, tableName string, ts *TableSizes, rules ClassifyRules, result *ClassifyResult) {
 	switch c := cmd.(type) {
 	case *tree.AlterTableAddColumn:
-		if isAddColumnWithNonNullDefault(c.ColumnDef) && ts.IsLargeTable(tableName) {
-			markAsync(result, fmt.Sprintf("ADD COLUMN with NOT NULL DEFAULT on large table %s", tableName))
+		if isAddColumnWithNonNullDefault(c.ColumnDef) {
+			applyRule(result, ts, rules, RuleAddColumnDefault, tableName, "ADD COLUMN with NOT NULL DEFAULT")
 		}
 
 	case *tree.AlterTableSetNotNull:
-		if ts.IsLargeTable(tableName) {
-			markAsync(result, fmt.Sprintf("SET NOT NULL on large table %s", tableName))
-		}
+		applyRule(result, ts, rules, RuleSetNotNull, tableName, "SET NOT NULL")
 
 	case *tree.AlterTableAddConstraint:
-		if isValidatingConstraint(c) && ts.IsLargeTable(tableName) {
-			markAsync(result, fmt.Sprintf("ADD CONSTRAINT on large table %s", tableName))
+		if isIndexBackedConstraint(c) {
+			applyRule(result, ts, rules, RuleAddUniqueConstraint, tableName, "ADD UNIQUE CONSTRAINT")
+		} else if isValidatingConstraint(c) {
+			applyRule(result, ts, rules, RuleAddConstraint, tableName, "ADD CONSTRAINT")
 		}
 
+	case *tree.AlterTableValidateConstraint:
+		applyRule(result, ts, rules, RuleValidateConstraint, tableName, "VALIDATE CONSTRAINT")
+
 	case *tree.AlterTableAlterColumnType:
+		applyRule(result, ts, rules, RuleAlterColumnType, tableName, "ALTER COLUMN TYPE")
+	}
+}
+
+// applyRule marks the result async if the operation is on a large table, unless
+// the rules force its mode
+func applyRule(result *ClassifyResult, ts *TableSizes, rules ClassifyRules, rule, tableName, operation string) {
+	preposition := "on"
+	if rule == RuleInsertSelect {
+		preposition = "into"
+	}
+	switch rules[rule] {
+	case ModeSync:
+		return
+	case ModeAsync:
+		markAsync(result, tableName, fmt.Sprintf("%s %s %s (forced by migrations.classify.%s=async)", operation, preposition, tableName, rule))
+	default:
 		if ts.IsLargeTable(tableName) {
-			markAsync(result, fmt.Sprintf("ALTER COLUMN TYPE on large table %s", tableName))
+			markAsync(result, tableName, fmt.Sprintf("%s %s large table %s", operation, preposition, tableName))
 		}
 	}
 }
@@ -159,14 +262,24 @@ func isAddColumnWithNonNullDefault(col *tree.ColumnTableDef) bool {
 	return col.Nullable.Nullability == tree.NotNull && col.HasDefaultExpr()
 }
 
-// isValidatingConstraint returns true if the constraint is a FK or CHECK that will be validated.
+// isIndexBackedConstraint returns true if the constraint is a UNIQUE constraint,
+// which builds a unique index over the whole table.
+func isIndexBackedConstraint(c *tree.AlterTableAddConstraint) bool {
+	unique, ok := c.ConstraintDef.(*tree.UniqueConstraintTableDef)
+	return ok && !unique.WithoutIndex
+}
+
+// isValidatingConstraint returns true if the constraint is a FK, CHECK or UNIQUE
+// WITHOUT INDEX that will be validated.
 func isValidatingConstraint(c *tree.AlterTableAddConstraint) bool {
 	if c.ValidationBehavior == tree.ValidationSkip {
 		return false
 	}
-	switch c.ConstraintDef.(type) {
+	switch def := c.ConstraintDef.(type) {
 	case *tree.ForeignKeyConstraintTableDef, *tree.CheckConstraintTableDef:
 		return true
+	case *tree.UniqueConstraintTableDef:
+		return def.WithoutIndex
 	default:
 		return false
 	}
@@ -181,7 +294,25 @@ func qualifiedTableName(name tree.TableName) string {
 	return schemaName + "." + tableName
 }
 
-func markAsync(result *ClassifyResult, reason string) {
+func markAsync(result *ClassifyResult, tableName, reason string) {
 	result.Mode = ModeAsync
 	result.Reasons = append(result.Reasons, reason)
+	addTable(result, tableName)
+}
+
+func addTable(result *ClassifyResult, tableName string) {
+	if !slices.Contains(result.Tables, tableName) {
+		result.Tables = append(result.Tables, tableName)
+	}
+}
+
+// statementTable returns the table an index or ALTER TABLE statement changes
+func statementTable(stmt tree.Statement) (string, bool) {
+	switch s := stmt.(type) {
+	case *tree.CreateIndex:
+		return qualifiedTableName(s.Table), true
+	case *tree.AlterTable:
+		return qualifiedTableName(s.Table.ToTableName()), true
+	}
+	return "", false
 }
@@ -70,12 +70,12 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.CreateIndex{
 							Name:  "idx_small",
 							Table: smallTable,
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: smallTableSizes(),
@@ -86,12 +86,12 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.CreateIndex{
 							Name:  "idx_posts",
 							Table: postsTable,
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
@@ -103,7 +103,7 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableColumnModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.AlterTable{
 							Table: postsTable.ToUnresolvedObjectName(),
 							Cmds: tree.AlterTableCmds{
@@ -127,7 +127,7 @@ func TestClassifyDifferences(t *testing.T) {
 								},
 							},
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
@@ -139,7 +139,7 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableColumnModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.AlterTable{
 							Table: postsTable.ToUnresolvedObjectName(),
 							Cmds: tree.AlterTableCmds{
@@ -157,7 +157,7 @@ func TestClassifyDifferences(t *testing.T) {
 								},
 							},
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
@@ -168,7 +168,7 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableColumnModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.AlterTable{
 							Table: postsTable.ToUnresolvedObjectName(),
 							Cmds: tree.AlterTableCmds{
@@ -177,7 +177,7 @@ func TestClassifyDifferences(t *testing.T) {
 								},
 							},
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
@@ -189,7 +189,7 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.AlterTable{
 							Table: postsTable.ToUnresolvedObjectName(),
 							Cmds: tree.AlterTableCmds{
@@ -204,7 +204,7 @@ func TestClassifyDifferences(t *testing.T) {
 								},
 							},
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
@@ -216,7 +216,7 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.AlterTable{
 							Table: postsTable.ToUnresolvedObjectName(),
 							Cmds: tree.AlterTableCmds{
@@ -229,7 +229,7 @@ func TestClassifyDifferences(t *testing.T) {
 								},
 							},
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
@@ -241,7 +241,7 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.AlterTable{
 							Table: postsTable.ToUnresolvedObjectName(),
 							Cmds: tree.AlterTableCmds{
@@ -253,18 +253,37 @@ func TestClassifyDifferences(t *testing.T) {
 								},
 							},
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
 			wantMode:   ModeSync,
 		},
+		{
+			name: "VALIDATE CONSTRAINT on large table is async",
+			diffs: []schema.Difference{
+				{
+					Type: schema.DiffTypeTableModified,
+					Phases: []schema.Phase{{Statements: []tree.Statement{
+						&tree.AlterTable{
+							Table: postsTable.ToUnresolvedObjectName(),
+							Cmds: tree.AlterTableCmds{
+								&tree.AlterTableValidateConstraint{Constraint: "fk_user"},
+							},
+						},
+					}}},
+				},
+			},
+			tableSizes: largeTableSizes(),
+			wantMode:   ModeAsync,
+			wantAsync:  true,
+		},
 		{
 			name: "ALTER COLUMN TYPE on large table is async",
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeColumnTypeChanged,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.AlterTable{
 							Table: postsTable.ToUnresolvedObjectName(),
 							Cmds: tree.AlterTableCmds{
@@ -274,7 +293,7 @@ func TestClassifyDifferences(t *testing.T) {
 								},
 							},
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
@@ -287,12 +306,12 @@ func TestClassifyDifferences(t *testing.T) {
 				{Type: schema.DiffTypeTableAdded}, // sync
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.CreateIndex{
 							Name:  "idx_posts",
 							Table: postsTable,
 						},
-					},
+					}}},
 				}, // async
 			},
 			tableSizes: largeTableSizes(),
@@ -304,12 +323,12 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.CreateIndex{
 							Name:  "idx_posts",
 							Table: postsTable,
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: nil,
@@ -320,12 +339,12 @@ func TestClassifyDifferences(t *testing.T) {
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.CreateIndex{
 							Name:  "idx_unknown",
 							Table: makeTableName("public", "unknown_table"),
 						},
-					},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
@@ -372,11 +391,11 @@ func TestClassifyDifferences(t *testing.T) {
 			wantMode:   ModeSync,
 		},
 		{
-			name: "ADD UNIQUE constraint on large table is sync",
+			name: "ADD UNIQUE constraint on large table is async",
 			diffs: []schema.Difference{
 				{
 					Type: schema.DiffTypeTableModified,
-					MigrationStatements: []tree.Statement{
+					Phases: []schema.Phase{{Statements: []tree.Statement{
 						&tree.AlterTable{
 							Table: postsTable.ToUnresolvedObjectName(),
 							Cmds: tree.AlterTableCmds{
@@ -386,18 +405,114 @@ func TestClassifyDifferences(t *testing.T) {
 								},
 							},
 						},
-					},
+					}}},
+				},
+			},
+			tableSizes: largeTableSizes(),
+			wantMode:   ModeAsync,
+			wantAsync:  true,
+		},
+		{
+			name: "ADD UNIQUE WITHOUT INDEX on large table is async",
+			diffs: []schema.Difference{
+				{
+					Type: schema.DiffTypeTableModified,
+					Phases: []schema.Phase{{Statements: []tree.Statement{
+						&tree.AlterTable{
+							Table: postsTable.ToUnresolvedObjectName(),
+							Cmds: tree.AlterTableCmds{
+								&tree.AlterTableAddConstraint{
+									ConstraintDef: &tree.UniqueConstraintTableDef{WithoutIndex: true},
+								},
+							},
+						},
+					}}},
+				},
+			},
+			tableSizes: largeTableSizes(),
+			wantMode:   ModeAsync,
+			wantAsync:  true,
+		},
+		{
+			name: "ADD UNIQUE WITHOUT INDEX NOT VALID on large table is sync",
+			diffs: []schema.Difference{
+				{
+					Type: schema.DiffTypeTableModified,
+					Phases: []schema.Phase{{Statements: []tree.Statement{
+						&tree.AlterTable{
+							Table: postsTable.ToUnresolvedObjectName(),
+							Cmds: tree.AlterTableCmds{
+								&tree.AlterTableAddConstraint{
+									ConstraintDef:      &tree.UniqueConstraintTableDef{WithoutIndex: true},
+									ValidationBehavior: tree.ValidationSkip,
+								},
+							},
+						},
+					}}},
 				},
 			},
 			tableSizes: largeTableSizes(),
 			wantMode:   ModeSync,
 		},
+		{
+			name: "CREATE UNIQUE INDEX on large table is async",
+			diffs: []schema.Difference{
+				{
+					Type: schema.DiffTypeTableModified,
+					Phases: []schema.Phase{{Statements: []tree.Statement{
+						&tree.CreateIndex{
+							Name:   "posts_slug_key",
+							Table:  postsTable,
+							Unique: true,
+						},
+					}}},
+				},
+			},
+			tableSizes: largeTableSizes(),
+			wantMode:   ModeAsync,
+			wantAsync:  true,
+		},
+		{
+			name: "classify=sync directive overrides a large table",
+			diffs: []schema.Difference{
+				{
+					Type:           schema.DiffTypeTableModified,
+					Classification: schema.ClassifySync,
+					Phases: []schema.Phase{{Statements: []tree.Statement{
+						&tree.CreateIndex{
+							Name:  "idx_posts",
+							Table: postsTable,
+						},
+					}}},
+				},
+			},
+			tableSizes: largeTableSizes(),
+			wantMode:   ModeSync,
+		},
+		{
+			name: "classify=async directive overrides a small table",
+			diffs: []schema.Difference{
+				{
+					Type:           schema.DiffTypeTableModified,
+					Classification: schema.ClassifyAsync,
+					Phases: []schema.Phase{{Statements: []tree.Statement{
+						&tree.CreateIndex{
+							Name:  "idx_small",
+							Table: smallTable,
+						},
+					}}},
+				},
+			},
+			tableSizes: smallTableSizes(),
+			wantMode:   ModeAsync,
+			wantAsync:  true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			result := ClassifyDifferences(tt.diffs, tt.tableSizes)
+			result := ClassifyDifferences(tt.diffs, tt.tableSizes, nil)
 			assert.Equal(t, tt.wantMode, result.Mode)
 			if tt.wantAsync {
 				assert.NotEmpty(t, result.Reasons)
@@ -408,6 +523,36 @@ func TestClassifyDifferences(t *testing.T) {
 	}
 }
 
+func TestClassifyDifferencesTables(t *testing.T) {
+	t.Parallel()
+
+	postsTable := makeTableName("public", "posts")
+	usersTable := makeTableName("public", "users")
+	smallTable := makeTableName("public", "small_table")
+
+	diffs := []schema.Difference{
+		{
+			Type: schema.DiffTypeTableModified,
+			Phases: []schema.Phase{{Statements: []tree.Statement{
+				&tree.CreateIndex{Name: "idx_posts_a", Table: postsTable},
+				&tree.CreateIndex{Name: "idx_posts_b", Table: postsTable},
+				&tree.CreateIndex{Name: "idx_small", Table: smallTable},
+			}}},
+		},
+		{
+			Type:           schema.DiffTypeTableModified,
+			Classification: schema.ClassifyAsync,
+			Phases: []schema.Phase{{Statements: []tree.Statement{
+				&tree.CreateIndex{Name: "idx_users", Table: usersTable},
+			}}},
+		},
+	}
+
+	result := ClassifyDifferences(diffs, largeTableSizes(), nil)
+	assert.Equal(t, ModeAsync, result.Mode)
+	assert.Equal(t, []string{"public.posts", "public.users"}, result.Tables)
+}
+
 // TestClassifyStatements covers classifying raw migration statements (as used for
 // custom SQL supplied to `migration local`) against table sizes.
 func TestClassifyStatements(t *testing.T) {
@@ -471,6 +616,12 @@ func TestClassifyStatements(t *testing.T) {
 			tableSizes: largeTableSizes(),
 			wantMode:   ModeSync,
 		},
+		{
+			name:       "import into small table is async",
+			sql:        "IMPORT INTO small_table (x) CSV DATA ('s3://bucket/x.csv')",
+			tableSizes: largeTableSizes(),
+			wantMode:   ModeAsync,
+		},
 		{
 			name:       "nil table sizes classifies sync",
 			sql:        "CREATE INDEX idx ON posts (author_id)",
@@ -494,8 +645,89 @@ func TestClassifyStatements(t *testing.T) {
 			for i, p := range parsed {
 				stmts[i] = p.AST
 			}
-			result := ClassifyStatements(stmts, tt.tableSizes)
+			result := ClassifyStatements(stmts, tt.tableSizes, nil)
+			assert.Equal(t, tt.wantMode, result.Mode)
+		})
+	}
+}
+
+func TestClassifyStatementsWithRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		sql         string
+		rules       ClassifyRules
+		wantMode    MigrationMode
+		wantReasons []string
+	}{
+		{
+			name:        "async rule on a small table",
+			sql:         "CREATE INDEX idx ON small_table (x)",
+			rules:       ClassifyRules{RuleCreateIndex: ModeAsync},
+			wantMode:    ModeAsync,
+			wantReasons: []string{"CREATE INDEX on public.small_table (forced by migrations.classify.create_index=async)"},
+		},
+		{
+			name:     "sync rule on a large table",
+			sql:      "CREATE INDEX idx ON posts (author_id)",
+			rules:    ClassifyRules{RuleCreateIndex: ModeSync},
+			wantMode: ModeSync,
+		},
+		{
+			name:        "rules only apply to their operation",
+			sql:         "ALTER TABLE posts ALTER COLUMN title SET NOT NULL",
+			rules:       ClassifyRules{RuleCreateIndex: ModeSync},
+			wantMode:    ModeAsync,
+			wantReasons: []string{"SET NOT NULL on large table public.posts"},
+		},
+		{
+			name:        "insert select rule",
+			sql:         "INSERT INTO small_table (x) SELECT x FROM other",
+			rules:       ClassifyRules{RuleInsertSelect: ModeAsync},
+			wantMode:    ModeAsync,
+			wantReasons: []string{"INSERT ... SELECT into public.small_table (forced by migrations.classify.insert_select=async)"},
+		},
+		{
+			name:     "import rule forces sync",
+			sql:      "IMPORT INTO small_table (x) CSV DATA ('s3://bucket/x.csv')",
+			rules:    ClassifyRules{RuleImport: ModeSync},
+			wantMode: ModeSync,
+		},
+	}
+
+	tableSizes := largeTableSizes()
+	tableSizes.Tables["public.small_table"] = TableInfo{Rows: 50}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			parsed, err := parser.Parse(tt.sql)
+			require.NoError(t, err)
+			stmts := make([]tree.Statement, len(parsed))
+			for i, p := range parsed {
+				stmts[i] = p.AST
+			}
+			result := ClassifyStatements(stmts, tableSizes, tt.rules)
 			assert.Equal(t, tt.wantMode, result.Mode)
+			assert.Equal(t, tt.wantReasons, result.Reasons)
 		})
 	}
 }
+
+func TestParseClassifyRules(t *testing.T) {
+	t.Parallel()
+
+	rules, err := ParseClassifyRules(map[string]string{"create_index": "async", "update": "sync"})
+	require.NoError(t, err)
+	assert.Equal(t, ClassifyRules{RuleCreateIndex: ModeAsync, RuleUpdate: ModeSync}, rules)
+
+	rules, err = ParseClassifyRules(nil)
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+
+	_, err = ParseClassifyRules(map[string]string{"create_table": "async"})
+	assert.ErrorContains(t, err, `unknown classify rule "create_table"`)
+
+	_, err = ParseClassifyRules(map[string]string{"create_index": "later"})
+	assert.ErrorContains(t, err, `invalid mode "later" for classify rule create_index`)
+}
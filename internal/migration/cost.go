@@ -0,0 +1,146 @@
+package migration
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// CostClass is how much work a statement makes the database do
+type CostClass int
+
+// Cost classes, from cheapest to most expensive
+const (
+	// CostMetadata only changes the schema descriptor
+	CostMetadata CostClass = iota
+	// CostBackfill reads or writes every row of the table, e.g. to fill a new
+	// column's default or to validate a constraint
+	CostBackfill
+	// CostIndexBuild builds a new index over every row of the table
+	CostIndexBuild
+	// CostRewrite rewrites the table's primary index
+	CostRewrite
+)
+
+func (c CostClass) String() string {
+	switch c {
+	case CostBackfill:
+		return "backfill"
+	case CostIndexBuild:
+		return "index build"
+	case CostRewrite:
+		return "full rewrite"
+	default:
+		return "metadata-only"
+	}
+}
+
+// StatementCost estimates the cost of a migration statement
+type StatementCost struct {
+	Class CostClass
+	Table string // The qualified table the work happens on, if any
+	Rows  int64  // The table's row count from table sizes, or -1 when unknown
+}
+
+// String describes the cost, e.g. "index build on public.posts (~15,000,000 rows)"
+func (c StatementCost) String() string {
+	if c.Class == CostMetadata || c.Table == "" {
+		return c.Class.String()
+	}
+	if c.Rows < 0 {
+		return fmt.Sprintf("%s on %s (row count unknown)", c.Class, c.Table)
+	}
+	return fmt.Sprintf("%s on %s (~%s rows)", c.Class, c.Table, formatRowCount(c.Rows))
+}
+
+// EstimateCost classifies the work a statement does and looks up the row count
+// of the table it does it on. Like ClassifyStatements, it works from the
+// statement alone, so it can't tell whether CockroachDB will manage a type
+// change in place; those are counted as rewrites.
+func EstimateCost(stmt tree.Statement, ts *TableSizes) StatementCost {
+	cost := StatementCost{Class: CostMetadata, Rows: -1}
+
+	switch s := stmt.(type) {
+	case *tree.CreateIndex:
+		cost.Class = CostIndexBuild
+		cost.Table = qualifiedTableName(s.Table)
+
+	case *tree.AlterTable:
+		cost.Table = qualifiedTableName(s.Table.ToTableName())
+		for _, cmd := range s.Cmds {
+			cost.Class = max(cost.Class, alterTableCmdCost(cmd))
+		}
+
+	case *tree.Update:
+		if name, ok := dmlTargetTable(s.Table); ok {
+			cost.Class, cost.Table = CostBackfill, name
+		}
+
+	case *tree.Delete:
+		if name, ok := dmlTargetTable(s.Table); ok {
+			cost.Class, cost.Table = CostBackfill, name
+		}
+
+	case *tree.Insert:
+		if isSelectSourcedInsert(s) {
+			if name, ok := dmlTargetTable(s.Table); ok {
+				cost.Class, cost.Table = CostBackfill, name
+			}
+		}
+	}
+
+	if cost.Class == CostMetadata {
+		cost.Table = ""
+		return cost
+	}
+	if ts != nil {
+		if info, ok := ts.Tables[cost.Table]; ok {
+			cost.Rows = info.Rows
+		}
+	}
+	return cost
+}
+
+func alterTableCmdCost(cmd tree.AlterTableCmd) CostClass {
+	switch c := cmd.(type) {
+	case *tree.AlterTableAddColumn:
+		col := c.ColumnDef
+		if col.Unique.IsUnique || col.PrimaryKey.IsPrimaryKey {
+			return CostIndexBuild
+		}
+		if col.HasDefaultExpr() || (col.IsComputed() && !col.IsVirtual()) {
+			return CostBackfill
+		}
+	case *tree.AlterTableDropColumn:
+		// The column's data is removed from every row of the primary index
+		return CostRewrite
+	case *tree.AlterTableAlterColumnType:
+		return CostRewrite
+	case *tree.AlterTableAlterPrimaryKey:
+		return CostRewrite
+	case *tree.AlterTableSetNotNull, *tree.AlterTableValidateConstraint:
+		return CostBackfill
+	case *tree.AlterTableAddConstraint:
+		if isIndexBackedConstraint(c) {
+			return CostIndexBuild
+		}
+		if isValidatingConstraint(c) {
+			return CostBackfill
+		}
+	}
+	return CostMetadata
+}
+
+// formatRowCount formats n with thousands separators
+func formatRowCount(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	var out []byte
+	for i, d := range []byte(digits) {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out = append(out, ',')
+		}
+		out = append(out, d)
+	}
+	return string(out)
+}
@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "create table is metadata-only",
+			sql:  "CREATE TABLE t (id INT PRIMARY KEY)",
+			want: "metadata-only",
+		},
+		{
+			name: "nullable column is metadata-only",
+			sql:  "ALTER TABLE posts ADD COLUMN note STRING",
+			want: "metadata-only",
+		},
+		{
+			name: "virtual computed column is metadata-only",
+			sql:  "ALTER TABLE posts ADD COLUMN lower_title STRING AS (lower(title)) VIRTUAL",
+			want: "metadata-only",
+		},
+		{
+			name: "column with default is a backfill",
+			sql:  "ALTER TABLE posts ADD COLUMN views INT NOT NULL DEFAULT 0",
+			want: "backfill on public.posts (~15,000,000 rows)",
+		},
+		{
+			name: "unique column is an index build",
+			sql:  "ALTER TABLE users ADD COLUMN handle STRING UNIQUE",
+			want: "index build on public.users (~500,000 rows)",
+		},
+		{
+			name: "create index is an index build",
+			sql:  "CREATE INDEX ON posts (author_id)",
+			want: "index build on public.posts (~15,000,000 rows)",
+		},
+		{
+			name: "type change is a full rewrite",
+			sql:  "ALTER TABLE posts ALTER COLUMN title TYPE TEXT",
+			want: "full rewrite on public.posts (~15,000,000 rows)",
+		},
+		{
+			name: "most expensive command wins",
+			sql:  "ALTER TABLE posts ADD COLUMN note STRING, DROP COLUMN body, ALTER COLUMN author_id SET NOT NULL",
+			want: "full rewrite on public.posts (~15,000,000 rows)",
+		},
+		{
+			name: "validating a constraint is a backfill",
+			sql:  "ALTER TABLE posts VALIDATE CONSTRAINT posts_author_fk",
+			want: "backfill on public.posts (~15,000,000 rows)",
+		},
+		{
+			name: "unvalidated constraint is metadata-only",
+			sql:  "ALTER TABLE posts ADD CONSTRAINT posts_author_fk FOREIGN KEY (author_id) REFERENCES users (id) NOT VALID",
+			want: "metadata-only",
+		},
+		{
+			name: "update on a table without sizes",
+			sql:  "UPDATE app.events SET kind = 'x'",
+			want: "backfill on app.events (row count unknown)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stmt, err := parser.ParseOne(tt.sql)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, EstimateCost(stmt.AST, largeTableSizes()).String())
+		})
+	}
+}
+
+func TestFormatRowCount(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "0", formatRowCount(0))
+	assert.Equal(t, "999", formatRowCount(999))
+	assert.Equal(t, "1,000", formatRowCount(1000))
+	assert.Equal(t, "15,000,000", formatRowCount(15000000))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
 
+	"github.com/pjtatlow/scurry/internal/db"
 	"github.com/pjtatlow/scurry/internal/schema"
 	"github.com/pjtatlow/scurry/internal/set"
 )
@@ -33,6 +34,12 @@ type Header struct {
 	Mode      MigrationMode
 	DependsOn []string
 	Squash    bool
+	// RefreshStats are the tables to collect statistics on once the migration
+	// succeeds, so query plans catch up with big changes
+	RefreshStats []string
+	// Settings are session variables set while just this migration runs, e.g.
+	// statement_timeout=30m;default_transaction_priority=low
+	Settings []db.SessionSetting
 	// Sig is a short signature scurry writes over the header fields and body. It lets
 	// scurry detect a hand-authored or edited header (which won't carry a valid sig),
 	// forcing header generation through scurry (which classifies correctly). It is NOT
@@ -62,6 +69,14 @@ func ComputeSig(h *Header, body string) (string, error) {
 	if h.Squash {
 		sb.WriteString(";squash=true")
 	}
+	if len(h.RefreshStats) > 0 {
+		sb.WriteString(";refresh_stats=")
+		sb.WriteString(strings.Join(h.RefreshStats, ";"))
+	}
+	if len(h.Settings) > 0 {
+		sb.WriteString(";settings=")
+		sb.WriteString(formatSettings(h.Settings))
+	}
 	sb.WriteString(";body=")
 	sb.WriteString(canonicalBody)
 
@@ -103,7 +118,7 @@ func ParseHeader(sql string) (*Header, error) {
 	line, _, _ := strings.Cut(sql, "\n")
 	line = strings.TrimSpace(line)
 
-	if !strings.HasPrefix(line, headerPrefix) {
+	if !isHeaderLine(line) {
 		return nil, nil
 	}
 
@@ -133,6 +148,17 @@ func ParseHeader(sql string) (*Header, error) {
 				return nil, fmt.Errorf("squash must be \"true\"")
 			}
 			h.Squash = true
+		case "refresh_stats":
+			if value == "" {
+				return nil, fmt.Errorf("refresh_stats must not be empty")
+			}
+			h.RefreshStats = strings.Split(value, ";")
+		case "settings":
+			settings, err := parseSettings(value)
+			if err != nil {
+				return nil, err
+			}
+			h.Settings = settings
 		case "sig":
 			if value == "" {
 				return nil, fmt.Errorf("sig must not be empty")
@@ -150,6 +176,40 @@ func ParseHeader(sql string) (*Header, error) {
 	return h, nil
 }
 
+// parseSettings parses the name=value pairs of a settings field, separated by
+// semicolons
+func parseSettings(value string) ([]db.SessionSetting, error) {
+	if value == "" {
+		return nil, fmt.Errorf("settings must not be empty")
+	}
+	var settings []db.SessionSetting
+	for _, pair := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(pair, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid setting %q: want name=value", pair)
+		}
+		if !db.IsSessionVariableName(name) {
+			return nil, fmt.Errorf("invalid setting name: %q", name)
+		}
+		settings = append(settings, db.SessionSetting{Name: name, Value: val})
+	}
+	return settings, nil
+}
+
+func formatSettings(settings []db.SessionSetting) string {
+	pairs := make([]string, len(settings))
+	for i, setting := range settings {
+		pairs[i] = setting.Name + "=" + setting.Value
+	}
+	return strings.Join(pairs, ";")
+}
+
+// isHeaderLine reports whether line is a scurry header, rather than a directive
+// in the migration body that shares its prefix
+func isHeaderLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), headerPrefix) && !db.IsTransactionDirective(line)
+}
+
 // FormatHeader serializes a Header to a SQL comment line.
 func FormatHeader(h *Header) string {
 	var sb strings.Builder
@@ -166,6 +226,16 @@ func FormatHeader(h *Header) string {
 		sb.WriteString(",squash=true")
 	}
 
+	if len(h.RefreshStats) > 0 {
+		sb.WriteString(",refresh_stats=")
+		sb.WriteString(strings.Join(h.RefreshStats, ";"))
+	}
+
+	if len(h.Settings) > 0 {
+		sb.WriteString(",settings=")
+		sb.WriteString(formatSettings(h.Settings))
+	}
+
 	if h.Sig != "" {
 		sb.WriteString(",sig=")
 		sb.WriteString(h.Sig)
@@ -178,7 +248,7 @@ func FormatHeader(h *Header) string {
 // If no header is present, the original string is returned unchanged.
 func StripHeader(sql string) string {
 	line, rest, found := strings.Cut(sql, "\n")
-	if !strings.HasPrefix(strings.TrimSpace(line), headerPrefix) {
+	if !isHeaderLine(line) {
 		return sql
 	}
 	if !found {
@@ -219,6 +289,21 @@ func FindDependencies(newStatements []tree.Statement, existingMigrations []Migra
 		}
 	}
 
+	return findDependenciesOnNames(newNames, existingMigrations)
+}
+
+// FindTableDependencies returns the most recent existing migrations that touch
+// any of the given qualified tables, for migrations (such as data backfills)
+// whose statements scurry can't read dependencies from.
+func FindTableDependencies(tables []string, existingMigrations []MigrationInfo) []string {
+	newNames := set.New[string]()
+	for _, table := range tables {
+		newNames.Add(table)
+	}
+	return findDependenciesOnNames(newNames, existingMigrations)
+}
+
+func findDependenciesOnNames(newNames set.Set[string], existingMigrations []MigrationInfo) []string {
 	if newNames.Size() == 0 {
 		return nil
 	}
@@ -7,6 +7,8 @@ import (
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
 )
 
 func TestParseHeader(t *testing.T) {
@@ -38,6 +40,11 @@ func TestParseHeader(t *testing.T) {
 			sql:  "-- scurry:mode=async,depends_on=20251115211817_foo;20251116125806_bar\nALTER TABLE t ADD COLUMN x INT;",
 			want: &Header{Mode: ModeAsync, DependsOn: []string{"20251115211817_foo", "20251116125806_bar"}},
 		},
+		{
+			name: "transaction directive is not a header",
+			sql:  "-- scurry:no-transaction\nALTER TYPE status ADD VALUE 'archived';",
+			want: nil,
+		},
 		{
 			name: "no header",
 			sql:  "CREATE TABLE t (id INT);",
@@ -88,6 +95,34 @@ func TestParseHeader(t *testing.T) {
 			sql:     "-- scurry:mode=sync,squash=false\nCREATE TABLE t (id INT);",
 			wantErr: true,
 		},
+		{
+			name: "async with refresh_stats",
+			sql:  "-- scurry:mode=async,refresh_stats=public.posts;public.users\nCREATE INDEX ON posts (a);",
+			want: &Header{Mode: ModeAsync, RefreshStats: []string{"public.posts", "public.users"}},
+		},
+		{
+			name:    "empty refresh_stats",
+			sql:     "-- scurry:mode=async,refresh_stats=\nCREATE INDEX ON posts (a);",
+			wantErr: true,
+		},
+		{
+			name: "settings",
+			sql:  "-- scurry:mode=async,settings=statement_timeout=30m;default_transaction_priority=low\nCREATE INDEX ON posts (a);",
+			want: &Header{Mode: ModeAsync, Settings: []db.SessionSetting{
+				{Name: "statement_timeout", Value: "30m"},
+				{Name: "default_transaction_priority", Value: "low"},
+			}},
+		},
+		{
+			name:    "setting without a value",
+			sql:     "-- scurry:mode=sync,settings=statement_timeout\nCREATE TABLE t (id INT);",
+			wantErr: true,
+		},
+		{
+			name:    "setting name that isn't a session variable",
+			sql:     "-- scurry:mode=sync,settings=statement_timeout = 1; DROP TABLE t=1\nCREATE TABLE t (id INT);",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -137,6 +172,16 @@ func TestFormatHeader(t *testing.T) {
 			header: &Header{Mode: ModeSync, Squash: true},
 			want:   "-- scurry:mode=sync,squash=true",
 		},
+		{
+			name:   "async with refresh_stats",
+			header: &Header{Mode: ModeAsync, DependsOn: []string{"20251115211817_foo"}, RefreshStats: []string{"public.posts"}, Sig: "abc"},
+			want:   "-- scurry:mode=async,depends_on=20251115211817_foo,refresh_stats=public.posts,sig=abc",
+		},
+		{
+			name:   "sync with settings",
+			header: &Header{Mode: ModeSync, Settings: []db.SessionSetting{{Name: "statement_timeout", Value: "30m"}, {Name: "default_transaction_quality_of_service", Value: "background"}}},
+			want:   "-- scurry:mode=sync,settings=statement_timeout=30m;default_transaction_quality_of_service=background",
+		},
 	}
 
 	for _, tt := range tests {
@@ -171,6 +216,11 @@ func TestStripHeader(t *testing.T) {
 			sql:  "CREATE TABLE t (id INT);",
 			want: "CREATE TABLE t (id INT);",
 		},
+		{
+			name: "transaction directive is kept",
+			sql:  "-- scurry:no-transaction\nCREATE TABLE t (id INT);",
+			want: "-- scurry:no-transaction\nCREATE TABLE t (id INT);",
+		},
 		{
 			name: "empty string",
 			sql:  "",
@@ -259,6 +309,14 @@ func TestFormatThenParse(t *testing.T) {
 			name:   "sync squash round-trip",
 			header: &Header{Mode: ModeSync, Squash: true},
 		},
+		{
+			name:   "async refresh_stats round-trip",
+			header: &Header{Mode: ModeAsync, RefreshStats: []string{"public.posts", "app.events"}},
+		},
+		{
+			name:   "settings round-trip",
+			header: &Header{Mode: ModeAsync, Settings: []db.SessionSetting{{Name: "statement_timeout", Value: "1h"}}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -272,6 +330,8 @@ func TestFormatThenParse(t *testing.T) {
 			if len(tt.header.DependsOn) > 0 {
 				assert.Equal(t, tt.header.DependsOn, parsed.DependsOn)
 			}
+			assert.Equal(t, tt.header.RefreshStats, parsed.RefreshStats)
+			assert.Equal(t, tt.header.Settings, parsed.Settings)
 		})
 	}
 }
@@ -315,6 +375,16 @@ func TestHeaderSignature(t *testing.T) {
 		assert.NotEqual(t, forged.Sig, sig)
 	})
 
+	t.Run("adding settings breaks the signature", func(t *testing.T) {
+		t.Parallel()
+		h := &Header{Mode: ModeSync}
+		require.NoError(t, SignHeader(h, body))
+		edited := &Header{Mode: ModeSync, Settings: []db.SessionSetting{{Name: "statement_timeout", Value: "0"}}, Sig: h.Sig}
+		sig, err := ComputeSig(edited, body)
+		require.NoError(t, err)
+		assert.NotEqual(t, edited.Sig, sig)
+	})
+
 	t.Run("signature is independent of dependency order", func(t *testing.T) {
 		t.Parallel()
 		a := &Header{Mode: ModeSync, DependsOn: []string{"x", "y"}}
@@ -464,3 +534,17 @@ func TestFindDependencies(t *testing.T) {
 		})
 	}
 }
+
+func TestFindTableDependencies(t *testing.T) {
+	t.Parallel()
+
+	migrations := []MigrationInfo{
+		{Name: "20250101000000_create_users", SQL: "CREATE TABLE users (id INT PRIMARY KEY);"},
+		{Name: "20250102000000_create_posts", SQL: "CREATE TABLE posts (id INT PRIMARY KEY);"},
+		{Name: "20250103000000_add_email", SQL: "ALTER TABLE users ADD COLUMN email STRING;"},
+	}
+
+	assert.Equal(t, []string{"20250103000000_add_email"}, FindTableDependencies([]string{"public.users"}, migrations))
+	assert.Equal(t, []string{"20250102000000_create_posts"}, FindTableDependencies([]string{"public.posts"}, migrations))
+	assert.Nil(t, FindTableDependencies(nil, migrations))
+}
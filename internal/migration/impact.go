@@ -0,0 +1,210 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// Lock levels, from least to most disruptive
+const (
+	LockNone        = "none"
+	LockWrites      = "writes"
+	LockReadsWrites = "reads and writes"
+)
+
+// Impact is how CockroachDB runs a schema change statement
+type Impact struct {
+	Online    bool   // Runs as an online schema change, so the table stays readable and writable
+	Backfills bool   // Reads or rewrites every row of the table
+	Blocks    string // What the statement blocks while it runs, one of the lock levels
+}
+
+// String formats the impact as it's written above statements, e.g.
+// "online: yes, backfills: no, blocks: none"
+func (i Impact) String() string {
+	return fmt.Sprintf("online: %s, backfills: %s, blocks: %s", yesNo(i.Online), yesNo(i.Backfills), i.Blocks)
+}
+
+var (
+	online          = Impact{Online: true, Blocks: LockNone}
+	onlineBackfill  = Impact{Online: true, Backfills: true, Blocks: LockNone}
+	offlineBackfill = Impact{Backfills: true, Blocks: LockWrites}
+)
+
+// impactRules maps kinds of statements, as named by statementKinds, to how
+// CockroachDB runs them. An ALTER TABLE or ALTER TYPE command without a rule of
+// its own falls back to the rule for the statement.
+var impactRules = map[string]Impact{
+	"CREATE TABLE":     online,
+	"CREATE VIEW":      online,
+	"CREATE SEQUENCE":  online,
+	"CREATE TYPE":      online,
+	"CREATE SCHEMA":    online,
+	"CREATE FUNCTION":  online,
+	"CREATE PROCEDURE": online,
+	"CREATE TRIGGER":   online,
+	"CREATE INDEX":     onlineBackfill,
+
+	"DROP TABLE":     online,
+	"DROP VIEW":      online,
+	"DROP SEQUENCE":  online,
+	"DROP TYPE":      online,
+	"DROP SCHEMA":    online,
+	"DROP FUNCTION":  online,
+	"DROP PROCEDURE": online,
+	"DROP TRIGGER":   online,
+	"DROP INDEX":     online,
+
+	"ALTER TABLE":                          online,
+	"ALTER TABLE ADD COLUMN WITH BACKFILL": onlineBackfill,
+	"ALTER TABLE DROP COLUMN":              onlineBackfill,
+	"ALTER TABLE ALTER COLUMN TYPE":        offlineBackfill,
+	"ALTER TABLE ALTER PRIMARY KEY":        onlineBackfill,
+	"ALTER TABLE SET NOT NULL":             onlineBackfill,
+	"ALTER TABLE ADD CONSTRAINT":           onlineBackfill,
+	"ALTER TABLE VALIDATE CONSTRAINT":      onlineBackfill,
+
+	"ALTER TYPE":            online,
+	"ALTER TYPE DROP VALUE": onlineBackfill,
+
+	"ALTER SEQUENCE": online,
+	"ALTER VIEW":     online,
+	"ALTER INDEX":    online,
+	"COMMENT ON":     online,
+
+	"TRUNCATE": {Blocks: LockReadsWrites},
+}
+
+// DescribeImpact returns how CockroachDB runs a statement. It reports false for
+// statements that have no rule, like DML and transaction control. Statements
+// with several commands take the most disruptive impact of any of them.
+func DescribeImpact(stmt tree.Statement) (Impact, bool) {
+	kinds := statementKinds(stmt)
+	if len(kinds) == 0 {
+		return Impact{}, false
+	}
+
+	impact := Impact{Online: true, Blocks: LockNone}
+	for _, kind := range kinds {
+		rule, ok := impactRules[kind]
+		if !ok {
+			return Impact{}, false
+		}
+		impact.Online = impact.Online && rule.Online
+		impact.Backfills = impact.Backfills || rule.Backfills
+		if lockRank(rule.Blocks) > lockRank(impact.Blocks) {
+			impact.Blocks = rule.Blocks
+		}
+	}
+	return impact, true
+}
+
+// AnnotateStatements adds a comment describing the impact of each statement
+// above it, after any warnings already there, so the impact can be reviewed
+// in migration.sql. Statements that can't be parsed or have no rule are left
+// alone.
+func AnnotateStatements(statements []string) []string {
+	annotated := make([]string, len(statements))
+	for i, stmt := range statements {
+		annotated[i] = stmt
+		parsed, err := parser.ParseOne(stmt)
+		if err != nil {
+			continue
+		}
+		impact, ok := DescribeImpact(parsed.AST)
+		if !ok {
+			continue
+		}
+
+		lines := strings.Split(stmt, "\n")
+		n := 0
+		for n < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[n]), "--") {
+			n++
+		}
+		lines = append(lines[:n], append([]string{"-- " + impact.String()}, lines[n:]...)...)
+		annotated[i] = strings.Join(lines, "\n")
+	}
+	return annotated
+}
+
+// statementKinds names the kinds of a statement to look up in impactRules: one
+// per command for ALTER TABLE and ALTER TYPE, and the statement tag otherwise
+func statementKinds(stmt tree.Statement) []string {
+	switch s := stmt.(type) {
+	case *tree.AlterTable:
+		kinds := make([]string, 0, len(s.Cmds))
+		for _, cmd := range s.Cmds {
+			kinds = append(kinds, alterTableCmdKind(cmd))
+		}
+		return kinds
+	case *tree.AlterType:
+		if _, ok := s.Cmd.(*tree.AlterTypeDropValue); ok {
+			return []string{"ALTER TYPE DROP VALUE"}
+		}
+		return []string{"ALTER TYPE"}
+	case *tree.CreateRoutine:
+		if s.IsProcedure {
+			return []string{"CREATE PROCEDURE"}
+		}
+		return []string{"CREATE FUNCTION"}
+	case *tree.DropRoutine:
+		if s.Procedure {
+			return []string{"DROP PROCEDURE"}
+		}
+		return []string{"DROP FUNCTION"}
+	case *tree.CommentOnTable, *tree.CommentOnColumn, *tree.CommentOnIndex, *tree.CommentOnConstraint,
+		*tree.CommentOnSchema, *tree.CommentOnType, *tree.CommentOnDatabase:
+		return []string{"COMMENT ON"}
+	}
+	if stmt.StatementType() != tree.TypeDDL {
+		return nil
+	}
+	return []string{stmt.StatementTag()}
+}
+
+func alterTableCmdKind(cmd tree.AlterTableCmd) string {
+	switch c := cmd.(type) {
+	case *tree.AlterTableAddColumn:
+		// Filling a default or stored computed value, or building a unique
+		// index, touches every row
+		if alterTableCmdCost(c) != CostMetadata {
+			return "ALTER TABLE ADD COLUMN WITH BACKFILL"
+		}
+	case *tree.AlterTableDropColumn:
+		return "ALTER TABLE DROP COLUMN"
+	case *tree.AlterTableAlterColumnType:
+		return "ALTER TABLE ALTER COLUMN TYPE"
+	case *tree.AlterTableAlterPrimaryKey:
+		return "ALTER TABLE ALTER PRIMARY KEY"
+	case *tree.AlterTableSetNotNull:
+		return "ALTER TABLE SET NOT NULL"
+	case *tree.AlterTableAddConstraint:
+		if alterTableCmdCost(c) != CostMetadata {
+			return "ALTER TABLE ADD CONSTRAINT"
+		}
+	case *tree.AlterTableValidateConstraint:
+		return "ALTER TABLE VALIDATE CONSTRAINT"
+	}
+	return "ALTER TABLE"
+}
+
+func lockRank(level string) int {
+	switch level {
+	case LockWrites:
+		return 1
+	case LockReadsWrites:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
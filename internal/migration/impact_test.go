@@ -0,0 +1,99 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeImpact(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sql  string
+		want string // Empty when the statement has no rule
+	}{
+		{
+			name: "create table",
+			sql:  "CREATE TABLE t (id INT PRIMARY KEY)",
+			want: "online: yes, backfills: no, blocks: none",
+		},
+		{
+			name: "create index backfills",
+			sql:  "CREATE INDEX ON posts (author_id)",
+			want: "online: yes, backfills: yes, blocks: none",
+		},
+		{
+			name: "nullable column",
+			sql:  "ALTER TABLE posts ADD COLUMN note STRING",
+			want: "online: yes, backfills: no, blocks: none",
+		},
+		{
+			name: "column with default backfills",
+			sql:  "ALTER TABLE posts ADD COLUMN views INT NOT NULL DEFAULT 0",
+			want: "online: yes, backfills: yes, blocks: none",
+		},
+		{
+			name: "not valid constraint skips the scan",
+			sql:  "ALTER TABLE posts ADD CONSTRAINT posts_user_fk FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID",
+			want: "online: yes, backfills: no, blocks: none",
+		},
+		{
+			name: "type change blocks writes",
+			sql:  "ALTER TABLE posts ALTER COLUMN title TYPE TEXT",
+			want: "online: no, backfills: yes, blocks: writes",
+		},
+		{
+			name: "most disruptive command wins",
+			sql:  "ALTER TABLE posts ADD COLUMN note STRING, ALTER COLUMN title TYPE TEXT",
+			want: "online: no, backfills: yes, blocks: writes",
+		},
+		{
+			name: "dropping an enum value checks existing rows",
+			sql:  "ALTER TYPE status DROP VALUE 'archived'",
+			want: "online: yes, backfills: yes, blocks: none",
+		},
+		{
+			name: "dml has no rule",
+			sql:  "UPDATE posts SET views = 0",
+		},
+		{
+			name: "transaction control has no rule",
+			sql:  "COMMIT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			stmt, err := parser.ParseOne(tt.sql)
+			require.NoError(t, err)
+
+			impact, ok := DescribeImpact(stmt.AST)
+			if tt.want == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.want, impact.String())
+		})
+	}
+}
+
+func TestAnnotateStatements(t *testing.T) {
+	t.Parallel()
+
+	got := AnnotateStatements([]string{
+		"-- WARNING: Column 'public.posts.body' will be dropped\nALTER TABLE posts DROP COLUMN body",
+		"COMMIT",
+		"CREATE INDEX posts_author_idx ON posts (author_id)",
+	})
+	assert.Equal(t, []string{
+		"-- WARNING: Column 'public.posts.body' will be dropped\n-- online: yes, backfills: yes, blocks: none\nALTER TABLE posts DROP COLUMN body",
+		"COMMIT",
+		"-- online: yes, backfills: yes, blocks: none\nCREATE INDEX posts_author_idx ON posts (author_id)",
+	}, got)
+}
@@ -0,0 +1,115 @@
+package migration
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+// Plan is the order pending migrations execute in. Each stage holds the
+// migrations whose dependencies are all applied or in earlier stages, so the
+// migrations within a stage don't depend on each other.
+type Plan struct {
+	Stages [][]db.Migration
+}
+
+// Migrations returns the plan's migrations in execution order
+func (p *Plan) Migrations() []db.Migration {
+	var migrations []db.Migration
+	for _, stage := range p.Stages {
+		migrations = append(migrations, stage...)
+	}
+	return migrations
+}
+
+// PlanExecution orders migrations by their depends_on headers: each migration
+// goes in the stage after the last of its dependencies, and keeps its place in
+// migrations (timestamp order) within the stage. Dependencies that aren't in
+// migrations are left for the caller to check against the database. Fails if
+// the dependencies have a cycle.
+func PlanExecution(migrations []db.Migration) (*Plan, error) {
+	index := make(map[string]int, len(migrations))
+	for i, m := range migrations {
+		index[m.Name] = i
+	}
+
+	if cycle := findDependencyCycle(migrations, index); cycle != nil {
+		return nil, fmt.Errorf("migration dependency cycle: %s", strings.Join(cycle, " → "))
+	}
+
+	// depth is the stage of each migration; -1 until computed
+	depth := make([]int, len(migrations))
+	for i := range depth {
+		depth[i] = -1
+	}
+	var stageOf func(i int) int
+	stageOf = func(i int) int {
+		if depth[i] >= 0 {
+			return depth[i]
+		}
+		depth[i] = 0
+		for _, dep := range migrations[i].DependsOn {
+			if j, ok := index[dep]; ok {
+				depth[i] = max(depth[i], stageOf(j)+1)
+			}
+		}
+		return depth[i]
+	}
+
+	plan := &Plan{}
+	for i, m := range migrations {
+		stage := stageOf(i)
+		for len(plan.Stages) <= stage {
+			plan.Stages = append(plan.Stages, nil)
+		}
+		plan.Stages[stage] = append(plan.Stages[stage], m)
+	}
+	return plan, nil
+}
+
+// findDependencyCycle returns the names along a depends_on cycle, starting and
+// ending with the same migration, or nil if there isn't one
+func findDependencyCycle(migrations []db.Migration, index map[string]int) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(migrations))
+	var path []string
+
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		state[i] = visiting
+		path = append(path, migrations[i].Name)
+		for _, dep := range migrations[i].DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				continue
+			}
+			switch state[j] {
+			case visiting:
+				start := slices.Index(path, dep)
+				return append(slices.Clone(path[start:]), dep)
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = done
+		return nil
+	}
+
+	for i := range migrations {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestPlanExecution(t *testing.T) {
+	t.Parallel()
+
+	m := func(name string, deps ...string) db.Migration {
+		return db.Migration{Name: name, DependsOn: deps}
+	}
+
+	tests := []struct {
+		name       string
+		migrations []db.Migration
+		wantStages [][]string
+		wantErr    string
+	}{
+		{
+			name:       "no dependencies",
+			migrations: []db.Migration{m("001_a"), m("002_b"), m("003_c")},
+			wantStages: [][]string{{"001_a", "002_b", "003_c"}},
+		},
+		{
+			name:       "chain",
+			migrations: []db.Migration{m("001_a"), m("002_b", "001_a"), m("003_c", "002_b")},
+			wantStages: [][]string{{"001_a"}, {"002_b"}, {"003_c"}},
+		},
+		{
+			name: "independent branches",
+			migrations: []db.Migration{
+				m("001_users"), m("002_orders"),
+				m("003_users_idx", "001_users"), m("004_orders_idx", "002_orders"),
+				m("005_report", "003_users_idx", "004_orders_idx"),
+			},
+			wantStages: [][]string{{"001_users", "002_orders"}, {"003_users_idx", "004_orders_idx"}, {"005_report"}},
+		},
+		{
+			name:       "dependency on a later migration",
+			migrations: []db.Migration{m("001_a", "002_b"), m("002_b")},
+			wantStages: [][]string{{"002_b"}, {"001_a"}},
+		},
+		{
+			name:       "dependency outside the plan",
+			migrations: []db.Migration{m("002_b", "001_applied"), m("003_c")},
+			wantStages: [][]string{{"002_b", "003_c"}},
+		},
+		{
+			name:       "cycle",
+			migrations: []db.Migration{m("001_a", "003_c"), m("002_b", "001_a"), m("003_c", "002_b")},
+			wantErr:    "migration dependency cycle: 001_a → 003_c → 002_b → 001_a",
+		},
+		{
+			name:       "self dependency",
+			migrations: []db.Migration{m("001_a"), m("002_b", "002_b")},
+			wantErr:    "migration dependency cycle: 002_b → 002_b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan, err := PlanExecution(tt.migrations)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var stages [][]string
+			for _, stage := range plan.Stages {
+				var names []string
+				for _, m := range stage {
+					names = append(names, m.Name)
+				}
+				stages = append(stages, names)
+			}
+			assert.Equal(t, tt.wantStages, stages)
+			assert.Len(t, plan.Migrations(), len(tt.migrations))
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package migration
 
 import (
+	"fmt"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
@@ -10,17 +13,29 @@ import (
 const (
 	tableSizesFileName               = "table_sizes.yaml"
 	DefaultLargeTableThreshold int64 = 100000
+	// DefaultTableSizesMaxAge is how old table sizes can get before classifying
+	// with them warns that they may be out of date
+	DefaultTableSizesMaxAge = 30 * 24 * time.Hour
 )
 
 // TableInfo holds size information for a single table
 type TableInfo struct {
-	Rows int64 `yaml:"rows"`
+	Rows  int64 `yaml:"rows"`
+	Bytes int64 `yaml:"bytes,omitempty"` // Approximate size on disk
+	// Source and CapturedAt are set on tables merged in from a different source
+	// than the rest of the file
+	Source     string    `yaml:"source,omitempty"`
+	CapturedAt time.Time `yaml:"captured_at,omitempty"`
 }
 
 // TableSizes holds table size data loaded from table_sizes.yaml
 type TableSizes struct {
-	Threshold int64                `yaml:"threshold"`
-	Tables    map[string]TableInfo `yaml:"tables"`
+	Threshold int64 `yaml:"threshold"`
+	// Source is where the sizes were read from, e.g. the database's host, and
+	// CapturedAt when. Files written before they were recorded have neither.
+	Source     string               `yaml:"source,omitempty"`
+	CapturedAt time.Time            `yaml:"captured_at,omitempty"`
+	Tables     map[string]TableInfo `yaml:"tables"`
 }
 
 // LoadTableSizes reads table_sizes.yaml from the migrations directory.
@@ -80,3 +95,63 @@ func (ts *TableSizes) IsLargeTable(tableName string) bool {
 	}
 	return info.Rows >= threshold
 }
+
+// Merge adds the tables of other that ts doesn't have, recording other's source
+// and capture time on each. It returns the names of the tables added, sorted.
+func (ts *TableSizes) Merge(other *TableSizes) []string {
+	if other == nil {
+		return nil
+	}
+	if ts.Tables == nil {
+		ts.Tables = make(map[string]TableInfo)
+	}
+	var added []string
+	for name, info := range other.Tables {
+		if _, ok := ts.Tables[name]; ok {
+			continue
+		}
+		if info.Source == "" {
+			info.Source = other.Source
+		}
+		if info.CapturedAt.IsZero() {
+			info.CapturedAt = other.CapturedAt
+		}
+		ts.Tables[name] = info
+		added = append(added, name)
+	}
+	sort.Strings(added)
+	return added
+}
+
+// StaleWarning returns a warning if the sizes were captured more than maxAge
+// before now, or weren't recorded when, or "" if they are recent enough
+func (ts *TableSizes) StaleWarning(maxAge time.Duration, now time.Time) string {
+	if ts == nil {
+		return ""
+	}
+	if ts.CapturedAt.IsZero() {
+		return "table_sizes.yaml doesn't record when its sizes were captured, so they may be out of date"
+	}
+	age := now.Sub(ts.CapturedAt)
+	if age <= maxAge {
+		return ""
+	}
+	days := int(age.Hours() / 24)
+	return fmt.Sprintf("table_sizes.yaml was captured %d day(s) ago (%s), so classification may not match how large the tables are now", days, ts.CapturedAt.Format(time.DateOnly))
+}
+
+// MissingTables returns the tables that ts has no size for, in order. Changes to
+// them are classified as if the tables were small.
+func (ts *TableSizes) MissingTables(tables []string) []string {
+	var missing []string
+	for _, name := range tables {
+		if ts == nil {
+			missing = append(missing, name)
+			continue
+		}
+		if _, ok := ts.Tables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
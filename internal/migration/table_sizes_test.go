@@ -2,6 +2,7 @@ package migration
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -36,6 +37,46 @@ tables:
 				},
 			},
 		},
+		{
+			name:      "with bytes",
+			writeFile: true,
+			content: `threshold: 100000
+tables:
+  public.posts:
+    rows: 15000000
+    bytes: 4294967296
+`,
+			want: &TableSizes{
+				Threshold: 100000,
+				Tables: map[string]TableInfo{
+					"public.posts": {Rows: 15000000, Bytes: 4294967296},
+				},
+			},
+		},
+		{
+			name:      "with source and capture time",
+			writeFile: true,
+			content: `threshold: 100000
+source: prod.example.com:26257/app
+captured_at: 2024-03-01T12:00:00Z
+tables:
+  public.posts:
+    rows: 15000000
+  public.drafts:
+    rows: 20
+    source: localhost:26257/app
+    captured_at: 2024-03-05T08:00:00Z
+`,
+			want: &TableSizes{
+				Threshold:  100000,
+				Source:     "prod.example.com:26257/app",
+				CapturedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+				Tables: map[string]TableInfo{
+					"public.posts":  {Rows: 15000000},
+					"public.drafts": {Rows: 20, Source: "localhost:26257/app", CapturedAt: time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)},
+				},
+			},
+		},
 		{
 			name: "missing file",
 			want: nil,
@@ -178,3 +219,55 @@ func TestIsLargeTable(t *testing.T) {
 		})
 	}
 }
+
+func TestTableSizesMerge(t *testing.T) {
+	t.Parallel()
+
+	captured := time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)
+	ts := &TableSizes{
+		Threshold: 100000,
+		Source:    "prod.example.com:26257/app",
+		Tables:    map[string]TableInfo{"public.posts": {Rows: 15000000}},
+	}
+	live := &TableSizes{
+		Source:     "localhost:26257/app",
+		CapturedAt: captured,
+		Tables: map[string]TableInfo{
+			"public.posts":    {Rows: 10},
+			"public.comments": {Rows: 300000},
+			"public.drafts":   {Rows: 20},
+		},
+	}
+
+	assert.Equal(t, []string{"public.comments", "public.drafts"}, ts.Merge(live))
+	assert.Equal(t, map[string]TableInfo{
+		"public.posts":    {Rows: 15000000},
+		"public.comments": {Rows: 300000, Source: "localhost:26257/app", CapturedAt: captured},
+		"public.drafts":   {Rows: 20, Source: "localhost:26257/app", CapturedAt: captured},
+	}, ts.Tables)
+	assert.True(t, ts.IsLargeTable("public.comments"))
+	assert.Nil(t, ts.Merge(nil))
+}
+
+func TestTableSizesStaleWarning(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	maxAge := 7 * 24 * time.Hour
+
+	var nilSizes *TableSizes
+	assert.Empty(t, nilSizes.StaleWarning(maxAge, now))
+	assert.Equal(t, "table_sizes.yaml doesn't record when its sizes were captured, so they may be out of date",
+		(&TableSizes{}).StaleWarning(maxAge, now))
+	assert.Empty(t, (&TableSizes{CapturedAt: now.Add(-24 * time.Hour)}).StaleWarning(maxAge, now))
+	assert.Equal(t, "table_sizes.yaml was captured 31 day(s) ago (2024-03-01), so classification may not match how large the tables are now",
+		(&TableSizes{CapturedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}).StaleWarning(maxAge, now))
+}
+
+func TestTableSizesMissingTables(t *testing.T) {
+	t.Parallel()
+
+	ts := &TableSizes{Tables: map[string]TableInfo{"public.posts": {Rows: 15000000}}}
+	assert.Equal(t, []string{"public.comments"}, ts.MissingTables([]string{"public.posts", "public.comments"}))
+	assert.Empty(t, ts.MissingTables([]string{"public.posts"}))
+}
@@ -0,0 +1,101 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// Templates for hand-written migrations
+const (
+	// TemplateEmpty is a migration with no statements
+	TemplateEmpty = "empty"
+	// TemplateBackfill updates a table's rows in batches
+	TemplateBackfill = "backfill"
+)
+
+// TemplateNames are the names of the migration templates
+var TemplateNames = []string{TemplateEmpty, TemplateBackfill}
+
+// TemplateOptions fills in a migration template
+type TemplateOptions struct {
+	// Table is the qualified table the migration changes, for templates that
+	// need one
+	Table string
+	// Column is a column of Table, used as a placeholder in statements the
+	// author fills in
+	Column string
+}
+
+const emptyTemplate = `-- Write the statements for this migration here, then sign its header with
+-- 'scurry migration validate --signatures=fix'.
+`
+
+// The loop runs until the WHERE clause matches no rows, so it has to skip rows
+// that were already updated
+const backfillTemplate = `-- Backfill %[1]s in batches of 1000 rows.
+--
+-- Replace the SET clause with the change to make, and both WHERE clauses with
+-- a condition matching the rows that still need it, so each batch makes
+-- progress and the loop ends. Then sign the header with
+-- 'scurry migration validate --signatures=fix'.
+DO $$
+BEGIN
+  LOOP
+    UPDATE %[1]s SET %[2]s = %[2]s WHERE false LIMIT 1000;
+    EXIT WHEN NOT EXISTS (SELECT 1 FROM %[1]s WHERE false);
+  END LOOP;
+END
+$$;
+`
+
+// RenderTemplate returns the body of a new migration from the named template
+func RenderTemplate(name string, opts TemplateOptions) (string, error) {
+	switch name {
+	case TemplateEmpty:
+		return emptyTemplate, nil
+	case TemplateBackfill:
+		if opts.Table == "" || opts.Column == "" {
+			return "", fmt.Errorf("the %s template needs a table", name)
+		}
+		schemaName, tableName, _ := strings.Cut(opts.Table, ".")
+		table := tree.NameString(schemaName) + "." + tree.NameString(tableName)
+		return fmt.Sprintf(backfillTemplate, table, tree.NameString(opts.Column)), nil
+	}
+	return "", fmt.Errorf("unknown template %q: expected one of %s", name, strings.Join(TemplateNames, ", "))
+}
+
+// nullValuePlaceholder stands in for the value of a NULL backfill when the
+// column has no default to use
+const nullValuePlaceholder = "<value>"
+
+const nullBackfillTemplate = `-- Set the NULL values of %[1]s.%[2]s in batches of 1000 rows, so the column
+-- can be made NOT NULL.
+DO $$
+BEGIN
+  LOOP
+    UPDATE %[1]s SET %[2]s = %[3]s WHERE %[2]s IS NULL LIMIT 1000;
+    EXIT WHEN NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[2]s IS NULL);
+  END LOOP;
+END
+$$;
+`
+
+const nullBackfillPlaceholderNote = `-- Replace %s with the value the NULL rows should get, then sign the header
+-- with 'scurry migration validate --signatures=fix'.
+`
+
+// NullBackfill returns the body of a migration that sets the NULL values of a
+// column to value in batches. If value is nil, the body has a placeholder for
+// the author to replace, and complete is false.
+func NullBackfill(table, column string, value tree.Expr) (body string, complete bool) {
+	schemaName, tableName, _ := strings.Cut(table, ".")
+	qualified := tree.NameString(schemaName) + "." + tree.NameString(tableName)
+
+	if value == nil {
+		body = fmt.Sprintf(nullBackfillTemplate, qualified, tree.NameString(column), nullValuePlaceholder)
+		return fmt.Sprintf(nullBackfillPlaceholderNote, nullValuePlaceholder) + body, false
+	}
+	return fmt.Sprintf(nullBackfillTemplate, qualified, tree.NameString(column), tree.AsString(value)), true
+}
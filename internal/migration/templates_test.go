@@ -0,0 +1,76 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+		body, err := RenderTemplate(TemplateEmpty, TemplateOptions{})
+		require.NoError(t, err)
+		stmts, err := parser.Parse(body)
+		require.NoError(t, err)
+		assert.Empty(t, stmts)
+	})
+
+	t.Run("backfill", func(t *testing.T) {
+		t.Parallel()
+		body, err := RenderTemplate(TemplateBackfill, TemplateOptions{Table: "public.users", Column: "email"})
+		require.NoError(t, err)
+		assert.Contains(t, body, "UPDATE public.users SET email = email WHERE false LIMIT 1000;")
+		stmts, err := parser.Parse(body)
+		require.NoError(t, err)
+		assert.Len(t, stmts, 1)
+	})
+
+	t.Run("backfill quotes names", func(t *testing.T) {
+		t.Parallel()
+		body, err := RenderTemplate(TemplateBackfill, TemplateOptions{Table: "public.Users", Column: "order"})
+		require.NoError(t, err)
+		assert.Contains(t, body, `UPDATE public."Users" SET "order" = "order"`)
+	})
+
+	t.Run("backfill without a table", func(t *testing.T) {
+		t.Parallel()
+		_, err := RenderTemplate(TemplateBackfill, TemplateOptions{})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		t.Parallel()
+		_, err := RenderTemplate("nope", TemplateOptions{})
+		assert.ErrorContains(t, err, "unknown template")
+	})
+}
+
+func TestNullBackfill(t *testing.T) {
+	t.Parallel()
+
+	t.Run("with a value", func(t *testing.T) {
+		t.Parallel()
+		value, err := parser.ParseExpr("'unknown'")
+		require.NoError(t, err)
+		body, complete := NullBackfill("public.users", "email", value)
+		assert.True(t, complete)
+		assert.Contains(t, body, "UPDATE public.users SET email = 'unknown' WHERE email IS NULL LIMIT 1000;")
+		assert.Contains(t, body, "EXIT WHEN NOT EXISTS (SELECT 1 FROM public.users WHERE email IS NULL);")
+		stmts, err := parser.Parse(body)
+		require.NoError(t, err)
+		assert.Len(t, stmts, 1)
+	})
+
+	t.Run("without a value", func(t *testing.T) {
+		t.Parallel()
+		body, complete := NullBackfill("public.Users", "order", nil)
+		assert.False(t, complete)
+		assert.Contains(t, body, "Replace <value> with")
+		assert.Contains(t, body, `UPDATE public."Users" SET "order" = <value> WHERE "order" IS NULL LIMIT 1000;`)
+	})
+}
@@ -0,0 +1,143 @@
+// Package notify posts the outcome of push and migration execute to a webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// Event statuses
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Timeout bounds how long Send waits for the webhook
+var Timeout = 10 * time.Second
+
+// MigrationResult is the outcome of a single migration
+type MigrationResult struct {
+	Name       string `json:"name"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Event is the payload posted to the webhook. Text is a one-line summary, so
+// Slack incoming webhooks can display it as is.
+type Event struct {
+	Text       string            `json:"text"`
+	Command    string            `json:"command"`
+	Status     string            `json:"status"`
+	Database   string            `json:"database,omitempty"`
+	Migrations []MigrationResult `json:"migrations,omitempty"`
+	Statements int               `json:"statements,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	Error      string            `json:"error,omitempty"`
+	Executor   string            `json:"executor"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// NewEvent returns an event for command, finished after duration with err
+// (nil on success). Executor and Timestamp are filled in, and Text is set when
+// the event is sent.
+func NewEvent(command string, duration time.Duration, err error) Event {
+	event := Event{
+		Command:    command,
+		Status:     StatusSuccess,
+		DurationMs: duration.Milliseconds(),
+		Executor:   Executor(),
+		Timestamp:  time.Now().UTC(),
+	}
+	if err != nil {
+		event.Status = StatusFailure
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// Executor identifies who ran scurry, as user@hostname
+func Executor() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return name
+	}
+	return name + "@" + hostname
+}
+
+// Summary returns the one-line summary of the event
+func (e Event) Summary() string {
+	var sb strings.Builder
+	if e.Status == StatusSuccess {
+		sb.WriteString("✅ scurry ")
+	} else {
+		sb.WriteString("❌ scurry ")
+	}
+	sb.WriteString(e.Command)
+	if e.Database != "" {
+		fmt.Fprintf(&sb, " on %s", e.Database)
+	}
+	if e.Status == StatusSuccess {
+		sb.WriteString(" succeeded")
+	} else {
+		sb.WriteString(" failed")
+	}
+
+	var details []string
+	if len(e.Migrations) > 0 {
+		details = append(details, fmt.Sprintf("%d migration(s)", len(e.Migrations)))
+	}
+	if e.Statements > 0 {
+		details = append(details, fmt.Sprintf("%d statement(s)", e.Statements))
+	}
+	details = append(details, (time.Duration(e.DurationMs) * time.Millisecond).String())
+	fmt.Fprintf(&sb, " (%s) by %s", strings.Join(details, ", "), e.Executor)
+
+	if e.Error != "" {
+		fmt.Fprintf(&sb, ": %s", e.Error)
+	}
+	return sb.String()
+}
+
+// Send posts the event as JSON to url
+func Send(ctx context.Context, url string, event Event) error {
+	if event.Text == "" {
+		event.Text = event.Summary()
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notification webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
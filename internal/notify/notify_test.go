@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEvent(t *testing.T) {
+	event := NewEvent("push", 1500*time.Millisecond, nil)
+	assert.Equal(t, "push", event.Command)
+	assert.Equal(t, StatusSuccess, event.Status)
+	assert.Equal(t, int64(1500), event.DurationMs)
+	assert.Empty(t, event.Error)
+	assert.NotEmpty(t, event.Executor)
+
+	event = NewEvent("migration execute", time.Second, errors.New("boom"))
+	assert.Equal(t, StatusFailure, event.Status)
+	assert.Equal(t, "boom", event.Error)
+}
+
+func TestSummary(t *testing.T) {
+	event := Event{
+		Command:    "migration execute",
+		Status:     StatusSuccess,
+		Database:   "app",
+		Migrations: []MigrationResult{{Name: "001_a"}, {Name: "002_b"}},
+		DurationMs: 2500,
+		Executor:   "ci@runner",
+	}
+	assert.Equal(t, "✅ scurry migration execute on app succeeded (2 migration(s), 2.5s) by ci@runner", event.Summary())
+
+	event = Event{
+		Command:    "push",
+		Status:     StatusFailure,
+		Statements: 3,
+		DurationMs: 10,
+		Executor:   "ci@runner",
+		Error:      "relation does not exist",
+	}
+	assert.Equal(t, "❌ scurry push failed (3 statement(s), 10ms) by ci@runner: relation does not exist", event.Summary())
+}
+
+func TestSend(t *testing.T) {
+	var received map[string]any
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	event := Event{
+		Command:    "migration execute",
+		Status:     StatusFailure,
+		Migrations: []MigrationResult{{Name: "001_a", DurationMs: 12}, {Name: "002_b", DurationMs: 3, Error: "boom"}},
+		DurationMs: 15,
+		Error:      "boom",
+		Executor:   "ci@runner",
+	}
+	require.NoError(t, Send(context.Background(), server.URL, event))
+
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, event.Summary(), received["text"])
+	assert.Equal(t, "failure", received["status"])
+	assert.Equal(t, "boom", received["error"])
+	migrations := received["migrations"].([]any)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, "002_b", migrations[1].(map[string]any)["name"])
+	assert.Equal(t, "boom", migrations[1].(map[string]any)["error"])
+}
+
+func TestSend_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := Send(context.Background(), server.URL, Event{Command: "push"})
+	assert.ErrorContains(t, err, "403 Forbidden: invalid_token")
+}
@@ -8,12 +8,14 @@ import (
 	"github.com/charmbracelet/huh"
 
 	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/logging"
 	"github.com/pjtatlow/scurry/internal/ui"
 )
 
 // Recovery option constants
 const (
 	OptionTryAgain      = "try_again"
+	OptionResume        = "resume"
 	OptionMarkSucceeded = "mark_succeeded"
 	OptionDropDatabase  = "drop_database"
 	OptionSkipAll       = "skip_all"
@@ -37,13 +39,16 @@ func TruncateChecksum(checksum string) string {
 // TryAgain resets a migration and re-executes all statements from the beginning.
 // It updates the migration status based on the outcome.
 func TryAgain(ctx context.Context, dbClient *db.Client, migration db.Migration) error {
-	fmt.Println()
-	fmt.Println(ui.Info("Retrying migration from the beginning..."))
+	logging.Newline()
+	logging.Info("Retrying migration from the beginning...")
 
 	// Reset migration to pending state
 	if err := dbClient.ResetMigrationForRetry(ctx, migration.Name, migration.Checksum); err != nil {
 		return fmt.Errorf("failed to reset migration: %w", err)
 	}
+	if err := dbClient.RecordMigrationSQL(ctx, migration.Name, migration.SQL); err != nil {
+		return err
+	}
 
 	// Parse and execute all statements
 	statements, err := db.SplitStatements(migration.SQL)
@@ -52,7 +57,7 @@ func TryAgain(ctx context.Context, dbClient *db.Client, migration db.Migration)
 	}
 
 	for i, stmt := range statements {
-		fmt.Printf("  Executing statement %d/%d...\n", i+1, len(statements))
+		logging.Print(fmt.Sprintf("  Executing statement %d/%d...", i+1, len(statements)))
 		_, err := dbClient.ExecContext(ctx, stmt)
 		if err != nil {
 			// Update failure info
@@ -71,9 +76,17 @@ func TryAgain(ctx context.Context, dbClient *db.Client, migration db.Migration)
 	return nil
 }
 
+// Resume runs the migration again from statement from (in
+// db.MigrationStatements order), leaving the statements before it alone
+func Resume(ctx context.Context, dbClient *db.Client, migration db.Migration, from int) error {
+	logging.Newline()
+	logging.Info(fmt.Sprintf("Resuming migration from statement %d...", from+1))
+	return dbClient.ResumeMigration(ctx, migration, from)
+}
+
 // MarkSucceeded marks the migration as recovered without executing any statements.
 func MarkSucceeded(ctx context.Context, dbClient *db.Client, migration db.Migration) error {
-	fmt.Println()
+	logging.Newline()
 
 	if err := dbClient.RecoverMigration(ctx, migration.Name); err != nil {
 		return fmt.Errorf("failed to mark migration as recovered: %w", err)
@@ -91,6 +104,8 @@ type RecoveryPromptConfig struct {
 	IncludeSkipAll bool
 	// MigrationStatus is "pending" or "failed" - affects descriptions
 	MigrationStatus string
+	// ResumeFrom includes the "resume" option, continuing from this statement
+	ResumeFrom *int
 }
 
 // RecoveryResult represents the outcome of a recovery loop.
@@ -122,6 +137,9 @@ type RecoveryLoopConfig struct {
 	IncludeSkipAll bool
 	// MigrationStatus is "pending" or "failed" for display purposes
 	MigrationStatus string
+	// ResumeFrom is the statement the migration can resume from, or nil if it
+	// can't be resumed
+	ResumeFrom *int
 
 	// OnRetryFailure is called when a retry fails, allowing the caller to refresh
 	// the migration info display. Returns the refreshed migration record.
@@ -152,10 +170,14 @@ func PromptRecoveryOption(config RecoveryPromptConfig) (string, error) {
 	}
 
 	// Build options list
-	options := []huh.Option[string]{
+	var options []huh.Option[string]
+	if config.ResumeFrom != nil {
+		options = append(options, huh.NewOption(fmt.Sprintf("Resume - Continue from statement %d", *config.ResumeFrom+1), OptionResume))
+	}
+	options = append(options,
 		huh.NewOption("Try again - Re-run all statements from the beginning", OptionTryAgain),
 		huh.NewOption("Mark as succeeded - Mark the migration as recovered without re-running", OptionMarkSucceeded),
-	}
+	)
 
 	if config.IncludeDropDatabase {
 		options = append(options, huh.NewOption("Drop database - Drop the entire database and start fresh", OptionDropDatabase))
@@ -185,6 +207,20 @@ func PromptRecoveryOption(config RecoveryPromptConfig) (string, error) {
 	return choice, nil
 }
 
+// nextResumePoint is where a migration can resume after a resume attempt
+// failed at the statement refreshed records, or nil if that isn't known
+func nextResumePoint(migration db.Migration, refreshed *db.AppliedMigration) *int {
+	if refreshed == nil || refreshed.CurrentStatement == nil {
+		return nil
+	}
+	statements, err := db.MigrationStatements(migration, false)
+	if err != nil {
+		return nil
+	}
+	from := db.ResumePoint(statements, *refreshed.CurrentStatement, false)
+	return &from
+}
+
 // RunRecoveryLoop runs the interactive recovery loop, prompting the user for actions
 // until the migration is recovered or the user aborts.
 func RunRecoveryLoop(ctx context.Context, config RecoveryLoopConfig) (RecoveryResult, error) {
@@ -193,6 +229,7 @@ func RunRecoveryLoop(ctx context.Context, config RecoveryLoopConfig) (RecoveryRe
 			IncludeDropDatabase: config.IncludeDropDatabase,
 			IncludeSkipAll:      config.IncludeSkipAll,
 			MigrationStatus:     config.MigrationStatus,
+			ResumeFrom:          config.ResumeFrom,
 		})
 		if err != nil {
 			return ResultAbort, fmt.Errorf("failed to get user input: %w", err)
@@ -201,22 +238,36 @@ func RunRecoveryLoop(ctx context.Context, config RecoveryLoopConfig) (RecoveryRe
 		switch choice {
 		case OptionTryAgain:
 			if err := TryAgain(ctx, config.DbClient, config.Migration); err != nil {
-				fmt.Println(ui.Error(fmt.Sprintf("Retry failed: %v", err)))
+				logging.Error(fmt.Sprintf("Retry failed: %v", err))
 				// Allow caller to refresh migration display
 				if config.OnRetryFailure != nil {
 					config.OnRetryFailure(ctx, config.DbClient)
 				}
-				fmt.Println()
+				logging.Newline()
+				continue
+			}
+			logging.Success("Migration completed successfully!")
+			return ResultSuccess, nil
+
+		case OptionResume:
+			if err := Resume(ctx, config.DbClient, config.Migration, *config.ResumeFrom); err != nil {
+				logging.Error(fmt.Sprintf("Resume failed: %v", err))
+				var refreshed *db.AppliedMigration
+				if config.OnRetryFailure != nil {
+					refreshed, _ = config.OnRetryFailure(ctx, config.DbClient)
+				}
+				config.ResumeFrom = nextResumePoint(config.Migration, refreshed)
+				logging.Newline()
 				continue
 			}
-			fmt.Println(ui.Success("Migration completed successfully!"))
+			logging.Success("Migration completed successfully!")
 			return ResultSuccess, nil
 
 		case OptionMarkSucceeded:
 			if err := MarkSucceeded(ctx, config.DbClient, config.Migration); err != nil {
 				return ResultAbort, fmt.Errorf("failed to mark as succeeded: %w", err)
 			}
-			fmt.Println(ui.Success("Migration marked as recovered"))
+			logging.Success("Migration marked as recovered")
 			return ResultSuccess, nil
 
 		case OptionDropDatabase:
@@ -226,7 +277,7 @@ func RunRecoveryLoop(ctx context.Context, config RecoveryLoopConfig) (RecoveryRe
 			if err := config.OnDropDatabase(ctx, config.DbClient); err != nil {
 				return ResultAbort, fmt.Errorf("failed to drop database: %w", err)
 			}
-			fmt.Println(ui.Success("Database dropped. Please re-run push to start fresh."))
+			logging.Success("Database dropped. Please re-run push to start fresh.")
 			return ResultDropDatabase, nil
 
 		case OptionSkipAll:
@@ -236,7 +287,7 @@ func RunRecoveryLoop(ctx context.Context, config RecoveryLoopConfig) (RecoveryRe
 			if err := config.OnSkipAll(ctx, config.DbClient); err != nil {
 				return ResultAbort, fmt.Errorf("failed to skip migrations: %w", err)
 			}
-			fmt.Println(ui.Success("All pending migrations marked as complete."))
+			logging.Success("All pending migrations marked as complete.")
 			return ResultSkipAll, nil
 
 		case OptionAbort:
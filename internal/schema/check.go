@@ -0,0 +1,191 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/set"
+)
+
+// DefinitionIssue is a problem found in definitions without a database
+type DefinitionIssue struct {
+	Object  string
+	Message string
+	Source  SourceLocation
+}
+
+func (i DefinitionIssue) String() string {
+	if i.Source.IsZero() {
+		return fmt.Sprintf("%s: %s", i.Object, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Source, i.Object, i.Message)
+}
+
+// CheckReferences reports references between objects in the schema that don't
+// resolve: schemas, user defined types, sequences, foreign key targets and the
+// tables and functions of triggers. It's a fast check for definitions loaded
+// with LoadDefinitions, and catches a subset of what the database would.
+func CheckReferences(s *Schema) []DefinitionIssue {
+	schemas := set.New("public")
+	for _, sc := range s.Schemas {
+		schemas.Add(sc.Name)
+	}
+	types := set.New[string]()
+	for _, t := range s.Types {
+		types.Add(t.ResolvedName())
+	}
+	sequences := set.New[string]()
+	for _, seq := range s.Sequences {
+		sequences.Add(seq.ResolvedName())
+	}
+	routines := set.New[string]()
+	for _, r := range s.Routines {
+		routines.Add(r.ResolvedName())
+	}
+	tableColumns := make(map[string]set.Set[string])
+	for _, t := range s.Tables {
+		columns := set.New[string]()
+		for _, def := range t.Ast.Defs {
+			if col, ok := def.(*tree.ColumnTableDef); ok {
+				columns.Add(col.Name.Normalize())
+			}
+		}
+		tableColumns[t.ResolvedName()] = columns
+	}
+
+	var issues []DefinitionIssue
+	report := func(object string, source SourceLocation, format string, args ...any) {
+		issues = append(issues, DefinitionIssue{Object: object, Message: fmt.Sprintf(format, args...), Source: source})
+	}
+	checkSchema := func(object, schemaName string, source SourceLocation) {
+		if !schemas.Contains(schemaName) {
+			report(object, source, "schema %s is not defined", schemaName)
+		}
+	}
+	checkSequences := func(object string, expr tree.Expr, source SourceLocation) {
+		for _, name := range referencedSequences(expr) {
+			if !sequences.Contains(name) {
+				report(object, source, "sequence %s is not defined", name)
+			}
+		}
+	}
+
+	for _, t := range s.Types {
+		checkSchema(t.ResolvedName(), t.Schema, t.Source)
+	}
+	for _, r := range s.Routines {
+		checkSchema(r.ResolvedName(), r.Schema, r.Source)
+	}
+	for _, v := range s.Views {
+		checkSchema(v.ResolvedName(), v.Schema, v.Source)
+	}
+
+	for _, seq := range s.Sequences {
+		name := seq.ResolvedName()
+		checkSchema(name, seq.Schema, seq.Source)
+		for _, opt := range seq.Ast.Options {
+			if opt.Name != tree.SeqOptOwnedBy || opt.ColumnItemVal == nil || opt.ColumnItemVal.TableName == nil {
+				continue
+			}
+			tableSchema, tableName := getObjectName(opt.ColumnItemVal.TableName)
+			owner := tableSchema + "." + tableName
+			if columns, ok := tableColumns[owner]; !ok {
+				report(name, seq.Source, "owner table %s is not defined", owner)
+			} else if col := opt.ColumnItemVal.ColumnName.Normalize(); !columns.Contains(col) {
+				report(name, seq.Source, "owner column %s.%s is not defined", owner, col)
+			}
+		}
+	}
+
+	for _, t := range s.Tables {
+		name := t.ResolvedName()
+		checkSchema(name, t.Schema, t.Source)
+		for _, def := range t.Ast.Defs {
+			switch d := def.(type) {
+			case *tree.ColumnTableDef:
+				if typeName, ok := getResolvableTypeReferenceDepName(d.Type); ok && !types.Contains(typeName) {
+					report(name, t.Source, "type %s of column %s is not defined", typeName, d.Name.Normalize())
+				}
+				if d.DefaultExpr.Expr != nil {
+					checkSequences(name, d.DefaultExpr.Expr, t.Source)
+				}
+				if d.OnUpdateExpr.Expr != nil {
+					checkSequences(name, d.OnUpdateExpr.Expr, t.Source)
+				}
+
+			case *tree.ForeignKeyConstraintTableDef:
+				for _, col := range d.FromCols {
+					if !tableColumns[name].Contains(col.Normalize()) {
+						report(name, t.Source, "foreign key column %s is not defined", col.Normalize())
+					}
+				}
+				targetSchema, targetTable := getTableName(d.Table)
+				target := targetSchema + "." + targetTable
+				targetColumns, ok := tableColumns[target]
+				if !ok {
+					report(name, t.Source, "foreign key references table %s, which is not defined", target)
+					continue
+				}
+				for _, col := range d.ToCols {
+					if !targetColumns.Contains(col.Normalize()) {
+						report(name, t.Source, "foreign key references column %s.%s, which is not defined", target, col.Normalize())
+					}
+				}
+			}
+		}
+	}
+
+	for _, trigger := range s.Triggers {
+		name := trigger.ResolvedName()
+		tableSchema, tableName := getObjectName(trigger.Ast.TableName)
+		if _, ok := tableColumns[tableSchema+"."+tableName]; !ok {
+			report(name, trigger.Source, "table %s.%s is not defined", tableSchema, tableName)
+		}
+		funcSchema, funcName := getTriggerFunctionName(trigger.Ast.FuncName)
+		if !routines.Contains(funcSchema + "." + funcName) {
+			report(name, trigger.Source, "function %s.%s is not defined", funcSchema, funcName)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Source.File != issues[j].Source.File {
+			return issues[i].Source.File < issues[j].Source.File
+		}
+		return issues[i].Source.Line < issues[j].Source.Line
+	})
+	return issues
+}
+
+// referencedSequences returns the qualified names of sequences used by
+// nextval, currval and setval in an expression
+func referencedSequences(expr tree.Expr) []string {
+	v := &sequenceVisitor{}
+	tree.WalkExpr(v, expr)
+	return v.names
+}
+
+type sequenceVisitor struct {
+	names []string
+}
+
+func (v *sequenceVisitor) VisitPre(expr tree.Expr) (bool, tree.Expr) {
+	if f, ok := expr.(*tree.FuncExpr); ok && len(f.Exprs) > 0 {
+		switch strings.ToLower(f.Func.String()) {
+		case "nextval", "currval", "setval":
+			if seqName, ok := extractSequenceName(f.Exprs[0]); ok {
+				if !strings.Contains(seqName, ".") {
+					seqName = "public." + seqName
+				}
+				v.names = append(v.names, strings.ToLower(seqName))
+			}
+		}
+	}
+	return true, expr
+}
+
+func (v *sequenceVisitor) VisitPost(expr tree.Expr) tree.Expr {
+	return expr
+}
@@ -0,0 +1,77 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      []string
+		expected []string
+	}{
+		{
+			name: "all references resolve",
+			sql: []string{
+				"CREATE SCHEMA app",
+				"CREATE TYPE app.status AS ENUM ('a', 'b')",
+				"CREATE SEQUENCE app.users_seq",
+				"CREATE TABLE app.users (id INT8 PRIMARY KEY DEFAULT nextval('app.users_seq'), status app.status)",
+				"CREATE TABLE app.posts (id INT8 PRIMARY KEY, user_id INT8 REFERENCES app.users (id))",
+			},
+		},
+		{
+			name:     "undefined schema",
+			sql:      []string{"CREATE TABLE app.users (id INT8 PRIMARY KEY)"},
+			expected: []string{"app.users: schema app is not defined"},
+		},
+		{
+			name:     "undefined type",
+			sql:      []string{"CREATE TABLE users (id INT8 PRIMARY KEY, status status)"},
+			expected: []string{"public.users: type public.status of column status is not defined"},
+		},
+		{
+			name:     "undefined sequence",
+			sql:      []string{"CREATE TABLE users (id INT8 PRIMARY KEY DEFAULT nextval('users_seq'))"},
+			expected: []string{"public.users: sequence public.users_seq is not defined"},
+		},
+		{
+			name:     "undefined foreign key table",
+			sql:      []string{"CREATE TABLE posts (id INT8 PRIMARY KEY, user_id INT8 REFERENCES users (id))"},
+			expected: []string{"public.posts: foreign key references table public.users, which is not defined"},
+		},
+		{
+			name: "undefined foreign key column",
+			sql: []string{
+				"CREATE TABLE users (id INT8 PRIMARY KEY)",
+				"CREATE TABLE posts (id INT8 PRIMARY KEY, user_id INT8, CONSTRAINT fk FOREIGN KEY (author_id) REFERENCES users (uid))",
+			},
+			expected: []string{
+				"public.posts: foreign key column author_id is not defined",
+				"public.posts: foreign key references column public.users.uid, which is not defined",
+			},
+		},
+		{
+			name:     "undefined sequence owner",
+			sql:      []string{"CREATE TABLE users (id INT8 PRIMARY KEY)", "CREATE SEQUENCE s OWNED BY users.uid"},
+			expected: []string{"public.s: owner column public.users.uid is not defined"},
+		},
+		{
+			name:     "undefined trigger table and function",
+			sql:      []string{"CREATE TRIGGER audit BEFORE INSERT ON users FOR EACH ROW EXECUTE FUNCTION audit()"},
+			expected: []string{"public.users.audit: table public.users is not defined", "public.users.audit: function public.audit is not defined"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var issues []string
+			for _, issue := range CheckReferences(NewSchema(parseStatements(tt.sql...)...)) {
+				issues = append(issues, issue.String())
+			}
+			assert.Equal(t, tt.expected, issues)
+		})
+	}
+}
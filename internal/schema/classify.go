@@ -0,0 +1,192 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+const classifyPrefix = "-- scurry:classify="
+
+// Values of a -- scurry:classify directive
+const (
+	ClassifySync  = "sync"
+	ClassifyAsync = "async"
+)
+
+// ClassifyOverrides records -- scurry:classify directives, keyed by "schema.table".
+type ClassifyOverrides map[string]TableClassifyOverrides
+
+// TableClassifyOverrides are the classify directives for one table
+type TableClassifyOverrides struct {
+	Table   string            // Applies to every change to the table
+	Indexes map[string]string // Applies to changes to one index or constraint, by name
+}
+
+func (c ClassifyOverrides) merge(other ClassifyOverrides) {
+	for table, overrides := range other {
+		existing := c[table]
+		if overrides.Table != "" {
+			existing.Table = overrides.Table
+		}
+		for name, value := range overrides.Indexes {
+			if existing.Indexes == nil {
+				existing.Indexes = make(map[string]string)
+			}
+			existing.Indexes[name] = value
+		}
+		c[table] = existing
+	}
+}
+
+// parseClassifyDirectives finds -- scurry:classify directives, which force
+// changes to be classified as sync or async regardless of table sizes. A
+// directive in the comments directly above a CREATE TABLE applies to every
+// change to the table; one at the end of an index or constraint's line applies
+// to changes to that index, as does one directly above a CREATE INDEX:
+//
+//	-- scurry:classify=sync
+//	CREATE TABLE users (
+//	    id INT PRIMARY KEY,
+//	    email TEXT,
+//	    UNIQUE INDEX users_email_key (email) -- scurry:classify=async
+//	);
+//
+//	-- scurry:classify=async
+//	CREATE INDEX users_name_idx ON users (name);
+func parseClassifyDirectives(sql string) (ClassifyOverrides, error) {
+	overrides := make(ClassifyOverrides)
+	if !strings.Contains(sql, classifyPrefix) {
+		return overrides, nil
+	}
+
+	for _, def := range parseTableDefinitions(sql) {
+		var table TableClassifyOverrides
+		value, err := precedingClassifyValue(def.preceding)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", def.qualified, err)
+		}
+		table.Table = value
+
+		for name, raw := range def.indexDirectives(classifyPrefix) {
+			value, err := classifyValue(raw)
+			if err != nil {
+				return nil, fmt.Errorf("index %s.%s: %w", def.qualified, name, err)
+			}
+			if table.Indexes == nil {
+				table.Indexes = make(map[string]string)
+			}
+			table.Indexes[name] = value
+		}
+
+		if table.Table != "" || table.Indexes != nil {
+			overrides[def.qualified] = table
+		}
+	}
+
+	// A directive above a CREATE INDEX statement applies to that index
+	for _, stmt := range parseDefinitionStatements(sql) {
+		ci, ok := stmt.ast.(*tree.CreateIndex)
+		if !ok {
+			continue
+		}
+		schemaName, tableName := getTableName(ci.Table)
+		qualified := schemaName + "." + tableName
+		value, err := precedingClassifyValue(stmt.preceding)
+		if err != nil {
+			return nil, fmt.Errorf("index %s.%s: %w", qualified, ci.Name.Normalize(), err)
+		}
+		if value == "" {
+			continue
+		}
+		table := overrides[qualified]
+		if table.Indexes == nil {
+			table.Indexes = make(map[string]string)
+		}
+		table.Indexes[ci.Name.Normalize()] = value
+		overrides[qualified] = table
+	}
+	return overrides, nil
+}
+
+// precedingClassifyValue returns the value of the last -- scurry:classify
+// directive in the comments above a statement, or "" if there is none
+func precedingClassifyValue(preceding string) (string, error) {
+	var value string
+	for _, line := range strings.Split(preceding, "\n") {
+		idx := strings.Index(line, classifyPrefix)
+		if idx == -1 {
+			continue
+		}
+		v, err := classifyValue(line[idx+len(classifyPrefix):])
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+	return value, nil
+}
+
+func classifyValue(raw string) (string, error) {
+	switch value := firstWord(raw); value {
+	case ClassifySync, ClassifyAsync:
+		return value, nil
+	default:
+		return "", fmt.Errorf("invalid scurry:classify value %q: expected sync or async", value)
+	}
+}
+
+// applyClassifyOverrides sets the Classification of a table's differences from
+// its classify directives. An index's directive wins over the table's.
+func applyClassifyOverrides(diffs []Difference, overrides TableClassifyOverrides) {
+	if overrides.Table == "" && len(overrides.Indexes) == 0 {
+		return
+	}
+	for i := range diffs {
+		diff := &diffs[i]
+		if overrides.Table != "" {
+			diff.Classification = overrides.Table
+		}
+		for _, name := range changedIndexNames(diff.Statements()) {
+			if value, ok := overrides.Indexes[name]; ok {
+				diff.Classification = value
+			}
+		}
+	}
+}
+
+// changedIndexNames returns the names of the indexes and constraints that
+// statements create, drop or add
+func changedIndexNames(stmts []tree.Statement) []string {
+	var names []string
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *tree.CreateIndex:
+			names = append(names, s.Name.Normalize())
+		case *tree.DropIndex:
+			for _, index := range s.IndexList {
+				names = append(names, tree.Name(index.Index).Normalize())
+			}
+		case *tree.AlterTable:
+			for _, cmd := range s.Cmds {
+				switch c := cmd.(type) {
+				case *tree.AlterTableAddConstraint:
+					switch d := c.ConstraintDef.(type) {
+					case *tree.UniqueConstraintTableDef:
+						names = append(names, d.Name.Normalize())
+					case *tree.ForeignKeyConstraintTableDef:
+						names = append(names, d.Name.Normalize())
+					case *tree.CheckConstraintTableDef:
+						names = append(names, d.Name.Normalize())
+					}
+				case *tree.AlterTableValidateConstraint:
+					names = append(names, c.Constraint.Normalize())
+				case *tree.AlterTableDropConstraint:
+					names = append(names, c.Constraint.Normalize())
+				}
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClassifyDirectives(t *testing.T) {
+	tests := []struct {
+		name        string
+		sql         string
+		expected    ClassifyOverrides
+		errContains string
+	}{
+		{
+			name:     "no directives",
+			sql:      "CREATE TABLE t (id INT PRIMARY KEY);",
+			expected: ClassifyOverrides{},
+		},
+		{
+			name: "table directive",
+			sql:  "-- scurry:classify=sync\nCREATE TABLE t (id INT PRIMARY KEY);",
+			expected: ClassifyOverrides{
+				"public.t": {Table: ClassifySync},
+			},
+		},
+		{
+			name: "index directives",
+			sql:  "CREATE TABLE app.t (\n  id INT PRIMARY KEY,\n  email STRING,\n  name STRING,\n  UNIQUE INDEX t_email_key (email), -- scurry:classify=async\n  INDEX t_name_idx (name) -- scurry:classify=sync -- small enough\n);",
+			expected: ClassifyOverrides{
+				"app.t": {Indexes: map[string]string{"t_email_key": ClassifyAsync, "t_name_idx": ClassifySync}},
+			},
+		},
+		{
+			name: "constraint directive",
+			sql:  "CREATE TABLE t (\n  id INT PRIMARY KEY,\n  email STRING,\n  CONSTRAINT t_email_key UNIQUE (email) -- scurry:classify=async\n);",
+			expected: ClassifyOverrides{
+				"public.t": {Indexes: map[string]string{"t_email_key": ClassifyAsync}},
+			},
+		},
+		{
+			name: "create index directive",
+			sql:  "-- scurry:classify=sync\nCREATE TABLE t (id INT PRIMARY KEY, name STRING);\n\n-- scurry:classify=async\nCREATE INDEX t_name_idx ON t (name);\nCREATE INDEX t_id_name_idx ON t (id, name);",
+			expected: ClassifyOverrides{
+				"public.t": {Table: ClassifySync, Indexes: map[string]string{"t_name_idx": ClassifyAsync}},
+			},
+		},
+		{
+			name:        "invalid value",
+			sql:         "-- scurry:classify=later\nCREATE TABLE t (id INT PRIMARY KEY);",
+			errContains: `invalid scurry:classify value "later"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides, err := parseClassifyDirectives(tt.sql)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, overrides)
+		})
+	}
+}
+
+func TestCompareAppliesClassifyDirectives(t *testing.T) {
+	local := NewSchema(parseStatements(`CREATE TABLE t (
+		id INT8 NOT NULL,
+		email STRING NULL,
+		name STRING NULL,
+		CONSTRAINT t_pkey PRIMARY KEY (id),
+		UNIQUE INDEX t_email_key (email),
+		INDEX t_name_idx (name)
+	)`)...)
+	local.Classify = ClassifyOverrides{
+		"public.t": {Table: ClassifySync, Indexes: map[string]string{"t_email_key": ClassifyAsync}},
+	}
+	remote := NewSchema(parseStatements(`CREATE TABLE t (
+		id INT8 NOT NULL,
+		email STRING NULL,
+		name STRING NULL,
+		CONSTRAINT t_pkey PRIMARY KEY (id)
+	)`)...)
+
+	result := Compare(local, remote)
+
+	classifications := make(map[string]string)
+	for _, diff := range result.Differences {
+		for _, name := range changedIndexNames(diff.Statements()) {
+			classifications[name] = diff.Classification
+		}
+	}
+	assert.Equal(t, map[string]string{"t_email_key": ClassifyAsync, "t_name_idx": ClassifySync}, classifications)
+}
@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+// ClusterSettingsFile is the file in the root of a definitions directory
+// holding the cluster settings the schema relies on, as SET CLUSTER SETTING
+// statements:
+//
+//	SET CLUSTER SETTING sql.defaults.serial_normalization = 'sql_sequence';
+//	SET CLUSTER SETTING sql.ttl.default_delete_batch_size = 500;
+const ClusterSettingsFile = "cluster_settings.sql"
+
+// clusterSettingPrefix starts the ObjectName of differences to cluster settings
+const clusterSettingPrefix = "cluster_setting:"
+
+// ClusterSettings are cluster settings by name. Only the settings in
+// cluster_settings.sql are managed, every other setting is left alone.
+type ClusterSettings struct {
+	Settings map[string]string
+	// Source is the cluster_settings.sql the settings were read from
+	Source SourceLocation
+}
+
+// LoadClusterSettings reads the cluster settings of the cluster the client is
+// connected to
+func LoadClusterSettings(ctx context.Context, dbClient *db.Client) (*ClusterSettings, error) {
+	settings, err := dbClient.GetClusterSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterSettings{Settings: settings}, nil
+}
+
+// parseClusterSettings parses a cluster_settings.sql file
+func parseClusterSettings(sql, file string) (*ClusterSettings, error) {
+	statements, err := parser.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+	}
+
+	settings := &ClusterSettings{
+		Settings: make(map[string]string),
+		Source:   SourceLocation{File: file},
+	}
+	for _, stmt := range statements {
+		s, ok := stmt.AST.(*tree.SetClusterSetting)
+		if !ok {
+			return nil, fmt.Errorf("unsupported statement in %s: %s, it only supports SET CLUSTER SETTING", ClusterSettingsFile, stmt.AST.StatementTag())
+		}
+		if _, ok := s.Value.(tree.DefaultVal); ok || s.Value == nil {
+			return nil, fmt.Errorf("cluster setting %s needs a value, the default can't be compared with the cluster's", s.Name)
+		}
+		settings.Settings[strings.ToLower(s.Name)] = settingValue(tree.Exprs{s.Value})
+	}
+	return settings, nil
+}
+
+// merge applies the settings of an overlay's cluster_settings.sql over c
+func (c *ClusterSettings) merge(overlay *ClusterSettings) {
+	maps.Copy(c.Settings, overlay.Settings)
+}
+
+// sameClusterSettingValue compares a value from the definitions with the value
+// SHOW CLUSTER SETTINGS shows, which can be written differently, e.g. 1m0s
+// for '1m' or 0.50 for 0.5
+func sameClusterSettingValue(local, remote string) bool {
+	if strings.EqualFold(local, remote) {
+		return true
+	}
+	if l, err := time.ParseDuration(local); err == nil {
+		r, err := time.ParseDuration(remote)
+		return err == nil && l == r
+	}
+	if l, err := strconv.ParseFloat(local, 64); err == nil {
+		r, err := strconv.ParseFloat(remote, 64)
+		return err == nil && l == r
+	}
+	return false
+}
+
+// compareClusterSettings finds the cluster settings in the definitions with a
+// different value in the cluster, when the definitions have a
+// cluster_settings.sql and the remote settings were loaded
+func compareClusterSettings(local, remote *Schema) []Difference {
+	if local.ClusterSettings == nil || remote.ClusterSettings == nil {
+		return nil
+	}
+
+	var diffs []Difference
+	for _, setting := range slices.Sorted(maps.Keys(local.ClusterSettings.Settings)) {
+		value := local.ClusterSettings.Settings[setting]
+		current, ok := remote.ClusterSettings.Settings[setting]
+		if ok && sameClusterSettingValue(value, current) {
+			continue
+		}
+
+		diff := Difference{
+			Type:           DiffTypeClusterSettingModified,
+			ObjectName:     clusterSettingPrefix + setting,
+			Description:    fmt.Sprintf("Cluster setting '%s' changed from '%s' to '%s'", setting, current, value),
+			WarningMessage: fmt.Sprintf("%s applies to every database in the cluster, not just this one.", setting),
+			// SET CLUSTER SETTING can't run in a transaction with other statements
+			Phases: []Phase{{
+				Statements:    []tree.Statement{&tree.SetClusterSetting{Name: setting, Value: tree.NewStrVal(value)}},
+				NoTransaction: true,
+			}},
+		}
+		if !ok {
+			diff.Description = fmt.Sprintf("Cluster setting '%s' set to '%s'", setting, value)
+			diff.BlockingError = fmt.Sprintf("Cluster setting '%s' doesn't exist in this cluster's version", setting)
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
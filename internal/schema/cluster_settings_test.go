@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClusterSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected map[string]string
+		wantErr  string
+	}{
+		{
+			name: "settings",
+			sql: `SET CLUSTER SETTING sql.defaults.serial_normalization = 'sql_sequence';
+				SET CLUSTER SETTING sql.ttl.default_delete_batch_size = 500;
+				SET CLUSTER SETTING Feature.Schema_Change.Enabled = true;`,
+			expected: map[string]string{
+				"sql.defaults.serial_normalization": "sql_sequence",
+				"sql.ttl.default_delete_batch_size": "500",
+				"feature.schema_change.enabled":     "true",
+			},
+		},
+		{
+			name:    "default",
+			sql:     `SET CLUSTER SETTING sql.ttl.default_delete_batch_size = DEFAULT;`,
+			wantErr: "cluster setting sql.ttl.default_delete_batch_size needs a value",
+		},
+		{
+			name:    "session settings",
+			sql:     `SET statement_timeout = '10s';`,
+			wantErr: "unsupported statement in cluster_settings.sql: SET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := parseClusterSettings(tt.sql, "defs/cluster_settings.sql")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, settings.Settings)
+			assert.Equal(t, "defs/cluster_settings.sql", settings.Source.File)
+		})
+	}
+}
+
+func TestCompareClusterSettings(t *testing.T) {
+	remote := &ClusterSettings{Settings: map[string]string{
+		"sql.defaults.serial_normalization":                  "rowid",
+		"sql.ttl.default_delete_batch_size":                  "100",
+		"kv.rangefeed.enabled":                               "true",
+		"sql.stats.automatic_collection.fraction_stale_rows": "0.20",
+		"jobs.retention_time":                                "336h0m0s",
+	}}
+
+	tests := []struct {
+		name         string
+		local        *ClusterSettings
+		remote       *ClusterSettings
+		expected     []string
+		wantBlocking string
+	}{
+		{
+			name:   "no cluster_settings.sql",
+			remote: remote,
+		},
+		{
+			name:  "not loaded without --cluster-settings",
+			local: &ClusterSettings{Settings: map[string]string{"sql.ttl.default_delete_batch_size": "500"}},
+		},
+		{
+			name: "same values written differently",
+			local: &ClusterSettings{Settings: map[string]string{
+				"kv.rangefeed.enabled":                               "TRUE",
+				"sql.stats.automatic_collection.fraction_stale_rows": "0.2",
+				"jobs.retention_time":                                "336h",
+			}},
+			remote: remote,
+		},
+		{
+			name: "changed settings",
+			local: &ClusterSettings{Settings: map[string]string{
+				"sql.defaults.serial_normalization": "sql_sequence",
+				"sql.ttl.default_delete_batch_size": "500",
+			}},
+			remote: remote,
+			expected: []string{
+				`SET CLUSTER SETTING "sql.defaults.serial_normalization" = 'sql_sequence'`,
+				`SET CLUSTER SETTING "sql.ttl.default_delete_batch_size" = '500'`,
+			},
+		},
+		{
+			name:         "unknown setting",
+			local:        &ClusterSettings{Settings: map[string]string{"sql.made_up": "on"}},
+			remote:       remote,
+			expected:     []string{`SET CLUSTER SETTING "sql.made_up" = 'on'`},
+			wantBlocking: "Cluster setting 'sql.made_up' doesn't exist in this cluster's version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, remoteSchema := NewSchema(), NewSchema()
+			local.ClusterSettings = tt.local
+			remoteSchema.ClusterSettings = tt.remote
+
+			var statements []string
+			blocking := ""
+			for _, diff := range compareClusterSettings(local, remoteSchema) {
+				assert.Equal(t, DiffTypeClusterSettingModified, diff.Type)
+				blocking += diff.BlockingError
+				for _, phase := range diff.Phases {
+					assert.True(t, phase.NoTransaction)
+				}
+				for _, stmt := range diff.Statements() {
+					statements = append(statements, stmt.String())
+				}
+			}
+			assert.Equal(t, tt.expected, statements)
+			assert.Equal(t, tt.wantBlocking, blocking)
+		})
+	}
+}
+
+func TestLoadDefinitionsClusterSettingsFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"defs/cluster_settings.sql":               "SET CLUSTER SETTING sql.ttl.default_delete_batch_size = 100;\nSET CLUSTER SETTING kv.rangefeed.enabled = true;",
+		"defs/tables/t.sql":                       "CREATE TABLE t (id INT8 PRIMARY KEY);",
+		"defs/overlays/prod/cluster_settings.sql": "SET CLUSTER SETTING sql.ttl.default_delete_batch_size = 500;",
+	}
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+	}
+
+	s, err := LoadDefinitions(fs, []string{"defs"}, "prod")
+	require.NoError(t, err)
+	require.Len(t, s.Tables, 1)
+	require.NotNil(t, s.ClusterSettings)
+	assert.Equal(t, map[string]string{"sql.ttl.default_delete_batch_size": "500", "kv.rangefeed.enabled": "true"}, s.ClusterSettings.Settings)
+	assert.Equal(t, "defs/cluster_settings.sql", s.SourceOf("cluster_setting:kv.rangefeed.enabled").File)
+}
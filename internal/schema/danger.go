@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// DangerItem summarizes a dangerous difference for review before it is applied
+type DangerItem struct {
+	Object      string
+	Type        DiffType
+	Reason      string
+	Statements  []string
+	Mitigations []string
+	Source      SourceLocation
+}
+
+// DangerReport returns an item for each dangerous difference, in order
+func (r *ComparisonResult) DangerReport() []DangerItem {
+	var items []DangerItem
+	for _, diff := range r.Differences {
+		if !diff.Dangerous {
+			continue
+		}
+		reason := diff.Description
+		if diff.WarningMessage != "" {
+			reason = diff.Description + ": " + diff.WarningMessage
+		}
+		item := DangerItem{
+			Object:      diff.ObjectName,
+			Type:        diff.Type,
+			Reason:      reason,
+			Mitigations: dangerMitigations(diff),
+			Source:      diff.Source,
+		}
+		for _, stmt := range diff.Statements() {
+			item.Statements = append(item.Statements, formatNode(stmt))
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// dangerMitigations suggests ways to make a dangerous difference safer, based on
+// what its statements do
+func dangerMitigations(diff Difference) []string {
+	var mitigations []string
+	add := func(m string) {
+		if !slices.Contains(mitigations, m) {
+			mitigations = append(mitigations, m)
+		}
+	}
+
+	if diff.InferredRename {
+		add("Declare the rename with -- scurry:renamed-from in the definition to confirm it isn't a drop and create")
+	}
+	if diff.Type == DiffTypeColumnTypeChanged && !diff.HasUsingDirective {
+		add("Declare how existing values convert with a -- scurry:using directive, and try the conversion on a copy of the data first")
+	}
+	if diff.IsDropCreate {
+		add("The object is dropped and re-created: copy anything it holds first, or change it in steps that keep it")
+	}
+
+	for _, stmt := range diff.Statements() {
+		switch s := stmt.(type) {
+		case *tree.DropTable:
+			for _, name := range s.Names {
+				add(fmt.Sprintf("Back up the table before dropping it, e.g. BACKUP TABLE %s INTO '<destination>'", name.String()))
+			}
+			add(fmt.Sprintf("If the table should stay, put it back in the definitions or skip it with --skip %s", diff.ObjectName))
+		case *tree.AlterTable:
+			for _, cmd := range s.Cmds {
+				switch c := cmd.(type) {
+				case *tree.AlterTableDropColumn:
+					add(fmt.Sprintf("Copy the data in column %s elsewhere first, or stop using the column in a release before dropping it", c.Column.String()))
+				case *tree.AlterTableAlterPrimaryKey:
+					add("Changing the primary key rewrites the table: apply it when the load is low")
+				case *tree.AlterTableDropConstraint:
+					add("Check the application doesn't rely on the constraint to keep bad data out")
+				}
+			}
+		case *tree.DropIndex:
+			add("Check that no query still reads the index, e.g. with scurry lint --unused-indexes")
+		case *tree.AlterType:
+			if _, ok := s.Cmd.(*tree.AlterTypeDropValue); ok {
+				add("Add -- scurry:remap-value=<old>:<new> above the type to update the rows still using a dropped value")
+			}
+		case *tree.DropType, *tree.DropRoutine, *tree.DropSequence:
+			add("Make sure nothing in the database or the application still uses it")
+		}
+	}
+
+	if len(mitigations) == 0 {
+		add("Review the statements and back up the affected data before applying them")
+	}
+	return mitigations
+}
@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDangerReport(t *testing.T) {
+	t.Parallel()
+
+	remote := NewSchema(parseStatements(
+		"CREATE TABLE public.users (id INT8 NOT NULL, email STRING NULL, name STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id), INDEX users_name_idx (name))",
+		"CREATE TABLE public.audit (id INT8 NOT NULL, CONSTRAINT audit_pkey PRIMARY KEY (id))",
+	)...)
+	local := NewSchema(parseStatements(
+		"CREATE TABLE public.users (id INT8 NOT NULL, email STRING NULL, name STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id), INDEX users_email_idx (email))",
+	)...)
+
+	report := Compare(local, remote).DangerReport()
+	require.Len(t, report, 2)
+
+	byObject := make(map[string]DangerItem)
+	for _, item := range report {
+		byObject[item.Object] = item
+	}
+
+	audit := byObject["public.audit"]
+	assert.Equal(t, DiffTypeTableRemoved, audit.Type)
+	assert.Equal(t, "Table 'public.audit' removed", audit.Reason)
+	assert.Equal(t, []string{"DROP TABLE IF EXISTS public.audit RESTRICT"}, audit.Statements)
+	assert.Equal(t, []string{
+		"Back up the table before dropping it, e.g. BACKUP TABLE public.audit INTO '<destination>'",
+		"If the table should stay, put it back in the definitions or skip it with --skip public.audit",
+	}, audit.Mitigations)
+
+	users := byObject["public.users"]
+	assert.Equal(t, "Index 'public.users.users_name_idx' removed", users.Reason)
+	assert.Equal(t, []string{"Check that no query still reads the index, e.g. with scurry lint --unused-indexes"}, users.Mitigations)
+}
+
+func TestDangerMitigationsFallback(t *testing.T) {
+	t.Parallel()
+
+	diff := Difference{Type: DiffTypeTableModified, Dangerous: true}
+	assert.Equal(t, []string{"Review the statements and back up the affected data before applying them"}, dangerMitigations(diff))
+
+	diff = Difference{Type: DiffTypeColumnTypeChanged, Dangerous: true, InferredRename: true}
+	assert.Equal(t, []string{
+		"Declare the rename with -- scurry:renamed-from in the definition to confirm it isn't a drop and create",
+		"Declare how existing values convert with a -- scurry:using directive, and try the conversion on a copy of the data first",
+	}, dangerMitigations(diff))
+}
@@ -0,0 +1,300 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+// DatabaseFile is the file in the root of a definitions directory holding the
+// settings of the database itself, as ALTER DATABASE statements:
+//
+//	ALTER DATABASE app SET default_transaction_isolation = 'read committed';
+//	ALTER DATABASE app PRIMARY REGION "us-east1";
+//	ALTER DATABASE app ADD REGION "us-west1";
+//	ALTER DATABASE app SURVIVE REGION FAILURE;
+const DatabaseFile = "database.sql"
+
+// DatabaseSettings are the settings of the database itself: the defaults of
+// session variables and the multi-region configuration. Without a database.sql
+// they aren't compared at all. With one, every session variable default is
+// managed, and the regions, survival goal and placement only if it sets them.
+type DatabaseSettings struct {
+	// Name is the database's name. The definitions can use any name, the
+	// statements are written for the database being changed.
+	Name string
+	// Settings are the session variable defaults, ALTER DATABASE ... SET
+	Settings map[string]string
+	// PrimaryRegion is empty unless the database is multi-region
+	PrimaryRegion string
+	// Regions include the primary region
+	Regions []string
+	// SurvivalGoal is "zone" or "region"
+	SurvivalGoal string
+	// Placement is "default" or "restricted"
+	Placement string
+	// Source is the database.sql the settings were read from
+	Source SourceLocation
+}
+
+// LoadDatabaseSettings reads the settings of the database the client is connected to
+func LoadDatabaseSettings(ctx context.Context, dbClient *db.Client) (*DatabaseSettings, error) {
+	settings, err := dbClient.GetDatabaseSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &DatabaseSettings{
+		Name:          settings.Name,
+		Settings:      settings.Settings,
+		PrimaryRegion: settings.PrimaryRegion,
+		Regions:       settings.Regions,
+		SurvivalGoal:  settings.SurvivalGoal,
+		Placement:     settings.Placement,
+	}, nil
+}
+
+// parseDatabaseSettings parses a database.sql file
+func parseDatabaseSettings(sql, file string) (*DatabaseSettings, error) {
+	statements, err := parser.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+	}
+
+	settings := &DatabaseSettings{
+		Settings: make(map[string]string),
+		Source:   SourceLocation{File: file},
+	}
+	for _, stmt := range statements {
+		var name tree.Name
+		switch s := stmt.AST.(type) {
+		case *tree.AlterRoleSet:
+			// ALTER DATABASE ... SET parses as ALTER ROLE ALL IN DATABASE ... SET
+			if !s.AllRoles || s.DatabaseName == "" || s.SetOrReset == nil || isSetDefault(s.SetOrReset) {
+				return nil, unsupportedDatabaseStatement(stmt.AST)
+			}
+			name = s.DatabaseName
+			settings.Settings[strings.ToLower(s.SetOrReset.Name)] = settingValue(s.SetOrReset.Values)
+		case *tree.AlterDatabasePrimaryRegion:
+			name = s.Name
+			settings.PrimaryRegion = string(s.PrimaryRegion)
+			settings.addRegion(string(s.PrimaryRegion))
+		case *tree.AlterDatabaseAddRegion:
+			name = s.Name
+			settings.addRegion(string(s.Region))
+		case *tree.AlterDatabaseSurvivalGoal:
+			name = s.Name
+			settings.SurvivalGoal = survivalGoalName(s.SurvivalGoal)
+		case *tree.AlterDatabasePlacement:
+			name = s.Name
+			settings.Placement = placementName(s.Placement)
+		default:
+			return nil, unsupportedDatabaseStatement(stmt.AST)
+		}
+		if settings.Name == "" {
+			settings.Name = string(name)
+		}
+	}
+	if settings.PrimaryRegion == "" && len(settings.Regions) > 0 {
+		return nil, fmt.Errorf("regions need a PRIMARY REGION")
+	}
+	return settings, nil
+}
+
+func unsupportedDatabaseStatement(stmt tree.Statement) error {
+	return fmt.Errorf("unsupported statement in %s: %s.\n%s only supports:\n\tALTER DATABASE ... SET\n\tALTER DATABASE ... PRIMARY REGION\n\tALTER DATABASE ... ADD REGION\n\tALTER DATABASE ... SURVIVE\n\tALTER DATABASE ... PLACEMENT",
+		DatabaseFile, stmt.StatementTag(), DatabaseFile)
+}
+
+func (d *DatabaseSettings) addRegion(region string) {
+	if !slices.Contains(d.Regions, region) {
+		d.Regions = append(d.Regions, region)
+	}
+}
+
+// merge applies the settings of an overlay's database.sql over d
+func (d *DatabaseSettings) merge(overlay *DatabaseSettings) {
+	maps.Copy(d.Settings, overlay.Settings)
+	if overlay.PrimaryRegion != "" {
+		d.PrimaryRegion = overlay.PrimaryRegion
+		d.Regions = overlay.Regions
+	}
+	if overlay.SurvivalGoal != "" {
+		d.SurvivalGoal = overlay.SurvivalGoal
+	}
+	if overlay.Placement != "" {
+		d.Placement = overlay.Placement
+	}
+}
+
+// isSetDefault returns true for SET x = DEFAULT, which is how RESET parses
+func isSetDefault(v *tree.SetVar) bool {
+	if len(v.Values) != 1 {
+		return false
+	}
+	_, ok := v.Values[0].(tree.DefaultVal)
+	return ok
+}
+
+// settingValue formats a setting's value the way the database stores it,
+// without quotes
+func settingValue(values tree.Exprs) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		if s, ok := value.(*tree.StrVal); ok {
+			parts[i] = s.RawString()
+		} else {
+			parts[i] = value.String()
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func survivalGoalName(goal tree.SurvivalGoal) string {
+	if goal == tree.SurvivalGoalRegionFailure {
+		return "region"
+	}
+	return "zone"
+}
+
+func placementName(placement tree.DataPlacement) string {
+	if placement == tree.DataPlacementRestricted {
+		return "restricted"
+	}
+	return "default"
+}
+
+// compareDatabase finds differences in the settings of the database itself,
+// when the definitions have a database.sql and the remote settings were loaded
+func compareDatabase(local, remote *Schema) []Difference {
+	if local.Database == nil || remote.Database == nil {
+		return nil
+	}
+	name := tree.Name(remote.Database.Name)
+	diffs := compareDatabaseVariables(name, local.Database.Settings, remote.Database.Settings)
+	if diff := compareDatabaseRegions(name, local.Database, remote.Database); diff != nil {
+		diffs = append(diffs, *diff)
+	}
+	return diffs
+}
+
+// compareDatabaseVariables sets the session variable defaults that differ, and
+// resets the ones the definitions don't have
+func compareDatabaseVariables(name tree.Name, local, remote map[string]string) []Difference {
+	var diffs []Difference
+	for _, setting := range slices.Sorted(maps.Keys(local)) {
+		value := local[setting]
+		current, ok := remote[setting]
+		if ok && current == value {
+			continue
+		}
+		description := fmt.Sprintf("Database setting '%s' set to '%s'", setting, value)
+		if ok {
+			description = fmt.Sprintf("Database setting '%s' changed from '%s' to '%s'", setting, current, value)
+		}
+		diffs = append(diffs, databaseVariableDiff(name, setting, description, tree.NewStrVal(value)))
+	}
+	for _, setting := range slices.Sorted(maps.Keys(remote)) {
+		if _, ok := local[setting]; ok {
+			continue
+		}
+		description := fmt.Sprintf("Database setting '%s' reset", setting)
+		diffs = append(diffs, databaseVariableDiff(name, setting, description, tree.DefaultVal{}))
+	}
+	return diffs
+}
+
+func databaseVariableDiff(name tree.Name, setting, description string, value tree.Expr) Difference {
+	return Difference{
+		Type:           DiffTypeDatabaseModified,
+		ObjectName:     databaseObjectName,
+		Description:    description,
+		WarningMessage: fmt.Sprintf("The new default of %s only applies to sessions opened after the change; open connections keep the old one.", setting),
+		Phases: inOnePhase(&tree.AlterRoleSet{
+			IsRole:       true,
+			AllRoles:     true,
+			DatabaseName: name,
+			SetOrReset:   &tree.SetVar{Name: setting, Values: tree.Exprs{value}},
+		}),
+	}
+}
+
+// compareDatabaseRegions changes the multi-region configuration, in the order
+// the database accepts it: regions are added before one of them becomes the
+// primary region or the survival goal needs them, and dropped last. Each
+// statement runs outside of a transaction.
+func compareDatabaseRegions(name tree.Name, local, remote *DatabaseSettings) *Difference {
+	var stmts []tree.Statement
+	var changes []string
+	dangerous := false
+
+	if local.PrimaryRegion != "" {
+		if remote.PrimaryRegion == "" {
+			// The first region makes the database multi-region
+			stmts = append(stmts, &tree.AlterDatabasePrimaryRegion{Name: name, PrimaryRegion: tree.Name(local.PrimaryRegion)})
+			changes = append(changes, fmt.Sprintf("primary region %s", local.PrimaryRegion))
+			dangerous = true
+		}
+		for _, region := range local.Regions {
+			if region != local.PrimaryRegion || remote.PrimaryRegion != "" {
+				if !slices.Contains(remote.Regions, region) {
+					stmts = append(stmts, &tree.AlterDatabaseAddRegion{Name: name, Region: tree.Name(region)})
+					changes = append(changes, fmt.Sprintf("added region %s", region))
+				}
+			}
+		}
+		if remote.PrimaryRegion != "" && remote.PrimaryRegion != local.PrimaryRegion {
+			stmts = append(stmts, &tree.AlterDatabasePrimaryRegion{Name: name, PrimaryRegion: tree.Name(local.PrimaryRegion)})
+			changes = append(changes, fmt.Sprintf("primary region %s instead of %s", local.PrimaryRegion, remote.PrimaryRegion))
+			dangerous = true
+		}
+	}
+	if local.SurvivalGoal != "" && local.SurvivalGoal != remote.SurvivalGoal {
+		goal := tree.SurvivalGoalZoneFailure
+		if local.SurvivalGoal == "region" {
+			goal = tree.SurvivalGoalRegionFailure
+		}
+		stmts = append(stmts, &tree.AlterDatabaseSurvivalGoal{Name: name, SurvivalGoal: goal})
+		changes = append(changes, fmt.Sprintf("survives %s failure", local.SurvivalGoal))
+		dangerous = true
+	}
+	if local.Placement != "" && local.Placement != remote.Placement {
+		placement := tree.DataPlacementDefault
+		if local.Placement == "restricted" {
+			placement = tree.DataPlacementRestricted
+		}
+		stmts = append(stmts, &tree.AlterDatabasePlacement{Name: name, Placement: placement})
+		changes = append(changes, fmt.Sprintf("%s placement", local.Placement))
+	}
+	if local.PrimaryRegion != "" {
+		for _, region := range remote.Regions {
+			if !slices.Contains(local.Regions, region) {
+				stmts = append(stmts, &tree.AlterDatabaseDropRegion{Name: name, Region: tree.Name(region)})
+				changes = append(changes, fmt.Sprintf("dropped region %s", region))
+				dangerous = true
+			}
+		}
+	}
+
+	if len(stmts) == 0 {
+		return nil
+	}
+	phases := make([]Phase, len(stmts))
+	for i, stmt := range stmts {
+		phases[i] = Phase{Statements: []tree.Statement{stmt}, NoTransaction: true}
+	}
+	return &Difference{
+		Type:           DiffTypeDatabaseModified,
+		ObjectName:     databaseObjectName,
+		Description:    fmt.Sprintf("Database regions changed: %s", strings.Join(changes, ", ")),
+		Dangerous:      dangerous,
+		WarningMessage: "Changing the database's regions moves replicas and leaseholders, which can take a long time and raise latencies while it happens.",
+		Phases:         phases,
+	}
+}
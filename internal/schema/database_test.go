@@ -0,0 +1,206 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDatabaseSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected *DatabaseSettings
+		wantErr  string
+	}{
+		{
+			name: "settings",
+			sql: `ALTER DATABASE app SET default_transaction_isolation = 'read committed';
+				ALTER DATABASE app SET statement_timeout = '10s';`,
+			expected: &DatabaseSettings{
+				Name:     "app",
+				Settings: map[string]string{"default_transaction_isolation": "read committed", "statement_timeout": "10s"},
+			},
+		},
+		{
+			name: "regions",
+			sql: `ALTER DATABASE app PRIMARY REGION "us-east1";
+				ALTER DATABASE app ADD REGION "us-west1";
+				ALTER DATABASE app ADD REGION "europe-west1";
+				ALTER DATABASE app SURVIVE REGION FAILURE;
+				ALTER DATABASE app PLACEMENT RESTRICTED;`,
+			expected: &DatabaseSettings{
+				Name:          "app",
+				Settings:      map[string]string{},
+				PrimaryRegion: "us-east1",
+				Regions:       []string{"us-east1", "us-west1", "europe-west1"},
+				SurvivalGoal:  "region",
+				Placement:     "restricted",
+			},
+		},
+		{
+			name:    "regions without a primary region",
+			sql:     `ALTER DATABASE app ADD REGION "us-west1";`,
+			wantErr: "regions need a PRIMARY REGION",
+		},
+		{
+			name:    "reset",
+			sql:     `ALTER DATABASE app RESET statement_timeout;`,
+			wantErr: "unsupported statement in database.sql",
+		},
+		{
+			name:    "role settings",
+			sql:     `ALTER ROLE reader SET statement_timeout = '10s';`,
+			wantErr: "unsupported statement in database.sql: ALTER ROLE",
+		},
+		{
+			name:    "tables",
+			sql:     `CREATE TABLE t (id INT8 PRIMARY KEY);`,
+			wantErr: "unsupported statement in database.sql: CREATE TABLE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := parseDatabaseSettings(tt.sql, "defs/database.sql")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.expected.Source = SourceLocation{File: "defs/database.sql"}
+			assert.Equal(t, tt.expected, settings)
+		})
+	}
+}
+
+func TestCompareDatabase(t *testing.T) {
+	tests := []struct {
+		name          string
+		local         *DatabaseSettings
+		remote        *DatabaseSettings
+		expected      []string
+		wantDangerous bool
+	}{
+		{
+			name:     "no database.sql",
+			remote:   &DatabaseSettings{Name: "prod", Settings: map[string]string{"statement_timeout": "10s"}},
+			expected: nil,
+		},
+		{
+			name:     "settings match",
+			local:    &DatabaseSettings{Name: "app", Settings: map[string]string{"statement_timeout": "10s"}},
+			remote:   &DatabaseSettings{Name: "prod", Settings: map[string]string{"statement_timeout": "10s"}},
+			expected: nil,
+		},
+		{
+			name:  "settings set, changed and reset",
+			local: &DatabaseSettings{Name: "app", Settings: map[string]string{"default_transaction_isolation": "read committed", "statement_timeout": "30s"}},
+			remote: &DatabaseSettings{Name: "prod", Settings: map[string]string{
+				"statement_timeout":                   "10s",
+				"idle_in_transaction_session_timeout": "1min",
+			}},
+			expected: []string{
+				"ALTER ROLE ALL IN DATABASE prod SET default_transaction_isolation = 'read committed'",
+				"ALTER ROLE ALL IN DATABASE prod SET statement_timeout = '30s'",
+				"ALTER ROLE ALL IN DATABASE prod SET idle_in_transaction_session_timeout = DEFAULT",
+			},
+		},
+		{
+			name: "database made multi-region",
+			local: &DatabaseSettings{
+				Settings:      map[string]string{},
+				PrimaryRegion: "us-east1",
+				Regions:       []string{"us-east1", "us-west1", "europe-west1"},
+				SurvivalGoal:  "region",
+			},
+			remote: &DatabaseSettings{Name: "prod", Settings: map[string]string{}, SurvivalGoal: "zone"},
+			expected: []string{
+				`ALTER DATABASE prod PRIMARY REGION "us-east1"`,
+				`ALTER DATABASE prod ADD REGION "us-west1"`,
+				`ALTER DATABASE prod ADD REGION "europe-west1"`,
+				"ALTER DATABASE prod SURVIVE REGION FAILURE",
+			},
+			wantDangerous: true,
+		},
+		{
+			name: "primary region moved",
+			local: &DatabaseSettings{
+				Settings:      map[string]string{},
+				PrimaryRegion: "us-west1",
+				Regions:       []string{"us-west1", "europe-west1"},
+			},
+			remote: &DatabaseSettings{
+				Name:          "prod",
+				Settings:      map[string]string{},
+				PrimaryRegion: "us-east1",
+				Regions:       []string{"europe-west1", "us-east1"},
+				SurvivalGoal:  "zone",
+			},
+			expected: []string{
+				`ALTER DATABASE prod ADD REGION "us-west1"`,
+				`ALTER DATABASE prod PRIMARY REGION "us-west1"`,
+				`ALTER DATABASE prod DROP REGION "us-east1"`,
+			},
+			wantDangerous: true,
+		},
+		{
+			name:   "regions aren't managed without a primary region",
+			local:  &DatabaseSettings{Settings: map[string]string{}},
+			remote: &DatabaseSettings{Name: "prod", Settings: map[string]string{}, PrimaryRegion: "us-east1", Regions: []string{"us-east1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, remote := NewSchema(), NewSchema()
+			local.Database = tt.local
+			remote.Database = tt.remote
+
+			var statements []string
+			dangerous := false
+			for _, diff := range compareDatabase(local, remote) {
+				assert.Equal(t, DiffTypeDatabaseModified, diff.Type)
+				dangerous = dangerous || diff.Dangerous
+				for _, stmt := range diff.Statements() {
+					statements = append(statements, stmt.String())
+				}
+			}
+			assert.Equal(t, tt.expected, statements)
+			assert.Equal(t, tt.wantDangerous, dangerous)
+		})
+	}
+}
+
+func TestLoadDefinitionsDatabaseFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"defs/database.sql":               "ALTER DATABASE app SET statement_timeout = '10s';\nALTER DATABASE app PRIMARY REGION \"us-east1\";",
+		"defs/tables/t.sql":               "CREATE TABLE t (id INT8 PRIMARY KEY);",
+		"defs/overlays/prod/database.sql": "ALTER DATABASE app SET statement_timeout = '30s';\nALTER DATABASE app SURVIVE REGION FAILURE;",
+	}
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+	}
+
+	s, err := LoadDefinitions(fs, []string{"defs"}, "")
+	require.NoError(t, err)
+	require.Len(t, s.Tables, 1)
+	require.NotNil(t, s.Database)
+	assert.Equal(t, map[string]string{"statement_timeout": "10s"}, s.Database.Settings)
+	assert.Equal(t, "us-east1", s.Database.PrimaryRegion)
+	assert.Equal(t, "defs/database.sql", s.SourceOf("database").File)
+
+	s, err = LoadDefinitions(fs, []string{"defs"}, "prod")
+	require.NoError(t, err)
+	require.NotNil(t, s.Database)
+	assert.Equal(t, map[string]string{"statement_timeout": "30s"}, s.Database.Settings)
+	assert.Equal(t, "us-east1", s.Database.PrimaryRegion)
+	assert.Equal(t, "region", s.Database.SurvivalGoal)
+
+	s, err = LoadDefinitions(fs, []string{"defs/tables"}, "")
+	require.NoError(t, err)
+	assert.Nil(t, s.Database)
+}
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
 
@@ -22,10 +23,16 @@ func GetDependencyNames(stmt tree.Statement, strict bool) set.Set[string] {
 		return getCreateTypeDependencies(stmt)
 	case *tree.CreateSequence:
 		return getCreateSequenceDependencies(stmt)
+	case *tree.CreateTrigger:
+		return getCreateTriggerDependencies(stmt)
 	case *tree.AlterType:
 		return getAlterTypeDependencies(stmt)
 	case *tree.AlterTable:
 		return getAlterTableDependencies(stmt, strict)
+	case *tree.AlterSequence:
+		return getAlterSequenceDependencies(stmt)
+	case *tree.AlterTableOwner:
+		return getAlterTableOwnerDependencies(stmt)
 	case *tree.CreateIndex:
 		return getIndexDependencies(stmt.Table, stmt.Columns, stmt.Storing, stmt.Predicate)
 
@@ -37,9 +44,34 @@ func GetDependencyNames(stmt tree.Statement, strict bool) set.Set[string] {
 	case *tree.DropType:
 	case *tree.DropView:
 	case *tree.DropIndex:
+	case *tree.DropTrigger:
 	case *tree.BeginTransaction:
 	case *tree.CommitTransaction:
 
+	// Renamed tables already exist under their old name
+	case *tree.RenameTable:
+
+	// Indexes made visible or NOT VISIBLE already exist
+	case *tree.AlterIndexVisible:
+
+	// Rows are only updated in tables that already exist
+	case *tree.Update:
+
+	// IMPORT INTO only loads data into tables that already exist
+	case *tree.Import:
+
+	// Session settings apply to the statements after them
+	case *tree.SetVar:
+
+	// Settings of the database and cluster don't depend on or provide any objects
+	case *tree.AlterRoleSet:
+	case *tree.AlterDatabasePrimaryRegion:
+	case *tree.AlterDatabaseAddRegion:
+	case *tree.AlterDatabaseDropRegion:
+	case *tree.AlterDatabaseSurvivalGoal:
+	case *tree.AlterDatabasePlacement:
+	case *tree.SetClusterSetting:
+
 	// Schemas have no dependencies.
 	case *tree.CreateSchema:
 	case *tree.DropSchema:
@@ -63,7 +95,7 @@ func getCreateTableDependencies(stmt *tree.CreateTable) set.Set[string] {
 			deps = addColumnDeps(schemaName, tableName, d, deps)
 		case *tree.ForeignKeyConstraintTableDef:
 			schema, table := getTableName(d.Table)
-			if table != tableName {
+			if schema != schemaName || table != tableName {
 				deps.Add(fmt.Sprintf("%s.%s", schema, table))
 			}
 			// FK targets a unique constraint on the referenced columns.
@@ -76,11 +108,15 @@ func getCreateTableDependencies(stmt *tree.CreateTable) set.Set[string] {
 			if len(d.ToCols) > 0 {
 				deps.Add(uniqueProviderName(schema, table, nameListStrings(d.ToCols)))
 			}
-		// None of these TableDefs can have dependencies afaik
-		case *tree.FamilyTableDef:
 		case *tree.CheckConstraintTableDef:
+			deps = deps.Union(getExprColumnDeps(schemaName, tableName, d.Expr))
 		case *tree.UniqueConstraintTableDef:
+			deps = deps.Union(getInlineIndexDependencies(schemaName, tableName, &d.IndexTableDef))
 		case *tree.IndexTableDef:
+			deps = deps.Union(getInlineIndexDependencies(schemaName, tableName, d))
+
+		// Families can't have dependencies
+		case *tree.FamilyTableDef:
 
 		// Not supported in scurry
 		case *tree.LikeTableDef:
@@ -91,6 +127,22 @@ func getCreateTableDependencies(stmt *tree.CreateTable) set.Set[string] {
 	return deps
 }
 
+// getInlineIndexDependencies returns what the expressions and predicate of an index
+// defined in CREATE TABLE reference. Plain columns are left out, they're part of
+// the table itself.
+func getInlineIndexDependencies(schemaName, tableName string, index *tree.IndexTableDef) set.Set[string] {
+	deps := set.New[string]()
+	for _, col := range index.Columns {
+		if col.Expr != nil {
+			deps = deps.Union(getExprColumnDeps(schemaName, tableName, col.Expr))
+		}
+	}
+	if index.Predicate != nil {
+		deps = deps.Union(getExprColumnDeps(schemaName, tableName, index.Predicate))
+	}
+	return deps
+}
+
 func addColumnDeps(schemaName, tableName string, d *tree.ColumnTableDef, deps set.Set[string]) set.Set[string] {
 	if d.Computed.Computed {
 		// Computed column expressions reference other columns in the same table,
@@ -101,6 +153,12 @@ func addColumnDeps(schemaName, tableName string, d *tree.ColumnTableDef, deps se
 	if d.DefaultExpr.Expr != nil {
 		deps = deps.Union(getExprDeps(d.DefaultExpr.Expr))
 	}
+	if d.OnUpdateExpr.Expr != nil {
+		deps = deps.Union(getExprDeps(d.OnUpdateExpr.Expr))
+	}
+	for _, check := range d.CheckExprs {
+		deps = deps.Union(getExprColumnDeps(schemaName, tableName, check.Expr))
+	}
 	if name, ok := getResolvableTypeReferenceDepName(d.Type); ok {
 		deps.Add(name)
 	}
@@ -112,8 +170,78 @@ func getCreateViewDependencies(stmt *tree.CreateView) set.Set[string] {
 
 	schemaName, _ := getTableName(stmt.Name)
 	deps.Add("schema:" + schemaName)
-	// TODO: find dependencies in the view definition
+	deps = deps.Union(getSelectDependencies(stmt.AsSource, set.New[string]()))
+
+	return deps
+}
+
+// getSelectDependencies returns the tables and views a query reads from. Names
+// defined by WITH are skipped, since they don't refer to other objects.
+func getSelectDependencies(sel *tree.Select, ctes set.Set[string]) set.Set[string] {
+	deps := set.New[string]()
+	if sel == nil {
+		return deps
+	}
+
+	if sel.With != nil {
+		for _, cte := range sel.With.CTEList {
+			ctes.Add(cte.Name.Alias.Normalize())
+			if stmt, ok := cte.Stmt.(*tree.Select); ok {
+				deps = deps.Union(getSelectDependencies(stmt, ctes))
+			}
+		}
+	}
+
+	return deps.Union(getSelectStatementDependencies(sel.Select, ctes))
+}
 
+func getSelectStatementDependencies(stmt tree.SelectStatement, ctes set.Set[string]) set.Set[string] {
+	deps := set.New[string]()
+	switch s := stmt.(type) {
+	case *tree.SelectClause:
+		for _, table := range s.From.Tables {
+			deps = deps.Union(getTableExprDependencies(table, ctes))
+		}
+		for _, expr := range s.Exprs {
+			deps = deps.Union(getExprTypeDeps(expr.Expr))
+		}
+		if s.Where != nil {
+			deps = deps.Union(getExprTypeDeps(s.Where.Expr))
+		}
+	case *tree.ParenSelect:
+		deps = deps.Union(getSelectDependencies(s.Select, ctes))
+	case *tree.UnionClause:
+		deps = deps.Union(getSelectDependencies(s.Left, ctes))
+		deps = deps.Union(getSelectDependencies(s.Right, ctes))
+	}
+	return deps
+}
+
+func getTableExprDependencies(expr tree.TableExpr, ctes set.Set[string]) set.Set[string] {
+	deps := set.New[string]()
+	switch e := expr.(type) {
+	case *tree.AliasedTableExpr:
+		deps = deps.Union(getTableExprDependencies(e.Expr, ctes))
+	case *tree.ParenTableExpr:
+		deps = deps.Union(getTableExprDependencies(e.Expr, ctes))
+	case *tree.JoinTableExpr:
+		deps = deps.Union(getTableExprDependencies(e.Left, ctes))
+		deps = deps.Union(getTableExprDependencies(e.Right, ctes))
+	case *tree.Subquery:
+		deps = deps.Union(getSelectStatementDependencies(e.Select, ctes))
+	case *tree.UnresolvedObjectName:
+		if !e.HasExplicitSchema() && ctes.Contains(strings.ToLower(e.Object())) {
+			break
+		}
+		schemaName, tableName := getObjectName(e)
+		deps.Add(schemaName + "." + tableName)
+	case *tree.TableName:
+		if !e.ExplicitSchema && ctes.Contains(e.ObjectName.Normalize()) {
+			break
+		}
+		schemaName, tableName := getTableName(*e)
+		deps.Add(schemaName + "." + tableName)
+	}
 	return deps
 }
 
@@ -122,7 +250,43 @@ func getCreateRoutineDependencies(stmt *tree.CreateRoutine) set.Set[string] {
 
 	schemaName, _ := getRoutineName(stmt.Name)
 	deps.Add("schema:" + schemaName)
-	// TODO: find dependencies in the routine definition
+
+	// User defined types in the signature have to exist first
+	for _, param := range stmt.Params {
+		if name, ok := getResolvableTypeReferenceDepName(param.Type); ok {
+			deps.Add(name)
+		}
+		if param.DefaultVal != nil {
+			deps = deps.Union(getExprDeps(param.DefaultVal))
+		}
+	}
+	if stmt.ReturnType != nil {
+		if name, ok := getResolvableTypeReferenceDepName(stmt.ReturnType.Type); ok {
+			deps.Add(name)
+		}
+	}
+	// TODO: find dependencies in the routine body
+
+	return deps
+}
+
+func getCreateTriggerDependencies(stmt *tree.CreateTrigger) set.Set[string] {
+	deps := set.New[string]()
+
+	// Triggers need their table, the function they execute, and any UPDATE OF columns
+	schemaName, tableName := getObjectName(stmt.TableName)
+	deps.Add(schemaName + "." + tableName)
+	if schemaName == "public" {
+		deps.Add(tableName)
+	}
+	for _, event := range stmt.Events {
+		for _, col := range event.Columns {
+			deps.Add(schemaName + "." + tableName + "." + col.Normalize())
+		}
+	}
+
+	funcSchema, funcName := getTriggerFunctionName(stmt.FuncName)
+	deps.Add(funcSchema + "." + funcName)
 
 	return deps
 }
@@ -132,7 +296,12 @@ func getCreateTypeDependencies(stmt *tree.CreateType) set.Set[string] {
 
 	schemaName, _ := getObjectName(stmt.TypeName)
 	deps.Add("schema:" + schemaName)
-	// TODO: find dependencies in the type definition
+	// Composite types need the user defined types of their members
+	for _, elem := range stmt.CompositeTypeList {
+		if name, ok := getResolvableTypeReferenceDepName(elem.Type); ok {
+			deps.Add(name)
+		}
+	}
 
 	return deps
 }
@@ -148,6 +317,28 @@ func getCreateSequenceDependencies(stmt *tree.CreateSequence) set.Set[string] {
 	return deps
 }
 
+func getAlterSequenceDependencies(stmt *tree.AlterSequence) set.Set[string] {
+	deps := set.New[string]()
+
+	schemaName, seqName := getObjectName(stmt.Name)
+	deps.Add(schemaName + "." + seqName)
+	if schemaName == "public" {
+		deps.Add(seqName)
+	}
+
+	// OWNED BY needs the owning column to exist
+	for _, opt := range stmt.Options {
+		if opt.Name != tree.SeqOptOwnedBy || opt.ColumnItemVal == nil || opt.ColumnItemVal.TableName == nil {
+			continue
+		}
+		tableSchema, tableName := getObjectName(opt.ColumnItemVal.TableName)
+		deps.Add(tableSchema + "." + tableName)
+		deps.Add(tableSchema + "." + tableName + "." + opt.ColumnItemVal.ColumnName.Normalize())
+	}
+
+	return deps
+}
+
 func getAlterTypeDependencies(stmt *tree.AlterType) set.Set[string] {
 	deps := set.New[string]()
 
@@ -160,6 +351,20 @@ func getAlterTypeDependencies(stmt *tree.AlterType) set.Set[string] {
 	return deps
 }
 
+// getAlterTableOwnerDependencies returns the table, view or sequence an
+// ALTER ... OWNER TO changes the owner of
+func getAlterTableOwnerDependencies(stmt *tree.AlterTableOwner) set.Set[string] {
+	deps := set.New[string]()
+
+	schemaName, objectName := getObjectName(stmt.Name)
+	deps.Add(schemaName + "." + objectName)
+	if schemaName == "public" {
+		deps.Add(objectName)
+	}
+
+	return deps
+}
+
 func getAlterTableDependencies(stmt *tree.AlterTable, strict bool) set.Set[string] {
 	deps := set.New[string]()
 
@@ -226,11 +431,13 @@ func getAlterTableDependencies(stmt *tree.AlterTable, strict bool) set.Set[strin
 			deps = deps.Union(getIndexDependencies(stmt.Table.ToTableName(), c.Columns, tree.NameList{}, nil))
 
 		// These have no dependencies
+		case *tree.AlterTableRenameColumn:
 		case *tree.AlterTableDropColumn:
 		case *tree.AlterTableDropNotNull:
 		case *tree.AlterTableDropStored:
 		case *tree.AlterTableSetNotNull:
 		case *tree.AlterTableDropConstraint:
+		case *tree.AlterTableValidateConstraint:
 		case *tree.AlterTableSetVisible:
 		case *tree.AlterTableSetStorageParams:
 		case *tree.AlterTableResetStorageParams:
@@ -0,0 +1,386 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/idxtype"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/types"
+)
+
+// Dialect is the database generated statements are written for
+type Dialect string
+
+const (
+	DialectCockroachDB Dialect = "cockroachdb"
+	DialectPostgres    Dialect = "postgres"
+)
+
+// ParseDialect validates a --dialect value
+func ParseDialect(s string) (Dialect, error) {
+	switch d := Dialect(strings.ToLower(s)); d {
+	case DialectCockroachDB, DialectPostgres:
+		return d, nil
+	case "postgresql":
+		return DialectPostgres, nil
+	default:
+		return "", fmt.Errorf("unknown dialect %q, expected cockroachdb or postgres", s)
+	}
+}
+
+// ForDialect rewrites the statements of each difference for the dialect. For
+// Postgres, types get their Postgres names, indexes move out of CREATE TABLE
+// into CREATE INDEX statements, and type annotations are dropped. A difference
+// using something only CockroachDB has gets a BlockingError, so
+// GenerateMigrations fails before a migration is written.
+func (r *ComparisonResult) ForDialect(d Dialect) {
+	if d != DialectPostgres {
+		return
+	}
+	for i := range r.Differences {
+		diff := &r.Differences[i]
+		var unsupported []string
+		for j := range diff.Phases {
+			var stmts []tree.Statement
+			for _, stmt := range diff.Phases[j].Statements {
+				rewriter := &postgresRewriter{}
+				stmts = append(stmts, rewriter.statement(stmt)...)
+				for _, feature := range rewriter.unsupported {
+					if !slices.Contains(unsupported, feature) {
+						unsupported = append(unsupported, feature)
+					}
+				}
+			}
+			diff.Phases[j].Statements = stmts
+		}
+		if len(unsupported) > 0 {
+			diff.BlockingError = fmt.Sprintf("%s: Postgres has no equivalent of %s", diff.Description, strings.Join(unsupported, ", "))
+		}
+	}
+}
+
+// postgresType is a type reference written with its Postgres name
+type postgresType string
+
+func (t postgresType) SQLString() string { return string(t) }
+
+func (t postgresType) Format(ctx *tree.FmtCtx) { ctx.WriteString(string(t)) }
+
+// postgresTypeName returns the Postgres name of a CockroachDB type, or "" if
+// Postgres already spells it the same way
+func postgresTypeName(t *types.T) string {
+	switch t.Family() {
+	case types.IntFamily:
+		switch t.Width() {
+		case 16:
+			return "SMALLINT"
+		case 32:
+			return "INTEGER"
+		default:
+			return "BIGINT"
+		}
+	case types.FloatFamily:
+		if t.Width() == 32 {
+			return "REAL"
+		}
+		return "DOUBLE PRECISION"
+	case types.StringFamily:
+		name := t.SQLString()
+		if name == "STRING" {
+			return "TEXT"
+		}
+		if rest, ok := strings.CutPrefix(name, "STRING("); ok {
+			return "VARCHAR(" + rest
+		}
+	case types.BytesFamily:
+		return "BYTEA"
+	case types.ArrayFamily:
+		if elem := postgresTypeName(t.ArrayContents()); elem != "" {
+			return elem + "[]"
+		}
+	}
+	return ""
+}
+
+// postgresRewriter rewrites statements for Postgres, collecting the
+// CockroachDB features it found that Postgres has no equivalent of
+type postgresRewriter struct {
+	unsupported []string
+}
+
+func (p *postgresRewriter) unsupportedFeature(feature string) {
+	if !slices.Contains(p.unsupported, feature) {
+		p.unsupported = append(p.unsupported, feature)
+	}
+}
+
+func (p *postgresRewriter) statement(stmt tree.Statement) []tree.Statement {
+	switch stmt := stmt.(type) {
+	case *tree.CreateTable:
+		return p.createTable(stmt)
+	case *tree.CreateIndex:
+		return []tree.Statement{p.createIndex(stmt)}
+	case *tree.AlterTable:
+		return []tree.Statement{p.alterTable(stmt)}
+	case *tree.AlterIndexVisible:
+		p.unsupportedFeature("NOT VISIBLE indexes")
+	case *tree.AlterType:
+		if _, ok := stmt.Cmd.(*tree.AlterTypeDropValue); ok {
+			p.unsupportedFeature("dropping enum values")
+		}
+	case *tree.SetVar:
+		// CockroachDB session settings mean nothing to Postgres
+		if strings.HasPrefix(stmt.Name, "enable_experimental_") || strings.HasPrefix(stmt.Name, "experimental_enable_") {
+			return nil
+		}
+	}
+	return []tree.Statement{stmt}
+}
+
+// createTable rewrites the table's columns and constraints, and moves its
+// indexes into CREATE INDEX statements that follow it
+func (p *postgresRewriter) createTable(stmt *tree.CreateTable) []tree.Statement {
+	table := *stmt
+	table.Defs = make(tree.TableDefs, 0, len(stmt.Defs))
+	if len(stmt.StorageParams) > 0 {
+		p.unsupportedFeature("table storage parameters")
+	}
+	if stmt.Locality != nil {
+		p.unsupportedFeature("table localities")
+	}
+	if stmt.PartitionByTable != nil {
+		p.unsupportedFeature("CockroachDB partitioning")
+	}
+
+	var indexes []tree.Statement
+	for _, def := range stmt.Defs {
+		switch def := def.(type) {
+		case *tree.ColumnTableDef:
+			table.Defs = append(table.Defs, p.column(def))
+		case *tree.IndexTableDef:
+			indexes = append(indexes, p.createIndex(indexFromTableDef(stmt.Table, def, false)))
+		case *tree.UniqueConstraintTableDef:
+			if def.WithoutIndex {
+				p.unsupportedFeature("UNIQUE WITHOUT INDEX")
+			}
+			if def.PrimaryKey || isPlainUniqueConstraint(def) {
+				table.Defs = append(table.Defs, uniqueConstraint(def))
+				continue
+			}
+			indexes = append(indexes, p.createIndex(indexFromTableDef(stmt.Table, &def.IndexTableDef, true)))
+		case *tree.CheckConstraintTableDef:
+			check := *def
+			check.Expr = p.expr(def.Expr)
+			table.Defs = append(table.Defs, &check)
+		case *tree.FamilyTableDef:
+			p.unsupportedFeature("column families")
+		default:
+			table.Defs = append(table.Defs, def)
+		}
+	}
+	return append([]tree.Statement{&table}, indexes...)
+}
+
+// column rewrites a column's type and expressions
+func (p *postgresRewriter) column(def *tree.ColumnTableDef) *tree.ColumnTableDef {
+	col := *def
+	col.Type = p.typeRef(def.Type)
+	if def.DefaultExpr.Expr != nil {
+		col.DefaultExpr.Expr = p.expr(def.DefaultExpr.Expr)
+	}
+	if def.OnUpdateExpr.Expr != nil {
+		p.unsupportedFeature("ON UPDATE expressions")
+	}
+	if def.Computed.Computed {
+		p.unsupportedFeature("computed columns")
+	}
+	if def.Hidden {
+		p.unsupportedFeature("NOT VISIBLE columns")
+	}
+	if def.Family.Name != "" || def.Family.Create {
+		p.unsupportedFeature("column families")
+	}
+	col.CheckExprs = make([]tree.ColumnTableDefCheckExpr, len(def.CheckExprs))
+	for i, check := range def.CheckExprs {
+		check.Expr = p.expr(check.Expr)
+		col.CheckExprs[i] = check
+	}
+	return &col
+}
+
+func (p *postgresRewriter) createIndex(stmt *tree.CreateIndex) *tree.CreateIndex {
+	index := *stmt
+	switch stmt.Type {
+	case idxtype.INVERTED:
+		p.unsupportedFeature("inverted indexes (use a GIN index)")
+	case idxtype.VECTOR:
+		p.unsupportedFeature("vector indexes")
+	}
+	if stmt.Sharded != nil {
+		p.unsupportedFeature("hash-sharded indexes")
+	}
+	if len(stmt.Storing) > 0 {
+		p.unsupportedFeature("STORING (use INCLUDE)")
+	}
+	if stmt.PartitionByIndex != nil {
+		p.unsupportedFeature("CockroachDB partitioning")
+	}
+	if stmt.Invisibility.Value != 0 {
+		p.unsupportedFeature("NOT VISIBLE indexes")
+	}
+	if stmt.Predicate != nil {
+		index.Predicate = p.expr(stmt.Predicate)
+	}
+	index.Columns = make(tree.IndexElemList, len(stmt.Columns))
+	for i, elem := range stmt.Columns {
+		if elem.Expr != nil {
+			elem.Expr = p.expr(elem.Expr)
+		}
+		index.Columns[i] = elem
+	}
+	return &index
+}
+
+func (p *postgresRewriter) alterTable(stmt *tree.AlterTable) *tree.AlterTable {
+	alter := *stmt
+	alter.Cmds = make(tree.AlterTableCmds, len(stmt.Cmds))
+	for i, cmd := range stmt.Cmds {
+		switch cmd := cmd.(type) {
+		case *tree.AlterTableAddColumn:
+			add := *cmd
+			add.ColumnDef = p.column(cmd.ColumnDef)
+			alter.Cmds[i] = &add
+		case *tree.AlterTableAlterColumnType:
+			alterType := *cmd
+			alterType.ToType = p.typeRef(cmd.ToType)
+			if cmd.Using != nil {
+				alterType.Using = p.expr(cmd.Using)
+			}
+			alter.Cmds[i] = &alterType
+		case *tree.AlterTableSetDefault:
+			setDefault := *cmd
+			if cmd.Default != nil {
+				setDefault.Default = p.expr(cmd.Default)
+			}
+			alter.Cmds[i] = &setDefault
+		case *tree.AlterTableAddConstraint:
+			add := *cmd
+			switch def := cmd.ConstraintDef.(type) {
+			case *tree.CheckConstraintTableDef:
+				check := *def
+				check.Expr = p.expr(def.Expr)
+				add.ConstraintDef = &check
+			case *tree.UniqueConstraintTableDef:
+				if def.WithoutIndex {
+					p.unsupportedFeature("UNIQUE WITHOUT INDEX")
+				}
+				add.ConstraintDef = uniqueConstraint(def)
+			}
+			alter.Cmds[i] = &add
+		case *tree.AlterTableSetOnUpdate:
+			p.unsupportedFeature("ON UPDATE expressions")
+			alter.Cmds[i] = cmd
+		case *tree.AlterTableSetStorageParams, *tree.AlterTableResetStorageParams:
+			p.unsupportedFeature("table storage parameters")
+			alter.Cmds[i] = cmd
+		case *tree.AlterTableAlterPrimaryKey:
+			p.unsupportedFeature("ALTER PRIMARY KEY")
+			alter.Cmds[i] = cmd
+		case *tree.AlterTableSetVisible:
+			p.unsupportedFeature("NOT VISIBLE columns")
+			alter.Cmds[i] = cmd
+		case *tree.AlterTablePartitionByTable:
+			p.unsupportedFeature("CockroachDB partitioning")
+			alter.Cmds[i] = cmd
+		default:
+			alter.Cmds[i] = cmd
+		}
+	}
+	return &alter
+}
+
+// typeRef returns the Postgres spelling of a type reference. Types Postgres
+// spells the same way, and user-defined types, are kept.
+func (p *postgresRewriter) typeRef(ref tree.ResolvableTypeReference) tree.ResolvableTypeReference {
+	if t, ok := ref.(*types.T); ok {
+		if name := postgresTypeName(t); name != "" {
+			return postgresType(name)
+		}
+	}
+	return ref
+}
+
+// expr drops CockroachDB's type annotations (x:::STRING) and writes casts with
+// Postgres type names
+func (p *postgresRewriter) expr(expr tree.Expr) tree.Expr {
+	rewritten, err := tree.SimpleVisit(expr, func(expr tree.Expr) (bool, tree.Expr, error) {
+		switch e := expr.(type) {
+		case *tree.AnnotateTypeExpr:
+			return false, p.expr(e.Expr), nil
+		case *tree.CastExpr:
+			cast := *e
+			cast.Expr = p.expr(e.Expr)
+			cast.Type = p.typeRef(e.Type)
+			if cast.SyntaxMode == tree.CastPrepend {
+				cast.SyntaxMode = tree.CastShort
+			}
+			return false, &cast, nil
+		case *tree.FuncExpr:
+			if strings.EqualFold(e.Func.String(), "unique_rowid") {
+				p.unsupportedFeature("unique_rowid() (use an identity column or a sequence)")
+			}
+		}
+		return true, expr, nil
+	})
+	if err != nil {
+		return expr
+	}
+	return rewritten
+}
+
+// indexFromTableDef turns an index defined in a CREATE TABLE into a CREATE
+// INDEX statement
+func indexFromTableDef(table tree.TableName, def *tree.IndexTableDef, unique bool) *tree.CreateIndex {
+	return &tree.CreateIndex{
+		Name:             def.Name,
+		Table:            table,
+		Unique:           unique,
+		Type:             def.Type,
+		Columns:          def.Columns,
+		Sharded:          def.Sharded,
+		Storing:          def.Storing,
+		PartitionByIndex: def.PartitionByIndex,
+		StorageParams:    def.StorageParams,
+		Predicate:        def.Predicate,
+		Invisibility:     def.Invisibility,
+	}
+}
+
+// isPlainUniqueConstraint returns true if a unique index can be written as a
+// Postgres UNIQUE constraint: it only lists columns, without directions.
+func isPlainUniqueConstraint(def *tree.UniqueConstraintTableDef) bool {
+	if def.Sharded != nil || len(def.Storing) > 0 || def.Predicate != nil || def.Type != idxtype.FORWARD || def.PartitionByIndex != nil {
+		return false
+	}
+	for _, elem := range def.Columns {
+		if elem.Expr != nil || elem.Direction == tree.Descending {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueConstraint writes a primary key or unique index as a constraint,
+// without the ASC Postgres doesn't allow in one
+func uniqueConstraint(def *tree.UniqueConstraintTableDef) *tree.UniqueConstraintTableDef {
+	constraint := *def
+	constraint.FormatAsIndex = false
+	constraint.Columns = make(tree.IndexElemList, len(def.Columns))
+	for i, elem := range def.Columns {
+		elem.Direction = tree.DefaultDirection
+		constraint.Columns[i] = elem
+	}
+	return &constraint
+}
@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDialect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Dialect
+		wantErr  bool
+	}{
+		{input: "cockroachdb", expected: DialectCockroachDB},
+		{input: "postgres", expected: DialectPostgres},
+		{input: "PostgreSQL", expected: DialectPostgres},
+		{input: "mysql", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseDialect(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
+
+func TestForDialectPostgres(t *testing.T) {
+	tests := []struct {
+		name         string
+		statements   []string
+		expected     []string
+		wantBlocking []string
+	}{
+		{
+			name: "table with indexes",
+			statements: []string{`CREATE TABLE public.users (
+				id INT8 NOT NULL,
+				email STRING NOT NULL,
+				name STRING(100),
+				score FLOAT8,
+				avatar BYTES,
+				age INT4,
+				tags STRING[],
+				status STRING DEFAULT 'active':::STRING,
+				CONSTRAINT users_pkey PRIMARY KEY (id ASC),
+				UNIQUE INDEX users_email_key (email ASC),
+				INDEX users_name_idx (name DESC) WHERE status = 'active':::STRING
+			)`},
+			expected: []string{
+				"CREATE TABLE public.users (id BIGINT NOT NULL, email TEXT NOT NULL, name VARCHAR(100), score DOUBLE PRECISION, avatar BYTEA, age INTEGER, tags TEXT[], status TEXT DEFAULT 'active', CONSTRAINT users_pkey PRIMARY KEY (id), CONSTRAINT users_email_key UNIQUE (email))",
+				"CREATE INDEX users_name_idx ON public.users (name DESC) WHERE status = 'active'",
+			},
+		},
+		{
+			name:       "added column and type change",
+			statements: []string{"ALTER TABLE public.users ADD COLUMN bio STRING DEFAULT '':::STRING", "ALTER TABLE public.users ALTER COLUMN age TYPE INT8 USING age::INT8"},
+			expected: []string{
+				"ALTER TABLE public.users ADD COLUMN bio TEXT DEFAULT ''",
+				"ALTER TABLE public.users ALTER COLUMN age SET DATA TYPE BIGINT USING age::BIGINT",
+			},
+		},
+		{
+			name:       "experimental session settings are dropped",
+			statements: []string{"SET enable_experimental_alter_column_type_general = true", "ALTER TABLE public.users ALTER COLUMN age TYPE INT4"},
+			expected:   []string{"ALTER TABLE public.users ALTER COLUMN age SET DATA TYPE INTEGER"},
+		},
+		{
+			name:         "row-level TTL",
+			statements:   []string{"ALTER TABLE public.sessions SET (ttl_expiration_expression = 'expires_at')"},
+			wantBlocking: []string{"Postgres has no equivalent of table storage parameters"},
+		},
+		{
+			name:         "unique_rowid default",
+			statements:   []string{"CREATE TABLE public.events (id INT8 NOT NULL DEFAULT unique_rowid(), CONSTRAINT events_pkey PRIMARY KEY (id ASC))"},
+			wantBlocking: []string{"unique_rowid()"},
+		},
+		{
+			name:         "CockroachDB-only indexes",
+			statements:   []string{"CREATE TABLE public.docs (id INT8 NOT NULL, body JSONB, at TIMESTAMPTZ, CONSTRAINT docs_pkey PRIMARY KEY (id ASC), INVERTED INDEX docs_body_idx (body), INDEX docs_at_idx (at ASC) USING HASH)"},
+			wantBlocking: []string{"inverted indexes", "hash-sharded indexes"},
+		},
+		{
+			name:         "dropping an enum value",
+			statements:   []string{"ALTER TYPE public.status DROP VALUE 'pending'"},
+			wantBlocking: []string{"dropping enum values"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts := parseStatements(tt.statements...)
+			original := statementsToStringsTypes(stmts)
+			result := &ComparisonResult{Differences: []Difference{{Description: "change", Phases: inOnePhase(stmts...)}}}
+
+			result.ForDialect(DialectPostgres)
+
+			diff := result.Differences[0]
+			assert.Equal(t, original, statementsToStringsTypes(stmts), "the original statements should be left alone")
+			if len(tt.wantBlocking) == 0 {
+				assert.Empty(t, diff.BlockingError)
+				assert.Equal(t, tt.expected, statementsToStringsTypes(diff.Statements()))
+				return
+			}
+			for _, want := range tt.wantBlocking {
+				assert.Contains(t, diff.BlockingError, want)
+			}
+		})
+	}
+}
+
+func TestForDialectCockroachDB(t *testing.T) {
+	stmts := parseStatements("CREATE TABLE public.users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC), INDEX users_id_idx (id ASC))")
+	result := &ComparisonResult{Differences: []Difference{{Description: "change", Phases: inOnePhase(stmts...)}}}
+
+	result.ForDialect(DialectCockroachDB)
+
+	require.Len(t, result.Differences[0].Statements(), 1)
+	assert.Same(t, stmts[0].(*tree.CreateTable), result.Differences[0].Statements()[0])
+}
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
 
@@ -32,13 +33,27 @@ const (
 	DiffTypeViewRemoved  DiffType = "view_removed"
 	DiffTypeViewModified DiffType = "view_modified"
 
+	DiffTypeTriggerAdded    DiffType = "trigger_added"
+	DiffTypeTriggerRemoved  DiffType = "trigger_removed"
+	DiffTypeTriggerModified DiffType = "trigger_modified"
+
 	DiffTypeTableAdded          DiffType = "table_added"
 	DiffTypeTableRemoved        DiffType = "table_removed"
 	DiffTypeTableModified       DiffType = "table_modified"
 	DiffTypeTableColumnModified DiffType = "table_column_modified"
 	DiffTypeColumnTypeChanged   DiffType = "column_type_changed"
+	DiffTypeTableRenamed        DiffType = "table_renamed"
+	DiffTypeColumnRenamed       DiffType = "column_renamed"
+
+	DiffTypeOwnerModified DiffType = "owner_modified"
+
+	DiffTypeDatabaseModified       DiffType = "database_modified"
+	DiffTypeClusterSettingModified DiffType = "cluster_setting_modified"
 )
 
+// databaseObjectName is the ObjectName of differences to the database itself
+const databaseObjectName = "database"
+
 // Difference represents a single schema difference
 type Difference struct {
 	Type                 DiffType
@@ -47,8 +62,14 @@ type Difference struct {
 	Dangerous            bool
 	WarningMessage       string
 	IsDropCreate         bool
-	MigrationStatements  []tree.Statement
+	HasUsingDirective    bool   // Column type changes use expressions from -- scurry:using directives
+	InferredRename       bool   // A rename guessed from matching definitions rather than declared with -- scurry:renamed-from
+	Classification       string // ClassifySync or ClassifyAsync from a -- scurry:classify directive, overriding table sizes
+	Phases               []Phase
 	OriginalDependencies set.Set[string] // For DROP ordering: what the dropped object depended on
+	Source               SourceLocation  // Where the local object is defined, if known
+	IgnoreReason         string          // Why the object is expected to differ, for differences in ComparisonResult.Ignored
+	RunsAfter            []string        // Keys of differences this one is pinned to run after, on top of its dependencies
 
 	// BlockingError, when non-empty, indicates a difference that scurry cannot
 	// express as DDL. GenerateMigrations refuses to produce migrations while any
@@ -56,9 +77,88 @@ type Difference struct {
 	BlockingError string
 }
 
+// Phase is a group of a difference's statements that run together. Each phase
+// after the first runs in a transaction of its own, so a difference that has to
+// start in a new transaction leads with an empty phase, and one that must not
+// share its last transaction with what follows ends with one. NoTransaction
+// phases run outside of any explicit transaction.
+type Phase struct {
+	Statements    []tree.Statement
+	NoTransaction bool
+}
+
+// inOnePhase returns phases running every statement in the same transaction
+func inOnePhase(stmts ...tree.Statement) []Phase {
+	return []Phase{{Statements: stmts}}
+}
+
+// Statements returns the statements of every phase, in order
+func (d Difference) Statements() []tree.Statement {
+	var stmts []tree.Statement
+	for _, phase := range d.Phases {
+		stmts = append(stmts, phase.Statements...)
+	}
+	return stmts
+}
+
+// TransactionStatements returns the statements of every phase, with the
+// boundaries between phases written as COMMIT and BEGIN statements, the way
+// db.ExecuteBulkDDL splits them into transactions
+func (d Difference) TransactionStatements() []tree.Statement {
+	var stmts []tree.Statement
+	for i, phase := range d.Phases {
+		if phase.NoTransaction {
+			stmts = append(stmts, &tree.CommitTransaction{})
+			stmts = append(stmts, phase.Statements...)
+			stmts = append(stmts, &tree.BeginTransaction{})
+			continue
+		}
+		if i > 0 && !d.Phases[i-1].NoTransaction {
+			stmts = append(stmts, &tree.CommitTransaction{}, &tree.BeginTransaction{})
+		}
+		stmts = append(stmts, phase.Statements...)
+	}
+	return stmts
+}
+
+// Key identifies a difference across repeated comparisons of the same schemas
+func (d Difference) Key() string {
+	return fmt.Sprintf("%s|%s|%s", d.Type, d.ObjectName, d.Description)
+}
+
+// DescriptionWithSource returns the description followed by the definition
+// location, e.g. "Table 'public.users' modified (schema/tables/users.sql:14)"
+func (d Difference) DescriptionWithSource() string {
+	if d.Source.IsZero() {
+		return d.Description
+	}
+	return fmt.Sprintf("%s (%s)", d.Description, d.Source)
+}
+
+// DropsData returns true if the difference drops a table or a column, losing the data in it
+func (d Difference) DropsData() bool {
+	for _, stmt := range d.Statements() {
+		switch stmt := stmt.(type) {
+		case *tree.DropTable:
+			return true
+		case *tree.AlterTable:
+			for _, cmd := range stmt.Cmds {
+				if _, ok := cmd.(*tree.AlterTableDropColumn); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // ComparisonResult holds all differences between two schemas
 type ComparisonResult struct {
 	Differences []Difference
+	// Ignored are the differences to objects the local schema declares as
+	// expected to differ (see IgnoredObjects); no statements are generated
+	// for them
+	Ignored []Difference
 }
 
 // Compare compares two schemas and returns all differences
@@ -67,12 +167,23 @@ func Compare(local, remote *Schema) *ComparisonResult {
 		Differences: make([]Difference, 0),
 	}
 
+	result.Differences = append(result.Differences, compareClusterSettings(local, remote)...)
+	result.Differences = append(result.Differences, compareDatabase(local, remote)...)
 	result.Differences = append(result.Differences, compareSchemas(local, remote)...)
 	result.Differences = append(result.Differences, compareTypes(local, remote)...)
 	result.Differences = append(result.Differences, compareSequences(local, remote)...)
 	result.Differences = append(result.Differences, compareRoutines(local, remote)...)
 	result.Differences = append(result.Differences, compareTables(local, remote)...)
 	result.Differences = append(result.Differences, compareViews(local, remote)...)
+	result.Differences = append(result.Differences, compareTriggers(local, remote)...)
+	result.Differences = append(result.Differences, compareOwners(local, remote, result.Differences)...)
+
+	for i := range result.Differences {
+		result.Differences[i].Source = local.SourceOf(result.Differences[i].ObjectName)
+	}
+	result.ignore(local.IgnoreDiff)
+	result.orderTypeDrops(remote)
+	result.enableUniqueWithoutIndex()
 
 	return &result
 }
@@ -82,6 +193,51 @@ func (r *ComparisonResult) HasChanges() bool {
 	return len(r.Differences) > 0
 }
 
+// Filter returns a new result containing only the differences for which keep returns true
+func (r *ComparisonResult) Filter(keep func(Difference) bool) *ComparisonResult {
+	filtered := ComparisonResult{
+		Differences: make([]Difference, 0, len(r.Differences)),
+		Ignored:     r.Ignored,
+	}
+	for _, diff := range r.Differences {
+		if keep(diff) {
+			filtered.Differences = append(filtered.Differences, diff)
+		}
+	}
+	return &filtered
+}
+
+// DifferenceOf returns the difference a statement from GenerateMigrations came
+// from, pretty printed or not, so a statement the database rejects can be traced
+// back to the change in the definitions
+func (r *ComparisonResult) DifferenceOf(statement string) (Difference, bool) {
+	statement = stripWarningComment(statement)
+	for _, diff := range r.Differences {
+		for _, stmt := range diff.Statements() {
+			if stmt.String() == statement {
+				return diff, true
+			}
+			if pretty, err := tree.Pretty(stmt); err == nil && pretty == statement {
+				return diff, true
+			}
+		}
+	}
+	return Difference{}, false
+}
+
+// stripWarningComment removes the warning comment GenerateMigrations puts
+// before a statement
+func stripWarningComment(statement string) string {
+	for strings.HasPrefix(statement, "-- WARNING: ") {
+		_, rest, ok := strings.Cut(statement, "\n")
+		if !ok {
+			return ""
+		}
+		statement = rest
+	}
+	return statement
+}
+
 // Summary returns a human-readable summary of differences
 func (r *ComparisonResult) Summary() string {
 	if !r.HasChanges() {
@@ -90,7 +246,7 @@ func (r *ComparisonResult) Summary() string {
 
 	summary := fmt.Sprintf("Found %d difference(s):\n", len(r.Differences))
 	for i, diff := range r.Differences {
-		summary += fmt.Sprintf("%d. %s\n", i+1, diff.Description)
+		summary += fmt.Sprintf("%d. %s\n", i+1, diff.DescriptionWithSource())
 	}
 	return summary
 }
@@ -117,12 +273,12 @@ func compareSchemas(local, remote *Schema) []Difference {
 			Type:        DiffSchemaAdded,
 			ObjectName:  "schema:" + name,
 			Description: fmt.Sprintf("Schema \"%s\" added", name),
-			MigrationStatements: []tree.Statement{
+			Phases: inOnePhase(
 				&tree.CreateSchema{
 					IfNotExists: true,
 					Schema:      tree.ObjectNamePrefix{SchemaName: tree.Name(name), ExplicitSchema: true},
 				},
-			},
+			),
 		})
 	}
 
@@ -131,11 +287,11 @@ func compareSchemas(local, remote *Schema) []Difference {
 			Type:        DiffSchemaRemoved,
 			ObjectName:  "schema:" + name,
 			Description: fmt.Sprintf("Schema \"%s\" removed", name),
-			MigrationStatements: []tree.Statement{
+			Phases: inOnePhase(
 				&tree.DropSchema{
 					Names: []tree.ObjectNamePrefix{{SchemaName: tree.Name(name), ExplicitSchema: true}},
 				},
-			},
+			),
 		})
 	}
 
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
@@ -449,6 +450,100 @@ func TestMigrationOrderingAddComputedColumnDependency(t *testing.T) {
 	}
 }
 
+func TestMigrationOrderingAcrossObjectKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		local    []string
+		remote   []string
+		expected []string
+	}{
+		{
+			name: "type used by a check constraint",
+			local: []string{
+				"CREATE SCHEMA app",
+				"CREATE TYPE app.status AS ENUM ('a')",
+				"CREATE TABLE a (id INT8 PRIMARY KEY, s STRING, CHECK (s::app.status = 'a'))",
+			},
+			expected: []string{
+				"CREATE SCHEMA IF NOT EXISTS app",
+				"CREATE TYPE app.status AS ENUM ('a')",
+				"CREATE TABLE a (id INT8 PRIMARY KEY, s STRING, CHECK (s::app.status = 'a'))",
+			},
+		},
+		{
+			name: "type used by a function signature",
+			local: []string{
+				"CREATE SCHEMA app",
+				"CREATE TYPE app.status AS ENUM ('a')",
+				"CREATE FUNCTION a(s app.status) RETURNS app.status LANGUAGE SQL AS $$ SELECT s $$",
+			},
+			expected: []string{
+				"CREATE SCHEMA IF NOT EXISTS app",
+				"CREATE TYPE app.status AS ENUM ('a')",
+				"CREATE FUNCTION a(s app.status)\n\tRETURNS app.status\n\tLANGUAGE SQL\n\tAS $$ SELECT s $$",
+			},
+		},
+		{
+			name: "table read by a view",
+			local: []string{
+				"CREATE VIEW a AS SELECT id FROM app.z",
+				"CREATE SCHEMA app",
+				"CREATE TABLE app.z (id INT8 PRIMARY KEY)",
+			},
+			expected: []string{
+				"CREATE SCHEMA IF NOT EXISTS app",
+				"CREATE TABLE app.z (id INT8 PRIMARY KEY)",
+				"CREATE VIEW a AS SELECT id FROM app.z",
+			},
+		},
+		{
+			name: "unrelated objects are created by kind",
+			local: []string{
+				"CREATE VIEW a AS SELECT 1",
+				"CREATE TABLE b (id INT8 PRIMARY KEY)",
+				"CREATE SEQUENCE c",
+				"CREATE TYPE d AS ENUM ('a')",
+			},
+			expected: []string{
+				"CREATE TYPE d AS ENUM ('a')",
+				"CREATE SEQUENCE c",
+				"CREATE TABLE b (id INT8 PRIMARY KEY)",
+				"CREATE VIEW a AS SELECT 1",
+			},
+		},
+		{
+			name: "unrelated objects are dropped in reverse",
+			remote: []string{
+				"CREATE TYPE a AS ENUM ('a')",
+				"CREATE SEQUENCE b",
+				"CREATE TABLE c (id INT8 PRIMARY KEY)",
+				"CREATE VIEW d AS SELECT 1",
+			},
+			expected: []string{
+				"DROP VIEW IF EXISTS d RESTRICT",
+				"DROP TABLE IF EXISTS c RESTRICT",
+				"DROP SEQUENCE IF EXISTS b RESTRICT",
+				"DROP TYPE IF EXISTS a RESTRICT",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := NewSchema(parseStatements(tt.local...)...)
+			remote := NewSchema(parseStatements(tt.remote...)...)
+
+			migrations, _, err := Compare(local, remote).GenerateMigrations(false)
+			if err != nil {
+				t.Fatalf("GenerateMigrations() error: %v", err)
+			}
+			if strings.Join(migrations, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("unexpected migration order.\nGot:\n%s\n\nWant:\n%s", strings.Join(migrations, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+}
+
 func TestMigrationOrderingDropIndexBeforeDropColumnPartialPredicate(t *testing.T) {
 	// Regression test: when multiple columns are dropped and one of them is referenced
 	// in a partial index's WHERE predicate, the DROP INDEX must run before the
@@ -1063,3 +1158,213 @@ func TestComparisonResult_Summary(t *testing.T) {
 		})
 	}
 }
+
+func TestComparisonResult_Filter(t *testing.T) {
+	differences := []Difference{
+		{Type: DiffTypeTableAdded, ObjectName: "public.users", Description: "Table 'public.users' added"},
+		{Type: DiffTypeTableRemoved, ObjectName: "public.posts", Description: "Table 'public.posts' removed", Dangerous: true},
+		{Type: DiffTypeViewAdded, ObjectName: "public.active_users", Description: "View 'public.active_users' added"},
+	}
+
+	tests := []struct {
+		name      string
+		keep      func(Difference) bool
+		wantNames []string
+	}{
+		{
+			name:      "keep all",
+			keep:      func(Difference) bool { return true },
+			wantNames: []string{"public.users", "public.posts", "public.active_users"},
+		},
+		{
+			name:      "keep none",
+			keep:      func(Difference) bool { return false },
+			wantNames: []string{},
+		},
+		{
+			name:      "drop dangerous",
+			keep:      func(d Difference) bool { return !d.Dangerous },
+			wantNames: []string{"public.users", "public.active_users"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &ComparisonResult{Differences: differences}
+			filtered := result.Filter(tt.keep)
+
+			gotNames := make([]string, 0, len(filtered.Differences))
+			for _, d := range filtered.Differences {
+				gotNames = append(gotNames, d.ObjectName)
+			}
+			if strings.Join(gotNames, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("Filter() = %v, want %v", gotNames, tt.wantNames)
+			}
+			if len(result.Differences) != len(differences) {
+				t.Errorf("Filter() modified the original result")
+			}
+		})
+	}
+}
+
+func TestComparisonResult_DifferenceOf(t *testing.T) {
+	local := createSchemaWithTypesAndTables(nil, []string{
+		"CREATE TABLE public.users (id INT8 NOT NULL, email STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC))",
+		"CREATE TABLE public.posts (id INT8 NOT NULL, CONSTRAINT posts_pkey PRIMARY KEY (id ASC))",
+	})
+	remote := createSchemaWithTypesAndTables(nil, []string{
+		"CREATE TABLE public.users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC))",
+	})
+	result := Compare(local, remote)
+
+	for _, pretty := range []bool{false, true} {
+		statements, _, err := result.GenerateMigrations(pretty)
+		if err != nil {
+			t.Fatalf("GenerateMigrations() error = %v", err)
+		}
+
+		var objects []string
+		for _, stmt := range statements {
+			diff, ok := result.DifferenceOf(stmt)
+			if !ok {
+				t.Fatalf("DifferenceOf(%q) found no difference", stmt)
+			}
+			objects = append(objects, diff.ObjectName)
+		}
+		slices.Sort(objects)
+		if strings.Join(objects, ",") != "public.posts,public.users" {
+			t.Errorf("DifferenceOf() with pretty=%v = %v, want public.posts and public.users", pretty, objects)
+		}
+	}
+
+	diff, ok := result.DifferenceOf("-- WARNING: slow\nALTER TABLE public.users ADD COLUMN email STRING NULL")
+	if !ok || diff.ObjectName != "public.users" {
+		t.Errorf("DifferenceOf() after a warning comment = %q, %v, want public.users", diff.ObjectName, ok)
+	}
+
+	if _, ok := result.DifferenceOf("DROP TABLE public.comments"); ok {
+		t.Errorf("DifferenceOf() found a difference for a statement it didn't generate")
+	}
+}
+
+func TestDifferenceTransactionStatements(t *testing.T) {
+	stmts := parseStatements("CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)", "CREATE TABLE c (id INT)")
+	a, b, c := stmts[0], stmts[1], stmts[2]
+
+	tests := []struct {
+		name   string
+		phases []Phase
+		want   []string
+	}{
+		{
+			name:   "single phase",
+			phases: inOnePhase(a, b),
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "phases split by a transaction boundary",
+			phases: []Phase{{Statements: []tree.Statement{a}}, {Statements: []tree.Statement{b}}},
+			want:   []string{"a", "COMMIT", "BEGIN", "b"},
+		},
+		{
+			name:   "leading and trailing empty phases",
+			phases: []Phase{{}, {Statements: []tree.Statement{a}}, {}},
+			want:   []string{"COMMIT", "BEGIN", "a", "COMMIT", "BEGIN"},
+		},
+		{
+			name: "statements outside of a transaction",
+			phases: []Phase{
+				{},
+				{Statements: []tree.Statement{a}, NoTransaction: true},
+				{Statements: []tree.Statement{b}, NoTransaction: true},
+				{Statements: []tree.Statement{c}},
+			},
+			want: []string{"COMMIT", "a", "BEGIN", "COMMIT", "b", "BEGIN", "c"},
+		},
+	}
+
+	names := map[tree.Statement]string{a: "a", b: "b", c: "c"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, stmt := range (Difference{Phases: tt.phases}).TransactionStatements() {
+				switch stmt.(type) {
+				case *tree.CommitTransaction:
+					got = append(got, "COMMIT")
+				case *tree.BeginTransaction:
+					got = append(got, "BEGIN")
+				default:
+					got = append(got, names[stmt])
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("TransactionStatements() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckConstraintModifiedByName(t *testing.T) {
+	tests := []struct {
+		name         string
+		remoteTables []string
+		localTables  []string
+		wantOrder    []string
+	}{
+		{
+			name: "expression edited",
+			remoteTables: []string{
+				"CREATE TABLE items (id INT NOT NULL, price INT, CONSTRAINT price_positive CHECK (price > 0), CONSTRAINT items_pkey PRIMARY KEY (id))",
+			},
+			localTables: []string{
+				"CREATE TABLE items (id INT NOT NULL, price INT, CONSTRAINT price_positive CHECK (price >= 0), CONSTRAINT items_pkey PRIMARY KEY (id))",
+			},
+			wantOrder: []string{"DROP CONSTRAINT IF EXISTS price_positive", "COMMIT", "BEGIN", "ADD CONSTRAINT price_positive"},
+		},
+		{
+			name: "expression moved from a dropped column to a new one",
+			remoteTables: []string{
+				"CREATE TABLE items (id INT NOT NULL, old_price INT, CONSTRAINT price_positive CHECK (old_price > 0), CONSTRAINT items_pkey PRIMARY KEY (id))",
+			},
+			localTables: []string{
+				"CREATE TABLE items (id INT NOT NULL, price DECIMAL, CONSTRAINT price_positive CHECK (price > 0), CONSTRAINT items_pkey PRIMARY KEY (id))",
+			},
+			wantOrder: []string{"ADD COLUMN price", "DROP CONSTRAINT IF EXISTS price_positive", "ADD CONSTRAINT price_positive", "DROP COLUMN old_price"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// Ordering between differences depends on map iteration order, so
+			// check it more than once
+			for range 20 {
+				diffResult := Compare(createSchemaWithTypesAndTables(nil, tt.localTables), createSchemaWithTypesAndTables(nil, tt.remoteTables))
+
+				checkDiffs := 0
+				for _, diff := range diffResult.Differences {
+					if strings.Contains(diff.Description, "price_positive") {
+						checkDiffs++
+					}
+				}
+				if checkDiffs != 1 {
+					t.Fatalf("expected a single difference for the check constraint, got %d", checkDiffs)
+				}
+
+				migrations, _, err := diffResult.GenerateMigrations(false)
+				if err != nil {
+					t.Fatalf("GenerateMigrations() error: %v", err)
+				}
+				allDDL := strings.Join(migrations, "\n")
+				pos := 0
+				for _, want := range tt.wantOrder {
+					idx := strings.Index(allDDL[pos:], want)
+					if idx == -1 {
+						t.Fatalf("expected %q after position %d.\nGot:\n%s", want, pos, allDDL)
+					}
+					pos += idx + len(want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// tableDefinition is a CREATE TABLE statement along with its text in a definition
+// file, so -- scurry: directives in comments can be matched to the table and columns.
+type tableDefinition struct {
+	ast       *tree.CreateTable
+	schema    string
+	qualified string // "schema.table"
+	preceding string // Text between the previous statement and this one
+	body      string // The statement itself, including comments inside it
+}
+
+// definitionStatement is a statement in a definition file along with its text
+type definitionStatement struct {
+	ast       tree.Statement
+	preceding string // Text between the previous statement and this one
+	body      string // The statement itself, including comments inside it
+}
+
+// parseDefinitionStatements returns every statement in a definition file with its text.
+// Files that fail to parse return nothing; parsing errors are reported by schema loading.
+func parseDefinitionStatements(sql string) []definitionStatement {
+	statements, err := parser.Parse(sql)
+	if err != nil {
+		return nil
+	}
+
+	var defs []definitionStatement
+	offset := 0
+	for _, stmt := range statements {
+		idx := strings.Index(sql[offset:], stmt.SQL)
+		if idx == -1 {
+			continue
+		}
+		preceding := sql[offset : offset+idx]
+		offset += idx + len(stmt.SQL)
+		defs = append(defs, definitionStatement{
+			ast:       stmt.AST,
+			preceding: preceding,
+			body:      stmt.SQL,
+		})
+	}
+	return defs
+}
+
+// parseTableDefinitions returns every CREATE TABLE in a definition file with its text.
+func parseTableDefinitions(sql string) []tableDefinition {
+	var defs []tableDefinition
+	for _, stmt := range parseDefinitionStatements(sql) {
+		ct, ok := stmt.ast.(*tree.CreateTable)
+		if !ok {
+			continue
+		}
+		schemaName, tableName := getTableName(ct.Table)
+		defs = append(defs, tableDefinition{
+			ast:       ct,
+			schema:    schemaName,
+			qualified: schemaName + "." + tableName,
+			preceding: stmt.preceding,
+			body:      stmt.body,
+		})
+	}
+	return defs
+}
+
+// columnDirectives returns the values of directives with the given prefix that end
+// a column's line, keyed by column name:
+//
+//	email TEXT -- scurry:renamed-from=mail
+func (d tableDefinition) columnDirectives(prefix string) map[string]string {
+	columns := make(map[string]bool)
+	for _, def := range d.ast.Defs {
+		if col, ok := def.(*tree.ColumnTableDef); ok {
+			columns[col.Name.Normalize()] = true
+		}
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(d.body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		idx := strings.Index(line, prefix)
+		if idx == -1 {
+			continue
+		}
+		colName := leadingIdentifier(line[:strings.Index(line, "--")])
+		if !columns[colName] {
+			continue
+		}
+		if value := strings.TrimSpace(line[idx+len(prefix):]); value != "" {
+			values[colName] = value
+		}
+	}
+	return values
+}
+
+// indexDirectives returns the values of directives with the given prefix that
+// end an index or constraint's line, keyed by its name:
+//
+//	UNIQUE INDEX users_email_key (email) -- scurry:classify=async
+func (d tableDefinition) indexDirectives(prefix string) map[string]string {
+	names := make(map[string]bool)
+	for _, def := range d.ast.Defs {
+		switch c := def.(type) {
+		case *tree.IndexTableDef:
+			names[c.Name.Normalize()] = true
+		case *tree.UniqueConstraintTableDef:
+			names[c.Name.Normalize()] = true
+		case *tree.ForeignKeyConstraintTableDef:
+			names[c.Name.Normalize()] = true
+		case *tree.CheckConstraintTableDef:
+			names[c.Name.Normalize()] = true
+		}
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(d.body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		idx := strings.Index(line, prefix)
+		if idx == -1 {
+			continue
+		}
+		name := indexIdentifier(line[:strings.Index(line, "--")])
+		if name == "" || !names[name] {
+			continue
+		}
+		if value := strings.TrimSpace(line[idx+len(prefix):]); value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// indexIdentifier returns the normalized name of the index or constraint
+// defined on a line, e.g. `UNIQUE INDEX users_email_key (email),` ->
+// "users_email_key", or "" if the line doesn't start with one
+func indexIdentifier(line string) string {
+	fields := strings.Fields(line)
+	keywords := 0
+	for keywords < len(fields) {
+		switch strings.ToLower(fields[keywords]) {
+		case "unique", "inverted", "vector", "index", "constraint":
+			keywords++
+			continue
+		}
+		break
+	}
+	if keywords == 0 || keywords == len(fields) {
+		return ""
+	}
+	return leadingIdentifier(strings.Join(fields[keywords:], " "))
+}
+
+// leadingIdentifier returns the normalized first identifier on a line of a
+// column definition, e.g. `  "Email" TEXT,` -> "email", `email TEXT` -> "email"
+func leadingIdentifier(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, `"`) {
+		if end := strings.Index(line[1:], `"`); end != -1 {
+			return tree.Name(line[1 : end+1]).Normalize()
+		}
+		return ""
+	}
+	if end := strings.IndexAny(line, " \t,("); end != -1 {
+		line = line[:end]
+	}
+	return strings.ToLower(line)
+}
@@ -0,0 +1,231 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree/treecmp"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+const remapValuePrefix = "-- scurry:remap-value="
+
+// EnumRemaps records replacements for dropped enum values declared with
+// -- scurry:remap-value directives, keyed by "schema.type" and then the
+// dropped value.
+type EnumRemaps map[string]map[string]string
+
+func (r EnumRemaps) merge(other EnumRemaps) {
+	for typeName, values := range other {
+		if r[typeName] == nil {
+			r[typeName] = make(map[string]string)
+		}
+		for oldVal, newVal := range values {
+			r[typeName][oldVal] = newVal
+		}
+	}
+}
+
+// parseRemapDirectives finds -- scurry:remap-value directives in the comments
+// directly above a CREATE TYPE. Rows using the dropped value are updated to the
+// replacement before the value is dropped:
+//
+//	-- scurry:remap-value=pending:active
+//	CREATE TYPE status AS ENUM ('active', 'inactive');
+func parseRemapDirectives(sql string) EnumRemaps {
+	remaps := make(EnumRemaps)
+	if !strings.Contains(sql, remapValuePrefix) {
+		return remaps
+	}
+
+	for _, stmt := range parseDefinitionStatements(sql) {
+		ct, ok := stmt.ast.(*tree.CreateType)
+		if !ok || ct.Variety != tree.Enum {
+			continue
+		}
+		schemaName, typeName := getObjectName(ct.TypeName)
+		for oldVal, newVal := range enumValueDirectives(stmt.preceding, remapValuePrefix) {
+			qualified := schemaName + "." + typeName
+			if remaps[qualified] == nil {
+				remaps[qualified] = make(map[string]string)
+			}
+			remaps[qualified][oldVal] = newVal
+		}
+	}
+	return remaps
+}
+
+// validEnumRemaps returns the remaps of values being dropped to values the
+// enum still has, leaving out any that couldn't be applied
+func validEnumRemaps(remaps map[string]string, removed, localValues []string) map[string]string {
+	valid := make(map[string]string)
+	for _, value := range removed {
+		if replacement, ok := remaps[value]; ok && slices.Contains(localValues, replacement) {
+			valid[value] = replacement
+		}
+	}
+	return valid
+}
+
+// enumColumn is a table column whose type is an enum, or an array of one
+type enumColumn struct {
+	table     tree.TableName
+	qualified string // "schema.table.column"
+	column    tree.Name
+	array     bool
+}
+
+// enumColumns returns the columns of the schema's tables that use the named
+// enum, sorted by name
+func enumColumns(s *Schema, typeName string) []enumColumn {
+	var columns []enumColumn
+	for _, table := range s.Tables {
+		for _, def := range table.Ast.Defs {
+			col, ok := def.(*tree.ColumnTableDef)
+			if !ok || !columnUsesType(col, typeName) {
+				continue
+			}
+			_, array := col.Type.(*tree.ArrayTypeReference)
+			columns = append(columns, enumColumn{
+				table:     table.Ast.Table,
+				qualified: table.ResolvedName() + "." + col.Name.Normalize(),
+				column:    col.Name,
+				array:     array,
+			})
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].qualified < columns[j].qualified })
+	return columns
+}
+
+// remapStatement updates the rows of the column using oldVal to use newVal
+// instead. Array columns have the value replaced in each element.
+func (c enumColumn) remapStatement(oldVal, newVal string) tree.Statement {
+	colRef := &tree.UnresolvedName{NumParts: 1, Parts: tree.NameParts{string(c.column)}}
+	var set tree.Expr = tree.NewStrVal(newVal)
+	where := &tree.ComparisonExpr{
+		Operator: treecmp.MakeComparisonOperator(treecmp.EQ),
+		Left:     colRef,
+		Right:    tree.NewStrVal(oldVal),
+	}
+	if c.array {
+		set = &tree.FuncExpr{
+			Func:  tree.ResolvableFunctionReference{FunctionReference: tree.NewUnresolvedName("array_replace")},
+			Exprs: tree.Exprs{colRef, tree.NewStrVal(oldVal), tree.NewStrVal(newVal)},
+		}
+		where = &tree.ComparisonExpr{
+			Operator:    treecmp.MakeComparisonOperator(treecmp.Any),
+			SubOperator: treecmp.MakeComparisonOperator(treecmp.EQ),
+			Left:        tree.NewStrVal(oldVal),
+			Right:       &tree.ParenExpr{Expr: colRef},
+		}
+	}
+	table := c.table
+	return &tree.Update{
+		Table:     &tree.AliasedTableExpr{Expr: &table},
+		Exprs:     tree.UpdateExprs{{Names: tree.NameList{c.column}, Expr: set}},
+		Where:     tree.NewWhere(tree.AstWhere, where),
+		Returning: tree.AbsentReturningClause,
+	}
+}
+
+// droppedEnumValuesWarning explains what happens to the rows using the values
+// dropped from an enum, or returns "" if no column uses it
+func droppedEnumValuesWarning(typeName string, removed []string, remaps map[string]string, columns []enumColumn) string {
+	if len(removed) == 0 || len(columns) == 0 {
+		return ""
+	}
+	columnNames := make([]string, len(columns))
+	for i, col := range columns {
+		columnNames[i] = col.qualified
+	}
+
+	var unmapped, remapped []string
+	for _, value := range removed {
+		if replacement, ok := remaps[value]; ok {
+			remapped = append(remapped, fmt.Sprintf("'%s' to '%s'", value, replacement))
+		} else {
+			unmapped = append(unmapped, fmt.Sprintf("'%s'", value))
+		}
+	}
+
+	var parts []string
+	if len(unmapped) > 0 {
+		parts = append(parts, fmt.Sprintf(
+			"Dropping %s from type '%s' fails while any row of %s still uses it. Add -- scurry:remap-value=<old>:<new> above the type to update those rows first.",
+			strings.Join(unmapped, ", "), typeName, strings.Join(columnNames, ", "),
+		))
+	}
+	if len(remapped) > 0 {
+		parts = append(parts, fmt.Sprintf("Rows of %s are updated from %s before the values are dropped.", strings.Join(columnNames, ", "), strings.Join(remapped, ", ")))
+	}
+	return strings.Join(parts, " ")
+}
+
+// CheckDroppedEnumValues counts the rows of the database that still use each
+// enum value the differences drop without a remap, and adds them to the warning
+// of the difference dropping it, so the drop doesn't fail partway through a push.
+func CheckDroppedEnumValues(ctx context.Context, client *db.Client, local, remote *Schema, result *ComparisonResult) error {
+	localTypes := make(map[string]ObjectSchema[*tree.CreateType])
+	for _, t := range local.Types {
+		localTypes[t.ResolvedName()] = t
+	}
+
+	for i := range result.Differences {
+		diff := &result.Differences[i]
+		if diff.Type != DiffTypeTypeModified {
+			continue
+		}
+		localType, ok := localTypes[diff.ObjectName]
+		if !ok {
+			continue
+		}
+		remoteType := findType(remote, diff.ObjectName)
+		if remoteType == nil {
+			continue
+		}
+		localValues := getEnumValues(localType.Ast)
+		remoteValues := getEnumValues(remoteType.Ast)
+		if localValues == nil || remoteValues == nil {
+			continue
+		}
+
+		_, removed, _ := enumValueChanges(localValues, remoteValues, local.Renames.Values[diff.ObjectName])
+		remaps := validEnumRemaps(local.Remaps[diff.ObjectName], removed, localValues)
+		var inUse []string
+		for _, value := range removed {
+			if _, ok := remaps[value]; ok {
+				continue
+			}
+			for _, col := range enumColumns(remote, diff.ObjectName) {
+				schemaName, tableName := getTableName(col.table)
+				rows, err := client.CountEnumValueRows(ctx, schemaName, tableName, col.column.Normalize(), value, col.array)
+				if err != nil {
+					return err
+				}
+				if rows > 0 {
+					inUse = append(inUse, fmt.Sprintf("%s has %d row(s) using '%s'", col.qualified, rows, value))
+				}
+			}
+		}
+		if len(inUse) > 0 {
+			diff.Dangerous = true
+			diff.WarningMessage = strings.TrimSpace(diff.WarningMessage + " " + strings.Join(inUse, ", ") + ".")
+		}
+	}
+	return nil
+}
+
+func findType(s *Schema, name string) *ObjectSchema[*tree.CreateType] {
+	for i := range s.Types {
+		if s.Types[i].ResolvedName() == name {
+			return &s.Types[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package schema
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestParseRemapDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected EnumRemaps
+	}{
+		{
+			name:     "no directives",
+			sql:      "CREATE TYPE status AS ENUM ('active');",
+			expected: EnumRemaps{},
+		},
+		{
+			name:     "remap above a type",
+			sql:      "-- scurry:remap-value=pending:active\nCREATE TYPE app.status AS ENUM ('active', 'inactive');",
+			expected: EnumRemaps{"app.status": {"pending": "active"}},
+		},
+		{
+			name:     "directive above a table is ignored",
+			sql:      "-- scurry:remap-value=pending:active\nCREATE TABLE users (id INT PRIMARY KEY);",
+			expected: EnumRemaps{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseRemapDirectives(tt.sql))
+		})
+	}
+}
+
+func TestCompareEnumTypesDroppedValues(t *testing.T) {
+	const usersTable = "CREATE TABLE public.users (id INT8 NOT NULL, status public.status, history public.status[], CONSTRAINT users_pkey PRIMARY KEY (id))"
+
+	tests := []struct {
+		name        string
+		localType   string
+		remoteType  string
+		tables      []string
+		remaps      map[string]string
+		wantPhases  []string
+		wantWarning []string
+	}{
+		{
+			name:       "unused type drops without a warning",
+			localType:  "CREATE TYPE public.status AS ENUM ('active')",
+			remoteType: "CREATE TYPE public.status AS ENUM ('active', 'pending')",
+			wantPhases: []string{"ALTER TYPE public.status DROP VALUE 'pending'"},
+		},
+		{
+			name:       "columns using the type are listed",
+			localType:  "CREATE TYPE public.status AS ENUM ('active')",
+			remoteType: "CREATE TYPE public.status AS ENUM ('active', 'pending')",
+			tables:     []string{usersTable},
+			wantPhases: []string{"ALTER TYPE public.status DROP VALUE 'pending'"},
+			wantWarning: []string{
+				"Dropping 'pending' from type 'public.status'",
+				"public.users.history, public.users.status",
+				"scurry:remap-value",
+			},
+		},
+		{
+			name:       "remapped rows are updated before the drop",
+			localType:  "CREATE TYPE public.status AS ENUM ('active')",
+			remoteType: "CREATE TYPE public.status AS ENUM ('active', 'pending')",
+			tables:     []string{usersTable},
+			remaps:     map[string]string{"pending": "active"},
+			wantPhases: []string{
+				"UPDATE public.users SET history = array_replace(history, 'pending', 'active') WHERE 'pending' = ANY (history); UPDATE public.users SET status = 'active' WHERE status = 'pending'",
+				"ALTER TYPE public.status DROP VALUE 'pending'",
+			},
+			wantWarning: []string{"updated from 'pending' to 'active'"},
+		},
+		{
+			name:       "remap to an added value waits for it to commit",
+			localType:  "CREATE TYPE public.status AS ENUM ('active', 'waiting')",
+			remoteType: "CREATE TYPE public.status AS ENUM ('active', 'pending')",
+			tables:     []string{usersTable},
+			remaps:     map[string]string{"pending": "waiting"},
+			wantPhases: []string{
+				"ALTER TYPE public.status ADD VALUE IF NOT EXISTS 'waiting'",
+				"UPDATE public.users SET history = array_replace(history, 'pending', 'waiting') WHERE 'pending' = ANY (history); UPDATE public.users SET status = 'waiting' WHERE status = 'pending'",
+				"ALTER TYPE public.status DROP VALUE 'pending'",
+			},
+			wantWarning: []string{"updated from 'pending' to 'waiting'"},
+		},
+		{
+			name:        "remap to a value the type doesn't have is ignored",
+			localType:   "CREATE TYPE public.status AS ENUM ('active')",
+			remoteType:  "CREATE TYPE public.status AS ENUM ('active', 'pending')",
+			tables:      []string{usersTable},
+			remaps:      map[string]string{"pending": "missing"},
+			wantPhases:  []string{"ALTER TYPE public.status DROP VALUE 'pending'"},
+			wantWarning: []string{"Dropping 'pending'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localSchema := createSchemaWithTypesAndTables([]string{tt.localType}, tt.tables)
+			remoteSchema := createSchemaWithTypesAndTables([]string{tt.remoteType}, tt.tables)
+			if tt.remaps != nil {
+				localSchema.Remaps = EnumRemaps{"public.status": tt.remaps}
+			}
+
+			diffs := compareTypes(localSchema, remoteSchema)
+			require.Len(t, diffs, 1)
+			diff := diffs[0]
+			assert.True(t, diff.Dangerous)
+
+			var gotPhases []string
+			for _, phase := range diff.Phases {
+				if len(phase.Statements) > 0 {
+					gotPhases = append(gotPhases, strings.Join(statementsToStringsTypes(phase.Statements), "; "))
+				}
+			}
+			assert.Equal(t, tt.wantPhases, gotPhases)
+
+			_, _, err := (&ComparisonResult{Differences: diffs}).GenerateMigrations(false)
+			assert.NoError(t, err)
+
+			if len(tt.wantWarning) == 0 {
+				assert.Empty(t, diff.WarningMessage)
+			}
+			for _, want := range tt.wantWarning {
+				assert.Contains(t, diff.WarningMessage, want)
+			}
+		})
+	}
+}
+
+func TestCheckDroppedEnumValues(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx,
+		"CREATE TYPE public.status AS ENUM ('active', 'pending', 'closed')",
+		"CREATE TABLE public.users (id INT8 PRIMARY KEY, status public.status, history public.status[])",
+		"INSERT INTO public.users VALUES (1, 'pending', ARRAY['active']), (2, 'active', ARRAY['closed', 'active'])",
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	remoteSchema, err := LoadFromDatabase(ctx, client)
+	require.NoError(t, err)
+	localSchema := createSchemaWithTypesAndTables([]string{"CREATE TYPE public.status AS ENUM ('active')"}, nil)
+	localSchema.Tables = remoteSchema.Tables
+
+	result := &ComparisonResult{Differences: compareTypes(localSchema, remoteSchema)}
+	require.NoError(t, CheckDroppedEnumValues(ctx, client, localSchema, remoteSchema, result))
+	require.Len(t, result.Differences, 1)
+
+	warning := result.Differences[0].WarningMessage
+	assert.Contains(t, warning, "public.users.status has 1 row(s) using 'pending'")
+	assert.Contains(t, warning, "public.users.history has 1 row(s) using 'closed'")
+	assert.NotContains(t, warning, "public.users.history has 1 row(s) using 'pending'")
+}
@@ -17,6 +17,37 @@ func getExprDeps(expr tree.Expr) set.Set[string] {
 	return v.deps
 }
 
+// getExprTypeDeps returns the user defined types an expression casts to. Unlike
+// getExprDeps it ignores column and function names, for expressions like view
+// queries where those usually refer to columns of other objects.
+func getExprTypeDeps(expr tree.Expr) set.Set[string] {
+	v := &typeVisitor{deps: set.New[string]()}
+	tree.WalkExpr(v, expr)
+	return v.deps
+}
+
+type typeVisitor struct {
+	deps set.Set[string]
+}
+
+func (v *typeVisitor) VisitPre(expr tree.Expr) (bool, tree.Expr) {
+	switch e := expr.(type) {
+	case *tree.CastExpr:
+		if name, ok := getResolvableTypeReferenceDepName(e.Type); ok {
+			v.deps.Add(name)
+		}
+	case *tree.AnnotateTypeExpr:
+		if name, ok := getResolvableTypeReferenceDepName(e.Type); ok {
+			v.deps.Add(name)
+		}
+	}
+	return true, expr
+}
+
+func (v *typeVisitor) VisitPost(expr tree.Expr) tree.Expr {
+	return expr
+}
+
 // Attempts to find any names that were automatically prefixed with "public." that might have actually been column names,
 // and adds a "tableName." prefix to them instead.
 func getExprColumnDeps(schemaTableName, tableName string, expr tree.Expr) set.Set[string] {
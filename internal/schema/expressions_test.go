@@ -255,6 +255,13 @@ func TestGetExprColumnDeps(t *testing.T) {
 			expr:            "upper(name)",
 			wantDeps:        []string{"public.upper", "public.users.upper", "public.name", "public.users.name"},
 		},
+		{
+			name:            "columns of a table outside of public keep its schema",
+			schemaTableName: "app",
+			tableName:       "orders",
+			expr:            "status = 'open':::app.status",
+			wantDeps:        []string{"public.status", "app.orders.status", "app.status", "app.status.open"},
+		},
 		{
 			name:            "schema qualified names not duplicated",
 			schemaTableName: "public",
@@ -1,6 +1,11 @@
 package schema
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
 )
 
@@ -9,3 +14,134 @@ func formatNode(node tree.NodeFormatter) string {
 	node.Format(fmtCtx)
 	return fmtCtx.CloseAndGetString()
 }
+
+// FormatDefinitionFile rewrites the statements in a definition file with the
+// CockroachDB pretty-printer. Comments between statements are kept above the
+// statement that follows them, and statements with comments inside them (such
+// as -- scurry: column directives) are kept as written so nothing is lost.
+func FormatDefinitionFile(sql string) (string, error) {
+	statements, err := parser.Parse(sql)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse SQL: %w", err)
+	}
+
+	// Each statement is written with its leading comments, separated by a blank line
+	var chunks []string
+	offset := 0
+	for _, stmt := range statements {
+		idx := strings.Index(sql[offset:], stmt.SQL)
+		if idx == -1 {
+			return "", fmt.Errorf("failed to locate statement: %s", stmt.SQL)
+		}
+		comments := commentsBetween(sql[offset : offset+idx])
+		offset += idx + len(stmt.SQL)
+
+		formatted := stmt.SQL
+		if !strings.Contains(stmt.SQL, "--") && !strings.Contains(stmt.SQL, "/*") {
+			if formatted, err = formatStatement(stmt.AST); err != nil {
+				return "", err
+			}
+		}
+		if comments != "" {
+			formatted = comments + "\n" + formatted
+		}
+		chunks = append(chunks, formatted+";")
+	}
+	if comments := commentsBetween(sql[offset:]); comments != "" {
+		chunks = append(chunks, comments)
+	}
+	if len(chunks) == 0 {
+		return "", nil
+	}
+
+	return strings.Join(chunks, "\n\n") + "\n", nil
+}
+
+// commentsBetween returns the comments in the text between two statements
+func commentsBetween(between string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(between), ";"))
+}
+
+// formatStatement pretty-prints a statement. Tables are written with one column
+// or constraint per line, and constraints in a stable order.
+func formatStatement(stmt tree.Statement) (string, error) {
+	table, ok := stmt.(*tree.CreateTable)
+	if !ok || len(table.Defs) == 0 || table.As() {
+		return tree.Pretty(stmt)
+	}
+
+	// Format the table without its definitions, then put them between the parentheses
+	withoutDefs := *table
+	withoutDefs.Defs = nil
+	shell, err := tree.Pretty(&withoutDefs)
+	if err != nil {
+		return "", err
+	}
+	open := strings.Index(shell, "()")
+	if open == -1 {
+		return tree.Pretty(stmt)
+	}
+
+	defs := make([]string, 0, len(table.Defs))
+	for _, def := range sortTableDefs(table.Defs) {
+		s, err := tree.Pretty(def)
+		if err != nil {
+			return "", err
+		}
+		defs = append(defs, "\t"+strings.ReplaceAll(s, "\n", "\n\t"))
+	}
+
+	return shell[:open] + "(\n" + strings.Join(defs, ",\n") + "\n)" + shell[open+2:], nil
+}
+
+// sortTableDefs orders a table's definitions as columns (in their original order),
+// the primary key, foreign keys, indexes and unique constraints, families (in their
+// original order), then checks. Constraints of the same kind are sorted by name.
+func sortTableDefs(defs tree.TableDefs) tree.TableDefs {
+	rank := func(def tree.TableDef) int {
+		switch d := def.(type) {
+		case *tree.ColumnTableDef:
+			return 0
+		case *tree.UniqueConstraintTableDef:
+			if d.PrimaryKey {
+				return 1
+			}
+			return 3
+		case *tree.ForeignKeyConstraintTableDef:
+			return 2
+		case *tree.IndexTableDef:
+			return 3
+		case *tree.FamilyTableDef:
+			return 4
+		case *tree.CheckConstraintTableDef:
+			return 5
+		}
+		return 6
+	}
+	name := func(def tree.TableDef) string {
+		switch d := def.(type) {
+		case *tree.ColumnTableDef, *tree.FamilyTableDef:
+			// Order is significant
+			return ""
+		case *tree.UniqueConstraintTableDef:
+			return d.Name.Normalize()
+		case *tree.IndexTableDef:
+			return d.Name.Normalize()
+		case *tree.ForeignKeyConstraintTableDef:
+			return d.Name.Normalize()
+		case *tree.CheckConstraintTableDef:
+			return d.Name.Normalize()
+		}
+		return ""
+	}
+
+	sorted := make(tree.TableDefs, len(defs))
+	copy(sorted, defs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ri, rj := rank(sorted[i]), rank(sorted[j]); ri != rj {
+			return ri < rj
+		}
+		return name(sorted[i]) < name(sorted[j])
+	})
+	return sorted
+}
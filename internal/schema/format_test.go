@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatDefinitionFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name:     "empty file",
+			sql:      "\n",
+			expected: "",
+		},
+		{
+			name: "one column per line with constraints ordered",
+			sql:  "create table users (id int primary key, email text not null, check (email <> ''), index users_b_idx (email), index users_a_idx (id, email), constraint users_org_fk foreign key (id) references orgs (id))",
+			expected: "CREATE TABLE users (\n" +
+				"\tid INT8 PRIMARY KEY,\n" +
+				"\temail STRING NOT NULL,\n" +
+				"\tCONSTRAINT users_org_fk\n" +
+				"\t\tFOREIGN KEY (id) REFERENCES orgs (id),\n" +
+				"\tINDEX users_a_idx (id, email),\n" +
+				"\tINDEX users_b_idx (email),\n" +
+				"\tCHECK (email != '')\n" +
+				");\n",
+		},
+		{
+			name: "primary key constraint comes after columns",
+			sql:  "CREATE TABLE t (a INT, CONSTRAINT t_pkey PRIMARY KEY (a), b INT) WITH (ttl_expire_after = '3 days')",
+			expected: "CREATE TABLE t (\n" +
+				"\ta INT8,\n" +
+				"\tb INT8,\n" +
+				"\tCONSTRAINT t_pkey PRIMARY KEY (a)\n" +
+				") WITH ('ttl_expire_after' = '3 days');\n",
+		},
+		{
+			name:     "comments between statements are kept",
+			sql:      "-- scurry:allow-destructive=old\n\n-- the users\ncreate type status as enum ('a');\n\n\n-- trailing\n",
+			expected: "-- scurry:allow-destructive=old\n\n-- the users\nCREATE TYPE status AS ENUM ('a');\n\n-- trailing\n",
+		},
+		{
+			name:     "statements with comments inside are left as written",
+			sql:      "create table t (\n  id int primary key,\n  email text -- scurry:renamed-from=mail\n);\ncreate type s as enum ('a')",
+			expected: "create table t (\n  id int primary key,\n  email text -- scurry:renamed-from=mail\n);\n\nCREATE TYPE s AS ENUM ('a');\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := FormatDefinitionFile(tt.sql)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, formatted)
+
+			// Formatting is stable
+			again, err := FormatDefinitionFile(formatted)
+			require.NoError(t, err)
+			assert.Equal(t, formatted, again)
+		})
+	}
+}
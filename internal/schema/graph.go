@@ -0,0 +1,192 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// GraphNode is an object in the schema
+type GraphNode struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// GraphEdge says that From depends on To, so To has to be created first
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// DependencyGraph is the graph of dependencies between the objects in a schema
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// BuildDependencyGraph returns the dependencies between the objects in the schema,
+// using the same names migrations are ordered by. Edges between tables are foreign
+// keys, every other edge is a reference. Dependencies on objects that aren't in
+// the schema are left out.
+func BuildDependencyGraph(s *Schema) *DependencyGraph {
+	type object struct {
+		node GraphNode
+		stmt tree.Statement
+	}
+	var objects []object
+	for _, sc := range s.Schemas {
+		objects = append(objects, object{GraphNode{Name: sc.Name, Kind: "schema"}, sc.Ast})
+	}
+	for _, t := range s.Types {
+		objects = append(objects, object{GraphNode{Name: t.ResolvedName(), Kind: "type"}, t.Ast})
+	}
+	for _, seq := range s.Sequences {
+		objects = append(objects, object{GraphNode{Name: seq.ResolvedName(), Kind: "sequence"}, seq.Ast})
+	}
+	for _, t := range s.Tables {
+		objects = append(objects, object{GraphNode{Name: t.ResolvedName(), Kind: "table"}, t.Ast})
+	}
+	for _, v := range s.Views {
+		objects = append(objects, object{GraphNode{Name: v.ResolvedName(), Kind: "view"}, v.Ast})
+	}
+	for _, r := range s.Routines {
+		objects = append(objects, object{GraphNode{Name: r.ResolvedName(), Kind: "routine"}, r.Ast})
+	}
+	for _, trigger := range s.Triggers {
+		objects = append(objects, object{GraphNode{Name: trigger.ResolvedName(), Kind: "trigger"}, trigger.Ast})
+	}
+
+	providers := make(map[string]GraphNode)
+	for _, obj := range objects {
+		for name := range GetProvidedNames(obj.stmt, false).Values() {
+			providers[name] = obj.node
+		}
+	}
+
+	graph := &DependencyGraph{}
+	seen := make(map[GraphEdge]bool)
+	for _, obj := range objects {
+		graph.Nodes = append(graph.Nodes, obj.node)
+		for name := range GetDependencyNames(obj.stmt, false).Values() {
+			target, ok := providers[name]
+			if !ok || target.Name == obj.node.Name {
+				continue
+			}
+			kind := "reference"
+			if obj.node.Kind == "table" && target.Kind == "table" {
+				kind = "foreign_key"
+			}
+			edge := GraphEdge{From: obj.node.Name, To: target.Name, Kind: kind}
+			if !seen[edge] {
+				seen[edge] = true
+				graph.Edges = append(graph.Edges, edge)
+			}
+		}
+	}
+
+	slices.SortFunc(graph.Nodes, func(a, b GraphNode) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(graph.Edges, func(a, b GraphEdge) int {
+		if c := strings.Compare(a.From, b.From); c != 0 {
+			return c
+		}
+		return strings.Compare(a.To, b.To)
+	})
+	return graph
+}
+
+// Cycles returns the groups of objects that depend on each other, which can't be
+// created in any order. Each cycle is sorted by name.
+func (g *DependencyGraph) Cycles() [][]string {
+	edges := make(map[string][]string)
+	for _, edge := range g.Edges {
+		edges[edge.From] = append(edges[edge.From], edge.To)
+	}
+
+	// Tarjan's strongly connected components
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var connect func(name string)
+	connect = func(name string) {
+		index[name] = len(index)
+		lowLink[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, next := range edges[name] {
+			if _, visited := index[next]; !visited {
+				connect(next)
+				lowLink[name] = min(lowLink[name], lowLink[next])
+			} else if onStack[next] {
+				lowLink[name] = min(lowLink[name], index[next])
+			}
+		}
+
+		if lowLink[name] != index[name] {
+			return
+		}
+		var component []string
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == name {
+				break
+			}
+		}
+		if len(component) > 1 {
+			slices.Sort(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, node := range g.Nodes {
+		if _, visited := index[node.Name]; !visited {
+			connect(node.Name)
+		}
+	}
+
+	slices.SortFunc(cycles, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	return cycles
+}
+
+// DOT returns the graph in Graphviz DOT format
+func (g *DependencyGraph) DOT() string {
+	shapes := map[string]string{
+		"schema":   "folder",
+		"type":     "hexagon",
+		"sequence": "cds",
+		"table":    "box",
+		"view":     "ellipse",
+		"routine":  "component",
+		"trigger":  "diamond",
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph schema {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&sb, "\t%s [shape=%s];\n", strconv.Quote(node.Name), shapes[node.Kind])
+	}
+	for _, edge := range g.Edges {
+		if edge.Kind == "foreign_key" {
+			fmt.Fprintf(&sb, "\t%s -> %s [label=\"fk\"];\n", strconv.Quote(edge.From), strconv.Quote(edge.To))
+		} else {
+			fmt.Fprintf(&sb, "\t%s -> %s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To))
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
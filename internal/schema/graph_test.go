@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDependencyGraph(t *testing.T) {
+	s := NewSchema(parseStatements(
+		"CREATE SCHEMA app",
+		"CREATE TYPE app.status AS ENUM ('active', 'inactive')",
+		"CREATE SEQUENCE app.user_ids",
+		"CREATE TABLE app.users (id INT8 PRIMARY KEY DEFAULT nextval('app.user_ids'), status app.status)",
+		"CREATE TABLE app.orders (id INT8 PRIMARY KEY, user_id INT8 REFERENCES app.users (id))",
+		"CREATE VIEW app.active_orders AS WITH active AS (SELECT id FROM app.users WHERE status = 'active') SELECT o.id FROM app.orders o JOIN active ON o.user_id = active.id",
+		"CREATE FUNCTION app.touch() RETURNS TRIGGER LANGUAGE PLpgSQL AS $$ BEGIN RETURN NEW; END $$",
+		"CREATE TRIGGER orders_touch BEFORE UPDATE ON app.orders FOR EACH ROW EXECUTE FUNCTION app.touch()",
+	)...)
+
+	g := BuildDependencyGraph(s)
+
+	assert.Equal(t, []GraphNode{
+		{Name: "app", Kind: "schema"},
+		{Name: "app.active_orders", Kind: "view"},
+		{Name: "app.orders", Kind: "table"},
+		{Name: "app.orders.orders_touch", Kind: "trigger"},
+		{Name: "app.status", Kind: "type"},
+		{Name: "app.touch", Kind: "routine"},
+		{Name: "app.user_ids", Kind: "sequence"},
+		{Name: "app.users", Kind: "table"},
+	}, g.Nodes)
+	assert.Equal(t, []GraphEdge{
+		{From: "app.active_orders", To: "app", Kind: "reference"},
+		{From: "app.active_orders", To: "app.orders", Kind: "reference"},
+		{From: "app.active_orders", To: "app.users", Kind: "reference"},
+		{From: "app.orders", To: "app", Kind: "reference"},
+		{From: "app.orders", To: "app.users", Kind: "foreign_key"},
+		{From: "app.orders.orders_touch", To: "app.orders", Kind: "reference"},
+		{From: "app.orders.orders_touch", To: "app.touch", Kind: "reference"},
+		{From: "app.status", To: "app", Kind: "reference"},
+		{From: "app.touch", To: "app", Kind: "reference"},
+		{From: "app.user_ids", To: "app", Kind: "reference"},
+		{From: "app.users", To: "app", Kind: "reference"},
+		{From: "app.users", To: "app.status", Kind: "reference"},
+		{From: "app.users", To: "app.user_ids", Kind: "reference"},
+	}, g.Edges)
+	assert.Empty(t, g.Cycles())
+}
+
+func TestDependencyGraphCycles(t *testing.T) {
+	tests := []struct {
+		name     string
+		edges    []GraphEdge
+		expected [][]string
+	}{
+		{
+			name:  "no cycles",
+			edges: []GraphEdge{{From: "a", To: "b"}, {From: "b", To: "c"}},
+		},
+		{
+			name:     "two objects",
+			edges:    []GraphEdge{{From: "a", To: "b"}, {From: "b", To: "a"}, {From: "b", To: "c"}},
+			expected: [][]string{{"a", "b"}},
+		},
+		{
+			name: "separate cycles",
+			edges: []GraphEdge{
+				{From: "d", To: "e"}, {From: "e", To: "f"}, {From: "f", To: "d"},
+				{From: "a", To: "b"}, {From: "b", To: "a"},
+			},
+			expected: [][]string{{"a", "b"}, {"d", "e", "f"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &DependencyGraph{Edges: tt.edges}
+			for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+				g.Nodes = append(g.Nodes, GraphNode{Name: name, Kind: "table"})
+			}
+			assert.Equal(t, tt.expected, g.Cycles())
+		})
+	}
+}
+
+func TestDependencyGraphDOT(t *testing.T) {
+	g := &DependencyGraph{
+		Nodes: []GraphNode{{Name: "public.orders", Kind: "table"}, {Name: "public.users", Kind: "table"}},
+		Edges: []GraphEdge{{From: "public.orders", To: "public.users", Kind: "foreign_key"}},
+	}
+
+	assert.Equal(t, `digraph schema {
+	rankdir=LR;
+	"public.orders" [shape=box];
+	"public.users" [shape=box];
+	"public.orders" -> "public.users" [label="fk"];
+}
+`, g.DOT())
+}
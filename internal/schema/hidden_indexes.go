@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// HideRemovedIndexes removes indexes in two steps. An index removed from the
+// definitions is first made NOT VISIBLE instead of dropped, so queries stop
+// using it while writes keep it up to date, and making it visible again undoes
+// the change at once if queries slow down. An index the database already has
+// NOT VISIBLE is dropped if confirmDrops is set, and otherwise left alone. The
+// names of the indexes left alone are returned.
+func (r *ComparisonResult) HideRemovedIndexes(remote *Schema, confirmDrops bool) []string {
+	var waiting []string
+	kept := r.Differences[:0]
+	for _, diff := range r.Differences {
+		indexName, ok := removedIndexName(diff)
+		if !ok {
+			kept = append(kept, diff)
+			continue
+		}
+		index := findRemoteIndex(remote, diff.ObjectName, indexName)
+		switch {
+		case index == nil:
+			// UNIQUE constraints and indexes we can't find are dropped as usual
+			kept = append(kept, diff)
+		case index.Invisibility.Value < 1:
+			dropIndex := diff.Statements()[0].(*tree.DropIndex)
+			kept = append(kept, Difference{
+				Type:        DiffTypeTableModified,
+				ObjectName:  diff.ObjectName,
+				Description: fmt.Sprintf("Index '%s.%s' made NOT VISIBLE before it's dropped", diff.ObjectName, indexName),
+				Phases: inOnePhase(&tree.AlterIndexVisible{
+					Index:        *dropIndex.IndexList[0],
+					Invisibility: tree.IndexInvisibility{Value: 1},
+				}),
+			})
+		case confirmDrops:
+			kept = append(kept, diff)
+		default:
+			waiting = append(waiting, diff.ObjectName+"@"+indexName)
+		}
+	}
+	r.Differences = kept
+	return waiting
+}
+
+// removedIndexName returns the name of the index a difference only drops,
+// the way compareIndexes removes an index that isn't in the definitions.
+// Indexes dropped for the columns being dropped have OriginalDependencies and
+// are left out, since the columns can't be dropped while they exist.
+func removedIndexName(diff Difference) (string, bool) {
+	if diff.Type != DiffTypeTableModified || diff.IsDropCreate {
+		return "", false
+	}
+	if diff.OriginalDependencies != nil && diff.OriginalDependencies.Size() > 0 {
+		return "", false
+	}
+	stmts := diff.Statements()
+	if len(stmts) != 1 {
+		return "", false
+	}
+	dropIndex, ok := stmts[0].(*tree.DropIndex)
+	if !ok || dropIndex.DropBehavior != tree.DropRestrict || len(dropIndex.IndexList) != 1 {
+		return "", false
+	}
+	return string(dropIndex.IndexList[0].Index), true
+}
+
+// findRemoteIndex returns the definition of a table's index, leaving out the
+// indexes of UNIQUE constraints
+func findRemoteIndex(remote *Schema, tableName, indexName string) *tree.IndexTableDef {
+	for _, table := range remote.Tables {
+		if table.ResolvedName() != tableName {
+			continue
+		}
+		for _, def := range table.Ast.Defs {
+			if index, ok := def.(*tree.IndexTableDef); ok && string(index.Name) == indexName {
+				return index
+			}
+		}
+	}
+	return nil
+}
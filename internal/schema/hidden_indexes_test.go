@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHideRemovedIndexes(t *testing.T) {
+	table := func(index string) string {
+		return "CREATE TABLE public.users (id INT8 NOT NULL, email STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC)" + index + ")"
+	}
+
+	tests := []struct {
+		name         string
+		remote       string
+		confirmDrops bool
+		expected     []string
+		wantWaiting  []string
+	}{
+		{
+			name:     "visible index hidden",
+			remote:   table(", INDEX email_idx (email ASC)"),
+			expected: []string{"ALTER INDEX public.users@email_idx NOT VISIBLE"},
+		},
+		{
+			name:     "partially visible index hidden",
+			remote:   table(", INDEX email_idx (email ASC) VISIBILITY 0.5"),
+			expected: []string{"ALTER INDEX public.users@email_idx NOT VISIBLE"},
+		},
+		{
+			name:        "hidden index waits for confirmation",
+			remote:      table(", INDEX email_idx (email ASC) NOT VISIBLE"),
+			wantWaiting: []string{"public.users@email_idx"},
+		},
+		{
+			name:         "hidden index dropped once confirmed",
+			remote:       table(", INDEX email_idx (email ASC) NOT VISIBLE"),
+			confirmDrops: true,
+			expected:     []string{"DROP INDEX public.users@email_idx RESTRICT"},
+		},
+		{
+			name:     "unique constraints dropped as usual",
+			remote:   table(", UNIQUE INDEX email_key (email ASC)"),
+			expected: []string{"DROP INDEX public.users@email_key CASCADE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localSchema := createSchemaWithTypesAndTables(nil, []string{table("")})
+			remoteSchema := createSchemaWithTypesAndTables(nil, []string{tt.remote})
+
+			result := Compare(localSchema, remoteSchema)
+			waiting := result.HideRemovedIndexes(remoteSchema, tt.confirmDrops)
+			assert.Equal(t, tt.wantWaiting, waiting)
+
+			var statements []string
+			for _, diff := range result.Differences {
+				statements = append(statements, statementsToStringsTables(diff.Statements())...)
+			}
+			require.Equal(t, tt.expected, statements)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+const ignoreDiffPrefix = "-- scurry:ignore-diff"
+
+// IgnoredObjects are objects expected to differ from the database, e.g. a table
+// partway through a multi-step rollout, keyed by difference-style name
+// ("schema.object", or "schema:name" for schemas) with why they're ignored.
+// Compare reports their differences as ignored instead of returning them.
+type IgnoredObjects map[string]string
+
+// Add ignores the object with the given difference-style name
+func (i IgnoredObjects) Add(name, reason string) {
+	i[name] = reason
+}
+
+func (i IgnoredObjects) merge(other IgnoredObjects) {
+	for name, reason := range other {
+		i[name] = reason
+	}
+}
+
+// reasonFor returns why the object a difference is for is ignored. Ignoring a
+// table ignores its triggers too, which are named "schema.table.trigger".
+func (i IgnoredObjects) reasonFor(objectName string) (string, bool) {
+	for name, reason := range i {
+		if objectName == name || strings.HasPrefix(objectName, name+".") {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// parseIgnoreDirectives finds -- scurry:ignore-diff directives in the comments
+// directly above a CREATE statement, optionally followed by the reason:
+//
+//	-- scurry:ignore-diff being split into accounts, see #412
+//	CREATE TABLE users (...);
+func parseIgnoreDirectives(sql string, path string) IgnoredObjects {
+	ignored := make(IgnoredObjects)
+	if !strings.Contains(sql, ignoreDiffPrefix) {
+		return ignored
+	}
+
+	for _, stmt := range parseDefinitionStatements(sql) {
+		for _, line := range strings.Split(stmt.preceding, "\n") {
+			idx := strings.Index(line, ignoreDiffPrefix)
+			if idx == -1 {
+				continue
+			}
+			rest := line[idx+len(ignoreDiffPrefix):]
+			if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+				continue
+			}
+			name := definedObjectName(stmt.ast)
+			if name == "" {
+				continue
+			}
+			reason := strings.TrimSpace(rest)
+			if reason == "" {
+				reason = fmt.Sprintf("scurry:ignore-diff in %s", path)
+			}
+			ignored[name] = reason
+		}
+	}
+	return ignored
+}
+
+// definedObjectName returns the difference-style name of the object a CREATE
+// statement defines, or "" if it doesn't define one
+func definedObjectName(stmt tree.Statement) string {
+	s := NewSchema(stmt)
+	switch {
+	case len(s.Schemas) > 0:
+		return "schema:" + s.Schemas[0].Name
+	case len(s.Tables) > 0:
+		return s.Tables[0].ResolvedName()
+	case len(s.Types) > 0:
+		return s.Types[0].ResolvedName()
+	case len(s.Sequences) > 0:
+		return s.Sequences[0].ResolvedName()
+	case len(s.Views) > 0:
+		return s.Views[0].ResolvedName()
+	case len(s.Routines) > 0:
+		return s.Routines[0].ResolvedName()
+	case len(s.Triggers) > 0:
+		return s.Triggers[0].ResolvedName()
+	}
+	return ""
+}
+
+// ignore moves the differences to ignored objects from Differences to Ignored
+func (r *ComparisonResult) ignore(ignored IgnoredObjects) {
+	if len(ignored) == 0 {
+		return
+	}
+	kept := r.Differences[:0]
+	for _, diff := range r.Differences {
+		if reason, ok := ignored.reasonFor(diff.ObjectName); ok {
+			diff.IgnoreReason = reason
+			r.Ignored = append(r.Ignored, diff)
+			continue
+		}
+		kept = append(kept, diff)
+	}
+	r.Differences = kept
+}
+
+// IgnoredSummary returns a human-readable list of the ignored differences, or
+// "" if there are none
+func (r *ComparisonResult) IgnoredSummary() string {
+	if len(r.Ignored) == 0 {
+		return ""
+	}
+	summary := fmt.Sprintf("Ignored %d difference(s) to objects expected to differ:\n", len(r.Ignored))
+	for _, diff := range r.Ignored {
+		summary += fmt.Sprintf("- %s (%s)\n", diff.DescriptionWithSource(), diff.IgnoreReason)
+	}
+	return summary
+}
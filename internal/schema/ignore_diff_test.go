@@ -0,0 +1,72 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIgnoreDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected IgnoredObjects
+	}{
+		{
+			name:     "no directives",
+			sql:      "CREATE TABLE t (id INT PRIMARY KEY);",
+			expected: IgnoredObjects{},
+		},
+		{
+			name:     "table with a reason",
+			sql:      "-- scurry:ignore-diff being split into accounts\nCREATE TABLE app.users (id INT PRIMARY KEY);",
+			expected: IgnoredObjects{"app.users": "being split into accounts"},
+		},
+		{
+			name:     "without a reason",
+			sql:      "CREATE TABLE a (id INT PRIMARY KEY);\n\n-- scurry:ignore-diff\nCREATE TYPE status AS ENUM ('a');",
+			expected: IgnoredObjects{"public.status": "scurry:ignore-diff in definitions/types.sql"},
+		},
+		{
+			name:     "schema",
+			sql:      "-- scurry:ignore-diff managed by the analytics team\nCREATE SCHEMA analytics;",
+			expected: IgnoredObjects{"schema:analytics": "managed by the analytics team"},
+		},
+		{
+			name:     "other directive with the same prefix",
+			sql:      "-- scurry:ignore-diffs\nCREATE TABLE t (id INT PRIMARY KEY);",
+			expected: IgnoredObjects{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseIgnoreDirectives(tt.sql, "definitions/types.sql"))
+		})
+	}
+}
+
+func TestCompareIgnoresObjects(t *testing.T) {
+	local := NewSchema(parseStatements(`
+		CREATE TABLE users (id INT8 NOT NULL, email STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id));
+		CREATE TABLE posts (id INT8 NOT NULL, title STRING NULL, CONSTRAINT posts_pkey PRIMARY KEY (id));
+	`)...)
+	local.IgnoreDiff = IgnoredObjects{"public.users": "--skip"}
+	remote := NewSchema(parseStatements(`
+		CREATE TABLE users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id));
+		CREATE TABLE posts (id INT8 NOT NULL, CONSTRAINT posts_pkey PRIMARY KEY (id));
+	`)...)
+
+	result := Compare(local, remote)
+	require.Len(t, result.Differences, 1)
+	assert.Equal(t, "public.posts", result.Differences[0].ObjectName)
+	require.Len(t, result.Ignored, 1)
+	assert.Equal(t, "public.users", result.Ignored[0].ObjectName)
+	assert.Equal(t, "--skip", result.Ignored[0].IgnoreReason)
+	assert.Contains(t, result.IgnoredSummary(), "Ignored 1 difference(s) to objects expected to differ:")
+
+	filtered := result.Filter(func(Difference) bool { return false })
+	assert.False(t, filtered.HasChanges())
+	assert.Len(t, filtered.Ignored, 1)
+}
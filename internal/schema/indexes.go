@@ -0,0 +1,129 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/idxtype"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// defaultOpClasses are the operator classes an index uses when none is named,
+// by index type. Naming one of these explicitly doesn't change the index.
+var defaultOpClasses = map[idxtype.T]map[tree.Name]bool{
+	idxtype.INVERTED: {"jsonb_ops": true, "array_ops": true},
+	idxtype.VECTOR:   {"vector_l2_ops": true},
+}
+
+// indexComparisonKey formats an index for comparison, leaving out the ways two
+// definitions of the same index can be spelled differently: explicit ASC and
+// default NULLS ordering, default operator classes, redundant parentheses
+// around expressions, expressions that are just a column, and VISIBILITY
+// written for fully visible or NOT VISIBLE.
+func indexComparisonKey(index *tree.IndexTableDef) string {
+	normalized := *index
+	normalized.Invisibility = normalizeInvisibility(index.Invisibility)
+	normalized.Columns = make(tree.IndexElemList, len(index.Columns))
+	for i, elem := range index.Columns {
+		normalized.Columns[i] = normalizeIndexElem(elem, index.Type)
+	}
+	if normalized.Predicate != nil {
+		normalized.Predicate = tree.StripParens(normalized.Predicate)
+	}
+	return formatNode(&normalized)
+}
+
+func normalizeIndexElem(elem tree.IndexElem, indexType idxtype.T) tree.IndexElem {
+	if elem.Expr != nil {
+		expr := tree.StripParens(elem.Expr)
+		if name, ok := expr.(*tree.UnresolvedName); ok && name.NumParts == 1 && !name.Star {
+			elem.Column = tree.Name(name.Parts[0])
+			expr = nil
+		}
+		elem.Expr = expr
+	}
+
+	if elem.Direction == tree.Ascending {
+		elem.Direction = tree.DefaultDirection
+	}
+	// NULLs sort first ascending and last descending unless told otherwise
+	if (elem.Direction == tree.Descending && elem.NullsOrder == tree.NullsLast) ||
+		(elem.Direction != tree.Descending && elem.NullsOrder == tree.NullsFirst) {
+		elem.NullsOrder = tree.DefaultNullsOrder
+	}
+
+	if defaultOpClasses[indexType][elem.OpClass] {
+		elem.OpClass = ""
+	}
+	return elem
+}
+
+func normalizeInvisibility(invisibility tree.IndexInvisibility) tree.IndexInvisibility {
+	if invisibility.Value == 0 || invisibility.Value == 1 {
+		invisibility.FloatProvided = false
+	}
+	return invisibility
+}
+
+// indexVisibilityChanged returns true if the indexes only differ in how
+// visible they are to the optimizer, which ALTER INDEX changes in place
+func indexVisibilityChanged(local, remote *tree.IndexTableDef) bool {
+	if local.Invisibility.Value == remote.Invisibility.Value {
+		return false
+	}
+	l, r := *local, *remote
+	l.Invisibility, r.Invisibility = tree.IndexInvisibility{}, tree.IndexInvisibility{}
+	return indexComparisonKey(&l) == indexComparisonKey(&r)
+}
+
+// uniqueVisibilityChanged is indexVisibilityChanged for the index of a UNIQUE
+// constraint
+func uniqueVisibilityChanged(local, remote tree.ConstraintTableDef) (*tree.UniqueConstraintTableDef, bool) {
+	l, ok := local.(*tree.UniqueConstraintTableDef)
+	if !ok {
+		return nil, false
+	}
+	r, ok := remote.(*tree.UniqueConstraintTableDef)
+	if !ok || l.Invisibility.Value == r.Invisibility.Value {
+		return nil, false
+	}
+	lCopy, rCopy := *l, *r
+	lCopy.Invisibility, rCopy.Invisibility = tree.IndexInvisibility{}, tree.IndexInvisibility{}
+	return l, formatNode(&lCopy) == formatNode(&rCopy)
+}
+
+// buildIndexVisibilityDiff changes how visible an index is to the optimizer,
+// without rebuilding it
+func buildIndexVisibilityDiff(tableName string, tableRef tree.TableName, indexName string, invisibility tree.IndexInvisibility) Difference {
+	invisibility = normalizeInvisibility(invisibility)
+	diff := Difference{
+		Type:        DiffTypeTableModified,
+		ObjectName:  tableName,
+		Description: fmt.Sprintf("Index '%s.%s' visibility set to %.2f", tableName, indexName, 1-invisibility.Value),
+		Phases: inOnePhase(&tree.AlterIndexVisible{
+			Index:        tree.TableIndexName{Table: tableRef, Index: tree.UnrestrictedName(indexName)},
+			Invisibility: invisibility,
+		}),
+	}
+	switch invisibility.Value {
+	case 0:
+		diff.Description = fmt.Sprintf("Index '%s.%s' made visible", tableName, indexName)
+	case 1:
+		diff.Description = fmt.Sprintf("Index '%s.%s' made NOT VISIBLE", tableName, indexName)
+	}
+	if invisibility.Value > 0 {
+		diff.WarningMessage = fmt.Sprintf("Queries may stop using index '%s.%s'; writes still keep it up to date.", tableName, indexName)
+	}
+	return diff
+}
+
+// indexExpressionColumns returns the columns referenced by an index's
+// expression elements
+func indexExpressionColumns(columns tree.IndexElemList) []string {
+	var cols []string
+	for _, col := range columns {
+		if col.Expr != nil {
+			cols = append(cols, getCheckConstraintColumns(col.Expr)...)
+		}
+	}
+	return cols
+}
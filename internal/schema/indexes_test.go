@@ -0,0 +1,322 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseIndexDef(t *testing.T, table string) *tree.IndexTableDef {
+	t.Helper()
+	stmt, err := parser.ParseOne(table)
+	require.NoError(t, err)
+	for _, def := range stmt.AST.(*tree.CreateTable).Defs {
+		if idx, ok := def.(*tree.IndexTableDef); ok {
+			return idx
+		}
+	}
+	t.Fatalf("no index in %s", table)
+	return nil
+}
+
+func TestIndexComparisonKey(t *testing.T) {
+	t.Parallel()
+
+	// The parser doesn't accept NULLS orderings CockroachDB doesn't default to,
+	// so those are set on the parsed index
+	nullsOrder := func(order tree.NullsOrder) func(*tree.IndexTableDef) {
+		return func(index *tree.IndexTableDef) { index.Columns[0].NullsOrder = order }
+	}
+
+	tests := []struct {
+		name  string
+		a, b  string
+		editA func(*tree.IndexTableDef)
+		equal bool
+	}{
+		{
+			name:  "explicit ASC",
+			a:     "CREATE TABLE t (a INT, INDEX i (a ASC))",
+			b:     "CREATE TABLE t (a INT, INDEX i (a))",
+			equal: true,
+		},
+		{
+			name:  "default NULLS ordering",
+			a:     "CREATE TABLE t (a INT, INDEX i (a DESC NULLS LAST, a ASC NULLS FIRST))",
+			b:     "CREATE TABLE t (a INT, INDEX i (a DESC, a))",
+			equal: true,
+		},
+		{
+			name:  "non-default NULLS ordering descending",
+			a:     "CREATE TABLE t (a INT, INDEX i (a DESC))",
+			b:     "CREATE TABLE t (a INT, INDEX i (a DESC))",
+			editA: nullsOrder(tree.NullsFirst),
+			equal: false,
+		},
+		{
+			name:  "non-default NULLS ordering ascending",
+			a:     "CREATE TABLE t (a INT, INDEX i (a))",
+			b:     "CREATE TABLE t (a INT, INDEX i (a))",
+			editA: nullsOrder(tree.NullsLast),
+			equal: false,
+		},
+		{
+			name:  "expression parentheses",
+			a:     "CREATE TABLE t (a STRING, INDEX i ((lower(a))))",
+			b:     "CREATE TABLE t (a STRING, INDEX i (((lower(a)))))",
+			equal: true,
+		},
+		{
+			name:  "expression that is a column",
+			a:     "CREATE TABLE t (a STRING, INDEX i ((a)))",
+			b:     "CREATE TABLE t (a STRING, INDEX i (a))",
+			equal: true,
+		},
+		{
+			name:  "expression changed",
+			a:     "CREATE TABLE t (a STRING, INDEX i ((lower(a))))",
+			b:     "CREATE TABLE t (a STRING, INDEX i ((upper(a))))",
+			equal: false,
+		},
+		{
+			name:  "default inverted opclass",
+			a:     "CREATE TABLE t (j JSONB, INVERTED INDEX i (j jsonb_ops))",
+			b:     "CREATE TABLE t (j JSONB, INVERTED INDEX i (j))",
+			equal: true,
+		},
+		{
+			name:  "trigram opclass",
+			a:     "CREATE TABLE t (s STRING, INVERTED INDEX i (s gin_trgm_ops))",
+			b:     "CREATE TABLE t (s STRING, INVERTED INDEX i (s gist_trgm_ops))",
+			equal: false,
+		},
+		{
+			name:  "default vector opclass",
+			a:     "CREATE TABLE t (v VECTOR(3), VECTOR INDEX i (v vector_l2_ops))",
+			b:     "CREATE TABLE t (v VECTOR(3), VECTOR INDEX i (v))",
+			equal: true,
+		},
+		{
+			name:  "vector opclass changed",
+			a:     "CREATE TABLE t (v VECTOR(3), VECTOR INDEX i (v vector_cosine_ops))",
+			b:     "CREATE TABLE t (v VECTOR(3), VECTOR INDEX i (v))",
+			equal: false,
+		},
+		{
+			name:  "predicate parentheses",
+			a:     "CREATE TABLE t (a INT, INDEX i (a) WHERE (a > 0))",
+			b:     "CREATE TABLE t (a INT, INDEX i (a) WHERE a > 0)",
+			equal: true,
+		},
+		{
+			name:  "NOT VISIBLE written as a visibility",
+			a:     "CREATE TABLE t (a INT, INDEX i (a) VISIBILITY 0.0)",
+			b:     "CREATE TABLE t (a INT, INDEX i (a) NOT VISIBLE)",
+			equal: true,
+		},
+		{
+			name:  "visibility changed",
+			a:     "CREATE TABLE t (a INT, INDEX i (a) VISIBILITY 0.5)",
+			b:     "CREATE TABLE t (a INT, INDEX i (a))",
+			equal: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			indexA := parseIndexDef(t, tt.a)
+			if tt.editA != nil {
+				tt.editA(indexA)
+			}
+			a := indexComparisonKey(indexA)
+			b := indexComparisonKey(parseIndexDef(t, tt.b))
+			if tt.equal {
+				assert.Equal(t, a, b)
+			} else {
+				assert.NotEqual(t, a, b)
+			}
+		})
+	}
+}
+
+func TestCompareTablesSpecialIndexes(t *testing.T) {
+	tests := []struct {
+		name            string
+		localTable      string
+		remoteTable     string
+		wantDiffCount   int
+		wantDDLContains []string
+	}{
+		{
+			name:          "expression index unchanged",
+			localTable:    "CREATE TABLE users (id INT PRIMARY KEY, email STRING, INDEX email_lower_idx ((lower(email))))",
+			remoteTable:   "CREATE TABLE users (id INT PRIMARY KEY, email STRING, INDEX email_lower_idx ((lower(email))))",
+			wantDiffCount: 0,
+		},
+		{
+			name:          "inverted index unchanged",
+			localTable:    "CREATE TABLE docs (id INT PRIMARY KEY, body JSONB, INVERTED INDEX body_idx (body))",
+			remoteTable:   "CREATE TABLE docs (id INT PRIMARY KEY, body JSONB, INVERTED INDEX body_idx (body))",
+			wantDiffCount: 0,
+		},
+		{
+			name:          "trigram index unchanged",
+			localTable:    "CREATE TABLE users (id INT PRIMARY KEY, name STRING, INVERTED INDEX name_trgm_idx (name gin_trgm_ops))",
+			remoteTable:   "CREATE TABLE users (id INT PRIMARY KEY, name STRING, INVERTED INDEX name_trgm_idx (name gin_trgm_ops))",
+			wantDiffCount: 0,
+		},
+		{
+			name:            "expression index added",
+			localTable:      "CREATE TABLE users (id INT PRIMARY KEY, email STRING, INDEX email_lower_idx ((lower(email))))",
+			remoteTable:     "CREATE TABLE users (id INT PRIMARY KEY, email STRING)",
+			wantDiffCount:   1,
+			wantDDLContains: []string{"CREATE INDEX email_lower_idx", "lower(email)"},
+		},
+		{
+			name:            "inverted index added",
+			localTable:      "CREATE TABLE docs (id INT PRIMARY KEY, body JSONB, INVERTED INDEX body_idx (body))",
+			remoteTable:     "CREATE TABLE docs (id INT PRIMARY KEY, body JSONB)",
+			wantDiffCount:   1,
+			wantDDLContains: []string{"CREATE INVERTED INDEX body_idx"},
+		},
+		{
+			name:            "trigram index added",
+			localTable:      "CREATE TABLE users (id INT PRIMARY KEY, name STRING, INVERTED INDEX name_trgm_idx (name gin_trgm_ops))",
+			remoteTable:     "CREATE TABLE users (id INT PRIMARY KEY, name STRING)",
+			wantDiffCount:   1,
+			wantDDLContains: []string{"CREATE INVERTED INDEX name_trgm_idx", "gin_trgm_ops"},
+		},
+		{
+			name:          "vector index unchanged",
+			localTable:    "CREATE TABLE items (id INT PRIMARY KEY, embedding VECTOR(3), VECTOR INDEX embedding_idx (embedding))",
+			remoteTable:   "CREATE TABLE items (id INT PRIMARY KEY, embedding VECTOR(3), VECTOR INDEX embedding_idx (embedding))",
+			wantDiffCount: 0,
+		},
+		{
+			name:            "vector index added",
+			localTable:      "CREATE TABLE items (id INT PRIMARY KEY, embedding VECTOR(3), VECTOR INDEX embedding_idx (embedding vector_cosine_ops))",
+			remoteTable:     "CREATE TABLE items (id INT PRIMARY KEY, embedding VECTOR(3))",
+			wantDiffCount:   1,
+			wantDDLContains: []string{"CREATE VECTOR INDEX embedding_idx", "vector_cosine_ops"},
+		},
+		{
+			name:            "vector column dimension changed",
+			localTable:      "CREATE TABLE items (id INT PRIMARY KEY, embedding VECTOR(4))",
+			remoteTable:     "CREATE TABLE items (id INT PRIMARY KEY, embedding VECTOR(3))",
+			wantDiffCount:   1,
+			wantDDLContains: []string{"embedding", "VECTOR(4)"},
+		},
+		{
+			name:            "expression changed",
+			localTable:      "CREATE TABLE users (id INT PRIMARY KEY, email STRING, INDEX email_idx ((lower(email))))",
+			remoteTable:     "CREATE TABLE users (id INT PRIMARY KEY, email STRING, INDEX email_idx ((upper(email))))",
+			wantDiffCount:   1,
+			wantDDLContains: []string{"DROP INDEX", "CREATE INDEX email_idx", "lower(email)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			localSchema := createSchemaWithTables([]string{tt.localTable})
+			remoteSchema := createSchemaWithTables([]string{tt.remoteTable})
+
+			diffs := compareTables(localSchema, remoteSchema)
+			require.Len(t, diffs, tt.wantDiffCount)
+
+			var ddl []string
+			for _, diff := range diffs {
+				ddl = append(ddl, statementsToStringsTables(diff.Statements())...)
+			}
+			all := strings.Join(ddl, "\n")
+			for _, want := range tt.wantDDLContains {
+				assert.Contains(t, all, want)
+			}
+		})
+	}
+}
+
+func TestCompareIndexVisibility(t *testing.T) {
+	table := func(index string) string {
+		return "CREATE TABLE public.users (id INT8 NOT NULL, email STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC), " + index + ")"
+	}
+
+	tests := []struct {
+		name     string
+		local    string
+		remote   string
+		expected []string
+	}{
+		{
+			name:     "index made NOT VISIBLE",
+			local:    table("INDEX email_idx (email ASC) NOT VISIBLE"),
+			remote:   table("INDEX email_idx (email ASC)"),
+			expected: []string{"ALTER INDEX public.users@email_idx NOT VISIBLE"},
+		},
+		{
+			name:     "index made visible",
+			local:    table("INDEX email_idx (email ASC)"),
+			remote:   table("INDEX email_idx (email ASC) NOT VISIBLE"),
+			expected: []string{"ALTER INDEX public.users@email_idx VISIBLE"},
+		},
+		{
+			name:     "index partially visible",
+			local:    table("INDEX email_idx (email ASC) VISIBILITY 0.25"),
+			remote:   table("INDEX email_idx (email ASC)"),
+			expected: []string{"ALTER INDEX public.users@email_idx VISIBILITY 0.25"},
+		},
+		{
+			name:     "unique index made NOT VISIBLE",
+			local:    table("UNIQUE INDEX email_key (email ASC) NOT VISIBLE"),
+			remote:   table("UNIQUE INDEX email_key (email ASC)"),
+			expected: []string{"ALTER INDEX public.users@email_key NOT VISIBLE"},
+		},
+		{
+			name:   "NOT VISIBLE written as a visibility",
+			local:  table("INDEX email_idx (email ASC) VISIBILITY 0.0"),
+			remote: table("INDEX email_idx (email ASC) NOT VISIBLE"),
+		},
+		{
+			name:     "visibility and columns changed",
+			local:    table("INDEX email_idx (email DESC) NOT VISIBLE"),
+			remote:   table("INDEX email_idx (email ASC)"),
+			expected: []string{"DROP INDEX public.users@email_idx RESTRICT", "CREATE INDEX email_idx ON public.users (email DESC) NOT VISIBLE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			local := createSchemaWithTypesAndTables(nil, []string{tt.local})
+			remote := createSchemaWithTypesAndTables(nil, []string{tt.remote})
+
+			var statements []string
+			for _, diff := range compareTables(local, remote) {
+				statements = append(statements, statementsToStringsTables(diff.Statements())...)
+			}
+			assert.Equal(t, tt.expected, statements)
+		})
+	}
+}
+
+func TestDropColumnReferencedByExpressionIndex(t *testing.T) {
+	t.Parallel()
+	localSchema := createSchemaWithTables([]string{"CREATE TABLE users (id INT PRIMARY KEY)"})
+	remoteSchema := createSchemaWithTables([]string{"CREATE TABLE users (id INT PRIMARY KEY, email STRING, INDEX email_lower_idx ((lower(email))))"})
+
+	diffs := compareTables(localSchema, remoteSchema)
+
+	var ddl []string
+	for _, diff := range diffs {
+		ddl = append(ddl, statementsToStringsTables(diff.Statements())...)
+	}
+	all := strings.Join(ddl, "\n")
+	assert.Contains(t, all, "DROP INDEX")
+	assert.Contains(t, all, "email_lower_idx")
+	assert.Contains(t, all, "DROP COLUMN email")
+}
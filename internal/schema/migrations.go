@@ -14,6 +14,7 @@ import (
 type migrationStatement struct {
 	stmts    []tree.Statement
 	requires set.Set[*migrationStatement]
+	diff     int // Index of the difference in ComparisonResult.Differences
 }
 
 type dependencyMap map[string]set.Set[*migrationStatement]
@@ -40,8 +41,125 @@ func (r *ComparisonResult) GenerateMigrations(pretty bool) ([]string, []string,
 		return nil, nil, fmt.Errorf("schema change cannot be applied:\n  - %s", strings.Join(blockingErrors, "\n  - "))
 	}
 
-	statements := make([]*migrationStatement, 0)
-	warnings := make([]string, 0)
+	if err := r.ValidateOrdering(); err != nil {
+		return nil, nil, err
+	}
+
+	graph := r.buildMigrationGraph()
+	statements, warnings, statementWarnings := graph.statements, graph.warnings, graph.statementWarnings
+	graph.addOrderingPins(r.Differences)
+
+	slices.SortFunc(statements, compareMigrationStatements)
+
+	// Collect all of the statements in a set, making sure dependencies are put in first.
+	// Then convert them into a big list of strings.
+	statementSet := set.New[*migrationStatement]()
+	for _, migration := range statements {
+		if statementSet.Contains(migration) {
+			continue
+		}
+		result, err := exploreDeps(migration, set.New[*migrationStatement]())
+		if err != nil {
+			return nil, nil, err
+		}
+		statementSet = statementSet.Union(result)
+	}
+	orderedStatements := slices.Collect(statementSet.Values())
+
+	start := 0
+	currentChunk := set.New[*migrationStatement]()
+	for end := range orderedStatements {
+		stmt := orderedStatements[end]
+		// check if any of this statement's dependencies are in the current chunk
+		chunkHasDep := false
+		for dep := range stmt.requires.Values() {
+			if currentChunk.Contains(dep) {
+				chunkHasDep = true
+				break
+			}
+		}
+		// If this statement has no dependencies in the current chunk, add it to the current chunk
+		if !chunkHasDep {
+			currentChunk.Add(stmt)
+		} else {
+			// otherwise we need to end that chunk and make a new one
+			slices.SortFunc(orderedStatements[start:end], compareMigrationStatements)
+			start = end
+			currentChunk = set.New[*migrationStatement]()
+			currentChunk.Add(stmt)
+		}
+	}
+	slices.SortFunc(orderedStatements[start:], compareMigrationStatements)
+
+	// Build a map to track which tree.Statement belongs to which migrationStatement
+	// This lets us identify the first statement of each migration group
+	stmtToMigration := make(map[tree.Statement]*migrationStatement)
+	for _, migration := range orderedStatements {
+		if len(migration.stmts) > 0 {
+			stmtToMigration[migration.stmts[0]] = migration
+		}
+	}
+
+	// Flatten all statements from each group, preserving their order
+	allStatements := make([]tree.Statement, 0)
+	for _, migration := range orderedStatements {
+		allStatements = append(allStatements, migration.stmts...)
+	}
+
+	// Each Difference independently marks the transaction boundaries between its
+	// phases with COMMIT/BEGIN statements. Once flattened, consecutive Differences can
+	// produce redundant runs of boundaries (e.g. "COMMIT; BEGIN; COMMIT; BEGIN;")
+	// as well as leading/trailing boundaries. Coalesce them down to the minimal
+	// set that produces the same transaction structure at execution time.
+	allStatements = coalesceTransactionBoundaries(allStatements)
+
+	ddl := make([]string, 0)
+	for _, stmt := range allStatements {
+		var s string
+		var err error
+		if pretty {
+			s, err = tree.Pretty(stmt)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to pretty print migration statement: %w", err)
+			}
+		} else {
+			s = stmt.String()
+		}
+
+		// If this is the first statement of a migration group with a warning, prepend the warning comment
+		if migration, isFirst := stmtToMigration[stmt]; isFirst {
+			if warning, hasWarning := statementWarnings[migration]; hasWarning {
+				warningComment := formatWarningComment(warning)
+				if warningComment != "" {
+					s = warningComment + "\n" + s
+				}
+			}
+		}
+
+		ddl = append(ddl, s)
+	}
+	return ddl, warnings, nil
+}
+
+// migrationGraph holds a statement group for each difference with statements,
+// and the dependencies between them that come from the objects they change
+type migrationGraph struct {
+	statements        []*migrationStatement
+	warnings          []string
+	statementWarnings map[*migrationStatement]string
+	byDifference      map[int]*migrationStatement
+}
+
+// buildMigrationGraph creates a statement group for each difference, and makes
+// each group require the groups creating what it uses, and dropping objects
+// only after the groups dropping what depends on them
+func (r *ComparisonResult) buildMigrationGraph() *migrationGraph {
+	graph := &migrationGraph{
+		warnings:          make([]string, 0),
+		statementWarnings: make(map[*migrationStatement]string),
+		byDifference:      make(map[int]*migrationStatement),
+	}
+
 	// Multiple statements can provide the same name, like functions with overloads
 	providers := make(dependencyMap)
 
@@ -49,35 +167,35 @@ func (r *ComparisonResult) GenerateMigrations(pretty bool) ([]string, []string,
 	dropStatements := make(dependencyMap)
 	originalDependencies := make(map[*migrationStatement]set.Set[string])
 
-	// Map to track warnings for each migration statement
-	statementWarnings := make(map[*migrationStatement]string)
-
 	// Dropping the schema has to come last, save them for the end
 	dropSchemaStmts := make([]*migrationStatement, 0)
-	for _, difference := range r.Differences {
+	for i, difference := range r.Differences {
 		if difference.WarningMessage != "" {
-			warnings = append(warnings, difference.WarningMessage)
+			graph.warnings = append(graph.warnings, difference.WarningMessage)
 		}
 
-		if len(difference.MigrationStatements) == 0 {
+		diffStatements := difference.TransactionStatements()
+		if len(diffStatements) == 0 {
 			continue
 		}
 
 		// Create one migrationStatement per Difference, containing all its statements
 		// This ensures all statements from a single diff stay together and execute in order
 		stmt := &migrationStatement{
-			stmts:    difference.MigrationStatements,
+			stmts:    diffStatements,
 			requires: set.New[*migrationStatement](),
+			diff:     i,
 		}
+		graph.byDifference[i] = stmt
 
 		// Store warning for this statement if it exists
 		if difference.WarningMessage != "" {
-			statementWarnings[stmt] = difference.WarningMessage
+			graph.statementWarnings[stmt] = difference.WarningMessage
 		}
 
 		// Check if this is a drop schema statement (they go last)
 		isDropSchema := false
-		for _, ddl := range difference.MigrationStatements {
+		for _, ddl := range difference.Statements() {
 			if _, ok := ddl.(*tree.DropSchema); ok {
 				isDropSchema = true
 				break
@@ -87,7 +205,7 @@ func (r *ComparisonResult) GenerateMigrations(pretty bool) ([]string, []string,
 		if isDropSchema {
 			dropSchemaStmts = append(dropSchemaStmts, stmt)
 		} else {
-			statements = append(statements, stmt)
+			graph.statements = append(graph.statements, stmt)
 		}
 
 		if difference.OriginalDependencies != nil && difference.OriginalDependencies.Size() > 0 {
@@ -95,7 +213,7 @@ func (r *ComparisonResult) GenerateMigrations(pretty bool) ([]string, []string,
 		}
 
 		// Track what each statement group drops for reverse dependency resolution
-		for _, ddl := range difference.MigrationStatements {
+		for _, ddl := range difference.Statements() {
 			switch d := ddl.(type) {
 			case *tree.DropType:
 				for _, name := range d.Names {
@@ -135,10 +253,10 @@ func (r *ComparisonResult) GenerateMigrations(pretty bool) ([]string, []string,
 			}
 		}
 	}
-	statements = append(statements, dropSchemaStmts...)
+	graph.statements = append(graph.statements, dropSchemaStmts...)
 
 	// Collect all of the names provided by each statement group, so as we explore dependencies we can connect statements together.
-	for _, migration := range statements {
+	for _, migration := range graph.statements {
 		for _, ddl := range migration.stmts {
 			for name := range GetProvidedNames(ddl, true).Values() {
 				providers.add(name, migration)
@@ -148,7 +266,7 @@ func (r *ComparisonResult) GenerateMigrations(pretty bool) ([]string, []string,
 
 	// Add dependencies between statement groups by checking the requirements against the things that other groups provide.
 	// If we don't have a provider for a requirement, we will assume it is already present or a builtin.
-	for _, migration := range statements {
+	for _, migration := range graph.statements {
 		for _, ddl := range migration.stmts {
 			for name := range GetDependencyNames(ddl, true).Values() {
 				if others, ok := providers[name]; ok {
@@ -174,102 +292,54 @@ func (r *ComparisonResult) GenerateMigrations(pretty bool) ([]string, []string,
 		}
 	}
 
-	slices.SortFunc(statements, func(a, b *migrationStatement) int {
-		return strings.Compare(a.stmts[0].String(), b.stmts[0].String())
-	})
-
-	// Collect all of the statements in a set, making sure dependencies are put in first.
-	// Then convert them into a big list of strings.
-	statementSet := set.New[*migrationStatement]()
-	for _, migration := range statements {
-		if statementSet.Contains(migration) {
-			continue
-		}
-		result, err := exploreDeps(migration, set.New[*migrationStatement]())
-		if err != nil {
-			return nil, nil, err
-		}
-		statementSet = statementSet.Union(result)
-	}
-	orderedStatements := slices.Collect(statementSet.Values())
-
-	start := 0
-	currentChunk := set.New[*migrationStatement]()
-	for end := range orderedStatements {
-		stmt := orderedStatements[end]
-		// check if any of this statement's dependencies are in the current chunk
-		chunkHasDep := false
-		for dep := range stmt.requires.Values() {
-			if currentChunk.Contains(dep) {
-				chunkHasDep = true
-				break
-			}
-		}
-		// If this statement has no dependencies in the current chunk, add it to the current chunk
-		if !chunkHasDep {
-			currentChunk.Add(stmt)
-		} else {
-			// otherwise we need to end that chunk and make a new one
-			slices.SortFunc(orderedStatements[start:end], func(a, b *migrationStatement) int {
-				return strings.Compare(a.stmts[0].String(), b.stmts[0].String())
-			})
-			start = end
-			currentChunk = set.New[*migrationStatement]()
-			currentChunk.Add(stmt)
-		}
-	}
-	slices.SortFunc(orderedStatements[start:], func(a, b *migrationStatement) int {
-		return strings.Compare(a.stmts[0].String(), b.stmts[0].String())
-	})
-
-	// Build a map to track which tree.Statement belongs to which migrationStatement
-	// This lets us identify the first statement of each migration group
-	stmtToMigration := make(map[tree.Statement]*migrationStatement)
-	for _, migration := range orderedStatements {
-		if len(migration.stmts) > 0 {
-			stmtToMigration[migration.stmts[0]] = migration
-		}
-	}
+	return graph
+}
 
-	// Flatten all statements from each group, preserving their order
-	allStatements := make([]tree.Statement, 0)
-	for _, migration := range orderedStatements {
-		allStatements = append(allStatements, migration.stmts...)
+// compareMigrationStatements orders statement groups that don't depend on each
+// other by the kind of object they change, then alphabetically. Dependencies
+// always win, this only decides what happens when a dependency isn't known:
+// schemas, types and sequences are created before the tables that use them,
+// views, routines and triggers after, and objects are dropped in reverse.
+func compareMigrationStatements(a, b *migrationStatement) int {
+	if ra, rb := migrationStatementRank(a), migrationStatementRank(b); ra != rb {
+		return ra - rb
 	}
+	return strings.Compare(a.stmts[0].String(), b.stmts[0].String())
+}
 
-	// Each Difference independently prepends/appends COMMIT/BEGIN transaction
-	// boundaries to its statements. Once flattened, consecutive Differences can
-	// produce redundant runs of boundaries (e.g. "COMMIT; BEGIN; COMMIT; BEGIN;")
-	// as well as leading/trailing boundaries. Coalesce them down to the minimal
-	// set that produces the same transaction structure at execution time.
-	allStatements = coalesceTransactionBoundaries(allStatements)
-
-	ddl := make([]string, 0)
-	for _, stmt := range allStatements {
-		var s string
-		var err error
-		if pretty {
-			s, err = tree.Pretty(stmt)
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to pretty print migration statement: %w", err)
-			}
-		} else {
-			s = stmt.String()
-		}
-
-		// If this is the first statement of a migration group with a warning, prepend the warning comment
-		if migration, isFirst := stmtToMigration[stmt]; isFirst {
-			if warning, hasWarning := statementWarnings[migration]; hasWarning {
-				warningComment := formatWarningComment(warning)
-				if warningComment != "" {
-					s = warningComment + "\n" + s
-				}
-			}
+func migrationStatementRank(migration *migrationStatement) int {
+	for _, stmt := range migration.stmts {
+		switch stmt.(type) {
+		case *tree.BeginTransaction, *tree.CommitTransaction:
+			continue
+		case *tree.CreateSchema:
+			return 0
+		case *tree.CreateType, *tree.AlterType:
+			return 1
+		case *tree.CreateSequence, *tree.AlterSequence:
+			return 2
+		case *tree.CreateView, *tree.CreateRoutine:
+			return 4
+		case *tree.CreateTrigger:
+			return 5
+		case *tree.DropTrigger:
+			return 6
+		case *tree.DropView, *tree.DropRoutine:
+			return 7
+		case *tree.DropTable:
+			return 8
+		case *tree.DropSequence:
+			return 9
+		case *tree.DropType:
+			return 10
+		case *tree.DropSchema:
+			return 11
+		default:
+			// Tables, indexes and everything else
+			return 3
 		}
-
-		ddl = append(ddl, s)
 	}
-	return ddl, warnings, nil
+	return 3
 }
 
 func exploreDeps(migration *migrationStatement, pending set.Set[*migrationStatement]) (set.Set[*migrationStatement], error) {
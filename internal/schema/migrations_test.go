@@ -259,6 +259,46 @@ func TestForeignKeyDependsOnNewUniqueConstraint(t *testing.T) {
 	}
 }
 
+// TestCrossSchemaMigrationOrder checks that objects outside of public keep
+// their schema when dependencies are resolved, so a table depending on a
+// same-named table or type in another schema is created after it.
+func TestCrossSchemaMigrationOrder(t *testing.T) {
+	localSchema := createSchemaWithTypesAndTables(
+		[]string{
+			`CREATE TYPE app.status AS ENUM ('active', 'suspended')`,
+		},
+		[]string{
+			`CREATE TABLE app.users (
+				id INT8 NOT NULL,
+				account_id INT8 NOT NULL,
+				status app.status NOT NULL DEFAULT 'active':::app.status,
+				CONSTRAINT users_pkey PRIMARY KEY (id ASC),
+				CONSTRAINT users_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.users (id)
+			)`,
+			`CREATE TABLE public.users (
+				id INT8 NOT NULL,
+				CONSTRAINT users_pkey PRIMARY KEY (id ASC)
+			)`,
+		},
+	)
+	remoteSchema := createSchemaWithTypesAndTables(nil, nil)
+
+	migrations, _, err := Compare(localSchema, remoteSchema).GenerateMigrations(false)
+	if err != nil {
+		t.Fatalf("GenerateMigrations() error: %v", err)
+	}
+	allDDL := strings.Join(migrations, "\n")
+
+	lastIndex := -1
+	for _, want := range []string{"CREATE TYPE app.status", "CREATE TABLE public.users", "CREATE TABLE app.users"} {
+		index := strings.Index(allDDL[lastIndex+1:], want)
+		if index == -1 {
+			t.Fatalf("expected %q to appear after position %d.\nGot:\n%s", want, lastIndex, allDDL)
+		}
+		lastIndex = lastIndex + 1 + index
+	}
+}
+
 // TestForeignKeyDependsOnNewUniqueConstraintApplies is an end-to-end check
 // that the migration generated for "new FK references newly added unique
 // constraint" actually applies to a CockroachDB instance — verifying the
@@ -0,0 +1,77 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+// NullRows is a column the differences make NOT NULL while rows of the database
+// still hold NULL in it, so ALTER ... SET NOT NULL would fail
+type NullRows struct {
+	Table  string // "schema.table"
+	Column string
+	Rows   int64
+	// Default is the column's DEFAULT in the local schema, which the NULL rows
+	// can be backfilled with, or nil if it has none
+	Default tree.Expr
+}
+
+// notNullColumn is a column of an existing table that a SET NOT NULL makes NOT NULL
+type notNullColumn struct {
+	schema string
+	table  string
+	column string
+}
+
+// notNullColumns returns the columns the differences' statements set NOT NULL, in order
+func (r *ComparisonResult) notNullColumns() []notNullColumn {
+	var columns []notNullColumn
+	for _, diff := range r.Differences {
+		for _, stmt := range diff.Statements() {
+			alter, ok := stmt.(*tree.AlterTable)
+			if !ok {
+				continue
+			}
+			schemaName, tableName := getObjectName(alter.Table)
+			for _, cmd := range alter.Cmds {
+				if setNotNull, ok := cmd.(*tree.AlterTableSetNotNull); ok {
+					columns = append(columns, notNullColumn{schema: schemaName, table: tableName, column: setNotNull.Column.Normalize()})
+				}
+			}
+		}
+	}
+	return columns
+}
+
+// CheckNullRows counts the NULL rows in the database of each column the
+// differences make NOT NULL, returning the columns that still have some. Each
+// count scans the table, so this is opt-in for callers.
+func CheckNullRows(ctx context.Context, client *db.Client, local *Schema, result *ComparisonResult) ([]NullRows, error) {
+	var nulls []NullRows
+	for _, col := range result.notNullColumns() {
+		rows, err := client.CountNullRows(ctx, col.schema, col.table, col.column)
+		if err != nil {
+			return nil, err
+		}
+		if rows == 0 {
+			continue
+		}
+
+		qualified := fmt.Sprintf("%s.%s", col.schema, col.table)
+		n := NullRows{Table: qualified, Column: col.column, Rows: rows}
+		for _, table := range local.Tables {
+			if table.ResolvedName() != qualified {
+				continue
+			}
+			if def := findColumnDef(table.Ast, col.column); def != nil && def.HasDefaultExpr() {
+				n.Default = def.DefaultExpr.Expr
+			}
+		}
+		nulls = append(nulls, n)
+	}
+	return nulls, nil
+}
@@ -0,0 +1,62 @@
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestNotNullColumns(t *testing.T) {
+	t.Parallel()
+
+	remote := NewSchema(parseStatements(
+		"CREATE TABLE public.users (id INT8 NOT NULL, email STRING NULL, name STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id))",
+		"CREATE TABLE app.orders (id INT8 NOT NULL, total DECIMAL NULL, CONSTRAINT orders_pkey PRIMARY KEY (id))",
+	)...)
+	local := NewSchema(parseStatements(
+		"CREATE TABLE public.users (id INT8 NOT NULL, email STRING NOT NULL, name STRING NULL, created_at TIMESTAMPTZ NOT NULL DEFAULT now(), CONSTRAINT users_pkey PRIMARY KEY (id))",
+		"CREATE TABLE app.orders (id INT8 NOT NULL, total DECIMAL NOT NULL, CONSTRAINT orders_pkey PRIMARY KEY (id))",
+	)...)
+
+	columns := Compare(local, remote).notNullColumns()
+	assert.ElementsMatch(t, []notNullColumn{
+		{schema: "public", table: "users", column: "email"},
+		{schema: "app", table: "orders", column: "total"},
+	}, columns)
+}
+
+func TestCheckNullRows(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	client, err := db.GetShadowDB(ctx,
+		"CREATE TABLE public.users (id INT8 PRIMARY KEY, email STRING, status STRING, name STRING)",
+		"INSERT INTO public.users VALUES (1, NULL, NULL, 'a'), (2, 'b@example.com', NULL, 'b'), (3, NULL, 'active', 'c')",
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	remoteSchema, err := LoadFromDatabase(ctx, client)
+	require.NoError(t, err)
+	local := NewSchema(parseStatements("CREATE TABLE public.users (id INT8 NOT NULL, email STRING NOT NULL, status STRING NOT NULL DEFAULT 'active':::STRING, name STRING NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id))")...)
+
+	nulls, err := CheckNullRows(ctx, client, local, Compare(local, remoteSchema))
+	require.NoError(t, err)
+	require.Len(t, nulls, 2)
+
+	byColumn := make(map[string]NullRows)
+	for _, n := range nulls {
+		byColumn[n.Column] = n
+	}
+	assert.Equal(t, "public.users", byColumn["email"].Table)
+	assert.Equal(t, int64(2), byColumn["email"].Rows)
+	assert.Nil(t, byColumn["email"].Default)
+	assert.Equal(t, int64(2), byColumn["status"].Rows)
+	require.NotNil(t, byColumn["status"].Default)
+	assert.Equal(t, "'active':::STRING", tree.AsString(byColumn["status"].Default))
+}
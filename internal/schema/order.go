@@ -82,14 +82,14 @@ func ComputeTableInsertionOrder(tables []ObjectSchema[*tree.CreateTable]) (*Tabl
 	selfRefColumns := make(map[string][]string)
 	for _, t := range tables {
 		qualifiedName := t.ResolvedName()
-		_, tableName := getTableName(t.Ast.Table)
+		schemaName, tableName := getTableName(t.Ast.Table)
 		for _, def := range t.Ast.Defs {
 			fk, ok := def.(*tree.ForeignKeyConstraintTableDef)
 			if !ok {
 				continue
 			}
-			_, refTable := getTableName(fk.Table)
-			if refTable == tableName {
+			refSchema, refTable := getTableName(fk.Table)
+			if refSchema == schemaName && refTable == tableName {
 				cols := make([]string, len(fk.FromCols))
 				for i, col := range fk.FromCols {
 					cols[i] = col.Normalize()
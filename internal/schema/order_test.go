@@ -84,6 +84,18 @@ func TestComputeTableInsertionOrder(t *testing.T) {
 				"public.categories": {"parent_id"},
 			},
 		},
+		{
+			name: "same table name in two schemas",
+			tables: []ObjectSchema[*tree.CreateTable]{
+				makeTable("app", "users", `CREATE TABLE app.users (
+					id INT8 PRIMARY KEY,
+					account_id INT8 REFERENCES public.users(id)
+				)`),
+				makeTable("public", "users", `CREATE TABLE public.users (id INT8 PRIMARY KEY)`),
+			},
+			expectedOrder:   []string{"public.users", "app.users"},
+			expectedSelfRef: map[string][]string{},
+		},
 		{
 			name: "many-to-one",
 			tables: []ObjectSchema[*tree.CreateTable]{
@@ -0,0 +1,132 @@
+package schema
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DiffDependency is an order between two differences that their statements
+// require: Then uses an object First creates, or drops an object only after
+// First drops what depended on it. Both are indexes into
+// ComparisonResult.Differences.
+type DiffDependency struct {
+	First int
+	Then  int
+}
+
+// Dependencies returns the orders between the differences that their
+// statements require, which pinned orders can't contradict
+func (r *ComparisonResult) Dependencies() []DiffDependency {
+	graph := r.buildMigrationGraph()
+	var deps []DiffDependency
+	for _, stmt := range graph.statements {
+		for req := range stmt.requires.Values() {
+			deps = append(deps, DiffDependency{First: req.diff, Then: stmt.diff})
+		}
+	}
+	slices.SortFunc(deps, func(a, b DiffDependency) int {
+		return cmp.Or(cmp.Compare(a.Then, b.Then), cmp.Compare(a.First, b.First))
+	})
+	return slices.Compact(deps)
+}
+
+// PinOrder makes the difference at index then run after the one at index
+// first, on top of the dependencies between them. If the dependencies or the
+// other pins require the opposite order, it fails and changes nothing.
+func (r *ComparisonResult) PinOrder(first, then int) error {
+	if first < 0 || first >= len(r.Differences) || then < 0 || then >= len(r.Differences) {
+		return fmt.Errorf("there is no difference %d", max(first, then)+1)
+	}
+	if first == then {
+		return fmt.Errorf("a difference can't run after itself")
+	}
+
+	key := r.Differences[first].Key()
+	pinned := &r.Differences[then]
+	if slices.Contains(pinned.RunsAfter, key) {
+		return nil
+	}
+	previous := pinned.RunsAfter
+	pinned.RunsAfter = append(slices.Clip(previous), key)
+	if err := r.ValidateOrdering(); err != nil {
+		pinned.RunsAfter = previous
+		return err
+	}
+	return nil
+}
+
+// ValidateOrdering checks that the pinned orders agree with the dependencies
+// between the differences and with each other. Pins to differences that
+// aren't in the result, e.g. ones filtered out, are ignored.
+func (r *ComparisonResult) ValidateOrdering() error {
+	if !slices.ContainsFunc(r.Differences, func(d Difference) bool { return len(d.RunsAfter) > 0 }) {
+		return nil
+	}
+
+	graph := r.buildMigrationGraph()
+	graph.addOrderingPins(r.Differences)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[*migrationStatement]int, len(graph.statements))
+	var path []*migrationStatement
+	var visit func(stmt *migrationStatement) []*migrationStatement
+	visit = func(stmt *migrationStatement) []*migrationStatement {
+		switch state[stmt] {
+		case visiting:
+			start := slices.Index(path, stmt)
+			return append(slices.Clone(path[start:]), stmt)
+		case done:
+			return nil
+		}
+		state[stmt] = visiting
+		path = append(path, stmt)
+		for req := range stmt.requires.Values() {
+			if cycle := visit(req); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[stmt] = done
+		return nil
+	}
+
+	for _, stmt := range graph.statements {
+		cycle := visit(stmt)
+		if cycle == nil {
+			continue
+		}
+		// Each statement group requires the next one, so reverse it into the
+		// order they'd have to run in
+		names := make([]string, len(cycle))
+		for i, s := range cycle {
+			names[len(cycle)-1-i] = r.Differences[s.diff].Description
+		}
+		return fmt.Errorf("the pinned order conflicts with the dependencies between the changes: %s", strings.Join(names, " → "))
+	}
+	return nil
+}
+
+// addOrderingPins makes each statement group require the groups of the
+// differences it's pinned to run after
+func (g *migrationGraph) addOrderingPins(diffs []Difference) {
+	byKey := make(map[string][]*migrationStatement)
+	for _, stmt := range g.statements {
+		key := diffs[stmt.diff].Key()
+		byKey[key] = append(byKey[key], stmt)
+	}
+	for _, stmt := range g.statements {
+		for _, key := range diffs[stmt.diff].RunsAfter {
+			for _, other := range byKey[key] {
+				if other != stmt {
+					stmt.requires.Add(other)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func orderingTestResult(t *testing.T) (*ComparisonResult, map[string]int) {
+	t.Helper()
+	local := createSchemaWithTypesAndTables(nil, []string{
+		`CREATE TABLE public.a (id INT8 NOT NULL, CONSTRAINT a_pkey PRIMARY KEY (id ASC))`,
+		`CREATE TABLE public.b (
+			id INT8 NOT NULL,
+			a_id INT8 NOT NULL,
+			CONSTRAINT b_pkey PRIMARY KEY (id ASC),
+			CONSTRAINT b_a_id_fkey FOREIGN KEY (a_id) REFERENCES public.a (id)
+		)`,
+		`CREATE TABLE public.c (id INT8 NOT NULL, CONSTRAINT c_pkey PRIMARY KEY (id ASC))`,
+	})
+	result := Compare(local, createSchemaWithTypesAndTables(nil, nil))
+
+	index := make(map[string]int)
+	for i, diff := range result.Differences {
+		index[diff.ObjectName] = i
+	}
+	require.Contains(t, index, "public.a")
+	require.Contains(t, index, "public.b")
+	require.Contains(t, index, "public.c")
+	return result, index
+}
+
+func TestDependencies(t *testing.T) {
+	result, index := orderingTestResult(t)
+
+	assert.Equal(t, []DiffDependency{{First: index["public.a"], Then: index["public.b"]}}, result.Dependencies())
+}
+
+func TestPinOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		first   string
+		then    string
+		wantErr string
+	}{
+		{
+			name:  "independent differences",
+			first: "public.c",
+			then:  "public.a",
+		},
+		{
+			name:  "agrees with a dependency",
+			first: "public.a",
+			then:  "public.b",
+		},
+		{
+			name:    "contradicts a dependency",
+			first:   "public.b",
+			then:    "public.a",
+			wantErr: "the pinned order conflicts with the dependencies between the changes",
+		},
+		{
+			name:    "itself",
+			first:   "public.a",
+			then:    "public.a",
+			wantErr: "a difference can't run after itself",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, index := orderingTestResult(t)
+
+			err := result.PinOrder(index[tt.first], index[tt.then])
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				assert.Empty(t, result.Differences[index[tt.then]].RunsAfter)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{result.Differences[index[tt.first]].Key()}, result.Differences[index[tt.then]].RunsAfter)
+		})
+	}
+}
+
+func TestPinOrderCycle(t *testing.T) {
+	result, index := orderingTestResult(t)
+
+	require.NoError(t, result.PinOrder(index["public.c"], index["public.a"]))
+	err := result.PinOrder(index["public.b"], index["public.c"])
+	require.ErrorContains(t, err, "the pinned order conflicts with the dependencies between the changes")
+	assert.Empty(t, result.Differences[index["public.c"]].RunsAfter)
+}
+
+func TestGenerateMigrationsFollowsPinnedOrder(t *testing.T) {
+	createdOrder := func(result *ComparisonResult) []string {
+		statements, _, err := result.GenerateMigrations(false)
+		require.NoError(t, err)
+		var tables []string
+		for _, stmt := range statements {
+			for _, name := range []string{"public.a", "public.b", "public.c"} {
+				if strings.HasPrefix(stmt, "CREATE TABLE "+name+" ") {
+					tables = append(tables, name)
+				}
+			}
+		}
+		return tables
+	}
+
+	result, index := orderingTestResult(t)
+	assert.Equal(t, []string{"public.a", "public.b", "public.c"}, createdOrder(result))
+
+	require.NoError(t, result.PinOrder(index["public.c"], index["public.a"]))
+	order := createdOrder(result)
+	assert.Less(t, slices.Index(order, "public.c"), slices.Index(order, "public.a"))
+	assert.Less(t, slices.Index(order, "public.a"), slices.Index(order, "public.b"))
+
+	// The pin still applies to a filtered copy of the differences
+	filtered := result.Filter(func(Difference) bool { return true })
+	assert.Equal(t, order, createdOrder(filtered))
+}
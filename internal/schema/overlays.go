@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// overlaysDir is the directory in a definitions directory holding per-environment
+// overrides, e.g. overlays/prod/*.sql
+const overlaysDir = "overlays"
+
+// SeedDir is the directory in a definitions directory holding seed data, which
+// isn't loaded as part of the schema
+const SeedDir = "seed"
+
+// TestsDir is the directory in a definitions directory holding the queries that
+// scurry test runs against the schema, which aren't loaded as part of the schema
+const TestsDir = "tests"
+
+// isStorageParamOverride returns true for ALTER TABLE statements that only set or
+// reset storage parameters, which overlays use to change settings like TTL:
+//
+//	ALTER TABLE events SET (ttl_expire_after = '90 days');
+func isStorageParamOverride(stmt tree.Statement) bool {
+	alter, ok := stmt.(*tree.AlterTable)
+	if !ok || len(alter.Cmds) == 0 {
+		return false
+	}
+	for _, cmd := range alter.Cmds {
+		switch cmd.(type) {
+		case *tree.AlterTableSetStorageParams:
+		case *tree.AlterTableResetStorageParams:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// overlayKey identifies the object a CREATE statement defines, or whose owner an
+// ALTER ... OWNER TO sets, so an overlay definition can replace the base
+// definition of the same object
+func overlayKey(stmt tree.Statement) string {
+	switch s := stmt.(type) {
+	case *tree.CreateSchema:
+		return "schema:" + s.Schema.Schema()
+	case *tree.CreateTable:
+		schemaName, tableName := getTableName(s.Table)
+		return "relation:" + schemaName + "." + tableName
+	case *tree.CreateSequence:
+		schemaName, seqName := getTableName(s.Name)
+		return "relation:" + schemaName + "." + seqName
+	case *tree.CreateView:
+		schemaName, viewName := getTableName(s.Name)
+		return "relation:" + schemaName + "." + viewName
+	case *tree.CreateType:
+		schemaName, typeName := getObjectName(s.TypeName)
+		return "type:" + schemaName + "." + typeName
+	case *tree.CreateRoutine:
+		return "routine:" + getRoutineSignature(s)
+	case *tree.CreateTrigger:
+		schemaName, tableName := getObjectName(s.TableName)
+		return "trigger:" + schemaName + "." + tableName + "." + s.Name.Normalize()
+	case *tree.AlterTableOwner:
+		return "owner:" + ownerName(s)
+	}
+	return ""
+}
+
+// applyOverlay merges overlay statements over base definitions. An overlay CREATE
+// replaces the base definition of the same object, or adds it if there is none,
+// and storage parameter overrides are applied to the base CREATE TABLE.
+func applyOverlay(base, overlay []tree.Statement) ([]tree.Statement, error) {
+	merged := make([]tree.Statement, len(base))
+	copy(merged, base)
+
+	indexByKey := make(map[string]int, len(merged))
+	for i, stmt := range merged {
+		indexByKey[overlayKey(stmt)] = i
+	}
+
+	for _, stmt := range overlay {
+		if alter, ok := stmt.(*tree.AlterTable); ok {
+			schemaName, tableName := getObjectName(alter.Table)
+			idx, exists := indexByKey["relation:"+schemaName+"."+tableName]
+			var table *tree.CreateTable
+			if exists {
+				table, _ = merged[idx].(*tree.CreateTable)
+			}
+			if table == nil {
+				return nil, fmt.Errorf("cannot override storage parameters of %s.%s: table is not defined", schemaName, tableName)
+			}
+			table.StorageParams = overrideStorageParams(table.StorageParams, alter.Cmds)
+			continue
+		}
+
+		key := overlayKey(stmt)
+		if idx, exists := indexByKey[key]; exists {
+			merged[idx] = stmt
+		} else {
+			indexByKey[key] = len(merged)
+			merged = append(merged, stmt)
+		}
+	}
+
+	return merged, nil
+}
+
+// overrideStorageParams applies SET and RESET commands to a table's storage parameters
+func overrideStorageParams(params tree.StorageParams, cmds tree.AlterTableCmds) tree.StorageParams {
+	result := make(tree.StorageParams, 0, len(params))
+	result = append(result, params...)
+
+	remove := func(key string) {
+		for i := range result {
+			if strings.EqualFold(result[i].Key, key) {
+				result = append(result[:i], result[i+1:]...)
+				return
+			}
+		}
+	}
+
+	for _, cmd := range cmds {
+		switch c := cmd.(type) {
+		case *tree.AlterTableSetStorageParams:
+			for _, param := range c.StorageParams {
+				remove(param.Key)
+				result = append(result, param)
+			}
+		case *tree.AlterTableResetStorageParams:
+			for _, key := range c.Params {
+				remove(key)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyOverlay(t *testing.T) {
+	tests := []struct {
+		name        string
+		base        []string
+		overlay     string
+		expected    []string
+		errContains string
+	}{
+		{
+			name:     "create replaces the base definition",
+			base:     []string{"CREATE TABLE t (id INT8 PRIMARY KEY)", "CREATE TABLE u (id INT8 PRIMARY KEY)"},
+			overlay:  "CREATE TABLE t (id INT8 PRIMARY KEY, n INT8);",
+			expected: []string{"CREATE TABLE t (id INT8 PRIMARY KEY, n INT8)", "CREATE TABLE u (id INT8 PRIMARY KEY)"},
+		},
+		{
+			name:     "create adds new objects",
+			base:     []string{"CREATE TABLE t (id INT8 PRIMARY KEY)"},
+			overlay:  "CREATE TABLE audit (id INT8 PRIMARY KEY);",
+			expected: []string{"CREATE TABLE t (id INT8 PRIMARY KEY)", "CREATE TABLE audit (id INT8 PRIMARY KEY)"},
+		},
+		{
+			name:     "storage parameters are overridden",
+			base:     []string{"CREATE TABLE events (id INT8 PRIMARY KEY) WITH (ttl_expire_after = '30 days', ttl_job_cron = '@daily')"},
+			overlay:  "ALTER TABLE events SET (ttl_expire_after = '90 days');",
+			expected: []string{"CREATE TABLE events (id INT8 PRIMARY KEY) WITH ('ttl_job_cron' = '@daily', 'ttl_expire_after' = '90 days')"},
+		},
+		{
+			name:     "storage parameters are reset",
+			base:     []string{"CREATE TABLE events (id INT8 PRIMARY KEY) WITH (ttl_expire_after = '30 days', ttl_job_cron = '@daily')"},
+			overlay:  "ALTER TABLE events RESET (ttl_job_cron);",
+			expected: []string{"CREATE TABLE events (id INT8 PRIMARY KEY) WITH ('ttl_expire_after' = '30 days')"},
+		},
+		{
+			name:        "storage parameters of an undefined table",
+			base:        []string{"CREATE TABLE t (id INT8 PRIMARY KEY)"},
+			overlay:     "ALTER TABLE events SET (ttl_expire_after = '90 days');",
+			errContains: "cannot override storage parameters of public.events: table is not defined",
+		},
+		{
+			name:        "other alter statements are rejected",
+			base:        []string{"CREATE TABLE t (id INT8 PRIMARY KEY)"},
+			overlay:     "ALTER TABLE t ADD COLUMN n INT8;",
+			errContains: "unsupported DDL statement",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlay, _, err := parseDefinitionSQL(tt.overlay, "overlays/prod/t.sql", true)
+			if err == nil {
+				var merged []string
+				result, applyErr := applyOverlay(parseStatements(tt.base...), overlay)
+				for _, stmt := range result {
+					merged = append(merged, stmt.String())
+				}
+				err = applyErr
+				if err == nil {
+					assert.Equal(t, tt.expected, merged)
+				}
+			}
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestParseDefinitionSQLRejectsOverridesOutsideOverlays(t *testing.T) {
+	_, _, err := parseDefinitionSQL("ALTER TABLE events SET (ttl_expire_after = '90 days');", "tables/events.sql", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported DDL statement")
+}
+
+func TestLoadDefinitionsWithOverlays(t *testing.T) {
+	files := map[string]string{
+		"defs/tables/events.sql":        "CREATE TABLE events (id INT8 PRIMARY KEY) WITH (ttl_expire_after = '30 days');",
+		"defs/overlays/prod/events.sql": "ALTER TABLE events SET (ttl_expire_after = '90 days');",
+		"defs/overlays/dev/events.sql":  "CREATE TABLE debug (id INT8 PRIMARY KEY);",
+		// Seed data isn't loaded as definitions
+		"defs/seed/events.sql": "INSERT INTO events (id) VALUES (1) ON CONFLICT DO NOTHING;",
+		// Neither are schema tests
+		"defs/tests/events.sql": "-- scurry:test events\nSELECT id FROM events;",
+	}
+
+	tests := []struct {
+		name     string
+		env      string
+		expected []string
+	}{
+		{
+			name:     "overlays are skipped without an env",
+			expected: []string{"CREATE TABLE events (id INT8 PRIMARY KEY) WITH ('ttl_expire_after' = '30 days')"},
+		},
+		{
+			name:     "selected overlay is merged",
+			env:      "prod",
+			expected: []string{"CREATE TABLE events (id INT8 PRIMARY KEY) WITH ('ttl_expire_after' = '90 days')"},
+		},
+		{
+			name:     "unknown env uses the base definitions",
+			env:      "staging",
+			expected: []string{"CREATE TABLE events (id INT8 PRIMARY KEY) WITH ('ttl_expire_after' = '30 days')"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, content := range files {
+				require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+			}
+
+			s, err := LoadDefinitions(fs, []string{"defs"}, tt.env)
+			require.NoError(t, err)
+			var tables []string
+			for _, table := range s.Tables {
+				tables = append(tables, table.Ast.String())
+			}
+			assert.Equal(t, tt.expected, tables)
+		})
+	}
+}
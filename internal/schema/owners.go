@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+// Owners are the owners of tables, views and sequences by "schema.name". Only
+// the owners set with ALTER ... OWNER TO in the definitions are managed, every
+// other object keeps whoever created it as its owner:
+//
+//	ALTER TABLE users OWNER TO app_service;
+type Owners map[string]ObjectOwner
+
+// ObjectOwner is the role owning an object
+type ObjectOwner struct {
+	Role string
+	// Stmt is the ALTER ... OWNER TO statement the owner was set with, if
+	// loaded from definitions
+	Stmt *tree.AlterTableOwner
+}
+
+// LoadOwners reads the owners of the objects in the database the client is
+// connected to
+func LoadOwners(ctx context.Context, dbClient *db.Client) (Owners, error) {
+	roles, err := dbClient.GetOwners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(Owners, len(roles))
+	for name, role := range roles {
+		owners[name] = ObjectOwner{Role: role}
+	}
+	return owners, nil
+}
+
+// ownerName returns the "schema.name" of the object an ALTER ... OWNER TO changes
+func ownerName(stmt *tree.AlterTableOwner) string {
+	schemaName, objectName := getObjectName(stmt.Name)
+	return schemaName + "." + objectName
+}
+
+// checkOwners makes sure every owner in the definitions is set on an object
+// the definitions create
+func (s *Schema) checkOwners() error {
+	for _, name := range slices.Sorted(maps.Keys(s.Owners)) {
+		owner := s.Owners[name]
+		if _, ok := s.ownedObject(name); !ok {
+			return fmt.Errorf("%s: %s is not a table, view or sequence in the definitions", owner.Stmt, name)
+		}
+	}
+	return nil
+}
+
+// ownedObject returns an ALTER ... OWNER TO for the table, view or sequence
+// with the given name, without the owner
+func (s *Schema) ownedObject(name string) (*tree.AlterTableOwner, bool) {
+	for _, table := range s.Tables {
+		if table.ResolvedName() == name {
+			return &tree.AlterTableOwner{Name: table.Ast.Table.ToUnresolvedObjectName()}, true
+		}
+	}
+	for _, view := range s.Views {
+		if view.ResolvedName() == name {
+			return &tree.AlterTableOwner{Name: view.Ast.Name.ToUnresolvedObjectName(), IsView: true, IsMaterialized: view.Ast.Materialized}, true
+		}
+	}
+	for _, sequence := range s.Sequences {
+		if sequence.ResolvedName() == name {
+			return &tree.AlterTableOwner{Name: sequence.Ast.Name.ToUnresolvedObjectName(), IsSequence: true}, true
+		}
+	}
+	return nil, false
+}
+
+// compareOwners finds the objects in the definitions owned by a different role
+// in the database, when the definitions set owners and the remote owners were
+// loaded. Objects created or recreated by the other differences are owned by
+// whoever runs the migration, so their owners are always set.
+func compareOwners(local, remote *Schema, diffs []Difference) []Difference {
+	if len(local.Owners) == 0 || remote.Owners == nil {
+		return nil
+	}
+
+	recreated := make(map[string]bool)
+	for _, diff := range diffs {
+		if dropsObject(diff) {
+			recreated[diff.ObjectName] = true
+		}
+	}
+
+	var ownerDiffs []Difference
+	for _, name := range slices.Sorted(maps.Keys(local.Owners)) {
+		role := local.Owners[name].Role
+		current, ok := remote.Owners[name]
+		if ok && current.Role == role && !recreated[name] {
+			continue
+		}
+		stmt, ok := local.ownedObject(name)
+		if !ok {
+			continue
+		}
+		stmt.Owner = tree.MakeRoleSpecWithRoleName(role)
+
+		description := fmt.Sprintf("Owner of '%s' set to %s", name, role)
+		if current.Role != "" && !recreated[name] {
+			description = fmt.Sprintf("Owner of '%s' changed from %s to %s", name, current.Role, role)
+		}
+		ownerDiffs = append(ownerDiffs, Difference{
+			Type:        DiffTypeOwnerModified,
+			ObjectName:  name,
+			Description: description,
+			Phases:      inOnePhase(stmt),
+		})
+	}
+	return ownerDiffs
+}
+
+// dropsObject returns true if the difference drops the table, view or sequence
+// itself, rather than one of its columns or indexes
+func dropsObject(diff Difference) bool {
+	for _, stmt := range diff.Statements() {
+		switch stmt.(type) {
+		case *tree.DropTable, *tree.DropView, *tree.DropSequence:
+			return true
+		}
+	}
+	return false
+}
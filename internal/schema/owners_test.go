@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefinitionsOwners(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		env      string
+		expected map[string]string
+		wantErr  string
+	}{
+		{
+			name: "owners",
+			files: map[string]string{
+				"defs/users.sql": "CREATE TABLE users (id INT8 PRIMARY KEY);\nALTER TABLE users OWNER TO app_service;",
+				"defs/views.sql": "CREATE VIEW reporting.active AS SELECT 1;\nALTER VIEW reporting.active OWNER TO \"Reporter\";",
+				"defs/seq.sql":   "CREATE SEQUENCE ids;\nALTER SEQUENCE ids OWNER TO app_service;",
+			},
+			expected: map[string]string{"public.users": "app_service", "reporting.active": "Reporter", "public.ids": "app_service"},
+		},
+		{
+			name: "overlay",
+			files: map[string]string{
+				"defs/users.sql":               "CREATE TABLE users (id INT8 PRIMARY KEY);\nALTER TABLE users OWNER TO app_service;",
+				"defs/overlays/prod/users.sql": "ALTER TABLE users OWNER TO prod_service;",
+			},
+			env:      "prod",
+			expected: map[string]string{"public.users": "prod_service"},
+		},
+		{
+			name: "current user",
+			files: map[string]string{
+				"defs/users.sql": "CREATE TABLE users (id INT8 PRIMARY KEY);\nALTER TABLE users OWNER TO CURRENT_USER;",
+			},
+			wantErr: "OWNER TO needs a role name",
+		},
+		{
+			name: "undefined table",
+			files: map[string]string{
+				"defs/users.sql": "ALTER TABLE users OWNER TO app_service;",
+			},
+			wantErr: "public.users is not a table, view or sequence in the definitions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, content := range tt.files {
+				require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+			}
+
+			s, err := LoadDefinitions(fs, []string{"defs"}, tt.env)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			owners := make(map[string]string)
+			for name, owner := range s.Owners {
+				owners[name] = owner.Role
+			}
+			assert.Equal(t, tt.expected, owners)
+		})
+	}
+}
+
+func TestCompareOwners(t *testing.T) {
+	users := "CREATE TABLE public.users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC))"
+	ids := "CREATE SEQUENCE public.ids MINVALUE 1 MAXVALUE 9223372036854775807 INCREMENT 1 START 1"
+	active := "CREATE MATERIALIZED VIEW public.active AS SELECT 1"
+
+	tests := []struct {
+		name        string
+		owners      map[string]string
+		remote      []string
+		remoteOwner map[string]string
+		expected    []string
+		wantDesc    []string
+	}{
+		{
+			name:        "same owners",
+			owners:      map[string]string{"public.users": "app_service"},
+			remote:      []string{users, ids, active},
+			remoteOwner: map[string]string{"public.users": "app_service", "public.ids": "root", "public.active": "root"},
+		},
+		{
+			name:        "owners not loaded",
+			owners:      map[string]string{"public.users": "app_service"},
+			remote:      []string{users, ids, active},
+			remoteOwner: nil,
+		},
+		{
+			name:        "changed owners",
+			owners:      map[string]string{"public.users": "app_service", "public.ids": "app_service", "public.active": "reporter"},
+			remote:      []string{users, ids, active},
+			remoteOwner: map[string]string{"public.users": "root", "public.ids": "root", "public.active": "root"},
+			expected: []string{
+				"ALTER MATERIALIZED VIEW public.active OWNER TO reporter",
+				"ALTER SEQUENCE public.ids OWNER TO app_service",
+				"ALTER TABLE public.users OWNER TO app_service",
+			},
+			wantDesc: []string{
+				"Owner of 'public.active' changed from root to reporter",
+				"Owner of 'public.ids' changed from root to app_service",
+				"Owner of 'public.users' changed from root to app_service",
+			},
+		},
+		{
+			name:        "recreated sequence",
+			owners:      map[string]string{"public.ids": "app_service"},
+			remote:      []string{users, "CREATE SEQUENCE public.ids MINVALUE 1 MAXVALUE 9223372036854775807 INCREMENT 1 START 1 VIRTUAL", active},
+			remoteOwner: map[string]string{"public.users": "root", "public.ids": "app_service", "public.active": "root"},
+			expected:    []string{"ALTER SEQUENCE public.ids OWNER TO app_service"},
+			wantDesc:    []string{"Owner of 'public.ids' set to app_service"},
+		},
+		{
+			name:        "new table",
+			owners:      map[string]string{"public.users": "app_service"},
+			remote:      []string{ids, active},
+			remoteOwner: map[string]string{"public.ids": "root", "public.active": "root"},
+			expected:    []string{"ALTER TABLE public.users OWNER TO app_service"},
+			wantDesc:    []string{"Owner of 'public.users' set to app_service"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localStatements, err := ParseSQL(users + ";" + ids + ";" + active)
+			require.NoError(t, err)
+			local := NewSchema(localStatements...)
+			local.Owners = make(Owners)
+			for name, role := range tt.owners {
+				local.Owners[name] = ObjectOwner{Role: role}
+			}
+
+			remoteStatements, err := ParseSQL(strings.Join(tt.remote, ";"))
+			require.NoError(t, err)
+			remote := NewSchema(remoteStatements...)
+			if tt.remoteOwner != nil {
+				remote.Owners = make(Owners)
+				for name, role := range tt.remoteOwner {
+					remote.Owners[name] = ObjectOwner{Role: role}
+				}
+			}
+
+			var statements, descriptions []string
+			for _, diff := range Compare(local, remote).Differences {
+				if diff.Type != DiffTypeOwnerModified {
+					continue
+				}
+				descriptions = append(descriptions, diff.Description)
+				for _, stmt := range diff.Statements() {
+					statements = append(statements, stmt.String())
+				}
+			}
+			assert.Equal(t, tt.expected, statements)
+			assert.Equal(t, tt.wantDesc, descriptions)
+		})
+	}
+}
+
+func TestOwnerOfNewTableRunsAfterCreate(t *testing.T) {
+	local := createSchemaWithTypesAndTables(nil, []string{"CREATE TABLE public.users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC))"})
+	local.Owners = Owners{"public.users": {Role: "app_service"}}
+	remote := createSchemaWithTypesAndTables(nil, nil)
+	remote.Owners = Owners{}
+
+	statements, _, err := Compare(local, remote).GenerateMigrations(false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"CREATE TABLE public.users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC))",
+		"ALTER TABLE public.users OWNER TO app_service",
+	}, statements)
+}
@@ -97,6 +97,11 @@ func GetProvidedNames(stmt tree.Statement, strict bool) set.Set[string] {
 					colName := c.ColumnDef.Name.Normalize()
 					names.Add(schemaName + "." + tableName + "." + colName)
 
+				case *tree.AlterTableRenameColumn:
+					// Renaming a column provides the new column name
+					colName := c.NewName.Normalize()
+					names.Add(schemaName + "." + tableName + "." + colName)
+
 				case *tree.AlterTableAlterColumnType:
 					// Altering a column's type provides the column name as well
 					colName := c.Column.Normalize()
@@ -118,6 +123,7 @@ func GetProvidedNames(stmt tree.Statement, strict bool) set.Set[string] {
 				case *tree.AlterTableDropStored:
 				case *tree.AlterTableSetNotNull:
 				case *tree.AlterTableDropConstraint:
+				case *tree.AlterTableValidateConstraint:
 				case *tree.AlterTableSetVisible:
 				case *tree.AlterTableSetOnUpdate:
 				case *tree.AlterTableAlterPrimaryKey:
@@ -169,16 +175,42 @@ func GetProvidedNames(stmt tree.Statement, strict bool) set.Set[string] {
 			}
 		}
 
+	case *tree.RenameTable:
+		{
+			// Advertise the new name so statements against the renamed table order after it.
+			schemaName, tableName := getObjectName(s.NewName)
+			names.Add(schemaName + "." + tableName)
+			if schemaName == "public" {
+				names.Add(tableName)
+			}
+		}
+
 	// These are possible statements we could encounter, but don't provide anything.
+	case *tree.CreateTrigger:
+	case *tree.DropTrigger:
+	case *tree.AlterSequence:
 	case *tree.DropRoutine:
 	case *tree.DropTable:
 	case *tree.DropSequence:
 	case *tree.DropType:
 	case *tree.DropView:
 	case *tree.DropIndex:
+	case *tree.AlterIndexVisible:
+	case *tree.AlterTableOwner:
 	case *tree.BeginTransaction:
 	case *tree.CommitTransaction:
 	case *tree.DropSchema:
+	case *tree.Update:
+	case *tree.SetVar:
+
+	// Settings of the database and cluster don't depend on or provide any objects
+	case *tree.AlterRoleSet:
+	case *tree.AlterDatabasePrimaryRegion:
+	case *tree.AlterDatabaseAddRegion:
+	case *tree.AlterDatabaseDropRegion:
+	case *tree.AlterDatabaseSurvivalGoal:
+	case *tree.AlterDatabasePlacement:
+	case *tree.SetClusterSetting:
 	default:
 		if strict {
 			panic(fmt.Sprintf("unexpected statement type: %s", stmt.StatementTag()))
@@ -0,0 +1,492 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+const (
+	renamedFromPrefix  = "-- scurry:renamed-from="
+	renamedValuePrefix = "-- scurry:renamed-value="
+)
+
+// RenameHints records renames declared with -- scurry:renamed-from directives in
+// definition files, keyed by the object's new name.
+type RenameHints struct {
+	Tables  map[string]string            // "schema.new_table" -> "schema.old_table"
+	Columns map[string]map[string]string // "schema.table" -> new column -> old column
+	Values  map[string]map[string]string // "schema.type" -> new enum value -> old enum value
+}
+
+func newRenameHints() RenameHints {
+	return RenameHints{
+		Tables:  make(map[string]string),
+		Columns: make(map[string]map[string]string),
+		Values:  make(map[string]map[string]string),
+	}
+}
+
+func (h RenameHints) merge(other RenameHints) {
+	for newName, oldName := range other.Tables {
+		h.Tables[newName] = oldName
+	}
+	for table, cols := range other.Columns {
+		if h.Columns[table] == nil {
+			h.Columns[table] = make(map[string]string)
+		}
+		for newCol, oldCol := range cols {
+			h.Columns[table][newCol] = oldCol
+		}
+	}
+	for typeName, values := range other.Values {
+		if h.Values[typeName] == nil {
+			h.Values[typeName] = make(map[string]string)
+		}
+		for newVal, oldVal := range values {
+			h.Values[typeName][newVal] = oldVal
+		}
+	}
+}
+
+// parseRenameDirectives finds -- scurry:renamed-from and -- scurry:renamed-value
+// directives in a definition file. A renamed-from directive in the comments
+// directly above a CREATE TABLE renames the table; one at the end of a column's
+// line renames that column. A renamed-value directive above a CREATE TYPE renames
+// one of the enum's values:
+//
+//	-- scurry:renamed-from=people
+//	CREATE TABLE users (
+//	    id INT PRIMARY KEY,
+//	    email TEXT -- scurry:renamed-from=mail
+//	);
+//
+//	-- scurry:renamed-value=inactive:disabled
+//	CREATE TYPE status AS ENUM ('active', 'disabled');
+func parseRenameDirectives(sql string) RenameHints {
+	hints := newRenameHints()
+	if !strings.Contains(sql, renamedFromPrefix) && !strings.Contains(sql, renamedValuePrefix) {
+		return hints
+	}
+
+	for _, def := range parseTableDefinitions(sql) {
+		for _, oldName := range renamedFromValues(def.preceding) {
+			if !strings.Contains(oldName, ".") {
+				oldName = def.schema + "." + oldName
+			}
+			hints.Tables[def.qualified] = oldName
+		}
+
+		for colName, value := range def.columnDirectives(renamedFromPrefix) {
+			if hints.Columns[def.qualified] == nil {
+				hints.Columns[def.qualified] = make(map[string]string)
+			}
+			hints.Columns[def.qualified][colName] = firstWord(value)
+		}
+	}
+
+	for _, stmt := range parseDefinitionStatements(sql) {
+		ct, ok := stmt.ast.(*tree.CreateType)
+		if !ok || ct.Variety != tree.Enum {
+			continue
+		}
+		schemaName, typeName := getObjectName(ct.TypeName)
+		for oldVal, newVal := range enumValueDirectives(stmt.preceding, renamedValuePrefix) {
+			qualified := schemaName + "." + typeName
+			if hints.Values[qualified] == nil {
+				hints.Values[qualified] = make(map[string]string)
+			}
+			hints.Values[qualified][newVal] = oldVal
+		}
+	}
+
+	return hints
+}
+
+// enumValueDirectives returns old -> new enum values given by any directives
+// with the given prefix in text, written as old:new. Enum values are case
+// sensitive, so they are kept as written.
+func enumValueDirectives(text, prefix string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		idx := strings.Index(line, prefix)
+		if idx == -1 {
+			continue
+		}
+		fields := strings.Fields(line[idx+len(prefix):])
+		if len(fields) == 0 {
+			continue
+		}
+		oldVal, newVal, ok := strings.Cut(fields[0], ":")
+		if ok && oldVal != "" && newVal != "" {
+			values[oldVal] = newVal
+		}
+	}
+	return values
+}
+
+// renamedFromValues returns the names given by any renamed-from directives in text
+func renamedFromValues(text string) []string {
+	var values []string
+	for _, line := range strings.Split(text, "\n") {
+		idx := strings.Index(line, renamedFromPrefix)
+		if idx == -1 {
+			continue
+		}
+		if value := firstWord(line[idx+len(renamedFromPrefix):]); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// firstWord strips inline comments from a directive value and normalizes it:
+// "People -- merged into users" → "people"
+func firstWord(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// tableRename is a table present only in remote that became a table present only in local
+type tableRename struct {
+	from     ObjectSchema[*tree.CreateTable] // present only in remote
+	to       ObjectSchema[*tree.CreateTable] // present only in local
+	inferred bool                            // guessed rather than declared by a directive
+}
+
+// detectTableRenames pairs dropped and added tables as renames. A renamed-from
+// directive always wins; otherwise a rename is only inferred when a dropped and
+// an added table in the same schema have identical columns (at least two), and no
+// other dropped or added table shares those columns. A directive naming a table
+// in another schema can't be applied, since RENAME doesn't move tables between
+// schemas, so it is returned as a blocking difference.
+func detectTableRenames(local, remote *Schema) ([]tableRename, []Difference) {
+	localTables := make(map[string]ObjectSchema[*tree.CreateTable])
+	remoteTables := make(map[string]ObjectSchema[*tree.CreateTable])
+	for _, t := range local.Tables {
+		localTables[t.ResolvedName()] = t
+	}
+	for _, t := range remote.Tables {
+		remoteTables[t.ResolvedName()] = t
+	}
+
+	var renames []tableRename
+	var blocking []Difference
+	paired := make(map[string]bool)
+
+	for newName, oldName := range local.Renames.Tables {
+		to, inLocal := localTables[newName]
+		from, inRemote := remoteTables[oldName]
+		if !inLocal || !inRemote {
+			continue
+		}
+		if _, stillLocal := localTables[oldName]; stillLocal {
+			continue
+		}
+		if _, alreadyRemote := remoteTables[newName]; alreadyRemote {
+			continue
+		}
+		if from.Schema != to.Schema {
+			blocking = append(blocking, Difference{
+				Type:        DiffTypeTableRenamed,
+				ObjectName:  newName,
+				Description: fmt.Sprintf("Table '%s' renamed to '%s'", oldName, newName),
+				BlockingError: fmt.Sprintf(
+					"Table '%s' is declared as renamed from '%s', but RENAME can't move a table to another schema. Rename it within schema '%s' and move it with ALTER TABLE ... SET SCHEMA in a migration, or remove the directive.",
+					newName, oldName, from.Schema,
+				),
+			})
+			paired[oldName] = true
+			paired[newName] = true
+			continue
+		}
+		renames = append(renames, tableRename{from: from, to: to})
+		paired[oldName] = true
+		paired[newName] = true
+	}
+
+	type bucket struct {
+		olds []ObjectSchema[*tree.CreateTable]
+		news []ObjectSchema[*tree.CreateTable]
+	}
+	buckets := make(map[string]*bucket)
+	bucketFor := func(sig string) *bucket {
+		if buckets[sig] == nil {
+			buckets[sig] = &bucket{}
+		}
+		return buckets[sig]
+	}
+
+	// A table with a single column, usually just an id, says too little about
+	// where it came from to guess
+	for name, t := range remoteTables {
+		if len(tableColumns(t.Ast)) < 2 {
+			continue
+		}
+		if _, inLocal := localTables[name]; !inLocal && !paired[name] {
+			b := bucketFor(t.Schema + ":" + tableColumnSignature(t.Ast))
+			b.olds = append(b.olds, t)
+		}
+	}
+	for name, t := range localTables {
+		if _, inRemote := remoteTables[name]; !inRemote && !paired[name] {
+			b := bucketFor(t.Schema + ":" + tableColumnSignature(t.Ast))
+			b.news = append(b.news, t)
+		}
+	}
+
+	for _, b := range buckets {
+		if len(b.olds) != 1 || len(b.news) != 1 {
+			continue
+		}
+		renames = append(renames, tableRename{from: b.olds[0], to: b.news[0], inferred: true})
+	}
+
+	sort.Slice(renames, func(i, j int) bool { return renames[i].from.ResolvedName() < renames[j].from.ResolvedName() })
+	sort.Slice(blocking, func(i, j int) bool { return blocking[i].ObjectName < blocking[j].ObjectName })
+	return renames, blocking
+}
+
+// tableColumnSignature is the ordered list of a table's column definitions
+func tableColumnSignature(ct *tree.CreateTable) string {
+	var parts []string
+	for _, def := range ct.Defs {
+		if col, ok := def.(*tree.ColumnTableDef); ok {
+			parts = append(parts, tree.AsString(col))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// buildTableRenameDiff emits `ALTER TABLE from RENAME TO to`. An inferred rename
+// carries a warning, since the tables may only look alike.
+func buildTableRenameDiff(r tableRename) Difference {
+	diff := Difference{
+		Type:        DiffTypeTableRenamed,
+		ObjectName:  r.to.ResolvedName(),
+		Description: fmt.Sprintf("Table '%s' renamed to '%s'", r.from.ResolvedName(), r.to.ResolvedName()),
+		Phases: inOnePhase(
+			&tree.RenameTable{
+				Name:    r.from.Ast.Table.ToUnresolvedObjectName(),
+				NewName: r.to.Ast.Table.ToUnresolvedObjectName(),
+			},
+		),
+	}
+	if r.inferred {
+		diff.InferredRename = true
+		diff.WarningMessage = fmt.Sprintf("Table '%s' is assumed to be renamed to '%s' because their columns match. Add %s%s above the table to confirm it.",
+			r.from.ResolvedName(), r.to.ResolvedName(), renamedFromPrefix, r.from.Name)
+	}
+	return diff
+}
+
+// detectColumnRenames returns old -> new column names for columns renamed within a
+// table, and the old names of those that were inferred. A renamed-from directive
+// always wins; otherwise a rename is only inferred when the only dropped and the
+// only added column have the same position in the table and an identical
+// definition apart from the name.
+func detectColumnRenames(local, remote *tree.CreateTable, hints map[string]string) (map[string]string, map[string]bool) {
+	localCols := tableColumns(local)
+	remoteCols := tableColumns(remote)
+
+	localIndex := make(map[string]int)
+	for i, col := range localCols {
+		localIndex[col.Name.Normalize()] = i
+	}
+	remoteIndex := make(map[string]int)
+	for i, col := range remoteCols {
+		remoteIndex[col.Name.Normalize()] = i
+	}
+
+	renames := make(map[string]string)
+	inferred := make(map[string]bool)
+	paired := make(map[string]bool)
+
+	for newName, oldName := range hints {
+		_, inLocal := localIndex[newName]
+		_, inRemote := remoteIndex[oldName]
+		_, stillLocal := localIndex[oldName]
+		_, alreadyRemote := remoteIndex[newName]
+		if inLocal && inRemote && !stillLocal && !alreadyRemote {
+			renames[oldName] = newName
+			paired[oldName] = true
+			paired[newName] = true
+		}
+	}
+
+	// Without a directive, only guess when exactly one column was dropped and one
+	// added, so tables with several new columns keep their drop and add.
+	var dropped, added []int
+	for i, col := range remoteCols {
+		if _, inLocal := localIndex[col.Name.Normalize()]; !inLocal && !paired[col.Name.Normalize()] {
+			dropped = append(dropped, i)
+		}
+	}
+	for i, col := range localCols {
+		if _, inRemote := remoteIndex[col.Name.Normalize()]; !inRemote && !paired[col.Name.Normalize()] {
+			added = append(added, i)
+		}
+	}
+	if len(dropped) == 1 && len(added) == 1 && dropped[0] == added[0] {
+		remoteCol, localCol := remoteCols[dropped[0]], localCols[added[0]]
+		if unnamedColumnSignature(localCol) == unnamedColumnSignature(remoteCol) {
+			renames[remoteCol.Name.Normalize()] = localCol.Name.Normalize()
+			inferred[remoteCol.Name.Normalize()] = true
+		}
+	}
+
+	return renames, inferred
+}
+
+func tableColumns(ct *tree.CreateTable) []*tree.ColumnTableDef {
+	var cols []*tree.ColumnTableDef
+	for _, def := range ct.Defs {
+		if col, ok := def.(*tree.ColumnTableDef); ok {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// unnamedColumnSignature formats a column definition without its name
+func unnamedColumnSignature(col *tree.ColumnTableDef) string {
+	unnamed := *col
+	unnamed.Name = ""
+	return tree.AsString(&unnamed)
+}
+
+// buildColumnRenameDiffs emits one `ALTER TABLE t RENAME COLUMN old TO new` per
+// rename, with a warning on those that were inferred
+func buildColumnRenameDiffs(tableName string, tableRef tree.TableName, renames map[string]string, inferred map[string]bool) []Difference {
+	oldNames := make([]string, 0, len(renames))
+	for oldName := range renames {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
+
+	diffs := make([]Difference, 0, len(renames))
+	for _, oldName := range oldNames {
+		newName := renames[oldName]
+		diff := Difference{
+			Type:        DiffTypeColumnRenamed,
+			ObjectName:  tableName,
+			Description: fmt.Sprintf("Column '%s.%s' renamed to '%s'", tableName, oldName, newName),
+			Phases: inOnePhase(
+				&tree.AlterTable{
+					Table: tableRef.ToUnresolvedObjectName(),
+					Cmds: tree.AlterTableCmds{
+						&tree.AlterTableRenameColumn{
+							Column:  tree.Name(oldName),
+							NewName: tree.Name(newName),
+						},
+					},
+				},
+			),
+		}
+		if inferred[oldName] {
+			diff.InferredRename = true
+			diff.WarningMessage = fmt.Sprintf("Column '%s.%s' is assumed to be renamed to '%s' because their definitions match. Add %s%s to the column's line to confirm it.",
+				tableName, oldName, newName, renamedFromPrefix, oldName)
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// renameTableColumns returns a copy of the table with columns renamed, including
+// every index, constraint, family and expression that references them, so the
+// renamed table can be compared against the local definition as-is.
+func renameTableColumns(ct *tree.CreateTable, renames map[string]string) (*tree.CreateTable, error) {
+	statements, err := parser.Parse(ct.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy table %s: %w", ct.Table.String(), err)
+	}
+	renamed, ok := statements[0].AST.(*tree.CreateTable)
+	if !ok {
+		return nil, fmt.Errorf("failed to copy table %s", ct.Table.String())
+	}
+	renamed.HoistConstraints()
+
+	rename := func(name tree.Name) tree.Name {
+		if newName, ok := renames[name.Normalize()]; ok {
+			return tree.Name(newName)
+		}
+		return name
+	}
+	renameList := func(names tree.NameList) {
+		for i := range names {
+			names[i] = rename(names[i])
+		}
+	}
+	v := &columnRenameVisitor{renames: renames}
+	renameExpr := func(expr tree.Expr) tree.Expr {
+		if expr == nil {
+			return nil
+		}
+		newExpr, _ := tree.WalkExpr(v, expr)
+		return newExpr
+	}
+	renameIndex := func(idx *tree.IndexTableDef) {
+		for i := range idx.Columns {
+			idx.Columns[i].Column = rename(idx.Columns[i].Column)
+			idx.Columns[i].Expr = renameExpr(idx.Columns[i].Expr)
+		}
+		renameList(idx.Storing)
+		idx.Predicate = renameExpr(idx.Predicate)
+	}
+
+	for _, def := range renamed.Defs {
+		switch d := def.(type) {
+		case *tree.ColumnTableDef:
+			d.Name = rename(d.Name)
+			d.DefaultExpr.Expr = renameExpr(d.DefaultExpr.Expr)
+			d.OnUpdateExpr.Expr = renameExpr(d.OnUpdateExpr.Expr)
+			d.Computed.Expr = renameExpr(d.Computed.Expr)
+		case *tree.IndexTableDef:
+			renameIndex(d)
+		case *tree.UniqueConstraintTableDef:
+			renameIndex(&d.IndexTableDef)
+		case *tree.ForeignKeyConstraintTableDef:
+			renameList(d.FromCols)
+		case *tree.CheckConstraintTableDef:
+			d.Expr = renameExpr(d.Expr)
+		case *tree.FamilyTableDef:
+			renameList(d.Columns)
+		}
+	}
+
+	return renamed, nil
+}
+
+// columnRenameVisitor rewrites unqualified column references in an expression
+type columnRenameVisitor struct {
+	renames map[string]string
+}
+
+func (v *columnRenameVisitor) VisitPre(expr tree.Expr) (bool, tree.Expr) {
+	switch e := expr.(type) {
+	case *tree.UnresolvedName:
+		if e.NumParts == 1 {
+			if newName, ok := v.renames[tree.Name(e.Parts[0]).Normalize()]; ok {
+				return false, tree.NewUnresolvedName(newName)
+			}
+		}
+	case *tree.ColumnItem:
+		if newName, ok := v.renames[e.ColumnName.Normalize()]; ok {
+			renamed := *e
+			renamed.ColumnName = tree.Name(newName)
+			return false, &renamed
+		}
+	}
+	return true, expr
+}
+
+func (v *columnRenameVisitor) VisitPost(expr tree.Expr) tree.Expr {
+	return expr
+}
@@ -0,0 +1,240 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRenameDirectives(t *testing.T) {
+	tests := []struct {
+		name            string
+		sql             string
+		expectedTables  map[string]string
+		expectedColumns map[string]map[string]string
+	}{
+		{
+			name:            "no directives",
+			sql:             "CREATE TABLE users (id INT PRIMARY KEY);",
+			expectedTables:  map[string]string{},
+			expectedColumns: map[string]map[string]string{},
+		},
+		{
+			name:            "table rename",
+			sql:             "-- scurry:renamed-from=people\nCREATE TABLE users (id INT PRIMARY KEY);",
+			expectedTables:  map[string]string{"public.users": "public.people"},
+			expectedColumns: map[string]map[string]string{},
+		},
+		{
+			name:            "table rename with schema and inline comment",
+			sql:             "-- scurry:renamed-from=app.People -- merged\nCREATE TABLE app.users (id INT PRIMARY KEY);",
+			expectedTables:  map[string]string{"app.users": "app.people"},
+			expectedColumns: map[string]map[string]string{},
+		},
+		{
+			name:           "column rename",
+			sql:            "CREATE TABLE users (\n  id INT PRIMARY KEY,\n  email TEXT -- scurry:renamed-from=mail\n);",
+			expectedTables: map[string]string{},
+			expectedColumns: map[string]map[string]string{
+				"public.users": {"email": "mail"},
+			},
+		},
+		{
+			name:            "directive applies only to the following table",
+			sql:             "CREATE TABLE a (id INT PRIMARY KEY);\n-- scurry:renamed-from=old_b\nCREATE TABLE b (id INT PRIMARY KEY);",
+			expectedTables:  map[string]string{"public.b": "public.old_b"},
+			expectedColumns: map[string]map[string]string{},
+		},
+		{
+			name:            "directive on a non-column line is ignored",
+			sql:             "CREATE TABLE users (\n  id INT,\n  CONSTRAINT users_pkey PRIMARY KEY (id) -- scurry:renamed-from=pk\n);",
+			expectedTables:  map[string]string{},
+			expectedColumns: map[string]map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := parseRenameDirectives(tt.sql)
+			assert.Equal(t, tt.expectedTables, hints.Tables)
+			assert.Equal(t, tt.expectedColumns, hints.Columns)
+		})
+	}
+}
+
+func TestParseRenamedValueDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected map[string]map[string]string
+	}{
+		{
+			name:     "no directives",
+			sql:      "CREATE TYPE status AS ENUM ('active');",
+			expected: map[string]map[string]string{},
+		},
+		{
+			name:     "value rename keeps case",
+			sql:      "-- scurry:renamed-value=Inactive:Disabled\nCREATE TYPE app.status AS ENUM ('active', 'Disabled');",
+			expected: map[string]map[string]string{"app.status": {"Disabled": "Inactive"}},
+		},
+		{
+			name: "several renames with an inline comment",
+			sql:  "-- scurry:renamed-value=inactive:disabled -- clearer\n-- scurry:renamed-value=new:pending\nCREATE TYPE status AS ENUM ('disabled', 'pending');",
+			expected: map[string]map[string]string{
+				"public.status": {"disabled": "inactive", "pending": "new"},
+			},
+		},
+		{
+			name:     "directive applies only to the following type",
+			sql:      "CREATE TYPE a AS ENUM ('x');\n-- scurry:renamed-value=y:z\nCREATE TYPE b AS ENUM ('z');",
+			expected: map[string]map[string]string{"public.b": {"z": "y"}},
+		},
+		{
+			name:     "malformed directive is ignored",
+			sql:      "-- scurry:renamed-value=inactive\nCREATE TYPE status AS ENUM ('disabled');",
+			expected: map[string]map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := parseRenameDirectives(tt.sql)
+			assert.Equal(t, tt.expected, hints.Values)
+		})
+	}
+}
+
+func TestCompareDetectsRenames(t *testing.T) {
+	tests := []struct {
+		name        string
+		local       []string
+		remote      []string
+		renames     RenameHints
+		contains    []string
+		notContains []string
+		inferred    bool
+		blocked     string
+	}{
+		{
+			name:        "table with identical columns is renamed",
+			local:       []string{`CREATE TABLE accounts (id INT8 NOT NULL, name STRING NULL, CONSTRAINT accounts_pkey PRIMARY KEY (id))`},
+			remote:      []string{`CREATE TABLE users (id INT8 NOT NULL, name STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id))`},
+			contains:    []string{"ALTER TABLE users RENAME TO accounts"},
+			notContains: []string{"DROP TABLE", "CREATE TABLE"},
+			inferred:    true,
+		},
+		{
+			name:        "tables with different columns are not renamed",
+			local:       []string{`CREATE TABLE accounts (id INT8 NOT NULL, CONSTRAINT accounts_pkey PRIMARY KEY (id))`},
+			remote:      []string{`CREATE TABLE users (id INT8 NOT NULL, name STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id))`},
+			contains:    []string{"DROP TABLE", "CREATE TABLE"},
+			notContains: []string{"RENAME"},
+		},
+		{
+			name: "ambiguous table renames are not guessed",
+			local: []string{
+				`CREATE TABLE c (id INT8 NOT NULL, n INT8 NULL, CONSTRAINT c_pkey PRIMARY KEY (id))`,
+				`CREATE TABLE d (id INT8 NOT NULL, n INT8 NULL, CONSTRAINT d_pkey PRIMARY KEY (id))`,
+			},
+			remote: []string{
+				`CREATE TABLE a (id INT8 NOT NULL, n INT8 NULL, CONSTRAINT a_pkey PRIMARY KEY (id))`,
+				`CREATE TABLE b (id INT8 NOT NULL, n INT8 NULL, CONSTRAINT b_pkey PRIMARY KEY (id))`,
+			},
+			notContains: []string{"RENAME"},
+		},
+		{
+			name:        "single column tables are not guessed",
+			local:       []string{`CREATE TABLE posts (id INT8 NOT NULL, CONSTRAINT posts_pkey PRIMARY KEY (id))`},
+			remote:      []string{`CREATE TABLE comments (id INT8 NOT NULL, CONSTRAINT comments_pkey PRIMARY KEY (id))`},
+			notContains: []string{"RENAME"},
+		},
+		{
+			name:        "several dropped and added columns are not guessed",
+			local:       []string{`CREATE TABLE t (id INT8 NOT NULL, email STRING NULL, age INT8 NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`},
+			remote:      []string{`CREATE TABLE t (id INT8 NOT NULL, name STRING NULL, phone INT8 NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`},
+			notContains: []string{"RENAME"},
+		},
+		{
+			name:    "directive renames a table with changed columns",
+			local:   []string{`CREATE TABLE accounts (id INT8 NOT NULL, email STRING NULL, CONSTRAINT accounts_pkey PRIMARY KEY (id))`},
+			remote:  []string{`CREATE TABLE users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id))`},
+			renames: RenameHints{Tables: map[string]string{"public.accounts": "public.users"}},
+			contains: []string{
+				"ALTER TABLE users RENAME TO accounts",
+				"ALTER TABLE accounts ADD COLUMN email STRING NULL",
+			},
+			notContains: []string{"DROP TABLE", "CREATE TABLE"},
+		},
+		{
+			name:        "column with identical definition is renamed",
+			local:       []string{`CREATE TABLE t (id INT8 NOT NULL, email STRING NOT NULL, CONSTRAINT t_pkey PRIMARY KEY (id), INDEX t_email_idx (email))`},
+			remote:      []string{`CREATE TABLE t (id INT8 NOT NULL, mail STRING NOT NULL, CONSTRAINT t_pkey PRIMARY KEY (id), INDEX t_email_idx (mail))`},
+			contains:    []string{"ALTER TABLE t RENAME COLUMN mail TO email"},
+			notContains: []string{"DROP COLUMN", "ADD COLUMN", "DROP INDEX"},
+			inferred:    true,
+		},
+		{
+			name:        "column with different type is not renamed",
+			local:       []string{`CREATE TABLE t (id INT8 NOT NULL, email STRING NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`},
+			remote:      []string{`CREATE TABLE t (id INT8 NOT NULL, mail INT8 NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`},
+			contains:    []string{"DROP COLUMN mail", "ADD COLUMN email"},
+			notContains: []string{"RENAME"},
+		},
+		{
+			name:    "directive renames a column with a changed type",
+			local:   []string{`CREATE TABLE t (id INT8 NOT NULL, email STRING NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`},
+			remote:  []string{`CREATE TABLE t (id INT8 NOT NULL, mail VARCHAR(100) NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`},
+			renames: RenameHints{Columns: map[string]map[string]string{"public.t": {"email": "mail"}}},
+			contains: []string{
+				"ALTER TABLE t RENAME COLUMN mail TO email",
+				"ALTER COLUMN email SET DATA TYPE STRING",
+			},
+			notContains: []string{"DROP COLUMN", "ADD COLUMN"},
+		},
+		{
+			name:    "directive can't rename across schemas",
+			local:   []string{`CREATE SCHEMA app`, `CREATE TABLE app.accounts (id INT8 NOT NULL, CONSTRAINT accounts_pkey PRIMARY KEY (id))`},
+			remote:  []string{`CREATE SCHEMA app`, `CREATE TABLE public.users (id INT8 NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id))`},
+			renames: RenameHints{Tables: map[string]string{"app.accounts": "public.users"}},
+			blocked: "can't move a table to another schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := NewSchema(parseStatements(tt.local...)...)
+			local.Renames = tt.renames
+			remote := NewSchema(parseStatements(tt.remote...)...)
+
+			result := Compare(local, remote)
+			ddl, warnings, err := result.GenerateMigrations(false)
+			if tt.blocked != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.blocked)
+				return
+			}
+			require.NoError(t, err)
+
+			// Only guessed renames are flagged and warned about
+			inferred := false
+			for _, diff := range result.Differences {
+				inferred = inferred || diff.InferredRename
+			}
+			assert.Equal(t, tt.inferred, inferred)
+			if tt.inferred {
+				assert.NotEmpty(t, warnings)
+			}
+			joined := strings.Join(ddl, "\n")
+
+			for _, s := range tt.contains {
+				assert.Contains(t, joined, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, joined, s)
+			}
+		})
+	}
+}
@@ -54,23 +54,23 @@ func compareRoutines(local, remote *Schema) []Difference {
 		if !existsInRemote {
 			// Routine added - create it
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeRoutineAdded,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("Routine '%s' added", name),
-				MigrationStatements: []tree.Statement{localRoutine.Ast},
+				Type:        DiffTypeRoutineAdded,
+				ObjectName:  name,
+				Description: fmt.Sprintf("Routine '%s' added", name),
+				Phases:      inOnePhase(localRoutine.Ast),
 			})
 		} else {
 			// Check if routine was modified
-			if localRoutine.Ast.String() != remoteRoutine.Ast.String() {
+			if local.checksum(localRoutine.Ast) != remote.checksum(remoteRoutine.Ast) {
 				// For modified routines, use CREATE OR REPLACE
 				// CockroachDB supports this for functions/procedures
 				ast := *localRoutine.Ast
 				ast.Replace = true
 				diffs = append(diffs, Difference{
-					Type:                DiffTypeRoutineModified,
-					ObjectName:          name,
-					Description:         fmt.Sprintf("Routine '%s' modified", name),
-					MigrationStatements: []tree.Statement{&ast},
+					Type:        DiffTypeRoutineModified,
+					ObjectName:  name,
+					Description: fmt.Sprintf("Routine '%s' modified", name),
+					Phases:      inOnePhase(&ast),
 				})
 			}
 		}
@@ -90,11 +90,11 @@ func compareRoutines(local, remote *Schema) []Difference {
 				}},
 			}
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeRoutineRemoved,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("Routine '%s' removed", name),
-				Dangerous:           true,
-				MigrationStatements: []tree.Statement{drop},
+				Type:        DiffTypeRoutineRemoved,
+				ObjectName:  name,
+				Description: fmt.Sprintf("Routine '%s' removed", name),
+				Dangerous:   true,
+				Phases:      inOnePhase(drop),
 			})
 		}
 	}
@@ -2,11 +2,18 @@ package schema
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	// Registers the PL/pgSQL parser, which DO blocks need to parse
+	_ "github.com/cockroachdb/cockroachdb-parser/pkg/sql/plpgsql/parser"
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
 	"github.com/spf13/afero"
 
@@ -15,7 +22,7 @@ import (
 
 type CreateObjectStatement interface {
 	tree.Statement
-	*tree.CreateTable | *tree.CreateType | *tree.CreateSequence | *tree.CreateView | *tree.CreateRoutine | *tree.CreateSchema
+	*tree.CreateTable | *tree.CreateType | *tree.CreateSequence | *tree.CreateView | *tree.CreateRoutine | *tree.CreateSchema | *tree.CreateTrigger
 }
 
 // Schema represents the complete database schema
@@ -24,9 +31,21 @@ type Schema struct {
 	Schemas            []ObjectSchema[*tree.CreateSchema]
 	Sequences          []ObjectSchema[*tree.CreateSequence]
 	Tables             []ObjectSchema[*tree.CreateTable]
+	Triggers           []ObjectSchema[*tree.CreateTrigger] // Named "table.trigger", as trigger names are unique per table
 	Types              []ObjectSchema[*tree.CreateType]
 	Views              []ObjectSchema[*tree.CreateView]
-	OriginalStatements []string // Original SQL statement strings in order
+	OriginalStatements []string          // Original SQL statement strings in order
+	Renames            RenameHints       // Renames declared in definition files
+	Using              UsingExpressions  // Column conversion expressions declared in definition files
+	Classify           ClassifyOverrides // Sync/async classification overrides declared in definition files
+	Remaps             EnumRemaps        // Replacements for dropped enum values declared in definition files
+	IgnoreDiff         IgnoredObjects    // Objects expected to differ from the database, declared in definition files or added by the caller
+	Fingerprint        string            // Hash of the database's create statements, when loaded from a database
+	Database           *DatabaseSettings // Settings of the database itself, from database.sql or LoadDatabaseSettings
+	ClusterSettings    *ClusterSettings  // Cluster settings, from cluster_settings.sql or LoadClusterSettings
+	Owners             Owners            // Owners of tables, views and sequences, from ALTER ... OWNER TO in definition files or LoadOwners
+
+	checksums *checksumCache // Checksums of the objects' ASTs, computed on first comparison
 }
 
 // TableSchema represents a table definition
@@ -34,6 +53,29 @@ type ObjectSchema[T CreateObjectStatement] struct {
 	Name   string
 	Schema string
 	Ast    T
+	Source SourceLocation // Where the object was defined, if loaded from files
+}
+
+// SourceLocation identifies the definition file and line a statement came from
+type SourceLocation struct {
+	File string
+	Line int
+}
+
+// IsZero returns true if the location is unknown
+func (l SourceLocation) IsZero() bool {
+	return l.File == ""
+}
+
+// String formats the location as file:line, or "" if unknown
+func (l SourceLocation) String() string {
+	if l.File == "" {
+		return ""
+	}
+	if l.Line <= 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
 }
 
 func (o *ObjectSchema[T]) ResolvedName() string {
@@ -43,6 +85,112 @@ func (o *ObjectSchema[T]) ResolvedName() string {
 	return fmt.Sprintf("%s.%s", o.Schema, o.Name)
 }
 
+// Checksum returns a hash of the object's formatted definition. Objects with the
+// same checksum have no differences, so comparing them in detail can be skipped.
+func (o *ObjectSchema[T]) Checksum() string {
+	return checksumOf(o.Ast)
+}
+
+func checksumOf(ast tree.NodeFormatter) string {
+	sum := sha256.Sum256([]byte(formatNode(ast)))
+	return hex.EncodeToString(sum[:])
+}
+
+// checksumCache holds the checksums of a schema's object ASTs. Object ASTs are
+// replaced rather than edited in place, so a pointer's checksum doesn't change.
+type checksumCache struct {
+	mu   sync.Mutex
+	sums map[tree.Statement]string
+}
+
+// checksum returns the Checksum of one of the schema's object ASTs, formatting
+// it only the first time
+func (s *Schema) checksum(ast tree.Statement) string {
+	if s.checksums == nil {
+		return checksumOf(ast)
+	}
+	s.checksums.mu.Lock()
+	defer s.checksums.mu.Unlock()
+	sum, ok := s.checksums.sums[ast]
+	if !ok {
+		sum = checksumOf(ast)
+		s.checksums.sums[ast] = sum
+	}
+	return sum
+}
+
+// SourceOf returns where the object with the given difference-style name
+// ("schema.object", or "schema:name" for schemas) was defined.
+func (s *Schema) SourceOf(objectName string) SourceLocation {
+	if objectName == databaseObjectName && s.Database != nil {
+		return s.Database.Source
+	}
+	if strings.HasPrefix(objectName, clusterSettingPrefix) && s.ClusterSettings != nil {
+		return s.ClusterSettings.Source
+	}
+	if name, ok := strings.CutPrefix(objectName, "schema:"); ok {
+		return findSource(s.Schemas, name)
+	}
+	for _, loc := range []SourceLocation{
+		findSource(s.Tables, objectName),
+		findSource(s.Types, objectName),
+		findSource(s.Sequences, objectName),
+		findSource(s.Views, objectName),
+		findSource(s.Routines, objectName),
+		findSource(s.Triggers, objectName),
+	} {
+		if !loc.IsZero() {
+			return loc
+		}
+	}
+	return SourceLocation{}
+}
+
+// setSources assigns locations to objects by the statement they were built from
+func (s *Schema) setSources(locations map[tree.Statement]SourceLocation) {
+	setObjectSources(s.Schemas, locations)
+	setObjectSources(s.Tables, locations)
+	setObjectSources(s.Types, locations)
+	setObjectSources(s.Sequences, locations)
+	setObjectSources(s.Views, locations)
+	setObjectSources(s.Routines, locations)
+	setObjectSources(s.Triggers, locations)
+}
+
+// copySources copies locations from another schema's objects with matching names
+func (s *Schema) copySources(from *Schema) {
+	copyObjectSources(s.Schemas, from.Schemas)
+	copyObjectSources(s.Tables, from.Tables)
+	copyObjectSources(s.Types, from.Types)
+	copyObjectSources(s.Sequences, from.Sequences)
+	copyObjectSources(s.Views, from.Views)
+	copyObjectSources(s.Routines, from.Routines)
+	copyObjectSources(s.Triggers, from.Triggers)
+}
+
+func findSource[T CreateObjectStatement](objects []ObjectSchema[T], name string) SourceLocation {
+	for _, obj := range objects {
+		if obj.ResolvedName() == name && !obj.Source.IsZero() {
+			return obj.Source
+		}
+	}
+	return SourceLocation{}
+}
+
+func setObjectSources[T CreateObjectStatement](objects []ObjectSchema[T], locations map[tree.Statement]SourceLocation) {
+	for i := range objects {
+		if loc, ok := locations[objects[i].Ast]; ok {
+			objects[i].Source = loc
+		}
+	}
+}
+
+func copyObjectSources[T CreateObjectStatement](objects, from []ObjectSchema[T]) {
+	for i := range objects {
+		objects[i].Source = findSource(from, objects[i].ResolvedName())
+	}
+}
+
 func NewSchema(statements ...tree.Statement) *Schema {
 	schema := &Schema{
 		Tables:             make([]ObjectSchema[*tree.CreateTable], 0),
@@ -51,7 +199,9 @@ func NewSchema(statements ...tree.Statement) *Schema {
 		Sequences:          make([]ObjectSchema[*tree.CreateSequence], 0),
 		Views:              make([]ObjectSchema[*tree.CreateView], 0),
 		Routines:           make([]ObjectSchema[*tree.CreateRoutine], 0),
+		Triggers:           make([]ObjectSchema[*tree.CreateTrigger], 0),
 		OriginalStatements: make([]string, 0, len(statements)),
+		checksums:          &checksumCache{sums: make(map[tree.Statement]string)},
 	}
 	for _, stmt := range statements {
 		// Store the statement string
@@ -111,6 +261,21 @@ func NewSchema(statements ...tree.Statement) *Schema {
 				Ast:    stmt,
 			}
 			schema.Routines = append(schema.Routines, obj)
+
+		case *tree.CreateTrigger:
+			schemaName, tableName := getObjectName(stmt.TableName)
+			obj := ObjectSchema[*tree.CreateTrigger]{
+				Name:   tableName + "." + stmt.Name.Normalize(),
+				Schema: schemaName,
+				Ast:    stmt,
+			}
+			schema.Triggers = append(schema.Triggers, obj)
+
+		case *tree.AlterTableOwner:
+			if schema.Owners == nil {
+				schema.Owners = make(Owners)
+			}
+			schema.Owners[ownerName(stmt)] = ObjectOwner{Role: stmt.Owner.Name, Stmt: stmt}
 		}
 	}
 
@@ -119,20 +284,111 @@ func NewSchema(statements ...tree.Statement) *Schema {
 
 // LoadFromDirectories loads schema from SQL files across multiple directories
 func LoadFromDirectories(ctx context.Context, fs afero.Fs, dirPaths []string, dbClient *db.Client) (*Schema, error) {
+	return LoadFromDirectoriesForEnv(ctx, fs, dirPaths, "", dbClient)
+}
+
+// LoadFromDirectoriesForEnv loads schema from SQL files across multiple directories,
+// merging the overlays/<env> directory of each over its base definitions.
+// Overlays are skipped entirely when env is empty.
+func LoadFromDirectoriesForEnv(ctx context.Context, fs afero.Fs, dirPaths []string, env string, dbClient *db.Client) (*Schema, error) {
 
 	// 1. Load raw schemas from fs
+	rawSchema, err := LoadDefinitions(fs, dirPaths, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Load schemas into a new database
+	diff := Compare(rawSchema, NewSchema())
+	statements, _, err := diff.GenerateMigrations(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dbClient.ExecuteBulkDDL(ctx, statements...); err != nil {
+		return nil, err
+	}
+
+	// 3. Get standardized create statements from the database
+	schema, err := LoadFromDatabase(ctx, dbClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. The database round trip loses file positions, so carry them over by name
+	schema.copySources(rawSchema)
+	schema.Renames = rawSchema.Renames
+	schema.Using = rawSchema.Using
+	schema.Classify = rawSchema.Classify
+	schema.Remaps = rawSchema.Remaps
+	schema.IgnoreDiff = rawSchema.IgnoreDiff
+	schema.Database = rawSchema.Database
+	schema.ClusterSettings = rawSchema.ClusterSettings
+	schema.Owners = rawSchema.Owners
+	return schema, nil
+}
+
+// LoadDefinitions parses the SQL files in the definition directories, with the
+// overlays/<env> directories merged over them, without a database. The statements
+// are as written, so most callers want LoadFromDirectoriesForEnv instead.
+func LoadDefinitions(fs afero.Fs, dirPaths []string, env string) (*Schema, error) {
 	allStatements := make([]tree.Statement, 0)
-	for _, dirPath := range dirPaths {
+	locations := make(map[tree.Statement]SourceLocation)
+	renames := newRenameHints()
+	using := make(UsingExpressions)
+	classify := make(ClassifyOverrides)
+	remaps := make(EnumRemaps)
+	ignored := make(IgnoredObjects)
+	var database *DatabaseSettings
+	var clusterSettings *ClusterSettings
+	loadDir := func(dirPath string, overlay bool) ([]tree.Statement, error) {
+		var dirStatements []tree.Statement
 		err := afero.Walk(fs, dirPath, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
 			if info.IsDir() {
+				// Overlays are merged separately, after all base definitions are loaded
+				if !overlay && path == filepath.Join(dirPath, overlaysDir) {
+					return filepath.SkipDir
+				}
+				// Seed data isn't part of the schema
+				if !overlay && path == filepath.Join(dirPath, SeedDir) {
+					return filepath.SkipDir
+				}
+				// Neither are schema tests
+				if !overlay && path == filepath.Join(dirPath, TestsDir) {
+					return filepath.SkipDir
+				}
 				return nil
 			}
 			if !strings.HasSuffix(strings.ToLower(path), ".sql") {
 				return nil
 			}
+			if path == filepath.Join(dirPath, DatabaseFile) {
+				settings, err := loadDatabaseFile(fs, path)
+				if err != nil {
+					return err
+				}
+				if database == nil {
+					database = settings
+				} else {
+					database.merge(settings)
+				}
+				return nil
+			}
+			if path == filepath.Join(dirPath, ClusterSettingsFile) {
+				settings, err := loadClusterSettingsFile(fs, path)
+				if err != nil {
+					return err
+				}
+				if clusterSettings == nil {
+					clusterSettings = settings
+				} else {
+					clusterSettings.merge(settings)
+				}
+				return nil
+			}
 
 			content, err := afero.ReadFile(fs, path)
 			if err != nil {
@@ -140,33 +396,103 @@ func LoadFromDirectories(ctx context.Context, fs afero.Fs, dirPaths []string, db
 			}
 
 			sql := string(content)
-			statements, err := parseSQL(sql)
+			statements, sources, err := parseDefinitionSQL(sql, path, overlay)
+			if err != nil {
+				return fmt.Errorf("in file %s: %w", path, err)
+			}
+
+			for i, stmt := range statements {
+				locations[stmt] = sources[i]
+			}
+			renames.merge(parseRenameDirectives(sql))
+			remaps.merge(parseRemapDirectives(sql))
+			ignored.merge(parseIgnoreDirectives(sql, path))
+
+			fileUsing, err := parseUsingDirectives(sql)
 			if err != nil {
 				return fmt.Errorf("in file %s: %w", path, err)
 			}
+			using.merge(fileUsing)
 
-			allStatements = append(allStatements, statements...)
+			fileClassify, err := parseClassifyDirectives(sql)
+			if err != nil {
+				return fmt.Errorf("in file %s: %w", path, err)
+			}
+			classify.merge(fileClassify)
+			dirStatements = append(dirStatements, statements...)
 			return nil
 		})
+		return dirStatements, err
+	}
+
+	for _, dirPath := range dirPaths {
+		statements, err := loadDir(dirPath, false)
 		if err != nil {
 			return nil, err
 		}
+		allStatements = append(allStatements, statements...)
+	}
+
+	if env != "" {
+		for _, dirPath := range dirPaths {
+			overlayPath := filepath.Join(dirPath, overlaysDir, env)
+			exists, err := afero.DirExists(fs, overlayPath)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				continue
+			}
+			statements, err := loadDir(overlayPath, true)
+			if err != nil {
+				return nil, err
+			}
+			allStatements, err = applyOverlay(allStatements, statements)
+			if err != nil {
+				return nil, fmt.Errorf("in overlay %s: %w", overlayPath, err)
+			}
+		}
 	}
 
-	// 2. Load schemas into a new database
 	rawSchema := NewSchema(allStatements...)
-	diff := Compare(rawSchema, NewSchema())
-	statements, _, err := diff.GenerateMigrations(false)
-	if err != nil {
+	rawSchema.setSources(locations)
+	rawSchema.Renames = renames
+	rawSchema.Using = using
+	rawSchema.Classify = classify
+	rawSchema.Remaps = remaps
+	rawSchema.IgnoreDiff = ignored
+	rawSchema.Database = database
+	rawSchema.ClusterSettings = clusterSettings
+	if err := rawSchema.checkOwners(); err != nil {
 		return nil, err
 	}
+	return rawSchema, nil
+}
 
-	if err := dbClient.ExecuteBulkDDL(ctx, statements...); err != nil {
-		return nil, err
+// loadDatabaseFile reads the settings of the database from a database.sql file
+func loadDatabaseFile(fs afero.Fs, path string) (*DatabaseSettings, error) {
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
 	}
+	settings, err := parseDatabaseSettings(string(content), path)
+	if err != nil {
+		return nil, fmt.Errorf("in file %s: %w", path, err)
+	}
+	return settings, nil
+}
 
-	// 3. Get standardized create statements from the database
-	return LoadFromDatabase(ctx, dbClient)
+// loadClusterSettingsFile reads cluster settings from a cluster_settings.sql file
+func loadClusterSettingsFile(fs afero.Fs, path string) (*ClusterSettings, error) {
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	settings, err := parseClusterSettings(string(content), path)
+	if err != nil {
+		return nil, fmt.Errorf("in file %s: %w", path, err)
+	}
+	return settings, nil
 }
 
 // LoadFromDirectory loads schema from SQL files in a directory
@@ -176,41 +502,119 @@ func LoadFromDirectory(ctx context.Context, fs afero.Fs, dirPath string, dbClien
 
 // LoadFromDatabase loads schema from all non-system schemas in the database
 func LoadFromDatabase(ctx context.Context, dbClient *db.Client) (*Schema, error) {
-	statements, err := dbClient.GetAllCreateStatements(ctx)
+	return LoadFromDatabaseAsOf(ctx, dbClient, "")
+}
+
+// LoadFromDatabaseAsOf loads the schema as it was at asOf, an AS OF SYSTEM
+// TIME expression like '-1h', or as it is now if asOf is empty
+func LoadFromDatabaseAsOf(ctx context.Context, dbClient *db.Client, asOf string) (*Schema, error) {
+	statements, err := dbClient.GetAllCreateStatementsAsOf(ctx, asOf)
 	if err != nil {
 		return nil, err
 	}
 
-	allStatements := make([]tree.Statement, 0, len(statements))
-	for _, s := range statements {
-		stmt, err := parseSQL(s)
-		if err != nil {
-			return nil, err
-		}
-		allStatements = append(allStatements, stmt...)
+	allStatements, err := parseConcurrently(ctx, statements)
+	if err != nil {
+		return nil, err
 	}
 
 	schema := NewSchema(allStatements...)
+	schema.Fingerprint = db.SchemaFingerprint(statements)
 
 	return schema, nil
 }
 
+// parseConcurrency is the number of statements parsed at once when loading from a database
+var parseConcurrency = runtime.GOMAXPROCS(0)
+
+// parseConcurrently parses each SQL string with a bounded pool of workers, keeping
+// the statements in input order. It stops at the first error or when ctx is done.
+func parseConcurrently(ctx context.Context, sqls []string) ([]tree.Statement, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parsed := make([][]tree.Statement, len(sqls))
+	errs := make([]error, len(sqls))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(parseConcurrency, len(sqls)) {
+		wg.Go(func() {
+			for i := range jobs {
+				parsed[i], errs[i] = parseSQL(sqls[i])
+				if errs[i] != nil {
+					cancel()
+				}
+			}
+		})
+	}
+
+feed:
+	for i := range sqls {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Report the first failing statement in input order, so errors are stable
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Without a parse error, ctx can only be done because the caller's context is
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	statements := make([]tree.Statement, 0, len(sqls))
+	for _, stmts := range parsed {
+		statements = append(statements, stmts...)
+	}
+	return statements, nil
+}
+
 // ParseSQL parses SQL string into statements (exported for use in migrate command)
 func ParseSQL(sql string) ([]tree.Statement, error) {
 	return parseSQL(sql)
 }
 
 func parseSQL(sql string) ([]tree.Statement, error) {
+	results, _, err := parseSQLWithSources(sql, "")
+	return results, err
+}
+
+// parseSQLWithSources parses SQL like parseSQL, additionally returning the
+// location of each statement within the given file.
+func parseSQLWithSources(sql, file string) ([]tree.Statement, []SourceLocation, error) {
+	return parseDefinitionSQL(sql, file, false)
+}
+
+// parseDefinitionSQL parses a definition file, returning the location of each
+// statement. Overlay files may also override table storage parameters.
+func parseDefinitionSQL(sql, file string, overlay bool) ([]tree.Statement, []SourceLocation, error) {
 	statements, err := parser.Parse(sql)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse SQL: %w", err)
 	}
 
 	var results []tree.Statement
+	var sources []SourceLocation
+	offset := 0
 	for _, stmt := range statements {
+		// Owners are set with ALTER ... OWNER TO, which counts as DCL
+		owner, isOwner := stmt.AST.(*tree.AlterTableOwner)
+		if isOwner && owner.Owner.RoleSpecType != tree.RoleName {
+			return nil, nil, fmt.Errorf("%s: OWNER TO needs a role name, the current user depends on who runs scurry", owner)
+		}
+
 		// Validate that only DDL statements are present
-		if stmt.AST.StatementType() != tree.TypeDDL {
-			return nil, fmt.Errorf("non-DDL statement found: %s (type: %s). Schema files should only contain DDL statements (CREATE TABLE, CREATE TYPE, etc.)",
+		if stmt.AST.StatementType() != tree.TypeDDL && !isOwner {
+			return nil, nil, fmt.Errorf("non-DDL statement found: %s (type: %s). Schema files should only contain DDL statements (CREATE TABLE, CREATE TYPE, etc.)",
 				stmt.AST.StatementTag(), stmt.AST.StatementType())
 		}
 
@@ -222,14 +626,29 @@ func parseSQL(sql string) ([]tree.Statement, error) {
 		case *tree.CreateSequence:
 		case *tree.CreateView:
 		case *tree.CreateSchema:
+		case *tree.CreateTrigger:
+		case *tree.AlterTableOwner:
 		default:
-			return nil, fmt.Errorf("unsupported DDL statement: %s.\nscurry currently supports:\n\tCREATE SCHEMA\n\tCREATE TABLE\n\tCREATE TYPE\n\tCREATE SEQUENCE\n\tCREATE (MATERIALIZED) VIEW\n\tCREATE FUNCTION\n\tCREATE PROCEDURE\nIndexes should be defined inline within CREATE TABLE statements",
+			if overlay && isStorageParamOverride(stmt.AST) {
+				break
+			}
+			return nil, nil, fmt.Errorf("unsupported DDL statement: %s.\nscurry currently supports:\n\tCREATE SCHEMA\n\tCREATE TABLE\n\tCREATE TYPE\n\tCREATE SEQUENCE\n\tCREATE (MATERIALIZED) VIEW\n\tCREATE FUNCTION\n\tCREATE PROCEDURE\n\tCREATE TRIGGER\n\tALTER TABLE/VIEW/SEQUENCE ... OWNER TO\nIndexes should be defined inline within CREATE TABLE statements",
 				stmt.AST.StatementTag(),
 			)
 		}
 
+		// stmt.SQL is a substring of the input, so searching forward from the
+		// previous statement finds where this one starts.
+		line := 0
+		if idx := strings.Index(sql[offset:], stmt.SQL); idx != -1 {
+			offset += idx
+			line = strings.Count(sql[:offset], "\n") + 1
+			offset += len(stmt.SQL)
+		}
+
 		results = append(results, stmt.AST)
+		sources = append(sources, SourceLocation{File: file, Line: line})
 	}
 
-	return results, nil
+	return results, sources, nil
 }
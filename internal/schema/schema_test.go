@@ -2,7 +2,9 @@ package schema
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -50,6 +52,7 @@ func TestLoadFromDirectory(t *testing.T) {
 				require.Len(t, s.Tables, 1)
 				assert.Equal(t, "users", s.Tables[0].Name)
 				assert.Equal(t, "public", s.Tables[0].Schema)
+				assert.Equal(t, SourceLocation{File: "/schema/tables/users.sql", Line: 2}, s.Tables[0].Source)
 			},
 		},
 		{
@@ -351,3 +354,131 @@ func TestParseSQL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSQLWithSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		sql         string
+		expectLines []int
+	}{
+		{
+			name:        "single statement on first line",
+			sql:         "CREATE TABLE users (id INT PRIMARY KEY);",
+			expectLines: []int{1},
+		},
+		{
+			name: "multiple statements with comments",
+			sql: `-- users
+CREATE TABLE users (
+	id INT PRIMARY KEY
+);
+
+-- posts
+CREATE TABLE posts (
+	id INT PRIMARY KEY
+);`,
+			expectLines: []int{2, 7},
+		},
+		{
+			name: "identical statements",
+			sql: `CREATE SCHEMA app;
+CREATE SCHEMA app;`,
+			expectLines: []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, sources, err := parseSQLWithSources(tt.sql, "defs/file.sql")
+			require.NoError(t, err)
+			require.Len(t, statements, len(tt.expectLines))
+			require.Len(t, sources, len(tt.expectLines))
+			for i, line := range tt.expectLines {
+				assert.Equal(t, SourceLocation{File: "defs/file.sql", Line: line}, sources[i])
+			}
+		})
+	}
+}
+
+func TestCompareSetsSource(t *testing.T) {
+	tests := []struct {
+		name         string
+		localSQL     string
+		remoteSQL    string
+		expectSource string
+	}{
+		{
+			name:         "added table",
+			localSQL:     "CREATE TABLE users (id INT PRIMARY KEY);",
+			expectSource: "defs/users.sql:3",
+		},
+		{
+			name:         "modified table",
+			localSQL:     "CREATE TABLE users (id INT NOT NULL, email TEXT, CONSTRAINT users_pkey PRIMARY KEY (id));",
+			remoteSQL:    "CREATE TABLE users (id INT NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id));",
+			expectSource: "defs/users.sql:3",
+		},
+		{
+			name:         "removed table has no source",
+			remoteSQL:    "CREATE TABLE users (id INT PRIMARY KEY);",
+			expectSource: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localStmts, err := parseSQL(tt.localSQL)
+			require.NoError(t, err)
+			remoteStmts, err := parseSQL(tt.remoteSQL)
+			require.NoError(t, err)
+
+			local := NewSchema(localStmts...)
+			for i := range local.Tables {
+				local.Tables[i].Source = SourceLocation{File: "defs/users.sql", Line: 3}
+			}
+
+			result := Compare(local, NewSchema(remoteStmts...))
+			require.NotEmpty(t, result.Differences)
+			for _, d := range result.Differences {
+				assert.Equal(t, tt.expectSource, d.Source.String())
+				if tt.expectSource != "" {
+					assert.Contains(t, result.Summary(), "("+tt.expectSource+")")
+				}
+			}
+		})
+	}
+}
+
+func TestParseConcurrently(t *testing.T) {
+	sqls := make([]string, 100)
+	for i := range sqls {
+		sqls[i] = fmt.Sprintf("CREATE TABLE t%d (id INT8 PRIMARY KEY)", i)
+	}
+
+	t.Run("keeps input order", func(t *testing.T) {
+		statements, err := parseConcurrently(context.Background(), sqls)
+		require.NoError(t, err)
+		require.Len(t, statements, len(sqls))
+		for i, stmt := range statements {
+			assert.Equal(t, sqls[i], stmt.String())
+		}
+	})
+
+	t.Run("reports the first error", func(t *testing.T) {
+		invalid := slices.Clone(sqls)
+		invalid[40] = "CREATE TABLE"
+		invalid[60] = "SELECT 1"
+
+		_, err := parseConcurrently(context.Background(), invalid)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse SQL")
+	})
+
+	t.Run("stops when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := parseConcurrently(ctx, sqls)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -2,8 +2,11 @@ package schema
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/set"
 )
 
 // compareSequences finds differences in sequences
@@ -25,28 +28,32 @@ func compareSequences(local, remote *Schema) []Difference {
 	for name, localSeq := range localSequences {
 		remoteSeq, existsInRemote := remoteSequences[name]
 		if !existsInRemote {
-			// Sequence added - create it
+			// Sequence added - create it. OWNED BY is set separately once the
+			// owning table exists, since that table often uses the sequence.
+			create, owner := splitSequenceOwner(localSeq.Ast)
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeSequenceAdded,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("Sequence '%s' added", name),
-				MigrationStatements: []tree.Statement{localSeq.Ast},
+				Type:        DiffTypeSequenceAdded,
+				ObjectName:  name,
+				Description: fmt.Sprintf("Sequence '%s' added", name),
+				Phases:      inOnePhase(create),
 			})
+			if owner != nil {
+				diffs = append(diffs, Difference{
+					Type:        DiffTypeSequenceModified,
+					ObjectName:  name,
+					Description: fmt.Sprintf("Sequence '%s' owned by %s", name, owner.ColumnItemVal.String()),
+					Phases: inOnePhase(&tree.AlterSequence{
+						Name:    localSeq.Ast.Name.ToUnresolvedObjectName(),
+						Options: tree.SequenceOptions{*owner},
+					}),
+				})
+			}
 		} else {
 			// Check if sequence was modified
-			if localSeq.Ast.String() != remoteSeq.Ast.String() {
-				// Sequence modified - drop and recreate
-				drop := &tree.DropSequence{
-					Names:        []tree.TableName{remoteSeq.Ast.Name},
-					IfExists:     true,
-					DropBehavior: tree.DropRestrict,
+			if local.checksum(localSeq.Ast) != remote.checksum(remoteSeq.Ast) {
+				if diff, ok := compareSequenceOptions(name, localSeq.Ast, remoteSeq.Ast); ok {
+					diffs = append(diffs, diff)
 				}
-				diffs = append(diffs, Difference{
-					Type:                DiffTypeSequenceModified,
-					ObjectName:          name,
-					Description:         fmt.Sprintf("Sequence '%s' modified", name),
-					MigrationStatements: []tree.Statement{drop, localSeq.Ast},
-				})
 			}
 		}
 	}
@@ -54,20 +61,189 @@ func compareSequences(local, remote *Schema) []Difference {
 	// Find removed sequences
 	for name, remoteSeq := range remoteSequences {
 		if _, existsInLocal := localSequences[name]; !existsInLocal {
-			// Sequence removed - drop it
+			// Sequence removed - drop it. If it's owned by a column that is also
+			// being dropped, CockroachDB drops it with the column, so IF EXISTS
+			// keeps this a no-op.
 			drop := &tree.DropSequence{
 				Names:        []tree.TableName{remoteSeq.Ast.Name},
 				IfExists:     true,
 				DropBehavior: tree.DropRestrict,
 			}
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeSequenceRemoved,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("Sequence '%s' removed", name),
-				MigrationStatements: []tree.Statement{drop},
+				Type:        DiffTypeSequenceRemoved,
+				ObjectName:  name,
+				Description: fmt.Sprintf("Sequence '%s' removed", name),
+				Phases:      inOnePhase(drop),
 			})
 		}
 	}
 
 	return diffs
 }
+
+// compareSequenceOptions emits an ALTER SEQUENCE setting every option that differs,
+// so the sequence keeps its current value. Options that can't be altered fall back
+// to dropping and recreating the sequence. It returns false if the definitions only
+// differ in ways that don't need a change, like optional words or RESTART.
+func compareSequenceOptions(name string, local, remote *tree.CreateSequence) (Difference, bool) {
+	if local.Persistence != remote.Persistence || hasSequenceOption(local, tree.SeqOptVirtual) != hasSequenceOption(remote, tree.SeqOptVirtual) {
+		drop := &tree.DropSequence{
+			Names:        []tree.TableName{remote.Name},
+			IfExists:     true,
+			DropBehavior: tree.DropRestrict,
+		}
+		return Difference{
+			Type:           DiffTypeSequenceModified,
+			ObjectName:     name,
+			Description:    fmt.Sprintf("Sequence '%s' modified", name),
+			Dangerous:      true,
+			WarningMessage: fmt.Sprintf("Sequence '%s' will be dropped and re-created, restarting its value.", name),
+			IsDropCreate:   true,
+			Phases:         inOnePhase(drop, local),
+		}, true
+	}
+
+	localOpts := sequenceOptionsByKey(local.Options)
+	remoteOpts := sequenceOptionsByKey(remote.Options)
+
+	keys := make([]string, 0, len(localOpts)+len(remoteOpts))
+	for key := range localOpts {
+		keys = append(keys, key)
+	}
+	for key := range remoteOpts {
+		if _, ok := localOpts[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changed tree.SequenceOptions
+	for _, key := range keys {
+		localOpt, inLocal := localOpts[key]
+		remoteOpt, inRemote := remoteOpts[key]
+		localOpt.OptionalWord = false
+		switch {
+		case inLocal && inRemote:
+			if formatSequenceOption(localOpt) != formatSequenceOption(remoteOpt) {
+				changed = append(changed, localOpt)
+			}
+		case inLocal:
+			changed = append(changed, localOpt)
+		default:
+			if reset, ok := defaultSequenceOption(remoteOpt); ok {
+				changed = append(changed, reset)
+			}
+		}
+	}
+
+	if len(changed) == 0 {
+		return Difference{}, false
+	}
+
+	return Difference{
+		Type:        DiffTypeSequenceModified,
+		ObjectName:  name,
+		Description: fmt.Sprintf("Sequence '%s' modified:%s", name, tree.AsString(&changed)),
+		Phases: inOnePhase(&tree.AlterSequence{
+			Name:    local.Name.ToUnresolvedObjectName(),
+			Options: changed,
+		}),
+		// Dropping the column that owns a sequence drops the sequence too, so a
+		// change of owner has to happen before the old owner is dropped
+		OriginalDependencies: sequenceOwnerNames(remote),
+	}, true
+}
+
+// sequenceOwnerNames returns the table and column named by a sequence's OWNED BY
+func sequenceOwnerNames(seq *tree.CreateSequence) set.Set[string] {
+	names := set.New[string]()
+	for _, opt := range seq.Options {
+		if opt.Name != tree.SeqOptOwnedBy || opt.ColumnItemVal == nil || opt.ColumnItemVal.TableName == nil {
+			continue
+		}
+		schemaName, tableName := getObjectName(opt.ColumnItemVal.TableName)
+		names.Add(schemaName+"."+tableName, schemaName+"."+tableName+"."+opt.ColumnItemVal.ColumnName.Normalize())
+	}
+	return names
+}
+
+// sequenceOptionKey groups options that set the same property, like CYCLE and NO CYCLE
+func sequenceOptionKey(opt tree.SequenceOption) string {
+	switch opt.Name {
+	case tree.SeqOptNoCycle:
+		return tree.SeqOptCycle
+	case tree.SeqOptCacheNode:
+		return tree.SeqOptCache
+	default:
+		return opt.Name
+	}
+}
+
+func sequenceOptionsByKey(opts tree.SequenceOptions) map[string]tree.SequenceOption {
+	byKey := make(map[string]tree.SequenceOption)
+	for _, opt := range opts {
+		if opt.Name == tree.SeqOptVirtual || opt.Name == tree.SeqOptRestart {
+			continue
+		}
+		byKey[sequenceOptionKey(opt)] = opt
+	}
+	return byKey
+}
+
+func hasSequenceOption(seq *tree.CreateSequence, name string) bool {
+	for _, opt := range seq.Options {
+		if opt.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSequenceOption formats an option without optional words, so
+// "INCREMENT BY 2" and "INCREMENT 2" compare equal
+func formatSequenceOption(opt tree.SequenceOption) string {
+	opt.OptionalWord = false
+	return tree.AsString(&tree.SequenceOptions{opt})
+}
+
+// defaultSequenceOption returns the option that undoes a setting removed from the
+// definition. START only matters on creation and AS can't be unset, so those are left.
+func defaultSequenceOption(opt tree.SequenceOption) (tree.SequenceOption, bool) {
+	one := int64(1)
+	switch sequenceOptionKey(opt) {
+	case tree.SeqOptCycle:
+		return tree.SequenceOption{Name: tree.SeqOptNoCycle}, true
+	case tree.SeqOptCache:
+		return tree.SequenceOption{Name: tree.SeqOptCache, IntVal: &one}, true
+	case tree.SeqOptIncrement:
+		return tree.SequenceOption{Name: tree.SeqOptIncrement, IntVal: &one}, true
+	case tree.SeqOptMinValue, tree.SeqOptMaxValue:
+		return tree.SequenceOption{Name: opt.Name}, true
+	case tree.SeqOptOwnedBy:
+		return tree.SequenceOption{Name: tree.SeqOptOwnedBy}, true
+	default:
+		return tree.SequenceOption{}, false
+	}
+}
+
+// splitSequenceOwner returns the sequence without its OWNED BY option, and that
+// option if it names a column
+func splitSequenceOwner(seq *tree.CreateSequence) (*tree.CreateSequence, *tree.SequenceOption) {
+	var owner *tree.SequenceOption
+	opts := make(tree.SequenceOptions, 0, len(seq.Options))
+	for i, opt := range seq.Options {
+		if opt.Name == tree.SeqOptOwnedBy {
+			if opt.ColumnItemVal != nil {
+				owner = &seq.Options[i]
+			}
+			continue
+		}
+		opts = append(opts, opt)
+	}
+	if owner == nil {
+		return seq, nil
+	}
+	withoutOwner := *seq
+	withoutOwner.Options = opts
+	return &withoutOwner, owner
+}
@@ -62,6 +62,18 @@ func TestCompareSequences(t *testing.T) {
 			remoteSeqs:    []string{"CREATE SEQUENCE user_id_seq"},
 			wantDiffCount: 0,
 		},
+		{
+			name:          "only optional words differ",
+			localSeqs:     []string{"CREATE SEQUENCE user_id_seq INCREMENT BY 2 START WITH 10"},
+			remoteSeqs:    []string{"CREATE SEQUENCE user_id_seq INCREMENT 2 START 10"},
+			wantDiffCount: 0,
+		},
+		{
+			name:          "only restart differs",
+			localSeqs:     []string{"CREATE SEQUENCE user_id_seq RESTART WITH 5"},
+			remoteSeqs:    []string{"CREATE SEQUENCE user_id_seq"},
+			wantDiffCount: 0,
+		},
 		{
 			name:          "sequence added",
 			localSeqs:     []string{"CREATE SEQUENCE user_id_seq", "CREATE SEQUENCE post_id_seq"},
@@ -163,20 +175,38 @@ func TestSequenceModifiedMigration(t *testing.T) {
 		remoteSeq     string
 		wantStmtCount int
 		wantContains  []string
+		wantMissing   []string
 	}{
 		{
-			name:          "sequence modified generates drop and create",
+			name:          "sequence modified generates alter sequence",
 			localSeq:      "CREATE SEQUENCE user_id_seq INCREMENT BY 2",
 			remoteSeq:     "CREATE SEQUENCE user_id_seq INCREMENT BY 1",
-			wantStmtCount: 2, // DROP + CREATE
-			wantContains:  []string{"DROP SEQUENCE", "user_id_seq", "CREATE SEQUENCE", "INCREMENT BY 2"},
+			wantStmtCount: 1,
+			wantContains:  []string{"ALTER SEQUENCE", "user_id_seq", "INCREMENT 2"},
+			wantMissing:   []string{"DROP SEQUENCE", "MINVALUE"},
 		},
 		{
 			name:          "sequence with multiple changes",
 			localSeq:      "CREATE SEQUENCE user_id_seq INCREMENT BY 5 MINVALUE 100 MAXVALUE 10000 CACHE 10",
 			remoteSeq:     "CREATE SEQUENCE user_id_seq",
+			wantStmtCount: 1,
+			wantContains:  []string{"ALTER SEQUENCE", "INCREMENT 5", "MINVALUE 100", "MAXVALUE 10000", "CACHE 10"},
+			wantMissing:   []string{"DROP SEQUENCE"},
+		},
+		{
+			name:          "removed option is reset",
+			localSeq:      "CREATE SEQUENCE user_id_seq",
+			remoteSeq:     "CREATE SEQUENCE user_id_seq CACHE 10",
+			wantStmtCount: 1,
+			wantContains:  []string{"ALTER SEQUENCE", "CACHE 1"},
+			wantMissing:   []string{"DROP SEQUENCE", "CACHE 10"},
+		},
+		{
+			name:          "virtual sequence is recreated",
+			localSeq:      "CREATE SEQUENCE user_id_seq VIRTUAL",
+			remoteSeq:     "CREATE SEQUENCE user_id_seq",
 			wantStmtCount: 2, // DROP + CREATE
-			wantContains:  []string{"DROP SEQUENCE", "CREATE SEQUENCE", "INCREMENT BY 5", "MINVALUE 100"},
+			wantContains:  []string{"DROP SEQUENCE", "CREATE SEQUENCE", "VIRTUAL"},
 		},
 	}
 
@@ -197,17 +227,22 @@ func TestSequenceModifiedMigration(t *testing.T) {
 				t.Errorf("expected DiffTypeSequenceModified, got %s", diff.Type)
 			}
 
-			if len(diff.MigrationStatements) != tt.wantStmtCount {
-				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.MigrationStatements))
+			if len(diff.Statements()) != tt.wantStmtCount {
+				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.Statements()))
 			}
 
 			// Check for expected strings in migration DDL
-			allDDL := strings.Join(statementsToStrings(diff.MigrationStatements), "\n")
+			allDDL := strings.Join(statementsToStrings(diff.Statements()), "\n")
 			for _, expected := range tt.wantContains {
 				if !strings.Contains(allDDL, expected) {
 					t.Errorf("migration DDL missing expected string %q.\nGot:\n%s", expected, allDDL)
 				}
 			}
+			for _, unexpected := range tt.wantMissing {
+				if strings.Contains(allDDL, unexpected) {
+					t.Errorf("migration DDL contains unexpected string %q.\nGot:\n%s", unexpected, allDDL)
+				}
+			}
 		})
 	}
 }
@@ -266,12 +301,12 @@ func TestSequenceAddedRemoved(t *testing.T) {
 				t.Errorf("expected %s, got %s", tt.diffType, diff.Type)
 			}
 
-			if len(diff.MigrationStatements) != tt.wantStmtCount {
-				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.MigrationStatements))
+			if len(diff.Statements()) != tt.wantStmtCount {
+				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.Statements()))
 			}
 
 			// Check for expected strings in migration DDL
-			allDDL := strings.Join(statementsToStrings(diff.MigrationStatements), "\n")
+			allDDL := strings.Join(statementsToStrings(diff.Statements()), "\n")
 			for _, expected := range tt.wantContains {
 				if !strings.Contains(allDDL, expected) {
 					t.Errorf("migration DDL missing expected string %q.\nGot:\n%s", expected, allDDL)
@@ -280,3 +315,46 @@ func TestSequenceAddedRemoved(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitSequenceOwner(t *testing.T) {
+	tests := []struct {
+		name       string
+		sql        string
+		wantCreate string
+		wantOwner  string
+	}{
+		{
+			name:       "no owner",
+			sql:        "CREATE SEQUENCE s INCREMENT 2",
+			wantCreate: "CREATE SEQUENCE s INCREMENT 2",
+		},
+		{
+			name:       "owned by column",
+			sql:        "CREATE SEQUENCE s INCREMENT 2 OWNED BY users.id",
+			wantCreate: "CREATE SEQUENCE s INCREMENT 2",
+			wantOwner:  "OWNED BY users.id",
+		},
+		{
+			name:       "owned by none",
+			sql:        "CREATE SEQUENCE s OWNED BY NONE",
+			wantCreate: "CREATE SEQUENCE s OWNED BY NONE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := parseStatements(tt.sql)[0].(*tree.CreateSequence)
+			create, owner := splitSequenceOwner(seq)
+			if got := create.String(); got != tt.wantCreate {
+				t.Errorf("expected create %q, got %q", tt.wantCreate, got)
+			}
+			gotOwner := ""
+			if owner != nil {
+				gotOwner = strings.TrimSpace(tree.AsString(&tree.SequenceOptions{*owner}))
+			}
+			if gotOwner != tt.wantOwner {
+				t.Errorf("expected owner %q, got %q", tt.wantOwner, gotOwner)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/types"
@@ -27,20 +28,46 @@ func compareTables(local, remote *Schema) []Difference {
 		remoteTables[t.ResolvedName()] = t
 	}
 
+	// Tables renamed in place of a drop and create are compared under their new name
+	tableRenames, blocking := detectTableRenames(local, remote)
+	diffs = append(diffs, blocking...)
+	for _, r := range tableRenames {
+		diffs = append(diffs, buildTableRenameDiff(r))
+		delete(remoteTables, r.from.ResolvedName())
+		remoteTables[r.to.ResolvedName()] = r.from
+	}
+
 	// Find added and modified tables
 	for name, localTable := range localTables {
 		remoteTable, existsInRemote := remoteTables[name]
+		if existsInRemote && local.checksum(localTable.Ast) == remote.checksum(remoteTable.Ast) {
+			// Unchanged tables don't need to be compared column by column
+			continue
+		}
+		if existsInRemote {
+			// Renamed columns are renamed in place, then the rest of the table is compared
+			renames, inferred := detectColumnRenames(localTable.Ast, remoteTable.Ast, local.Renames.Columns[name])
+			if len(renames) > 0 {
+				renamed, err := renameTableColumns(remoteTable.Ast, renames)
+				if err == nil {
+					diffs = append(diffs, buildColumnRenameDiffs(name, localTable.Ast.Table, renames, inferred)...)
+					remoteTable.Ast = renamed
+				}
+			}
+		}
 		if !existsInRemote {
 			// Table added - create it
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTableAdded,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("Table '%s' added", name),
-				MigrationStatements: []tree.Statement{localTable.Ast},
+				Type:        DiffTypeTableAdded,
+				ObjectName:  name,
+				Description: fmt.Sprintf("Table '%s' added", name),
+				Phases:      inOnePhase(localTable.Ast),
 			})
 		} else {
 			// Table exists in both - check for modifications
 			tableDiffs := compareTableModifications(name, localTable.Ast, remoteTable.Ast, enumCtx)
+			applyUsingExpressions(tableDiffs, local.Using[name])
+			applyClassifyOverrides(tableDiffs, local.Classify[name])
 			diffs = append(diffs, tableDiffs...)
 		}
 	}
@@ -60,7 +87,7 @@ func compareTables(local, remote *Schema) []Difference {
 				ObjectName:           name,
 				Description:          fmt.Sprintf("Table '%s' removed", name),
 				Dangerous:            true,
-				MigrationStatements:  []tree.Statement{drop},
+				Phases:               inOnePhase(drop),
 				OriginalDependencies: originalDeps,
 			})
 		}
@@ -95,7 +122,13 @@ func extractTableComponents(stmt *tree.CreateTable) *tableComponents {
 		case *tree.ForeignKeyConstraintTableDef:
 			tc.constraints[d.Name.Normalize()] = d
 		case *tree.CheckConstraintTableDef:
-			tc.constraints[d.Name.Normalize()] = d
+			// Checks are matched by name when they have one, and by expression
+			// otherwise, so unnamed checks don't collide
+			if d.Name != "" {
+				tc.constraints[d.Name.Normalize()] = d
+			} else {
+				tc.constraints[formatNode(d)] = d
+			}
 		case *tree.UniqueConstraintTableDef:
 			tc.constraints[d.Name.Normalize()] = d
 
@@ -156,7 +189,7 @@ func compareTableModifications(tableName string, local, remote *tree.CreateTable
 	// generate separate DROP CONSTRAINT statements.
 	// Partial unique constraints with predicates referencing dropped columns need
 	// explicit DROP INDEX statements, returned separately.
-	predicateUniqueConstraints := removeConstraintsOnDroppedColumns(localComponents.columns, remoteComponents.columns, remoteComponents.constraints)
+	predicateUniqueConstraints := removeConstraintsOnDroppedColumns(localComponents.columns, remoteComponents.columns, localComponents.constraints, remoteComponents.constraints)
 
 	// Compare remaining columns (type changes already handled above).
 	// Family info is passed in so new ADD COLUMN statements include the FAMILY
@@ -188,7 +221,7 @@ func compareTableModifications(tableName string, local, remote *tree.CreateTable
 	indexDiffs := compareIndexes(tableName, local.Table, localComponents.indexes, remoteComponents.indexes)
 
 	// Compare remaining constraints
-	constraintDiffs := compareConstraints(tableName, local.Table, localComponents.constraints, remoteComponents.constraints)
+	constraintDiffs := compareConstraints(tableName, local.Table, localComponents.constraints, remoteComponents.constraints, droppedCols)
 
 	// CockroachDB requires columns to be "public" (committed) before they can be referenced
 	// in a partial index WHERE clause. If a new partial index references a newly-added column,
@@ -214,7 +247,9 @@ func compareTableModifications(tableName string, local, remote *tree.CreateTable
 	storageParamDiffs := compareStorageParams(tableName, local.Table, local.StorageParams, remote.StorageParams)
 	diffs = append(diffs, storageParamDiffs...)
 
-	familyDiffs := compareFamilies(tableName, local, remote, localComponents.columns, remoteComponents.columns)
+	// Compare families against every column; the component maps no longer hold
+	// the columns whose type changed, and a family move on those would go unnoticed.
+	familyDiffs := compareFamilies(tableName, local, remote, extractTableComponents(local).columns, extractTableComponents(remote).columns)
 	diffs = append(diffs, familyDiffs...)
 
 	return diffs
@@ -270,29 +305,25 @@ func handleColumnTypeChanges(
 		delete(remoteComponents.columns, colName)
 	}
 
-	statements := make([]tree.Statement, 0)
 	hasDrops := len(affectedRemoteIndexes) > 0 || len(affectedRemoteUniqueConstraints) > 0
 	hasCreates := len(affectedLocalIndexes) > 0 || len(affectedLocalUniqueConstraints) > 0
 
 	// Type changes requiring rewrite cannot run inside a transaction
-	statements = append(statements, &tree.CommitTransaction{}, &tree.BeginTransaction{})
+	phases := []Phase{{}}
 
 	if hasDrops {
+		var drops []tree.Statement
 		for indexName := range affectedRemoteIndexes {
-			statements = append(statements, &tree.DropIndex{
+			drops = append(drops, &tree.DropIndex{
 				IndexList:    tree.TableIndexNames{{Table: tableRef, Index: tree.UnrestrictedName(indexName)}},
 				DropBehavior: tree.DropRestrict,
 			})
 		}
 
-		for constraintName := range affectedRemoteUniqueConstraints {
-			statements = append(statements, &tree.DropIndex{
-				IndexList:    tree.TableIndexNames{{Table: tableRef, Index: tree.UnrestrictedName(constraintName)}},
-				DropBehavior: tree.DropCascade,
-			})
+		for _, uniqueConstraint := range affectedRemoteUniqueConstraints {
+			drops = append(drops, removeConstraint(tableRef, uniqueConstraint))
 		}
-
-		statements = append(statements, &tree.CommitTransaction{}, &tree.BeginTransaction{})
+		phases = append(phases, Phase{Statements: drops})
 	}
 
 	// ALTER COLUMN TYPE requiring rewrite must run outside a transaction.
@@ -300,43 +331,31 @@ func handleColumnTypeChanges(
 	// allow combining multiple ALTER COLUMN TYPE operations in a single ALTER TABLE.
 	for _, colName := range typeChangedColNames {
 		localCol := typeChangedLocalCols[colName]
-		statements = append(statements,
-			&tree.CommitTransaction{},
-			&tree.AlterTable{
-				Table: tableRef.ToUnresolvedObjectName(),
-				Cmds: tree.AlterTableCmds{
-					&tree.AlterTableAlterColumnType{
-						Column: localCol.Name,
-						ToType: localCol.Type,
-						Using:  buildColumnTypeChangeUsing(localCol, enumCtx),
+		phases = append(phases, Phase{
+			Statements: []tree.Statement{
+				&tree.AlterTable{
+					Table: tableRef.ToUnresolvedObjectName(),
+					Cmds: tree.AlterTableCmds{
+						&tree.AlterTableAlterColumnType{
+							Column: localCol.Name,
+							ToType: localCol.Type,
+							Using:  buildColumnTypeChangeUsing(localCol, enumCtx),
+						},
 					},
 				},
 			},
-			&tree.BeginTransaction{},
-		)
+			NoTransaction: true,
+		})
 	}
 
 	if hasCreates {
-		statements = append(statements, &tree.CommitTransaction{}, &tree.BeginTransaction{})
-
+		var creates []tree.Statement
 		for _, uniqueConstraint := range affectedLocalUniqueConstraints {
-			statements = append(statements, &tree.CreateIndex{
-				Name:             uniqueConstraint.Name,
-				Table:            tableRef,
-				Unique:           true,
-				Columns:          uniqueConstraint.Columns,
-				Storing:          uniqueConstraint.Storing,
-				Type:             uniqueConstraint.Type,
-				Sharded:          uniqueConstraint.Sharded,
-				PartitionByIndex: uniqueConstraint.PartitionByIndex,
-				StorageParams:    uniqueConstraint.StorageParams,
-				Predicate:        uniqueConstraint.Predicate,
-				Invisibility:     uniqueConstraint.Invisibility,
-			})
+			creates = append(creates, createConstraint(tableRef, uniqueConstraint))
 		}
 
 		for indexName, localIndex := range affectedLocalIndexes {
-			statements = append(statements, &tree.CreateIndex{
+			creates = append(creates, &tree.CreateIndex{
 				Name:             tree.Name(indexName),
 				Table:            tableRef,
 				Columns:          localIndex.Columns,
@@ -349,6 +368,7 @@ func handleColumnTypeChanges(
 				Invisibility:     localIndex.Invisibility,
 			})
 		}
+		phases = append(phases, Phase{Statements: creates})
 	}
 
 	// Build description
@@ -358,11 +378,11 @@ func handleColumnTypeChanges(
 	}
 
 	return []Difference{{
-		Type:                DiffTypeColumnTypeChanged,
-		ObjectName:          tableName,
-		Description:         description,
-		Dangerous:           true,
-		MigrationStatements: statements,
+		Type:        DiffTypeColumnTypeChanged,
+		ObjectName:  tableName,
+		Description: description,
+		Dangerous:   true,
+		Phases:      phases,
 	}}
 }
 
@@ -418,12 +438,12 @@ func compareColumns(tableName string, tableRef tree.TableName, localCols, remote
 				},
 			}
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTableModified,
-				ObjectName:          tableName,
-				Description:         fmt.Sprintf("Column '%s.%s' added", tableName, colName),
-				Dangerous:           warningMessage != "",
-				WarningMessage:      warningMessage,
-				MigrationStatements: []tree.Statement{createColumn},
+				Type:           DiffTypeTableModified,
+				ObjectName:     tableName,
+				Description:    fmt.Sprintf("Column '%s.%s' added", tableName, colName),
+				Dangerous:      warningMessage != "",
+				WarningMessage: warningMessage,
+				Phases:         inOnePhase(createColumn),
 			})
 		} else {
 			diffs = append(diffs, compareColumn(tableName, colName, tableRef, localCol, remoteCols[colName], enumCtx)...)
@@ -443,10 +463,10 @@ func compareColumns(tableName string, tableRef tree.TableName, localCols, remote
 				},
 			}
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTableModified,
-				ObjectName:          tableName,
-				Description:         fmt.Sprintf("Column '%s.%s' removed", tableName, colName),
-				MigrationStatements: []tree.Statement{removeColumn},
+				Type:        DiffTypeTableModified,
+				ObjectName:  tableName,
+				Description: fmt.Sprintf("Column '%s.%s' removed", tableName, colName),
+				Phases:      inOnePhase(removeColumn),
 			})
 		}
 	}
@@ -465,29 +485,31 @@ func compareColumn(tableName, colName string, tableRef tree.TableName, localCol,
 				Dangerous:      true,
 				WarningMessage: fmt.Sprintf("Column '%s.%s' will be dropped and re-created, can result in data loss.", tableName, colName),
 				IsDropCreate:   true,
-				MigrationStatements: []tree.Statement{
-					&tree.AlterTable{
-						Table: tableRef.ToUnresolvedObjectName(),
-						Cmds: tree.AlterTableCmds{
-							&tree.AlterTableDropColumn{
-								Column:       tree.Name(colName),
-								DropBehavior: tree.DropRestrict,
+				Phases: []Phase{
+					{Statements: []tree.Statement{
+						&tree.AlterTable{
+							Table: tableRef.ToUnresolvedObjectName(),
+							Cmds: tree.AlterTableCmds{
+								&tree.AlterTableDropColumn{
+									Column:       tree.Name(colName),
+									DropBehavior: tree.DropRestrict,
+								},
 							},
 						},
-					},
+					}},
 					// CockroachDB drops columns asynchronously. Without a transaction
 					// boundary, the ADD COLUMN fails with "column being dropped, try
 					// again later" because the column name is still in use.
-					&tree.CommitTransaction{},
-					&tree.BeginTransaction{},
-					&tree.AlterTable{
-						Table: tableRef.ToUnresolvedObjectName(),
-						Cmds: tree.AlterTableCmds{
-							&tree.AlterTableAddColumn{
-								ColumnDef: localCol,
+					{Statements: []tree.Statement{
+						&tree.AlterTable{
+							Table: tableRef.ToUnresolvedObjectName(),
+							Cmds: tree.AlterTableCmds{
+								&tree.AlterTableAddColumn{
+									ColumnDef: localCol,
+								},
 							},
 						},
-					},
+					}},
 				},
 			},
 		}
@@ -509,12 +531,12 @@ func compareColumn(tableName, colName string, tableRef tree.TableName, localCol,
 			ObjectName:  tableName,
 			Description: fmt.Sprintf("Column '%s.%s' type changed from %s to %s", tableName, colName, remoteCol.Type.SQLString(), localCol.Type.SQLString()),
 			Dangerous:   true,
-			MigrationStatements: []tree.Statement{
+			Phases: inOnePhase(
 				&tree.AlterTable{
 					Table: tableRef.ToUnresolvedObjectName(),
 					Cmds:  tree.AlterTableCmds{typeChangeCmd},
 				},
-			},
+			),
 		})
 	}
 
@@ -592,7 +614,7 @@ func compareColumn(tableName, colName string, tableRef tree.TableName, localCol,
 		})
 	}
 
-	// TODO: Column families?
+	// Family membership can't be changed in place, see compareFamilies
 
 	if len(cmds) > 0 {
 		alterTable := &tree.AlterTable{
@@ -600,11 +622,11 @@ func compareColumn(tableName, colName string, tableRef tree.TableName, localCol,
 			Cmds:  cmds,
 		}
 		diffs = append(diffs, Difference{
-			Type:                DiffTypeTableModified,
-			ObjectName:          tableName,
-			Description:         fmt.Sprintf("Column '%s.%s' modified", tableName, colName),
-			Dangerous:           dangerous,
-			MigrationStatements: []tree.Statement{alterTable},
+			Type:        DiffTypeTableModified,
+			ObjectName:  tableName,
+			Description: fmt.Sprintf("Column '%s.%s' modified", tableName, colName),
+			Dangerous:   dangerous,
+			Phases:      inOnePhase(alterTable),
 		})
 	}
 	return diffs
@@ -631,28 +653,30 @@ func compareIndexes(tableName string, tableRef tree.TableName, localIndexes, rem
 		if remoteIndex, existsInRemote := remoteIndexes[indexName]; !existsInRemote {
 			// Index added - generate CREATE INDEX
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTableModified,
-				ObjectName:          tableName,
-				Description:         fmt.Sprintf("Index '%s.%s' added", tableName, indexName),
-				MigrationStatements: []tree.Statement{createIndex},
+				Type:        DiffTypeTableModified,
+				ObjectName:  tableName,
+				Description: fmt.Sprintf("Index '%s.%s' added", tableName, indexName),
+				Phases:      inOnePhase(createIndex),
 			})
 		} else {
 			// Compare index definitions, if they differ at all, drop / create them.
-			localIndexStr := formatNode(localIndex)
-			remoteIndexStr := formatNode(remoteIndex)
+			localIndexStr := indexComparisonKey(localIndex)
+			remoteIndexStr := indexComparisonKey(remoteIndex)
 
-			if localIndexStr != remoteIndexStr {
+			if localIndexStr != remoteIndexStr && indexVisibilityChanged(localIndex, remoteIndex) {
+				diffs = append(diffs, buildIndexVisibilityDiff(tableName, tableRef, indexName, localIndex.Invisibility))
+			} else if localIndexStr != remoteIndexStr {
 				dropIndex := &tree.DropIndex{
 					IndexList:    tree.TableIndexNames{{Table: tableRef, Index: tree.UnrestrictedName(indexName)}},
 					DropBehavior: tree.DropRestrict,
 				}
 				diffs = append(diffs, Difference{
-					Type:                DiffTypeTableModified,
-					ObjectName:          tableName,
-					Description:         fmt.Sprintf("Index '%s.%s' modified", tableName, indexName),
-					Dangerous:           true,
-					IsDropCreate:        true,
-					MigrationStatements: []tree.Statement{dropIndex, &tree.CommitTransaction{}, &tree.BeginTransaction{}, createIndex},
+					Type:         DiffTypeTableModified,
+					ObjectName:   tableName,
+					Description:  fmt.Sprintf("Index '%s.%s' modified", tableName, indexName),
+					Dangerous:    true,
+					IsDropCreate: true,
+					Phases:       []Phase{{Statements: []tree.Statement{dropIndex}}, {Statements: []tree.Statement{createIndex}}},
 				})
 			}
 		}
@@ -667,11 +691,11 @@ func compareIndexes(tableName string, tableRef tree.TableName, localIndexes, rem
 				DropBehavior: tree.DropRestrict,
 			}
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTableModified,
-				ObjectName:          tableName,
-				Description:         fmt.Sprintf("Index '%s.%s' removed", tableName, indexName),
-				Dangerous:           true,
-				MigrationStatements: []tree.Statement{dropIndex},
+				Type:        DiffTypeTableModified,
+				ObjectName:  tableName,
+				Description: fmt.Sprintf("Index '%s.%s' removed", tableName, indexName),
+				Dangerous:   true,
+				Phases:      inOnePhase(dropIndex),
 			})
 		}
 	}
@@ -680,7 +704,7 @@ func compareIndexes(tableName string, tableRef tree.TableName, localIndexes, rem
 }
 
 // compareConstraints finds differences in table constraints.
-func compareConstraints(tableName string, tableRef tree.TableName, localConstraints, remoteConstraints map[string]tree.ConstraintTableDef) []Difference {
+func compareConstraints(tableName string, tableRef tree.TableName, localConstraints, remoteConstraints map[string]tree.ConstraintTableDef, droppedCols map[string]bool) []Difference {
 	diffs := make([]Difference, 0)
 	localPrimaryKey := findPrimaryKey(localConstraints)
 	remotePrimaryKey := findPrimaryKey(remoteConstraints)
@@ -700,20 +724,23 @@ func compareConstraints(tableName string, tableRef tree.TableName, localConstrai
 			Description:  "Primary key modified",
 			Dangerous:    true,
 			IsDropCreate: false,
-			MigrationStatements: []tree.Statement{
-				&tree.CommitTransaction{}, &tree.BeginTransaction{},
-				&tree.AlterTable{
-					Table: tableRef.ToUnresolvedObjectName(),
-					Cmds: tree.AlterTableCmds{
-						&tree.AlterTableDropConstraint{
-							Constraint: remotePrimaryKey.Name,
-						},
-						&tree.AlterTableAddConstraint{
-							ConstraintDef: localPrimaryKey,
+			// The primary key change runs in a transaction of its own
+			Phases: []Phase{
+				{},
+				{Statements: []tree.Statement{
+					&tree.AlterTable{
+						Table: tableRef.ToUnresolvedObjectName(),
+						Cmds: tree.AlterTableCmds{
+							&tree.AlterTableDropConstraint{
+								Constraint: remotePrimaryKey.Name,
+							},
+							&tree.AlterTableAddConstraint{
+								ConstraintDef: localPrimaryKey,
+							},
 						},
 					},
-				},
-				&tree.CommitTransaction{}, &tree.BeginTransaction{},
+				}},
+				{},
 			},
 		})
 	}
@@ -725,26 +752,37 @@ func compareConstraints(tableName string, tableRef tree.TableName, localConstrai
 			remoteConstraintStr := formatNode(remoteConstraint)
 
 			if localConstraintStr != remoteConstraintStr {
+				if localFK, remoteFK, ok := foreignKeyActionsChanged(localConstraint, remoteConstraint); ok {
+					diffs = append(diffs, buildForeignKeyActionDiff(tableName, tableRef, localFK, remoteFK))
+					continue
+				}
+				if localCheck, remoteCheck, ok := checkConstraintsByName(localConstraint, remoteConstraint); ok {
+					diffs = append(diffs, buildCheckModifiedDiff(tableName, tableRef, localCheck, remoteCheck, droppedCols))
+					continue
+				}
+				if localUnique, ok := uniqueVisibilityChanged(localConstraint, remoteConstraint); ok {
+					diffs = append(diffs, buildIndexVisibilityDiff(tableName, tableRef, constraintName, localUnique.Invisibility))
+					continue
+				}
 				diffs = append(diffs, Difference{
 					Type:         DiffTypeTableModified,
 					ObjectName:   tableName,
 					Description:  fmt.Sprintf("Constraint '%s' modified", constraintName),
 					Dangerous:    true,
 					IsDropCreate: true,
-					MigrationStatements: []tree.Statement{
-						removeConstraint(tableRef, remoteConstraint),
-						&tree.CommitTransaction{}, &tree.BeginTransaction{},
-						createConstraint(tableRef, localConstraint),
+					Phases: []Phase{
+						{Statements: []tree.Statement{removeConstraint(tableRef, remoteConstraint)}},
+						{Statements: []tree.Statement{createConstraint(tableRef, localConstraint)}},
 					},
 				})
 			}
 		} else {
 			createStatement := createConstraint(tableRef, localConstraint)
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTableModified,
-				ObjectName:          tableName,
-				Description:         fmt.Sprintf("Constraint '%s' added", constraintName),
-				MigrationStatements: []tree.Statement{createStatement},
+				Type:        DiffTypeTableModified,
+				ObjectName:  tableName,
+				Description: fmt.Sprintf("Constraint '%s' added", constraintName),
+				Phases:      inOnePhase(createStatement),
 			})
 		}
 	}
@@ -754,11 +792,11 @@ func compareConstraints(tableName string, tableRef tree.TableName, localConstrai
 		if _, existsInLocal := localConstraints[constraintName]; !existsInLocal {
 			dropStatement := removeConstraint(tableRef, remoteConstraint)
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTableModified,
-				ObjectName:          tableName,
-				Description:         fmt.Sprintf("Constraint %s removed", constraintName),
-				Dangerous:           true,
-				MigrationStatements: []tree.Statement{dropStatement},
+				Type:        DiffTypeTableModified,
+				ObjectName:  tableName,
+				Description: fmt.Sprintf("Constraint %s removed", constraintName),
+				Dangerous:   true,
+				Phases:      inOnePhase(dropStatement),
 			})
 		}
 	}
@@ -766,6 +804,118 @@ func compareConstraints(tableName string, tableRef tree.TableName, localConstrai
 	return diffs
 }
 
+// foreignKeyActionsChanged reports whether two versions of a constraint are the same
+// foreign key apart from their ON DELETE / ON UPDATE actions.
+func foreignKeyActionsChanged(local, remote tree.ConstraintTableDef) (*tree.ForeignKeyConstraintTableDef, *tree.ForeignKeyConstraintTableDef, bool) {
+	localFK, ok := local.(*tree.ForeignKeyConstraintTableDef)
+	if !ok {
+		return nil, nil, false
+	}
+	remoteFK, ok := remote.(*tree.ForeignKeyConstraintTableDef)
+	if !ok || localFK.Actions == remoteFK.Actions {
+		return nil, nil, false
+	}
+
+	withRemoteActions := *localFK
+	withRemoteActions.Actions = remoteFK.Actions
+	if formatNode(&withRemoteActions) != formatNode(remoteFK) {
+		return nil, nil, false
+	}
+	return localFK, remoteFK, true
+}
+
+// buildForeignKeyActionDiff replaces a foreign key whose actions changed. The
+// replacement is added NOT VALID under a temporary name before the old one is
+// dropped, so the table is never without a foreign key that new writes must
+// satisfy. It then takes over the old name and is validated against existing
+// rows in its own transaction.
+func buildForeignKeyActionDiff(tableName string, tableRef tree.TableName, local, remote *tree.ForeignKeyConstraintTableDef) Difference {
+	replacement := *local
+	replacement.Name = tree.Name(local.Name.Normalize() + "_scurry_new")
+
+	return Difference{
+		Type:        DiffTypeTableModified,
+		ObjectName:  tableName,
+		Description: fmt.Sprintf("Foreign key '%s' actions changed from %s to %s", local.Name.Normalize(), formatReferenceActions(remote.Actions), formatReferenceActions(local.Actions)),
+		Phases: []Phase{
+			{Statements: []tree.Statement{
+				&tree.AlterTable{
+					Table: tableRef.ToUnresolvedObjectName(),
+					Cmds: tree.AlterTableCmds{
+						&tree.AlterTableAddConstraint{
+							ConstraintDef:      &replacement,
+							ValidationBehavior: tree.ValidationSkip,
+						},
+					},
+				},
+			}},
+			{Statements: []tree.Statement{
+				removeConstraint(tableRef, remote),
+			}},
+			{Statements: []tree.Statement{
+				&tree.AlterTable{
+					Table: tableRef.ToUnresolvedObjectName(),
+					Cmds: tree.AlterTableCmds{
+						&tree.AlterTableRenameConstraint{Constraint: replacement.Name, NewName: local.Name},
+					},
+				},
+			}},
+			{Statements: []tree.Statement{
+				&tree.AlterTable{
+					Table: tableRef.ToUnresolvedObjectName(),
+					Cmds: tree.AlterTableCmds{
+						&tree.AlterTableValidateConstraint{Constraint: local.Name},
+					},
+				},
+			}},
+		},
+	}
+}
+
+// checkConstraintsByName returns two versions of a constraint when both are
+// named CHECK constraints
+func checkConstraintsByName(local, remote tree.ConstraintTableDef) (*tree.CheckConstraintTableDef, *tree.CheckConstraintTableDef, bool) {
+	localCheck, ok := local.(*tree.CheckConstraintTableDef)
+	if !ok || localCheck.Name == "" {
+		return nil, nil, false
+	}
+	remoteCheck, ok := remote.(*tree.CheckConstraintTableDef)
+	if !ok || remoteCheck.Name == "" {
+		return nil, nil, false
+	}
+	return localCheck, remoteCheck, true
+}
+
+// buildCheckModifiedDiff replaces a CHECK constraint whose expression changed,
+// dropping the old one and adding the new one under the same name in the next
+// transaction. The old expression may reference columns being dropped, which
+// would take the constraint with them, so the difference runs before those
+// columns are dropped. The new expression's columns order it after any columns
+// being added.
+func buildCheckModifiedDiff(tableName string, tableRef tree.TableName, local, remote *tree.CheckConstraintTableDef, droppedCols map[string]bool) Difference {
+	schemaName, table := getTableName(tableRef)
+	return Difference{
+		Type:         DiffTypeTableModified,
+		ObjectName:   tableName,
+		Description:  fmt.Sprintf("Constraint '%s' modified", local.Name.Normalize()),
+		Dangerous:    true,
+		IsDropCreate: true,
+		Phases: []Phase{
+			{Statements: []tree.Statement{removeConstraint(tableRef, remote)}},
+			{Statements: []tree.Statement{createConstraint(tableRef, local)}},
+		},
+		OriginalDependencies: collectDroppedColumnDeps(schemaName, table, remote.Expr, nil, droppedCols),
+	}
+}
+
+// formatReferenceActions formats foreign key actions for descriptions, e.g. "ON DELETE CASCADE"
+func formatReferenceActions(actions tree.ReferenceActions) string {
+	if formatted := strings.TrimSpace(formatNode(&actions)); formatted != "" {
+		return formatted
+	}
+	return "NO ACTION"
+}
+
 func getConstraintName(constraint tree.ConstraintTableDef) string {
 	name := ""
 	switch constraint := constraint.(type) {
@@ -779,8 +929,10 @@ func getConstraintName(constraint tree.ConstraintTableDef) string {
 	return name
 }
 
+// createConstraint adds a constraint to an existing table. UNIQUE constraints
+// are added as unique indexes, unless they're WITHOUT INDEX.
 func createConstraint(tableRef tree.TableName, constraint tree.ConstraintTableDef) tree.Statement {
-	if uniqueConstraint, ok := constraint.(*tree.UniqueConstraintTableDef); ok && !uniqueConstraint.PrimaryKey {
+	if uniqueConstraint, ok := constraint.(*tree.UniqueConstraintTableDef); ok && !uniqueConstraint.PrimaryKey && !uniqueConstraint.WithoutIndex {
 		return &tree.CreateIndex{
 			Name:             uniqueConstraint.Name,
 			Table:            tableRef,
@@ -800,14 +952,14 @@ func createConstraint(tableRef tree.TableName, constraint tree.ConstraintTableDe
 		Cmds: tree.AlterTableCmds{
 			&tree.AlterTableAddConstraint{
 				ConstraintDef:      constraint,
-				ValidationBehavior: tree.ValidationDefault, // TODO: support deferred constraints?
+				ValidationBehavior: tree.ValidationDefault, // See ComparisonResult.DeferValidation
 			},
 		},
 	}
 }
 
 func removeConstraint(tableRef tree.TableName, constraint tree.ConstraintTableDef) tree.Statement {
-	if uniqueConstraint, ok := constraint.(*tree.UniqueConstraintTableDef); ok && !uniqueConstraint.PrimaryKey {
+	if uniqueConstraint, ok := constraint.(*tree.UniqueConstraintTableDef); ok && !uniqueConstraint.PrimaryKey && !uniqueConstraint.WithoutIndex {
 		return &tree.DropIndex{
 			IndexList:    tree.TableIndexNames{{Table: tableRef, Index: tree.UnrestrictedName(uniqueConstraint.Name)}},
 			DropBehavior: tree.DropCascade,
@@ -838,7 +990,7 @@ func findPrimaryKey(constraints map[string]tree.ConstraintTableDef) *tree.Unique
 }
 
 // getIndexColumnNames returns the column names referenced by an index,
-// including columns in the WHERE clause predicate.
+// including columns in expressions and the WHERE clause predicate.
 func getIndexColumnNames(index *tree.IndexTableDef) []string {
 	cols := make([]string, 0, len(index.Columns)+len(index.Storing))
 	for _, col := range index.Columns {
@@ -846,6 +998,7 @@ func getIndexColumnNames(index *tree.IndexTableDef) []string {
 			cols = append(cols, col.Column.Normalize())
 		}
 	}
+	cols = append(cols, indexExpressionColumns(index.Columns)...)
 	for _, col := range index.Storing {
 		cols = append(cols, col.Normalize())
 	}
@@ -863,6 +1016,7 @@ func getUniqueConstraintColumnNames(constraint *tree.UniqueConstraintTableDef) [
 			cols = append(cols, col.Column.Normalize())
 		}
 	}
+	cols = append(cols, indexExpressionColumns(constraint.Columns)...)
 	for _, col := range constraint.Storing {
 		cols = append(cols, col.Normalize())
 	}
@@ -967,7 +1121,7 @@ func (v *checkConstraintColumnVisitor) VisitPost(expr tree.Expr) tree.Expr {
 // Partial unique constraints (UNIQUE INDEX with WHERE predicate) that reference
 // dropped columns are NOT auto-dropped by CockroachDB. These are returned as a
 // separate map so the caller can generate explicit DROP INDEX statements.
-func removeConstraintsOnDroppedColumns(localColumns, remoteColumns map[string]*tree.ColumnTableDef, remoteConstraints map[string]tree.ConstraintTableDef) map[string]*tree.UniqueConstraintTableDef {
+func removeConstraintsOnDroppedColumns(localColumns, remoteColumns map[string]*tree.ColumnTableDef, localConstraints, remoteConstraints map[string]tree.ConstraintTableDef) map[string]*tree.UniqueConstraintTableDef {
 	// Find dropped columns
 	droppedCols := make(map[string]bool)
 	for colName := range remoteColumns {
@@ -1001,6 +1155,12 @@ func removeConstraintsOnDroppedColumns(localColumns, remoteColumns map[string]*t
 			}
 		}
 
+		// A CHECK constraint that is still defined is replaced by name instead,
+		// so the new one isn't added while the old one still holds its name
+		if _, _, ok := checkConstraintsByName(localConstraints[constraintName], constraint); ok {
+			continue
+		}
+
 		for _, col := range getConstraintColumns(constraint) {
 			if droppedCols[col] {
 				delete(remoteConstraints, constraintName)
@@ -1012,8 +1172,9 @@ func removeConstraintsOnDroppedColumns(localColumns, remoteColumns map[string]*t
 	return predicateUniqueConstraints
 }
 
-// getIndexKeyAndStoringColumnNames returns only the key and storing column names
-// for an index, excluding columns referenced only in the WHERE predicate.
+// getIndexKeyAndStoringColumnNames returns only the key (including those in
+// expressions) and storing column names for an index, excluding columns
+// referenced only in the WHERE predicate.
 func getIndexKeyAndStoringColumnNames(index *tree.IndexTableDef) []string {
 	cols := make([]string, 0, len(index.Columns)+len(index.Storing))
 	for _, col := range index.Columns {
@@ -1021,6 +1182,7 @@ func getIndexKeyAndStoringColumnNames(index *tree.IndexTableDef) []string {
 			cols = append(cols, col.Column.Normalize())
 		}
 	}
+	cols = append(cols, indexExpressionColumns(index.Columns)...)
 	for _, col := range index.Storing {
 		cols = append(cols, col.Normalize())
 	}
@@ -1032,8 +1194,9 @@ func getIndexKeyAndStoringColumnNames(index *tree.IndexTableDef) []string {
 // Non-partial indexes where the dropped column is in key/storing columns are
 // auto-dropped by CockroachDB when the column is dropped. Partial indexes
 // (those with WHERE predicates) that reference dropped columns anywhere — in
-// key, storing, or predicate — are NOT auto-dropped and are returned so the
-// caller can generate explicit DROP INDEX statements.
+// key, storing, or predicate — are NOT auto-dropped, nor are indexes with an
+// expression that references one. Those are returned so the caller can
+// generate explicit DROP INDEX statements.
 func removeIndexesOnDroppedColumns(localColumns, remoteColumns map[string]*tree.ColumnTableDef, remoteIndexes map[string]*tree.IndexTableDef, tableRef tree.TableName) map[string]*tree.IndexTableDef {
 	// Find dropped columns
 	droppedCols := make(map[string]bool)
@@ -1059,21 +1222,25 @@ func removeIndexesOnDroppedColumns(localColumns, remoteColumns map[string]*tree.
 			}
 		}
 
-		// Check if any dropped column is in the predicate
+		// Check if any dropped column is in the predicate or an expression
 		inPredicate := false
+		exprCols := indexExpressionColumns(index.Columns)
 		if index.Predicate != nil {
-			for _, col := range getCheckConstraintColumns(index.Predicate) {
-				if droppedCols[col] {
-					inPredicate = true
-					break
-				}
+			exprCols = append(exprCols, getCheckConstraintColumns(index.Predicate)...)
+		}
+		for _, col := range exprCols {
+			if droppedCols[col] {
+				inPredicate = true
+				break
 			}
 		}
 
 		if inPredicate {
 			// CockroachDB does NOT auto-drop partial indexes when a column they
 			// reference in their WHERE predicate is dropped, regardless of whether
-			// the column is also in key/storing columns. Need explicit DROP INDEX.
+			// the column is also in key/storing columns. Expression indexes are
+			// dropped explicitly too, since their key is a hidden computed column
+			// rather than the dropped one. Need explicit DROP INDEX.
 			predicateOnlyIndexes[indexName] = index
 			delete(remoteIndexes, indexName)
 		} else if inKeyStoring {
@@ -1102,12 +1269,12 @@ func buildDropIndexDiffsForDroppedColumns(indexes map[string]*tree.IndexTableDef
 			Type:        DiffTypeTableModified,
 			ObjectName:  tableName,
 			Description: fmt.Sprintf("Index '%s.%s' dropped (referenced column being dropped)", tableName, indexName),
-			MigrationStatements: []tree.Statement{
+			Phases: inOnePhase(
 				&tree.DropIndex{
 					IndexList:    tree.TableIndexNames{{Table: tableRef, Index: tree.UnrestrictedName(indexName)}},
 					DropBehavior: tree.DropRestrict,
 				},
-			},
+			),
 			OriginalDependencies: deps,
 		})
 	}
@@ -1126,15 +1293,10 @@ func buildDropUniqueConstraintIndexDiffsForDroppedColumns(constraints map[string
 	for constraintName, uc := range constraints {
 		deps := collectDroppedColumnDeps(schemaName, tableName, uc.Predicate, getUniqueConstraintColumnNames(uc), droppedCols)
 		diffs = append(diffs, Difference{
-			Type:        DiffTypeTableModified,
-			ObjectName:  tableName,
-			Description: fmt.Sprintf("Unique constraint index '%s.%s' dropped (referenced column being dropped)", tableName, constraintName),
-			MigrationStatements: []tree.Statement{
-				&tree.DropIndex{
-					IndexList:    tree.TableIndexNames{{Table: tableRef, Index: tree.UnrestrictedName(constraintName)}},
-					DropBehavior: tree.DropCascade,
-				},
-			},
+			Type:                 DiffTypeTableModified,
+			ObjectName:           tableName,
+			Description:          fmt.Sprintf("Unique constraint index '%s.%s' dropped (referenced column being dropped)", tableName, constraintName),
+			Phases:               inOnePhase(removeConstraint(tableRef, uc)),
 			OriginalDependencies: deps,
 		})
 	}
@@ -1163,14 +1325,14 @@ func collectDroppedColumnDeps(schemaName, tableName string, predicate tree.Expr,
 	return deps
 }
 
-// addPartialIndexTransactionBoundaries prepends COMMIT/BEGIN to any CREATE INDEX
-// statement that has a WHERE predicate referencing a newly-added column.
+// addPartialIndexTransactionBoundaries starts any diff with a CREATE INDEX whose
+// WHERE predicate references a newly-added column in a new transaction.
 // CockroachDB requires columns to be fully "public" (committed) before they can
 // be referenced in a partial index WHERE clause.
 func addPartialIndexTransactionBoundaries(newColumns map[string]bool, diffs []Difference) {
 	for i, diff := range diffs {
 		needsBoundary := false
-		for _, stmt := range diff.MigrationStatements {
+		for _, stmt := range diff.Statements() {
 			createIdx, ok := stmt.(*tree.CreateIndex)
 			if !ok || createIdx.Predicate == nil {
 				continue
@@ -1186,10 +1348,7 @@ func addPartialIndexTransactionBoundaries(newColumns map[string]bool, diffs []Di
 			}
 		}
 		if needsBoundary {
-			diffs[i].MigrationStatements = append(
-				[]tree.Statement{&tree.CommitTransaction{}, &tree.BeginTransaction{}},
-				diffs[i].MigrationStatements...,
-			)
+			diffs[i].Phases = append([]Phase{{}}, diffs[i].Phases...)
 		}
 	}
 }
@@ -1198,7 +1357,7 @@ func addPartialIndexTransactionBoundaries(newColumns map[string]bool, diffs []Di
 // ALTER TABLE ADD CONSTRAINT FOREIGN KEY statement.
 func hasNewForeignKeyConstraint(diffs []Difference) bool {
 	for _, diff := range diffs {
-		for _, stmt := range diff.MigrationStatements {
+		for _, stmt := range diff.Statements() {
 			alterTable, ok := stmt.(*tree.AlterTable)
 			if !ok {
 				continue
@@ -1215,17 +1374,14 @@ func hasNewForeignKeyConstraint(diffs []Difference) bool {
 	return false
 }
 
-// addCreateIndexTransactionBoundaries prepends COMMIT/BEGIN to any diff that
-// contains a CREATE INDEX statement. This is used to separate CREATE INDEX from
+// addCreateIndexTransactionBoundaries starts any diff that contains a CREATE
+// INDEX statement in a new transaction. This is used to separate CREATE INDEX from
 // ADD CONSTRAINT FOREIGN KEY on the same table within the same transaction.
 func addCreateIndexTransactionBoundaries(diffs []Difference) {
 	for i, diff := range diffs {
-		for _, stmt := range diff.MigrationStatements {
+		for _, stmt := range diff.Statements() {
 			if _, ok := stmt.(*tree.CreateIndex); ok {
-				diffs[i].MigrationStatements = append(
-					[]tree.Statement{&tree.CommitTransaction{}, &tree.BeginTransaction{}},
-					diffs[i].MigrationStatements...,
-				)
+				diffs[i].Phases = append([]Phase{{}}, diffs[i].Phases...)
 				break
 			}
 		}
@@ -1295,7 +1451,9 @@ func typeChangeRequiresRewrite(localType, remoteType *types.T) bool {
 }
 
 // compareStorageParams compares table-level storage parameters (like TTL settings)
-// and generates ALTER TABLE SET/RESET statements for changes.
+// and generates an ALTER TABLE SET and/or RESET for changes. Parameters are set
+// before others are reset, so switching between ttl_expire_after and
+// ttl_expiration_expression never leaves the table without an expiration.
 func compareStorageParams(tableName string, tableRef tree.TableName, localParams, remoteParams tree.StorageParams) []Difference {
 	diffs := make([]Difference, 0)
 
@@ -1317,76 +1475,82 @@ func compareStorageParams(tableName string, tableRef tree.TableName, localParams
 		remoteParamMap[p.Key] = p.Value
 	}
 
-	// Find added or modified params
+	// Find added or modified params, in the order they're defined
 	var addedOrModified tree.StorageParams
-	for key, localValue := range localParamMap {
-		remoteValue, existsInRemote := remoteParamMap[key]
-		if !existsInRemote {
-			// Param added
-			addedOrModified = append(addedOrModified, tree.StorageParam{Key: key, Value: localValue})
-		} else if formatExpr(localValue) != formatExpr(remoteValue) {
-			// Param modified
-			addedOrModified = append(addedOrModified, tree.StorageParam{Key: key, Value: localValue})
+	for _, p := range localParams {
+		remoteValue, existsInRemote := remoteParamMap[p.Key]
+		if !existsInRemote || formatExpr(p.Value) != formatExpr(remoteValue) {
+			addedOrModified = append(addedOrModified, tree.StorageParam{Key: p.Key, Value: p.Value})
 		}
 	}
 
-	// Find removed params
+	// Find removed params. Resetting ttl removes every ttl_* parameter with it,
+	// and CockroachDB refuses to reset the expiration of a table that keeps TTL
+	// enabled, so the other TTL parameters aren't reset on their own.
+	_, ttlRemoved := remoteParamMap["ttl"]
+	if _, ok := localParamMap["ttl"]; ok {
+		ttlRemoved = false
+	}
 	var removed []string
-	for key := range remoteParamMap {
-		if _, existsInLocal := localParamMap[key]; !existsInLocal {
-			removed = append(removed, key)
+	for _, p := range remoteParams {
+		if _, inRemote := remoteParamMap[p.Key]; !inRemote {
+			continue
 		}
-	}
-
-	// Generate SET statement for added/modified params
-	if len(addedOrModified) > 0 {
-		setCmd := &tree.AlterTable{
-			Table: tableRef.ToUnresolvedObjectName(),
-			Cmds: tree.AlterTableCmds{
-				&tree.AlterTableSetStorageParams{
-					StorageParams: addedOrModified,
-				},
-			},
+		if _, existsInLocal := localParamMap[p.Key]; existsInLocal {
+			continue
 		}
-
-		description := fmt.Sprintf("Storage params changed for '%s'", tableName)
-		if len(addedOrModified) == 1 {
-			description = fmt.Sprintf("Storage param '%s' set on '%s'", addedOrModified[0].Key, tableName)
+		if ttlRemoved && strings.HasPrefix(p.Key, "ttl_") {
+			continue
 		}
+		removed = append(removed, p.Key)
+	}
 
-		diffs = append(diffs, Difference{
-			Type:                DiffTypeTableModified,
-			ObjectName:          tableName,
-			Description:         description,
-			MigrationStatements: []tree.Statement{setCmd},
-		})
+	if len(addedOrModified) == 0 && len(removed) == 0 {
+		return diffs
 	}
 
-	// Generate RESET statement for removed params
+	var cmds tree.AlterTableCmds
+	if len(addedOrModified) > 0 {
+		cmds = append(cmds, &tree.AlterTableSetStorageParams{StorageParams: addedOrModified})
+	}
 	if len(removed) > 0 {
-		resetCmd := &tree.AlterTable{
+		cmds = append(cmds, &tree.AlterTableResetStorageParams{Params: removed})
+	}
+	var stmts []tree.Statement
+	for _, cmd := range cmds {
+		stmts = append(stmts, &tree.AlterTable{
 			Table: tableRef.ToUnresolvedObjectName(),
-			Cmds: tree.AlterTableCmds{
-				&tree.AlterTableResetStorageParams{
-					Params: removed,
-				},
-			},
-		}
+			Cmds:  tree.AlterTableCmds{cmd},
+		})
+	}
 
-		description := fmt.Sprintf("Storage params removed from '%s'", tableName)
-		if len(removed) == 1 {
-			description = fmt.Sprintf("Storage param '%s' removed from '%s'", removed[0], tableName)
-		}
+	description := fmt.Sprintf("Storage params changed for '%s'", tableName)
+	switch {
+	case len(addedOrModified) == 1 && len(removed) == 0:
+		description = fmt.Sprintf("Storage param '%s' set on '%s'", addedOrModified[0].Key, tableName)
+	case len(addedOrModified) == 0 && len(removed) == 1:
+		description = fmt.Sprintf("Storage param '%s' removed from '%s'", removed[0], tableName)
+	case len(addedOrModified) == 0:
+		description = fmt.Sprintf("Storage params removed from '%s'", tableName)
+	}
 
-		diffs = append(diffs, Difference{
-			Type:                DiffTypeTableModified,
-			ObjectName:          tableName,
-			Description:         description,
-			MigrationStatements: []tree.Statement{resetCmd},
-		})
+	diff := Difference{
+		Type:        DiffTypeTableModified,
+		ObjectName:  tableName,
+		Description: description,
+		Phases:      inOnePhase(stmts...),
 	}
 
-	return diffs
+	// A new or changed expiration makes the TTL job delete rows that were kept before
+	for _, p := range addedOrModified {
+		if p.Key == "ttl_expire_after" || p.Key == "ttl_expiration_expression" {
+			diff.Dangerous = true
+			diff.WarningMessage = fmt.Sprintf("The row-level TTL of '%s' changed, so its TTL job will delete the rows that have expired under the new %s.", tableName, p.Key)
+			break
+		}
+	}
+
+	return append(diffs, diff)
 }
 
 // formatExpr returns a string representation of an expression for comparison.
@@ -245,12 +245,12 @@ func TestTableAddedMigration(t *testing.T) {
 				t.Errorf("expected DiffTypeTableAdded, got %s", diff.Type)
 			}
 
-			if len(diff.MigrationStatements) != 1 {
-				t.Errorf("expected 1 migration statement, got %d", len(diff.MigrationStatements))
+			if len(diff.Statements()) != 1 {
+				t.Errorf("expected 1 migration statement, got %d", len(diff.Statements()))
 			}
 
 			// Check for expected strings in migration DDL
-			allDDL := strings.Join(statementsToStringsTables(diff.MigrationStatements), "\n")
+			allDDL := strings.Join(statementsToStringsTables(diff.Statements()), "\n")
 			for _, expected := range tt.wantContains {
 				if !strings.Contains(allDDL, expected) {
 					t.Errorf("migration DDL missing expected string %q.\nGot:\n%s", expected, allDDL)
@@ -304,12 +304,12 @@ func TestTableRemovedMigration(t *testing.T) {
 				t.Errorf("expected DiffTypeTableRemoved, got %s", diff.Type)
 			}
 
-			if len(diff.MigrationStatements) != 1 {
-				t.Errorf("expected 1 migration statement, got %d", len(diff.MigrationStatements))
+			if len(diff.Statements()) != 1 {
+				t.Errorf("expected 1 migration statement, got %d", len(diff.Statements()))
 			}
 
 			// Check for expected strings in migration DDL
-			allDDL := strings.Join(statementsToStringsTables(diff.MigrationStatements), "\n")
+			allDDL := strings.Join(statementsToStringsTables(diff.Statements()), "\n")
 			for _, expected := range tt.wantContains {
 				if !strings.Contains(allDDL, expected) {
 					t.Errorf("migration DDL missing expected string %q.\nGot:\n%s", expected, allDDL)
@@ -388,12 +388,12 @@ func TestCompareTablesColumnAdditions(t *testing.T) {
 					t.Errorf("description %q does not contain %q", diff.Description, tt.wantDescContains)
 				}
 
-				if len(diff.MigrationStatements) != 1 {
-					t.Errorf("expected 1 migration statement, got %d", len(diff.MigrationStatements))
+				if len(diff.Statements()) != 1 {
+					t.Errorf("expected 1 migration statement, got %d", len(diff.Statements()))
 					continue
 				}
 
-				ddl := diff.MigrationStatements[0].String()
+				ddl := diff.Statements()[0].String()
 				for _, expected := range tt.wantDDLContains {
 					if !strings.Contains(ddl, expected) {
 						t.Errorf("DDL %q does not contain %q", ddl, expected)
@@ -455,12 +455,12 @@ func TestCompareTablesColumnRemovals(t *testing.T) {
 					t.Errorf("description %q does not contain %q", diff.Description, tt.wantDescContains)
 				}
 
-				if len(diff.MigrationStatements) != 1 {
-					t.Errorf("expected 1 migration statement, got %d", len(diff.MigrationStatements))
+				if len(diff.Statements()) != 1 {
+					t.Errorf("expected 1 migration statement, got %d", len(diff.Statements()))
 					continue
 				}
 
-				ddl := diff.MigrationStatements[0].String()
+				ddl := diff.Statements()[0].String()
 				for _, expected := range tt.wantDDLContains {
 					if !strings.Contains(ddl, expected) {
 						t.Errorf("DDL %q does not contain %q", ddl, expected)
@@ -538,7 +538,7 @@ func TestDropColumnReferencedByPartialIndexPredicate(t *testing.T) {
 			// Collect all DDL from all diffs
 			var allDDL []string
 			for _, diff := range diffs {
-				for _, stmt := range diff.MigrationStatements {
+				for _, stmt := range diff.Statements() {
 					allDDL = append(allDDL, stmt.String())
 				}
 			}
@@ -664,12 +664,12 @@ func TestCompareTablesIndexAdditions(t *testing.T) {
 					t.Errorf("description %q does not contain %q", diff.Description, tt.wantDescContains)
 				}
 
-				if len(diff.MigrationStatements) != 1 {
-					t.Errorf("expected 1 migration statement, got %d", len(diff.MigrationStatements))
+				if len(diff.Statements()) != 1 {
+					t.Errorf("expected 1 migration statement, got %d", len(diff.Statements()))
 					continue
 				}
 
-				ddl := diff.MigrationStatements[0].String()
+				ddl := diff.Statements()[0].String()
 				for _, expected := range tt.wantDDLContains {
 					if !strings.Contains(ddl, expected) {
 						t.Errorf("DDL %q does not contain %q", ddl, expected)
@@ -731,12 +731,12 @@ func TestCompareTablesIndexRemovals(t *testing.T) {
 					t.Errorf("description %q does not contain %q", diff.Description, tt.wantDescContains)
 				}
 
-				if len(diff.MigrationStatements) != 1 {
-					t.Errorf("expected 1 migration statement, got %d", len(diff.MigrationStatements))
+				if len(diff.Statements()) != 1 {
+					t.Errorf("expected 1 migration statement, got %d", len(diff.Statements()))
 					continue
 				}
 
-				ddl := diff.MigrationStatements[0].String()
+				ddl := diff.Statements()[0].String()
 				for _, expected := range tt.wantDDLContains {
 					if !strings.Contains(ddl, expected) {
 						t.Errorf("DDL %q does not contain %q", ddl, expected)
@@ -856,19 +856,16 @@ func TestCompareTablesIndexModifications(t *testing.T) {
 				t.Errorf("description %q does not contain %q", diff.Description, tt.wantDescContains)
 			}
 
-			// Index modifications require transaction boundaries:
-			// DROP INDEX, COMMIT, BEGIN, CREATE INDEX (4 statements)
-			if len(diff.MigrationStatements) != 4 {
-				t.Errorf("expected 4 migration statements (DROP + COMMIT + BEGIN + CREATE), got %d", len(diff.MigrationStatements))
-				for i, stmt := range diff.MigrationStatements {
-					t.Logf("Statement %d: %s", i, stmt.String())
-				}
+			// Index modifications drop the index in one transaction and create it
+			// in the next
+			if len(diff.Phases) != 2 || len(diff.Phases[0].Statements) != 1 || len(diff.Phases[1].Statements) != 1 {
+				t.Errorf("expected DROP and CREATE in 2 phases, got %+v", diff.Phases)
 				return
 			}
 
-			// Verify DROP, COMMIT, BEGIN, and CREATE are present
+			// Verify DROP and CREATE are present
 			allDDL := ""
-			for _, stmt := range diff.MigrationStatements {
+			for _, stmt := range diff.Statements() {
 				allDDL += stmt.String() + "\n"
 			}
 			for _, expected := range tt.wantDDLContains {
@@ -973,19 +970,17 @@ func TestCompareTablesPrimaryKeyChanges(t *testing.T) {
 				t.Errorf("expected Dangerous=%v, got %v", tt.wantDangerous, diff.Dangerous)
 			}
 
-			// Primary key modifications require transaction boundaries:
-			// COMMIT, BEGIN, ALTER TABLE (DROP + ADD), COMMIT, BEGIN (5 statements)
-			if len(diff.MigrationStatements) != 5 {
-				t.Errorf("expected 5 migration statements (COMMIT + BEGIN + ALTER + COMMIT + BEGIN), got %d", len(diff.MigrationStatements))
-				for i, stmt := range diff.MigrationStatements {
-					t.Logf("Statement %d: %s", i, stmt.String())
-				}
+			// Primary key modifications run the ALTER TABLE (DROP + ADD) in a
+			// transaction of its own, between two empty phases
+			if len(diff.Phases) != 3 || len(diff.Phases[1].Statements) != 1 ||
+				len(diff.Phases[0].Statements) != 0 || len(diff.Phases[2].Statements) != 0 {
+				t.Errorf("expected the ALTER TABLE alone in the middle of 3 phases, got %+v", diff.Phases)
 				return
 			}
 
 			// Verify expected DDL is present
 			allDDL := ""
-			for _, stmt := range diff.MigrationStatements {
+			for _, stmt := range diff.Statements() {
 				allDDL += stmt.String() + "\n"
 			}
 			for _, expected := range tt.wantDDLContains {
@@ -1062,7 +1057,7 @@ func TestCompareTablesColumnTypeChangeWithIndex(t *testing.T) {
 			// Collect all DDL from all diffs
 			allDDL := ""
 			for _, diff := range diffs {
-				for _, stmt := range diff.MigrationStatements {
+				for _, stmt := range diff.Statements() {
 					allDDL += stmt.String() + "\n"
 				}
 			}
@@ -1074,10 +1069,13 @@ func TestCompareTablesColumnTypeChangeWithIndex(t *testing.T) {
 				}
 			}
 
-			// Verify transaction boundaries are present when there are index drops
-			if strings.Contains(allDDL, "DROP INDEX") {
-				if !strings.Contains(allDDL, "COMMIT") || !strings.Contains(allDDL, "BEGIN") {
-					t.Errorf("expected transaction boundaries (COMMIT/BEGIN) when dropping indexes.\nGot:\n%s", allDDL)
+			// Verify index drops never share a phase with the type change
+			for _, diff := range diffs {
+				for _, phase := range diff.Phases {
+					phaseDDL := strings.Join(statementsToStringsTables(phase.Statements), "\n")
+					if strings.Contains(phaseDDL, "DROP INDEX") && strings.Contains(phaseDDL, "ALTER COLUMN") {
+						t.Errorf("expected index drops in a separate phase from the type change.\nGot:\n%s", phaseDDL)
+					}
 				}
 			}
 		})
@@ -1159,7 +1157,7 @@ func TestRemoveIndexesOnDroppedColumns(t *testing.T) {
 
 			allDDL := ""
 			for _, diff := range diffs {
-				for _, stmt := range diff.MigrationStatements {
+				for _, stmt := range diff.Statements() {
 					allDDL += stmt.String() + "\n"
 				}
 			}
@@ -1190,6 +1188,7 @@ func TestCompareStorageParams(t *testing.T) {
 		wantDiffCount int
 		wantDDL       []string
 		wantNoDDL     []string
+		wantDangerous bool
 	}{
 		{
 			name:          "no differences",
@@ -1203,6 +1202,7 @@ func TestCompareStorageParams(t *testing.T) {
 			remoteParams:  tree.StorageParams{},
 			wantDiffCount: 1,
 			wantDDL:       []string{"SET", "ttl_expire_after"},
+			wantDangerous: true,
 		},
 		{
 			name:          "param removed",
@@ -1241,6 +1241,42 @@ func TestCompareStorageParams(t *testing.T) {
 			wantDiffCount: 1,
 			wantDDL:       []string{"SET", "ttl_expire_after"},
 			wantNoDDL:     []string{"schema_locked"},
+			wantDangerous: true,
+		},
+		{
+			name: "params set and reset in one difference, set first",
+			localParams: tree.StorageParams{
+				{Key: "ttl", Value: tree.NewDString("on")},
+				{Key: "ttl_expiration_expression", Value: tree.NewDString("created_at + INTERVAL '30 days'")},
+				{Key: "ttl_job_cron", Value: tree.NewDString("@daily")},
+			},
+			remoteParams: tree.StorageParams{
+				{Key: "ttl", Value: tree.NewDString("on")},
+				{Key: "ttl_expire_after", Value: tree.NewDString("30 days")},
+				{Key: "ttl_job_cron", Value: tree.NewDString("@hourly")},
+				{Key: "exclude_data_from_backup", Value: tree.DBoolTrue},
+			},
+			wantDiffCount: 1,
+			wantDDL: []string{
+				"SET ('ttl_expiration_expression' = e'created_at + INTERVAL \\'30 days\\'', 'ttl_job_cron' = '@daily')\nALTER TABLE",
+				"RESET ('ttl_expire_after', 'exclude_data_from_backup')",
+			},
+			wantDangerous: true,
+		},
+		{
+			name:          "removing ttl resets only ttl",
+			localParams:   tree.StorageParams{{Key: "fillfactor", Value: tree.NewDInt(80)}},
+			remoteParams:  tree.StorageParams{{Key: "ttl", Value: tree.NewDString("on")}, {Key: "ttl_expire_after", Value: tree.NewDString("30 days")}, {Key: "fillfactor", Value: tree.NewDInt(80)}},
+			wantDiffCount: 1,
+			wantDDL:       []string{"RESET ('ttl')"},
+			wantNoDDL:     []string{"ttl_expire_after", "fillfactor"},
+		},
+		{
+			name:          "changing the TTL cron isn't dangerous",
+			localParams:   tree.StorageParams{{Key: "ttl_job_cron", Value: tree.NewDString("@daily")}},
+			remoteParams:  tree.StorageParams{{Key: "ttl_job_cron", Value: tree.NewDString("@hourly")}},
+			wantDiffCount: 1,
+			wantDDL:       []string{"SET ('ttl_job_cron' = '@daily')"},
 		},
 	}
 
@@ -1255,7 +1291,10 @@ func TestCompareStorageParams(t *testing.T) {
 
 			allDDL := ""
 			for _, diff := range diffs {
-				for _, stmt := range diff.MigrationStatements {
+				if diff.Dangerous != tt.wantDangerous {
+					t.Errorf("expected Dangerous=%v, got %v", tt.wantDangerous, diff.Dangerous)
+				}
+				for _, stmt := range diff.Statements() {
 					allDDL += stmt.String() + "\n"
 				}
 			}
@@ -1311,6 +1350,13 @@ func TestColumnFamilies(t *testing.T) {
 			wantDiffCount:      2,
 			wantBlockingSubstr: "does not support changing a column's family",
 		},
+		{
+			name:               "family changed on column whose type also changed - blocking",
+			localTable:         "CREATE TABLE t (id INT PRIMARY KEY, a STRING, b STRING, FAMILY f1 (id, a), FAMILY f2 (b))",
+			remoteTable:        "CREATE TABLE t (id INT PRIMARY KEY, a INT, b STRING, FAMILY f1 (id), FAMILY f2 (a, b))",
+			wantDiffCount:      2,
+			wantBlockingSubstr: "Column 'public.t.a' family changed",
+		},
 		{
 			name:            "new column with existing family - ADD COLUMN includes FAMILY",
 			localTable:      "CREATE TABLE t (id INT PRIMARY KEY, a STRING, b STRING, FAMILY f1 (id), FAMILY f2 (a, b))",
@@ -1356,7 +1402,7 @@ func TestColumnFamilies(t *testing.T) {
 			var allDDL string
 			var allBlocking string
 			for _, d := range diffs {
-				allDDL += "\n" + strings.Join(statementsToStringsTables(d.MigrationStatements), "\n")
+				allDDL += "\n" + strings.Join(statementsToStringsTables(d.Statements()), "\n")
 				if d.BlockingError != "" {
 					allBlocking += d.BlockingError + "\n"
 				}
@@ -1402,7 +1448,7 @@ func TestColumnFamilyMigrationApplies(t *testing.T) {
 		t.Fatalf("expected 1 diff, got %d:\n%+v", len(diffs), diffs)
 	}
 
-	migration := diffs[0].MigrationStatements[0].String()
+	migration := diffs[0].Statements()[0].String()
 
 	// Run the migration on a fresh shadow DB that already has the remote table.
 	client, err := db.GetShadowDB(ctx, remoteSQL)
@@ -1414,3 +1460,121 @@ func TestColumnFamilyMigrationApplies(t *testing.T) {
 		t.Fatalf("generated migration %q failed: %v", migration, err)
 	}
 }
+
+func TestCompareTablesForeignKeyActions(t *testing.T) {
+	const users = "CREATE TABLE users (id INT PRIMARY KEY)"
+	tests := []struct {
+		name             string
+		localPosts       string
+		remotePosts      string
+		wantDescContains string
+		wantDDL          []string
+	}{
+		{
+			name:             "on delete changed",
+			localPosts:       "CREATE TABLE posts (id INT PRIMARY KEY, user_id INT, CONSTRAINT posts_user_fk FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL)",
+			remotePosts:      "CREATE TABLE posts (id INT PRIMARY KEY, user_id INT, CONSTRAINT posts_user_fk FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE)",
+			wantDescContains: "actions changed from ON DELETE CASCADE to ON DELETE SET NULL",
+			wantDDL: []string{
+				"ADD CONSTRAINT posts_user_fk_scurry_new FOREIGN KEY",
+				"ON DELETE SET NULL NOT VALID",
+				"DROP CONSTRAINT IF EXISTS posts_user_fk",
+				"RENAME CONSTRAINT posts_user_fk_scurry_new TO posts_user_fk",
+				"VALIDATE CONSTRAINT posts_user_fk",
+			},
+		},
+		{
+			name:             "on update added",
+			localPosts:       "CREATE TABLE posts (id INT PRIMARY KEY, user_id INT, CONSTRAINT posts_user_fk FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE)",
+			remotePosts:      "CREATE TABLE posts (id INT PRIMARY KEY, user_id INT, CONSTRAINT posts_user_fk FOREIGN KEY (user_id) REFERENCES users (id))",
+			wantDescContains: "actions changed from NO ACTION to ON UPDATE CASCADE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			localSchema := createSchemaWithTables([]string{users, tt.localPosts})
+			remoteSchema := createSchemaWithTables([]string{users, tt.remotePosts})
+
+			diffs := compareTables(localSchema, remoteSchema)
+			if len(diffs) != 1 {
+				t.Fatalf("expected 1 diff, got %d:\n%+v", len(diffs), diffs)
+			}
+			if !strings.Contains(diffs[0].Description, tt.wantDescContains) {
+				t.Errorf("description %q does not contain %q", diffs[0].Description, tt.wantDescContains)
+			}
+			if diffs[0].IsDropCreate {
+				t.Errorf("foreign key action change should not be a drop and create")
+			}
+			// The replacement is added before the old constraint is dropped, so
+			// the table always has a foreign key enforcing new writes
+			ddl := strings.Join(statementsToStringsTables(diffs[0].Statements()), "\n")
+			last := -1
+			for _, want := range tt.wantDDL {
+				idx := strings.Index(ddl, want)
+				if idx <= last {
+					t.Errorf("expected %q after the previous statement in DDL:\n%s", want, ddl)
+				}
+				last = idx
+			}
+		})
+	}
+}
+
+func TestTableChecksum(t *testing.T) {
+	base := "CREATE TABLE users (id INT8 PRIMARY KEY, email STRING NOT NULL)"
+
+	tests := []struct {
+		name  string
+		other string
+		equal bool
+	}{
+		{
+			name:  "same definition formatted differently",
+			other: "create table users (\n\tid int8 primary key,\n\temail string not null\n)",
+			equal: true,
+		},
+		{
+			name:  "different column",
+			other: "CREATE TABLE users (id INT8 PRIMARY KEY, email STRING)",
+		},
+		{
+			name:  "different table",
+			other: "CREATE TABLE accounts (id INT8 PRIMARY KEY, email STRING NOT NULL)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewSchema(parseStatements(base)...).Tables[0]
+			b := NewSchema(parseStatements(tt.other)...).Tables[0]
+			if equal := a.Checksum() == b.Checksum(); equal != tt.equal {
+				t.Errorf("Checksum() equal = %v, want %v", equal, tt.equal)
+			}
+		})
+	}
+}
+
+func TestCompareTablesSkipsUnchangedTables(t *testing.T) {
+	statements := []string{
+		"CREATE TABLE users (id INT8 NOT NULL, email STRING NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC))",
+	}
+	local := NewSchema(parseStatements(statements...)...)
+	remote := NewSchema(parseStatements(statements...)...)
+
+	if diffs := compareTables(local, remote); len(diffs) != 0 {
+		t.Fatalf("compareTables() returned %d differences for unchanged tables", len(diffs))
+	}
+
+	// Editing the remote AST in place keeps its cached checksum, so only the
+	// deep comparison could notice the column is now nullable. It mustn't run.
+	for _, def := range remote.Tables[0].Ast.Defs {
+		if col, ok := def.(*tree.ColumnTableDef); ok && col.Name == "email" {
+			col.Nullable.Nullability = tree.Null
+		}
+	}
+	if diffs := compareTables(local, remote); len(diffs) != 0 {
+		t.Errorf("compareTables() compared a table with a matching checksum in detail: %v", diffs)
+	}
+}
@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/set"
+)
+
+// getTriggerFunctionName returns the schema and name of the function a trigger executes
+func getTriggerFunctionName(name *tree.UnresolvedName) (string, string) {
+	schemaName := "public"
+	if name.NumParts > 1 {
+		schemaName = tree.Name(name.Parts[1]).Normalize()
+	}
+	return schemaName, tree.Name(name.Parts[0]).Normalize()
+}
+
+// triggerOriginalDependencies returns the objects a trigger depends on, so dropping
+// the trigger is ordered before dropping its table or function
+func triggerOriginalDependencies(trigger *tree.CreateTrigger) set.Set[string] {
+	deps := set.New[string]()
+	tableSchema, tableName := getObjectName(trigger.TableName)
+	deps.Add(tableSchema + "." + tableName)
+	funcSchema, funcName := getTriggerFunctionName(trigger.FuncName)
+	deps.Add(funcSchema + "." + funcName)
+	return deps
+}
+
+func dropTrigger(trigger *tree.CreateTrigger) *tree.DropTrigger {
+	return &tree.DropTrigger{
+		IfExists: true,
+		Trigger:  trigger.Name,
+		Table:    trigger.TableName,
+	}
+}
+
+// compareTriggers finds differences in triggers
+func compareTriggers(local, remote *Schema) []Difference {
+	diffs := make([]Difference, 0)
+
+	// Trigger names are only unique per table, so they're keyed by schema.table.trigger
+	localTriggers := make(map[string]ObjectSchema[*tree.CreateTrigger])
+	remoteTriggers := make(map[string]ObjectSchema[*tree.CreateTrigger])
+
+	for _, t := range local.Triggers {
+		localTriggers[t.ResolvedName()] = t
+	}
+	for _, t := range remote.Triggers {
+		remoteTriggers[t.ResolvedName()] = t
+	}
+
+	// Find added and modified triggers
+	for name, localTrigger := range localTriggers {
+		remoteTrigger, existsInRemote := remoteTriggers[name]
+		if !existsInRemote {
+			// Trigger added - create it
+			diffs = append(diffs, Difference{
+				Type:        DiffTypeTriggerAdded,
+				ObjectName:  name,
+				Description: fmt.Sprintf("Trigger '%s' added", name),
+				Phases:      inOnePhase(localTrigger.Ast),
+			})
+		} else if local.checksum(localTrigger.Ast) != remote.checksum(remoteTrigger.Ast) {
+			// Trigger modified - drop and recreate
+			diffs = append(diffs, Difference{
+				Type:                 DiffTypeTriggerModified,
+				ObjectName:           name,
+				Description:          fmt.Sprintf("Trigger '%s' modified", name),
+				Phases:               inOnePhase(dropTrigger(remoteTrigger.Ast), localTrigger.Ast),
+				OriginalDependencies: triggerOriginalDependencies(remoteTrigger.Ast),
+			})
+		}
+	}
+
+	// Find removed triggers
+	for name, remoteTrigger := range remoteTriggers {
+		if _, existsInLocal := localTriggers[name]; !existsInLocal {
+			// Trigger removed - drop it
+			diffs = append(diffs, Difference{
+				Type:                 DiffTypeTriggerRemoved,
+				ObjectName:           name,
+				Description:          fmt.Sprintf("Trigger '%s' removed", name),
+				Phases:               inOnePhase(dropTrigger(remoteTrigger.Ast)),
+				OriginalDependencies: triggerOriginalDependencies(remoteTrigger.Ast),
+			})
+		}
+	}
+
+	return diffs
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareTriggers(t *testing.T) {
+	const fn = `CREATE FUNCTION audit() RETURNS TRIGGER LANGUAGE PLpgSQL AS $$ BEGIN RETURN NEW; END $$`
+
+	tests := []struct {
+		name          string
+		local         []string
+		remote        []string
+		wantDiffTypes []DiffType
+		wantContains  []string
+	}{
+		{
+			name:   "no differences",
+			local:  []string{`CREATE TRIGGER t_audit BEFORE INSERT ON t FOR EACH ROW EXECUTE FUNCTION audit()`},
+			remote: []string{`CREATE TRIGGER t_audit BEFORE INSERT ON t FOR EACH ROW EXECUTE FUNCTION audit()`},
+		},
+		{
+			name:          "trigger added",
+			local:         []string{`CREATE TRIGGER t_audit BEFORE INSERT ON t FOR EACH ROW EXECUTE FUNCTION audit()`},
+			wantDiffTypes: []DiffType{DiffTypeTriggerAdded},
+			wantContains:  []string{"CREATE TRIGGER t_audit BEFORE INSERT ON t FOR EACH ROW EXECUTE FUNCTION audit()"},
+		},
+		{
+			name:          "trigger removed",
+			remote:        []string{`CREATE TRIGGER t_audit BEFORE INSERT ON t FOR EACH ROW EXECUTE FUNCTION audit()`},
+			wantDiffTypes: []DiffType{DiffTypeTriggerRemoved},
+			wantContains:  []string{"DROP TRIGGER IF EXISTS t_audit ON t"},
+		},
+		{
+			name:          "trigger modified is dropped and recreated",
+			local:         []string{`CREATE TRIGGER t_audit AFTER INSERT OR UPDATE ON t FOR EACH ROW EXECUTE FUNCTION audit()`},
+			remote:        []string{`CREATE TRIGGER t_audit BEFORE INSERT ON t FOR EACH ROW EXECUTE FUNCTION audit()`},
+			wantDiffTypes: []DiffType{DiffTypeTriggerModified},
+			wantContains:  []string{"DROP TRIGGER IF EXISTS t_audit ON t", "CREATE TRIGGER t_audit AFTER INSERT OR UPDATE ON t"},
+		},
+		{
+			name: "same trigger name on different tables",
+			local: []string{
+				`CREATE TRIGGER audit BEFORE INSERT ON a FOR EACH ROW EXECUTE FUNCTION audit()`,
+				`CREATE TRIGGER audit BEFORE INSERT ON b FOR EACH ROW EXECUTE FUNCTION audit()`,
+			},
+			remote:        []string{`CREATE TRIGGER audit BEFORE INSERT ON a FOR EACH ROW EXECUTE FUNCTION audit()`},
+			wantDiffTypes: []DiffType{DiffTypeTriggerAdded},
+			wantContains:  []string{"CREATE TRIGGER audit BEFORE INSERT ON b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := NewSchema(parseStatements(append([]string{fn}, tt.local...)...)...)
+			remote := NewSchema(parseStatements(append([]string{fn}, tt.remote...)...)...)
+
+			diffs := compareTriggers(local, remote)
+			var diffTypes []DiffType
+			var ddl []string
+			for _, diff := range diffs {
+				diffTypes = append(diffTypes, diff.Type)
+				ddl = append(ddl, statementsToStrings(diff.Statements())...)
+			}
+			assert.Equal(t, tt.wantDiffTypes, diffTypes)
+
+			joined := strings.Join(ddl, "\n")
+			for _, s := range tt.wantContains {
+				assert.Contains(t, joined, s)
+			}
+		})
+	}
+}
+
+func TestTriggerMigrationOrdering(t *testing.T) {
+	table := `CREATE TABLE t (id INT8 NOT NULL, n INT8 NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`
+	fn := `CREATE FUNCTION audit() RETURNS TRIGGER LANGUAGE PLpgSQL AS $$ BEGIN RETURN NEW; END $$`
+	trigger := `CREATE TRIGGER t_audit BEFORE UPDATE OF n ON t FOR EACH ROW EXECUTE FUNCTION audit()`
+
+	t.Run("created after its table and function", func(t *testing.T) {
+		local := NewSchema(parseStatements(trigger, fn, table)...)
+
+		ddl, _, err := Compare(local, NewSchema()).GenerateMigrations(false)
+		require.NoError(t, err)
+		joined := strings.Join(ddl, "\n")
+
+		triggerIdx := strings.Index(joined, "CREATE TRIGGER")
+		require.NotEqual(t, -1, triggerIdx)
+		assert.Less(t, strings.Index(joined, "CREATE TABLE"), triggerIdx)
+		assert.Less(t, strings.Index(joined, "CREATE FUNCTION"), triggerIdx)
+	})
+
+	t.Run("dropped before its table and function", func(t *testing.T) {
+		remote := NewSchema(parseStatements(table, fn, trigger)...)
+
+		ddl, _, err := Compare(NewSchema(), remote).GenerateMigrations(false)
+		require.NoError(t, err)
+		joined := strings.Join(ddl, "\n")
+
+		triggerIdx := strings.Index(joined, "DROP TRIGGER")
+		require.NotEqual(t, -1, triggerIdx)
+		assert.Less(t, triggerIdx, strings.Index(joined, "DROP TABLE"))
+		assert.Less(t, triggerIdx, strings.Index(joined, "DROP FUNCTION"))
+	})
+}
@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+
+	"github.com/pjtatlow/scurry/internal/set"
+)
+
+// typeUsage is a column, view, routine or type in the database that uses a type
+type typeUsage struct {
+	// Object is the ObjectName of the differences that change the user
+	Object string
+	// Column is the column of table Object using the type, if it's a table
+	Column string
+}
+
+func (u typeUsage) String() string {
+	if u.Column == "" {
+		return u.Object
+	}
+	return u.Object + "." + u.Column
+}
+
+// orderTypeDrops makes each DROP TYPE wait for the differences that stop
+// columns, views, routines and other types from using the type, since it fails
+// while anything still does. A usage no difference takes away, e.g. in an
+// ignored table, makes the drop a blocking error instead.
+func (r *ComparisonResult) orderTypeDrops(remote *Schema) {
+	for i := range r.Differences {
+		drop := &r.Differences[i]
+		if drop.Type != DiffTypeTypeRemoved {
+			continue
+		}
+
+		var unresolved []string
+		for _, usage := range typeUsages(remote, drop.ObjectName) {
+			resolved := false
+			for j := range r.Differences {
+				diff := &r.Differences[j]
+				if !diff.removesTypeUsage(usage) {
+					continue
+				}
+				resolved = true
+				// The DROP TYPE requires the differences that depended on the type,
+				// the same as any other dropped object
+				if diff.OriginalDependencies == nil {
+					diff.OriginalDependencies = set.New[string]()
+				}
+				diff.OriginalDependencies.Add(drop.ObjectName)
+			}
+			if !resolved {
+				unresolved = append(unresolved, usage.String())
+			}
+		}
+		if len(unresolved) > 0 {
+			drop.BlockingError = fmt.Sprintf("Type '%s' can't be dropped, it's still used by %s; drop or change them in the definitions too", drop.ObjectName, strings.Join(unresolved, ", "))
+		}
+	}
+}
+
+// typeUsages returns what uses the type named typeName in s: columns of that
+// type or with defaults casting to it, views, routines and composite types
+func typeUsages(s *Schema, typeName string) []typeUsage {
+	var usages []typeUsage
+	for _, table := range s.Tables {
+		schemaName, tableName := getTableName(table.Ast.Table)
+		for _, def := range table.Ast.Defs {
+			col, ok := def.(*tree.ColumnTableDef)
+			if ok && columnReferencesType(schemaName, tableName, col, typeName) {
+				usages = append(usages, typeUsage{Object: table.ResolvedName(), Column: col.Name.Normalize()})
+			}
+		}
+	}
+	for _, view := range s.Views {
+		if GetDependencyNames(view.Ast, false).Contains(typeName) {
+			usages = append(usages, typeUsage{Object: view.ResolvedName()})
+		}
+	}
+	for _, routine := range s.Routines {
+		if GetDependencyNames(routine.Ast, false).Contains(typeName) {
+			usages = append(usages, typeUsage{Object: getRoutineSignature(routine.Ast)})
+		}
+	}
+	for _, t := range s.Types {
+		if t.ResolvedName() != typeName && GetDependencyNames(t.Ast, false).Contains(typeName) {
+			usages = append(usages, typeUsage{Object: t.ResolvedName()})
+		}
+	}
+	return usages
+}
+
+// columnReferencesType returns true if the column is of the type, or its
+// default, ON UPDATE or computed expression refers to it
+func columnReferencesType(schemaName, tableName string, col *tree.ColumnTableDef, typeName string) bool {
+	if columnUsesType(col, typeName) {
+		return true
+	}
+	if col.DefaultExpr.Expr != nil && getExprDeps(col.DefaultExpr.Expr).Contains(typeName) {
+		return true
+	}
+	if col.OnUpdateExpr.Expr != nil && getExprDeps(col.OnUpdateExpr.Expr).Contains(typeName) {
+		return true
+	}
+	return col.Computed.Computed && getExprColumnDeps(schemaName, tableName, col.Computed.Expr).Contains(typeName)
+}
+
+// removesTypeUsage returns true if the difference takes away the usage: drops
+// or changes the view, routine or type, or drops the table or column, or
+// changes the column's type, default or ON UPDATE expression
+func (d Difference) removesTypeUsage(usage typeUsage) bool {
+	if d.ObjectName != usage.Object {
+		return false
+	}
+	if usage.Column == "" {
+		return true
+	}
+	for _, stmt := range d.Statements() {
+		switch stmt := stmt.(type) {
+		case *tree.DropTable:
+			return true
+		case *tree.AlterTable:
+			for _, cmd := range stmt.Cmds {
+				var column tree.Name
+				switch cmd := cmd.(type) {
+				case *tree.AlterTableDropColumn:
+					column = cmd.Column
+				case *tree.AlterTableAlterColumnType:
+					column = cmd.Column
+				case *tree.AlterTableSetDefault:
+					column = cmd.Column
+				case *tree.AlterTableSetOnUpdate:
+					column = cmd.Column
+				default:
+					continue
+				}
+				if column.Normalize() == usage.Column {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderTypeDrops(t *testing.T) {
+	moodType := []string{`CREATE TYPE public.mood AS ENUM ('happy', 'sad')`}
+	remoteTable := `CREATE TABLE public.people (
+		id INT8 NOT NULL,
+		mood public.mood NOT NULL,
+		label STRING NULL DEFAULT 'happy':::public.mood::STRING,
+		CONSTRAINT people_pkey PRIMARY KEY (id ASC)
+	)`
+
+	tests := []struct {
+		name        string
+		localTables []string
+		ignore      IgnoredObjects
+		// wantBefore are statements GenerateMigrations has to put before DROP TYPE
+		wantBefore []string
+		wantErr    string
+	}{
+		{
+			name:        "column dropped",
+			localTables: []string{`CREATE TABLE public.people (id INT8 NOT NULL, CONSTRAINT people_pkey PRIMARY KEY (id ASC))`},
+			wantBefore:  []string{"ALTER TABLE public.people DROP COLUMN mood", "ALTER TABLE public.people DROP COLUMN label"},
+		},
+		{
+			name: "column type and default changed",
+			localTables: []string{`CREATE TABLE public.people (
+				id INT8 NOT NULL,
+				mood STRING NOT NULL,
+				label STRING NULL DEFAULT 'happy',
+				CONSTRAINT people_pkey PRIMARY KEY (id ASC)
+			)`},
+			wantBefore: []string{"ALTER TABLE public.people ALTER COLUMN mood SET DATA TYPE STRING", "ALTER TABLE public.people ALTER COLUMN label SET DEFAULT 'happy'"},
+		},
+		{
+			name:       "table dropped",
+			wantBefore: []string{"DROP TABLE IF EXISTS public.people"},
+		},
+		{
+			name:        "table ignored",
+			localTables: []string{remoteTable},
+			ignore:      IgnoredObjects{"public.people": "ignore_diff"},
+			wantErr:     "Type 'public.mood' can't be dropped, it's still used by public.people.mood, public.people.label",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := createSchemaWithTypesAndTables(nil, tt.localTables)
+			local.IgnoreDiff = tt.ignore
+			remote := createSchemaWithTypesAndTables(moodType, []string{remoteTable})
+
+			result := Compare(local, remote)
+			statements, _, err := result.GenerateMigrations(false)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			dropType := slices.IndexFunc(statements, func(stmt string) bool {
+				return strings.HasPrefix(stmt, "DROP TYPE")
+			})
+			require.GreaterOrEqual(t, dropType, 0, "no DROP TYPE in %v", statements)
+			for _, want := range tt.wantBefore {
+				i := slices.IndexFunc(statements, func(stmt string) bool { return strings.HasPrefix(stmt, want) })
+				require.GreaterOrEqual(t, i, 0, "no %q in %v", want, statements)
+				assert.Less(t, i, dropType, "%q runs after DROP TYPE", want)
+			}
+		})
+	}
+}
+
+func TestTypeUsages(t *testing.T) {
+	remote := createSchemaWithTypesAndTables(
+		[]string{
+			`CREATE TYPE public.mood AS ENUM ('happy', 'sad')`,
+			`CREATE TYPE public.reading AS (mood public.mood, at TIMESTAMPTZ)`,
+		},
+		[]string{
+			`CREATE TABLE public.people (
+				id INT8 NOT NULL,
+				mood public.mood NULL,
+				moods public.mood[] NULL,
+				label STRING NULL DEFAULT 'happy':::public.mood::STRING,
+				CONSTRAINT people_pkey PRIMARY KEY (id ASC)
+			)`,
+			`CREATE TABLE public.other (id INT8 NOT NULL, CONSTRAINT other_pkey PRIMARY KEY (id ASC))`,
+		},
+	)
+
+	var usages []string
+	for _, usage := range typeUsages(remote, "public.mood") {
+		usages = append(usages, usage.String())
+	}
+	assert.Equal(t, []string{"public.people.mood", "public.people.moods", "public.people.label", "public.reading"}, usages)
+}
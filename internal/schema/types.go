@@ -2,6 +2,7 @@ package schema
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
@@ -43,16 +44,16 @@ func compareTypes(local, remote *Schema) []Difference {
 		if !existsInRemote {
 			// Type added - create it
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTypeAdded,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("Type '%s' added", name),
-				MigrationStatements: []tree.Statement{localType.Ast},
+				Type:        DiffTypeTypeAdded,
+				ObjectName:  name,
+				Description: fmt.Sprintf("Type '%s' added", name),
+				Phases:      inOnePhase(localType.Ast),
 			})
 		} else {
 			// Check if type was modified
-			if localType.Ast.String() != remoteType.Ast.String() {
+			if local.checksum(localType.Ast) != remote.checksum(remoteType.Ast) {
 				// Type modified - need to check what kind of modification
-				diff := compareTypeDetails(name, localType, remoteType)
+				diff := compareTypeDetails(name, localType, remoteType, local, remote)
 				if diff != nil {
 					diffs = append(diffs, *diff)
 				}
@@ -74,11 +75,11 @@ func compareTypes(local, remote *Schema) []Difference {
 				Names:        []*tree.UnresolvedObjectName{remoteType.Ast.TypeName},
 			}
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeTypeRemoved,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("Type '%s' removed", name),
-				Dangerous:           true,
-				MigrationStatements: []tree.Statement{&drop},
+				Type:        DiffTypeTypeRemoved,
+				ObjectName:  name,
+				Description: fmt.Sprintf("Type '%s' removed", name),
+				Dangerous:   true,
+				Phases:      inOnePhase(&drop),
 			})
 		}
 	}
@@ -97,26 +98,29 @@ func buildEnumRenameDiff(r enumRename) Difference {
 		},
 	}
 	return Difference{
-		Type:                DiffTypeTypeRenamed,
-		ObjectName:          r.oldName(),
-		Description:         fmt.Sprintf("Type '%s' renamed to '%s'", r.oldName(), r.newName()),
-		MigrationStatements: []tree.Statement{alter},
+		Type:        DiffTypeTypeRenamed,
+		ObjectName:  r.oldName(),
+		Description: fmt.Sprintf("Type '%s' renamed to '%s'", r.oldName(), r.newName()),
+		Phases:      inOnePhase(alter),
 	}
 }
 
 // compareTypeDetails compares the details of two types and generates appropriate migration DDL
-func compareTypeDetails(name string, local, remote ObjectSchema[*tree.CreateType]) *Difference {
+func compareTypeDetails(name string, local, remote ObjectSchema[*tree.CreateType], localSchema, remoteSchema *Schema) *Difference {
 	// Check if both are enum types
 	localEnum := getEnumValues(local.Ast)
 	remoteEnum := getEnumValues(remote.Ast)
 
 	if localEnum != nil && remoteEnum != nil {
 		// Both are enums - compare values
-		return compareEnumTypes(name, local, remote, localEnum, remoteEnum)
+		return compareEnumTypes(name, local, remote, localEnum, remoteEnum, localSchema, remoteSchema)
 	}
 
-	// For non-enum types or mixed types, require DROP and CREATE
-	// This is destructive but necessary for composite types, domains, etc.
+	if local.Ast.Variety == tree.Composite && remote.Ast.Variety == tree.Composite {
+		return compareCompositeTypes(name, local, remote, localSchema, remoteSchema)
+	}
+
+	// For mixed types, require DROP and CREATE
 	migrationDDL := []tree.Statement{
 		&tree.DropType{
 			IfExists:     true,
@@ -127,30 +131,148 @@ func compareTypeDetails(name string, local, remote ObjectSchema[*tree.CreateType
 	}
 
 	return &Difference{
-		Type:                DiffTypeTypeModified,
-		ObjectName:          name,
-		Description:         fmt.Sprintf("Type '%s' modified (requires DROP and CREATE)", name),
-		IsDropCreate:        true,
-		Dangerous:           true,
-		MigrationStatements: migrationDDL,
+		Type:         DiffTypeTypeModified,
+		ObjectName:   name,
+		Description:  fmt.Sprintf("Type '%s' modified (requires DROP and CREATE)", name),
+		IsDropCreate: true,
+		Dangerous:    true,
+		Phases:       inOnePhase(migrationDDL...),
 	}
 }
 
-// compareEnumTypes compares two enum types and generates ALTER TYPE statements
-func compareEnumTypes(name string, local, remote ObjectSchema[*tree.CreateType], localValues, remoteValues []string) *Difference {
-	// Find added and removed values
-	added := findAddedValues(remoteValues, localValues)
-	removed := findRemovedValues(remoteValues, localValues)
+// compareCompositeTypes replaces a composite type whose members changed.
+// CockroachDB can't alter the members of a composite type, or drop one that
+// columns still use, so columns of the type are dropped before it's recreated
+// and added back after, losing their values.
+func compareCompositeTypes(name string, local, remote ObjectSchema[*tree.CreateType], localSchema, remoteSchema *Schema) *Difference {
+	localTables := make(map[string]*tree.CreateTable)
+	for _, t := range localSchema.Tables {
+		localTables[t.ResolvedName()] = t.Ast
+	}
+
+	var dropColumns, addColumns []tree.Statement
+	var rewritten []string
+	for _, remoteTable := range remoteSchema.Tables {
+		tableName := remoteTable.ResolvedName()
+		localTable, ok := localTables[tableName]
+		if !ok {
+			continue
+		}
+		for _, def := range remoteTable.Ast.Defs {
+			remoteCol, ok := def.(*tree.ColumnTableDef)
+			if !ok || !columnUsesType(remoteCol, name) {
+				continue
+			}
+			// Columns that move off the type or go away are left to the table diff
+			localCol := findColumnDef(localTable, remoteCol.Name.Normalize())
+			if localCol == nil || !columnUsesType(localCol, name) {
+				continue
+			}
+			tableRef := remoteTable.Ast.Table.ToUnresolvedObjectName()
+			dropColumns = append(dropColumns, &tree.AlterTable{
+				Table: tableRef,
+				Cmds: tree.AlterTableCmds{
+					&tree.AlterTableDropColumn{
+						Column:       remoteCol.Name,
+						DropBehavior: tree.DropRestrict,
+					},
+				},
+			})
+			addColumns = append(addColumns, &tree.AlterTable{
+				Table: tableRef,
+				Cmds: tree.AlterTableCmds{
+					&tree.AlterTableAddColumn{
+						ColumnDef: localCol,
+					},
+				},
+			})
+			rewritten = append(rewritten, tableName+"."+remoteCol.Name.Normalize())
+		}
+	}
+
+	replaceType := []tree.Statement{
+		&tree.DropType{
+			IfExists:     true,
+			DropBehavior: tree.DropRestrict,
+			Names:        []*tree.UnresolvedObjectName{remote.Ast.TypeName},
+		},
+		local.Ast,
+	}
 
-	if len(added) == 0 && len(removed) == 0 {
+	diff := &Difference{
+		Type:         DiffTypeTypeModified,
+		ObjectName:   name,
+		Description:  fmt.Sprintf("Type '%s' modified (requires DROP and CREATE)", name),
+		IsDropCreate: true,
+		Dangerous:    true,
+		Phases:       inOnePhase(replaceType...),
+	}
+	if len(rewritten) > 0 {
+		diff.Description = fmt.Sprintf("Type '%s' modified (requires DROP and CREATE, rewrites %d columns)", name, len(rewritten))
+		diff.WarningMessage = fmt.Sprintf("Composite type '%s' changed, so columns %s will be dropped and re-created, can result in data loss.", name, strings.Join(rewritten, ", "))
+		// Like a re-created column, each step waits for the one before it to commit
+		diff.Phases = []Phase{
+			{Statements: dropColumns},
+			{Statements: replaceType},
+			{Statements: addColumns},
+		}
+	}
+	return diff
+}
+
+// columnUsesType reports whether a column's type, or its element type for
+// arrays, is the named user defined type
+func columnUsesType(col *tree.ColumnTableDef, typeName string) bool {
+	name, ok := getResolvableTypeReferenceDepName(col.Type)
+	return ok && name == typeName
+}
+
+func findColumnDef(table *tree.CreateTable, name string) *tree.ColumnTableDef {
+	for _, def := range table.Defs {
+		if col, ok := def.(*tree.ColumnTableDef); ok && col.Name.Normalize() == name {
+			return col
+		}
+	}
+	return nil
+}
+
+// compareEnumTypes compares two enum types and generates ALTER TYPE statements.
+// Values declared as renamed with -- scurry:renamed-value are renamed in place
+// instead of dropped and added, and the rows still using a dropped value that has
+// a -- scurry:remap-value replacement are updated to it first.
+func compareEnumTypes(name string, local, remote ObjectSchema[*tree.CreateType], localValues, remoteValues []string, localSchema, remoteSchema *Schema) *Difference {
+	added, removed, renamed := enumValueChanges(localValues, remoteValues, localSchema.Renames.Values[name])
+	if len(added) == 0 && len(removed) == 0 && len(renamed) == 0 {
 		// No changes (shouldn't happen as caller already checked DDL equality)
 		return nil
 	}
+	remaps := validEnumRemaps(localSchema.Remaps[name], removed, localValues)
+	columns := enumColumns(remoteSchema, name)
 
 	migrationDDL := make([]tree.Statement, 0)
+	phases := []Phase{{}}
 	descParts := make([]string, 0)
 
+	// Handle renamed values, in the order they appear in the remote type
+	if len(renamed) > 0 {
+		for _, value := range remoteValues {
+			newVal, ok := renamed[value]
+			if !ok {
+				continue
+			}
+			migrationDDL = append(migrationDDL, &tree.AlterType{
+				Type: remote.Ast.TypeName,
+				Cmd: &tree.AlterTypeRenameValue{
+					OldVal: tree.EnumValue(value),
+					NewVal: tree.EnumValue(newVal),
+				},
+			})
+		}
+		descParts = append(descParts, fmt.Sprintf("%d values renamed", len(renamed)))
+	}
+
 	// Handle removed values
+	var dropValues []tree.Statement
 	if len(removed) > 0 {
 		for _, value := range removed {
 			alter := &tree.AlterType{
@@ -159,10 +281,13 @@ func compareEnumTypes(name string, local, remote ObjectSchema[*tree.CreateType],
 					Val: tree.EnumValue(value),
 				},
 			}
-			migrationDDL = append(migrationDDL, alter)
+			dropValues = append(dropValues, alter)
 		}
 		descParts = append(descParts, fmt.Sprintf("-%d values", len(removed)))
 	}
+	if len(remaps) == 0 {
+		migrationDDL = append(migrationDDL, dropValues...)
+	}
 
 	// Handle added values
 	if len(added) > 0 {
@@ -178,19 +303,59 @@ func compareEnumTypes(name string, local, remote ObjectSchema[*tree.CreateType],
 		}
 		// CockroachDB requires new enum values to be committed before they can be
 		// referenced in expressions (e.g. CHECK constraints).
-		migrationDDL = append(migrationDDL, &tree.CommitTransaction{}, &tree.BeginTransaction{})
+		phases = append(phases, Phase{})
 		descParts = append(descParts, fmt.Sprintf("+%d values", len(added)))
 	}
 
+	phases[0].Statements = migrationDDL
+	if len(remaps) > 0 {
+		// The rows are updated once any value they're remapped to is committed,
+		// and the values are dropped in a transaction of their own, as schema
+		// changes can't follow writes in the same transaction
+		var updates []tree.Statement
+		for _, value := range removed {
+			if replacement, ok := remaps[value]; ok {
+				for _, col := range columns {
+					updates = append(updates, col.remapStatement(value, replacement))
+				}
+			}
+		}
+		if len(added) == 0 && len(migrationDDL) > 0 {
+			phases = append(phases, Phase{})
+		}
+		phases[len(phases)-1].Statements = updates
+		phases = append(phases, Phase{Statements: dropValues})
+	}
 	description := fmt.Sprintf("Type '%s' modified (%s)", name, strings.Join(descParts, ", "))
 
 	return &Difference{
-		Type:                DiffTypeTypeModified,
-		ObjectName:          name,
-		Description:         description,
-		Dangerous:           len(removed) > 0,
-		MigrationStatements: migrationDDL,
+		Type:           DiffTypeTypeModified,
+		ObjectName:     name,
+		Description:    description,
+		Dangerous:      len(removed) > 0,
+		WarningMessage: droppedEnumValuesWarning(name, removed, remaps, columns),
+		Phases:         phases,
+	}
+}
+
+// enumValueChanges returns the values added to and removed from an enum, and
+// the removed values renamed to an added one, old -> new. renamedValues maps
+// new values to the old ones they were declared as renamed from.
+func enumValueChanges(localValues, remoteValues []string, renamedValues map[string]string) (added, removed []string, renamed map[string]string) {
+	added = findAddedValues(remoteValues, localValues)
+	removed = findRemovedValues(remoteValues, localValues)
+	renamed = make(map[string]string)
+	renamedTo := make(map[string]bool)
+	for _, newVal := range added {
+		oldVal, ok := renamedValues[newVal]
+		if _, taken := renamed[oldVal]; ok && !taken && slices.Contains(removed, oldVal) {
+			renamed[oldVal] = newVal
+			renamedTo[newVal] = true
+		}
 	}
+	added = slices.DeleteFunc(added, func(v string) bool { return renamedTo[v] })
+	removed = slices.DeleteFunc(removed, func(v string) bool { _, ok := renamed[v]; return ok })
+	return added, removed, renamed
 }
 
 // getEnumValues extracts enum values from a CREATE TYPE statement if it's an enum
@@ -104,6 +104,20 @@ func TestCompareTypes(t *testing.T) {
 			wantDiffCount: 3,
 			wantDiffTypes: []DiffType{DiffTypeTypeModified, DiffTypeTypeAdded, DiffTypeTypeRemoved},
 		},
+		{
+			name:          "composite type added",
+			localTypes:    []string{"CREATE TYPE address AS (street STRING, city STRING)"},
+			remoteTypes:   []string{},
+			wantDiffCount: 1,
+			wantDiffTypes: []DiffType{DiffTypeTypeAdded},
+		},
+		{
+			name:          "composite member added",
+			localTypes:    []string{"CREATE TYPE address AS (street STRING, city STRING, zip STRING)"},
+			remoteTypes:   []string{"CREATE TYPE address AS (street STRING, city STRING)"},
+			wantDiffCount: 1,
+			wantDiffTypes: []DiffType{DiffTypeTypeModified},
+		},
 		{
 			name:          "empty schemas",
 			localTypes:    []string{},
@@ -150,43 +164,84 @@ func TestCompareEnumTypes(t *testing.T) {
 		name          string
 		localType     string
 		remoteType    string
+		renamedValues map[string]string
 		wantStmtCount int
+		wantPhases    int
 		wantContains  []string
+		wantMissing   []string
 	}{
 		{
 			name:          "enum value added",
 			localType:     "CREATE TYPE status AS ENUM ('active', 'inactive', 'pending')",
 			remoteType:    "CREATE TYPE status AS ENUM ('active', 'inactive')",
-			wantStmtCount: 3, // 1 ADD VALUE + COMMIT/BEGIN
+			wantStmtCount: 1,
+			wantPhases:    2, // ADD VALUE, then a new transaction
 			wantContains:  []string{"ALTER TYPE", "ADD VALUE", "'pending'"},
 		},
 		{
 			name:          "multiple enum values added",
 			localType:     "CREATE TYPE status AS ENUM ('active', 'inactive', 'pending', 'suspended')",
 			remoteType:    "CREATE TYPE status AS ENUM ('active', 'inactive')",
-			wantStmtCount: 4, // 2 ADD VALUE + COMMIT/BEGIN
+			wantStmtCount: 2,
+			wantPhases:    2,
 			wantContains:  []string{"ALTER TYPE", "ADD VALUE", "'pending'", "'suspended'"},
 		},
 		{
 			name:          "enum value removed",
 			localType:     "CREATE TYPE status AS ENUM ('active')",
 			remoteType:    "CREATE TYPE status AS ENUM ('active', 'inactive')",
-			wantStmtCount: 1, // 1 ALTER TYPE DROP VALUE
+			wantStmtCount: 1,
+			wantPhases:    1,
 			wantContains:  []string{"ALTER TYPE", "DROP VALUE", "'inactive'"},
 		},
 		{
 			name:          "enum values added and removed",
 			localType:     "CREATE TYPE status AS ENUM ('active', 'pending')",
 			remoteType:    "CREATE TYPE status AS ENUM ('active', 'inactive')",
-			wantStmtCount: 4, // 1 DROP VALUE + 1 ADD VALUE + COMMIT/BEGIN
+			wantStmtCount: 2,
+			wantPhases:    2,
 			wantContains:  []string{"DROP VALUE", "'inactive'", "ADD VALUE", "'pending'"},
 		},
+		{
+			name:          "enum value renamed by directive",
+			localType:     "CREATE TYPE status AS ENUM ('active', 'disabled')",
+			remoteType:    "CREATE TYPE status AS ENUM ('active', 'inactive')",
+			renamedValues: map[string]string{"disabled": "inactive"},
+			wantStmtCount: 1,
+			wantPhases:    1,
+			wantContains:  []string{"RENAME VALUE 'inactive' TO 'disabled'"},
+			wantMissing:   []string{"DROP VALUE", "ADD VALUE"},
+		},
+		{
+			name:          "enum value renamed alongside an added value",
+			localType:     "CREATE TYPE status AS ENUM ('active', 'disabled', 'pending')",
+			remoteType:    "CREATE TYPE status AS ENUM ('active', 'inactive')",
+			renamedValues: map[string]string{"disabled": "inactive"},
+			wantStmtCount: 2,
+			wantPhases:    2,
+			wantContains:  []string{"RENAME VALUE 'inactive' TO 'disabled'", "ADD VALUE", "'pending'"},
+			wantMissing:   []string{"DROP VALUE"},
+		},
+		{
+			name:          "directive naming a value that still exists is ignored",
+			localType:     "CREATE TYPE status AS ENUM ('active', 'inactive', 'disabled')",
+			remoteType:    "CREATE TYPE status AS ENUM ('active', 'inactive')",
+			renamedValues: map[string]string{"disabled": "inactive"},
+			wantStmtCount: 1,
+			wantPhases:    2,
+			wantContains:  []string{"ADD VALUE", "'disabled'"},
+			wantMissing:   []string{"RENAME VALUE"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			localSchema := createSchemaWithTypes([]string{tt.localType})
 			remoteSchema := createSchemaWithTypes([]string{tt.remoteType})
+			if tt.renamedValues != nil {
+				localSchema.Renames = newRenameHints()
+				localSchema.Renames.Values["public.status"] = tt.renamedValues
+			}
 
 			diffs := compareTypes(localSchema, remoteSchema)
 
@@ -196,21 +251,141 @@ func TestCompareEnumTypes(t *testing.T) {
 
 			diff := diffs[0]
 
-			if len(diff.MigrationStatements) != tt.wantStmtCount {
-				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.MigrationStatements))
+			if len(diff.Statements()) != tt.wantStmtCount {
+				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.Statements()))
+			}
+			if len(diff.Phases) != tt.wantPhases {
+				t.Errorf("expected %d phases, got %d", tt.wantPhases, len(diff.Phases))
 			}
 
 			// Check for expected strings in migration DDL
-			allDDL := strings.Join(statementsToStringsTypes(diff.MigrationStatements), "\n")
+			allDDL := strings.Join(statementsToStringsTypes(diff.Statements()), "\n")
 			for _, expected := range tt.wantContains {
 				if !contains(allDDL, expected) {
 					t.Errorf("migration DDL missing expected string %q.\nGot:\n%s", expected, allDDL)
 				}
 			}
+			for _, unexpected := range tt.wantMissing {
+				if contains(allDDL, unexpected) {
+					t.Errorf("migration DDL contains unexpected string %q.\nGot:\n%s", unexpected, allDDL)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareCompositeTypes(t *testing.T) {
+	tests := []struct {
+		name         string
+		localType    string
+		remoteType   string
+		localTables  []string
+		remoteTables []string
+		wantPhases   []string
+		wantWarning  string
+	}{
+		{
+			name:       "unused type is replaced",
+			localType:  "CREATE TYPE public.address AS (street STRING, city STRING, zip STRING)",
+			remoteType: "CREATE TYPE public.address AS (street STRING, city STRING)",
+			wantPhases: []string{
+				"DROP TYPE IF EXISTS public.address RESTRICT; CREATE TYPE public.address AS (street STRING, city STRING, zip STRING)",
+			},
+		},
+		{
+			name:       "columns using the type are rewritten",
+			localType:  "CREATE TYPE public.address AS (street STRING, city STRING, zip STRING)",
+			remoteType: "CREATE TYPE public.address AS (street STRING, city STRING)",
+			localTables: []string{
+				"CREATE TABLE public.users (id INT8 NOT NULL, home public.address, office public.address NOT NULL, name STRING, CONSTRAINT users_pkey PRIMARY KEY (id))",
+			},
+			remoteTables: []string{
+				"CREATE TABLE public.users (id INT8 NOT NULL, home public.address, office public.address NOT NULL, name STRING, CONSTRAINT users_pkey PRIMARY KEY (id))",
+			},
+			wantPhases: []string{
+				"ALTER TABLE public.users DROP COLUMN home RESTRICT; ALTER TABLE public.users DROP COLUMN office RESTRICT",
+				"DROP TYPE IF EXISTS public.address RESTRICT; CREATE TYPE public.address AS (street STRING, city STRING, zip STRING)",
+				"ALTER TABLE public.users ADD COLUMN home public.address; ALTER TABLE public.users ADD COLUMN office public.address NOT NULL",
+			},
+			wantWarning: "public.users.home, public.users.office",
+		},
+		{
+			name:       "columns leaving the type are left to the table diff",
+			localType:  "CREATE TYPE public.address AS (street STRING, city STRING, zip STRING)",
+			remoteType: "CREATE TYPE public.address AS (street STRING, city STRING)",
+			localTables: []string{
+				"CREATE TABLE public.users (id INT8 NOT NULL, home STRING, CONSTRAINT users_pkey PRIMARY KEY (id))",
+			},
+			remoteTables: []string{
+				"CREATE TABLE public.users (id INT8 NOT NULL, home public.address, CONSTRAINT users_pkey PRIMARY KEY (id))",
+			},
+			wantPhases: []string{
+				"DROP TYPE IF EXISTS public.address RESTRICT; CREATE TYPE public.address AS (street STRING, city STRING, zip STRING)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localSchema := createSchemaWithTypesAndTables([]string{tt.localType}, tt.localTables)
+			remoteSchema := createSchemaWithTypesAndTables([]string{tt.remoteType}, tt.remoteTables)
+
+			diffs := compareTypes(localSchema, remoteSchema)
+			if len(diffs) != 1 {
+				t.Fatalf("expected 1 diff, got %d", len(diffs))
+			}
+			diff := diffs[0]
+			if !diff.Dangerous {
+				t.Error("expected composite type change to be dangerous")
+			}
+
+			gotPhases := make([]string, len(diff.Phases))
+			for i, phase := range diff.Phases {
+				gotPhases[i] = strings.Join(statementsToStringsTypes(phase.Statements), "; ")
+			}
+			if strings.Join(gotPhases, "\n") != strings.Join(tt.wantPhases, "\n") {
+				t.Errorf("phases:\n%s\nwant:\n%s", strings.Join(gotPhases, "\n"), strings.Join(tt.wantPhases, "\n"))
+			}
+
+			if tt.wantWarning == "" {
+				if diff.WarningMessage != "" {
+					t.Errorf("unexpected warning %q", diff.WarningMessage)
+				}
+			} else if !strings.Contains(diff.WarningMessage, tt.wantWarning) {
+				t.Errorf("warning %q doesn't mention %q", diff.WarningMessage, tt.wantWarning)
+			}
 		})
 	}
 }
 
+func TestCompositeTypeDependencyOrder(t *testing.T) {
+	localSchema := createSchemaWithTypesAndTables(
+		[]string{
+			"CREATE TYPE public.address AS (street STRING, kind public.address_kind)",
+			"CREATE TYPE public.address_kind AS ENUM ('home', 'work')",
+		},
+		[]string{
+			"CREATE TABLE public.accounts (id INT8 PRIMARY KEY, address public.address)",
+		},
+	)
+	remoteSchema := createSchemaWithTypesAndTables(nil, nil)
+
+	migrations, _, err := Compare(localSchema, remoteSchema).GenerateMigrations(false)
+	if err != nil {
+		t.Fatalf("GenerateMigrations() error: %v", err)
+	}
+	allDDL := strings.Join(migrations, "\n")
+
+	lastIndex := -1
+	for _, want := range []string{"CREATE TYPE public.address_kind", "CREATE TYPE public.address AS", "CREATE TABLE public.accounts"} {
+		index := strings.Index(allDDL[lastIndex+1:], want)
+		if index == -1 {
+			t.Fatalf("expected %q to appear after position %d.\nGot:\n%s", want, lastIndex, allDDL)
+		}
+		lastIndex = lastIndex + 1 + index
+	}
+}
+
 func TestGetEnumValues(t *testing.T) {
 	tests := []struct {
 		name       string
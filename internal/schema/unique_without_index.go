@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// uniqueWithoutIndexSetting is the session setting UNIQUE WITHOUT INDEX
+// constraints need, since CockroachDB still treats them as experimental
+const uniqueWithoutIndexSetting = "experimental_enable_unique_without_index_constraints"
+
+// enableUniqueWithoutIndex enables UNIQUE WITHOUT INDEX constraints before the
+// first statement of each phase that adds one
+func (r *ComparisonResult) enableUniqueWithoutIndex() {
+	for i := range r.Differences {
+		diff := &r.Differences[i]
+		for j := range diff.Phases {
+			phase := &diff.Phases[j]
+			for k, stmt := range phase.Statements {
+				if !addsUniqueWithoutIndex(stmt) {
+					continue
+				}
+				enable := &tree.SetVar{Name: uniqueWithoutIndexSetting, Values: tree.Exprs{tree.DBoolTrue}}
+				phase.Statements = append(phase.Statements[:k:k], append([]tree.Statement{enable}, phase.Statements[k:]...)...)
+				break
+			}
+		}
+	}
+}
+
+// addsUniqueWithoutIndex returns true if the statement creates a table with a
+// UNIQUE WITHOUT INDEX constraint or adds one to a table
+func addsUniqueWithoutIndex(stmt tree.Statement) bool {
+	switch s := stmt.(type) {
+	case *tree.CreateTable:
+		for _, def := range s.Defs {
+			if unique, ok := def.(*tree.UniqueConstraintTableDef); ok && unique.WithoutIndex {
+				return true
+			}
+		}
+	case *tree.AlterTable:
+		for _, cmd := range s.Cmds {
+			if add, ok := cmd.(*tree.AlterTableAddConstraint); ok {
+				if unique, ok := add.ConstraintDef.(*tree.UniqueConstraintTableDef); ok && unique.WithoutIndex {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
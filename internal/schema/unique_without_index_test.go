@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareUniqueWithoutIndex(t *testing.T) {
+	table := func(constraint string) string {
+		return "CREATE TABLE public.users (id INT8 NOT NULL, email STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC)" + constraint + ")"
+	}
+
+	tests := []struct {
+		name     string
+		local    string
+		remote   string
+		expected []string
+	}{
+		{
+			name:   "added",
+			local:  table(", CONSTRAINT users_email_key UNIQUE WITHOUT INDEX (email)"),
+			remote: table(""),
+			expected: []string{
+				"SET experimental_enable_unique_without_index_constraints = true",
+				"ALTER TABLE public.users ADD CONSTRAINT users_email_key UNIQUE WITHOUT INDEX (email)",
+			},
+		},
+		{
+			name:     "removed",
+			local:    table(""),
+			remote:   table(", CONSTRAINT users_email_key UNIQUE WITHOUT INDEX (email)"),
+			expected: []string{"ALTER TABLE public.users DROP CONSTRAINT IF EXISTS users_email_key RESTRICT"},
+		},
+		{
+			name:   "unchanged",
+			local:  table(", CONSTRAINT users_email_key UNIQUE WITHOUT INDEX (email)"),
+			remote: table(", CONSTRAINT users_email_key UNIQUE WITHOUT INDEX (email)"),
+		},
+		{
+			name:   "table added",
+			local:  table(", CONSTRAINT users_email_key UNIQUE WITHOUT INDEX (email)"),
+			remote: "",
+			expected: []string{
+				"SET experimental_enable_unique_without_index_constraints = true",
+				"CREATE TABLE public.users (id INT8 NOT NULL, email STRING NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC), CONSTRAINT users_email_key UNIQUE WITHOUT INDEX (email))",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var remoteTables []string
+			if tt.remote != "" {
+				remoteTables = []string{tt.remote}
+			}
+			local := createSchemaWithTypesAndTables(nil, []string{tt.local})
+			remote := createSchemaWithTypesAndTables(nil, remoteTables)
+
+			statements, _, err := Compare(local, remote).GenerateMigrations(false)
+			require.NoError(t, err)
+			if len(tt.expected) == 0 {
+				assert.Empty(t, statements)
+				return
+			}
+			assert.Equal(t, tt.expected, statements)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+const usingPrefix = "-- scurry:using="
+
+// UsingExpressions records conversion expressions declared with -- scurry:using
+// directives, keyed by "schema.table" and then column name.
+type UsingExpressions map[string]map[string]tree.Expr
+
+func (u UsingExpressions) merge(other UsingExpressions) {
+	for table, cols := range other {
+		if u[table] == nil {
+			u[table] = make(map[string]tree.Expr)
+		}
+		for col, expr := range cols {
+			u[table][col] = expr
+		}
+	}
+}
+
+// parseUsingDirectives finds -- scurry:using directives at the end of column lines.
+// The rest of the line is the expression used to convert existing values when the
+// column's type changes:
+//
+//	CREATE TABLE orders (
+//	    id INT PRIMARY KEY,
+//	    amount DECIMAL(10,2) -- scurry:using=amount_cents::DECIMAL / 100
+//	);
+func parseUsingDirectives(sql string) (UsingExpressions, error) {
+	using := make(UsingExpressions)
+	if !strings.Contains(sql, usingPrefix) {
+		return using, nil
+	}
+
+	for _, def := range parseTableDefinitions(sql) {
+		for colName, value := range def.columnDirectives(usingPrefix) {
+			expr, err := parser.ParseExpr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid USING expression for column %s.%s: %w", def.qualified, colName, err)
+			}
+			if using[def.qualified] == nil {
+				using[def.qualified] = make(map[string]tree.Expr)
+			}
+			using[def.qualified][colName] = expr
+		}
+	}
+	return using, nil
+}
+
+// applyUsingExpressions sets the USING expression of column type changes in diffs
+// to the one declared for the column, if any.
+func applyUsingExpressions(diffs []Difference, using map[string]tree.Expr) {
+	if len(using) == 0 {
+		return
+	}
+	for i := range diffs {
+		diff := &diffs[i]
+		if diff.Type != DiffTypeColumnTypeChanged {
+			continue
+		}
+		for _, stmt := range diff.Statements() {
+			alterTable, ok := stmt.(*tree.AlterTable)
+			if !ok {
+				continue
+			}
+			for _, cmd := range alterTable.Cmds {
+				alterColType, ok := cmd.(*tree.AlterTableAlterColumnType)
+				if !ok {
+					continue
+				}
+				if expr, ok := using[alterColType.Column.Normalize()]; ok {
+					alterColType.Using = expr
+					diff.HasUsingDirective = true
+				}
+			}
+		}
+	}
+}
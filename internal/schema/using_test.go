@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUsingDirectives(t *testing.T) {
+	tests := []struct {
+		name        string
+		sql         string
+		expected    map[string]map[string]string
+		errContains string
+	}{
+		{
+			name:     "no directives",
+			sql:      "CREATE TABLE t (id INT PRIMARY KEY, n INT);",
+			expected: map[string]map[string]string{},
+		},
+		{
+			name: "column expression",
+			sql:  "CREATE TABLE t (\n  id INT PRIMARY KEY,\n  amount DECIMAL -- scurry:using=amount::DECIMAL / 100\n);",
+			expected: map[string]map[string]string{
+				"public.t": {"amount": "amount::DECIMAL / 100"},
+			},
+		},
+		{
+			name: "quoted column in another schema",
+			sql:  "CREATE TABLE app.t (\n  id INT PRIMARY KEY,\n  \"Status\" INT -- scurry:using=CASE WHEN \"Status\" = 'on' THEN 1 ELSE 0 END\n);",
+			expected: map[string]map[string]string{
+				"app.t": {"status": `CASE WHEN "Status" = 'on' THEN 1 ELSE 0 END`},
+			},
+		},
+		{
+			name:        "invalid expression",
+			sql:         "CREATE TABLE t (\n  id INT PRIMARY KEY,\n  n INT -- scurry:using=n::\n);",
+			errContains: "invalid USING expression for column public.t.n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			using, err := parseUsingDirectives(tt.sql)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+
+			actual := make(map[string]map[string]string)
+			for table, cols := range using {
+				actual[table] = make(map[string]string)
+				for col, expr := range cols {
+					actual[table][col] = expr.String()
+				}
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCompareAppliesUsingDirectives(t *testing.T) {
+	tests := []struct {
+		name      string
+		local     string
+		remote    string
+		using     string
+		contains  string
+		directive bool
+	}{
+		{
+			name:      "rewriting type change",
+			local:     `CREATE TABLE t (id INT8 NOT NULL, n STRING NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`,
+			remote:    `CREATE TABLE t (id INT8 NOT NULL, n INT8 NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`,
+			using:     "CREATE TABLE t (\n  id INT8 PRIMARY KEY,\n  n STRING -- scurry:using=lpad(n::STRING, 8, '0')\n)",
+			contains:  "USING lpad(n::STRING, 8, '0')",
+			directive: true,
+		},
+		{
+			name:     "no directive keeps default cast",
+			local:    `CREATE TABLE t (id INT8 NOT NULL, n STRING NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`,
+			remote:   `CREATE TABLE t (id INT8 NOT NULL, n INT8 NULL, CONSTRAINT t_pkey PRIMARY KEY (id))`,
+			contains: "USING n::STRING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := NewSchema(parseStatements(tt.local)...)
+			using, err := parseUsingDirectives(tt.using)
+			require.NoError(t, err)
+			local.Using = using
+			remote := NewSchema(parseStatements(tt.remote)...)
+
+			result := Compare(local, remote)
+			var typeChanges []Difference
+			for _, diff := range result.Differences {
+				if diff.Type == DiffTypeColumnTypeChanged {
+					typeChanges = append(typeChanges, diff)
+				}
+			}
+			require.Len(t, typeChanges, 1)
+			assert.Equal(t, tt.directive, typeChanges[0].HasUsingDirective)
+
+			ddl, _, err := result.GenerateMigrations(false)
+			require.NoError(t, err)
+			assert.Contains(t, strings.Join(ddl, "\n"), tt.contains)
+		})
+	}
+}
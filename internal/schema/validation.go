@@ -0,0 +1,152 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// DeferValidation changes FOREIGN KEY, CHECK and UNIQUE WITHOUT INDEX constraints
+// added to existing tables so they are added NOT VALID, which doesn't scan the
+// table, and returns the VALIDATE CONSTRAINT differences that check existing rows
+// afterwards. Validations already split out of a difference (like foreign key
+// action changes) move to the returned result as well. Constraints on new tables
+// are left alone since those tables are empty. The changed statements are copies,
+// so schema ASTs they came from are untouched. A deferrable constraint without a
+// name is an error, since there would be no way to validate it later.
+func (r *ComparisonResult) DeferValidation() (*ComparisonResult, error) {
+	return r.deferValidation(func(string, tree.ConstraintTableDef) bool { return true })
+}
+
+// DeferUniqueValidation is DeferValidation for just the UNIQUE WITHOUT INDEX
+// constraints added to the tables large returns true for. Without an index,
+// checking existing rows means scanning the whole table in the transaction
+// adding the constraint.
+func (r *ComparisonResult) DeferUniqueValidation(large func(tableName string) bool) (*ComparisonResult, error) {
+	return r.deferValidation(func(tableName string, def tree.ConstraintTableDef) bool {
+		unique, ok := def.(*tree.UniqueConstraintTableDef)
+		return ok && unique.WithoutIndex && large(tableName)
+	})
+}
+
+// deferValidation defers the validation of the constraints deferrable returns
+// true for. Validations already split out of a difference are passed a nil
+// constraint.
+func (r *ComparisonResult) deferValidation(deferrable func(tableName string, def tree.ConstraintTableDef) bool) (*ComparisonResult, error) {
+	deferred := &ComparisonResult{Differences: make([]Difference, 0)}
+
+	for i := range r.Differences {
+		diff := &r.Differences[i]
+		if diff.Type != DiffTypeTableModified {
+			continue
+		}
+
+		phases := make([]Phase, 0, len(diff.Phases))
+		for _, phase := range diff.Phases {
+			kept := make([]tree.Statement, 0, len(phase.Statements))
+			for _, stmt := range phase.Statements {
+				alterTable, ok := stmt.(*tree.AlterTable)
+				if !ok {
+					kept = append(kept, stmt)
+					continue
+				}
+
+				validateOnly := len(alterTable.Cmds) > 0
+				cmds := make(tree.AlterTableCmds, 0, len(alterTable.Cmds))
+				for _, cmd := range alterTable.Cmds {
+					switch c := cmd.(type) {
+					case *tree.AlterTableAddConstraint:
+						validateOnly = false
+						if !deferrable(diff.ObjectName, c.ConstraintDef) {
+							cmds = append(cmds, cmd)
+							continue
+						}
+						name, ok, err := deferrableConstraintName(c)
+						if err != nil {
+							return nil, fmt.Errorf("cannot defer validation on %s: %w", diff.ObjectName, err)
+						}
+						if !ok {
+							cmds = append(cmds, cmd)
+							continue
+						}
+						notValid := *c
+						notValid.ValidationBehavior = tree.ValidationSkip
+						cmds = append(cmds, &notValid)
+						deferred.Differences = append(deferred.Differences, buildValidateConstraintDiff(diff.ObjectName, alterTable.Table, name))
+					case *tree.AlterTableValidateConstraint:
+						if !deferrable(diff.ObjectName, nil) {
+							validateOnly = false
+							cmds = append(cmds, cmd)
+							continue
+						}
+						deferred.Differences = append(deferred.Differences, buildValidateConstraintDiff(diff.ObjectName, alterTable.Table, c.Constraint))
+					default:
+						validateOnly = false
+						cmds = append(cmds, cmd)
+					}
+				}
+				if !validateOnly {
+					altered := *alterTable
+					altered.Cmds = cmds
+					kept = append(kept, &altered)
+				}
+			}
+			phases = append(phases, Phase{Statements: kept, NoTransaction: phase.NoTransaction})
+		}
+		diff.Phases = trimTrailingEmptyPhases(phases)
+	}
+
+	return deferred, nil
+}
+
+// deferrableConstraintName returns the name of a constraint that is validated against
+// existing rows when it is added. It is an error for such a constraint to have no
+// name, since VALIDATE CONSTRAINT has to name it.
+func deferrableConstraintName(c *tree.AlterTableAddConstraint) (tree.Name, bool, error) {
+	if c.ValidationBehavior == tree.ValidationSkip {
+		return "", false, nil
+	}
+	var name tree.Name
+	switch def := c.ConstraintDef.(type) {
+	case *tree.ForeignKeyConstraintTableDef:
+		name = def.Name
+	case *tree.CheckConstraintTableDef:
+		name = def.Name
+	case *tree.UniqueConstraintTableDef:
+		if !def.WithoutIndex {
+			return "", false, nil
+		}
+		name = def.Name
+	default:
+		return "", false, nil
+	}
+	if name == "" {
+		return "", false, fmt.Errorf("constraint %s has no name; name it in its definition", tree.AsString(c.ConstraintDef))
+	}
+	return name, true, nil
+}
+
+func buildValidateConstraintDiff(tableName string, table *tree.UnresolvedObjectName, constraint tree.Name) Difference {
+	return Difference{
+		Type:        DiffTypeTableModified,
+		ObjectName:  tableName,
+		Description: fmt.Sprintf("Constraint '%s' validated", constraint.Normalize()),
+		Phases: inOnePhase(
+			&tree.AlterTable{
+				Table: table,
+				Cmds: tree.AlterTableCmds{
+					&tree.AlterTableValidateConstraint{Constraint: constraint},
+				},
+			},
+		),
+	}
+}
+
+// trimTrailingEmptyPhases drops the empty phases left at the end of a
+// difference once the statements in them have been removed
+func trimTrailingEmptyPhases(phases []Phase) []Phase {
+	for len(phases) > 0 && len(phases[len(phases)-1].Statements) == 0 {
+		phases = phases[:len(phases)-1]
+	}
+	return phases
+}
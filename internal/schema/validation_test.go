@@ -0,0 +1,215 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeferValidation(t *testing.T) {
+	postsTable := tree.MakeUnqualifiedTableName("posts")
+	posts := postsTable.ToUnresolvedObjectName()
+	addConstraint := func(def tree.ConstraintTableDef, behavior tree.ValidationBehavior) tree.Statement {
+		return &tree.AlterTable{
+			Table: posts,
+			Cmds: tree.AlterTableCmds{
+				&tree.AlterTableAddConstraint{ConstraintDef: def, ValidationBehavior: behavior},
+			},
+		}
+	}
+	fk := &tree.ForeignKeyConstraintTableDef{
+		Name:     "posts_user_fk",
+		Table:    tree.MakeUnqualifiedTableName("users"),
+		FromCols: tree.NameList{"user_id"},
+		ToCols:   tree.NameList{"id"},
+	}
+	checkExpr, err := parser.ParseExpr("n > 0")
+	require.NoError(t, err)
+	check := &tree.CheckConstraintTableDef{Name: "posts_positive", Expr: checkExpr}
+	unique := &tree.UniqueConstraintTableDef{
+		IndexTableDef: tree.IndexTableDef{Name: "posts_slug_key", Columns: tree.IndexElemList{{Column: "slug"}}},
+		WithoutIndex:  true,
+	}
+
+	tests := []struct {
+		name         string
+		differences  []Difference
+		wantNotValid bool
+		wantDeferred []string
+	}{
+		{
+			name: "foreign key added to existing table",
+			differences: []Difference{{
+				Type:       DiffTypeTableModified,
+				ObjectName: "public.posts",
+				Phases:     inOnePhase(addConstraint(fk, tree.ValidationDefault)),
+			}},
+			wantNotValid: true,
+			wantDeferred: []string{"ALTER TABLE posts VALIDATE CONSTRAINT posts_user_fk"},
+		},
+		{
+			name: "check added to existing table",
+			differences: []Difference{{
+				Type:       DiffTypeTableModified,
+				ObjectName: "public.posts",
+				Phases:     inOnePhase(addConstraint(check, tree.ValidationDefault)),
+			}},
+			wantNotValid: true,
+			wantDeferred: []string{"ALTER TABLE posts VALIDATE CONSTRAINT posts_positive"},
+		},
+		{
+			name: "unique without index added to existing table",
+			differences: []Difference{{
+				Type:       DiffTypeTableModified,
+				ObjectName: "public.posts",
+				Phases:     inOnePhase(addConstraint(unique, tree.ValidationDefault)),
+			}},
+			wantNotValid: true,
+			wantDeferred: []string{"ALTER TABLE posts VALIDATE CONSTRAINT posts_slug_key"},
+		},
+		{
+			name: "validation split from a difference moves",
+			differences: []Difference{{
+				Type:       DiffTypeTableModified,
+				ObjectName: "public.posts",
+				Phases: []Phase{
+					{Statements: []tree.Statement{addConstraint(fk, tree.ValidationSkip)}},
+					{Statements: []tree.Statement{
+						&tree.AlterTable{Table: posts, Cmds: tree.AlterTableCmds{&tree.AlterTableValidateConstraint{Constraint: "posts_user_fk"}}},
+					}},
+				},
+			}},
+			wantNotValid: true,
+			wantDeferred: []string{"ALTER TABLE posts VALIDATE CONSTRAINT posts_user_fk"},
+		},
+		{
+			name: "new tables are left alone",
+			differences: []Difference{{
+				Type:       DiffTypeTableAdded,
+				ObjectName: "public.posts",
+				Phases: inOnePhase(
+					addConstraint(fk, tree.ValidationDefault),
+				),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts := tt.differences[0].Statements()
+			original := statementsToStrings(stmts)
+			result := &ComparisonResult{Differences: tt.differences}
+			deferred, err := result.DeferValidation()
+			require.NoError(t, err)
+
+			// The statements passed in, which may be shared with a schema, aren't changed
+			assert.Equal(t, original, statementsToStrings(stmts))
+
+			require.Len(t, result.Differences, 1)
+			require.Len(t, result.Differences[0].Phases, 1)
+			ddl := strings.Join(statementsToStrings(result.Differences[0].Statements()), "\n")
+			assert.NotContains(t, ddl, "VALIDATE CONSTRAINT")
+			if tt.wantNotValid {
+				assert.Contains(t, ddl, "NOT VALID")
+			} else {
+				assert.NotContains(t, ddl, "NOT VALID")
+			}
+
+			var deferredDDL []string
+			for _, diff := range deferred.Differences {
+				deferredDDL = append(deferredDDL, statementsToStrings(diff.Statements())...)
+			}
+			assert.Equal(t, tt.wantDeferred, deferredDDL)
+		})
+	}
+}
+
+func TestDeferValidationUnnamedConstraint(t *testing.T) {
+	postsTable := tree.MakeUnqualifiedTableName("posts")
+	result := &ComparisonResult{Differences: []Difference{{
+		Type:       DiffTypeTableModified,
+		ObjectName: "public.posts",
+		Phases: inOnePhase(&tree.AlterTable{
+			Table: postsTable.ToUnresolvedObjectName(),
+			Cmds: tree.AlterTableCmds{&tree.AlterTableAddConstraint{
+				ConstraintDef: &tree.ForeignKeyConstraintTableDef{
+					Table:    tree.MakeUnqualifiedTableName("users"),
+					FromCols: tree.NameList{"user_id"},
+					ToCols:   tree.NameList{"id"},
+				},
+			}},
+		}),
+	}}}
+
+	_, err := result.DeferValidation()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no name")
+}
+
+func TestDeferUniqueValidation(t *testing.T) {
+	postsTable := tree.MakeUnqualifiedTableName("posts")
+	posts := postsTable.ToUnresolvedObjectName()
+	unique := &tree.UniqueConstraintTableDef{
+		IndexTableDef: tree.IndexTableDef{Name: "posts_slug_key", Columns: tree.IndexElemList{{Column: "slug"}}},
+		WithoutIndex:  true,
+	}
+	fk := &tree.ForeignKeyConstraintTableDef{
+		Table:    tree.MakeUnqualifiedTableName("users"),
+		FromCols: tree.NameList{"user_id"},
+		ToCols:   tree.NameList{"id"},
+	}
+	differences := func() []Difference {
+		return []Difference{{
+			Type:       DiffTypeTableModified,
+			ObjectName: "public.posts",
+			Phases: inOnePhase(&tree.AlterTable{
+				Table: posts,
+				Cmds: tree.AlterTableCmds{
+					&tree.AlterTableAddConstraint{ConstraintDef: unique},
+					// Unnamed, so it couldn't be deferred, but it isn't asked to be
+					&tree.AlterTableAddConstraint{ConstraintDef: fk},
+				},
+			}),
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		large        bool
+		expected     []string
+		wantDeferred []string
+	}{
+		{
+			name:     "small table",
+			expected: []string{"ALTER TABLE posts ADD CONSTRAINT posts_slug_key UNIQUE WITHOUT INDEX (slug), ADD FOREIGN KEY (user_id) REFERENCES users (id)"},
+		},
+		{
+			name:         "large table",
+			large:        true,
+			expected:     []string{"ALTER TABLE posts ADD CONSTRAINT posts_slug_key UNIQUE WITHOUT INDEX (slug) NOT VALID, ADD FOREIGN KEY (user_id) REFERENCES users (id)"},
+			wantDeferred: []string{"ALTER TABLE posts VALIDATE CONSTRAINT posts_slug_key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &ComparisonResult{Differences: differences()}
+			deferred, err := result.DeferUniqueValidation(func(tableName string) bool {
+				assert.Equal(t, "public.posts", tableName)
+				return tt.large
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, statementsToStrings(result.Differences[0].Statements()))
+			var deferredDDL []string
+			for _, diff := range deferred.Differences {
+				deferredDDL = append(deferredDDL, statementsToStrings(diff.Statements())...)
+			}
+			assert.Equal(t, tt.wantDeferred, deferredDDL)
+		})
+	}
+}
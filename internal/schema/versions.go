@@ -0,0 +1,236 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/idxtype"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+)
+
+// Version is a CockroachDB release, e.g. v24.1
+type Version struct {
+	Major int
+	Minor int
+}
+
+var versionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)`)
+
+// ParseVersion reads the release from a version like "v24.1.0" or "24.1", or
+// from the output of SELECT version(), e.g. "CockroachDB CCL v24.1.0 (...)"
+func ParseVersion(s string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(s)
+	if match == nil {
+		return Version{}, fmt.Errorf("no CockroachDB version in %q", s)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return Version{Major: major, Minor: minor}, nil
+}
+
+// Before returns true if v is an earlier release than other
+func (v Version) Before(other Version) bool {
+	return v.Major < other.Major || (v.Major == other.Major && v.Minor < other.Minor)
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("v%d.%d", v.Major, v.Minor)
+}
+
+// versionRequirement is a statement form that needs a minimum release. On an
+// older release the fallback, if there is one, replaces the statement with
+// statements that release supports.
+type versionRequirement struct {
+	feature  string
+	since    Version
+	matches  func(stmt tree.Statement) bool
+	fallback func(stmt tree.Statement) []tree.Statement
+}
+
+var versionRequirements = []versionRequirement{
+	{
+		feature: "ALTER TYPE ... DROP VALUE",
+		since:   Version{21, 2},
+		matches: func(stmt tree.Statement) bool {
+			alter, ok := stmt.(*tree.AlterType)
+			if !ok {
+				return false
+			}
+			_, ok = alter.Cmd.(*tree.AlterTypeDropValue)
+			return ok
+		},
+	},
+	{
+		// Type changes that rewrite the column were experimental until v25.1
+		feature: "ALTER COLUMN TYPE",
+		since:   Version{25, 1},
+		matches: func(stmt tree.Statement) bool {
+			return hasAlterTableCmd[*tree.AlterTableAlterColumnType](stmt)
+		},
+		fallback: func(stmt tree.Statement) []tree.Statement {
+			return []tree.Statement{
+				&tree.SetVar{Name: "enable_experimental_alter_column_type_general", Values: tree.Exprs{tree.DBoolTrue}},
+				stmt,
+			}
+		},
+	},
+	{
+		feature: "row-level TTL",
+		since:   Version{22, 1},
+		matches: func(stmt tree.Statement) bool { return setsStorageParam(stmt, "ttl_expire_after") },
+	},
+	{
+		feature: "ttl_expiration_expression",
+		since:   Version{22, 2},
+		matches: func(stmt tree.Statement) bool { return setsStorageParam(stmt, "ttl_expiration_expression") },
+	},
+	{
+		feature: "user-defined functions",
+		since:   Version{22, 2},
+		matches: func(stmt tree.Statement) bool {
+			_, ok := stmt.(*tree.CreateRoutine)
+			return ok
+		},
+	},
+	{
+		feature: "composite types",
+		since:   Version{23, 1},
+		matches: func(stmt tree.Statement) bool {
+			ct, ok := stmt.(*tree.CreateType)
+			return ok && ct.Variety == tree.Composite
+		},
+	},
+	{
+		feature: "stored procedures",
+		since:   Version{23, 2},
+		matches: func(stmt tree.Statement) bool {
+			routine, ok := stmt.(*tree.CreateRoutine)
+			return ok && routine.IsProcedure
+		},
+	},
+	{
+		feature: "PL/pgSQL routines",
+		since:   Version{23, 2},
+		matches: func(stmt tree.Statement) bool {
+			routine, ok := stmt.(*tree.CreateRoutine)
+			if !ok {
+				return false
+			}
+			for _, option := range routine.Options {
+				if lang, ok := option.(tree.RoutineLanguage); ok && lang == tree.RoutineLangPLpgSQL {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	{
+		feature: "triggers",
+		since:   Version{24, 3},
+		matches: func(stmt tree.Statement) bool {
+			_, ok := stmt.(*tree.CreateTrigger)
+			return ok
+		},
+	},
+	{
+		feature: "vector indexes",
+		since:   Version{25, 2},
+		matches: createsVectorIndex,
+	},
+}
+
+// ForTargetVersion rewrites the statements of each difference into forms the
+// target release supports. A difference that needs a newer release, with no
+// way to express it on the target, gets a BlockingError, so GenerateMigrations
+// fails before anything is applied.
+func (r *ComparisonResult) ForTargetVersion(target Version) {
+	for i := range r.Differences {
+		diff := &r.Differences[i]
+		var unsupported []string
+		for j := range diff.Phases {
+			var stmts []tree.Statement
+			for _, stmt := range diff.Phases[j].Statements {
+				rewritten, missing := statementForVersion(stmt, target)
+				stmts = append(stmts, rewritten...)
+				unsupported = append(unsupported, missing...)
+			}
+			diff.Phases[j].Statements = stmts
+		}
+		if len(unsupported) > 0 {
+			diff.BlockingError = fmt.Sprintf("%s: %s, but the target cluster is %s",
+				diff.Description, strings.Join(unsupported, ", "), target)
+		}
+	}
+}
+
+// statementForVersion returns the statements that express stmt on the target
+// release, and the features it needs that the release has no fallback for
+func statementForVersion(stmt tree.Statement, target Version) ([]tree.Statement, []string) {
+	stmts := []tree.Statement{stmt}
+	var unsupported []string
+	for _, req := range versionRequirements {
+		if !target.Before(req.since) || !req.matches(stmt) {
+			continue
+		}
+		if req.fallback != nil {
+			stmts = req.fallback(stmt)
+			continue
+		}
+		unsupported = append(unsupported, fmt.Sprintf("%s needs CockroachDB %s or later", req.feature, req.since))
+	}
+	return stmts, unsupported
+}
+
+// hasAlterTableCmd returns true if stmt is an ALTER TABLE with a command of type T
+func hasAlterTableCmd[T tree.AlterTableCmd](stmt tree.Statement) bool {
+	alter, ok := stmt.(*tree.AlterTable)
+	if !ok {
+		return false
+	}
+	for _, cmd := range alter.Cmds {
+		if _, ok := cmd.(T); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setsStorageParam returns true if stmt creates a table with the storage
+// param, or sets it on an existing one
+func setsStorageParam(stmt tree.Statement, key string) bool {
+	var params tree.StorageParams
+	switch stmt := stmt.(type) {
+	case *tree.CreateTable:
+		params = stmt.StorageParams
+	case *tree.AlterTable:
+		for _, cmd := range stmt.Cmds {
+			if set, ok := cmd.(*tree.AlterTableSetStorageParams); ok {
+				params = append(params, set.StorageParams...)
+			}
+		}
+	}
+	for _, param := range params {
+		if string(param.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// createsVectorIndex returns true if stmt creates a vector index, on its own
+// or as part of a new table
+func createsVectorIndex(stmt tree.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *tree.CreateIndex:
+		return stmt.Type == idxtype.VECTOR
+	case *tree.CreateTable:
+		for _, def := range stmt.Defs {
+			if index, ok := def.(*tree.IndexTableDef); ok && index.Type == idxtype.VECTOR {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Version
+		wantErr  bool
+	}{
+		{name: "release", input: "v24.1.0", expected: Version{24, 1}},
+		{name: "without v", input: "23.2", expected: Version{23, 2}},
+		{name: "version()", input: "CockroachDB CCL v25.2.1 (x86_64-pc-linux-gnu, built 2025/06/01 00:00:00, go1.23.7)", expected: Version{25, 2}},
+		{name: "no version", input: "latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := ParseVersion(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestForTargetVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		statements   []string
+		target       Version
+		expected     []string
+		wantBlocking []string
+	}{
+		{
+			name:       "supported statements are kept",
+			statements: []string{"ALTER TYPE public.status DROP VALUE 'pending'"},
+			target:     Version{24, 1},
+			expected:   []string{"ALTER TYPE public.status DROP VALUE 'pending'"},
+		},
+		{
+			name:         "enum drop value before it was supported",
+			statements:   []string{"ALTER TYPE public.status DROP VALUE 'pending'"},
+			target:       Version{21, 1},
+			wantBlocking: []string{"ALTER TYPE ... DROP VALUE needs CockroachDB v21.2 or later", "the target cluster is v21.1"},
+		},
+		{
+			name:       "column type change enables the experimental setting",
+			statements: []string{"ALTER TABLE public.users ALTER COLUMN age TYPE INT4"},
+			target:     Version{24, 3},
+			expected: []string{
+				"SET enable_experimental_alter_column_type_general = true",
+				"ALTER TABLE public.users ALTER COLUMN age SET DATA TYPE INT4",
+			},
+		},
+		{
+			name:       "column type change on a release that supports it",
+			statements: []string{"ALTER TABLE public.users ALTER COLUMN age TYPE INT4"},
+			target:     Version{25, 2},
+			expected:   []string{"ALTER TABLE public.users ALTER COLUMN age SET DATA TYPE INT4"},
+		},
+		{
+			name:         "TTL expression",
+			statements:   []string{"ALTER TABLE public.sessions SET (ttl_expiration_expression = 'expires_at')"},
+			target:       Version{22, 1},
+			wantBlocking: []string{"ttl_expiration_expression needs CockroachDB v22.2 or later"},
+		},
+		{
+			name:         "PL/pgSQL procedure",
+			statements:   []string{"CREATE PROCEDURE public.cleanup() LANGUAGE PLpgSQL AS $$ BEGIN END $$"},
+			target:       Version{23, 1},
+			wantBlocking: []string{"stored procedures needs CockroachDB v23.2 or later", "PL/pgSQL routines needs CockroachDB v23.2 or later"},
+		},
+		{
+			name:         "vector index in a new table",
+			statements:   []string{"CREATE TABLE public.items (id INT8 PRIMARY KEY, embedding VECTOR(3), VECTOR INDEX (embedding))"},
+			target:       Version{25, 1},
+			wantBlocking: []string{"vector indexes needs CockroachDB v25.2 or later"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts := parseStatements(tt.statements...)
+			result := &ComparisonResult{Differences: []Difference{{Description: "change", Phases: inOnePhase(stmts...)}}}
+
+			result.ForTargetVersion(tt.target)
+
+			diff := result.Differences[0]
+			if len(tt.wantBlocking) == 0 {
+				assert.Empty(t, diff.BlockingError)
+				assert.Equal(t, tt.expected, statementsToStringsTypes(diff.Statements()))
+				_, _, err := result.GenerateMigrations(false)
+				assert.NoError(t, err)
+				return
+			}
+			for _, want := range tt.wantBlocking {
+				assert.Contains(t, diff.BlockingError, want)
+			}
+			_, _, err := result.GenerateMigrations(false)
+			assert.Error(t, err)
+		})
+	}
+}
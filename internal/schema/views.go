@@ -27,14 +27,14 @@ func compareViews(local, remote *Schema) []Difference {
 		if !existsInRemote {
 			// View added - create it
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeViewAdded,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("View '%s' added", name),
-				MigrationStatements: []tree.Statement{localView.Ast},
+				Type:        DiffTypeViewAdded,
+				ObjectName:  name,
+				Description: fmt.Sprintf("View '%s' added", name),
+				Phases:      inOnePhase(localView.Ast),
 			})
 		} else {
 			// Check if view was modified
-			if localView.Ast.String() != remoteView.Ast.String() {
+			if local.checksum(localView.Ast) != remote.checksum(remoteView.Ast) {
 				// View modified - drop and recreate
 				// Use DROP VIEW which works for both regular and materialized views
 				drop := &tree.DropView{
@@ -44,10 +44,10 @@ func compareViews(local, remote *Schema) []Difference {
 					IsMaterialized: remoteView.Ast.Materialized,
 				}
 				diffs = append(diffs, Difference{
-					Type:                DiffTypeViewModified,
-					ObjectName:          name,
-					Description:         fmt.Sprintf("View '%s' modified", name),
-					MigrationStatements: []tree.Statement{drop, localView.Ast},
+					Type:        DiffTypeViewModified,
+					ObjectName:  name,
+					Description: fmt.Sprintf("View '%s' modified", name),
+					Phases:      inOnePhase(drop, localView.Ast),
 				})
 			}
 		}
@@ -64,10 +64,10 @@ func compareViews(local, remote *Schema) []Difference {
 				IsMaterialized: remoteView.Ast.Materialized,
 			}
 			diffs = append(diffs, Difference{
-				Type:                DiffTypeViewRemoved,
-				ObjectName:          name,
-				Description:         fmt.Sprintf("View '%s' removed", name),
-				MigrationStatements: []tree.Statement{drop},
+				Type:        DiffTypeViewRemoved,
+				ObjectName:  name,
+				Description: fmt.Sprintf("View '%s' removed", name),
+				Phases:      inOnePhase(drop),
 			})
 		}
 	}
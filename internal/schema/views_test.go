@@ -190,12 +190,12 @@ func TestViewModifiedMigration(t *testing.T) {
 				t.Errorf("expected DiffTypeViewModified, got %s", diff.Type)
 			}
 
-			if len(diff.MigrationStatements) != tt.wantStmtCount {
-				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.MigrationStatements))
+			if len(diff.Statements()) != tt.wantStmtCount {
+				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.Statements()))
 			}
 
 			// Check for expected strings in migration DDL
-			allDDL := strings.Join(statementsToStringsViews(diff.MigrationStatements), "\n")
+			allDDL := strings.Join(statementsToStringsViews(diff.Statements()), "\n")
 			for _, expected := range tt.wantContains {
 				if !strings.Contains(allDDL, expected) {
 					t.Errorf("migration DDL missing expected string %q.\nGot:\n%s", expected, allDDL)
@@ -275,12 +275,12 @@ func TestViewAddedRemoved(t *testing.T) {
 				t.Errorf("expected %s, got %s", tt.diffType, diff.Type)
 			}
 
-			if len(diff.MigrationStatements) != tt.wantStmtCount {
-				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.MigrationStatements))
+			if len(diff.Statements()) != tt.wantStmtCount {
+				t.Errorf("expected %d migration statements, got %d", tt.wantStmtCount, len(diff.Statements()))
 			}
 
 			// Check for expected strings in migration DDL
-			allDDL := strings.Join(statementsToStringsViews(diff.MigrationStatements), "\n")
+			allDDL := strings.Join(statementsToStringsViews(diff.Statements()), "\n")
 			for _, expected := range tt.wantContains {
 				if !strings.Contains(allDDL, expected) {
 					t.Errorf("migration DDL missing expected string %q.\nGot:\n%s", expected, allDDL)
@@ -0,0 +1,408 @@
+// Package schematest runs the schema test files in the tests directory of a
+// definitions directory, e.g. definitions/tests/*.sql, against a shadow database
+// built from the definitions.
+package schematest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+const (
+	directivePrefix           = "-- scurry:"
+	testDirective             = "test"
+	expectRowsDirective       = "expect-rows"
+	expectResultDirective     = "expect-result"
+	expectIndexDirective      = "expect-index"
+	expectNoFullScanDirective = "expect-no-full-scan"
+	expectErrorDirective      = "expect-error"
+)
+
+// File is a test file: its statements run in order against a fresh shadow database
+type File struct {
+	Path       string
+	Statements []Statement
+}
+
+// Statement is a statement in a test file. Statements without a -- scurry:test
+// directive set up data for the tests after them; their Test is nil.
+type Statement struct {
+	SQL  string
+	Test *Test
+}
+
+// Test is what a -- scurry:test directive and the expect-* directives after it
+// assert about the statement below them
+type Test struct {
+	Name string
+	// ExpectRows is the number of rows the statement returns or affects, or -1 to not check
+	ExpectRows int
+	// ExpectResult is the rows the query returns, each column formatted as text,
+	// or nil to not check
+	ExpectResult [][]string
+	// ExpectIndexes are indexes the query plan must use, as "index" or "table@index"
+	ExpectIndexes []string
+	// ExpectNoFullScan fails the test if the query plan scans a whole table or index
+	ExpectNoFullScan bool
+	// ExpectError is text the statement's error must contain, or "" if it must succeed
+	ExpectError string
+}
+
+// Result is the outcome of one test
+type Result struct {
+	File     string
+	Name     string
+	Failures []string
+}
+
+// Passed returns true if none of the test's assertions failed
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Load reads the test files in each definitions directory, in file name order
+func Load(fs afero.Fs, dirPaths []string) ([]File, error) {
+	var files []File
+	for _, dirPath := range dirPaths {
+		testsPath := filepath.Join(dirPath, schema.TestsDir)
+		exists, err := afero.DirExists(fs, testsPath)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		paths, err := afero.Glob(fs, filepath.Join(testsPath, "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(paths)
+		for _, path := range paths {
+			content, err := afero.ReadFile(fs, path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+			}
+			statements, err := parseTestSQL(string(content))
+			if err != nil {
+				return nil, fmt.Errorf("in file %s: %w", path, err)
+			}
+			files = append(files, File{Path: path, Statements: statements})
+		}
+	}
+	return files, nil
+}
+
+// parseTestSQL splits a test file into statements, attaching the test described
+// by the directives in the comments directly above each one:
+//
+//	-- scurry:test email lookups use the email index
+//	-- scurry:expect-index=users@users_email_key
+//	-- scurry:expect-no-full-scan
+//	-- scurry:expect-result
+//	-- 1 | a@example.com
+//	SELECT id, email FROM users WHERE email = 'a@example.com';
+func parseTestSQL(sql string) ([]Statement, error) {
+	parsed, err := parser.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := make([]Statement, 0, len(parsed))
+	offset := 0
+	for _, stmt := range parsed {
+		preceding := ""
+		if idx := strings.Index(sql[offset:], stmt.SQL); idx != -1 {
+			preceding = sql[offset : offset+idx]
+			offset += idx + len(stmt.SQL)
+		}
+		test, err := parseTestDirectives(preceding)
+		if err != nil {
+			return nil, fmt.Errorf("%w, above %q", err, firstLine(stmt.SQL))
+		}
+		if test != nil && test.Name == "" {
+			test.Name = firstLine(stmt.SQL)
+		}
+		statements = append(statements, Statement{SQL: stmt.SQL, Test: test})
+	}
+	return statements, nil
+}
+
+// parseTestDirectives returns the test described by the directives in the text
+// above a statement, or nil if there's no -- scurry:test directive
+func parseTestDirectives(text string) (*Test, error) {
+	var test *Test
+	var expect []string // Directives seen before -- scurry:test, to report them
+	inResult := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, directivePrefix) {
+			// Rows of an expected result are the comment lines after -- scurry:expect-result
+			if inResult && strings.HasPrefix(line, "--") {
+				test.ExpectResult = append(test.ExpectResult, parseResultRow(strings.TrimPrefix(line, "--")))
+				continue
+			}
+			inResult = false
+			continue
+		}
+		inResult = false
+
+		directive := strings.TrimPrefix(line, directivePrefix)
+		name, value, hasValue := strings.Cut(directive, "=")
+		if name == testDirective || strings.HasPrefix(name, testDirective+" ") {
+			test = &Test{Name: strings.TrimSpace(strings.TrimPrefix(directive, testDirective)), ExpectRows: -1}
+			continue
+		}
+		if !strings.HasPrefix(name, "expect-") {
+			// Other directives, like -- scurry:classify, aren't about tests
+			continue
+		}
+		if test == nil {
+			expect = append(expect, name)
+			continue
+		}
+
+		switch name {
+		case expectRowsDirective:
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid scurry:%s value %q: expected a number of rows", name, value)
+			}
+			test.ExpectRows = n
+		case expectResultDirective:
+			if hasValue {
+				return nil, fmt.Errorf("scurry:%s takes no value; put each expected row on a comment line below it", name)
+			}
+			test.ExpectResult = [][]string{}
+			inResult = true
+		case expectIndexDirective:
+			index := strings.TrimSpace(value)
+			if index == "" {
+				return nil, fmt.Errorf("scurry:%s needs an index name, e.g. scurry:%s=users@users_email_key", name, name)
+			}
+			test.ExpectIndexes = append(test.ExpectIndexes, index)
+		case expectNoFullScanDirective:
+			test.ExpectNoFullScan = true
+		case expectErrorDirective:
+			test.ExpectError = strings.TrimSpace(value)
+			if test.ExpectError == "" {
+				return nil, fmt.Errorf("scurry:%s needs text the error must contain", name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown directive scurry:%s", name)
+		}
+	}
+
+	if test == nil && len(expect) > 0 {
+		return nil, fmt.Errorf("scurry:%s needs a -- scurry:test directive above it", expect[0])
+	}
+	if test != nil && test.ExpectError != "" && (test.ExpectRows != -1 || test.ExpectResult != nil) {
+		return nil, fmt.Errorf("test %q can't expect both an error and rows", test.Name)
+	}
+	return test, nil
+}
+
+// parseResultRow splits an expected row into its columns, separated by |
+func parseResultRow(line string) []string {
+	columns := strings.Split(line, "|")
+	for i, column := range columns {
+		columns[i] = strings.TrimSpace(column)
+	}
+	return columns
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// Run runs the statements of a test file in order against client, which should
+// be a shadow database with the schema loaded. A setup statement that fails
+// stops the file, since the tests after it would fail for the wrong reason.
+func Run(ctx context.Context, client *db.Client, file File) ([]Result, error) {
+	var results []Result
+	for _, stmt := range file.Statements {
+		if stmt.Test == nil {
+			if _, err := client.GetDB().ExecContext(ctx, stmt.SQL); err != nil {
+				return results, fmt.Errorf("setup statement %q failed: %w", firstLine(stmt.SQL), err)
+			}
+			continue
+		}
+		results = append(results, Result{
+			File:     file.Path,
+			Name:     stmt.Test.Name,
+			Failures: runTest(ctx, client, stmt.SQL, stmt.Test),
+		})
+	}
+	return results, nil
+}
+
+// runTest runs a test's statement, checking the plan first so the statement
+// hasn't changed any data yet, and returns the assertions that failed
+func runTest(ctx context.Context, client *db.Client, query string, test *Test) []string {
+	var failures []string
+	if len(test.ExpectIndexes) > 0 || test.ExpectNoFullScan {
+		plan, err := client.Explain(ctx, query)
+		if err != nil {
+			return []string{fmt.Sprintf("EXPLAIN failed: %v", err)}
+		}
+		failures = append(failures, checkPlan(plan, test)...)
+	}
+
+	var rows [][]string
+	var count int64
+	var err error
+	if test.ExpectResult != nil {
+		rows, err = queryRows(ctx, client.GetDB(), query)
+		count = int64(len(rows))
+	} else {
+		count, err = execCount(ctx, client.GetDB(), query)
+	}
+	if test.ExpectError != "" {
+		if err == nil {
+			return append(failures, fmt.Sprintf("expected an error containing %q, but the statement succeeded", test.ExpectError))
+		}
+		if !strings.Contains(err.Error(), test.ExpectError) {
+			return append(failures, fmt.Sprintf("expected an error containing %q, got: %v", test.ExpectError, err))
+		}
+		return failures
+	}
+	if err != nil {
+		return append(failures, fmt.Sprintf("statement failed: %v", err))
+	}
+	return append(failures, checkRows(rows, count, test)...)
+}
+
+// checkPlan returns the plan assertions of a test that the EXPLAIN output fails
+func checkPlan(plan []string, test *Test) []string {
+	var failures []string
+	for _, index := range test.ExpectIndexes {
+		if !planUsesIndex(plan, index) {
+			failures = append(failures, fmt.Sprintf("expected the plan to use index %s:\n%s", index, indentPlan(plan)))
+		}
+	}
+	if test.ExpectNoFullScan {
+		for _, line := range plan {
+			if strings.Contains(line, "FULL SCAN") {
+				failures = append(failures, fmt.Sprintf("expected no full scans in the plan:\n%s", indentPlan(plan)))
+				break
+			}
+		}
+	}
+	return failures
+}
+
+// planUsesIndex returns true if a scan or join in the plan reads the index,
+// which EXPLAIN shows as "table: users@users_email_key". An index name without
+// a table matches that index on any table.
+func planUsesIndex(plan []string, index string) bool {
+	for _, line := range plan {
+		_, scanned, ok := strings.Cut(line, "table: ")
+		if !ok {
+			continue
+		}
+		scanned = strings.TrimSpace(scanned)
+		if strings.Contains(index, "@") {
+			if scanned == index {
+				return true
+			}
+		} else if strings.HasSuffix(scanned, "@"+index) {
+			return true
+		}
+	}
+	return false
+}
+
+func indentPlan(plan []string) string {
+	var sb strings.Builder
+	for i, line := range plan {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("    ")
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// queryRows runs the query and returns the rows, each column formatted as
+// text, with NULL for nulls
+func queryRows(ctx context.Context, conn *sql.DB, query string) ([][]string, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result [][]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make([]string, len(columns))
+		for i, v := range values {
+			if v.Valid {
+				row[i] = v.String
+			} else {
+				row[i] = "NULL"
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// execCount runs the statement and returns the number of rows it returned or
+// affected
+func execCount(ctx context.Context, conn *sql.DB, query string) (int64, error) {
+	result, err := conn.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// checkRows returns the result assertions of a test that the statement's rows
+// fail. rows is only read when the test expects a result.
+func checkRows(rows [][]string, count int64, test *Test) []string {
+	var failures []string
+	if test.ExpectRows != -1 && count != int64(test.ExpectRows) {
+		failures = append(failures, fmt.Sprintf("expected %d row(s), got %d", test.ExpectRows, count))
+	}
+	if test.ExpectResult != nil && !slices.EqualFunc(rows, test.ExpectResult, slices.Equal) {
+		failures = append(failures, fmt.Sprintf("expected rows:\n%s\ngot:\n%s", formatRows(test.ExpectResult), formatRows(rows)))
+	}
+	return failures
+}
+
+func formatRows(rows [][]string) string {
+	if len(rows) == 0 {
+		return "    (no rows)"
+	}
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = "    " + strings.Join(row, " | ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,272 @@
+package schematest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestLoad(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "defs/tests/02_orders.sql", []byte("-- scurry:test orders\nSELECT 2;"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/tests/01_users.sql", []byte("-- scurry:test users\nSELECT 1;"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/tables/users.sql", []byte("CREATE TABLE users (id INT8 PRIMARY KEY);"), 0644))
+
+	files, err := Load(fs, []string{"defs", "missing"})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "defs/tests/01_users.sql", files[0].Path)
+	assert.Equal(t, "users", files[0].Statements[0].Test.Name)
+	assert.Equal(t, "defs/tests/02_orders.sql", files[1].Path)
+}
+
+func TestParseTestSQL(t *testing.T) {
+	tests := []struct {
+		name        string
+		sql         string
+		expected    []Statement
+		errContains string
+	}{
+		{
+			name: "setup statements have no test",
+			sql:  "-- Some users\nINSERT INTO users VALUES (1);",
+			expected: []Statement{
+				{SQL: "INSERT INTO users VALUES (1)"},
+			},
+		},
+		{
+			name: "plan assertions",
+			sql: `-- scurry:test lookup by email
+-- scurry:expect-index=users@users_email_key
+-- scurry:expect-index=orders_user_id_idx
+-- scurry:expect-no-full-scan
+SELECT id FROM users WHERE email = 'a';`,
+			expected: []Statement{
+				{SQL: "SELECT id FROM users WHERE email = 'a'", Test: &Test{
+					Name:             "lookup by email",
+					ExpectRows:       -1,
+					ExpectIndexes:    []string{"users@users_email_key", "orders_user_id_idx"},
+					ExpectNoFullScan: true,
+				}},
+			},
+		},
+		{
+			name: "expected result rows",
+			sql: `INSERT INTO users VALUES (1, 'a');
+-- scurry:test users
+-- scurry:expect-rows=2
+-- scurry:expect-result
+--   1 | a
+--   2 | NULL
+
+SELECT id, email FROM users ORDER BY id;`,
+			expected: []Statement{
+				{SQL: "INSERT INTO users VALUES (1, 'a')"},
+				{SQL: "SELECT id, email FROM users ORDER BY id", Test: &Test{
+					Name:         "users",
+					ExpectRows:   2,
+					ExpectResult: [][]string{{"1", "a"}, {"2", "NULL"}},
+				}},
+			},
+		},
+		{
+			name: "expecting no rows",
+			sql:  "-- scurry:test nothing\n-- scurry:expect-result\nSELECT 1 WHERE false;",
+			expected: []Statement{
+				{SQL: "SELECT 1 WHERE false", Test: &Test{Name: "nothing", ExpectRows: -1, ExpectResult: [][]string{}}},
+			},
+		},
+		{
+			name: "expected error",
+			sql:  "-- scurry:test duplicates\n-- scurry:expect-error=duplicate key\nINSERT INTO users VALUES (1);",
+			expected: []Statement{
+				{SQL: "INSERT INTO users VALUES (1)", Test: &Test{Name: "duplicates", ExpectRows: -1, ExpectError: "duplicate key"}},
+			},
+		},
+		{
+			name: "unnamed test is named after its statement",
+			sql:  "-- scurry:test\nSELECT 1;",
+			expected: []Statement{
+				{SQL: "SELECT 1", Test: &Test{Name: "SELECT 1", ExpectRows: -1}},
+			},
+		},
+		{
+			name:        "expect directive without a test",
+			sql:         "-- scurry:expect-rows=1\nSELECT 1;",
+			errContains: "scurry:expect-rows needs a -- scurry:test directive above it",
+		},
+		{
+			name:        "unknown expect directive",
+			sql:         "-- scurry:test t\n-- scurry:expect-rowz=1\nSELECT 1;",
+			errContains: "unknown directive scurry:expect-rowz",
+		},
+		{
+			name:        "invalid row count",
+			sql:         "-- scurry:test t\n-- scurry:expect-rows=many\nSELECT 1;",
+			errContains: `invalid scurry:expect-rows value "many"`,
+		},
+		{
+			name:        "error and rows",
+			sql:         "-- scurry:test t\n-- scurry:expect-rows=1\n-- scurry:expect-error=boom\nSELECT 1;",
+			errContains: `test "t" can't expect both an error and rows`,
+		},
+		{
+			name:        "invalid SQL",
+			sql:         "SELEC 1;",
+			errContains: "syntax error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parseTestSQL(tt.sql)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestPlanUsesIndex(t *testing.T) {
+	plan := []string{
+		"distribution: local",
+		"",
+		"• scan",
+		"  missing stats",
+		"  table: users@users_email_key",
+		"  spans: [/'a' - /'a']",
+	}
+
+	tests := []struct {
+		index    string
+		expected bool
+	}{
+		{index: "users@users_email_key", expected: true},
+		{index: "users_email_key", expected: true},
+		{index: "orders@users_email_key", expected: false},
+		{index: "users_pkey", expected: false},
+		{index: "email_key", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.index, func(t *testing.T) {
+			assert.Equal(t, tt.expected, planUsesIndex(plan, tt.index))
+		})
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	plan := []string{"• scan", "  table: users@users_pkey", "  spans: FULL SCAN"}
+
+	failures := checkPlan(plan, &Test{ExpectIndexes: []string{"users_pkey"}})
+	assert.Empty(t, failures)
+
+	failures = checkPlan(plan, &Test{ExpectIndexes: []string{"users_email_key"}, ExpectNoFullScan: true})
+	require.Len(t, failures, 2)
+	assert.Contains(t, failures[0], "expected the plan to use index users_email_key")
+	assert.Contains(t, failures[1], "expected no full scans in the plan")
+	assert.Contains(t, failures[1], "    spans: FULL SCAN")
+}
+
+func TestCheckRows(t *testing.T) {
+	rows := [][]string{{"1", "a"}, {"2", "NULL"}}
+
+	tests := []struct {
+		name     string
+		rows     [][]string
+		count    int64
+		test     Test
+		expected []string
+	}{
+		{
+			name:  "matching rows",
+			rows:  rows,
+			count: 2,
+			test:  Test{ExpectRows: 2, ExpectResult: [][]string{{"1", "a"}, {"2", "NULL"}}},
+		},
+		{
+			name:     "wrong count",
+			count:    3,
+			test:     Test{ExpectRows: 1},
+			expected: []string{"expected 1 row(s), got 3"},
+		},
+		{
+			name:     "different rows",
+			rows:     rows,
+			count:    2,
+			test:     Test{ExpectRows: -1, ExpectResult: [][]string{{"1", "a"}}},
+			expected: []string{"expected rows:\n    1 | a\ngot:\n    1 | a\n    2 | NULL"},
+		},
+		{
+			name:     "no rows",
+			test:     Test{ExpectRows: -1, ExpectResult: [][]string{{"1"}}},
+			expected: []string{"expected rows:\n    1\ngot:\n    (no rows)"},
+		},
+		{
+			name:  "nothing expected",
+			count: 5,
+			test:  Test{ExpectRows: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, checkRows(tt.rows, tt.count, &tt.test))
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	client, err := db.GetShadowDB(ctx,
+		"CREATE TABLE public.users (id INT8 PRIMARY KEY, email STRING NOT NULL, UNIQUE INDEX users_email_key (email))",
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	statements, err := parseTestSQL(`
+INSERT INTO users VALUES (1, 'a@example.com'), (2, 'b@example.com');
+
+-- scurry:test lookup by email
+-- scurry:expect-index=users@users_email_key
+-- scurry:expect-no-full-scan
+-- scurry:expect-result
+-- 1 | a@example.com
+SELECT id, email FROM users WHERE email = 'a@example.com';
+
+-- scurry:test lookup by id
+-- scurry:expect-index=users_email_key
+SELECT email FROM users WHERE id = 1;
+
+-- scurry:test update
+-- scurry:expect-rows=2
+UPDATE users SET email = email || '.org';
+
+-- scurry:test duplicate emails
+-- scurry:expect-error=duplicate key
+INSERT INTO users VALUES (3, 'a@example.com.org');
+`)
+	require.NoError(t, err)
+
+	results, err := Run(ctx, client, File{Path: "tests/users.sql", Statements: statements})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.True(t, results[0].Passed(), results[0].Failures)
+	assert.False(t, results[1].Passed())
+	assert.Contains(t, results[1].Failures[0], "expected the plan to use index users_email_key")
+	assert.True(t, results[2].Passed(), results[2].Failures)
+	assert.True(t, results[3].Passed(), results[3].Failures)
+
+	// A failing setup statement stops the file
+	_, err = Run(ctx, client, File{Path: "tests/bad.sql", Statements: []Statement{{SQL: "INSERT INTO missing VALUES (1)"}}})
+	assert.ErrorContains(t, err, `setup statement "INSERT INTO missing VALUES (1)" failed`)
+}
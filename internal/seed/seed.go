@@ -0,0 +1,143 @@
+// Package seed applies reference data from the seed directory of a definitions
+// directory, e.g. definitions/seed/*.sql.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/parser"
+	"github.com/cockroachdb/cockroachdb-parser/pkg/sql/sem/tree"
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// Statement is an INSERT from a seed file
+type Statement struct {
+	File  string
+	Table string
+	SQL   string
+}
+
+// Result is what applying a seed statement did
+type Result struct {
+	Statement
+	// Inserted is the number of rows that weren't in the table yet
+	Inserted int64
+}
+
+// Load reads the seed files in each definitions directory, in file name order.
+// Only INSERT statements with an ON CONFLICT clause, and UPSERTs, are allowed,
+// so seeds can be applied again without failing or duplicating rows.
+func Load(fs afero.Fs, dirPaths []string) ([]Statement, error) {
+	var statements []Statement
+	for _, dirPath := range dirPaths {
+		seedPath := filepath.Join(dirPath, schema.SeedDir)
+		exists, err := afero.DirExists(fs, seedPath)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		files, err := afero.Glob(fs, filepath.Join(seedPath, "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(files)
+		for _, file := range files {
+			content, err := afero.ReadFile(fs, file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+			}
+			fileStatements, err := parseSeedSQL(string(content), file)
+			if err != nil {
+				return nil, fmt.Errorf("in file %s: %w", file, err)
+			}
+			statements = append(statements, fileStatements...)
+		}
+	}
+	return statements, nil
+}
+
+func parseSeedSQL(sql, file string) ([]Statement, error) {
+	parsed, err := parser.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := make([]Statement, 0, len(parsed))
+	for _, stmt := range parsed {
+		insert, ok := stmt.AST.(*tree.Insert)
+		if !ok {
+			return nil, fmt.Errorf("seed files can only contain INSERT statements, found %s", stmt.AST.StatementTag())
+		}
+		if insert.OnConflict == nil {
+			return nil, fmt.Errorf("INSERT INTO %s needs ON CONFLICT DO NOTHING (or UPSERT) so it can be applied more than once", insert.Table)
+		}
+		statements = append(statements, Statement{
+			File:  file,
+			Table: tree.AsString(insert.Table),
+			SQL:   stmt.SQL,
+		})
+	}
+	return statements, nil
+}
+
+// Apply runs the seed statements in one transaction, reporting how many rows
+// each one inserted. With dryRun, the transaction is rolled back, so the
+// results show what would be inserted.
+func Apply(ctx context.Context, client *db.Client, statements []Statement, dryRun bool) ([]Result, error) {
+	tx, err := client.GetDB().BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]Result, 0, len(statements))
+	for _, stmt := range statements {
+		res, err := tx.ExecContext(ctx, stmt.SQL)
+		if err != nil {
+			return nil, fmt.Errorf("seed %s (%s): %w", stmt.Table, stmt.File, err)
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("seed %s (%s): %w", stmt.Table, stmt.File, err)
+		}
+		results = append(results, Result{Statement: stmt, Inserted: inserted})
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit seed data: %w", err)
+	}
+	return results, nil
+}
+
+// Summary totals the rows inserted into each table, in the order the tables
+// were first seeded
+func Summary(results []Result) string {
+	var tables []string
+	inserted := make(map[string]int64)
+	for _, r := range results {
+		if _, ok := inserted[r.Table]; !ok {
+			tables = append(tables, r.Table)
+		}
+		inserted[r.Table] += r.Inserted
+	}
+
+	var sb strings.Builder
+	for _, table := range tables {
+		fmt.Fprintf(&sb, "  %s: %d new row(s)\n", table, inserted[table])
+	}
+	return sb.String()
+}
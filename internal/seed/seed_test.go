@@ -0,0 +1,89 @@
+package seed
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pjtatlow/scurry/internal/db"
+)
+
+func TestLoad(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "defs/seed/02_features.sql", []byte(`
+UPSERT INTO features (name, enabled) VALUES ('beta', false);
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/seed/01_plans.sql", []byte(`
+INSERT INTO plans (id, name) VALUES (1, 'free'), (2, 'pro') ON CONFLICT DO NOTHING;
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "defs/tables/plans.sql", []byte("CREATE TABLE plans (id INT8 PRIMARY KEY);"), 0644))
+
+	statements, err := Load(fs, []string{"defs", "missing"})
+	require.NoError(t, err)
+	require.Len(t, statements, 2)
+	assert.Equal(t, "plans", statements[0].Table)
+	assert.Equal(t, "defs/seed/01_plans.sql", statements[0].File)
+	assert.Equal(t, "features", statements[1].Table)
+}
+
+func TestLoadRejectsUnsafeStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		message string
+	}{
+		{"insert without on conflict", "INSERT INTO plans (id) VALUES (1);", "needs ON CONFLICT DO NOTHING"},
+		{"not an insert", "DELETE FROM plans;", "can only contain INSERT statements"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "defs/seed/plans.sql", []byte(tt.sql), 0644))
+			_, err := Load(fs, []string{"defs"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "defs/seed/plans.sql")
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	client, err := db.GetShadowDB(ctx, "CREATE TABLE public.plans (id INT8 PRIMARY KEY, name STRING NOT NULL)")
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.GetDB().ExecContext(ctx, "INSERT INTO plans VALUES (1, 'free')")
+	require.NoError(t, err)
+
+	statements, err := parseSeedSQL("INSERT INTO plans (id, name) VALUES (1, 'free'), (2, 'pro') ON CONFLICT DO NOTHING;", "seed/plans.sql")
+	require.NoError(t, err)
+
+	count := func() int {
+		var n int
+		require.NoError(t, client.GetDB().QueryRowContext(ctx, "SELECT count(*) FROM plans").Scan(&n))
+		return n
+	}
+
+	// A dry run counts the missing rows without inserting them
+	results, err := Apply(ctx, client, statements, true)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(1), results[0].Inserted)
+	assert.Equal(t, 1, count())
+
+	results, err = Apply(ctx, client, statements, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), results[0].Inserted)
+	assert.Equal(t, 2, count())
+
+	// Seeding again is a no-op
+	results, err = Apply(ctx, client, statements, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), results[0].Inserted)
+	assert.Equal(t, "  plans: 0 new row(s)\n", Summary(results))
+}
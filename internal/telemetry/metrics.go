@@ -0,0 +1,130 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PushTimeout bounds how long pushing metrics may take
+var PushTimeout = 10 * time.Second
+
+// Migration is the outcome of executing one migration
+type Migration struct {
+	Name       string
+	Mode       string
+	Status     string
+	Duration   time.Duration
+	Statements int
+}
+
+// Metrics collects the migrations executed by this process
+type Metrics struct {
+	mu         sync.Mutex
+	migrations []Migration
+}
+
+var defaultMetrics = &Metrics{}
+
+// RecordMigration records an executed migration, to be pushed when scurry exits
+func RecordMigration(m Migration) {
+	defaultMetrics.Record(m)
+}
+
+// Record adds an executed migration
+func (m *Metrics) Record(migration Migration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.migrations = append(m.migrations, migration)
+}
+
+// Write writes the metrics in the Prometheus text exposition format
+func (m *Metrics) Write(w io.Writer, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+	failures := 0
+	for _, migration := range m.migrations {
+		if migration.Status == "failed" {
+			failures++
+		}
+	}
+
+	writeHeader(&buf, "scurry_migration_duration_seconds", "gauge", "How long each migration took to execute.")
+	for _, migration := range m.migrations {
+		fmt.Fprintf(&buf, "scurry_migration_duration_seconds%s %g\n", migrationLabels(migration), migration.Duration.Seconds())
+	}
+	writeHeader(&buf, "scurry_migration_statements", "gauge", "Statements executed by each migration.")
+	for _, migration := range m.migrations {
+		fmt.Fprintf(&buf, "scurry_migration_statements%s %d\n", migrationLabels(migration), migration.Statements)
+	}
+	writeHeader(&buf, "scurry_migrations_executed_total", "counter", "Migrations executed by the last run.")
+	fmt.Fprintf(&buf, "scurry_migrations_executed_total %d\n", len(m.migrations))
+	writeHeader(&buf, "scurry_migration_failures_total", "counter", "Migrations that failed in the last run.")
+	fmt.Fprintf(&buf, "scurry_migration_failures_total %d\n", failures)
+	writeHeader(&buf, "scurry_last_run_timestamp_seconds", "gauge", "When the last run finished, in seconds since the epoch.")
+	fmt.Fprintf(&buf, "scurry_last_run_timestamp_seconds %d\n", now.Unix())
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Push replaces the metrics of job on the push gateway at gatewayURL. Nothing
+// is pushed when no migrations were executed, so commands that don't run
+// migrations leave the last deploy's metrics in place.
+func (m *Metrics) Push(ctx context.Context, gatewayURL, job string) error {
+	m.mu.Lock()
+	empty := len(m.migrations) == 0
+	m.mu.Unlock()
+	if empty {
+		return nil
+	}
+
+	var body bytes.Buffer
+	if err := m.Write(&body, time.Now()); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, PushTimeout)
+	defer cancel()
+
+	endpoint := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create push gateway request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push gateway returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func writeHeader(buf *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func migrationLabels(m Migration) string {
+	return fmt.Sprintf(`{migration="%s",mode="%s",status="%s"}`, escapeLabel(m.Name), escapeLabel(m.Mode), escapeLabel(m.Status))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	m := &Metrics{}
+	m.Record(Migration{Name: "001_users", Mode: "sync", Status: "succeeded", Duration: 1500 * time.Millisecond, Statements: 3})
+	m.Record(Migration{Name: `002_"odd"`, Mode: "async", Status: "failed", Duration: 250 * time.Millisecond, Statements: 1})
+
+	var buf bytes.Buffer
+	require.NoError(t, m.Write(&buf, time.Unix(1700000000, 0)))
+	out := buf.String()
+
+	assert.Contains(t, out, "# TYPE scurry_migration_duration_seconds gauge\n")
+	assert.Contains(t, out, `scurry_migration_duration_seconds{migration="001_users",mode="sync",status="succeeded"} 1.5`+"\n")
+	assert.Contains(t, out, `scurry_migration_duration_seconds{migration="002_\"odd\"",mode="async",status="failed"} 0.25`+"\n")
+	assert.Contains(t, out, `scurry_migration_statements{migration="001_users",mode="sync",status="succeeded"} 3`+"\n")
+	assert.Contains(t, out, "scurry_migrations_executed_total 2\n")
+	assert.Contains(t, out, "scurry_migration_failures_total 1\n")
+	assert.Contains(t, out, "scurry_last_run_timestamp_seconds 1700000000\n")
+}
+
+func TestMetricsPush(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	m := &Metrics{}
+	m.Record(Migration{Name: "001_users", Mode: "sync", Status: "succeeded", Duration: time.Second, Statements: 2})
+	require.NoError(t, m.Push(context.Background(), server.URL+"/", "schema deploy"))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/metrics/job/schema deploy", path)
+	assert.Contains(t, body, "scurry_migrations_executed_total 1\n")
+}
+
+func TestMetricsPush_NothingRecorded(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	require.NoError(t, (&Metrics{}).Push(context.Background(), server.URL, "scurry"))
+	assert.False(t, called)
+}
+
+func TestMetricsPush_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metric", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	m := &Metrics{}
+	m.Record(Migration{Name: "001_users", Status: "succeeded"})
+	assert.ErrorContains(t, m.Push(context.Background(), server.URL, "scurry"), "400 Bad Request: bad metric")
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv(EnvPushgatewayURL, "")
+
+	shutdown, err := Setup(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestTracingEnabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_SDK_DISABLED", "")
+	assert.False(t, tracingEnabled())
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://localhost:4318/v1/traces")
+	assert.True(t, tracingEnabled())
+
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	assert.False(t, tracingEnabled())
+}
@@ -0,0 +1,93 @@
+// Package telemetry exports traces and metrics of migration execution, for
+// observing schema deploys alongside application deploys. Both are disabled
+// unless configured through environment variables:
+//
+//   - OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) sends
+//     OpenTelemetry spans over OTLP/HTTP. The other standard OTEL_* variables,
+//     like OTEL_EXPORTER_OTLP_HEADERS and OTEL_SERVICE_NAME, are honored too.
+//   - SCURRY_PUSHGATEWAY_URL pushes migration metrics to a Prometheus push
+//     gateway when scurry exits, under SCURRY_PUSHGATEWAY_JOB (default "scurry").
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Environment variables that configure telemetry
+const (
+	EnvPushgatewayURL = "SCURRY_PUSHGATEWAY_URL"
+	EnvPushgatewayJob = "SCURRY_PUSHGATEWAY_JOB"
+)
+
+const instrumentationName = "github.com/pjtatlow/scurry"
+
+// Tracer returns the tracer for scurry spans. Spans are dropped unless Setup
+// enabled tracing.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup enables the exporters configured in the environment. The returned
+// function flushes pending spans and pushes metrics, and must be called before
+// exiting.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	var shutdowns []func(context.Context) error
+
+	if tracingEnabled() {
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		res, err := resource.New(ctx,
+			resource.WithAttributes(attribute.String("service.name", "scurry")),
+			resource.WithFromEnv(),
+			resource.WithTelemetrySDK(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OpenTelemetry resource: %w", err)
+		}
+		provider := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(res),
+		)
+		otel.SetTracerProvider(provider)
+		shutdowns = append(shutdowns, provider.Shutdown)
+	}
+
+	if url := os.Getenv(EnvPushgatewayURL); url != "" {
+		job := os.Getenv(EnvPushgatewayJob)
+		if job == "" {
+			job = "scurry"
+		}
+		shutdowns = append(shutdowns, func(ctx context.Context) error {
+			return defaultMetrics.Push(ctx, url, job)
+		})
+	}
+
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, shutdown := range shutdowns {
+			errs = append(errs, shutdown(ctx))
+		}
+		return errors.Join(errs...)
+	}, nil
+}
+
+// tracingEnabled reports whether an OTLP endpoint is configured
+func tracingEnabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
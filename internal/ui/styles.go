@@ -10,6 +10,7 @@ import (
 	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
 	"github.com/mattn/go-isatty"
 )
 
@@ -148,6 +149,28 @@ func SuccessBanner(text string) string {
 	return SuccessBannerStyle.Render(text)
 }
 
+// Table renders rows as a bordered table with a header row
+func Table(headers []string, rows [][]string) string {
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		Headers(headers...).
+		Rows(rows...)
+	if noColor {
+		t = t.StyleFunc(func(row, col int) lipgloss.Style {
+			return lipgloss.NewStyle().Padding(0, 1)
+		})
+	} else {
+		t = t.BorderStyle(SubtleStyle).
+			StyleFunc(func(row, col int) lipgloss.Style {
+				if row == table.HeaderRow {
+					return lipgloss.NewStyle().Bold(true).Padding(0, 1)
+				}
+				return lipgloss.NewStyle().Padding(0, 1)
+			})
+	}
+	return t.String()
+}
+
 func SqlCode(text string) string {
 	if noColor {
 		return text
@@ -232,3 +255,56 @@ func ConfirmPrompt(question string) (bool, error) {
 
 	return confirmed, nil
 }
+
+// TypedConfirmPrompt asks the user to type expected to confirm, the way cloud
+// consoles confirm deletions. Returns true only if the input matches exactly.
+// Returns an error if not running in an interactive terminal
+func TypedConfirmPrompt(question, expected string) (bool, error) {
+	if !IsInteractive() {
+		return false, fmt.Errorf("confirmation prompt requires an interactive terminal\nRun this command in a terminal with TTY support")
+	}
+
+	var typed string
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(question).
+				Description(fmt.Sprintf("Type %s to confirm", expected)).
+				Value(&typed),
+		),
+	).WithTheme(HuhTheme())
+
+	err := form.Run()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(typed) == expected, nil
+}
+
+// SelectPrompt displays a single-choice menu using huh and returns the selected option
+// Returns an error if not running in an interactive terminal
+func SelectPrompt(title string, options ...string) (string, error) {
+	if !IsInteractive() {
+		return "", fmt.Errorf("selection prompt requires an interactive terminal\nRun this command in a terminal with TTY support")
+	}
+
+	var selected string
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title(title).
+				Options(huh.NewOptions(options...)...).
+				Value(&selected),
+		),
+	).WithTheme(HuhTheme())
+
+	err := form.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return selected, nil
+}
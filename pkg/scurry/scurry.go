@@ -0,0 +1,238 @@
+// Package scurry diffs CockroachDB schemas and generates the migrations between
+// them. It's the library behind the scurry command, for programs that want to
+// plan schema changes without shelling out to the binary.
+//
+// A typical caller loads the desired schema from definition files and the
+// current one from a database, compares them, and generates the statements:
+//
+//	local, err := scurry.LoadDefinitions(ctx, afero.NewOsFs(), []string{"./schema"}, "")
+//	remote, err := scurry.LoadDatabase(ctx, dbURL)
+//	diff := scurry.Compare(local, remote)
+//	migration, err := diff.GenerateMigration(scurry.GenerateOptions{})
+//
+// Everything outside this package is internal and may change between releases.
+package scurry
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/afero"
+
+	"github.com/pjtatlow/scurry/internal/db"
+	"github.com/pjtatlow/scurry/internal/lint"
+	"github.com/pjtatlow/scurry/internal/schema"
+)
+
+// Schema is a set of schema objects: tables, types, sequences, routines, views
+// and triggers
+type Schema struct {
+	schema *schema.Schema
+}
+
+// ParseSchema parses CREATE statements into a schema without a database. The
+// statements are compared as written, so a schema parsed this way should only
+// be compared with another written the same way; see LoadDefinitions.
+func ParseSchema(sql string) (*Schema, error) {
+	statements, err := schema.ParseSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: schema.NewSchema(statements...)}, nil
+}
+
+// LoadDefinitions loads the SQL files in the definition directories, with the
+// overlays/<env> directories merged over them when env isn't empty. The
+// definitions are applied to a shadow database and read back, so they compare
+// cleanly with a schema loaded by LoadDatabase.
+func LoadDefinitions(ctx context.Context, fs afero.Fs, dirs []string, env string) (*Schema, error) {
+	client, err := db.GetShadowDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shadow database client: %w", err)
+	}
+	defer client.Close()
+
+	s, err := schema.LoadFromDirectoriesForEnv(ctx, fs, dirs, env, client)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: s}, nil
+}
+
+// LoadDatabase loads the schema of the database at dbURL
+func LoadDatabase(ctx context.Context, dbURL string) (*Schema, error) {
+	client, err := db.Connect(ctx, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer client.Close()
+
+	s, err := schema.LoadFromDatabase(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: s}, nil
+}
+
+// Tables returns the qualified names of the schema's tables
+func (s *Schema) Tables() []string {
+	names := make([]string, 0, len(s.schema.Tables))
+	for _, table := range s.schema.Tables {
+		names = append(names, table.ResolvedName())
+	}
+	return names
+}
+
+// Change is one difference between two schemas
+type Change struct {
+	// Type is the kind of change, e.g. "table_added" or "column_type_changed"
+	Type string `json:"type"`
+	// Object is the changed object, e.g. "public.users"
+	Object string `json:"object"`
+	// Description says what changed in a sentence
+	Description string `json:"description"`
+	// Source is the definition file and line the object is defined at, if known
+	Source string `json:"source,omitempty"`
+	// Dangerous is true when applying the change can lose data
+	Dangerous bool `json:"dangerous"`
+	// Warning explains the danger, or other caveats of applying the change
+	Warning string `json:"warning,omitempty"`
+}
+
+// Diff is the set of changes that turn one schema into another
+type Diff struct {
+	result *schema.ComparisonResult
+}
+
+// Compare returns the changes that make the remote schema match the local one
+func Compare(local, remote *Schema) *Diff {
+	return &Diff{result: schema.Compare(local.schema, remote.schema)}
+}
+
+// HasChanges returns true if the schemas differ
+func (d *Diff) HasChanges() bool {
+	return d.result.HasChanges()
+}
+
+// Changes returns each difference between the schemas
+func (d *Diff) Changes() []Change {
+	changes := make([]Change, 0, len(d.result.Differences))
+	for _, diff := range d.result.Differences {
+		changes = append(changes, Change{
+			Type:        string(diff.Type),
+			Object:      diff.ObjectName,
+			Description: diff.Description,
+			Source:      diff.Source.String(),
+			Dangerous:   diff.Dangerous,
+			Warning:     diff.WarningMessage,
+		})
+	}
+	return changes
+}
+
+// Summary returns a human-readable list of the changes
+func (d *Diff) Summary() string {
+	return d.result.Summary()
+}
+
+// GenerateOptions controls the statements GenerateMigration produces
+type GenerateOptions struct {
+	// Pretty formats each statement over multiple lines
+	Pretty bool
+	// TargetVersion is the CockroachDB release the migration will run on, e.g.
+	// "v24.1". Statements it doesn't support are rewritten, or fail the
+	// generation. Empty means the release scurry's parser tracks. It can't be
+	// set with the postgres dialect.
+	TargetVersion string
+	// Dialect is "cockroachdb" (the default) or "postgres"
+	Dialect string
+}
+
+// Migration is the statements that apply a Diff, in dependency order
+type Migration struct {
+	Statements []string `json:"statements"`
+	Warnings   []string `json:"warnings"`
+}
+
+// GenerateMigration orders the statements of every change so each object is
+// created after the objects it depends on. It fails if any change can't be
+// expressed for the target version or dialect.
+func (d *Diff) GenerateMigration(opts GenerateOptions) (*Migration, error) {
+	result := d.clone()
+
+	dialect := schema.DialectCockroachDB
+	if opts.Dialect != "" {
+		var err error
+		if dialect, err = schema.ParseDialect(opts.Dialect); err != nil {
+			return nil, err
+		}
+	}
+	if dialect == schema.DialectPostgres && opts.TargetVersion != "" {
+		return nil, fmt.Errorf("TargetVersion is a CockroachDB release and can't be used with the postgres dialect")
+	}
+	if dialect == schema.DialectPostgres {
+		result.ForDialect(dialect)
+	} else if opts.TargetVersion != "" {
+		target, err := schema.ParseVersion(opts.TargetVersion)
+		if err != nil {
+			return nil, err
+		}
+		result.ForTargetVersion(target)
+	}
+
+	statements, warnings, err := result.GenerateMigrations(opts.Pretty)
+	if err != nil {
+		return nil, err
+	}
+	return &Migration{Statements: statements, Warnings: warnings}, nil
+}
+
+// clone copies the differences deeply enough that rewriting their statements
+// for a version or dialect leaves the diff alone
+func (d *Diff) clone() *schema.ComparisonResult {
+	differences := make([]schema.Difference, len(d.result.Differences))
+	for i, diff := range d.result.Differences {
+		diff.Phases = slices.Clone(diff.Phases)
+		differences[i] = diff
+	}
+	return &schema.ComparisonResult{Differences: differences}
+}
+
+// LintIssue is a likely mistake in a schema that is still valid SQL
+type LintIssue struct {
+	// Rule names the check, e.g. "fk-missing-index"
+	Rule        string `json:"rule"`
+	Table       string `json:"table"`
+	Constraint  string `json:"constraint"`
+	Description string `json:"description"`
+	Suggestion  string `json:"suggestion"`
+	// Source is the definition file and line of the table, if known
+	Source string `json:"source,omitempty"`
+}
+
+// Lint checks the schema for likely mistakes, like foreign keys without a
+// covering index. Issues suppressed by -- scurry:lint-disable directives in
+// the definition directories are left out; pass no directories to report all.
+func Lint(fs afero.Fs, s *Schema, dirs ...string) ([]LintIssue, error) {
+	disables, err := lint.LoadDisables(fs, dirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lint directives: %w", err)
+	}
+
+	var issues []LintIssue
+	for _, issue := range lint.Check(s.schema) {
+		if lint.IsSuppressed(issue, disables) {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Rule:        issue.Rule,
+			Table:       issue.Table,
+			Constraint:  issue.Constraint,
+			Description: issue.Description,
+			Suggestion:  issue.Suggestion,
+			Source:      issue.Source.String(),
+		})
+	}
+	return issues, nil
+}
@@ -0,0 +1,152 @@
+package scurry
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareAndGenerate(t *testing.T) {
+	const users = `CREATE TABLE public.users (
+		id INT8 NOT NULL,
+		email STRING NOT NULL,
+		CONSTRAINT users_pkey PRIMARY KEY (id ASC)
+	);`
+
+	tests := []struct {
+		name      string
+		local     string
+		remote    string
+		opts      GenerateOptions
+		expected  []string
+		dangerous bool
+		wantErr   string
+	}{
+		{
+			name:   "no changes",
+			local:  users,
+			remote: users,
+		},
+		{
+			name:     "table added",
+			local:    users,
+			expected: []string{"CREATE TABLE public.users (id INT8 NOT NULL, email STRING NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id ASC))"},
+		},
+		{
+			name:      "table removed",
+			remote:    users,
+			expected:  []string{"DROP TABLE IF EXISTS public.users RESTRICT"},
+			dangerous: true,
+		},
+		{
+			name:     "postgres dialect",
+			local:    users,
+			opts:     GenerateOptions{Dialect: "postgres"},
+			expected: []string{"CREATE TABLE public.users (id BIGINT NOT NULL, email TEXT NOT NULL, CONSTRAINT users_pkey PRIMARY KEY (id))"},
+		},
+		{
+			name:    "unsupported on the target version",
+			local:   "CREATE TRIGGER audit AFTER INSERT ON public.users FOR EACH ROW EXECUTE FUNCTION public.audit();",
+			opts:    GenerateOptions{TargetVersion: "v24.1"},
+			wantErr: "triggers needs CockroachDB v24.3 or later",
+		},
+		{
+			name:    "target version with the postgres dialect",
+			local:   users,
+			opts:    GenerateOptions{Dialect: "postgres", TargetVersion: "v24.1"},
+			wantErr: "can't be used with the postgres dialect",
+		},
+		{
+			name:    "unknown dialect",
+			local:   users,
+			opts:    GenerateOptions{Dialect: "mysql"},
+			wantErr: "mysql",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, err := ParseSchema(tt.local)
+			require.NoError(t, err)
+			remote, err := ParseSchema(tt.remote)
+			require.NoError(t, err)
+
+			diff := Compare(local, remote)
+			assert.Equal(t, len(tt.expected) > 0 || tt.wantErr != "", diff.HasChanges())
+
+			migration, err := diff.GenerateMigration(tt.opts)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, migration.Statements)
+			for _, change := range diff.Changes() {
+				assert.Equal(t, tt.dangerous, change.Dangerous)
+			}
+		})
+	}
+}
+
+func TestGenerateMigrationLeavesDiffAlone(t *testing.T) {
+	diff := Compare(mustParse(t, "CREATE TABLE public.users (id INT8 NOT NULL, name STRING, CONSTRAINT users_pkey PRIMARY KEY (id ASC));"), mustParse(t, ""))
+	postgres, err := diff.GenerateMigration(GenerateOptions{Dialect: "postgres"})
+	require.NoError(t, err)
+	cockroach, err := diff.GenerateMigration(GenerateOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"CREATE TABLE public.users (id BIGINT NOT NULL, name TEXT, CONSTRAINT users_pkey PRIMARY KEY (id))"}, postgres.Statements)
+	assert.Equal(t, []string{"CREATE TABLE public.users (id INT8 NOT NULL, name STRING, CONSTRAINT users_pkey PRIMARY KEY (id ASC))"}, cockroach.Statements)
+}
+
+func TestLint(t *testing.T) {
+	const orders = `CREATE TABLE public.orders (
+		id INT8 NOT NULL,
+		user_id INT8,
+		CONSTRAINT orders_pkey PRIMARY KEY (id ASC),
+		CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES public.users (id)
+	);`
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantRules []string
+	}{
+		{
+			name:      "issue reported",
+			files:     map[string]string{"schema/orders.sql": orders},
+			wantRules: []string{"fk-missing-index"},
+		},
+		{
+			name:  "issue suppressed",
+			files: map[string]string{"schema/orders.sql": "-- scurry:lint-disable=fk-missing-index:orders\n" + orders},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, sql := range tt.files {
+				require.NoError(t, afero.WriteFile(fs, path, []byte(sql), 0644))
+			}
+
+			issues, err := Lint(fs, mustParse(t, orders), "schema")
+			require.NoError(t, err)
+			var rules []string
+			for _, issue := range issues {
+				rules = append(rules, issue.Rule)
+			}
+			assert.Equal(t, tt.wantRules, rules)
+		})
+	}
+}
+
+func mustParse(t *testing.T, sql string) *Schema {
+	t.Helper()
+	s, err := ParseSchema(sql)
+	require.NoError(t, err)
+	return s
+}